@@ -9,6 +9,7 @@ import (
 	"github.com/kiosk404/echoryn/internal/echoadm/types"
 	"github.com/kiosk404/echoryn/internal/echoadm/utils/templates"
 	"github.com/kiosk404/echoryn/internal/echoctl/cmd/chat"
+	"github.com/kiosk404/echoryn/internal/echoctl/cmd/models"
 	cmdutil "github.com/kiosk404/echoryn/internal/echoctl/cmd/util"
 	genericapiserver "github.com/kiosk404/echoryn/internal/pkg/server"
 	"github.com/kiosk404/echoryn/pkg/cli/genericclioptions"
@@ -72,6 +73,7 @@ func NewEchoCtlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 			Message: "Basic Commands:",
 			Commands: []*cobra.Command{
 				chat.NewCmdInfo(f, ioStreams),
+				models.NewCmdModels(f, ioStreams),
 			},
 		},
 	}
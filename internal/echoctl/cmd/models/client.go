@@ -0,0 +1,110 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// ScanRequest is the request body for POST /v1/models/scan.
+type ScanRequest struct {
+	Type        string   `json:"type,omitempty"`
+	Probes      []string `json:"probes,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"`
+	TimeoutMs   int64    `json:"timeout_ms,omitempty"`
+}
+
+// ScanEvent is one streamed scan result.
+type ScanEvent struct {
+	Completed int         `json:"completed"`
+	Total     int         `json:"total"`
+	Result    *ScanResult `json:"result"`
+}
+
+// ScanResult is the scan result of one model.
+type ScanResult struct {
+	Model     string        `json:"model"`
+	Available bool          `json:"available"`
+	Probes    []ProbeResult `json:"probes"`
+}
+
+// ProbeResult is the outcome of one probe.
+type ProbeResult struct {
+	Type      string `json:"type"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+}
+
+// ScanClient is the HTTP client for hivemind /v1/models/scan.
+type ScanClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewScanClient creates a new client. Scans can take minutes, so the
+// default client has no overall timeout; cancel the context instead.
+func NewScanClient(baseURL string, httpClient *http.Client) *ScanClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &ScanClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: httpClient,
+	}
+}
+
+// Scan starts a scan and calls onEvent for each model as its probes finish.
+func (c *ScanClient) Scan(ctx context.Context, scanReq ScanRequest, onEvent func(ScanEvent)) error {
+	body, err := json.Marshal(scanReq)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/models/scan", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return nil
+		}
+		var event ScanEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read stream: %w", err)
+	}
+	return fmt.Errorf("scan stream ended unexpectedly")
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/echoadm/utils/templates"
+	"github.com/kiosk404/echoryn/internal/echoctl/cmd/util"
+	"github.com/kiosk404/echoryn/pkg/cli/genericclioptions"
+	"github.com/spf13/cobra"
+)
+
+var scanExample = templates.Examples(`
+		# Probe every chat model with the basic chat probe
+		echoctl models scan
+
+		# Also check tool calling, probing 5 models at a time
+		echoctl models scan --probe chat,tool_call --concurrency 5
+
+		# Scan the embedding models of a specific hivemind server
+		echoctl models scan --type embedding --server=http://localhost:11789`)
+
+// ScanOptions holds the flags of `echoctl models scan`.
+type ScanOptions struct {
+	ServerAddr  string
+	Type        string
+	Probes      []string
+	Concurrency int
+	Timeout     time.Duration
+
+	factory util.Factory
+	genericclioptions.IOStreams
+}
+
+// NewCmdModels returns the `echoctl models` command group.
+func NewCmdModels(f util.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "models",
+		DisableFlagsInUseLine: true,
+		Short:                 "Inspect the models registered in the hivemind",
+		Run: func(cmd *cobra.Command, args []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.AddCommand(NewCmdScan(f, ioStreams))
+	return cmd
+}
+
+// NewCmdScan returns the `echoctl models scan` command.
+func NewCmdScan(f util.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	o := NewScanOptions(f, ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "scan",
+		DisableFlagsInUseLine: true,
+		Short:                 "Probe the availability of the registered models",
+		Long: `
+		Probe every enabled model of a type through the hivemind server and
+		print a table of the results (availability, latency, error).
+
+		Results stream in as each model's probes finish; a progress bar shows
+		how many models are done.
+		`,
+		Example: scanExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckErr(o.Complete(args))
+			util.CheckErr(o.Run(cmd.Context()))
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ServerAddr, "server", o.ServerAddr, "Hivemind HTTP Server Address")
+	cmd.Flags().StringVar(&o.Type, "type", o.Type, "Model type to scan: chat, embedding or rerank")
+	cmd.Flags().StringSliceVar(&o.Probes, "probe", o.Probes, "Probes to run: chat, tool_call, vision, streaming, context_window")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", o.Concurrency, "Maximum number of models probed in parallel (0: server default)")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "Per-probe timeout (0: server default)")
+
+	return cmd
+}
+
+// NewScanOptions returns ScanOptions with default values.
+func NewScanOptions(f util.Factory, ioStreams genericclioptions.IOStreams) *ScanOptions {
+	return &ScanOptions{
+		factory:    f,
+		IOStreams:  ioStreams,
+		ServerAddr: "http://localhost:11789",
+		Type:       "chat",
+		Probes:     []string{"chat"},
+	}
+}
+
+// Complete validates the flags and normalizes the server address.
+func (o *ScanOptions) Complete(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("--concurrency must not be negative")
+	}
+	if !strings.HasPrefix(o.ServerAddr, "http://") && !strings.HasPrefix(o.ServerAddr, "https://") {
+		o.ServerAddr = "http://" + o.ServerAddr
+	}
+	return nil
+}
+
+// Run scans the models, drawing a progress bar on ErrOut, then prints the
+// results table on Out.
+func (o *ScanOptions) Run(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := NewScanClient(o.ServerAddr, o.factory.HTTPClient())
+
+	var results []ScanResult
+	err := client.Scan(ctx, ScanRequest{
+		Type:        o.Type,
+		Probes:      o.Probes,
+		Concurrency: o.Concurrency,
+		TimeoutMs:   o.Timeout.Milliseconds(),
+	}, func(event ScanEvent) {
+		if event.Result != nil {
+			results = append(results, *event.Result)
+		}
+		fmt.Fprintf(o.ErrOut, "\r%s", RenderProgress(event.Completed, event.Total, progressWidth))
+	})
+	if len(results) > 0 {
+		fmt.Fprint(o.ErrOut, "\r\033[K")
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(o.Out, "No models to scan.")
+		return nil
+	}
+	fmt.Fprintln(o.Out, RenderResults(results))
+	return nil
+}
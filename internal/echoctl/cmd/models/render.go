@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// progressWidth is the number of cells of the progress bar.
+const progressWidth = 30
+
+var (
+	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("208")).Padding(0, 1)
+	cellStyle   = lipgloss.NewStyle().Padding(0, 1)
+	okStyle     = cellStyle.Foreground(lipgloss.Color("42"))
+	failStyle   = cellStyle.Foreground(lipgloss.Color("196"))
+	dimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	barStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+)
+
+// RenderProgress renders a progress bar such as "██████░░░░ 3/5".
+func RenderProgress(completed, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(width, completed*width/total)
+	}
+	return barStyle.Render(strings.Repeat("█", filled)) +
+		dimStyle.Render(strings.Repeat("░", width-filled)) +
+		fmt.Sprintf(" %d/%d", completed, total)
+}
+
+// RenderResults renders the scan results as a table sorted by model, with
+// columns MODEL, AVAILABLE, LATENCY and ERROR.
+func RenderResults(results []ScanResult) string {
+	sorted := slices.Clone(results)
+	slices.SortFunc(sorted, func(a, b ScanResult) int { return strings.Compare(a.Model, b.Model) })
+
+	rows := make([][]string, 0, len(sorted))
+	for _, r := range sorted {
+		available := "no"
+		if r.Available {
+			available = "yes"
+		}
+		rows = append(rows, []string{r.Model, available, formatLatency(r), firstError(r)})
+	}
+
+	return table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(dimStyle).
+		Headers("MODEL", "AVAILABLE", "LATENCY", "ERROR").
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == table.HeaderRow:
+				return headerStyle
+			case col == 1 && sorted[row].Available:
+				return okStyle
+			case col == 1:
+				return failStyle
+			}
+			return cellStyle
+		}).
+		String()
+}
+
+// formatLatency reports the latency of the chat probe, or of the first
+// successful probe when no chat probe ran.
+func formatLatency(r ScanResult) string {
+	var latency int64
+	for _, p := range r.Probes {
+		if !p.OK || p.LatencyMs <= 0 {
+			continue
+		}
+		if p.Type == "chat" {
+			latency = p.LatencyMs
+			break
+		}
+		if latency == 0 {
+			latency = p.LatencyMs
+		}
+	}
+	if latency == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", latency)
+}
+
+// firstError returns the error of the first failed probe, prefixed with the
+// probe type when several probes ran.
+func firstError(r ScanResult) string {
+	for _, p := range r.Probes {
+		if p.OK || p.Skipped || p.Error == "" {
+			continue
+		}
+		if len(r.Probes) > 1 {
+			return p.Type + ": " + p.Error
+		}
+		return p.Error
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderProgress(t *testing.T) {
+	tests := []struct {
+		completed, total, width int
+		wantFilled              int
+		wantCount               string
+	}{
+		{0, 5, 10, 0, " 0/5"},
+		{3, 5, 10, 6, " 3/5"},
+		{5, 5, 10, 10, " 5/5"},
+		{7, 5, 10, 10, " 7/5"},
+		{0, 0, 10, 0, " 0/0"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.wantCount), func(t *testing.T) {
+			got := RenderProgress(tt.completed, tt.total, tt.width)
+			if filled := strings.Count(got, "█"); filled != tt.wantFilled {
+				t.Fatalf("%q has %d filled cells, want %d", got, filled, tt.wantFilled)
+			}
+			if cells := strings.Count(got, "█") + strings.Count(got, "░"); cells != tt.width {
+				t.Fatalf("%q has %d cells, want %d", got, cells, tt.width)
+			}
+			if !strings.HasSuffix(got, tt.wantCount) {
+				t.Fatalf("%q does not end with %q", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes []ProbeResult
+		want   string
+	}{
+		{"no probes", nil, "-"},
+		{"chat", []ProbeResult{{Type: "tool_call", OK: true, LatencyMs: 40}, {Type: "chat", OK: true, LatencyMs: 120}}, "120ms"},
+		{"first successful without chat", []ProbeResult{{Type: "streaming", LatencyMs: 10}, {Type: "tool_call", OK: true, LatencyMs: 40}, {Type: "vision", OK: true, LatencyMs: 90}}, "40ms"},
+		{"failed chat", []ProbeResult{{Type: "chat", LatencyMs: 300, Error: "timeout"}}, "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLatency(ScanResult{Probes: tt.probes}); got != tt.want {
+				t.Fatalf("formatLatency = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes []ProbeResult
+		want   string
+	}{
+		{"all ok", []ProbeResult{{Type: "chat", OK: true}}, ""},
+		{"single probe", []ProbeResult{{Type: "chat", Error: "401 unauthorized"}}, "401 unauthorized"},
+		{"several probes", []ProbeResult{{Type: "chat", OK: true}, {Type: "vision", Error: "no image input"}}, "vision: no image input"},
+		{"skipped ignored", []ProbeResult{{Type: "vision", Skipped: true, Error: "skipped"}, {Type: "tool_call", Error: "no tools"}}, "tool_call: no tools"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstError(ScanResult{Probes: tt.probes}); got != tt.want {
+				t.Fatalf("firstError = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderResultsSortsByModel(t *testing.T) {
+	out := RenderResults([]ScanResult{
+		{Model: "openai/gpt-4o", Available: true, Probes: []ProbeResult{{Type: "chat", OK: true, LatencyMs: 250}}},
+		{Model: "anthropic/claude", Probes: []ProbeResult{{Type: "chat", Error: "401 unauthorized"}}},
+	})
+	for _, want := range []string{"MODEL", "AVAILABLE", "LATENCY", "ERROR", "250ms", "401 unauthorized"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("table lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "anthropic/claude") > strings.Index(out, "openai/gpt-4o") {
+		t.Fatalf("rows not sorted by model:\n%s", out)
+	}
+}
@@ -1,7 +1,12 @@
 package hivemind
 
 import (
+	"time"
+
 	"github.com/kiosk404/echoryn/internal/hivemind/handler/middleware"
+	v1 "github.com/kiosk404/echoryn/internal/hivemind/handler/v1"
+	"github.com/kiosk404/echoryn/internal/hivemind/options"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 )
 
 // GatewayConfig holds the gateway-level configuration for HTTP API endpoints.
@@ -12,6 +17,22 @@ type GatewayConfig struct {
 	Store StoreConfig `json:"store"`
 	// Defaults holds the default values for the gateway.
 	Defaults GatewayDefaults `json:"defaults"`
+	// RateLimit holds the throttling configuration for the chat endpoint.
+	RateLimit middleware.RateLimitConfig `json:"rate_limit"`
+	// Stream holds the SSE streaming configuration for the chat endpoint.
+	Stream StreamConfig `json:"stream"`
+	// IdempotencyTTL is how long chat completion results are kept for
+	// retries sending the same Idempotency-Key. A negative value disables it.
+	IdempotencyTTL time.Duration `json:"idempotency_ttl"`
+	// MaxChoices is the largest "n" a chat completion request may ask for.
+	MaxChoices int `json:"max_choices"`
+}
+
+// StreamConfig configures SSE responses.
+type StreamConfig struct {
+	// KeepaliveInterval is how long a stream may stay idle before a ": ping"
+	// comment is written. A negative value disables keepalive pings.
+	KeepaliveInterval time.Duration `json:"keepalive_interval"`
 }
 
 // StoreConfig configures the persistence backend
@@ -28,6 +49,10 @@ type GatewayDefaults struct {
 	AgentID string `json:"agent_id"`
 	// Model holds the default model to use.
 	Model string `json:"model"`
+	// Agent configures agents auto-created by /v1/chat/completions
+	// (memory, tools, max turns, system prompt, persona identity and
+	// workspace); X-Agent-* request headers override it.
+	Agent v1.AgentDefaults `json:"agent"`
 }
 
 func DefaultGatewayConfig() *GatewayConfig {
@@ -43,5 +68,73 @@ func DefaultGatewayConfig() *GatewayConfig {
 			AgentID: "main",
 			Model:   "Echoryn",
 		},
+		RateLimit: middleware.RateLimitConfig{
+			Enabled:           false,
+			RequestsPerMinute: 60,
+			Burst:             10,
+			MaxConcurrentRuns: 2,
+		},
+		Stream: StreamConfig{
+			KeepaliveInterval: v1.DefaultStreamKeepaliveInterval,
+		},
+		IdempotencyTTL: v1.DefaultIdempotencyTTL,
+		MaxChoices:     v1.DefaultMaxChoices,
+	}
+}
+
+// buildGatewayConfig returns the gateway configuration set by the "gateway"
+// options, on top of DefaultGatewayConfig. A nil o yields the defaults.
+func buildGatewayConfig(o *options.GatewayOptions) *GatewayConfig {
+	cfg := DefaultGatewayConfig()
+	if o == nil {
+		return cfg
+	}
+	cfg.Auth = middleware.AuthConfig{
+		Enabled: o.Auth.Enabled,
+		Token:   o.Auth.Token,
+	}
+	cfg.RateLimit = middleware.RateLimitConfig{
+		Enabled:           o.RateLimit.Enabled,
+		RequestsPerMinute: o.RateLimit.RequestsPerMinute,
+		Burst:             o.RateLimit.Burst,
+		MaxConcurrentRuns: o.RateLimit.MaxConcurrentRuns,
+	}
+	if o.Defaults.AgentID != "" {
+		cfg.Defaults.AgentID = o.Defaults.AgentID
+	}
+	if o.Defaults.Model != "" {
+		cfg.Defaults.Model = o.Defaults.Model
+	}
+	cfg.Defaults.Agent = buildAgentDefaults(o.Defaults.Agent)
+	if o.Stream.KeepaliveInterval != 0 {
+		cfg.Stream.KeepaliveInterval = o.Stream.KeepaliveInterval
+	}
+	if o.IdempotencyTTL != 0 {
+		cfg.IdempotencyTTL = o.IdempotencyTTL
+	}
+	if o.MaxChoices != 0 {
+		cfg.MaxChoices = o.MaxChoices
+	}
+	return cfg
+}
+
+// buildAgentDefaults converts the auto-created agent options.
+func buildAgentDefaults(o options.GatewayAgentDefaultsOptions) v1.AgentDefaults {
+	d := v1.AgentDefaults{
+		Memory:       o.Memory,
+		Tools:        o.Tools,
+		MaxTurns:     o.MaxTurns,
+		SystemPrompt: o.SystemPrompt,
+		WorkspaceDir: o.WorkspaceDir,
+	}
+	if id := o.Identity; id != (options.GatewayAgentIdentityOptions{}) {
+		d.Identity = &entity.AgentIdentity{
+			Name:     id.Name,
+			Emoji:    id.Emoji,
+			Creature: id.Creature,
+			Vibe:     id.Vibe,
+			Theme:    id.Theme,
+		}
 	}
+	return d
 }
@@ -0,0 +1,107 @@
+package hivemind
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/options"
+)
+
+func TestBuildGatewayConfigDefaults(t *testing.T) {
+	got := buildGatewayConfig(options.NewGatewayOptions())
+	want := DefaultGatewayConfig()
+
+	if got.RateLimit != want.RateLimit {
+		t.Fatalf("rate limit = %+v, want defaults %+v", got.RateLimit, want.RateLimit)
+	}
+	if got.Auth != want.Auth {
+		t.Fatalf("auth = %+v, want defaults %+v", got.Auth, want.Auth)
+	}
+	if got.Stream != want.Stream {
+		t.Fatalf("stream = %+v, want defaults %+v", got.Stream, want.Stream)
+	}
+	if got.MaxChoices != want.MaxChoices {
+		t.Fatalf("max choices = %d, want %d", got.MaxChoices, want.MaxChoices)
+	}
+	if got.IdempotencyTTL != want.IdempotencyTTL {
+		t.Fatalf("idempotency ttl = %s, want %s", got.IdempotencyTTL, want.IdempotencyTTL)
+	}
+	if got.Defaults.AgentID != want.Defaults.AgentID || got.Defaults.Model != want.Defaults.Model {
+		t.Fatalf("defaults = %+v, want %+v", got.Defaults, want.Defaults)
+	}
+}
+
+func TestBuildGatewayConfigFromOptions(t *testing.T) {
+	o := options.NewGatewayOptions()
+	o.Auth = options.GatewayAuthOptions{Enabled: true, Token: "secret"}
+	o.RateLimit = options.GatewayRateLimitOptions{
+		Enabled:           true,
+		RequestsPerMinute: 30,
+		Burst:             5,
+		MaxConcurrentRuns: 1,
+	}
+	o.Defaults.AgentID = "support"
+	o.IdempotencyTTL = -1
+	o.Stream.KeepaliveInterval = -1
+	o.MaxChoices = 2
+
+	cfg := buildGatewayConfig(o)
+	if !cfg.RateLimit.Enabled || cfg.RateLimit.RequestsPerMinute != 30 ||
+		cfg.RateLimit.Burst != 5 || cfg.RateLimit.MaxConcurrentRuns != 1 {
+		t.Fatalf("rate limit = %+v", cfg.RateLimit)
+	}
+	if !cfg.Auth.Enabled || cfg.Auth.Token != "secret" {
+		t.Fatalf("auth = %+v", cfg.Auth)
+	}
+	if cfg.Defaults.AgentID != "support" {
+		t.Fatalf("agent id = %q", cfg.Defaults.AgentID)
+	}
+	if cfg.IdempotencyTTL != -1 {
+		t.Fatalf("idempotency ttl = %s, want the disabling -1", cfg.IdempotencyTTL)
+	}
+	if cfg.MaxChoices != 2 {
+		t.Fatalf("max choices = %d, want 2", cfg.MaxChoices)
+	}
+	if cfg.Stream.KeepaliveInterval != -1 {
+		t.Fatalf("keepalive interval = %s, want the disabling -1", cfg.Stream.KeepaliveInterval)
+	}
+	if cfg.Defaults.Model != DefaultGatewayConfig().Defaults.Model {
+		t.Fatalf("empty model option overrode the default: %q", cfg.Defaults.Model)
+	}
+}
+
+func TestBuildGatewayConfigNil(t *testing.T) {
+	if cfg := buildGatewayConfig(nil); cfg == nil || cfg.RateLimit.Enabled {
+		t.Fatalf("nil options: %+v", cfg)
+	}
+}
+
+func TestBuildGatewayConfigAgentDefaults(t *testing.T) {
+	o := options.NewGatewayOptions()
+	raw := `{"defaults":{"agent":{
+		"memory":false,
+		"tools":["web_search","memory_search"],
+		"max-turns":4,
+		"system-prompt":"You are terse.",
+		"workspace-dir":"/srv/persona",
+		"identity":{"name":"Aria","emoji":"🦊","vibe":"sharp"}}}}`
+	if err := json.Unmarshal([]byte(raw), o); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buildGatewayConfig(o).Defaults.Agent
+	if got.SystemPrompt != "You are terse." || got.WorkspaceDir != "/srv/persona" {
+		t.Fatalf("agent defaults = %+v", got)
+	}
+	if got.Memory == nil || *got.Memory || got.MaxTurns != 4 ||
+		len(got.Tools) != 2 || got.Tools[0] != "web_search" || got.Tools[1] != "memory_search" {
+		t.Fatalf("memory = %v, tools = %v, max turns = %d", got.Memory, got.Tools, got.MaxTurns)
+	}
+	if got.Identity == nil || got.Identity.Name != "Aria" || got.Identity.Emoji != "🦊" || got.Identity.Vibe != "sharp" {
+		t.Fatalf("identity = %+v", got.Identity)
+	}
+
+	if d := buildGatewayConfig(options.NewGatewayOptions()).Defaults.Agent; d.Identity != nil || d.Memory != nil || d.Tools != nil || d.MaxTurns != 0 {
+		t.Fatalf("agent defaults without options = %+v, want none", d)
+	}
+}
@@ -34,7 +34,7 @@ func (c *AuthConfig) ResolveToken() string {
 // Security features (aligned with OpenClaw auth.ts):
 //   - Uses crypto/subtle.ConstantTimeCompare to prevent timing attacks
 //   - Skips auth for local loopback requests when allowLocal is true
-//   - Whitelists /healthz and /version paths
+//   - Whitelists /healthz, /readyz and /version paths
 func BearerAuth(cfg *AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !cfg.Enabled {
@@ -50,7 +50,7 @@ func BearerAuth(cfg *AuthConfig) gin.HandlerFunc {
 
 		// Whitelist paths that don't require auth.
 		path := c.Request.URL.Path
-		if path == "/healthz" || path == "/version" {
+		if path == "/healthz" || path == "/readyz" || path == "/version" {
 			c.Next()
 			return
 		}
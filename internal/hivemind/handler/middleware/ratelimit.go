@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig holds configuration for throttling run-starting endpoints.
+//
+// Requests are keyed by the X-Session-Key header, then by the Bearer token,
+// and finally by the client IP.
+type RateLimitConfig struct {
+	// Enabled controls whether rate limiting is enforced.
+	Enabled bool `json:"enabled"`
+
+	// RequestsPerMinute is the sustained request rate allowed per key.
+	// 0 disables the request-rate check.
+	RequestsPerMinute int `json:"requests_per_minute"`
+
+	// Burst is the number of requests a key may issue back-to-back before
+	// being throttled to RequestsPerMinute. Defaults to RequestsPerMinute.
+	Burst int `json:"burst"`
+
+	// MaxConcurrentRuns is the number of agent runs a key may have in flight.
+	// 0 disables the concurrency check.
+	MaxConcurrentRuns int `json:"max_concurrent_runs"`
+}
+
+// RateLimitStore holds rate limiter state.
+//
+// MemoryRateLimitStore keeps state in-process; a shared backend (e.g. Redis)
+// can implement the same interface for multi-instance deployments.
+type RateLimitStore interface {
+	// Take consumes one request token for key from a bucket refilled at
+	// perMinute tokens per minute and holding at most burst tokens.
+	// When the bucket is empty it returns false and the wait until the next token.
+	Take(key string, perMinute, burst int) (bool, time.Duration)
+
+	// Acquire reserves one concurrent-run slot for key.
+	// It returns false when max slots are already in use.
+	Acquire(key string, max int) bool
+
+	// Release frees a slot reserved by Acquire.
+	Release(key string)
+}
+
+// runSlotKey is the gin context key of the concurrent-run slot reserved for a request.
+const runSlotKey = "ratelimit.run_slot"
+
+// runSlot is a concurrent-run slot whose release can be handed over to the run.
+type runSlot struct {
+	release func()
+	taken   bool
+}
+
+// RateLimit returns a Gin middleware that enforces per-key request rate and
+// concurrent-run limits, responding 429 with a Retry-After header when exceeded.
+//
+// The concurrent-run slot is released when the handler returns, unless the
+// handler claims it with TakeRunRelease to release it when the run finishes.
+func RateLimit(cfg *RateLimitConfig, store RateLimitStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg == nil || !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c)
+
+		if cfg.RequestsPerMinute > 0 {
+			burst := cfg.Burst
+			if burst <= 0 {
+				burst = cfg.RequestsPerMinute
+			}
+			if ok, wait := store.Take(key, cfg.RequestsPerMinute, burst); !ok {
+				abortTooManyRequests(c, wait, "rate limit exceeded, retry later")
+				return
+			}
+		}
+
+		if cfg.MaxConcurrentRuns > 0 {
+			if !store.Acquire(key, cfg.MaxConcurrentRuns) {
+				abortTooManyRequests(c, time.Second, "too many concurrent runs, retry after a run finishes")
+				return
+			}
+			slot := &runSlot{release: sync.OnceFunc(func() { store.Release(key) })}
+			c.Set(runSlotKey, slot)
+			defer func() {
+				if !slot.taken {
+					slot.release()
+				}
+			}()
+		}
+
+		c.Next()
+	}
+}
+
+// TakeRunRelease transfers ownership of the request's concurrent-run slot to
+// the caller, which must invoke the returned func once the run finishes.
+// Returns nil when no slot was reserved for the request.
+func TakeRunRelease(c *gin.Context) func() {
+	v, ok := c.Get(runSlotKey)
+	if !ok {
+		return nil
+	}
+	slot := v.(*runSlot)
+	slot.taken = true
+	return slot.release
+}
+
+// rateLimitKey resolves the identity requests are throttled by.
+func rateLimitKey(c *gin.Context) string {
+	if sessionKey := c.GetHeader("X-Session-Key"); sessionKey != "" {
+		return "session:" + sessionKey
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		// Never keep raw credentials in limiter state.
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func abortTooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "rate_limit_error",
+		},
+	})
+}
+
+// MemoryRateLimitStore is an in-process RateLimitStore using token buckets.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	runs    map[string]int
+	takes   int
+	now     func() time.Time
+}
+
+// tokenBucket is the request budget of a single key.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Duration // time to refill from empty, used for eviction
+}
+
+// sweepEvery is the number of Take calls between sweeps of idle buckets.
+const sweepEvery = 1024
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		runs:    make(map[string]int),
+		now:     time.Now,
+	}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(key string, perMinute, burst int) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	rate := float64(perMinute) / float64(time.Minute) // tokens per nanosecond
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.full = time.Duration(float64(burst) / rate)
+	b.tokens = math.Min(float64(burst), b.tokens+float64(now.Sub(b.last))*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate)
+}
+
+// Acquire implements RateLimitStore.
+func (s *MemoryRateLimitStore) Acquire(key string, max int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.runs[key] >= max {
+		return false
+	}
+	s.runs[key]++
+	return true
+}
+
+// Release implements RateLimitStore.
+func (s *MemoryRateLimitStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.runs[key] <= 1 {
+		delete(s.runs, key)
+		return
+	}
+	s.runs[key]--
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// since a fresh bucket is equivalent. Must be called with s.mu held.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.full {
+			delete(s.buckets, key)
+		}
+	}
+}
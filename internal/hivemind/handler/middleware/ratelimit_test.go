@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeClock is a settable time source for MemoryRateLimitStore.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStore() (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	s := NewMemoryRateLimitStore()
+	s.now = clock.now
+	return s, clock
+}
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	s, clock := newTestStore()
+
+	// A fresh bucket allows a burst of 3.
+	for i := 0; i < 3; i++ {
+		if ok, _ := s.Take("k", 60, 3); !ok {
+			t.Fatalf("take %d: throttled within burst", i)
+		}
+	}
+	ok, wait := s.Take("k", 60, 3)
+	if ok {
+		t.Fatal("take beyond burst: allowed")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("wait = %s, want (0, 1s] at 60/min", wait)
+	}
+
+	// Other keys have their own bucket.
+	if ok, _ := s.Take("other", 60, 3); !ok {
+		t.Fatal("other key throttled")
+	}
+
+	// One token is refilled per second at 60/min.
+	clock.advance(time.Second)
+	if ok, _ := s.Take("k", 60, 3); !ok {
+		t.Fatal("take after refill: throttled")
+	}
+	if ok, _ := s.Take("k", 60, 3); ok {
+		t.Fatal("second take after a single refill: allowed")
+	}
+}
+
+func TestMemoryRateLimitStoreAcquireRelease(t *testing.T) {
+	s, _ := newTestStore()
+
+	if !s.Acquire("k", 2) || !s.Acquire("k", 2) {
+		t.Fatal("acquire within max failed")
+	}
+	if s.Acquire("k", 2) {
+		t.Fatal("acquire beyond max succeeded")
+	}
+	s.Release("k")
+	if !s.Acquire("k", 2) {
+		t.Fatal("acquire after release failed")
+	}
+	s.Release("k")
+	s.Release("k")
+	if len(s.runs) != 0 {
+		t.Fatalf("runs = %v, want empty after releasing every slot", s.runs)
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	s, clock := newTestStore()
+
+	s.Take("idle", 60, 1)
+	clock.advance(time.Minute)
+	for i := 0; i < sweepEvery; i++ {
+		s.Take("busy", 600000, 1)
+	}
+	if _, ok := s.buckets["idle"]; ok {
+		t.Fatal("idle bucket not swept")
+	}
+}
+
+// newLimitedEngine serves /run behind RateLimit. When hold is non-nil the
+// handler claims the run slot and hands its release to hold.
+func newLimitedEngine(cfg *RateLimitConfig, store RateLimitStore, hold chan<- func()) *gin.Engine {
+	g := gin.New()
+	g.POST("/run", RateLimit(cfg, store), func(c *gin.Context) {
+		if hold != nil {
+			if release := TakeRunRelease(c); release != nil {
+				hold <- release
+			}
+		}
+		c.Status(http.StatusOK)
+	})
+	return g
+}
+
+func doRun(g *gin.Engine, sessionKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/run", nil)
+	if sessionKey != "" {
+		req.Header.Set("X-Session-Key", sessionKey)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitRequestsPerMinute(t *testing.T) {
+	store, _ := newTestStore()
+	g := newLimitedEngine(&RateLimitConfig{Enabled: true, RequestsPerMinute: 60, Burst: 2}, store, nil)
+
+	for i := 0; i < 2; i++ {
+		if w := doRun(g, "a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := doRun(g, "a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("Retry-After = %q, want \"1\"", got)
+	}
+	if w := doRun(g, "b"); w.Code != http.StatusOK {
+		t.Fatalf("other session: status %d", w.Code)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	store, _ := newTestStore()
+	g := newLimitedEngine(&RateLimitConfig{Enabled: false, RequestsPerMinute: 1, Burst: 1}, store, nil)
+	for i := 0; i < 5; i++ {
+		if w := doRun(g, "a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d with limiting disabled", i, w.Code)
+		}
+	}
+}
+
+func TestRateLimitConcurrentRuns(t *testing.T) {
+	store, _ := newTestStore()
+	hold := make(chan func(), 4)
+	g := newLimitedEngine(&RateLimitConfig{Enabled: true, MaxConcurrentRuns: 1}, store, hold)
+
+	// The handler keeps the slot past its return, as a streamed run does.
+	if w := doRun(g, "a"); w.Code != http.StatusOK {
+		t.Fatalf("first run: status %d", w.Code)
+	}
+	release := <-hold
+	if w := doRun(g, "a"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second run while first in flight: status %d, want 429", w.Code)
+	}
+
+	release()
+	release() // releasing twice must not free a second slot
+	if w := doRun(g, "a"); w.Code != http.StatusOK {
+		t.Fatalf("run after release: status %d", w.Code)
+	}
+	(<-hold)()
+	if n := len(store.runs); n != 0 {
+		t.Fatalf("%d keys still hold slots", n)
+	}
+}
+
+func TestRateLimitReleasesUnclaimedSlot(t *testing.T) {
+	store, _ := newTestStore()
+	g := newLimitedEngine(&RateLimitConfig{Enabled: true, MaxConcurrentRuns: 1}, store, nil)
+	for i := 0; i < 3; i++ {
+		if w := doRun(g, "a"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d; slot not released when the handler returned", i, w.Code)
+		}
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"session key wins", map[string]string{"X-Session-Key": "s1", "Authorization": "Bearer tok"}, "session:s1"},
+		{"hashed bearer token", map[string]string{"Authorization": "Bearer tok"}, "token:"},
+		{"client ip", nil, "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/run", nil)
+			c.Request.RemoteAddr = "192.0.2.1:1234"
+			for k, v := range tt.headers {
+				c.Request.Header.Set(k, v)
+			}
+			got := rateLimitKey(c)
+			if len(got) < len(tt.want) || got[:len(tt.want)] != tt.want {
+				t.Fatalf("key = %q, want prefix %q", got, tt.want)
+			}
+			if tt.want == "token:" && got == "token:tok" {
+				t.Fatal("raw token kept in key")
+			}
+		})
+	}
+}
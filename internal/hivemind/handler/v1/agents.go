@@ -1,11 +1,15 @@
 package v1
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	"github.com/kiosk404/echoryn/internal/pkg/core"
 	"github.com/kiosk404/echoryn/pkg/errorx"
@@ -29,17 +33,39 @@ func (h *AgentHandler) Create(c *gin.Context) {
 		return
 	}
 
+	toolMode := entity.ToolMode(req.ToolMode)
+	if !toolMode.IsValid() {
+		core.WriteResponse(c, errorx.WithCode(ErrValidation, "invalid tool_mode %q: must be one of all, allowlist, denylist", req.ToolMode), nil)
+		return
+	}
+	maxTurnsBehavior := entity.MaxTurnsBehavior(req.MaxTurnsBehavior)
+	if !maxTurnsBehavior.IsValid() {
+		core.WriteResponse(c, errorx.WithCode(ErrValidation, "invalid max_turns_behavior %q: must be one of hard_stop, force_final_answer", req.MaxTurnsBehavior), nil)
+		return
+	}
+	toolBudget := toToolBudgetEntity(req.ToolBudget)
+	if err := toolBudget.Validate(); err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrValidation, "invalid tool_budget"), nil)
+		return
+	}
+
 	agent := &entity.Agent{
-		ID:           req.ID,
-		Name:         req.Name,
-		Description:  req.Description,
-		SystemPrompt: req.SystemPrompt,
-		Tools:        req.Tools,
-		MaxTurns:     req.MaxTurns,
-		Temperature:  req.Temperature,
-		MaxTokens:    req.MaxTokens,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:               req.ID,
+		Name:             req.Name,
+		Description:      req.Description,
+		SystemPrompt:     req.SystemPrompt,
+		ToolMode:         toolMode,
+		Tools:            req.Tools,
+		DeniedTools:      req.DeniedTools,
+		MaxTurns:         req.MaxTurns,
+		MaxTurnsBehavior: maxTurnsBehavior,
+		ToolBudget:       toolBudget,
+		Temperature:      req.Temperature,
+		MaxTokens:        req.MaxTokens,
+		ReserveTokens:    req.ReserveTokens,
+		Memory:           req.Memory,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
 	}
 	if req.ModelRef != nil {
 		agent.ModelRef = llmEntity.ModelRef{
@@ -49,6 +75,10 @@ func (h *AgentHandler) Create(c *gin.Context) {
 	}
 
 	if err := h.svc.CreateAgent(c.Request.Context(), agent); err != nil {
+		if errors.Is(err, errno.ErrModelNotToolCapable) || errors.Is(err, errno.ErrModelNotVisionCapable) || errors.Is(err, errno.ErrModelNotFound) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrAgentModel, "create agent"), nil)
+			return
+		}
 		core.WriteResponse(c, errorx.WrapC(err, ErrAgentCreate, "create agent"), nil)
 		return
 	}
@@ -82,6 +112,111 @@ func (h *AgentHandler) Get(c *gin.Context) {
 	core.WriteResponse(c, nil, toAgentResponse(agent))
 }
 
+// Update handles PATCH /v1/agents/:id.
+// Only the fields present in the request body are changed.
+func (h *AgentHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+	var req UpdateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrBind, "bind agent request"), nil)
+		return
+	}
+
+	current, err := h.svc.GetAgent(c.Request.Context(), id)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", id), nil)
+		return
+	}
+
+	// Patch a copy so a rejected update never touches the stored agent.
+	agent := *current
+	if req.Name != nil {
+		agent.Name = *req.Name
+	}
+	if req.Description != nil {
+		agent.Description = *req.Description
+	}
+	if req.SystemPrompt != nil {
+		agent.SystemPrompt = *req.SystemPrompt
+	}
+	if req.ModelRef != nil {
+		agent.ModelRef = llmEntity.ModelRef{
+			ProviderID: req.ModelRef.ProviderID,
+			ModelID:    req.ModelRef.ModelID,
+		}
+		// The fallback chain starts at the agent's model.
+		agent.Fallback.Primary = agent.ModelRef
+	}
+	if req.ToolMode != nil {
+		toolMode := entity.ToolMode(*req.ToolMode)
+		if !toolMode.IsValid() {
+			core.WriteResponse(c, errorx.WithCode(ErrValidation, "invalid tool_mode %q: must be one of all, allowlist, denylist", *req.ToolMode), nil)
+			return
+		}
+		agent.ToolMode = toolMode
+	}
+	if req.Tools != nil {
+		agent.Tools = *req.Tools
+	}
+	if req.DeniedTools != nil {
+		agent.DeniedTools = *req.DeniedTools
+	}
+	if req.MaxTurns != nil {
+		agent.MaxTurns = *req.MaxTurns
+	}
+	if req.MaxTurnsBehavior != nil {
+		maxTurnsBehavior := entity.MaxTurnsBehavior(*req.MaxTurnsBehavior)
+		if !maxTurnsBehavior.IsValid() {
+			core.WriteResponse(c, errorx.WithCode(ErrValidation, "invalid max_turns_behavior %q: must be one of hard_stop, force_final_answer", *req.MaxTurnsBehavior), nil)
+			return
+		}
+		agent.MaxTurnsBehavior = maxTurnsBehavior
+	}
+	if req.ToolBudget != nil {
+		toolBudget := toToolBudgetEntity(req.ToolBudget)
+		if err := toolBudget.Validate(); err != nil {
+			core.WriteResponse(c, errorx.WrapC(err, ErrValidation, "invalid tool_budget"), nil)
+			return
+		}
+		agent.ToolBudget = toolBudget
+	}
+	if req.Temperature != nil {
+		agent.Temperature = req.Temperature
+	}
+	if req.MaxTokens != nil {
+		agent.MaxTokens = req.MaxTokens
+	}
+	if req.ReserveTokens != nil {
+		agent.ReserveTokens = req.ReserveTokens
+	}
+	if req.Memory != nil {
+		agent.Memory = req.Memory
+	}
+	if req.Persona != nil {
+		persona := toAgentPersonaEntity(req.Persona)
+		if err := validatePersona(persona); err != nil {
+			core.WriteResponse(c, errorx.WrapC(err, ErrValidation, "invalid persona"), nil)
+			return
+		}
+		agent.Persona = persona
+	}
+
+	if err := h.svc.UpdateAgent(c.Request.Context(), &agent); err != nil {
+		if errors.Is(err, errno.ErrModelNotToolCapable) || errors.Is(err, errno.ErrModelNotVisionCapable) || errors.Is(err, errno.ErrModelNotFound) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrAgentModel, "update agent %q", id), nil)
+			return
+		}
+		if errors.Is(err, errno.ErrAgentNotFound) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", id), nil)
+			return
+		}
+		core.WriteResponse(c, errorx.WrapC(err, ErrAgentUpdate, "update agent %q", id), nil)
+		return
+	}
+
+	core.WriteResponse(c, nil, toAgentResponse(&agent))
+}
+
 // Delete handles DELETE /v1/agents/:id.
 func (h *AgentHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
@@ -92,15 +227,158 @@ func (h *AgentHandler) Delete(c *gin.Context) {
 	core.WriteResponse(c, nil, gin.H{"id": id, "deleted": true})
 }
 
+// PromptPreview handles POST /v1/agents/:id/prompt-preview.
+// It renders the system prompt the agent would receive for a new session
+// without starting a run.
+func (h *AgentHandler) PromptPreview(c *gin.Context) {
+	id := c.Param("id")
+	agent, err := h.svc.GetAgent(c.Request.Context(), id)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", id), nil)
+		return
+	}
+
+	report, err := h.svc.PreviewPrompt(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, errno.ErrAgentNotFound) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", id), nil)
+			return
+		}
+		core.WriteResponse(c, errorx.WrapC(err, ErrPromptPreview, "preview prompt for agent %q", id), nil)
+		return
+	}
+
+	resp := PromptPreviewResponse{
+		AgentID:  id,
+		Mode:     agent.EffectivePromptMode(),
+		Prompt:   report.Text,
+		Length:   len(report.Text),
+		Sections: make([]PromptSectionPreview, 0, len(report.Sections)),
+	}
+	for _, s := range report.Sections {
+		resp.Sections = append(resp.Sections, PromptSectionPreview{
+			Name:       s.Name,
+			Priority:   s.Priority,
+			Enabled:    s.Enabled,
+			Included:   s.Included,
+			SkipReason: string(s.SkipReason),
+			Length:     s.Length,
+			Error:      s.Error,
+		})
+	}
+	core.WriteResponse(c, nil, resp)
+}
+
+// Tools handles GET /v1/agents/:id/tools.
+// It lists the tools the agent would receive at run time, after all
+// tool and MCP filtering, without starting a run.
+func (h *AgentHandler) Tools(c *gin.Context) {
+	id := c.Param("id")
+	tools, err := h.svc.EffectiveTools(c.Request.Context(), id)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", id), nil)
+		return
+	}
+
+	resp := AgentToolsResponse{
+		AgentID: id,
+		Tools:   make([]AgentToolInfo, 0, len(tools)),
+	}
+	for _, t := range tools {
+		resp.Tools = append(resp.Tools, AgentToolInfo{
+			Name:        t.Name,
+			Description: t.Description,
+			Source:      t.Source,
+			Server:      t.Server,
+			MCPName:     t.DisplayName,
+		})
+	}
+	core.WriteResponse(c, nil, resp)
+}
+
+// validatePersona checks the enumerated and parsed fields of a persona.
+func validatePersona(p *entity.AgentPersona) error {
+	switch p.PromptMode {
+	case "", "full", "minimal", "none":
+	default:
+		return fmt.Errorf("prompt_mode %q must be one of full, minimal, none", p.PromptMode)
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("timezone %q: %w", p.Timezone, err)
+		}
+	}
+	return nil
+}
+
 func toAgentResponse(a *entity.Agent) AgentResponse {
 	return AgentResponse{
-		ID:           a.ID,
-		Name:         a.Name,
-		Description:  a.Description,
-		SystemPrompt: a.SystemPrompt,
-		Tools:        a.Tools,
-		MaxTurns:     a.MaxTurns,
-		CreatedAt:    FormatTime(a.CreatedAt),
-		UpdatedAt:    FormatTime(a.UpdatedAt),
+		ID:               a.ID,
+		Name:             a.Name,
+		Description:      a.Description,
+		SystemPrompt:     a.SystemPrompt,
+		ToolMode:         string(a.ToolMode),
+		Tools:            a.Tools,
+		DeniedTools:      a.DeniedTools,
+		MaxTurns:         a.MaxTurns,
+		MaxTurnsBehavior: string(a.MaxTurnsBehavior),
+		ReserveTokens:    a.ReserveTokens,
+		Memory:           a.MemoryEnabled(),
+		ToolBudget:       toToolBudget(a.ToolBudget),
+		Persona:          toAgentPersona(a.Persona),
+		CreatedAt:        FormatTime(a.CreatedAt),
+		UpdatedAt:        FormatTime(a.UpdatedAt),
+	}
+}
+
+// toToolBudgetEntity converts an API tool budget. A budget without limits
+// converts to nil, i.e. unlimited.
+func toToolBudgetEntity(b *ToolBudget) *entity.ToolBudget {
+	if b == nil || (b.MaxCalls == 0 && len(b.PerTool) == 0) {
+		return nil
+	}
+	return &entity.ToolBudget{MaxCalls: b.MaxCalls, PerTool: maps.Clone(b.PerTool)}
+}
+
+func toToolBudget(b *entity.ToolBudget) *ToolBudget {
+	if b == nil {
+		return nil
+	}
+	return &ToolBudget{MaxCalls: b.MaxCalls, PerTool: maps.Clone(b.PerTool)}
+}
+
+func toAgentPersonaEntity(p *AgentPersona) *entity.AgentPersona {
+	if p == nil {
+		return nil
+	}
+	persona := &entity.AgentPersona{
+		PromptMode:    p.PromptMode,
+		WorkspaceDir:  p.WorkspaceDir,
+		Timezone:      p.Timezone,
+		ExtraSections: maps.Clone(p.ExtraSections),
+	}
+	if id := p.Identity; id != nil {
+		persona.Identity = &entity.AgentIdentity{
+			Name: id.Name, Emoji: id.Emoji, Creature: id.Creature, Vibe: id.Vibe, Theme: id.Theme,
+		}
+	}
+	return persona
+}
+
+func toAgentPersona(p *entity.AgentPersona) *AgentPersona {
+	if p == nil {
+		return nil
+	}
+	persona := &AgentPersona{
+		PromptMode:    p.PromptMode,
+		WorkspaceDir:  p.WorkspaceDir,
+		Timezone:      p.Timezone,
+		ExtraSections: maps.Clone(p.ExtraSections),
+	}
+	if id := p.Identity; id != nil {
+		persona.Identity = &AgentIdentity{
+			Name: id.Name, Emoji: id.Emoji, Creature: id.Creature, Vibe: id.Vibe, Theme: id.Theme,
+		}
 	}
+	return persona
 }
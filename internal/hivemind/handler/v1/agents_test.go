@@ -0,0 +1,426 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+// memAgentService keeps agents in a map.
+type memAgentService struct {
+	service.AgentService
+	agents    map[string]*entity.Agent
+	updateErr error                  // returned by UpdateAgent when set
+	tools     []prompt.ToolSummary   // returned by EffectiveTools for known agents
+	preview   *prompt.AssembleReport // returned by PreviewPrompt for known agents
+}
+
+func newMemAgentService(agents ...*entity.Agent) *memAgentService {
+	s := &memAgentService{agents: make(map[string]*entity.Agent)}
+	for _, a := range agents {
+		s.agents[a.ID] = a
+	}
+	return s
+}
+
+func (s *memAgentService) CreateAgent(_ context.Context, a *entity.Agent) error {
+	s.agents[a.ID] = a
+	return nil
+}
+
+func (s *memAgentService) GetAgent(_ context.Context, id string) (*entity.Agent, error) {
+	a, ok := s.agents[id]
+	if !ok {
+		return nil, errno.ErrAgentNotFound
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (s *memAgentService) UpdateAgent(_ context.Context, a *entity.Agent) error {
+	if s.updateErr != nil {
+		return s.updateErr
+	}
+	if _, ok := s.agents[a.ID]; !ok {
+		return errno.ErrAgentNotFound
+	}
+	s.agents[a.ID] = a
+	return nil
+}
+
+func (s *memAgentService) EffectiveTools(_ context.Context, id string) ([]prompt.ToolSummary, error) {
+	if _, ok := s.agents[id]; !ok {
+		return nil, errno.ErrAgentNotFound
+	}
+	return s.tools, nil
+}
+
+func (s *memAgentService) PreviewPrompt(_ context.Context, id string) (*prompt.AssembleReport, error) {
+	if _, ok := s.agents[id]; !ok {
+		return nil, errno.ErrAgentNotFound
+	}
+	return s.preview, nil
+}
+
+// serveAgents sends one request to the agent routes backed by svc.
+func serveAgents(t *testing.T, svc service.AgentService, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := NewAgentHandler(svc)
+	r := gin.New()
+	r.POST("/v1/agents", h.Create)
+	r.PATCH("/v1/agents/:id", h.Update)
+	r.GET("/v1/agents/:id/tools", h.Tools)
+	r.POST("/v1/agents/:id/prompt-preview", h.PromptPreview)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAgentPatch(t *testing.T) {
+	temp := 0.3
+	existing := func() *entity.Agent {
+		return &entity.Agent{
+			ID: "a", Name: "A", Description: "old", SystemPrompt: "Be kind.",
+			ModelRef:    llmEntity.ModelRef{ProviderID: "p", ModelID: "m"},
+			ToolMode:    entity.ToolModeAllowlist,
+			Tools:       []string{"web_search"},
+			MaxTurns:    5,
+			Temperature: &temp,
+		}
+	}
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		updateErr error
+		wantCode  int
+		check     func(a *entity.Agent) bool
+	}{
+		{"only given fields change", "/v1/agents/a", `{"description":"new","max_turns":8}`, nil, http.StatusOK,
+			func(a *entity.Agent) bool {
+				return a.Description == "new" && a.MaxTurns == 8 && a.Name == "A" && a.SystemPrompt == "Be kind." &&
+					a.ModelRef.ModelID == "m" && slices.Equal(a.Tools, []string{"web_search"}) && a.Temperature != nil && *a.Temperature == 0.3
+			}},
+		{"empty list clears tools", "/v1/agents/a", `{"tools":[]}`, nil, http.StatusOK,
+			func(a *entity.Agent) bool { return a.Tools != nil && len(a.Tools) == 0 }},
+		{"model ref", "/v1/agents/a", `{"model_ref":{"provider_id":"q","model_id":"n"}}`, nil, http.StatusOK,
+			func(a *entity.Agent) bool {
+				want := llmEntity.ModelRef{ProviderID: "q", ModelID: "n"}
+				return a.ModelRef == want && a.Fallback.Primary == want
+			}},
+		{"invalid tool mode", "/v1/agents/a", `{"tool_mode":"some"}`, nil, http.StatusBadRequest, nil},
+		{"malformed body", "/v1/agents/a", `{"name":1}`, nil, http.StatusBadRequest, nil},
+		{"unknown agent", "/v1/agents/b", `{"name":"B"}`, nil, http.StatusNotFound, nil},
+		{"model not tool capable", "/v1/agents/a", `{"name":"B"}`, errno.ErrModelNotToolCapable, http.StatusBadRequest, nil},
+		{"store failure", "/v1/agents/a", `{"name":"B"}`, errors.New("disk full"), http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newMemAgentService(existing())
+			svc.updateErr = tt.updateErr
+			w := serveAgents(t, svc, http.MethodPatch, tt.path, tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			stored := svc.agents["a"]
+			if tt.check == nil {
+				if mustJSON(t, stored) != mustJSON(t, existing()) {
+					t.Fatalf("rejected patch changed the agent: %s", mustJSON(t, stored))
+				}
+				return
+			}
+			if !tt.check(stored) {
+				t.Fatalf("stored agent = %s", mustJSON(t, stored))
+			}
+		})
+	}
+}
+
+func TestAgentToolBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *entity.ToolBudget
+		method   string
+		body     string
+		wantCode int
+		want     *entity.ToolBudget
+	}{
+		{"create", nil, http.MethodPost,
+			`{"id":"a","name":"A","tool_budget":{"max_calls":5,"per_tool":{"web_search":2}}}`,
+			http.StatusOK, &entity.ToolBudget{MaxCalls: 5, PerTool: map[string]int{"web_search": 2}}},
+		{"create without limits", nil, http.MethodPost,
+			`{"id":"a","name":"A","tool_budget":{}}`, http.StatusOK, nil},
+		{"create negative", nil, http.MethodPost,
+			`{"id":"a","name":"A","tool_budget":{"max_calls":-1}}`, http.StatusBadRequest, nil},
+		{"patch replaces", &entity.ToolBudget{MaxCalls: 5}, http.MethodPatch,
+			`{"tool_budget":{"per_tool":{"shell":1}}}`,
+			http.StatusOK, &entity.ToolBudget{PerTool: map[string]int{"shell": 1}}},
+		{"patch null keeps", &entity.ToolBudget{MaxCalls: 5}, http.MethodPatch,
+			`{"name":"B"}`, http.StatusOK, &entity.ToolBudget{MaxCalls: 5}},
+		{"patch empty clears", &entity.ToolBudget{MaxCalls: 5}, http.MethodPatch,
+			`{"tool_budget":{}}`, http.StatusOK, nil},
+		{"patch negative per tool", &entity.ToolBudget{MaxCalls: 5}, http.MethodPatch,
+			`{"tool_budget":{"per_tool":{"shell":-2}}}`, http.StatusBadRequest, &entity.ToolBudget{MaxCalls: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newMemAgentService()
+			path := "/v1/agents"
+			if tt.method == http.MethodPatch {
+				svc = newMemAgentService(&entity.Agent{ID: "a", Name: "A", ToolBudget: tt.existing})
+				path = "/v1/agents/a"
+			}
+			w := serveAgents(t, svc, tt.method, path, tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+
+			var stored *entity.ToolBudget
+			if a, ok := svc.agents["a"]; ok {
+				stored = a.ToolBudget
+			}
+			if !sameToolBudget(stored, tt.want) {
+				t.Fatalf("stored budget = %+v, want %+v", stored, tt.want)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp AgentResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if got := toToolBudgetEntity(resp.ToolBudget); !sameToolBudget(got, tt.want) {
+				t.Fatalf("response budget = %+v, want %+v", resp.ToolBudget, tt.want)
+			}
+		})
+	}
+}
+
+func sameToolBudget(a, b *entity.ToolBudget) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.MaxCalls != b.MaxCalls || len(a.PerTool) != len(b.PerTool) {
+		return false
+	}
+	for k, v := range a.PerTool {
+		if b.PerTool[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestToolBudgetConversionCopies(t *testing.T) {
+	in := &ToolBudget{MaxCalls: 3, PerTool: map[string]int{"shell": 1}}
+	e := toToolBudgetEntity(in)
+	in.PerTool["shell"] = 9
+	if e.PerTool["shell"] != 1 {
+		t.Fatal("entity budget shares the request map")
+	}
+	out := toToolBudget(e)
+	out.PerTool["shell"] = 7
+	if e.PerTool["shell"] != 1 {
+		t.Fatal("response budget shares the entity map")
+	}
+	if toToolBudget(nil) != nil || toToolBudgetEntity(nil) != nil {
+		t.Fatal("nil budget not kept nil")
+	}
+}
+
+func TestAgentPersonaPatch(t *testing.T) {
+	existing := &entity.AgentPersona{PromptMode: "minimal", Identity: &entity.AgentIdentity{Name: "Old"}}
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		want     *entity.AgentPersona
+	}{
+		{"replace", `{"persona":{"identity":{"name":"Aria","emoji":"🦊"},"prompt_mode":"full","timezone":"Europe/Berlin","extra_sections":{"Rules":"Be brief."}}}`,
+			http.StatusOK, &entity.AgentPersona{
+				Identity:      &entity.AgentIdentity{Name: "Aria", Emoji: "🦊"},
+				PromptMode:    "full",
+				Timezone:      "Europe/Berlin",
+				ExtraSections: map[string]string{"Rules": "Be brief."},
+			}},
+		{"null keeps", `{"name":"B"}`, http.StatusOK, existing},
+		{"bad prompt mode", `{"persona":{"prompt_mode":"verbose"}}`, http.StatusBadRequest, existing},
+		{"bad timezone", `{"persona":{"timezone":"Mars/Olympus"}}`, http.StatusBadRequest, existing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newMemAgentService(&entity.Agent{ID: "a", Name: "A", Persona: existing})
+			w := serveAgents(t, svc, http.MethodPatch, "/v1/agents/a", tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			got := svc.agents["a"].Persona
+			if gotJSON, wantJSON := mustJSON(t, got), mustJSON(t, tt.want); gotJSON != wantJSON {
+				t.Fatalf("stored persona = %s, want %s", gotJSON, wantJSON)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp AgentResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if gotJSON, wantJSON := mustJSON(t, toAgentPersonaEntity(resp.Persona)), mustJSON(t, tt.want); gotJSON != wantJSON {
+				t.Fatalf("response persona = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestAgentReserveTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   *int
+	}{
+		{"create", http.MethodPost, "/v1/agents", `{"id":"a","name":"A","reserve_tokens":2048}`, intPtr(2048)},
+		{"create without", http.MethodPost, "/v1/agents", `{"id":"a","name":"A"}`, nil},
+		{"patch", http.MethodPatch, "/v1/agents/a", `{"reserve_tokens":0}`, intPtr(0)},
+		{"patch other field keeps", http.MethodPatch, "/v1/agents/a", `{"name":"B"}`, intPtr(512)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newMemAgentService()
+			if tt.method == http.MethodPatch {
+				svc = newMemAgentService(&entity.Agent{ID: "a", Name: "A", ReserveTokens: intPtr(512)})
+			}
+			w := serveAgents(t, svc, tt.method, tt.path, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+			}
+			var resp AgentResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range []*int{svc.agents["a"].ReserveTokens, resp.ReserveTokens} {
+				if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+					t.Fatalf("reserve tokens = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestAgentCreateDeniedTools(t *testing.T) {
+	svc := newMemAgentService()
+	w := serveAgents(t, svc, http.MethodPost, "/v1/agents", `{"id":"a","name":"A","tool_mode":"allowlist","tools":["web_search"],"denied_tools":["web_fetch","github__issues"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d: %s", w.Code, w.Body.String())
+	}
+	var resp AgentResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"web_fetch", "github__issues"}
+	if got := svc.agents["a"].DeniedTools; !slices.Equal(got, want) {
+		t.Fatalf("stored denied tools = %q, want %q", got, want)
+	}
+	if !slices.Equal(resp.DeniedTools, want) || resp.ToolMode != "allowlist" {
+		t.Fatalf("response = %+v, want allowlist mode and denied tools %q", resp, want)
+	}
+}
+
+func TestAgentTools(t *testing.T) {
+	svc := newMemAgentService(&entity.Agent{ID: "a", Name: "A"})
+	svc.tools = []prompt.ToolSummary{
+		{Name: "web_search", Description: "Search the web.", Source: "plugin"},
+		{Name: "github__search", Description: "Search issues.", Source: "mcp", Server: "github", DisplayName: "search"},
+	}
+
+	w := serveAgents(t, svc, http.MethodGet, "/v1/agents/a/tools", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp AgentToolsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []AgentToolInfo{
+		{Name: "web_search", Description: "Search the web.", Source: "plugin"},
+		{Name: "github__search", Description: "Search issues.", Source: "mcp", Server: "github", MCPName: "search"},
+	}
+	if resp.AgentID != "a" || !slices.Equal(resp.Tools, want) {
+		t.Fatalf("response = %+v, want agent a with tools %+v", resp, want)
+	}
+	if body := w.Body.String(); strings.Contains(body, `"server":""`) || strings.Contains(body, `"mcp_name":""`) {
+		t.Fatalf("body = %s, want empty MCP fields omitted for the plugin tool", body)
+	}
+
+	// An agent without tools lists an empty array rather than null.
+	svc.tools = nil
+	w = serveAgents(t, svc, http.MethodGet, "/v1/agents/a/tools", "")
+	if !strings.Contains(w.Body.String(), `"tools":[]`) {
+		t.Fatalf("body = %s, want an empty tools array", w.Body.String())
+	}
+
+	if w := serveAgents(t, svc, http.MethodGet, "/v1/agents/b/tools", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown agent: code = %d, want 404: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAgentPromptPreview(t *testing.T) {
+	svc := newMemAgentService(&entity.Agent{ID: "a", Name: "A", Persona: &entity.AgentPersona{PromptMode: "minimal"}})
+	svc.preview = &prompt.AssembleReport{
+		Text: "You are A.",
+		Sections: []prompt.SectionReport{
+			{Name: "identity", Priority: 100, Enabled: true, Included: true, Length: 10},
+			{Name: "cluster", Priority: 150, SkipReason: prompt.SkipReasonPromptMode},
+			{Name: "skills", Priority: 300, Enabled: true, SkipReason: prompt.SkipReasonRenderError, Error: "no skills dir"},
+		},
+	}
+
+	w := serveAgents(t, svc, http.MethodPost, "/v1/agents/a/prompt-preview", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp PromptPreviewResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []PromptSectionPreview{
+		{Name: "identity", Priority: 100, Enabled: true, Included: true, Length: 10},
+		{Name: "cluster", Priority: 150, SkipReason: "prompt_mode"},
+		{Name: "skills", Priority: 300, Enabled: true, SkipReason: "render_error", Error: "no skills dir"},
+	}
+	if resp.AgentID != "a" || resp.Mode != "minimal" || resp.Prompt != "You are A." || resp.Length != 10 ||
+		!slices.Equal(resp.Sections, want) {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	if w := serveAgents(t, svc, http.MethodPost, "/v1/agents/b/prompt-preview", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown agent: code = %d, want 404: %s", w.Code, w.Body.String())
+	}
+}
@@ -1,17 +1,24 @@
 package v1
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/kiosk404/echoryn/internal/hivemind/handler/middleware"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
 	"github.com/kiosk404/echoryn/internal/pkg/core"
@@ -20,6 +27,66 @@ import (
 	"github.com/kiosk404/echoryn/pkg/utils/json"
 )
 
+// DefaultStreamKeepaliveInterval is how long the SSE stream may stay idle before
+// a keepalive comment is written, so proxies don't drop the connection while a
+// long tool call is running.
+const DefaultStreamKeepaliveInterval = 15 * time.Second
+
+// DefaultMaxChoices caps the "n" of a chat completion request: each choice
+// is a full agent turn with its own tool calls, so n multiplies the cost and
+// the side effects of a request.
+const DefaultMaxChoices = 4
+
+// headerIncludeToolResults opts in to tool results in chat completion responses.
+const headerIncludeToolResults = "X-Include-Tool-Results"
+
+// headerIncludeStatus opts in to status deltas (e.g. compaction progress) in
+// streamed responses. Without it, status is only sent as SSE comments.
+const headerIncludeStatus = "X-Include-Status"
+
+// headerMaxHistoryTurns overrides how many recent user turns of session
+// history the run includes (positive integer), e.g. for a one-off query
+// that needs more context than the configured default.
+const headerMaxHistoryTurns = "X-Max-History-Turns"
+
+// Headers overriding AgentDefaults for an agent auto-created by this request.
+const (
+	headerAgentTools    = "X-Agent-Tools"     // comma-separated tool allowlist
+	headerAgentMemory   = "X-Agent-Memory"    // "true" or "false"
+	headerAgentMaxTurns = "X-Agent-Max-Turns" // positive integer
+)
+
+// AgentDefaults configures agents auto-created by /v1/chat/completions.
+type AgentDefaults struct {
+	// Memory enables the memory plugin for the agent. nil means enabled.
+	Memory *bool `json:"memory,omitempty"`
+	// Tools is the tool allowlist. Empty exposes all tools.
+	Tools []string `json:"tools,omitempty"`
+	// MaxTurns caps tool-call turns per run. 0 uses the module default.
+	MaxTurns int `json:"max_turns,omitempty"`
+	// SystemPrompt is the agent's system prompt when the creating request
+	// has no system messages.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// Identity is the persona identity (name, emoji, vibe, ...).
+	Identity *entity.AgentIdentity `json:"identity,omitempty"`
+	// WorkspaceDir is the persona workspace (SOUL.md, IDENTITY.md, AGENTS.md).
+	WorkspaceDir string `json:"workspace_dir,omitempty"`
+}
+
+// persona returns the AgentPersona of an auto-created agent, or nil when no
+// persona default is configured.
+func (d AgentDefaults) persona() *entity.AgentPersona {
+	if d.Identity == nil && d.WorkspaceDir == "" {
+		return nil
+	}
+	p := &entity.AgentPersona{WorkspaceDir: d.WorkspaceDir}
+	if d.Identity != nil {
+		identity := *d.Identity
+		p.Identity = &identity
+	}
+	return p
+}
+
 // ChatCompletionsHandler handles POST /v1/chat/completions (OpenAI-compatible).
 //
 // Modeled after OpenClaw's openai-http.ts:
@@ -32,6 +99,18 @@ type ChatCompletionsHandler struct {
 	llmManager     llmService.ModelManager
 	defaultAgentID string
 	defaultModel   string
+
+	// keepaliveInterval is the SSE idle time before a ping comment; <= 0 disables pings.
+	keepaliveInterval time.Duration
+
+	// agentDefaults configures auto-created agents.
+	agentDefaults AgentDefaults
+
+	// idempotency caches results by Idempotency-Key; nil disables the header.
+	idempotency *idempotencyStore
+
+	// maxChoices is the largest "n" a request may ask for.
+	maxChoices int
 }
 
 // NewChatCompletionsHandler creates a new ChatCompletionsHandler.
@@ -43,13 +122,47 @@ func NewChatCompletionsHandler(svc service.AgentService, llmManager llmService.M
 		defaultModel = "eidolon"
 	}
 	return &ChatCompletionsHandler{
-		svc:            svc,
-		llmManager:     llmManager,
-		defaultAgentID: defaultAgentID,
-		defaultModel:   defaultModel,
+		svc:               svc,
+		llmManager:        llmManager,
+		defaultAgentID:    defaultAgentID,
+		defaultModel:      defaultModel,
+		keepaliveInterval: DefaultStreamKeepaliveInterval,
+		idempotency:       newIdempotencyStore(DefaultIdempotencyTTL),
+		maxChoices:        DefaultMaxChoices,
 	}
 }
 
+// SetAgentDefaults sets the configuration of agents auto-created by the
+// endpoint. Request headers may override it per agent.
+func (h *ChatCompletionsHandler) SetAgentDefaults(d AgentDefaults) {
+	h.agentDefaults = d
+}
+
+// SetKeepaliveInterval sets how long a stream may stay idle before a ping
+// comment is written. A value <= 0 disables keepalive pings.
+func (h *ChatCompletionsHandler) SetKeepaliveInterval(d time.Duration) {
+	h.keepaliveInterval = d
+}
+
+// SetMaxChoices sets the largest "n" a request may ask for.
+// A value <= 0 restores DefaultMaxChoices.
+func (h *ChatCompletionsHandler) SetMaxChoices(n int) {
+	if n <= 0 {
+		n = DefaultMaxChoices
+	}
+	h.maxChoices = n
+}
+
+// SetIdempotencyTTL sets how long results are kept for Idempotency-Key
+// retries. A value <= 0 disables the header.
+func (h *ChatCompletionsHandler) SetIdempotencyTTL(d time.Duration) {
+	if d <= 0 {
+		h.idempotency = nil
+		return
+	}
+	h.idempotency = newIdempotencyStore(d)
+}
+
 // Handle is the main entry point for POST /v1/chat/completions.
 func (h *ChatCompletionsHandler) Handle(c *gin.Context) {
 	var req ChatCompletionRequest
@@ -69,29 +182,113 @@ func (h *ChatCompletionsHandler) Handle(c *gin.Context) {
 	// Resolve session key (OpenClaw: resolveSessionKey).
 	sessionID := h.resolveSessionID(c, req.User, agentID)
 
+	// A retry carrying the Idempotency-Key of an earlier request gets that
+	// request's result; the agent must not act twice.
+	var idem *idempotencyReservation
+	if key := c.GetHeader(headerIdempotencyKey); key != "" && h.idempotency != nil {
+		reservation, cached, err := h.idempotency.begin(c.Request.Context(), agentID+"\x00"+sessionID+"\x00"+key, requestFingerprint(&req))
+		if errors.Is(err, errIdempotencyMismatch) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrIdempotencyMismatch, "idempotency key %q", key), nil)
+			return
+		}
+		if err != nil {
+			core.WriteResponse(c, errorx.WrapC(err, ErrAgentRun, "wait for request with idempotency key %q", key), nil)
+			return
+		}
+		if cached != nil {
+			h.replayCompletion(c, cached, req.Stream, req.StreamOptions != nil && req.StreamOptions.IncludeUsage)
+			return
+		}
+		idem = reservation
+		defer idem.abandon()
+	}
+
 	// Extract the last user message as input; merge system messages as extra prompt.
-	userInput, extraSystem := extractUserInput(req.Messages)
-	if userInput == "" {
+	userInput, images, extraSystem := extractUserInput(req.Messages)
+	if userInput == "" && len(images) == 0 {
 		core.WriteResponse(c, errorx.WithCode(ErrNoUserMessage, "no user message found in messages array"), nil)
 		return
 	}
 
+	defaults, err := h.resolveAgentDefaults(c)
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+
 	// Ensure agent exists; auto-create a default one if it doesn't.
-	if err := h.ensureAgent(c, agentID, extraSystem); err != nil {
+	if err := h.ensureAgent(c, agentID, extraSystem, defaults); err != nil {
 		core.WriteResponse(c, errorx.WrapC(err, ErrEnsureAgent, "ensure agent %q", agentID), nil)
 		return
 	}
 
+	// Map response_format and verify the agent's model can honor it.
+	overrides, err := h.resolveResponseFormat(c, agentID, req.ResponseFormat)
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+	n := 1
+	if req.N != nil {
+		n = *req.N
+	}
+	if n < 1 || n > h.maxChoices {
+		core.WriteResponse(c, errorx.WithCode(ErrValidation, "n must be between 1 and %d, got %d", h.maxChoices, n), nil)
+		return
+	}
+	overrides, err = applySamplingParams(overrides, &req)
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+	maxHistoryTurns, err := resolveMaxHistoryTurns(c)
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+
+	// Image inputs require a vision-capable model.
+	if len(images) > 0 {
+		if err := h.checkImageInput(c, agentID); err != nil {
+			core.WriteResponse(c, err, nil)
+			return
+		}
+	}
+
 	// Build RunRequest.
 	runReq := &runtime.RunRequest{
-		AgentID:   agentID,
-		SessionID: sessionID,
-		Input:     userInput,
+		AgentID:      agentID,
+		SessionID:    sessionID,
+		Input:        userInput,
+		Images:       toImageContents(images),
+		LLMOverrides: overrides,
+		PromptExtra:  toPromptExtra(req.Metadata),
+		Choices:      n,
+
+		MaxHistoryTurns: maxHistoryTurns,
 	}
 
-	// Execute the agent run.
-	sr, err := h.svc.Run(c.Request.Context(), runReq)
+	// Hold the concurrent-run slot (if rate limited) until the run finishes,
+	// not just until this handler returns.
+	release := middleware.TakeRunRelease(c)
+	runReq.OnFinish = release
+
+	// Execute the agent run. A run with an Idempotency-Key must not be
+	// cancelled by the client going away: its retry waits for the result
+	// instead of running the agent again.
+	runCtx := c.Request.Context()
+	if idem != nil {
+		runCtx = context.WithoutCancel(runCtx)
+	}
+	sr, err := h.svc.Run(runCtx, runReq)
 	if err != nil {
+		if release != nil {
+			release()
+		}
+		if errors.Is(err, errno.ErrSessionBusy) {
+			core.WriteResponse(c, errorx.WrapC(err, ErrSessionBusy, "session %q has a run in progress", sessionID), nil)
+			return
+		}
 		core.WriteResponse(c, errorx.WrapC(err, ErrAgentRun, "run agent %q", agentID), nil)
 		return
 	}
@@ -102,10 +299,13 @@ func (h *ChatCompletionsHandler) Handle(c *gin.Context) {
 		model = h.defaultModel
 	}
 
+	includeToolResults := c.GetHeader(headerIncludeToolResults) == "true"
 	if req.Stream {
-		h.handleStream(c, sr, completionID, model)
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		includeStatus := c.GetHeader(headerIncludeStatus) == "true"
+		h.handleStream(c, sr, completionID, model, n, includeToolResults, includeUsage, includeStatus, idem)
 	} else {
-		h.handleNonStream(c, sr, completionID, model)
+		h.handleNonStream(c, sr, completionID, model, n, includeToolResults, idem)
 	}
 }
 
@@ -113,10 +313,21 @@ func (h *ChatCompletionsHandler) Handle(c *gin.Context) {
 //
 // OpenClaw equivalent: the streaming branch in openai-http.ts that subscribes
 // to onAgentEvent and emits SSE data chunks with "chat.completion.chunk" objects.
+//
+// As in OpenAI's API, usage is only sent when includeUsage is set, as an extra
+// chunk with empty choices after the finish chunks. With n > 1, the chunks of
+// the choices are interleaved as they arrive, each carrying its choice index.
+//
+// Compaction progress is written as an SSE comment, which OpenAI clients
+// ignore, and additionally as a status delta when includeStatus is set.
+// A mid-stream model switch is always written as a "reset" status delta.
 func (h *ChatCompletionsHandler) handleStream(
 	c *gin.Context,
 	sr *schema.StreamReader[*entity.AgentEvent],
 	completionID, model string,
+	n int,
+	includeToolResults, includeUsage, includeStatus bool,
+	idem *idempotencyReservation,
 ) {
 	// Set SSE headers.
 	c.Header("Content-Type", "text/event-stream")
@@ -127,33 +338,65 @@ func (h *ChatCompletionsHandler) handleStream(
 	w := c.Writer
 	created := time.Now().Unix()
 
-	// Send initial role chunk (OpenClaw: send role chunk before deltas).
-	h.writeSSEChunk(w, completionID, model, created, &ChatMessageDelta{Role: "assistant"}, nil, nil)
+	// Send initial role chunks (OpenClaw: send role chunk before deltas).
+	for i := 0; i < n; i++ {
+		h.writeSSEChunk(w, completionID, model, created, i, &ChatMessageDelta{Role: "assistant"}, nil, nil)
+	}
 	w.Flush()
 
-	var toolCallIndex int
-	var lastUsage *ChatCompletionUsage
+	// Per-choice output, also kept for Idempotency-Key retries.
+	choices := newCompletionChoices(n)
+	ended := 0 // choices that finished or failed
 
+	// Receive events in a separate goroutine so the loop below can interleave
+	// keepalives. All writes stay on this goroutine, so a keepalive can never
+	// land between a chunk and its flush.
+	events, stop := recvEvents(sr)
+	detached := false
+	defer func() {
+		if !detached {
+			close(stop)
+		}
+	}()
+
+	keepalive := newStreamKeepalive(h.keepaliveInterval)
+	defer keepalive.stop()
+
+loop:
 	for {
-		// Check client disconnect.
+		var recv eventRecv
 		select {
 		case <-c.Request.Context().Done():
+			// A run with an Idempotency-Key outlives its client; finish
+			// collecting it so that a retry gets its result.
+			if owner := idem.transfer(); owner != nil {
+				detached = true
+				go finishDetached(events, stop, choices, completionID, model, created, owner)
+			}
 			return
-		default:
+		case <-keepalive.C():
+			fmt.Fprint(w, ": ping\n\n")
+			w.Flush()
+			continue
+		case recv = <-events:
 		}
 
-		event, err := sr.Recv()
+		event, err := recv.event, recv.err
 		if err != nil {
-			if err == io.EOF {
-				break
+			if err != io.EOF {
+				logger.Warn("[ChatCompletions] stream recv error (code=%d): %v", ErrStreamRecv, err)
 			}
-			logger.Warn("[ChatCompletions] stream recv error (code=%d): %v", ErrStreamRecv, err)
-			break
+			break loop
+		}
+		if ended < n {
+			keepalive.reset()
 		}
 
+		index, choice := choices.of(event)
 		switch event.Type {
 		case entity.EventTextDelta:
-			h.writeSSEChunk(w, completionID, model, created, &ChatMessageDelta{
+			choice.content.WriteString(event.Delta)
+			h.writeSSEChunk(w, completionID, model, created, index, &ChatMessageDelta{
 				Content: event.Delta,
 			}, nil, nil)
 			w.Flush()
@@ -162,7 +405,7 @@ func (h *ChatCompletionsHandler) handleStream(
 			if event.ToolCall != nil {
 				delta := &ChatMessageDelta{
 					ToolCalls: []ToolCallChunk{{
-						Index: toolCallIndex,
+						Index: len(choice.toolCalls),
 						ID:    event.ToolCall.ID,
 						Type:  "function",
 						Function: ToolCallFunction{
@@ -171,40 +414,254 @@ func (h *ChatCompletionsHandler) handleStream(
 						},
 					}},
 				}
-				h.writeSSEChunk(w, completionID, model, created, delta, nil, nil)
+				h.writeSSEChunk(w, completionID, model, created, index, delta, nil, nil)
+				w.Flush()
+				choice.toolCalls = append(choice.toolCalls, delta.ToolCalls...)
+			}
+
+		case entity.EventToolCallEnd:
+			if includeToolResults && event.ToolResult != nil {
+				h.writeSSEChunk(w, completionID, model, created, index, &ChatMessageDelta{
+					ToolResults: []ToolResultChunk{toToolResultChunk(event.ToolResult)},
+				}, nil, nil)
 				w.Flush()
-				toolCallIndex++
 			}
 
+		case entity.EventCompaction:
+			if event.Compaction == nil {
+				continue
+			}
+			fmt.Fprintf(w, ": %s\n\n", compactionComment(event.Compaction))
+			if includeStatus {
+				h.writeSSEChunk(w, completionID, model, created, index, &ChatMessageDelta{
+					Status: toCompactionStatusChunk(event.Compaction),
+				}, nil, nil)
+			}
+			w.Flush()
+
 		case entity.EventDone:
+			if !choice.done {
+				choice.done = true
+				ended++
+			}
+			if ended == n {
+				keepalive.stop()
+			}
 			if event.Usage != nil {
-				lastUsage = &ChatCompletionUsage{
-					PromptTokens:     event.Usage.PromptTokens,
-					CompletionTokens: event.Usage.CompletionTokens,
-					TotalTokens:      event.Usage.TotalTokens,
-				}
+				choice.usage = event.Usage
+			}
+			if event.FinishReason != "" {
+				choice.finish = event.FinishReason
+			}
+
+		case entity.EventUsageDelta:
+			// Running tally; EventDone usage, when present, supersedes it.
+			if event.TotalUsage != nil {
+				choice.usage = event.TotalUsage
+			}
+
+		case entity.EventModelSwitch:
+			choice.reset()
+			// Already-streamed chunks cannot be retracted. The reset status
+			// tells the client to discard the choice's content and tool calls
+			// so far; clients that did not ask for status deltas also get a
+			// notice to tell the failed attempt's output from the retry's.
+			delta := &ChatMessageDelta{Status: toModelSwitchStatusChunk(event.Error)}
+			if !includeStatus {
+				delta.Content = "\n[" + event.Error + "]\n"
+			}
+			h.writeSSEChunk(w, completionID, model, created, index, delta, nil, nil)
+			w.Flush()
+
+		case entity.EventRunStatus:
+			if event.FinishReason != "" {
+				choice.finish = event.FinishReason
+			}
+			if event.RunStatus == entity.RunStatusFailed && !choice.done {
+				choice.done = true
+				ended++
 			}
 
 		case entity.EventError:
 			// Send error as a text delta so the client sees it.
-			h.writeSSEChunk(w, completionID, model, created, &ChatMessageDelta{
+			h.writeSSEChunk(w, completionID, model, created, index, &ChatMessageDelta{
 				Content: "\n[Error: " + event.Error + "]",
 			}, nil, nil)
 			w.Flush()
 		}
 	}
 
-	// Send final chunk with finish_reason="stop".
-	finishReason := "stop"
-	h.writeSSEChunk(w, completionID, model, created, &ChatMessageDelta{}, &finishReason, lastUsage)
+	// Send a final chunk per choice with its finish_reason ("stop" unless
+	// the choice ended early).
+	for i, choice := range choices {
+		finishReason := choice.streamFinishReason()
+		h.writeSSEChunk(w, completionID, model, created, i, &ChatMessageDelta{}, &finishReason, nil)
+	}
 	w.Flush()
 
+	// stream_options.include_usage: usage-only chunk with empty choices.
+	usage := choices.usage()
+	if includeUsage {
+		if usage == nil {
+			usage = &ChatCompletionUsage{}
+		}
+		h.writeSSEData(w, ChatCompletionChunk{
+			ID:      completionID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []ChatCompletionChunkChoice{},
+			Usage:   usage,
+		})
+		w.Flush()
+	}
+
 	// Send [DONE] sentinel (OpenAI SSE convention).
 	fmt.Fprintf(w, "data: [DONE]\n\n")
 	w.Flush()
+
+	// Failed runs are not cached: a retry should run again.
+	if choices.completed() {
+		idem.complete(choices.streamedResponse(completionID, model, created))
+	}
+}
+
+// finishDetached collects the rest of a run whose streaming client went away
+// and keeps its result for Idempotency-Key retries, which wait for it. It
+// closes stop once the run's events are drained.
+func finishDetached(events <-chan eventRecv, stop chan struct{}, choices completionChoices, completionID, model string, created int64, idem *idempotencyReservation) {
+	defer close(stop)
+	for {
+		recv := <-events
+		if recv.err != nil {
+			break
+		}
+		choices.collect(recv.event, false)
+	}
+	if choices.completed() {
+		idem.complete(choices.streamedResponse(completionID, model, created))
+		return
+	}
+	idem.abandon()
+}
+
+// replayCompletion answers an Idempotency-Key retry with the cached result,
+// as JSON or as a compact SSE stream depending on the retry's stream flag.
+func (h *ChatCompletionsHandler) replayCompletion(c *gin.Context, resp *ChatCompletionResponse, stream, includeUsage bool) {
+	if !stream {
+		writeCompletion(c, resp, "")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	w := c.Writer
+
+	for _, choice := range resp.Choices {
+		finishReason := choice.FinishReason
+		delta := &ChatMessageDelta{Role: "assistant"}
+		if choice.Message != nil {
+			delta.Content = choice.Message.Content
+			delta.ToolCalls = choice.Message.ToolCalls
+		}
+		h.writeSSEChunk(w, resp.ID, resp.Model, resp.Created, choice.Index, delta, nil, nil)
+		h.writeSSEChunk(w, resp.ID, resp.Model, resp.Created, choice.Index, &ChatMessageDelta{}, &finishReason, nil)
+	}
+	if includeUsage {
+		usage := resp.Usage
+		if usage == nil {
+			usage = &ChatCompletionUsage{}
+		}
+		h.writeSSEData(w, ChatCompletionChunk{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: []ChatCompletionChunkChoice{},
+			Usage:   usage,
+		})
+	}
+	fmt.Fprintf(w, "data: [DONE]\n\n")
+	w.Flush()
+}
+
+// toChatCompletionUsage converts agent token usage to the OpenAI usage object.
+func toChatCompletionUsage(u *entity.TokenUsage) *ChatCompletionUsage {
+	return &ChatCompletionUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+// eventRecv is a single sr.Recv result.
+type eventRecv struct {
+	event *entity.AgentEvent
+	err   error
+}
+
+// recvEvents pumps sr into a channel until Recv returns an error (including io.EOF)
+// or stop is closed. The caller must close stop when it stops reading.
+func recvEvents(sr *schema.StreamReader[*entity.AgentEvent]) (<-chan eventRecv, chan struct{}) {
+	events := make(chan eventRecv)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			event, err := sr.Recv()
+			select {
+			case events <- eventRecv{event: event, err: err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return events, stop
+}
+
+// streamKeepalive is an optional ticker driving SSE ping comments.
+// A zero-interval keepalive never fires.
+type streamKeepalive struct {
+	interval time.Duration
+	ticker   *time.Ticker
+}
+
+func newStreamKeepalive(interval time.Duration) *streamKeepalive {
+	k := &streamKeepalive{interval: interval}
+	if interval > 0 {
+		k.ticker = time.NewTicker(interval)
+	}
+	return k
 }
 
-// handleNonStream collects all events and returns a single JSON response.
+// C returns the tick channel; nil (blocks forever) when pings are disabled.
+func (k *streamKeepalive) C() <-chan time.Time {
+	if k.ticker == nil {
+		return nil
+	}
+	return k.ticker.C
+}
+
+// reset restarts the idle period after a real chunk was sent.
+func (k *streamKeepalive) reset() {
+	if k.ticker != nil {
+		k.ticker.Reset(k.interval)
+	}
+}
+
+func (k *streamKeepalive) stop() {
+	if k.ticker != nil {
+		k.ticker.Stop()
+	}
+}
+
+// handleNonStream collects all events and returns a single JSON response,
+// or just the assistant text when the client accepts text/plain (see
+// writeCompletion). With n > 1, the response has one choice per completion.
 //
 // OpenClaw equivalent: the non-streaming branch that waits for agentCommand
 // to complete, then builds a single chat.completion response.
@@ -212,12 +669,11 @@ func (h *ChatCompletionsHandler) handleNonStream(
 	c *gin.Context,
 	sr *schema.StreamReader[*entity.AgentEvent],
 	completionID, model string,
+	n int,
+	includeToolResults bool,
+	idem *idempotencyReservation,
 ) {
-	var content strings.Builder
-	var toolCalls []ToolCallChunk
-	var usage *ChatCompletionUsage
-	var lastErr string
-	toolCallIndex := 0
+	choices := newCompletionChoices(n)
 
 	for {
 		event, err := sr.Recv()
@@ -229,75 +685,181 @@ func (h *ChatCompletionsHandler) handleNonStream(
 			break
 		}
 
-		switch event.Type {
-		case entity.EventTextDelta:
-			content.WriteString(event.Delta)
+		choices.collect(event, includeToolResults)
+	}
 
-		case entity.EventToolCallStart:
-			if event.ToolCall != nil {
-				toolCalls = append(toolCalls, ToolCallChunk{
-					Index: toolCallIndex,
-					ID:    event.ToolCall.ID,
-					Type:  "function",
-					Function: ToolCallFunction{
-						Name:      event.ToolCall.Name,
-						Arguments: event.ToolCall.Arguments,
-					},
-				})
-				toolCallIndex++
-			}
+	// A run whose every choice failed without output is an error; a choice
+	// stopped at max turns still completes, with finish_reason "length".
+	if lastErr := choices.failure(); lastErr != "" {
+		core.WriteResponse(c, errorx.WithCode(ErrNonStreamResult, "%s", lastErr), nil)
+		return
+	}
 
-		case entity.EventDone:
-			if event.Usage != nil {
-				usage = &ChatCompletionUsage{
-					PromptTokens:     event.Usage.PromptTokens,
-					CompletionTokens: event.Usage.CompletionTokens,
-					TotalTokens:      event.Usage.TotalTokens,
-				}
+	resp := ChatCompletionResponse{
+		ID:      completionID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: make([]ChatCompletionChoice, 0, n),
+		Usage:   choices.usage(),
+	}
+	for i, choice := range choices {
+		msg := &ChatMessage{
+			Role:    "assistant",
+			Content: choice.content.String(),
+		}
+		if len(choice.toolCalls) > 0 {
+			msg.ToolCalls = choice.toolCalls
+		}
+		if len(choice.toolResults) > 0 {
+			msg.ToolResults = choice.toolResults
+		}
+		resp.Choices = append(resp.Choices, ChatCompletionChoice{
+			Index:        i,
+			Message:      msg,
+			FinishReason: choice.finishReason(),
+		})
+	}
+	if !choices.anyFailed() {
+		idem.complete(&resp)
+	}
+	writeCompletion(c, &resp, choices[0].lastErr)
+}
+
+// writeCompletion writes a non-streaming completion. A client whose Accept
+// header prefers text/plain over JSON (see preferPlainText) gets only the
+// assistant's text; JSON stays the default, including for a missing or
+// wildcard Accept header. A plain-text client has no finish_reason to look
+// at, so a first choice that failed is answered with an error status and
+// runErr (the run's last error, if any) instead of its partial text.
+func writeCompletion(c *gin.Context, resp *ChatCompletionResponse, runErr string) {
+	if !preferPlainText(c.GetHeader("Accept")) {
+		core.WriteResponse(c, nil, *resp)
+		return
+	}
+	var content string
+	if len(resp.Choices) > 0 {
+		if resp.Choices[0].FinishReason == string(entity.FinishReasonError) {
+			if runErr == "" {
+				runErr = "agent run failed"
 			}
+			core.WriteResponse(c, errorx.WithCode(ErrNonStreamResult, "%s", runErr), nil)
+			return
+		}
+		if msg := resp.Choices[0].Message; msg != nil {
+			content = msg.Content
+		}
+	}
+	c.String(http.StatusOK, "%s", content)
+}
 
-		case entity.EventError:
-			lastErr = event.Error
+// preferPlainText reports whether an Accept header ranks text/plain above
+// application/json. Each offer gets the quality of the most specific media
+// range matching it (exact, then type/*, then */*); an offer no range
+// matches, or matched with q=0, is not acceptable. Ties go to JSON.
+func preferPlainText(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	plain := acceptQuality(accept, gin.MIMEPlain)
+	return plain > 0 && plain > acceptQuality(accept, gin.MIMEJSON)
+}
+
+// acceptQuality returns the quality an Accept header assigns to the media
+// type offer, or 0 when it is not acceptable.
+func acceptQuality(accept, offer string) float64 {
+	offerType, _, _ := strings.Cut(offer, "/")
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var rank int
+		switch {
+		case mediaType == offer:
+			rank = 2
+		case mediaType == offerType+"/*":
+			rank = 1
+		case mediaType == "*/*":
+			rank = 0
+		default:
+			continue
+		}
+		if rank <= specificity {
+			continue
 		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+		}
+		quality, specificity = q, rank
 	}
+	return quality
+}
 
-	if lastErr != "" && content.Len() == 0 {
-		core.WriteResponse(c, errorx.WithCode(ErrNonStreamResult, "%s", lastErr), nil)
-		return
+// toOpenAIFinishReason maps a run finish reason to its OpenAI equivalent:
+// "stop", "length" (also for a run stopped at max turns), "content_filter",
+// or the Echoryn extension "error" for failed and aborted runs.
+func toOpenAIFinishReason(r entity.FinishReason) string {
+	switch r {
+	case "":
+		return "stop"
+	case entity.FinishReasonMaxTurns, entity.FinishReasonLength:
+		return "length"
 	}
+	return string(r)
+}
 
-	msg := &ChatMessage{
-		Role:    "assistant",
-		Content: content.String(),
+// toToolResultChunk converts an agent tool result to its API representation.
+func toToolResultChunk(r *entity.ToolResult) ToolResultChunk {
+	return ToolResultChunk{
+		ToolCallID: r.ToolCallID,
+		Name:       r.Name,
+		Content:    r.Content,
+		Truncated:  r.Truncated,
 	}
-	if len(toolCalls) > 0 {
-		msg.ToolCalls = toolCalls
+}
+
+// compactionComment renders a compaction event as an SSE comment line, e.g.
+// "compaction started reason=threshold tokens_before=91234".
+func compactionComment(info *entity.CompactionInfo) string {
+	comment := fmt.Sprintf("compaction %s reason=%s tokens_before=%d", info.Status, info.Reason, info.TokensBefore)
+	if info.Status == entity.CompactionCompleted {
+		comment += fmt.Sprintf(" tokens_after=%d", info.TokensAfter)
 	}
+	return comment
+}
 
-	finishReason := "stop"
-	if len(toolCalls) > 0 {
-		finishReason = "tool_calls"
+func toCompactionStatusChunk(info *entity.CompactionInfo) *StatusChunk {
+	return &StatusChunk{
+		Type:         "compaction",
+		State:        string(info.Status),
+		Reason:       string(info.Reason),
+		TokensBefore: info.TokensBefore,
+		TokensAfter:  info.TokensAfter,
 	}
+}
 
-	core.WriteResponse(c, nil, ChatCompletionResponse{
-		ID:      completionID,
-		Object:  "chat.completion",
-		Created: time.Now().Unix(),
-		Model:   model,
-		Choices: []ChatCompletionChoice{{
-			Index:        0,
-			Message:      msg,
-			FinishReason: finishReason,
-		}},
-		Usage: usage,
-	})
+// toModelSwitchStatusChunk returns the status delta telling the client to
+// drop the output streamed by a model that failed mid-stream.
+func toModelSwitchStatusChunk(notice string) *StatusChunk {
+	return &StatusChunk{
+		Type:   "model_switch",
+		State:  "reset",
+		Reason: notice,
+	}
 }
 
-// writeSSEChunk writes a single SSE data chunk in OpenAI chat.completion.chunk format.
+// writeSSEChunk writes a single SSE data chunk in OpenAI chat.completion.chunk
+// format, for the choice at index.
 func (h *ChatCompletionsHandler) writeSSEChunk(
 	w gin.ResponseWriter,
 	id, model string,
 	created int64,
+	index int,
 	delta *ChatMessageDelta,
 	finishReason *string,
 	usage *ChatCompletionUsage,
@@ -308,13 +870,17 @@ func (h *ChatCompletionsHandler) writeSSEChunk(
 		Created: created,
 		Model:   model,
 		Choices: []ChatCompletionChunkChoice{{
-			Index:        0,
+			Index:        index,
 			Delta:        delta,
 			FinishReason: finishReason,
 		}},
 		Usage: usage,
 	}
+	h.writeSSEData(w, chunk)
+}
 
+// writeSSEData writes chunk as a single SSE "data:" event.
+func (h *ChatCompletionsHandler) writeSSEData(w gin.ResponseWriter, chunk ChatCompletionChunk) {
 	data, err := json.Marshal(chunk)
 	if err != nil {
 		logger.Warn("[ChatCompletions] marshal chunk error: %v", err)
@@ -323,6 +889,148 @@ func (h *ChatCompletionsHandler) writeSSEChunk(
 	fmt.Fprintf(w, "data: %s\n\n", data)
 }
 
+// resolveResponseFormat maps the request's response_format onto LLM params.
+//
+// Returns nil params for plain text. Structured formats are rejected with
+// ErrResponseFormat when the agent's primary model has SupportsJSONMode=false
+// in its resolved compat config, instead of being silently ignored.
+func (h *ChatCompletionsHandler) resolveResponseFormat(c *gin.Context, agentID string, rf *ResponseFormat) (*llmEntity.LLMParams, error) {
+	if rf == nil {
+		return nil, nil
+	}
+
+	params := &llmEntity.LLMParams{}
+	switch rf.Type {
+	case "", "text":
+		return nil, nil
+	case "json_object":
+		params.ResponseFormat = llmEntity.ModelResponseFormatJSON
+	case "json_schema":
+		if rf.JSONSchema == nil || len(rf.JSONSchema.Schema) == 0 {
+			return nil, errorx.WithCode(ErrValidation, "response_format json_schema requires json_schema.schema")
+		}
+		params.ResponseFormat = llmEntity.ModelResponseFormatJSONSchema
+		params.JSONSchema = &llmEntity.ResponseJSONSchema{
+			Name:        rf.JSONSchema.Name,
+			Description: rf.JSONSchema.Description,
+			Schema:      rf.JSONSchema.Schema,
+			Strict:      rf.JSONSchema.Strict,
+		}
+	default:
+		return nil, errorx.WithCode(ErrValidation, "invalid response_format type %q: must be one of text, json_object, json_schema", rf.Type)
+	}
+
+	if h.llmManager == nil {
+		return params, nil
+	}
+	ref, err := h.agentPrimaryModel(c, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if ref.ProviderID == "" || ref.ModelID == "" {
+		return params, nil
+	}
+	compat, err := h.llmManager.ResolveCompat(c.Request.Context(), ref)
+	if err == nil && compat != nil && !llmEntity.GetBoolOrDefault(compat.SupportsJSONMode, true) {
+		return nil, errorx.WithCode(ErrResponseFormat,
+			"model %s does not support structured output (response_format %q)", ref, rf.Type)
+	}
+	return params, nil
+}
+
+// applySamplingParams maps the request's sampling params (temperature,
+// max_tokens, top_p, penalties, seed, stop) onto overrides, allocating it
+// when needed. Values outside the OpenAI ranges are rejected; per-model
+// limits are applied later by the model manager.
+func applySamplingParams(overrides *llmEntity.LLMParams, req *ChatCompletionRequest) (*llmEntity.LLMParams, error) {
+	if len(req.Stop) > 4 {
+		return nil, errorx.WithCode(ErrValidation, "stop accepts at most 4 sequences, got %d", len(req.Stop))
+	}
+	if t := req.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return nil, errorx.WithCode(ErrValidation, "temperature must be between 0 and 2, got %g", *t)
+	}
+	if p := req.TopP; p != nil && (*p < 0 || *p > 1) {
+		return nil, errorx.WithCode(ErrValidation, "top_p must be between 0 and 1, got %g", *p)
+	}
+	if p := req.FrequencyPenalty; p != nil && (*p < -2 || *p > 2) {
+		return nil, errorx.WithCode(ErrValidation, "frequency_penalty must be between -2 and 2, got %g", *p)
+	}
+	if p := req.PresencePenalty; p != nil && (*p < -2 || *p > 2) {
+		return nil, errorx.WithCode(ErrValidation, "presence_penalty must be between -2 and 2, got %g", *p)
+	}
+	if req.MaxTokens != nil && *req.MaxTokens <= 0 {
+		return nil, errorx.WithCode(ErrValidation, "max_tokens must be positive, got %d", *req.MaxTokens)
+	}
+
+	if req.Temperature == nil && req.MaxTokens == nil && req.TopP == nil &&
+		req.FrequencyPenalty == nil && req.PresencePenalty == nil &&
+		req.Seed == nil && len(req.Stop) == 0 {
+		return overrides, nil
+	}
+	if overrides == nil {
+		overrides = &llmEntity.LLMParams{}
+	}
+	if req.Temperature != nil {
+		t := float32(*req.Temperature)
+		overrides.Temperature = &t
+	}
+	if req.MaxTokens != nil {
+		overrides.MaxTokens = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		p := float32(*req.TopP)
+		overrides.TopP = &p
+	}
+	if req.FrequencyPenalty != nil {
+		p := float32(*req.FrequencyPenalty)
+		overrides.FrequencyPenalty = &p
+	}
+	if req.PresencePenalty != nil {
+		p := float32(*req.PresencePenalty)
+		overrides.PresencePenalty = &p
+	}
+	overrides.Seed = req.Seed
+	overrides.Stop = req.Stop
+	return overrides, nil
+}
+
+// checkImageInput rejects image inputs with ErrImageInput when the agent's
+// primary model does not have the ImageUnderstanding capability.
+func (h *ChatCompletionsHandler) checkImageInput(c *gin.Context, agentID string) error {
+	if h.llmManager == nil {
+		return nil
+	}
+	ref, err := h.agentPrimaryModel(c, agentID)
+	if err != nil {
+		return err
+	}
+	if ref.ProviderID == "" || ref.ModelID == "" {
+		return nil
+	}
+	instance, err := h.llmManager.GetModelByRef(c.Request.Context(), ref)
+	if err != nil {
+		return errorx.WrapC(err, ErrAgentModel, "resolve model %s", ref)
+	}
+	if !instance.Capability.ImageUnderstanding {
+		return errorx.WithCode(ErrImageInput, "model %s does not support image input", ref)
+	}
+	return nil
+}
+
+// agentPrimaryModel returns the model an agent runs on first: its ModelRef,
+// else its fallback primary. The ref is empty when neither is set.
+func (h *ChatCompletionsHandler) agentPrimaryModel(c *gin.Context, agentID string) (llmEntity.ModelRef, error) {
+	agent, err := h.svc.GetAgent(c.Request.Context(), agentID)
+	if err != nil {
+		return llmEntity.ModelRef{}, errorx.WrapC(err, ErrAgentNotFound, "agent %q not found", agentID)
+	}
+	ref := agent.ModelRef
+	if ref.ProviderID == "" || ref.ModelID == "" {
+		ref = agent.Fallback.Primary
+	}
+	return ref, nil
+}
+
 // resolveAgentID extracts agent ID from the model field or X-Agent-Id header.
 //
 // Parsing rules (aligned with OpenClaw http-utils.ts):
@@ -370,9 +1078,10 @@ func (h *ChatCompletionsHandler) resolveSessionID(c *gin.Context, user, agentID
 }
 
 // extractUserInput extracts the last user message content and any system prompts.
-// Returns (userInput, extraSystemPrompt).
-func extractUserInput(messages []ChatMessage) (string, string) {
+// Returns (userInput, userImages, extraSystemPrompt).
+func extractUserInput(messages []ChatMessage) (string, []ImageURL, string) {
 	var userInput string
+	var images []ImageURL
 	var systemParts []string
 
 	for _, msg := range messages {
@@ -381,28 +1090,105 @@ func extractUserInput(messages []ChatMessage) (string, string) {
 			systemParts = append(systemParts, msg.Content)
 		case "user":
 			userInput = msg.Content
+			images = msg.Images
 		}
 	}
 
 	extraSystem := strings.Join(systemParts, "\n")
-	return userInput, extraSystem
+	return userInput, images, extraSystem
+}
+
+// toImageContents converts request image parts into run image inputs.
+func toImageContents(images []ImageURL) []*entity.ImageContent {
+	if len(images) == 0 {
+		return nil
+	}
+	out := make([]*entity.ImageContent, 0, len(images))
+	for _, img := range images {
+		out = append(out, &entity.ImageContent{URL: img.URL, Detail: img.Detail})
+	}
+	return out
+}
+
+// toPromptExtra converts request metadata into prompt extra entries.
+func toPromptExtra(metadata map[string]string) map[string]interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
+
+// resolveAgentDefaults applies the X-Agent-* request headers on top of the
+// configured AgentDefaults.
+func (h *ChatCompletionsHandler) resolveAgentDefaults(c *gin.Context) (AgentDefaults, error) {
+	d := h.agentDefaults
+	if v := c.GetHeader(headerAgentTools); v != "" {
+		d.Tools = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				d.Tools = append(d.Tools, name)
+			}
+		}
+	}
+	if v := c.GetHeader(headerAgentMemory); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return d, errorx.WithCode(ErrValidation, "invalid %s header %q: must be true or false", headerAgentMemory, v)
+		}
+		d.Memory = &enabled
+	}
+	if v := c.GetHeader(headerAgentMaxTurns); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return d, errorx.WithCode(ErrValidation, "invalid %s header %q: must be a positive integer", headerAgentMaxTurns, v)
+		}
+		d.MaxTurns = n
+	}
+	return d, nil
+}
+
+// resolveMaxHistoryTurns parses the X-Max-History-Turns header. 0 means the
+// header is absent; large values are clamped by the runner (see
+// runtime.ContextBuilder.WithMaxHistoryTurns).
+func resolveMaxHistoryTurns(c *gin.Context) (int, error) {
+	v := c.GetHeader(headerMaxHistoryTurns)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errorx.WithCode(ErrValidation, "invalid %s header %q: must be a positive integer", headerMaxHistoryTurns, v)
+	}
+	return n, nil
 }
 
 // ensureAgent checks if the agent exists; if not, auto-creates a default one
 // with the system's default model bound as FallbackConfig.Primary.
 // This allows /v1/chat/completions to work even without pre-creating agents,
 // matching OpenClaw's behavior where a "main" agent always exists.
-func (h *ChatCompletionsHandler) ensureAgent(c *gin.Context, agentID, extraSystem string) error {
+func (h *ChatCompletionsHandler) ensureAgent(c *gin.Context, agentID, extraSystem string, defaults AgentDefaults) error {
 	_, err := h.svc.GetAgent(c.Request.Context(), agentID)
 	if err == nil {
 		return nil
 	}
 
-	// Auto-create agent with the given system prompt.
+	// Auto-create agent with the given system prompt, else the configured one.
+	systemPrompt := extraSystem
+	if systemPrompt == "" {
+		systemPrompt = defaults.SystemPrompt
+	}
 	agent := &entity.Agent{
 		ID:           agentID,
 		Name:         agentID,
-		SystemPrompt: extraSystem,
+		SystemPrompt: systemPrompt,
+		Tools:        defaults.Tools,
+		MaxTurns:     defaults.MaxTurns,
+		Memory:       defaults.Memory,
+		Persona:      defaults.persona(),
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -0,0 +1,832 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeAgentService knows every agent and answers runs with the events
+// returned by events, sent once proceed (if set) is closed. The stream ends
+// once hold (if set) is closed.
+type fakeAgentService struct {
+	service.AgentService
+
+	events  func(req *runtime.RunRequest) []*entity.AgentEvent
+	proceed chan struct{}
+	hold    chan struct{}
+	model   llmEntity.ModelRef
+
+	mu      sync.Mutex
+	runs    []*runtime.RunRequest
+	runCtxs []context.Context
+}
+
+func (f *fakeAgentService) GetAgent(_ context.Context, id string) (*entity.Agent, error) {
+	return &entity.Agent{ID: id, ModelRef: f.model}, nil
+}
+
+func (f *fakeAgentService) Run(ctx context.Context, req *runtime.RunRequest) (*schema.StreamReader[*entity.AgentEvent], error) {
+	f.mu.Lock()
+	f.runs = append(f.runs, req)
+	f.runCtxs = append(f.runCtxs, ctx)
+	f.mu.Unlock()
+
+	events := f.events(req)
+	sr, sw := schema.Pipe[*entity.AgentEvent](len(events))
+	go func() {
+		defer sw.Close()
+		if f.proceed != nil {
+			<-f.proceed
+		}
+		for _, e := range events {
+			sw.Send(e, nil)
+		}
+		if f.hold != nil {
+			<-f.hold
+		}
+	}()
+	return sr, nil
+}
+
+func (f *fakeAgentService) runCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.runs)
+}
+
+// replyEvents makes every run answer text.
+func replyEvents(text string) func(*runtime.RunRequest) []*entity.AgentEvent {
+	return func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{
+			{Type: entity.EventTextDelta, Delta: text},
+			{Type: entity.EventDone},
+		}
+	}
+}
+
+func newChatEngine(svc service.AgentService) (*gin.Engine, *ChatCompletionsHandler) {
+	h := NewChatCompletionsHandler(svc, nil, "main", "Echoryn")
+	g := gin.New()
+	g.POST("/v1/chat/completions", h.Handle)
+	return g, h
+}
+
+// postChat sends body to the engine with the given headers.
+func postChat(ctx context.Context, g *gin.Engine, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	return w
+}
+
+const helloBody = `{"messages":[{"role":"user","content":"hello"}]}`
+
+func TestIdempotencyKeyReplaysResult(t *testing.T) {
+	svc := &fakeAgentService{events: replyEvents("hi there")}
+	g, _ := newChatEngine(svc)
+	headers := map[string]string{"Idempotency-Key": "k1", "X-Session-Key": "s"}
+
+	first := postChat(context.Background(), g, helloBody, headers)
+	second := postChat(context.Background(), g, helloBody, headers)
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("status = %d, %d", first.Code, second.Code)
+	}
+	if n := svc.runCount(); n != 1 {
+		t.Fatalf("%d runs, want the retry to replay the first", n)
+	}
+	if !strings.Contains(second.Body.String(), "hi there") || second.Body.String() != first.Body.String() {
+		t.Fatalf("replay = %s, want %s", second.Body, first.Body)
+	}
+}
+
+func TestIdempotencyKeyBodyMismatch(t *testing.T) {
+	svc := &fakeAgentService{events: replyEvents("hi there")}
+	g, _ := newChatEngine(svc)
+	headers := map[string]string{"Idempotency-Key": "k1", "X-Session-Key": "s"}
+
+	postChat(context.Background(), g, helloBody, headers)
+	w := postChat(context.Background(), g, `{"messages":[{"role":"user","content":"something else"}]}`, headers)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	if n := svc.runCount(); n != 1 {
+		t.Fatalf("%d runs, want the mismatched retry rejected", n)
+	}
+}
+
+func TestIdempotencyKeyRunOutlivesStreamClient(t *testing.T) {
+	svc := &fakeAgentService{events: replyEvents("late answer"), proceed: make(chan struct{})}
+	g, _ := newChatEngine(svc)
+	headers := map[string]string{"Idempotency-Key": "k1", "X-Session-Key": "s"}
+
+	// The streaming client is gone before the run produced anything.
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+	postChat(gone, g, `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`, headers)
+
+	svc.mu.Lock()
+	runCtx := svc.runCtxs[0]
+	svc.mu.Unlock()
+	if runCtx.Err() != nil {
+		t.Fatal("the run was cancelled with its client")
+	}
+
+	// The retry waits for the detached run instead of starting another.
+	retry := make(chan *httptest.ResponseRecorder)
+	go func() {
+		retry <- postChat(context.Background(), g, `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`, headers)
+	}()
+	close(svc.proceed)
+
+	select {
+	case w := <-retry:
+		if !strings.Contains(w.Body.String(), "late answer") || !strings.Contains(w.Body.String(), "[DONE]") {
+			t.Fatalf("retry body = %s", w.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry did not get the detached run's result")
+	}
+	if n := svc.runCount(); n != 1 {
+		t.Fatalf("%d runs, want 1", n)
+	}
+}
+
+func TestNoIdempotencyKeyRunFollowsClient(t *testing.T) {
+	svc := &fakeAgentService{events: replyEvents("hi")}
+	g, _ := newChatEngine(svc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	postChat(ctx, g, helloBody, nil)
+	cancel()
+	svc.mu.Lock()
+	runCtx := svc.runCtxs[0]
+	svc.mu.Unlock()
+	if runCtx.Err() == nil {
+		t.Fatal("run without Idempotency-Key was detached from its request")
+	}
+}
+
+func TestStreamKeepalive(t *testing.T) {
+	const streamBody = `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantPing bool
+	}{
+		{"pings while idle", 5 * time.Millisecond, true},
+		{"disabled", -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents("slow answer"), proceed: make(chan struct{})}
+			g, h := newChatEngine(svc)
+			h.SetKeepaliveInterval(tt.interval)
+
+			done := make(chan *httptest.ResponseRecorder)
+			go func() { done <- postChat(context.Background(), g, streamBody, nil) }()
+			time.Sleep(50 * time.Millisecond) // the run stays silent for several intervals
+			close(svc.proceed)
+
+			body := (<-done).Body.String()
+			if got := strings.Contains(body, ": ping\n\n"); got != tt.wantPing {
+				t.Fatalf("ping in body = %v, want %v:\n%s", got, tt.wantPing, body)
+			}
+			if !strings.Contains(body, "slow answer") || !strings.Contains(body, "[DONE]") {
+				t.Fatalf("body = %s", body)
+			}
+			// Pings are SSE comments: they never split a data line.
+			for _, line := range strings.Split(body, "\n") {
+				if strings.HasPrefix(line, "data: ") && strings.Contains(line, "ping") {
+					t.Fatalf("ping inside a data line: %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamKeepaliveStops(t *testing.T) {
+	const streamBody = `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`
+
+	t.Run("after done", func(t *testing.T) {
+		svc := &fakeAgentService{events: replyEvents("answer"), hold: make(chan struct{})}
+		g, h := newChatEngine(svc)
+		h.SetKeepaliveInterval(5 * time.Millisecond)
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- postChat(context.Background(), g, streamBody, nil) }()
+		time.Sleep(50 * time.Millisecond) // the run is silent between Done and its end
+		close(svc.hold)
+
+		// The finish chunk is written when the stream ends, so look for
+		// pings from the answer on: Done follows it immediately.
+		body := (<-done).Body.String()
+		answer := strings.Index(body, "answer")
+		if answer < 0 || !strings.Contains(body, "[DONE]") {
+			t.Fatalf("body = %s", body)
+		}
+		if strings.Contains(body[answer:], ": ping") {
+			t.Fatalf("ping after the last choice finished:\n%s", body)
+		}
+	})
+
+	t.Run("on disconnect", func(t *testing.T) {
+		svc := &fakeAgentService{events: replyEvents("answer"), proceed: make(chan struct{})}
+		defer close(svc.proceed)
+		g, h := newChatEngine(svc)
+		h.SetKeepaliveInterval(5 * time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- postChat(ctx, g, streamBody, nil) }()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		select {
+		case w := <-done:
+			if !strings.Contains(w.Body.String(), ": ping") {
+				t.Fatalf("no ping before the disconnect:\n%s", w.Body)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("stream kept running after the client went away")
+		}
+	})
+}
+
+func TestIncludeToolResults(t *testing.T) {
+	result := &entity.ToolResult{ToolCallID: "c1", Name: "weather", Content: "sunny", Truncated: true}
+	events := func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{
+			{Type: entity.EventToolCallStart, ToolCall: &entity.ToolCall{ID: "c1", Name: "weather", Arguments: "{}"}},
+			{Type: entity.EventToolCallEnd, ToolResult: result},
+			{Type: entity.EventTextDelta, Delta: "It is sunny."},
+			{Type: entity.EventDone},
+		}
+	}
+	want := ToolResultChunk{ToolCallID: "c1", Name: "weather", Content: "sunny", Truncated: true}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"opted in", map[string]string{headerIncludeToolResults: "true"}, true},
+		{"no header", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/json", func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			w := postChat(context.Background(), g, helloBody, tt.headers)
+			var resp ChatCompletionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Choices) != 1 {
+				t.Fatalf("status %d, body %s: %v", w.Code, w.Body, err)
+			}
+			got := resp.Choices[0].Message.ToolResults
+			if tt.want && (len(got) != 1 || got[0] != want) {
+				t.Fatalf("tool_results = %+v, want [%+v]", got, want)
+			}
+			if !tt.want && len(got) != 0 {
+				t.Fatalf("tool_results = %+v without the header", got)
+			}
+		})
+		t.Run(tt.name+"/stream", func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			body := postChat(context.Background(), g, `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`, tt.headers).Body.String()
+			var got []ToolResultChunk
+			for _, chunk := range sseChunks(t, body) {
+				for _, choice := range chunk.Choices {
+					got = append(got, choice.Delta.ToolResults...)
+				}
+			}
+			if tt.want && (len(got) != 1 || got[0] != want) {
+				t.Fatalf("streamed tool_results = %+v, want [%+v]", got, want)
+			}
+			if !tt.want && len(got) != 0 {
+				t.Fatalf("streamed tool_results = %+v without the header", got)
+			}
+		})
+	}
+}
+
+func TestMaxChoices(t *testing.T) {
+	tests := []struct {
+		name       string
+		max        int
+		n          int
+		wantStatus int
+	}{
+		{"within the configured cap", 2, 2, http.StatusOK},
+		{"over the configured cap", 2, 3, http.StatusBadRequest},
+		{"default cap", 0, DefaultMaxChoices + 1, http.StatusBadRequest},
+		{"zero", 2, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents("hi")}
+			g, h := newChatEngine(svc)
+			h.SetMaxChoices(tt.max)
+
+			body := fmt.Sprintf(`{"n":%d,"messages":[{"role":"user","content":"hello"}]}`, tt.n)
+			w := postChat(context.Background(), g, body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK && (svc.runCount() != 1 || svc.runs[0].Choices != tt.n) {
+				t.Fatalf("runs = %d, choices = %d; want one run of %d choices", svc.runCount(), svc.runs[0].Choices, tt.n)
+			}
+		})
+	}
+}
+
+func TestModelSwitchDropsFailedOutput(t *testing.T) {
+	events := func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{
+			{Type: entity.EventTextDelta, Delta: "half an ans"},
+			{Type: entity.EventModelSwitch, Error: "model a/m failed (timeout), switching model..."},
+			{Type: entity.EventTextDelta, Delta: "full answer"},
+			{Type: entity.EventDone},
+		}
+	}
+	const streamBody = `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`
+	const reset = `"status":{"type":"model_switch","state":"reset","reason":"model a/m failed (timeout), switching model..."}`
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantNotice bool
+	}{
+		{"plain client", nil, true},
+		{"status client", map[string]string{"X-Include-Status": "true"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			body := postChat(context.Background(), g, streamBody, tt.headers).Body.String()
+
+			i, j := strings.Index(body, "half an ans"), strings.Index(body, reset)
+			if i < 0 || j < i || strings.Index(body, "full answer") < j {
+				t.Fatalf("want the reset status between the attempts:\n%s", body)
+			}
+			if got := strings.Contains(body, `"content":"\n[model a/m failed`); got != tt.wantNotice {
+				t.Fatalf("text notice = %v, want %v:\n%s", got, tt.wantNotice, body)
+			}
+		})
+	}
+
+	g, _ := newChatEngine(&fakeAgentService{events: events})
+	w := postChat(context.Background(), g, helloBody, nil)
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "full answer" {
+		t.Fatalf("content = %q, want only the retry's output", got)
+	}
+}
+
+func TestPreferPlainText(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"text/plain", true},
+		{"text/plain, application/json", false},
+		{"application/json;q=0.5, text/plain", true},
+		{"text/plain;q=0.9, application/json;q=0.8", true},
+		{"text/plain;q=0.5, application/json;q=0.5", false},
+		{"text/plain;q=0.2, */*", false},
+		{"text/*, application/json;q=0.1", true},
+		{"text/plain;q=0, */*", false},
+		{"text/plain;q=0", false},
+		{"text/*;q=0.1, text/plain;q=0.8, application/*;q=0.5", true},
+		{"text/html", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := preferPlainText(tt.accept); got != tt.want {
+				t.Fatalf("preferPlainText(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainTextCompletion(t *testing.T) {
+	failed := func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{
+			{Type: entity.EventTextDelta, Delta: "partial"},
+			{Type: entity.EventError, Error: "model unavailable"},
+			{Type: entity.EventDone, FinishReason: entity.FinishReasonError},
+		}
+	}
+	tests := []struct {
+		name       string
+		events     func(*runtime.RunRequest) []*entity.AgentEvent
+		accept     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"plain", replyEvents("hi there"), "text/plain", http.StatusOK, "text/plain", "hi there"},
+		{"json preferred by q", replyEvents("hi there"), "text/plain;q=0.5, application/json", http.StatusOK, "application/json", `"content":"hi there"`},
+		{"failed run", failed, "text/plain", http.StatusInternalServerError, "application/json", `"code":100106`},
+		{"failed run as json", failed, "application/json", http.StatusOK, "application/json", `"finish_reason":"error"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: tt.events})
+			w := postChat(context.Background(), g, helloBody, map[string]string{"Accept": tt.accept})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Fatalf("content type = %q, want %s", got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// sseChunks decodes the chat.completion.chunk events of an SSE body.
+func sseChunks(t *testing.T, body string) []ChatCompletionChunk {
+	t.Helper()
+	var chunks []ChatCompletionChunk
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("decode %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func TestStreamIncludeUsage(t *testing.T) {
+	delta := &entity.AgentEvent{Type: entity.EventUsageDelta,
+		Usage:      &entity.TokenUsage{PromptTokens: 4, CompletionTokens: 1, TotalTokens: 5},
+		TotalUsage: &entity.TokenUsage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12}}
+	done := &entity.AgentEvent{Type: entity.EventDone,
+		Usage: &entity.TokenUsage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}}
+
+	tests := []struct {
+		name          string
+		streamOptions string
+		last          *entity.AgentEvent
+		want          *ChatCompletionUsage // nil = no usage chunk
+	}{
+		{"not requested", ``, done, nil},
+		{"disabled", `,"stream_options":{"include_usage":false}`, done, nil},
+		{"done usage", `,"stream_options":{"include_usage":true}`, done, &ChatCompletionUsage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}},
+		{"running total", `,"stream_options":{"include_usage":true}`, &entity.AgentEvent{Type: entity.EventDone}, &ChatCompletionUsage{PromptTokens: 9, CompletionTokens: 3, TotalTokens: 12}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: func(*runtime.RunRequest) []*entity.AgentEvent {
+				return []*entity.AgentEvent{{Type: entity.EventTextDelta, Delta: "hi"}, delta, tt.last}
+			}}
+			g, _ := newChatEngine(svc)
+			body := postChat(context.Background(), g, `{"stream":true`+tt.streamOptions+`,"messages":[{"role":"user","content":"hello"}]}`, nil).Body.String()
+			if !strings.HasSuffix(body, "data: [DONE]\n\n") {
+				t.Fatalf("body does not end with [DONE]:\n%s", body)
+			}
+
+			chunks := sseChunks(t, body)
+			last := chunks[len(chunks)-1]
+			for _, chunk := range chunks[:len(chunks)-1] {
+				if chunk.Usage != nil {
+					t.Fatalf("usage before the final chunk:\n%s", body)
+				}
+			}
+			if tt.want == nil {
+				if last.Usage != nil {
+					t.Fatalf("usage = %+v, want none:\n%s", last.Usage, body)
+				}
+				return
+			}
+			if len(last.Choices) != 0 || last.Usage == nil || *last.Usage != *tt.want {
+				t.Fatalf("last chunk = %+v, want empty choices and usage %+v", last, tt.want)
+			}
+			if finish := chunks[len(chunks)-2].Choices; len(finish) != 1 || finish[0].FinishReason == nil {
+				t.Fatalf("usage chunk does not follow the finish chunk:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestStreamCompactionStatus(t *testing.T) {
+	events := func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{
+			{Type: entity.EventCompaction, Compaction: &entity.CompactionInfo{
+				Reason: entity.CompactionReasonOverflow, Status: entity.CompactionStarted, TokensBefore: 9000}},
+			{Type: entity.EventCompaction, Compaction: &entity.CompactionInfo{
+				Reason: entity.CompactionReasonOverflow, Status: entity.CompactionCompleted, TokensBefore: 9000, TokensAfter: 1200}},
+			{Type: entity.EventTextDelta, Delta: "hi"},
+			{Type: entity.EventDone},
+		}
+	}
+	const streamBody = `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`
+	wantComments := []string{
+		": compaction started reason=overflow tokens_before=9000\n\n",
+		": compaction completed reason=overflow tokens_before=9000 tokens_after=1200\n\n",
+	}
+
+	for _, includeStatus := range []bool{false, true} {
+		t.Run(fmt.Sprintf("include status %v", includeStatus), func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			headers := map[string]string{}
+			if includeStatus {
+				headers[headerIncludeStatus] = "true"
+			}
+			body := postChat(context.Background(), g, streamBody, headers).Body.String()
+			for _, c := range wantComments {
+				if !strings.Contains(body, c) {
+					t.Fatalf("body lacks comment %q:\n%s", c, body)
+				}
+			}
+
+			var statuses []StatusChunk
+			for _, chunk := range sseChunks(t, body) {
+				for _, choice := range chunk.Choices {
+					if choice.Delta != nil && choice.Delta.Status != nil {
+						statuses = append(statuses, *choice.Delta.Status)
+					}
+				}
+			}
+			if !includeStatus {
+				if len(statuses) != 0 {
+					t.Fatalf("status deltas without %s: %+v", headerIncludeStatus, statuses)
+				}
+				return
+			}
+			want := []StatusChunk{
+				{Type: "compaction", State: "started", Reason: "overflow", TokensBefore: 9000},
+				{Type: "compaction", State: "completed", Reason: "overflow", TokensBefore: 9000, TokensAfter: 1200},
+			}
+			if !slices.Equal(statuses, want) {
+				t.Fatalf("statuses = %+v, want %+v", statuses, want)
+			}
+		})
+	}
+}
+
+func TestSeedForwarded(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *int
+	}{
+		{"no seed", helloBody, nil},
+		{"seed", `{"messages":[{"role":"user","content":"hello"}],"seed":42}`, intPtr(42)},
+		{"zero seed", `{"messages":[{"role":"user","content":"hello"}],"seed":0}`, intPtr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents("hi")}
+			g, _ := newChatEngine(svc)
+			if w := postChat(context.Background(), g, tt.body, nil); w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			overrides := svc.runs[0].LLMOverrides
+			var got *int
+			if overrides != nil {
+				got = overrides.Seed
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("seed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopSequences(t *testing.T) {
+	tests := []struct {
+		name       string
+		stop       string
+		wantStatus int
+		want       []string
+	}{
+		{"string", `"END"`, http.StatusOK, []string{"END"}},
+		{"four", `["a","b","c","d"]`, http.StatusOK, []string{"a", "b", "c", "d"}},
+		{"too many", `["a","b","c","d","e"]`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents("hi")}
+			g, _ := newChatEngine(svc)
+			body := `{"messages":[{"role":"user","content":"hello"}],"stop":` + tt.stop + `}`
+			w := postChat(context.Background(), g, body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if n := svc.runCount(); n != 0 {
+					t.Fatalf("%d runs for a rejected request", n)
+				}
+				return
+			}
+			if got := svc.runs[0].LLMOverrides; got == nil || !slices.Equal(got.Stop, tt.want) {
+				t.Fatalf("overrides = %+v, want stop %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinishReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*entity.AgentEvent
+		want   string
+	}{
+		{"stop", []*entity.AgentEvent{{Type: entity.EventDone}}, "stop"},
+		{"max turns", []*entity.AgentEvent{{Type: entity.EventDone, FinishReason: entity.FinishReasonMaxTurns}}, "length"},
+		{"length", []*entity.AgentEvent{{Type: entity.EventDone, FinishReason: entity.FinishReasonLength}}, "length"},
+		{"max turns status before done", []*entity.AgentEvent{
+			{Type: entity.EventRunStatus, RunStatus: entity.RunStatusCompleted, FinishReason: entity.FinishReasonMaxTurns},
+			{Type: entity.EventDone}}, "length"},
+		{"content filter", []*entity.AgentEvent{{Type: entity.EventDone, FinishReason: entity.FinishReasonContentFilter}}, "content_filter"},
+		{"failed run", []*entity.AgentEvent{{Type: entity.EventRunStatus, RunStatus: entity.RunStatusFailed,
+			Error: "model unavailable", FinishReason: entity.FinishReasonError}}, "error"},
+	}
+	for _, tt := range tests {
+		events := func(*runtime.RunRequest) []*entity.AgentEvent {
+			return append([]*entity.AgentEvent{{Type: entity.EventTextDelta, Delta: "partial"}}, tt.events...)
+		}
+		t.Run(tt.name+"/json", func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			w := postChat(context.Background(), g, helloBody, nil)
+			var resp ChatCompletionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Choices) != 1 {
+				t.Fatalf("status %d, body %s: %v", w.Code, w.Body, err)
+			}
+			if got := resp.Choices[0].FinishReason; got != tt.want {
+				t.Fatalf("finish_reason = %q, want %q", got, tt.want)
+			}
+		})
+		t.Run(tt.name+"/stream", func(t *testing.T) {
+			g, _ := newChatEngine(&fakeAgentService{events: events})
+			body := postChat(context.Background(), g, `{"stream":true,"messages":[{"role":"user","content":"hello"}]}`, nil).Body.String()
+			var got []string
+			for _, chunk := range sseChunks(t, body) {
+				for _, choice := range chunk.Choices {
+					if choice.FinishReason != nil {
+						got = append(got, *choice.FinishReason)
+					}
+				}
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("finish reasons = %q, want [%s]:\n%s", got, tt.want, body)
+			}
+		})
+	}
+}
+
+func TestFailedRunWithoutOutput(t *testing.T) {
+	g, _ := newChatEngine(&fakeAgentService{events: func(*runtime.RunRequest) []*entity.AgentEvent {
+		return []*entity.AgentEvent{{Type: entity.EventRunStatus, RunStatus: entity.RunStatusFailed,
+			Error: "model unavailable", FinishReason: entity.FinishReasonError}}
+	}})
+	w := postChat(context.Background(), g, helloBody, nil)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"code":100106`) {
+		t.Fatalf("status = %d, body %s; want the non-stream result error", w.Code, w.Body)
+	}
+}
+
+// abilityModelManager resolves every model ref to a model with ability and
+// compat rules.
+type abilityModelManager struct {
+	llmService.ModelManager
+	ability llmEntity.ModelAbility
+	compat  llmEntity.ModelCompatConfig
+}
+
+func (m *abilityModelManager) ResolveCompat(context.Context, llmEntity.ModelRef) (*llmEntity.ModelCompatConfig, error) {
+	return &m.compat, nil
+}
+
+func (m *abilityModelManager) GetModelByRef(_ context.Context, ref llmEntity.ModelRef) (*llmEntity.ModelInstance, error) {
+	return &llmEntity.ModelInstance{ProviderID: ref.ProviderID, ModelID: ref.ModelID, Capability: m.ability}, nil
+}
+
+func TestImageInput(t *testing.T) {
+	const body = `{"messages":[{"role":"user","content":[
+		{"type":"text","text":"what is this?"},
+		{"type":"image_url","image_url":{"url":"https://example.com/cat.png","detail":"low"}}]}]}`
+	tests := []struct {
+		name       string
+		ability    llmEntity.ModelAbility
+		wantStatus int
+	}{
+		{"vision model", llmEntity.ModelAbility{ImageUnderstanding: true}, http.StatusOK},
+		{"text-only model", llmEntity.ModelAbility{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents("a cat"), model: llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}}
+			h := NewChatCompletionsHandler(svc, &abilityModelManager{ability: tt.ability}, "main", "Echoryn")
+			g := gin.New()
+			g.POST("/v1/chat/completions", h.Handle)
+
+			w := postChat(context.Background(), g, body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(w.Body.String(), fmt.Sprintf(`"code":%d`, ErrImageInput)) || svc.runCount() != 0 {
+					t.Fatalf("body %s, runs = %d; want ErrImageInput and no run", w.Body, svc.runCount())
+				}
+				return
+			}
+			if svc.runCount() != 1 {
+				t.Fatalf("runs = %d, want 1", svc.runCount())
+			}
+			req := svc.runs[0]
+			want := []*entity.ImageContent{{URL: "https://example.com/cat.png", Detail: "low"}}
+			if req.Input != "what is this?" || len(req.Images) != 1 || *req.Images[0] != *want[0] {
+				t.Fatalf("run input %q, images %+v; want the text and image parts", req.Input, req.Images)
+			}
+		})
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	noJSON := false
+	tests := []struct {
+		name       string
+		format     string
+		compat     llmEntity.ModelCompatConfig
+		wantStatus int
+		wantCode   int
+		want       llmEntity.ModelResponseFormat
+	}{
+		{"text", `{"type":"text"}`, llmEntity.ModelCompatConfig{}, http.StatusOK, 0, llmEntity.ModelResponseFormatText},
+		{"json object", `{"type":"json_object"}`, llmEntity.ModelCompatConfig{}, http.StatusOK, 0, llmEntity.ModelResponseFormatJSON},
+		{"json schema", `{"type":"json_schema","json_schema":{"name":"answer","schema":{"type":"object"},"strict":true}}`,
+			llmEntity.ModelCompatConfig{}, http.StatusOK, 0, llmEntity.ModelResponseFormatJSONSchema},
+		{"json schema without schema", `{"type":"json_schema","json_schema":{"name":"answer"}}`,
+			llmEntity.ModelCompatConfig{}, http.StatusBadRequest, ErrValidation, 0},
+		{"unknown type", `{"type":"yaml"}`, llmEntity.ModelCompatConfig{}, http.StatusBadRequest, ErrValidation, 0},
+		{"model without json mode", `{"type":"json_object"}`, llmEntity.ModelCompatConfig{SupportsJSONMode: &noJSON},
+			http.StatusBadRequest, ErrResponseFormat, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeAgentService{events: replyEvents(`{}`), model: llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}}
+			h := NewChatCompletionsHandler(svc, &abilityModelManager{compat: tt.compat}, "main", "Echoryn")
+			g := gin.New()
+			g.POST("/v1/chat/completions", h.Handle)
+
+			body := fmt.Sprintf(`{"response_format":%s,"messages":[{"role":"user","content":"hello"}]}`, tt.format)
+			w := postChat(context.Background(), g, body, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(w.Body.String(), fmt.Sprintf(`"code":%d`, tt.wantCode)) || svc.runCount() != 0 {
+					t.Fatalf("body %s, runs = %d; want code %d and no run", w.Body, svc.runCount(), tt.wantCode)
+				}
+				return
+			}
+			var got llmEntity.ModelResponseFormat
+			if o := svc.runs[0].LLMOverrides; o != nil {
+				got = o.ResponseFormat
+				if tt.want == llmEntity.ModelResponseFormatJSONSchema &&
+					(o.JSONSchema == nil || o.JSONSchema.Name != "answer" || !o.JSONSchema.Strict || string(o.JSONSchema.Schema) != `{"type":"object"}`) {
+					t.Fatalf("json schema = %+v", o.JSONSchema)
+				}
+			}
+			if got != tt.want {
+				t.Fatalf("response format = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
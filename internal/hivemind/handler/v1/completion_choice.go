@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"strings"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+)
+
+// completionChoice accumulates the output of one choice of a run.
+type completionChoice struct {
+	content     strings.Builder
+	toolCalls   []ToolCallChunk
+	toolResults []ToolResultChunk
+	usage       *entity.TokenUsage // latest cumulative usage of the choice
+	finish      entity.FinishReason
+	lastErr     string
+	done        bool // the choice finished, or failed (streaming)
+}
+
+// reset drops the output of a model attempt that failed mid-stream.
+func (ch *completionChoice) reset() {
+	ch.content.Reset()
+	ch.toolCalls = nil
+	ch.toolResults = nil
+}
+
+// finishReason is the OpenAI finish reason of a collected choice: "stop",
+// "tool_calls" when the choice ended on tool calls, or the mapped run
+// finish reason.
+func (ch *completionChoice) finishReason() string {
+	if ch.finish != "" {
+		return toOpenAIFinishReason(ch.finish)
+	}
+	if len(ch.toolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// streamFinishReason is the finish reason of a streamed choice: "stop"
+// unless the choice ended early.
+func (ch *completionChoice) streamFinishReason() string {
+	if ch.finish != "" {
+		return toOpenAIFinishReason(ch.finish)
+	}
+	return "stop"
+}
+
+// failed reports whether the choice failed without producing output. A
+// choice stopped at max turns is not failed.
+func (ch *completionChoice) failed() bool {
+	return ch.lastErr != "" && ch.content.Len() == 0 &&
+		(ch.finish == "" || ch.finish == entity.FinishReasonError)
+}
+
+// completionChoices holds the choices of a run, by index.
+type completionChoices []*completionChoice
+
+func newCompletionChoices(n int) completionChoices {
+	choices := make(completionChoices, n)
+	for i := range choices {
+		choices[i] = &completionChoice{}
+	}
+	return choices
+}
+
+// of returns the index and accumulator of event's choice. Events of an
+// unknown choice are attributed to choice 0.
+func (cs completionChoices) of(event *entity.AgentEvent) (int, *completionChoice) {
+	if event.Choice > 0 && event.Choice < len(cs) {
+		return event.Choice, cs[event.Choice]
+	}
+	return 0, cs[0]
+}
+
+// collect adds event to the output of its choice, as a non-streaming
+// response accumulates it.
+func (cs completionChoices) collect(event *entity.AgentEvent, includeToolResults bool) {
+	_, choice := cs.of(event)
+	switch event.Type {
+	case entity.EventTextDelta:
+		choice.content.WriteString(event.Delta)
+
+	case entity.EventToolCallStart:
+		if event.ToolCall != nil {
+			choice.toolCalls = append(choice.toolCalls, ToolCallChunk{
+				Index: len(choice.toolCalls),
+				ID:    event.ToolCall.ID,
+				Type:  "function",
+				Function: ToolCallFunction{
+					Name:      event.ToolCall.Name,
+					Arguments: event.ToolCall.Arguments,
+				},
+			})
+		}
+
+	case entity.EventToolCallEnd:
+		if includeToolResults && event.ToolResult != nil {
+			choice.toolResults = append(choice.toolResults, toToolResultChunk(event.ToolResult))
+		}
+
+	case entity.EventDone:
+		choice.done = true
+		if event.Usage != nil {
+			choice.usage = event.Usage
+		}
+		if event.FinishReason != "" {
+			choice.finish = event.FinishReason
+		}
+
+	case entity.EventUsageDelta:
+		if event.TotalUsage != nil {
+			choice.usage = event.TotalUsage
+		}
+
+	case entity.EventModelSwitch:
+		// Drop the output of the attempt that failed mid-stream.
+		choice.reset()
+
+	case entity.EventRunStatus:
+		if event.FinishReason != "" {
+			choice.finish = event.FinishReason
+		}
+		if event.RunStatus == entity.RunStatusFailed && event.Error != "" {
+			choice.lastErr = event.Error
+		}
+
+	case entity.EventError:
+		choice.lastErr = event.Error
+	}
+}
+
+// failure returns the error of the first choice when every choice failed
+// without output, and "" otherwise.
+func (cs completionChoices) failure() string {
+	for _, ch := range cs {
+		if !ch.failed() {
+			return ""
+		}
+	}
+	return cs[0].lastErr
+}
+
+// anyFailed reports whether a collected choice ended with finish reason "error".
+func (cs completionChoices) anyFailed() bool {
+	for _, ch := range cs {
+		if ch.finishReason() == string(entity.FinishReasonError) {
+			return true
+		}
+	}
+	return false
+}
+
+// completed reports whether every choice finished without error, so that
+// the result may be replayed to Idempotency-Key retries.
+func (cs completionChoices) completed() bool {
+	for _, ch := range cs {
+		if !ch.done || ch.streamFinishReason() == string(entity.FinishReasonError) {
+			return false
+		}
+	}
+	return true
+}
+
+// streamedResponse is the completion a streamed run amounts to, as kept for
+// Idempotency-Key retries.
+func (cs completionChoices) streamedResponse(id, model string, created int64) *ChatCompletionResponse {
+	resp := &ChatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   model,
+		Choices: make([]ChatCompletionChoice, 0, len(cs)),
+		Usage:   cs.usage(),
+	}
+	for i, choice := range cs {
+		resp.Choices = append(resp.Choices, ChatCompletionChoice{
+			Index:        i,
+			Message:      &ChatMessage{Role: "assistant", Content: choice.content.String(), ToolCalls: choice.toolCalls},
+			FinishReason: choice.streamFinishReason(),
+		})
+	}
+	return resp
+}
+
+// usage sums the token usage of all choices; nil when none reported usage.
+func (cs completionChoices) usage() *ChatCompletionUsage {
+	var total *entity.TokenUsage
+	for _, ch := range cs {
+		if ch.usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &entity.TokenUsage{}
+		}
+		total.PromptTokens += ch.usage.PromptTokens
+		total.CompletionTokens += ch.usage.CompletionTokens
+		total.TotalTokens += ch.usage.TotalTokens
+	}
+	if total == nil {
+		return nil
+	}
+	return toChatCompletionUsage(total)
+}
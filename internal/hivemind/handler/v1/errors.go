@@ -9,7 +9,7 @@ import (
 // Hivemind handler error codes.
 // Code format: 1XXYYZ
 //   - 1:  module prefix (hivemind handler)
-//   - XX: resource group (00=common, 01=chat, 02=agent, 03=session, 04=model)
+//   - XX: resource group (00=common, 01=chat, 02=agent, 03=session, 04=model, 05=memory)
 //   - YY: sequential error number
 //   - Z:  reserved (0)
 
@@ -19,26 +19,37 @@ const (
 	ErrValidation = 100002
 
 	// Chat completions errors (1001xx).
-	ErrMessagesEmpty   = 100101
-	ErrNoUserMessage   = 100102
-	ErrEnsureAgent     = 100103
-	ErrAgentRun        = 100104
-	ErrStreamRecv      = 100105
-	ErrNonStreamResult = 100106
+	ErrMessagesEmpty       = 100101
+	ErrNoUserMessage       = 100102
+	ErrEnsureAgent         = 100103
+	ErrAgentRun            = 100104
+	ErrStreamRecv          = 100105
+	ErrNonStreamResult     = 100106
+	ErrResponseFormat      = 100107
+	ErrImageInput          = 100108
+	ErrIdempotencyMismatch = 100109
 
 	// Agent errors (1002xx).
 	ErrAgentNotFound = 100201
 	ErrAgentCreate   = 100202
 	ErrAgentList     = 100203
 	ErrAgentDelete   = 100204
+	ErrAgentModel    = 100205
+	ErrPromptPreview = 100206
+	ErrAgentUpdate   = 100207
 
 	// Session errors (1003xx).
 	ErrSessionNotFound = 100301
 	ErrSessionList     = 100302
 	ErrSessionDelete   = 100303
+	ErrSessionBusy     = 100304
 
 	// Model errors (1004xx).
 	ErrModelList = 100401
+	ErrModelScan = 100402
+
+	// Memory errors (1005xx).
+	ErrMemoryUnavailable = 100501
 )
 
 func init() {
@@ -53,20 +64,31 @@ func init() {
 	errorx.MustRegister(newCoder(ErrAgentRun, http.StatusInternalServerError, "Agent run failed"))
 	errorx.MustRegister(newCoder(ErrStreamRecv, http.StatusInternalServerError, "Stream receive error"))
 	errorx.MustRegister(newCoder(ErrNonStreamResult, http.StatusInternalServerError, "Non-stream result error"))
+	errorx.MustRegister(newCoder(ErrResponseFormat, http.StatusBadRequest, "Model does not support the requested response format"))
+	errorx.MustRegister(newCoder(ErrImageInput, http.StatusBadRequest, "Model does not support image input"))
+	errorx.MustRegister(newCoder(ErrIdempotencyMismatch, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request"))
 
 	// Agent.
 	errorx.MustRegister(newCoder(ErrAgentNotFound, http.StatusNotFound, "Agent not found"))
 	errorx.MustRegister(newCoder(ErrAgentCreate, http.StatusInternalServerError, "Failed to create agent"))
 	errorx.MustRegister(newCoder(ErrAgentList, http.StatusInternalServerError, "Failed to list agents"))
 	errorx.MustRegister(newCoder(ErrAgentDelete, http.StatusInternalServerError, "Failed to delete agent"))
+	errorx.MustRegister(newCoder(ErrAgentModel, http.StatusBadRequest, "Agent model does not support required capabilities"))
+	errorx.MustRegister(newCoder(ErrPromptPreview, http.StatusInternalServerError, "Failed to preview agent prompt"))
+	errorx.MustRegister(newCoder(ErrAgentUpdate, http.StatusInternalServerError, "Failed to update agent"))
 
 	// Session.
 	errorx.MustRegister(newCoder(ErrSessionNotFound, http.StatusNotFound, "Session not found"))
 	errorx.MustRegister(newCoder(ErrSessionList, http.StatusInternalServerError, "Failed to list sessions"))
 	errorx.MustRegister(newCoder(ErrSessionDelete, http.StatusInternalServerError, "Failed to delete session"))
+	errorx.MustRegister(newCoder(ErrSessionBusy, http.StatusTooManyRequests, "Session has a run in progress"))
 
 	// Model.
 	errorx.MustRegister(newCoder(ErrModelList, http.StatusInternalServerError, "Failed to list models"))
+	errorx.MustRegister(newCoder(ErrModelScan, http.StatusInternalServerError, "Failed to scan models"))
+
+	// Memory.
+	errorx.MustRegister(newCoder(ErrMemoryUnavailable, http.StatusServiceUnavailable, "Memory plugin is not available"))
 }
 
 type coder struct {
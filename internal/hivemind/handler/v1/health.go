@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+	"github.com/kiosk404/echoryn/internal/pkg/core"
+)
+
+// Readiness states reported by GET /readyz.
+const (
+	ReadinessReady    = "ready"
+	ReadinessDegraded = "degraded"
+)
+
+// HealthHandler handles the readiness endpoint.
+// Process liveness is served separately by the generic API server's /healthz.
+type HealthHandler struct {
+	plugins    *plugin.Framework       // nil when the plugin framework is not initialized
+	llmManager llmService.ModelManager // nil when the LLM module is not configured
+	mcpManager mcp.Manager             // nil when MCP is not configured
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(plugins *plugin.Framework, llmManager llmService.ModelManager, mcpManager mcp.Manager) *HealthHandler {
+	return &HealthHandler{plugins: plugins, llmManager: llmManager, mcpManager: mcpManager}
+}
+
+// Ready handles GET /readyz.
+// It answers 200 when every dependency is healthy and 503 with the same body
+// when any of them is degraded, so readiness probes and load balancers take
+// the instance out of rotation. The gateway itself keeps serving requests
+// that reach it while degraded (a down MCP server only removes its tools from
+// runs, a degraded plugin still answers).
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx := c.Request.Context()
+	resp := ReadinessResponse{Status: ReadinessReady}
+
+	if h.plugins != nil {
+		for _, ph := range h.plugins.Health(ctx) {
+			health := PluginHealth{Name: ph.Name, Status: ReadinessReady}
+			if ph.Err != nil {
+				resp.Status = ReadinessDegraded
+				health.Status = ReadinessDegraded
+				health.Reason = ph.Err.Error()
+			}
+			resp.Plugins = append(resp.Plugins, health)
+		}
+	}
+
+	if h.llmManager != nil {
+		resp.DefaultModel = &DefaultModelHealth{Status: ReadinessReady}
+		model, err := h.llmManager.GetDefaultModel(ctx)
+		switch {
+		case err != nil:
+			resp.DefaultModel.Status = ReadinessDegraded
+			resp.DefaultModel.Reason = err.Error()
+		case model.Status != llmEntity.ModelStatus_Ready:
+			resp.DefaultModel.Status = ReadinessDegraded
+			resp.DefaultModel.Reason = "model status is " + model.Status.String()
+		}
+		if model != nil {
+			resp.DefaultModel.Model = llmEntity.ModelRef{ProviderID: model.ProviderID, ModelID: model.ModelID}.String()
+		}
+		if resp.DefaultModel.Status != ReadinessReady {
+			resp.Status = ReadinessDegraded
+		}
+	}
+
+	if h.mcpManager != nil {
+		for _, sh := range h.mcpManager.Health() {
+			if sh.Status != mcp.ServerStatusConnected {
+				resp.Status = ReadinessDegraded
+			}
+			srv := MCPServerHealth{
+				Name:                sh.Name,
+				Status:              sh.Status.String(),
+				Reason:              sh.Reason,
+				Reconnecting:        sh.Reconnecting,
+				ToolCount:           sh.ToolCount,
+				ConsecutiveFailures: sh.ConsecutiveFailures,
+			}
+			for _, tb := range sh.ToolBreakers {
+				b := MCPToolBreaker{
+					Tool:                tb.Tool,
+					State:               string(tb.State),
+					ConsecutiveFailures: tb.ConsecutiveFailures,
+				}
+				if !tb.OpenUntil.IsZero() {
+					b.OpenUntil = FormatTime(tb.OpenUntil)
+				}
+				srv.ToolBreakers = append(srv.ToolBreakers, b)
+			}
+			resp.MCPServers = append(resp.MCPServers, srv)
+		}
+	}
+	if resp.Status != ReadinessReady {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	core.WriteResponse(c, nil, resp)
+}
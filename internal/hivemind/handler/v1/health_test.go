@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
+)
+
+// defaultModelManager answers GetDefaultModel with model and err.
+type defaultModelManager struct {
+	llmService.ModelManager
+	model *llmEntity.ModelInstance
+	err   error
+}
+
+func (m *defaultModelManager) GetDefaultModel(context.Context) (*llmEntity.ModelInstance, error) {
+	return m.model, m.err
+}
+
+// healthMCPManager reports the given server health.
+type healthMCPManager struct {
+	mcp.Manager
+	servers []mcp.ServerHealth
+}
+
+func (m *healthMCPManager) Health() []mcp.ServerHealth { return m.servers }
+
+func TestReadyStatusCode(t *testing.T) {
+	readyModel := &llmEntity.ModelInstance{ProviderID: "openai", ModelID: "gpt-4o", Status: llmEntity.ModelStatus_Ready}
+	tests := []struct {
+		name       string
+		llm        llmService.ModelManager
+		mcp        mcp.Manager
+		wantCode   int
+		wantStatus string
+	}{
+		{"nothing configured", nil, nil, http.StatusOK, ReadinessReady},
+		{"all healthy", &defaultModelManager{model: readyModel},
+			&healthMCPManager{servers: []mcp.ServerHealth{{Name: "fs", Status: mcp.ServerStatusConnected}}},
+			http.StatusOK, ReadinessReady},
+		{"default model missing", &defaultModelManager{err: errors.New("no default model")}, nil,
+			http.StatusServiceUnavailable, ReadinessDegraded},
+		{"default model cooling down",
+			&defaultModelManager{model: &llmEntity.ModelInstance{ProviderID: "openai", ModelID: "gpt-4o", Status: llmEntity.ModelStatus_CoolDown}}, nil,
+			http.StatusServiceUnavailable, ReadinessDegraded},
+		{"mcp server down", &defaultModelManager{model: readyModel},
+			&healthMCPManager{servers: []mcp.ServerHealth{{Name: "fs", Status: mcp.ServerStatusError, Reason: "connection refused"}}},
+			http.StatusServiceUnavailable, ReadinessDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HealthHandler{llmManager: tt.llm, mcpManager: tt.mcp}
+			r := gin.New()
+			r.GET("/readyz", h.Ready)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var resp ReadinessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("body %q: %v", w.Body.String(), err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q (body %s)", resp.Status, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// headerIdempotencyKey makes a chat completion retry-safe: a request repeating
+// the key of an earlier one in the same session gets the earlier result
+// instead of starting a new run. Reusing a key with a different request body
+// is rejected with 422.
+const headerIdempotencyKey = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long a completed result is kept for retries.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// errIdempotencyMismatch is returned by begin when a key is reused with a
+// different request.
+var errIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// idempotencyStore keeps chat completion results by idempotency key.
+// A key is reserved while its run is in flight; concurrent retries wait
+// for that run instead of starting their own.
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	// expiring lists completed entries in completion order, which with a
+	// fixed ttl is also expiry order.
+	expiring []idempotencyExpiry
+}
+
+type idempotencyEntry struct {
+	fingerprint string        // requestFingerprint of the request that reserved the key
+	done        chan struct{} // closed once the run completed or was abandoned
+	resp        *ChatCompletionResponse
+	expires     time.Time // zero while in flight
+}
+
+type idempotencyExpiry struct {
+	key   string
+	entry *idempotencyEntry
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{ttl: ttl, now: time.Now, entries: make(map[string]*idempotencyEntry)}
+}
+
+// requestFingerprint identifies the request a key was first sent with.
+func requestFingerprint(req *ChatCompletionRequest) string {
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// begin looks up key. It returns the cached response of a completed run, or
+// reserves the key and returns a reservation the caller must complete or
+// abandon. If the key is in flight, begin waits for that run first. A key
+// already used with another fingerprint yields errIdempotencyMismatch.
+func (s *idempotencyStore) begin(ctx context.Context, key, fingerprint string) (*idempotencyReservation, *ChatCompletionResponse, error) {
+	for {
+		s.mu.Lock()
+		s.sweep(s.now())
+		entry, ok := s.entries[key]
+		if !ok {
+			entry = &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.entries[key] = entry
+			s.mu.Unlock()
+			return &idempotencyReservation{store: s, key: key, entry: entry}, nil, nil
+		}
+		if entry.fingerprint != fingerprint {
+			s.mu.Unlock()
+			return nil, nil, errIdempotencyMismatch
+		}
+		if entry.resp != nil {
+			s.mu.Unlock()
+			return nil, entry.resp, nil
+		}
+		s.mu.Unlock()
+
+		// In flight: wait, then re-check (the run may have been abandoned).
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// sweep drops expired results, oldest first. Called with s.mu held.
+func (s *idempotencyStore) sweep(now time.Time) {
+	for len(s.expiring) > 0 && now.After(s.expiring[0].entry.expires) {
+		e := s.expiring[0]
+		s.expiring[0] = idempotencyExpiry{}
+		s.expiring = s.expiring[1:]
+		if s.entries[e.key] == e.entry {
+			delete(s.entries, e.key)
+		}
+	}
+}
+
+// idempotencyReservation is an in-flight idempotency key.
+// A nil reservation (no key sent) is valid and does nothing.
+type idempotencyReservation struct {
+	store *idempotencyStore
+	key   string
+	entry *idempotencyEntry
+	ended bool
+}
+
+// complete stores the run's result for retries of the key.
+func (r *idempotencyReservation) complete(resp *ChatCompletionResponse) {
+	if r == nil || r.ended {
+		return
+	}
+	r.ended = true
+	r.store.mu.Lock()
+	r.entry.resp = resp
+	r.entry.expires = r.store.now().Add(r.store.ttl)
+	r.store.expiring = append(r.store.expiring, idempotencyExpiry{key: r.key, entry: r.entry})
+	r.store.mu.Unlock()
+	close(r.entry.done)
+}
+
+// abandon releases the key without a result, so that a retry runs again.
+// It is a no-op after complete.
+func (r *idempotencyReservation) abandon() {
+	if r == nil || r.ended {
+		return
+	}
+	r.ended = true
+	r.store.mu.Lock()
+	if r.store.entries[r.key] == r.entry {
+		delete(r.store.entries, r.key)
+	}
+	r.store.mu.Unlock()
+	close(r.entry.done)
+}
+
+// transfer hands the reservation to a new owner, e.g. a goroutine that
+// finishes the run after the client left; r itself becomes a no-op.
+// It returns nil for a nil or ended reservation.
+func (r *idempotencyReservation) transfer() *idempotencyReservation {
+	if r == nil || r.ended {
+		return nil
+	}
+	r.ended = true
+	return &idempotencyReservation{store: r.store, key: r.key, entry: r.entry}
+}
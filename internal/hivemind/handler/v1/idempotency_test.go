@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStoreReplay(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+
+	r, cached, err := s.begin(ctx, "k", "fp")
+	if err != nil || r == nil || cached != nil {
+		t.Fatalf("first begin = %v, %v, %v", r, cached, err)
+	}
+	resp := &ChatCompletionResponse{ID: "chatcmpl-1"}
+	r.complete(resp)
+
+	r2, cached, err := s.begin(ctx, "k", "fp")
+	if err != nil || r2 != nil || cached != resp {
+		t.Fatalf("retry = %v, %v, %v; want the stored response", r2, cached, err)
+	}
+}
+
+func TestIdempotencyStoreMismatch(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+
+	r, _, _ := s.begin(ctx, "k", "fp")
+	if _, _, err := s.begin(ctx, "k", "other"); !errors.Is(err, errIdempotencyMismatch) {
+		t.Fatalf("in-flight reuse: %v, want errIdempotencyMismatch", err)
+	}
+	r.complete(&ChatCompletionResponse{})
+	if _, _, err := s.begin(ctx, "k", "other"); !errors.Is(err, errIdempotencyMismatch) {
+		t.Fatalf("completed reuse: %v, want errIdempotencyMismatch", err)
+	}
+}
+
+func TestIdempotencyStoreWaitsForInFlight(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+	r, _, _ := s.begin(ctx, "k", "fp")
+
+	got := make(chan *ChatCompletionResponse)
+	go func() {
+		_, cached, _ := s.begin(ctx, "k", "fp")
+		got <- cached
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("retry returned while the first run was in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	resp := &ChatCompletionResponse{ID: "chatcmpl-1"}
+	r.complete(resp)
+	if cached := <-got; cached != resp {
+		t.Fatalf("retry got %v, want the first run's response", cached)
+	}
+
+	// A waiting retry gives up with its context.
+	r2, _, _ := s.begin(ctx, "k2", "fp")
+	defer r2.abandon()
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := s.begin(cctx, "k2", "fp"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled wait: %v", err)
+	}
+}
+
+func TestIdempotencyStoreAbandon(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+
+	r, _, _ := s.begin(ctx, "k", "fp")
+	r.abandon()
+	r.complete(&ChatCompletionResponse{}) // no-op after abandon
+
+	r2, cached, err := s.begin(ctx, "k", "fp")
+	if err != nil || r2 == nil || cached != nil {
+		t.Fatalf("begin after abandon = %v, %v, %v; want a new reservation", r2, cached, err)
+	}
+}
+
+func TestIdempotencyReservationTransfer(t *testing.T) {
+	s := newIdempotencyStore(time.Hour)
+	ctx := context.Background()
+
+	r, _, _ := s.begin(ctx, "k", "fp")
+	owner := r.transfer()
+	if owner == nil || r.transfer() != nil {
+		t.Fatal("transfer must hand over the reservation exactly once")
+	}
+	r.abandon() // the old owner no longer releases the key
+	if _, ok := s.entries["k"]; !ok {
+		t.Fatal("abandon of a transferred reservation released the key")
+	}
+	owner.complete(&ChatCompletionResponse{ID: "x"})
+	if _, cached, _ := s.begin(ctx, "k", "fp"); cached == nil || cached.ID != "x" {
+		t.Fatalf("cached = %v", cached)
+	}
+	if (*idempotencyReservation)(nil).transfer() != nil {
+		t.Fatal("nil reservation transferred")
+	}
+}
+
+func TestIdempotencyStoreSweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := newIdempotencyStore(time.Minute)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		r, _, _ := s.begin(ctx, key, "fp")
+		r.complete(&ChatCompletionResponse{ID: key})
+		now = now.Add(30 * time.Second)
+	}
+	inFlight, _, _ := s.begin(ctx, "c", "fp")
+	defer inFlight.abandon()
+
+	// "a" expired (60s after its completion); "b" has 30s left.
+	now = now.Add(time.Second)
+	if r, cached, _ := s.begin(ctx, "b", "fp"); r != nil || cached == nil {
+		t.Fatal("unexpired result swept")
+	}
+	if _, ok := s.entries["a"]; ok {
+		t.Fatal("expired result kept")
+	}
+	if _, ok := s.entries["c"]; !ok {
+		t.Fatal("in-flight key swept")
+	}
+	if len(s.expiring) != 1 {
+		t.Fatalf("expiry queue holds %d entries, want 1", len(s.expiring))
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	a := &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+	b := &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+	c := &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "bye"}}}
+	if requestFingerprint(a) != requestFingerprint(b) {
+		t.Fatal("equal requests have different fingerprints")
+	}
+	if requestFingerprint(a) == requestFingerprint(c) {
+		t.Fatal("different requests share a fingerprint")
+	}
+}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+	memory_core "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
+	"github.com/kiosk404/echoryn/internal/pkg/core"
+	"github.com/kiosk404/echoryn/pkg/errorx"
+)
+
+// memoryStatusSource is implemented by the memory-core plugin instance.
+// The concrete plugin type is unexported, so the handler reaches it through
+// the registry and this interface.
+type memoryStatusSource interface {
+	Manager() *manager.Manager
+	Config() *entity.MemoryConfig
+}
+
+// MemoryHandler handles memory diagnostic endpoints.
+type MemoryHandler struct {
+	plugins *plugin.Framework
+}
+
+// NewMemoryHandler creates a new MemoryHandler.
+func NewMemoryHandler(plugins *plugin.Framework) *MemoryHandler {
+	return &MemoryHandler{plugins: plugins}
+}
+
+// Status handles GET /v1/memory/status.
+// Returns the memory manager status plus a summary of the memory config.
+func (h *MemoryHandler) Status(c *gin.Context) {
+	src, ok := h.source(c)
+	if !ok {
+		return
+	}
+
+	cfg := src.Config()
+	resp := MemoryStatusResponse{
+		Plugin:       memory_core.PluginName,
+		Enabled:      cfg.Enabled,
+		WorkspaceDir: cfg.WorkspaceDir,
+		Chunking: MemoryChunkingSummary{
+			Tokens:                cfg.Chunking.Tokens,
+			Overlap:               cfg.Chunking.Overlap,
+			IncludeHeadingContext: cfg.Chunking.IncludeHeadingContext,
+		},
+		Hybrid: MemoryHybridSummary{
+			Enabled:             cfg.Query.Hybrid.Enabled,
+			VectorWeight:        cfg.Query.Hybrid.VectorWeight,
+			TextWeight:          cfg.Query.Hybrid.TextWeight,
+			CandidateMultiplier: cfg.Query.Hybrid.CandidateMultiplier,
+			Strategy:            string(cfg.Query.Hybrid.Strategy),
+			RRFK:                cfg.Query.Hybrid.RRFK,
+		},
+	}
+	// The manager is nil when the memory system is disabled.
+	if m := src.Manager(); m != nil {
+		status := m.Status()
+		resp.Status = &status
+	}
+
+	core.WriteResponse(c, nil, resp)
+}
+
+// Files handles GET /v1/memory/files.
+// Lists a page of the indexed memory files with their chunk counts, ordered
+// by path. The optional agent_id query parameter restricts the list to shared
+// files and that agent's own; offset and limit select the page (limit
+// defaults to manager.DefaultListFilesLimit, capped at
+// manager.MaxListFilesLimit).
+func (h *MemoryHandler) Files(c *gin.Context) {
+	src, ok := h.source(c)
+	if !ok {
+		return
+	}
+	m := src.Manager()
+	if m == nil {
+		core.WriteResponse(c, errorx.WithCode(ErrMemoryUnavailable, "memory system is disabled"), nil)
+		return
+	}
+
+	offset, err := queryNonNegativeInt(c, "offset")
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+	limit, err := queryNonNegativeInt(c, "limit")
+	if err != nil {
+		core.WriteResponse(c, err, nil)
+		return
+	}
+
+	files, total, err := m.ListFiles(c.Query("agent_id"), offset, limit)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrMemoryUnavailable, "list memory files"), nil)
+		return
+	}
+	core.WriteResponse(c, nil, MemoryFilesResponse{Files: files, Count: len(files), Total: total})
+}
+
+// source looks up the memory-core plugin, writing an error response and
+// returning false when it is unavailable.
+func (h *MemoryHandler) source(c *gin.Context) (memoryStatusSource, bool) {
+	if h.plugins == nil {
+		core.WriteResponse(c, errorx.WithCode(ErrMemoryUnavailable, "plugin framework is not initialized"), nil)
+		return nil, false
+	}
+	p, ok := h.plugins.Registry().GetPlugin(memory_core.PluginName)
+	if !ok {
+		core.WriteResponse(c, errorx.WithCode(ErrMemoryUnavailable, "plugin %q is not loaded", memory_core.PluginName), nil)
+		return nil, false
+	}
+	src, ok := p.(memoryStatusSource)
+	if !ok || src.Config() == nil {
+		core.WriteResponse(c, errorx.WithCode(ErrMemoryUnavailable, "plugin %q does not expose memory status", memory_core.PluginName), nil)
+		return nil, false
+	}
+	return src, true
+}
+
+// queryNonNegativeInt parses an optional non-negative integer query
+// parameter; 0 means it is absent.
+func queryNonNegativeInt(c *gin.Context, name string) (int, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errorx.WithCode(ErrValidation, "invalid %s %q: must be a non-negative integer", name, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,192 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+	memory_core "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
+)
+
+// fakeMemoryPlugin stands in for the memory-core plugin.
+type fakeMemoryPlugin struct {
+	cfg *entity.MemoryConfig
+	m   *manager.Manager
+}
+
+func (p *fakeMemoryPlugin) Name() string                 { return memory_core.PluginName }
+func (p *fakeMemoryPlugin) Manager() *manager.Manager    { return p.m }
+func (p *fakeMemoryPlugin) Config() *entity.MemoryConfig { return p.cfg }
+
+// newMemoryFramework returns a framework with p loaded; nil p loads nothing.
+func newMemoryFramework(t *testing.T, p plugin.Plugin) *plugin.Framework {
+	t.Helper()
+	fw := (&plugin.Config{}).Complete().New()
+	if p != nil {
+		factory := func(plugin.PluginArgs, plugin.Handle) (plugin.Plugin, error) { return p, nil }
+		if err := fw.RegisterFactory(plugin.Definition{ID: p.Name(), Name: p.Name()}, factory, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fw.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return fw
+}
+
+// serveMemory sends a GET request to the memory routes served from fw.
+func serveMemory(fw *plugin.Framework, path string) *httptest.ResponseRecorder {
+	h := NewMemoryHandler(fw)
+	r := gin.New()
+	r.GET("/v1/memory/status", h.Status)
+	r.GET("/v1/memory/files", h.Files)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+// newMemoryManager creates a manager over a temporary workspace holding
+// files (workspace-relative path -> content), embedding with a local stub of
+// the OpenAI embeddings API.
+func newMemoryManager(t *testing.T, files map[string]string) (*manager.Manager, *entity.MemoryConfig) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		resp := struct {
+			Data []item `json:"data"`
+		}{}
+		for i := range req.Input {
+			resp.Data = append(resp.Data, item{Index: i, Embedding: []float32{1, 0, 0}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := entity.DefaultMemoryConfig()
+	cfg.WorkspaceDir = t.TempDir()
+	cfg.Embedding.Remote = &entity.RemoteEmbeddingConfig{APIKey: "test", BaseURL: srv.URL}
+	cfg.Sync.Watch = false
+	cfg.Sync.WriteDebounceMs = 0
+	for rel, content := range files {
+		abs := filepath.Join(cfg.WorkspaceDir, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := manager.Get(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	if err := m.Sync(context.Background(), manager.SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	return m, cfg
+}
+
+func TestMemoryStatus(t *testing.T) {
+	disabled := entity.DefaultMemoryConfig()
+	disabled.Enabled = false
+	disabled.WorkspaceDir = "/srv/workspace"
+
+	tests := []struct {
+		name     string
+		fw       *plugin.Framework
+		wantCode int
+	}{
+		{"no framework", nil, http.StatusServiceUnavailable},
+		{"plugin not loaded", newMemoryFramework(t, nil), http.StatusServiceUnavailable},
+		{"plugin without config", newMemoryFramework(t, &fakeMemoryPlugin{}), http.StatusServiceUnavailable},
+		{"disabled", newMemoryFramework(t, &fakeMemoryPlugin{cfg: disabled}), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveMemory(tt.fw, "/v1/memory/status")
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+
+	w := serveMemory(newMemoryFramework(t, &fakeMemoryPlugin{cfg: disabled}), "/v1/memory/status")
+	var resp MemoryStatusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Plugin != memory_core.PluginName || resp.Enabled || resp.Status != nil ||
+		resp.WorkspaceDir != "/srv/workspace" || resp.Chunking.Tokens != disabled.Chunking.Tokens {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestMemoryFiles(t *testing.T) {
+	m, cfg := newMemoryManager(t, map[string]string{
+		"MEMORY.md":                "# Memory\n\nShared facts.\n",
+		"memory/a.md":              "# A\n\nNotes.\n",
+		"memory/agents/bot/x.md":   "# X\n\nBot notes.\n",
+		"memory/agents/other/y.md": "# Y\n\nOther notes.\n",
+	})
+	fw := newMemoryFramework(t, &fakeMemoryPlugin{cfg: cfg, m: m})
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantPaths []string
+		wantTotal int
+	}{
+		{"all", "", http.StatusOK, []string{"MEMORY.md", "memory/a.md", "memory/agents/bot/x.md", "memory/agents/other/y.md"}, 4},
+		{"agent", "?agent_id=bot", http.StatusOK, []string{"MEMORY.md", "memory/a.md", "memory/agents/bot/x.md"}, 3},
+		{"page", "?offset=1&limit=2", http.StatusOK, []string{"memory/a.md", "memory/agents/bot/x.md"}, 4},
+		{"negative offset", "?offset=-1", http.StatusBadRequest, nil, 0},
+		{"bad limit", "?limit=ten", http.StatusBadRequest, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveMemory(fw, "/v1/memory/files"+tt.query)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp MemoryFilesResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, f := range resp.Files {
+				paths = append(paths, f.Path)
+			}
+			if !slices.Equal(paths, tt.wantPaths) || resp.Count != len(tt.wantPaths) || resp.Total != tt.wantTotal {
+				t.Fatalf("files = %v (count %d, total %d), want %v (total %d)", paths, resp.Count, resp.Total, tt.wantPaths, tt.wantTotal)
+			}
+		})
+	}
+
+	disabled := newMemoryFramework(t, &fakeMemoryPlugin{cfg: cfg})
+	if w := serveMemory(disabled, "/v1/memory/files"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("files without a manager: status = %d, want 503", w.Code)
+	}
+}
@@ -1,10 +1,18 @@
 package v1
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
 	"github.com/kiosk404/echoryn/internal/pkg/core"
 	"github.com/kiosk404/echoryn/pkg/errorx"
+	"github.com/kiosk404/echoryn/pkg/logger"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
 )
 
 // ModelHandler handles GET /v1/models (OpenAI-compatible).
@@ -12,11 +20,12 @@ import (
 // Modeled after OpenClaw's openai-http.ts:
 type ModelHandler struct {
 	manager llmService.ModelManager
+	prober  *llmService.ModelProber // may be nil; disables /v1/models/scan
 }
 
 // NewModelHandler creates a new ModelHandler.
-func NewModelHandler(manager llmService.ModelManager) *ModelHandler {
-	return &ModelHandler{manager: manager}
+func NewModelHandler(manager llmService.ModelManager, prober *llmService.ModelProber) *ModelHandler {
+	return &ModelHandler{manager: manager, prober: prober}
 }
 
 // List handles GET /v1/models (OpenAI-compatible).
@@ -47,3 +56,118 @@ func (h *ModelHandler) List(c *gin.Context) {
 		Data:   data,
 	})
 }
+
+// Scan handles POST /v1/models/scan: it probes every enabled model of the
+// requested type and streams one SSE data event (ModelScanEvent) per model
+// as its probes finish, followed by "data: [DONE]".
+func (h *ModelHandler) Scan(c *gin.Context) {
+	if h.prober == nil {
+		core.WriteResponse(c, errorx.WithCode(ErrModelScan, "model prober is not available"), nil)
+		return
+	}
+
+	var req ModelScanRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			core.WriteResponse(c, errorx.WrapC(err, ErrBind, "bind model scan request"), nil)
+			return
+		}
+	}
+	modelType, err := parseScanModelType(req.Type)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrValidation, "invalid model type"), nil)
+		return
+	}
+	probeTypes, err := parseProbeTypes(req.Probes)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrValidation, "invalid probe type"), nil)
+		return
+	}
+	if req.Concurrency < 0 || req.TimeoutMs < 0 {
+		core.WriteResponse(c, errorx.WithCode(ErrValidation, "concurrency and timeout_ms must not be negative"), nil)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	w := c.Writer
+
+	// Callbacks are serialized by the prober and each result callback is
+	// followed by the onProgress call counting it, so the event is written
+	// once the counts are known.
+	var pending *entity.ModelScanResult
+	onProgress := func(completed, total int) {
+		data, _ := json.Marshal(ModelScanEvent{Completed: completed, Total: total, Result: toModelScanResultEntry(pending)})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		w.Flush()
+	}
+	opts := []llmService.ScanOption{
+		// The prober clamps the requested concurrency to its configured limit.
+		llmService.WithConcurrency(req.Concurrency),
+		llmService.WithResultCallback(func(r *entity.ModelScanResult) { pending = r }),
+	}
+	if req.TimeoutMs > 0 {
+		opts = append(opts, llmService.WithProbeTimeout(time.Duration(req.TimeoutMs)*time.Millisecond))
+	}
+
+	if _, err := h.prober.ScanAllModels(c.Request.Context(), modelType, probeTypes, onProgress, opts...); err != nil {
+		logger.Warn("[Models] model scan failed (code=%d): %v", ErrModelScan, err)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	w.Flush()
+}
+
+// parseScanModelType maps a model type name to its entity value.
+func parseScanModelType(s string) (entity.ModelType, error) {
+	switch strings.ToLower(s) {
+	case "", "chat", "llm":
+		return entity.ModelType_LLM, nil
+	case "embedding", "text_embedding":
+		return entity.ModelType_TextEmbedding, nil
+	case "rerank":
+		return entity.ModelType_Rerank, nil
+	}
+	return 0, fmt.Errorf("unknown model type %q, expected chat, embedding or rerank", s)
+}
+
+// parseProbeTypes maps probe type names to their entity values.
+func parseProbeTypes(names []string) ([]entity.ProbeType, error) {
+	known := []entity.ProbeType{
+		entity.ProbeType_Chat, entity.ProbeType_ToolCall, entity.ProbeType_Vision,
+		entity.ProbeType_Streaming, entity.ProbeType_ContextWindow,
+	}
+	var types []entity.ProbeType
+	for _, name := range names {
+		i := slices.IndexFunc(known, func(pt entity.ProbeType) bool { return pt.String() == strings.TrimSpace(name) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown probe type %q", name)
+		}
+		types = append(types, known[i])
+	}
+	return types, nil
+}
+
+// toModelScanResultEntry converts a scan result, listing probes in type order.
+func toModelScanResultEntry(r *entity.ModelScanResult) *ModelScanResultEntry {
+	entry := &ModelScanResultEntry{
+		Model:     r.Ref.ProviderID + "/" + r.Ref.ModelID,
+		Available: r.Available,
+		Probes:    make([]ProbeResultEntry, 0, len(r.Results)),
+	}
+	for pt, pr := range r.Results {
+		if pr == nil {
+			continue
+		}
+		entry.Probes = append(entry.Probes, ProbeResultEntry{
+			Type:      pt.String(),
+			OK:        pr.OK,
+			LatencyMs: pr.LatencyMs,
+			Error:     pr.Error,
+			Skipped:   pr.Skipped,
+		})
+	}
+	slices.SortFunc(entry.Probes, func(a, b ProbeResultEntry) int { return strings.Compare(a.Type, b.Type) })
+	return entry
+}
@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+func TestToModelScanResultEntry(t *testing.T) {
+	entry := toModelScanResultEntry(&entity.ModelScanResult{
+		Ref:       entity.ModelRef{ProviderID: "openai", ModelID: "gpt-4o"},
+		Available: true,
+		Results: map[entity.ProbeType]*entity.ProbeResult{
+			entity.ProbeType_Vision:   {OK: false, Error: "no image input"},
+			entity.ProbeType_Chat:     {OK: true, LatencyMs: 120},
+			entity.ProbeType_ToolCall: nil,
+		},
+	})
+	if entry.Model != "openai/gpt-4o" || !entry.Available {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if len(entry.Probes) != 2 {
+		t.Fatalf("probes = %+v, want the two non-nil results", entry.Probes)
+	}
+	if entry.Probes[0].Type != "chat" || entry.Probes[0].LatencyMs != 120 ||
+		entry.Probes[1].Type != "vision" || entry.Probes[1].Error != "no image input" {
+		t.Fatalf("probes = %+v, want chat then vision", entry.Probes)
+	}
+}
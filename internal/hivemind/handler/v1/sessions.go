@@ -2,6 +2,7 @@ package v1
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
 	"github.com/kiosk404/echoryn/internal/pkg/core"
 	"github.com/kiosk404/echoryn/pkg/errorx"
@@ -17,6 +18,23 @@ func NewSessionHandler(svc service.AgentService) *SessionHandler {
 	return &SessionHandler{svc: svc}
 }
 
+// List handles GET /v1/sessions, optionally filtered by ?agent_id=.
+// Sessions are ordered by most recent activity first.
+func (h *SessionHandler) List(c *gin.Context) {
+	agentID := c.Query("agent_id")
+	sessions, err := h.svc.ListSessions(c.Request.Context(), agentID)
+	if err != nil {
+		core.WriteResponse(c, errorx.WrapC(err, ErrSessionList, "list sessions"), nil)
+		return
+	}
+
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, toSessionResponse(s))
+	}
+	core.WriteResponse(c, nil, gin.H{"data": resp})
+}
+
 // ListByAgent handles GET /v1/agents/:id/sessions.
 func (h *SessionHandler) ListByAgent(c *gin.Context) {
 	agentID := c.Param("id")
@@ -28,13 +46,7 @@ func (h *SessionHandler) ListByAgent(c *gin.Context) {
 
 	resp := make([]SessionResponse, 0, len(sessions))
 	for _, s := range sessions {
-		resp = append(resp, SessionResponse{
-			ID:           s.ID,
-			AgentID:      s.AgentID,
-			MessageCount: len(s.Messages),
-			CreatedAt:    FormatTime(s.CreatedAt),
-			UpdatedAt:    FormatTime(s.UpdatedAt),
-		})
+		resp = append(resp, toSessionResponse(s))
 	}
 	core.WriteResponse(c, nil, gin.H{"data": resp})
 }
@@ -47,13 +59,7 @@ func (h *SessionHandler) Get(c *gin.Context) {
 		core.WriteResponse(c, errorx.WrapC(err, ErrSessionNotFound, "session %q not found", id), nil)
 		return
 	}
-	core.WriteResponse(c, nil, SessionResponse{
-		ID:           session.ID,
-		AgentID:      session.AgentID,
-		MessageCount: len(session.Messages),
-		CreatedAt:    FormatTime(session.CreatedAt),
-		UpdatedAt:    FormatTime(session.UpdatedAt),
-	})
+	core.WriteResponse(c, nil, toSessionResponse(session))
 }
 
 // Delete handles DELETE /v1/sessions/:id.
@@ -65,3 +71,14 @@ func (h *SessionHandler) Delete(c *gin.Context) {
 	}
 	core.WriteResponse(c, nil, gin.H{"id": id, "deleted": true})
 }
+
+func toSessionResponse(s *entity.Session) SessionResponse {
+	return SessionResponse{
+		ID:           s.ID,
+		AgentID:      s.AgentID,
+		MessageCount: len(s.Messages),
+		TotalCost:    s.TotalCost,
+		CreatedAt:    FormatTime(s.CreatedAt),
+		UpdatedAt:    FormatTime(s.UpdatedAt),
+	}
+}
@@ -1,7 +1,13 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
 )
 
 // --- OpenAI Chat Completions API Types ---
@@ -18,6 +24,9 @@ type ChatCompletionRequest struct {
 	// Stream controls whether the response is streamed via SSE.
 	Stream bool `json:"stream,omitempty"`
 
+	// StreamOptions configures streaming responses (only used when Stream is true).
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
 	// User is used for session key isolation (optional).
 	User string `json:"user,omitempty"`
 
@@ -26,6 +35,78 @@ type ChatCompletionRequest struct {
 
 	// MaxTokens limits the output tokens (optional, overrides agent default).
 	MaxTokens *int `json:"max_tokens,omitempty"`
+
+	// TopP, FrequencyPenalty and PresencePenalty tune sampling (optional,
+	// override agent defaults). Dropped for providers that do not support them.
+	TopP             *float64 `json:"top_p,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+
+	// Seed requests deterministic sampling (optional). Ignored by providers
+	// that do not support seeds.
+	Seed *int `json:"seed,omitempty"`
+
+	// N is the number of choices to generate (optional, default 1). Each
+	// choice is an independent agent turn over the same context; only the
+	// first successful one is kept in the session history. Tools are not
+	// shared: every choice makes its own tool calls, so a tool with side
+	// effects may run up to N times, each choice within the agent's full
+	// ToolBudget. The server caps N (gateway max-choices).
+	N *int `json:"n,omitempty"`
+
+	// Stop is a string or an array of up to 4 sequences at which generation
+	// stops (optional). Dropped for models that do not support stop sequences.
+	Stop StopSequences `json:"stop,omitempty"`
+
+	// ResponseFormat requests structured output (optional).
+	// Supported types: "text", "json_object", "json_schema".
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Metadata is a set of key/value pairs (optional). The entries are shown
+	// to the agent in the system prompt's request context section.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// StopSequences accepts the OpenAI "stop" field as a string or a string array.
+type StopSequences []string
+
+// UnmarshalJSON decodes a single string or an array of strings.
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var one string
+		if err := json.Unmarshal(data, &one); err != nil {
+			return err
+		}
+		*s = StopSequences{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*s = many
+	return nil
+}
+
+// StreamOptions is the OpenAI-compatible stream_options object.
+type StreamOptions struct {
+	// IncludeUsage requests a final chunk with empty choices carrying the usage.
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// ResponseFormat is the OpenAI-compatible response_format object.
+type ResponseFormat struct {
+	Type       string                    `json:"type"`
+	JSONSchema *ResponseFormatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseFormatJSONSchema is the json_schema payload of a response_format.
+type ResponseFormatJSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      bool            `json:"strict,omitempty"`
 }
 
 // ChatMessage is a single message in the OpenAI Chat Completions format.
@@ -35,6 +116,76 @@ type ChatMessage struct {
 	Name       string          `json:"name,omitempty"`
 	ToolCalls  []ToolCallChunk `json:"tool_calls,omitempty"`
 	ToolCallID string          `json:"tool_call_id,omitempty"`
+
+	// ToolResults is an Echoryn extension, only set on responses when the
+	// request carries the X-Include-Tool-Results header.
+	ToolResults []ToolResultChunk `json:"tool_results,omitempty"`
+
+	// Images holds the image_url parts of a content-parts request message.
+	// Their text parts are joined into Content.
+	Images []ImageURL `json:"-"`
+}
+
+// ContentPart is one element of the content-parts form of a message content.
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is the image of an "image_url" content part.
+type ImageURL struct {
+	// URL is an http(s) URL or a base64 "data:" URL.
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// UnmarshalJSON accepts content either as a string or as an array of
+// content parts ("text" and "image_url").
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type plain ChatMessage
+	aux := struct {
+		*plain
+		Content json.RawMessage `json:"content"`
+	}{plain: (*plain)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	m.Content, m.Images = "", nil
+	raw := bytes.TrimSpace(aux.Content)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	if raw[0] != '[' {
+		return json.Unmarshal(raw, &m.Content)
+	}
+
+	var parts []ContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return err
+	}
+	var texts []string
+	for i, p := range parts {
+		switch p.Type {
+		case "text":
+			texts = append(texts, p.Text)
+		case "image_url":
+			if p.ImageURL == nil || p.ImageURL.URL == "" {
+				return fmt.Errorf("content part %d: image_url.url is required", i)
+			}
+			switch p.ImageURL.Detail {
+			case "", "auto", "low", "high":
+			default:
+				return fmt.Errorf("content part %d: invalid image_url.detail %q: must be one of auto, low, high", i, p.ImageURL.Detail)
+			}
+			m.Images = append(m.Images, *p.ImageURL)
+		default:
+			return fmt.Errorf("content part %d: unsupported type %q: must be text or image_url", i, p.Type)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
 }
 
 // ToolCallChunk represents a tool call in OpenAI format.
@@ -45,6 +196,14 @@ type ToolCallChunk struct {
 	Function ToolCallFunction `json:"function"`
 }
 
+// ToolResultChunk is the (possibly truncated) result of an executed tool call.
+type ToolResultChunk struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
 // ToolCallFunction represents the function part of a tool call.
 type ToolCallFunction struct {
 	Name      string `json:"name,omitempty"`
@@ -101,6 +260,29 @@ type ChatMessageDelta struct {
 	Role      string          `json:"role,omitempty"`
 	Content   string          `json:"content,omitempty"`
 	ToolCalls []ToolCallChunk `json:"tool_calls,omitempty"`
+
+	// ToolResults is an Echoryn extension, see ChatMessage.ToolResults.
+	ToolResults []ToolResultChunk `json:"tool_results,omitempty"`
+
+	// Status is an Echoryn extension reporting run progress (such as
+	// "summarizing conversation…"). Compaction progress is only sent with
+	// X-Include-Status: true; model switches are always sent.
+	Status *StatusChunk `json:"status,omitempty"`
+}
+
+// StatusChunk is a run progress update carried by a streamed delta.
+type StatusChunk struct {
+	// Type is the kind of activity: "compaction" or "model_switch".
+	Type string `json:"type"`
+	// State is "started", "completed", "failed" or "scheduled" (proactive
+	// compaction, which runs after the stream ends) for compactions, and
+	// "reset" for a model switch: the model failed mid-stream and the content
+	// and tool calls streamed so far for the choice must be discarded. Reason
+	// then carries a notice for display.
+	State        string `json:"state"`
+	Reason       string `json:"reason,omitempty"`
+	TokensBefore int    `json:"tokens_before,omitempty"`
+	TokensAfter  int    `json:"tokens_after,omitempty"`
 }
 
 // --- Models API ---
@@ -118,19 +300,114 @@ type ModelListResponse struct {
 	Data   []ModelObject `json:"data"`
 }
 
+// ModelScanRequest is the request body for POST /v1/models/scan.
+type ModelScanRequest struct {
+	// Type is the model type to scan: "chat" (default), "embedding" or "rerank".
+	Type string `json:"type,omitempty"`
+	// Probes are the probe types to run ("chat", "tool_call", "vision",
+	// "streaming", "context_window"). Default: chat.
+	Probes []string `json:"probes,omitempty"`
+	// Concurrency is the maximum number of parallel probes. 0 uses the server
+	// default; larger values are clamped to it.
+	Concurrency int `json:"concurrency,omitempty"`
+	// TimeoutMs is the per-probe timeout. 0 uses the server default.
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+}
+
+// ModelScanEvent is one SSE data event of POST /v1/models/scan, sent as each
+// model's probes finish.
+type ModelScanEvent struct {
+	Completed int                   `json:"completed"`
+	Total     int                   `json:"total"`
+	Result    *ModelScanResultEntry `json:"result"`
+}
+
+// ModelScanResultEntry is the scan result of one model.
+type ModelScanResultEntry struct {
+	Model     string             `json:"model"` // "provider/model"
+	Available bool               `json:"available"`
+	Probes    []ProbeResultEntry `json:"probes"`
+}
+
+// ProbeResultEntry is the outcome of one probe.
+type ProbeResultEntry struct {
+	Type      string `json:"type"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+}
+
 // --- Agent API ---
 
 // CreateAgentRequest is the request body for POST /v1/agents.
 type CreateAgentRequest struct {
-	ID           string           `json:"id" binding:"required"`
-	Name         string           `json:"name" binding:"required"`
-	Description  string           `json:"description,omitempty"`
-	SystemPrompt string           `json:"system_prompt"`
-	ModelRef     *ModelRefRequest `json:"model_ref,omitempty"`
-	Tools        []string         `json:"tools,omitempty"`
-	MaxTurns     int              `json:"max_turns,omitempty"`
-	Temperature  *float64         `json:"temperature,omitempty"`
-	MaxTokens    *int             `json:"max_tokens,omitempty"`
+	ID               string           `json:"id" binding:"required"`
+	Name             string           `json:"name" binding:"required"`
+	Description      string           `json:"description,omitempty"`
+	SystemPrompt     string           `json:"system_prompt"`
+	ModelRef         *ModelRefRequest `json:"model_ref,omitempty"`
+	ToolMode         string           `json:"tool_mode,omitempty"` // "all" | "allowlist" | "denylist"
+	Tools            []string         `json:"tools,omitempty"`
+	DeniedTools      []string         `json:"denied_tools,omitempty"`
+	MaxTurns         int              `json:"max_turns,omitempty"`
+	MaxTurnsBehavior string           `json:"max_turns_behavior,omitempty"` // "hard_stop" | "force_final_answer"
+	Temperature      *float64         `json:"temperature,omitempty"`
+	MaxTokens        *int             `json:"max_tokens,omitempty"`
+	ReserveTokens    *int             `json:"reserve_tokens,omitempty"`
+	Memory           *bool            `json:"memory,omitempty"`
+
+	ToolBudget *ToolBudget `json:"tool_budget,omitempty"`
+}
+
+// UpdateAgentRequest is the request body for PATCH /v1/agents/:id.
+// Nil fields are left unchanged; an empty list clears Tools/DeniedTools.
+type UpdateAgentRequest struct {
+	Name             *string          `json:"name,omitempty"`
+	Description      *string          `json:"description,omitempty"`
+	SystemPrompt     *string          `json:"system_prompt,omitempty"`
+	ModelRef         *ModelRefRequest `json:"model_ref,omitempty"`
+	ToolMode         *string          `json:"tool_mode,omitempty"`
+	Tools            *[]string        `json:"tools,omitempty"`
+	DeniedTools      *[]string        `json:"denied_tools,omitempty"`
+	MaxTurns         *int             `json:"max_turns,omitempty"`
+	MaxTurnsBehavior *string          `json:"max_turns_behavior,omitempty"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+	MaxTokens        *int             `json:"max_tokens,omitempty"`
+	ReserveTokens    *int             `json:"reserve_tokens,omitempty"`
+	Memory           *bool            `json:"memory,omitempty"`
+
+	// ToolBudget replaces the agent's tool budget; null leaves it unchanged
+	// and an empty object removes all limits.
+	ToolBudget *ToolBudget `json:"tool_budget,omitempty"`
+
+	// Persona replaces the agent's persona as a whole; null leaves it unchanged.
+	Persona *AgentPersona `json:"persona,omitempty"`
+}
+
+// ToolBudget caps the tool calls of a single agent run. A call beyond a
+// limit is not executed; the model gets an error as the tool result.
+type ToolBudget struct {
+	MaxCalls int            `json:"max_calls,omitempty"` // 0 = unlimited
+	PerTool  map[string]int `json:"per_tool,omitempty"`  // tool name → max calls
+}
+
+// AgentPersona is the identity and prompt assembly configuration of an agent.
+type AgentPersona struct {
+	Identity      *AgentIdentity    `json:"identity,omitempty"`
+	PromptMode    string            `json:"prompt_mode,omitempty"` // "full" | "minimal" | "none"
+	WorkspaceDir  string            `json:"workspace_dir,omitempty"`
+	Timezone      string            `json:"timezone,omitempty"`       // IANA name, e.g. "Europe/Berlin"
+	ExtraSections map[string]string `json:"extra_sections,omitempty"` // heading → Markdown content
+}
+
+// AgentIdentity is the persona identity of an agent.
+type AgentIdentity struct {
+	Name     string `json:"name,omitempty"`
+	Emoji    string `json:"emoji,omitempty"`
+	Creature string `json:"creature,omitempty"`
+	Vibe     string `json:"vibe,omitempty"`
+	Theme    string `json:"theme,omitempty"`
 }
 
 // ModelRefRequest is a model reference in the API request.
@@ -141,23 +418,153 @@ type ModelRefRequest struct {
 
 // AgentResponse is the response for agent endpoints.
 type AgentResponse struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description,omitempty"`
+	SystemPrompt     string   `json:"system_prompt"`
+	ToolMode         string   `json:"tool_mode,omitempty"`
+	Tools            []string `json:"tools,omitempty"`
+	DeniedTools      []string `json:"denied_tools,omitempty"`
+	MaxTurns         int      `json:"max_turns,omitempty"`
+	MaxTurnsBehavior string   `json:"max_turns_behavior,omitempty"`
+	ReserveTokens    *int     `json:"reserve_tokens,omitempty"`
+	Memory           bool     `json:"memory"`
+
+	ToolBudget *ToolBudget   `json:"tool_budget,omitempty"`
+	Persona    *AgentPersona `json:"persona,omitempty"`
+	CreatedAt  string        `json:"created_at"`
+	UpdatedAt  string        `json:"updated_at"`
+}
+
+// PromptPreviewResponse is the response for POST /v1/agents/:id/prompt-preview.
+type PromptPreviewResponse struct {
+	AgentID  string                 `json:"agent_id"`
+	Mode     string                 `json:"mode"`
+	Prompt   string                 `json:"prompt"`
+	Length   int                    `json:"length"`
+	Sections []PromptSectionPreview `json:"sections"`
+}
+
+// AgentToolsResponse is the response for GET /v1/agents/:id/tools.
+type AgentToolsResponse struct {
+	AgentID string          `json:"agent_id"`
+	Tools   []AgentToolInfo `json:"tools"`
+}
+
+// AgentToolInfo describes one tool an agent receives at run time.
+type AgentToolInfo struct {
+	Name        string `json:"name"` // name the model calls the tool by
+	Description string `json:"description"`
+	Source      string `json:"source"` // "plugin" or "mcp"
+
+	// Server and MCPName are set for MCP tools: the server the tool belongs
+	// to and its original, un-prefixed name.
+	Server  string `json:"server,omitempty"`
+	MCPName string `json:"mcp_name,omitempty"`
+}
+
+// PromptSectionPreview describes how one prompt section fared during assembly.
+type PromptSectionPreview struct {
+	Name       string `json:"name"`
+	Priority   int    `json:"priority"`
+	Enabled    bool   `json:"enabled"`
+	Included   bool   `json:"included"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	Length     int    `json:"length"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SessionResponse is the response for session endpoints.
+type SessionResponse struct {
 	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Description  string   `json:"description,omitempty"`
-	SystemPrompt string   `json:"system_prompt"`
-	Tools        []string `json:"tools,omitempty"`
-	MaxTurns     int      `json:"max_turns,omitempty"`
+	AgentID      string   `json:"agent_id"`
+	MessageCount int      `json:"message_count"`
+	TotalCost    *float64 `json:"total_cost"` // null when no run was served by a priced model
 	CreatedAt    string   `json:"created_at"`
 	UpdatedAt    string   `json:"updated_at"`
 }
 
-// SessionResponse is the response for session endpoints.
-type SessionResponse struct {
-	ID           string `json:"id"`
-	AgentID      string `json:"agent_id"`
-	MessageCount int    `json:"message_count"`
-	CreatedAt    string `json:"created_at"`
-	UpdatedAt    string `json:"updated_at"`
+// --- Memory API ---
+
+// MemoryFilesResponse is the response for GET /v1/memory/files.
+type MemoryFilesResponse struct {
+	Files []manager.IndexedFile `json:"files"`
+	Count int                   `json:"count"` // files in this page
+	Total int                   `json:"total"` // files matching the query
+}
+
+// MemoryStatusResponse is the response for GET /v1/memory/status.
+type MemoryStatusResponse struct {
+	Plugin       string                 `json:"plugin"`
+	Enabled      bool                   `json:"enabled"`
+	Status       *manager.ManagerStatus `json:"status,omitempty"`
+	WorkspaceDir string                 `json:"workspace_dir"`
+	Chunking     MemoryChunkingSummary  `json:"chunking"`
+	Hybrid       MemoryHybridSummary    `json:"hybrid"`
+}
+
+// MemoryChunkingSummary summarizes the memory chunking parameters.
+type MemoryChunkingSummary struct {
+	Tokens                int  `json:"tokens"`
+	Overlap               int  `json:"overlap"`
+	IncludeHeadingContext bool `json:"include_heading_context"`
+}
+
+// MemoryHybridSummary summarizes the hybrid search weights.
+type MemoryHybridSummary struct {
+	Enabled             bool    `json:"enabled"`
+	VectorWeight        float64 `json:"vector_weight"`
+	TextWeight          float64 `json:"text_weight"`
+	CandidateMultiplier float64 `json:"candidate_multiplier"`
+	Strategy            string  `json:"strategy,omitempty"`
+	RRFK                int     `json:"rrf_k,omitempty"`
+}
+
+// --- Health API ---
+
+// ReadinessResponse is the response for GET /readyz, sent with 503 when
+// Status is "degraded".
+type ReadinessResponse struct {
+	Status       string              `json:"status"` // "ready" | "degraded"
+	Plugins      []PluginHealth      `json:"plugins,omitempty"`
+	DefaultModel *DefaultModelHealth `json:"default_model,omitempty"`
+	MCPServers   []MCPServerHealth   `json:"mcp_servers,omitempty"`
+}
+
+// PluginHealth is the health of one plugin that reports it.
+type PluginHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "ready" | "degraded"
+	Reason string `json:"reason,omitempty"`
+}
+
+// DefaultModelHealth is the availability of the system default model.
+type DefaultModelHealth struct {
+	Model  string `json:"model,omitempty"`
+	Status string `json:"status"` // "ready" | "degraded"
+	Reason string `json:"reason,omitempty"`
+}
+
+// MCPServerHealth is the health of one MCP server.
+type MCPServerHealth struct {
+	Name                string `json:"name"`
+	Status              string `json:"status"`
+	Reason              string `json:"reason,omitempty"`
+	Reconnecting        bool   `json:"reconnecting,omitempty"`
+	ToolCount           int    `json:"tool_count"`
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+
+	// ToolBreakers lists tools whose circuit is open or half-open, or that
+	// have failures counting toward opening it.
+	ToolBreakers []MCPToolBreaker `json:"tool_breakers,omitempty"`
+}
+
+// MCPToolBreaker is the circuit breaker state of one MCP tool.
+type MCPToolBreaker struct {
+	Tool                string `json:"tool"`
+	State               string `json:"state"` // "closed", "open" or "half_open"
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	OpenUntil           string `json:"open_until,omitempty"`
 }
 
 // --- Common ---
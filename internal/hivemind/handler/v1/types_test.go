@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestStopSequencesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    StopSequences
+		wantErr bool
+	}{
+		{"absent", `{}`, nil, false},
+		{"string", `{"stop":"\n\n"}`, StopSequences{"\n\n"}, false},
+		{"array", `{"stop":["END", "STOP"]}`, StopSequences{"END", "STOP"}, false},
+		{"padded string", `{"stop": "END" }`, StopSequences{"END"}, false},
+		{"number", `{"stop":4}`, nil, true},
+		{"mixed array", `{"stop":["END", 4]}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req ChatCompletionRequest
+			err := json.Unmarshal([]byte(tt.body), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(req.Stop, tt.want) {
+				t.Fatalf("Stop = %q, want %q", req.Stop, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/tokenizer"
+	"github.com/spf13/pflag"
+)
+
+// AgentOptions holds server-wide defaults for the Agents module.
+type AgentOptions struct {
+	// Timezone is the IANA zone (e.g., "Asia/Shanghai") used for the current
+	// time in system prompts. Agents may override it in their persona.
+	// Default: "" (server local time).
+	Timezone string `json:"timezone" mapstructure:"timezone"`
+
+	// TokenizerFile is a tiktoken ranks file (e.g., cl100k_base.tiktoken) used
+	// for exact token counts on OpenAI models. Default: "" (char estimate).
+	TokenizerFile string `json:"tokenizer-file" mapstructure:"tokenizer-file"`
+
+	// TokenizerEncoding names the encoding of TokenizerFile.
+	// Default: "cl100k_base".
+	TokenizerEncoding string `json:"tokenizer-encoding" mapstructure:"tokenizer-encoding"`
+
+	// TokenizerFiles maps tiktoken encodings to ranks files; each model is
+	// counted with the file of its encoding (e.g. o200k_base for GPT-4o).
+	TokenizerFiles map[string]string `json:"tokenizer-files" mapstructure:"tokenizer-files"`
+
+	// StoreType selects where agents, sessions and runs are persisted:
+	// "inmemory", "boltdb", "sqlite" or "postgres". Default: "inmemory".
+	StoreType string `json:"store-type" mapstructure:"store-type"`
+
+	// BoltDBPath is the BoltDB file when StoreType is "boltdb".
+	BoltDBPath string `json:"boltdb-path" mapstructure:"boltdb-path"`
+
+	// DSN is the database when StoreType is "sqlite" (file path or
+	// ":memory:") or "postgres" (connection string, required).
+	DSN string `json:"dsn" mapstructure:"dsn"`
+
+	// Prompt configures system prompt assembly.
+	Prompt AgentPromptOptions `json:"prompt" mapstructure:"prompt"`
+}
+
+// AgentPromptOptions configures system prompt assembly.
+type AgentPromptOptions struct {
+	// RedactionPatterns are regular expressions (Go syntax) masked in system
+	// prompts in addition to the built-in API key and token patterns.
+	RedactionPatterns []string `json:"redaction-patterns" mapstructure:"redaction-patterns"`
+
+	// ExtraHeading is the heading above request metadata in system prompts.
+	// Empty uses "## Request Context".
+	ExtraHeading string `json:"extra-heading" mapstructure:"extra-heading"`
+
+	// ExtraMaxValueLength caps each request metadata value in runes
+	// (0 = 256).
+	ExtraMaxValueLength int `json:"extra-max-value-length" mapstructure:"extra-max-value-length"`
+}
+
+// Store types accepted by AgentOptions.StoreType.
+var storeTypes = []string{"inmemory", "boltdb", "sqlite", "postgres"}
+
+// NewAgentOptions creates a default AgentOptions instance.
+func NewAgentOptions() *AgentOptions {
+	return &AgentOptions{
+		TokenizerEncoding: tokenizer.EncodingCL100K,
+	}
+}
+
+// Validate checks the AgentOptions for correctness.
+func (o *AgentOptions) Validate() error {
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("invalid agents.timezone %q: %w", o.Timezone, err)
+		}
+	}
+	if !validEncoding(o.TokenizerEncoding) {
+		return fmt.Errorf("invalid agents.tokenizer-encoding %q: must be %s or %s",
+			o.TokenizerEncoding, tokenizer.EncodingCL100K, tokenizer.EncodingO200K)
+	}
+	if o.StoreType != "" && !slices.Contains(storeTypes, o.StoreType) {
+		return fmt.Errorf("invalid agents.store-type %q: must be one of %s", o.StoreType, strings.Join(storeTypes, ", "))
+	}
+	if o.StoreType == "postgres" && o.DSN == "" {
+		return errors.New("agents.dsn is required when agents.store-type is postgres")
+	}
+	if o.Prompt.ExtraMaxValueLength < 0 {
+		return errors.New("agents.prompt.extra-max-value-length must not be negative")
+	}
+	for _, pattern := range o.Prompt.RedactionPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid agents.prompt.redaction-patterns entry %q: %w", pattern, err)
+		}
+	}
+	for encoding := range o.TokenizerFiles {
+		if encoding == "" || !validEncoding(encoding) {
+			return fmt.Errorf("invalid agents.tokenizer-files encoding %q: must be %s or %s",
+				encoding, tokenizer.EncodingCL100K, tokenizer.EncodingO200K)
+		}
+	}
+	return nil
+}
+
+// validEncoding reports whether encoding is empty or a supported tiktoken encoding.
+func validEncoding(encoding string) bool {
+	switch encoding {
+	case "", tokenizer.EncodingCL100K, tokenizer.EncodingO200K:
+		return true
+	}
+	return false
+}
+
+// AddFlags adds the AgentOptions flags to the given flag set.
+func (o *AgentOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Timezone, "agents.timezone", o.Timezone, "Default IANA timezone for the current time in agent prompts (empty = server local time).")
+	fs.StringVar(&o.TokenizerFile, "agents.tokenizer-file", o.TokenizerFile, "Path to a tiktoken ranks file for exact token counting on OpenAI models (empty = char-based estimate).")
+	fs.StringVar(&o.TokenizerEncoding, "agents.tokenizer-encoding", o.TokenizerEncoding, "Encoding of agents.tokenizer-file: cl100k_base or o200k_base.")
+	fs.StringVar(&o.StoreType, "agents.store-type", o.StoreType, "Persistence backend for agents, sessions and runs: inmemory, boltdb, sqlite or postgres.")
+	fs.StringVar(&o.BoltDBPath, "agents.boltdb-path", o.BoltDBPath, "BoltDB file when agents.store-type is boltdb.")
+	fs.StringVar(&o.DSN, "agents.dsn", o.DSN, "Database when agents.store-type is sqlite (file path) or postgres (connection string).")
+	fs.StringArrayVar(&o.Prompt.RedactionPatterns, "agents.prompt.redaction-patterns", o.Prompt.RedactionPatterns, "Regular expression masked in system prompts in addition to the built-in secret patterns (repeatable).")
+	fs.StringVar(&o.Prompt.ExtraHeading, "agents.prompt.extra-heading", o.Prompt.ExtraHeading, "Heading above request metadata in system prompts (empty = \"## Request Context\").")
+	fs.IntVar(&o.Prompt.ExtraMaxValueLength, "agents.prompt.extra-max-value-length", o.Prompt.ExtraMaxValueLength, "Runes kept of each request metadata value in system prompts (0 = 256).")
+	fs.StringToStringVar(&o.TokenizerFiles, "agents.tokenizer-files", o.TokenizerFiles, "Tiktoken ranks files per encoding (e.g. o200k_base=/path/o200k_base.tiktoken); each OpenAI model uses the file of its encoding.")
+}
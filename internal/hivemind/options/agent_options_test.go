@@ -0,0 +1,44 @@
+package options
+
+import "testing"
+
+func TestAgentOptionsValidate(t *testing.T) {
+	tests := []struct {
+		timezone string
+		wantErr  bool
+	}{
+		{"", false},
+		{"UTC", false},
+		{"Asia/Shanghai", false},
+		{"Mars/Olympus", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			err := (&AgentOptions{Timezone: tt.timezone}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAgentOptionsValidateTokenizerFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"known encodings", map[string]string{"cl100k_base": "/a", "o200k_base": "/b"}, false},
+		{"unknown encoding", map[string]string{"p50k_base": "/a"}, true},
+		{"empty encoding", map[string]string{"": "/a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&AgentOptions{TokenizerFiles: tt.files}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
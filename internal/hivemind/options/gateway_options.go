@@ -0,0 +1,164 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// GatewayOptions holds options for the HTTP gateway (/v1 endpoints).
+type GatewayOptions struct {
+	// Auth configures Bearer token authentication.
+	Auth GatewayAuthOptions `json:"auth" mapstructure:"auth"`
+
+	// RateLimit throttles run-starting endpoints per session / API key / IP.
+	RateLimit GatewayRateLimitOptions `json:"rate-limit" mapstructure:"rate-limit"`
+
+	// Defaults holds the agent and model used when a request names neither.
+	Defaults GatewayDefaultsOptions `json:"defaults" mapstructure:"defaults"`
+
+	// Stream configures SSE responses of the chat endpoint.
+	Stream GatewayStreamOptions `json:"stream" mapstructure:"stream"`
+
+	// IdempotencyTTL is how long chat completion results are kept for
+	// Idempotency-Key retries. A negative value disables the header.
+	IdempotencyTTL time.Duration `json:"idempotency-ttl" mapstructure:"idempotency-ttl"`
+
+	// MaxChoices is the largest "n" a chat completion request may ask for.
+	// Each choice is a full agent turn that runs its own tool calls.
+	MaxChoices int `json:"max-choices" mapstructure:"max-choices"`
+}
+
+// GatewayStreamOptions configures SSE streaming.
+type GatewayStreamOptions struct {
+	// KeepaliveInterval is how long a stream may stay idle before a ping
+	// comment is written. A negative value disables keepalive pings.
+	KeepaliveInterval time.Duration `json:"keepalive-interval" mapstructure:"keepalive-interval"`
+}
+
+// GatewayAuthOptions configures gateway authentication.
+type GatewayAuthOptions struct {
+	// Enabled enforces a Bearer token on every request.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+
+	// Token is the expected Bearer token. Empty falls back to the
+	// EIDOLON_GATEWAY_TOKEN environment variable.
+	Token string `json:"token" mapstructure:"token"`
+}
+
+// GatewayRateLimitOptions configures chat endpoint throttling.
+type GatewayRateLimitOptions struct {
+	// Enabled turns rate limiting on.
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+
+	// RequestsPerMinute is the sustained request rate per key (0 = unlimited).
+	RequestsPerMinute int `json:"requests-per-minute" mapstructure:"requests-per-minute"`
+
+	// Burst is how many requests a key may send back-to-back.
+	// 0 defaults to RequestsPerMinute.
+	Burst int `json:"burst" mapstructure:"burst"`
+
+	// MaxConcurrentRuns is the number of runs a key may have in flight
+	// (0 = unlimited).
+	MaxConcurrentRuns int `json:"max-concurrent-runs" mapstructure:"max-concurrent-runs"`
+}
+
+// GatewayDefaultsOptions holds gateway-wide defaults.
+type GatewayDefaultsOptions struct {
+	// AgentID is the agent used when a request names none.
+	AgentID string `json:"agent-id" mapstructure:"agent-id"`
+
+	// Model is the model name reported by the OpenAI-compatible endpoints.
+	Model string `json:"model" mapstructure:"model"`
+
+	// Agent configures agents auto-created by /v1/chat/completions.
+	Agent GatewayAgentDefaultsOptions `json:"agent" mapstructure:"agent"`
+}
+
+// GatewayAgentDefaultsOptions configures agents auto-created by the chat
+// endpoint.
+type GatewayAgentDefaultsOptions struct {
+	// Memory enables the memory plugin for the agent. nil means enabled.
+	Memory *bool `json:"memory" mapstructure:"memory"`
+
+	// Tools is the tool allowlist. Empty exposes all tools.
+	Tools []string `json:"tools" mapstructure:"tools"`
+
+	// MaxTurns caps tool-call turns per run. 0 uses the agent module default.
+	MaxTurns int `json:"max-turns" mapstructure:"max-turns"`
+
+	// SystemPrompt is the agent's system prompt when the creating request
+	// has no system messages.
+	SystemPrompt string `json:"system-prompt" mapstructure:"system-prompt"`
+
+	// Identity is the persona identity. An empty identity sets none.
+	Identity GatewayAgentIdentityOptions `json:"identity" mapstructure:"identity"`
+
+	// WorkspaceDir is the persona workspace (SOUL.md, IDENTITY.md, AGENTS.md).
+	WorkspaceDir string `json:"workspace-dir" mapstructure:"workspace-dir"`
+}
+
+// GatewayAgentIdentityOptions is the persona identity of auto-created agents.
+type GatewayAgentIdentityOptions struct {
+	Name     string `json:"name" mapstructure:"name"`
+	Emoji    string `json:"emoji" mapstructure:"emoji"`
+	Creature string `json:"creature" mapstructure:"creature"`
+	Vibe     string `json:"vibe" mapstructure:"vibe"`
+	Theme    string `json:"theme" mapstructure:"theme"`
+}
+
+// NewGatewayOptions creates a default GatewayOptions instance.
+func NewGatewayOptions() *GatewayOptions {
+	return &GatewayOptions{
+		RateLimit: GatewayRateLimitOptions{
+			RequestsPerMinute: 60,
+			Burst:             10,
+			MaxConcurrentRuns: 2,
+		},
+		Defaults: GatewayDefaultsOptions{
+			AgentID: "main",
+			Model:   "Echoryn",
+		},
+		Stream: GatewayStreamOptions{
+			KeepaliveInterval: 15 * time.Second,
+		},
+		IdempotencyTTL: 24 * time.Hour,
+		MaxChoices:     4,
+	}
+}
+
+// Validate checks the GatewayOptions for correctness.
+func (o *GatewayOptions) Validate() []error {
+	var errs []error
+	rl := o.RateLimit
+	if rl.RequestsPerMinute < 0 || rl.Burst < 0 || rl.MaxConcurrentRuns < 0 {
+		errs = append(errs, fmt.Errorf("gateway.rate-limit values must not be negative"))
+	}
+	if o.MaxChoices < 0 {
+		errs = append(errs, fmt.Errorf("gateway.max-choices must not be negative"))
+	}
+	if o.Defaults.Agent.MaxTurns < 0 {
+		errs = append(errs, fmt.Errorf("gateway.defaults.agent.max-turns must not be negative"))
+	}
+	return errs
+}
+
+// AddFlags adds the GatewayOptions flags to the given flag set.
+func (o *GatewayOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Auth.Enabled, "gateway.auth.enabled", o.Auth.Enabled, "Require a Bearer token on gateway requests.")
+	fs.BoolVar(&o.RateLimit.Enabled, "gateway.rate-limit.enabled", o.RateLimit.Enabled, "Throttle /v1/chat/completions per session, API key or client IP.")
+	fs.IntVar(&o.RateLimit.RequestsPerMinute, "gateway.rate-limit.requests-per-minute", o.RateLimit.RequestsPerMinute, "Sustained requests per minute allowed per key (0 = unlimited).")
+	fs.IntVar(&o.RateLimit.Burst, "gateway.rate-limit.burst", o.RateLimit.Burst, "Requests a key may send back-to-back (0 = requests-per-minute).")
+	fs.IntVar(&o.RateLimit.MaxConcurrentRuns, "gateway.rate-limit.max-concurrent-runs", o.RateLimit.MaxConcurrentRuns, "Agent runs a key may have in flight (0 = unlimited).")
+	fs.StringVar(&o.Defaults.AgentID, "gateway.defaults.agent-id", o.Defaults.AgentID, "Agent used when a request names none.")
+	fs.StringVar(&o.Defaults.Model, "gateway.defaults.model", o.Defaults.Model, "Model name reported by the OpenAI-compatible endpoints.")
+	fs.StringSliceVar(&o.Defaults.Agent.Tools, "gateway.defaults.agent.tools", o.Defaults.Agent.Tools, "Tool allowlist of agents auto-created by /v1/chat/completions (empty = all tools).")
+	fs.IntVar(&o.Defaults.Agent.MaxTurns, "gateway.defaults.agent.max-turns", o.Defaults.Agent.MaxTurns, "Tool-call turns per run of agents auto-created by /v1/chat/completions (0 = agent default).")
+	fs.StringVar(&o.Defaults.Agent.SystemPrompt, "gateway.defaults.agent.system-prompt", o.Defaults.Agent.SystemPrompt, "System prompt of agents auto-created by /v1/chat/completions.")
+	fs.StringVar(&o.Defaults.Agent.Identity.Name, "gateway.defaults.agent.identity.name", o.Defaults.Agent.Identity.Name, "Persona name of agents auto-created by /v1/chat/completions.")
+	fs.StringVar(&o.Defaults.Agent.WorkspaceDir, "gateway.defaults.agent.workspace-dir", o.Defaults.Agent.WorkspaceDir, "Persona workspace directory of agents auto-created by /v1/chat/completions.")
+	fs.DurationVar(&o.Stream.KeepaliveInterval, "gateway.stream.keepalive-interval", o.Stream.KeepaliveInterval, "Idle time before a ping comment is written to a chat completion stream (negative disables pings).")
+	fs.IntVar(&o.MaxChoices, "gateway.max-choices", o.MaxChoices, "Largest \"n\" a chat completion request may ask for; every choice runs its own tool calls.")
+	fs.DurationVar(&o.IdempotencyTTL, "gateway.idempotency-ttl", o.IdempotencyTTL, "How long chat completion results are kept for Idempotency-Key retries (negative disables the header).")
+}
@@ -13,6 +13,8 @@ type Options struct {
 	ModelOptions            *genericoptions.ModelOptions     `json:"models"   mapstructure:"models"`
 	PluginOptions           *genericoptions.PluginsOptions   `json:"plugins"  mapstructure:"plugins"`
 	MCPOptions              *MCPOptions                      `json:"mcp"      mapstructure:"mcp"`
+	AgentOptions            *AgentOptions                    `json:"agents"   mapstructure:"agents"`
+	GatewayOptions          *GatewayOptions                  `json:"gateway"  mapstructure:"gateway"`
 }
 
 func (o *Options) Flags() (fss cliflag.NamedFlagSets) {
@@ -21,6 +23,8 @@ func (o *Options) Flags() (fss cliflag.NamedFlagSets) {
 	o.ModelOptions.AddFlags(fss.FlagSet("models"))
 	o.PluginOptions.AddFlags(fss.FlagSet("plugins"))
 	o.MCPOptions.AddFlags(fss.FlagSet("mcp"))
+	o.AgentOptions.AddFlags(fss.FlagSet("agents"))
+	o.GatewayOptions.AddFlags(fss.FlagSet("gateway"))
 	return fss
 }
 
@@ -31,6 +35,8 @@ func NewOptions() *Options {
 		ModelOptions:            genericoptions.NewModelOptions(),
 		PluginOptions:           genericoptions.NewPluginsOptions(),
 		MCPOptions:              NewMCPOptions(),
+		AgentOptions:            NewAgentOptions(),
+		GatewayOptions:          NewGatewayOptions(),
 	}
 }
 
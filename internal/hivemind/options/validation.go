@@ -4,5 +4,9 @@ func (o *Options) Validate() []error {
 	var errs []error
 	errs = append(errs, o.GenericServerRunOptions.Validate()...)
 	errs = append(errs, o.GRPCOptions.Validate()...)
+	if err := o.AgentOptions.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, o.GatewayOptions.Validate()...)
 	return errs
 }
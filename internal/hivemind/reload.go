@@ -0,0 +1,58 @@
+package hivemind
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	genericoptions "github.com/kiosk404/echoryn/internal/pkg/options"
+	"github.com/kiosk404/echoryn/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// modelReloadTimeout bounds a single SIGHUP-triggered model reload.
+const modelReloadTimeout = 30 * time.Second
+
+// watchReloadSignal re-reads the config file and reloads the "models" section
+// whenever the process receives SIGHUP. The returned func stops watching.
+func (s *apiServer) watchReloadSignal() func() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigCh:
+				logger.Info("[Hivemind] SIGHUP received, reloading model config")
+				if err := s.reloadModels(); err != nil {
+					logger.Warn("[Hivemind] model config reload failed: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
+
+// reloadModels reads the current "models" config section and applies it to the LLM module.
+func (s *apiServer) reloadModels() error {
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	opts := genericoptions.NewModelOptions()
+	if err := viper.UnmarshalKey("models", opts); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelReloadTimeout)
+	defer cancel()
+	return s.llmModule.Reload(ctx, opts)
+}
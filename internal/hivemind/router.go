@@ -6,13 +6,19 @@ import (
 	v1 "github.com/kiosk404/echoryn/internal/hivemind/handler/v1"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
 	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
 )
 
 // routerDeps holds the dependencies needed for route registration.
 type routerDeps struct {
 	agentService  service.AgentService
 	llmManager    llmService.ModelManager
+	llmProber     *llmService.ModelProber
+	plugins       *plugin.Framework
+	mcpManager    mcp.Manager
 	authConfig    *middleware.AuthConfig
+	rateLimit     *middleware.RateLimitConfig
 	gatewayConfig *GatewayConfig
 }
 
@@ -44,26 +50,55 @@ func installController(g *gin.Engine, deps *routerDeps) {
 
 	// Handlers.
 	chatHandler := v1.NewChatCompletionsHandler(deps.agentService, deps.llmManager, defaultAgentID, defaultModel)
+	if deps.gatewayConfig != nil && deps.gatewayConfig.Stream.KeepaliveInterval != 0 {
+		chatHandler.SetKeepaliveInterval(deps.gatewayConfig.Stream.KeepaliveInterval)
+	}
+	if deps.gatewayConfig != nil {
+		chatHandler.SetAgentDefaults(deps.gatewayConfig.Defaults.Agent)
+	}
+	if deps.gatewayConfig != nil && deps.gatewayConfig.IdempotencyTTL != 0 {
+		chatHandler.SetIdempotencyTTL(deps.gatewayConfig.IdempotencyTTL)
+	}
+	if deps.gatewayConfig != nil {
+		chatHandler.SetMaxChoices(deps.gatewayConfig.MaxChoices)
+	}
 	agentHandler := v1.NewAgentHandler(deps.agentService)
 	sessionHandler := v1.NewSessionHandler(deps.agentService)
-	modelHandler := v1.NewModelHandler(deps.llmManager)
+	modelHandler := v1.NewModelHandler(deps.llmManager, deps.llmProber)
+	memoryHandler := v1.NewMemoryHandler(deps.plugins)
+	healthHandler := v1.NewHealthHandler(deps.plugins, deps.llmManager, deps.mcpManager)
+
+	// Throttle run-starting endpoints per session / API key / client IP.
+	chatRateLimit := middleware.RateLimit(deps.rateLimit, middleware.NewMemoryRateLimitStore())
+
+	// Readiness: plugin health, default model and MCP servers.
+	g.GET("/readyz", healthHandler.Ready)
 
 	// --- /v1 route group ---
 	apiV1 := g.Group("/v1")
 	{
 		// OpenAI-compatible endpoints.
-		apiV1.POST("/chat/completions", chatHandler.Handle)
+		apiV1.POST("/chat/completions", chatRateLimit, chatHandler.Handle)
 		apiV1.GET("/models", modelHandler.List)
+		apiV1.POST("/models/scan", modelHandler.Scan)
 
 		// Agent CRUD.
 		apiV1.POST("/agents", agentHandler.Create)
 		apiV1.GET("/agents", agentHandler.List)
 		apiV1.GET("/agents/:id", agentHandler.Get)
+		apiV1.PATCH("/agents/:id", agentHandler.Update)
 		apiV1.DELETE("/agents/:id", agentHandler.Delete)
+		apiV1.POST("/agents/:id/prompt-preview", agentHandler.PromptPreview)
+		apiV1.GET("/agents/:id/tools", agentHandler.Tools)
 
 		// Session management.
 		apiV1.GET("/agents/:id/sessions", sessionHandler.ListByAgent)
+		apiV1.GET("/sessions", sessionHandler.List)
 		apiV1.GET("/sessions/:id", sessionHandler.Get)
 		apiV1.DELETE("/sessions/:id", sessionHandler.Delete)
+
+		// Memory diagnostics.
+		apiV1.GET("/memory/status", memoryHandler.Status)
+		apiV1.GET("/memory/files", memoryHandler.Files)
 	}
 }
@@ -2,12 +2,18 @@ package hivemind
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"strings"
 
 	"github.com/cloudwego/eino/components/model"
 	"github.com/kiosk404/echoryn/internal/hivemind/config"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents"
+	agentEntity "github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	agentService "github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
@@ -32,6 +38,7 @@ type apiServer struct {
 	llmModule       *llm.Module
 	mcpModule       *mcp.Module
 	agentsModule    *agents.Module
+	gatewayConfig   *GatewayConfig
 }
 
 type preparedAPIServer struct {
@@ -99,16 +106,24 @@ func createAPIServer(cfg *config.Config) (*apiServer, error) {
 		return nil, fmt.Errorf("failed to initialize LLM module: %w", err)
 	}
 	logger.Info("LLM module initialized successfully")
+	runtimeAPI := plugin.NewRuntimeAPI(&modelManagerAdapter{llmModule.Manager})
 	pluginCfg := &plugin.Config{
 		SlotConfig: plugin.SlotConfig{
 			"memory": cfg.PluginOptions.Slots.Memory,
 		},
-		RuntimeAPI: plugin.NewRuntimeAPI(&modelManagerAdapter{llmModule.Manager}),
+		RuntimeAPI: runtimeAPI,
 	}
 	pluginFramework := pluginCfg.Complete().New()
 
 	// PromptPipeline is always created (builtin sections work without plugins).
-	promptPipeline := prompt.NewDefaultPipeline()
+	promptPipeline, err := prompt.NewDefaultPipeline(prompt.PipelineOptions{
+		RedactionPatterns: cfg.AgentOptions.Prompt.RedactionPatterns,
+		ExtraHeading:      cfg.AgentOptions.Prompt.ExtraHeading,
+		ExtraMaxValueLen:  cfg.AgentOptions.Prompt.ExtraMaxValueLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompt pipeline: %w", err)
+	}
 	pluginFramework.SetPromptPipeline(promptPipeline)
 
 	if cfg.PluginOptions.Enabled {
@@ -151,7 +166,15 @@ func createAPIServer(cfg *config.Config) (*apiServer, error) {
 	logger.Info("[Hivemind] MCP module initialized successfully")
 
 	// Initialize Agents module (K8S-style: Config → Complete → New).
-	agentsCfg := &agents.Config{}
+	agentsCfg := &agents.Config{
+		Timezone:          cfg.AgentOptions.Timezone,
+		TokenizerFile:     cfg.AgentOptions.TokenizerFile,
+		TokenizerEncoding: cfg.AgentOptions.TokenizerEncoding,
+		TokenizerFiles:    cfg.AgentOptions.TokenizerFiles,
+		StoreType:         cfg.AgentOptions.StoreType,
+		BoltDBPath:        cfg.AgentOptions.BoltDBPath,
+		DSN:               cfg.AgentOptions.DSN,
+	}
 	agentsModule, err := agentsCfg.Complete().New(context.Background(), agents.Dependencies{
 		LLM:     llmModule,
 		Plugins: pluginFramework,
@@ -162,6 +185,10 @@ func createAPIServer(cfg *config.Config) (*apiServer, error) {
 	}
 	logger.Info("[Hivemind] Agents module initialized successfully")
 
+	// Plugins are initialized before the Agents module; bind it now so that
+	// plugin tools (e.g. delegate) can run agents.
+	plugin.SetAgentRunner(runtimeAPI, &agentRunnerAdapter{agentsModule.Service})
+
 	server := &apiServer{
 		gs:               gs,
 		genericAPIServer: genericServer,
@@ -170,22 +197,31 @@ func createAPIServer(cfg *config.Config) (*apiServer, error) {
 		pluginFramework:  pluginFramework,
 		mcpModule:        mcpModule,
 		agentsModule:     agentsModule,
+		gatewayConfig:    buildGatewayConfig(cfg.GatewayOptions),
 	}
 
 	return server, nil
 }
 
 func (s *apiServer) PrepareRun() preparedAPIServer {
-	gatewayCfg := DefaultGatewayConfig()
+	gatewayCfg := s.gatewayConfig
 
 	initRouter(s.genericAPIServer.Engine, &routerDeps{
 		agentService:  s.agentsModule.Service,
 		llmManager:    s.llmModule.Manager,
+		llmProber:     s.llmModule.Prober,
+		plugins:       s.pluginFramework,
+		mcpManager:    s.mcpModule.Manager,
 		authConfig:    &gatewayCfg.Auth,
+		rateLimit:     &gatewayCfg.RateLimit,
 		gatewayConfig: gatewayCfg,
 	})
 
+	// SIGHUP reloads provider/model config without a restart.
+	stopReload := s.watchReloadSignal()
+
 	s.gs.AddShutdownCallback(shutdown.Func(func(string) error {
+		stopReload()
 		// Stop Plugin framework (reverse lifecycle: hooks -> services -> plugins).
 		if s.pluginFramework != nil {
 			s.pluginFramework.Stop(context.Background())
@@ -248,3 +284,55 @@ func (m modelManagerAdapter) GetChatModel(ctx context.Context, provideID, modelI
 func (m modelManagerAdapter) GetDefaultChatModel(ctx context.Context) (model.BaseChatModel, error) {
 	return m.llmManager.GetDefaultChatModel(ctx)
 }
+
+// --- AgentRunner Adapter ---
+// Bridge between plugin.AgentRunner (blocking) and AgentService.Run (streaming)
+type agentRunnerAdapter struct {
+	agentService agentService.AgentService
+}
+
+var _ plugin.AgentRunner = (*agentRunnerAdapter)(nil)
+
+func (a agentRunnerAdapter) RunAgent(ctx context.Context, agentID, input, promptMode string) (string, error) {
+	sr, err := a.agentService.Run(ctx, &runtime.RunRequest{
+		AgentID:    agentID,
+		Input:      input,
+		PromptMode: promptMode,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer sr.Close()
+
+	var answer strings.Builder
+	var lastErr string
+	for {
+		event, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch event.Type {
+		case agentEntity.EventTextDelta:
+			answer.WriteString(event.Delta)
+		case agentEntity.EventModelSwitch:
+			// Drop the output of the attempt that failed mid-stream.
+			answer.Reset()
+		case agentEntity.EventError:
+			lastErr = event.Error
+		case agentEntity.EventRunStatus:
+			if event.RunStatus == agentEntity.RunStatusFailed {
+				lastErr = event.Error
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if lastErr != "" && answer.Len() == 0 {
+		return "", errors.New(lastErr)
+	}
+	return answer.String(), nil
+}
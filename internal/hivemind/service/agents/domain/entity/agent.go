@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"fmt"
 	"time"
 
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
@@ -43,19 +44,41 @@ type Agent struct {
 	// When the primary model fails, candidates are tried in order.
 	Fallback llmEntity.FallbackConfig `json:"fallback"`
 
+	// ToolMode controls how Tools is interpreted when exposing plugin tools.
+	// Empty means "allowlist" when Tools is set, "all" otherwise (backward compat).
+	ToolMode ToolMode `json:"tool_mode,omitempty"`
+
 	// Tools is the list of tool names this agent can use.
 	// References tool IDs registered in the plugin.Registry.
+	// Interpreted according to ToolMode (allowlist or denylist).
 	Tools []string `json:"tools,omitempty"`
 
+	// DeniedTools names tools that are never exposed, applied after ToolMode/Tools.
+	// Matches plugin tool names and MCP tool names (either the namespaced
+	// "<server>__<tool>" form or the bare MCP tool name).
+	DeniedTools []string `json:"denied_tools,omitempty"`
+
 	// MCPServers is the list of MCP server names this agent can use.
 	// References server names defined in mcp.json.
 	// If empty, all connected MCP servers' tools are available.
 	MCPServers []string `json:"mcp_servers,omitempty"`
 
+	// MCPTools narrows the tools of the selected MCP servers to the named
+	// ones (namespaced "<server>__<tool>" or bare MCP tool names).
+	// If empty, all tools of the selected servers are available.
+	MCPTools []string `json:"mcp_tools,omitempty"`
+
 	// MaxTurns is the maximum number of tool-call turns per run.
 	// Prevents infinite tool loops. 0 means use module default.
 	MaxTurns int `json:"max_turns,omitempty"`
 
+	// MaxTurnsBehavior controls how a run ends when MaxTurns is reached.
+	// Empty means MaxTurnsHardStop.
+	MaxTurnsBehavior MaxTurnsBehavior `json:"max_turns_behavior,omitempty"`
+
+	// ToolBudget caps the tool calls of a single run. nil means unlimited.
+	ToolBudget *ToolBudget `json:"tool_budget,omitempty"`
+
 	// Temperature controls the LLM sampling temperature.
 	// nil means use model default.
 	Temperature *float64 `json:"temperature,omitempty"`
@@ -64,6 +87,14 @@ type Agent struct {
 	// nil means use model default.
 	MaxTokens *int `json:"max_tokens,omitempty"`
 
+	// ReserveTokens overrides the number of context-window tokens kept free
+	// for the model's output. nil means derive it from the model.
+	ReserveTokens *int `json:"reserve_tokens,omitempty"`
+
+	// Memory toggles the memory plugin for this agent: its tools, its prompt
+	// section and end-of-run memory extraction. nil means enabled.
+	Memory *bool `json:"memory,omitempty"`
+
 	// CreatedAt is when this agent was created.
 	CreatedAt time.Time `json:"created_at"`
 
@@ -71,6 +102,76 @@ type Agent struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ToolMode controls which plugin tools are exposed to an agent.
+type ToolMode string
+
+const (
+	// ToolModeAll exposes every registered plugin tool; Tools is ignored.
+	ToolModeAll ToolMode = "all"
+	// ToolModeAllowlist exposes only the tools named in Tools.
+	// An empty allowlist exposes no tools.
+	ToolModeAllowlist ToolMode = "allowlist"
+	// ToolModeDenylist exposes every registered tool except those named in Tools.
+	ToolModeDenylist ToolMode = "denylist"
+)
+
+// IsValid returns true if the mode is empty or one of the known modes.
+func (m ToolMode) IsValid() bool {
+	switch m {
+	case "", ToolModeAll, ToolModeAllowlist, ToolModeDenylist:
+		return true
+	}
+	return false
+}
+
+// MaxTurnsBehavior controls how a run ends when its tool loop reaches MaxTurns.
+type MaxTurnsBehavior string
+
+const (
+	// MaxTurnsHardStop ends the run at the cap with an error event and
+	// finish reason FinishReasonMaxTurns. The final message keeps the text
+	// the model wrote so far, followed by a stop notice.
+	MaxTurnsHardStop MaxTurnsBehavior = "hard_stop"
+	// MaxTurnsForceFinalAnswer makes one more LLM call without tools so the
+	// model answers from what it gathered so far.
+	MaxTurnsForceFinalAnswer MaxTurnsBehavior = "force_final_answer"
+)
+
+// IsValid returns true if the behavior is empty or one of the known behaviors.
+func (b MaxTurnsBehavior) IsValid() bool {
+	switch b {
+	case "", MaxTurnsHardStop, MaxTurnsForceFinalAnswer:
+		return true
+	}
+	return false
+}
+
+// ToolBudget caps tool calls per run. A call beyond a limit is not executed;
+// the model receives an error as the tool result instead, so it can adapt.
+type ToolBudget struct {
+	// MaxCalls limits the total number of tool calls per run. 0 means unlimited.
+	MaxCalls int `json:"max_calls,omitempty"`
+
+	// PerTool limits the calls of individual tools per run, keyed by tool name.
+	PerTool map[string]int `json:"per_tool,omitempty"`
+}
+
+// Validate reports negative limits.
+func (b *ToolBudget) Validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.MaxCalls < 0 {
+		return fmt.Errorf("max_calls must not be negative")
+	}
+	for name, limit := range b.PerTool {
+		if limit < 0 {
+			return fmt.Errorf("per_tool limit of %q must not be negative", name)
+		}
+	}
+	return nil
+}
+
 // AgentPersona defines the agent's identity and prompt assembly configuration.
 //
 // This is the Eidolon equivalent of OpenClaw's IdentityConfig + workspace file system.
@@ -94,6 +195,10 @@ type AgentPersona struct {
 	// and registers them as dynamic PromptSections (P1 feature).
 	WorkspaceDir string `json:"workspace_dir,omitempty"`
 
+	// Timezone overrides the server default timezone (IANA name, e.g., "Europe/Berlin")
+	// for the current time shown in the system prompt.
+	Timezone string `json:"timezone,omitempty"`
+
 	// ExtraSections holds user-defined additional prompt sections.
 	// Key is the section heading, value is the Markdown content.
 	// Analogous to K8s sidecar injection — extra content added without modifying core pipeline.
@@ -127,6 +232,24 @@ func (a *Agent) EffectivePromptMode() string {
 	return "full"
 }
 
+// EffectiveToolMode returns the agent's tool mode.
+// When ToolMode is unset, a non-empty Tools list is treated as an allowlist
+// and an empty one as "all", preserving the original behavior.
+func (a *Agent) EffectiveToolMode() ToolMode {
+	if a.ToolMode != "" {
+		return a.ToolMode
+	}
+	if len(a.Tools) > 0 {
+		return ToolModeAllowlist
+	}
+	return ToolModeAll
+}
+
+// MemoryEnabled reports whether the memory plugin serves this agent.
+func (a *Agent) MemoryEnabled() bool {
+	return a.Memory == nil || *a.Memory
+}
+
 // LLMParams converts agent configuration to LLM parameters.
 func (a *Agent) LLMParams() *llmEntity.LLMParams {
 	params := &llmEntity.LLMParams{}
@@ -147,3 +270,11 @@ func (a *Agent) EffectiveMaxTurns(defaultMax int) int {
 	}
 	return defaultMax
 }
+
+// EffectiveMaxTurnsBehavior returns the max-turns behavior, defaulting to MaxTurnsHardStop.
+func (a *Agent) EffectiveMaxTurnsBehavior() MaxTurnsBehavior {
+	if a.MaxTurnsBehavior == "" {
+		return MaxTurnsHardStop
+	}
+	return a.MaxTurnsBehavior
+}
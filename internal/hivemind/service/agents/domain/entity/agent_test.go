@@ -0,0 +1,43 @@
+package entity
+
+import "testing"
+
+func TestToolModeIsValid(t *testing.T) {
+	tests := []struct {
+		mode ToolMode
+		want bool
+	}{
+		{"", true},
+		{ToolModeAll, true},
+		{ToolModeAllowlist, true},
+		{ToolModeDenylist, true},
+		{"blocklist", false},
+		{"ALL", false},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.IsValid(); got != tt.want {
+			t.Errorf("ToolMode(%q).IsValid() = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveToolMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent Agent
+		want  ToolMode
+	}{
+		{"unset without tools", Agent{}, ToolModeAll},
+		{"unset with tools", Agent{Tools: []string{"web_search"}}, ToolModeAllowlist},
+		{"explicit all ignores tools", Agent{ToolMode: ToolModeAll, Tools: []string{"web_search"}}, ToolModeAll},
+		{"explicit allowlist without tools", Agent{ToolMode: ToolModeAllowlist}, ToolModeAllowlist},
+		{"explicit denylist", Agent{ToolMode: ToolModeDenylist, Tools: []string{"web_search"}}, ToolModeDenylist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.agent.EffectiveToolMode(); got != tt.want {
+				t.Fatalf("EffectiveToolMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
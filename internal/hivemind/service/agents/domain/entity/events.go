@@ -23,7 +23,24 @@ const (
 	// EventError indicates an error occurred during the run.
 	EventError EventType = "error"
 
+	// EventModelSwitch indicates the model failed after output was streamed and
+	// the turn restarts on the next fallback candidate. Text and tool calls
+	// received earlier in the turn belong to the failed attempt and should be
+	// discarded. Error carries a short notice for display.
+	EventModelSwitch EventType = "model_switch"
+
+	// EventUsageDelta reports the tokens used by one internal LLM call of the
+	// run. Usage holds the call's usage and TotalUsage the run total so far.
+	EventUsageDelta EventType = "usage_delta"
+
+	// EventCompaction reports session compaction progress: once when it starts
+	// and once when it ends. Compaction holds the reason and token counts.
+	// Proactive compaction runs in the background after the stream ends, so
+	// it is only reported as scheduled.
+	EventCompaction EventType = "compaction"
+
 	// EventDone indicates the run has completed and the stream is ending.
+	// A run with several choices sends one per successful choice.
 	EventDone EventType = "done"
 
 	// EventSubAgentSpawned indicates a sub-agent has been spawned.
@@ -35,6 +52,61 @@ const (
 	EventSubAgentCompleted EventType = "subagent_completed"
 )
 
+// FinishReason tells why a run ended other than by the model finishing on its own.
+type FinishReason string
+
+const (
+	// FinishReasonMaxTurns means the tool loop reached the agent's MaxTurns.
+	FinishReasonMaxTurns FinishReason = "max_turns"
+
+	// FinishReasonLength means the model's last reply hit its output token limit.
+	FinishReasonLength FinishReason = "length"
+
+	// FinishReasonContentFilter means the provider's content filter cut the reply.
+	FinishReasonContentFilter FinishReason = "content_filter"
+
+	// FinishReasonError means the run failed or was aborted.
+	FinishReasonError FinishReason = "error"
+)
+
+// CompactionReason tells why a session was compacted.
+type CompactionReason string
+
+const (
+	// CompactionReasonOverflow means the model rejected the prompt as too long
+	// and the turn is retried after compaction.
+	CompactionReasonOverflow CompactionReason = "overflow"
+
+	// CompactionReasonThreshold means the session crossed the proactive
+	// compaction threshold after a turn.
+	CompactionReasonThreshold CompactionReason = "threshold"
+)
+
+// CompactionStatus is the phase reported by an EventCompaction event.
+type CompactionStatus string
+
+const (
+	CompactionStarted   CompactionStatus = "started"
+	CompactionCompleted CompactionStatus = "completed"
+	CompactionFailed    CompactionStatus = "failed"
+
+	// CompactionScheduled means the compaction was queued to run in the
+	// background once the run has ended.
+	CompactionScheduled CompactionStatus = "scheduled"
+)
+
+// CompactionInfo describes a compaction for EventCompaction events.
+type CompactionInfo struct {
+	Reason CompactionReason `json:"reason"`
+	Status CompactionStatus `json:"status"`
+
+	// TokensBefore is the estimated size of the active history before compaction.
+	TokensBefore int `json:"tokens_before"`
+
+	// TokensAfter is the estimated size after compaction; set once completed.
+	TokensAfter int `json:"tokens_after,omitempty"`
+}
+
 // AgentEvent is a streaming event emitted during agent execution.
 //
 // This flows through schema.Pipe[*AgentEvent] from the execution goroutine
@@ -55,12 +127,29 @@ type AgentEvent struct {
 	// RunStatus contains the new status for EventRunStatus events.
 	RunStatus RunStatus `json:"run_status,omitempty"`
 
-	// Error contains the error message for EventError events.
+	// Error contains the error message for EventError events
+	// and the notice for EventModelSwitch events.
 	Error string `json:"error,omitempty"`
 
-	// Usage contains token usage information for EventDone events.
+	// Usage contains token usage information for EventDone events
+	// and the per-call usage for EventUsageDelta events.
 	Usage *TokenUsage `json:"usage,omitempty"`
 
+	// FinishReason tells why the run ended, for EventDone events and
+	// EventRunStatus events of failed runs. Empty means the model finished
+	// on its own.
+	FinishReason FinishReason `json:"finish_reason,omitempty"`
+
+	// TotalUsage is the cumulative run usage for EventUsageDelta events.
+	TotalUsage *TokenUsage `json:"total_usage,omitempty"`
+
+	// Choice is the index of the completion the event belongs to, for runs
+	// producing several choices (RunRequest.Choices > 1). Always 0 otherwise.
+	Choice int `json:"choice,omitempty"`
+
+	// Compaction describes the compaction for EventCompaction events.
+	Compaction *CompactionInfo `json:"compaction,omitempty"`
+
 	// SubAgentID is the sub-agent record ID for EventSubAgentSpawned/EventSubAgentCompleted.
 	// TODO(subagent): Populate when emitting sub-agent events.
 	SubAgentID string `json:"subagent_id,omitempty"`
@@ -36,6 +36,10 @@ type Message struct {
 	// Only present when Role == RoleTool.
 	ToolCallID string `json:"tool_call_id,omitempty"`
 
+	// Images are image inputs sent along with the text.
+	// Only present when Role == RoleUser.
+	Images []*ImageContent `json:"images,omitempty"`
+
 	// Metadata holds additional information (e.g., model name, latency).
 	Metadata map[string]string `json:"metadata,omitempty"`
 
@@ -43,6 +47,15 @@ type Message struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ImageContent is an image input of a user message.
+type ImageContent struct {
+	// URL is an http(s) URL or a base64 "data:" URL.
+	URL string `json:"url"`
+
+	// Detail is the requested image fidelity: "auto", "low" or "high" (optional).
+	Detail string `json:"detail,omitempty"`
+}
+
 // NewSystemMessage creates a system message.
 func NewSystemMessage(content string) *Message {
 	return &Message{
@@ -50,6 +50,10 @@ type Run struct {
 	// Usage tracks token usage for this run.
 	Usage *TokenUsage `json:"usage,omitempty"`
 
+	// Cost is the monetary cost of this run, computed from Usage and the
+	// serving model's pricing. nil when the model has no pricing configured.
+	Cost *float64 `json:"cost,omitempty"`
+
 	// Error holds error details if the run failed.
 	Error *RunError `json:"error,omitempty"`
 
@@ -26,6 +26,10 @@ type Session struct {
 	// Usage tracks cumulative token usage across all runs.
 	Usage *TokenUsage `json:"usage,omitempty"`
 
+	// TotalCost is the cumulative cost across all priced runs.
+	// nil until at least one run was served by a model with pricing configured.
+	TotalCost *float64 `json:"total_cost,omitempty"`
+
 	// Metadata holds arbitrary key-value pairs for extensibility.
 	Metadata map[string]string `json:"metadata,omitempty"`
 
@@ -47,6 +51,35 @@ type Session struct {
 
 	// UpdatedAt is when this session was last updated.
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Version is incremented by every successful SessionRepository.Update.
+	// An update carrying a stale version is rejected, so concurrent writers
+	// (e.g. several gateway instances sharing a store) cannot overwrite each
+	// other's changes.
+	Version int64 `json:"version"`
+}
+
+// Clone returns a copy of the session that can be modified without
+// affecting s. Messages themselves are shared, as they are never modified
+// once appended.
+func (s *Session) Clone() *Session {
+	cp := *s
+	cp.Messages = append([]*Message(nil), s.Messages...)
+	if s.Usage != nil {
+		usage := *s.Usage
+		cp.Usage = &usage
+	}
+	if s.TotalCost != nil {
+		cost := *s.TotalCost
+		cp.TotalCost = &cost
+	}
+	if s.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(s.Metadata))
+		for k, v := range s.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+	return &cp
 }
 
 // AppendMessage appends a message to the session history.
@@ -74,6 +107,17 @@ func (s *Session) AddUsage(usage *TokenUsage) {
 	s.Usage.TotalTokens += usage.TotalTokens
 }
 
+// AddCost accumulates run cost. A nil cost (unpriced model) is ignored.
+func (s *Session) AddCost(cost *float64) {
+	if cost == nil {
+		return
+	}
+	if s.TotalCost == nil {
+		s.TotalCost = new(float64)
+	}
+	*s.TotalCost += *cost
+}
+
 // ActiveMessages returns the messages that are still active (not compacted).
 // If compaction has occurred, only messages from FirstKeptIndex onward are returned.
 func (s *Session) ActiveMessages() []*Message {
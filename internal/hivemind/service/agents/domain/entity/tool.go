@@ -20,4 +20,6 @@ type ToolResult struct {
 	Content string `json:"content"`
 	// Error is the error message if the tool call failed.
 	Error string `json:"error"`
+	// Truncated is true when Content was cut short for event delivery.
+	Truncated bool `json:"truncated,omitempty"`
 }
@@ -16,4 +16,6 @@ type RunRepository interface {
 	Update(ctx context.Context, run *entity.Run) error
 	// ListBySession returns all runs for a given session.
 	ListBySession(ctx context.Context, sessionID string) ([]*entity.Run, error)
+	// DeleteBySession removes all runs of a given session.
+	DeleteBySession(ctx context.Context, sessionID string) error
 }
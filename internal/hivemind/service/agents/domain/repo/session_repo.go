@@ -12,10 +12,16 @@ type SessionRepository interface {
 	Create(ctx context.Context, session *entity.Session) error
 	// Get retrieves a session by ID.
 	Get(ctx context.Context, id string) (*entity.Session, error)
-	// Update updates an existing session.
+	// Update updates an existing session and increments its Version.
+	// It returns errno.ErrVersionConflict, leaving the stored session
+	// untouched, if session.Version differs from the stored one; callers
+	// reload the session and re-apply their changes.
 	Update(ctx context.Context, session *entity.Session) error
 	// Delete removes a session by ID.
 	Delete(ctx context.Context, id string) error
 	// ListByAgent returns all sessions for a given agent.
 	ListByAgent(ctx context.Context, agentID string) ([]*entity.Session, error)
+	// List returns sessions ordered by UpdatedAt, most recent first.
+	// An empty agentID lists sessions of every agent.
+	List(ctx context.Context, agentID string) ([]*entity.Session, error)
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/cloudwego/eino/schema"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
 )
 
 // AgentService is the application-level service interface for agent management and execution.
@@ -20,13 +21,22 @@ type AgentService interface {
 	CreateAgent(ctx context.Context, agent *entity.Agent) error
 	GetAgent(ctx context.Context, id string) (*entity.Agent, error)
 	ListAgents(ctx context.Context) ([]*entity.Agent, error)
+	// UpdateAgent replaces a stored agent, bumps UpdatedAt and drops cached
+	// chat models for both the previous and the new model reference.
 	UpdateAgent(ctx context.Context, agent *entity.Agent) error
 	DeleteAgent(ctx context.Context, id string) error
+	// PreviewPrompt renders the system prompt the agent would receive, with a per-section breakdown.
+	PreviewPrompt(ctx context.Context, id string) (*prompt.AssembleReport, error)
+	// EffectiveTools lists the plugin and MCP tools the agent would receive at run time.
+	EffectiveTools(ctx context.Context, id string) ([]prompt.ToolSummary, error)
 
 	// --- Session Management ---
 
 	GetSession(ctx context.Context, id string) (*entity.Session, error)
 	ListSessionsByAgent(ctx context.Context, agentID string) ([]*entity.Session, error)
+	// ListSessions returns sessions ordered by most recent activity; empty agentID lists all.
+	ListSessions(ctx context.Context, agentID string) ([]*entity.Session, error)
+	// DeleteSession removes a session together with its runs.
 	DeleteSession(ctx context.Context, id string) error
 
 	// --- Run Execution ---
@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/repo"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
 )
 
 // agentServiceImpl implements the AgentService interface.
@@ -15,20 +19,26 @@ type agentServiceImpl struct {
 	sessionRepo repo.SessionRepository
 	runRepo     repo.RunRepository
 	runner      *runtime.AgentRunner
+	models      llmService.ModelManager
 }
 
 func NewAgentService(agentRepo repo.AgentRepository,
 	sessionRepo repo.SessionRepository,
-	runRepo repo.RunRepository, runner *runtime.AgentRunner) AgentService {
+	runRepo repo.RunRepository, runner *runtime.AgentRunner,
+	models llmService.ModelManager) AgentService {
 	return &agentServiceImpl{
 		agentRepo:   agentRepo,
 		sessionRepo: sessionRepo,
 		runRepo:     runRepo,
 		runner:      runner,
+		models:      models,
 	}
 }
 
 func (a agentServiceImpl) CreateAgent(ctx context.Context, agent *entity.Agent) error {
+	if err := validateAgentModel(ctx, a.models, a.runner.VisionTools(), agent); err != nil {
+		return err
+	}
 	return a.agentRepo.Create(ctx, agent)
 }
 
@@ -41,13 +51,42 @@ func (a agentServiceImpl) ListAgents(ctx context.Context) ([]*entity.Agent, erro
 }
 
 func (a agentServiceImpl) UpdateAgent(ctx context.Context, agent *entity.Agent) error {
-	return a.agentRepo.Update(ctx, agent)
+	prev, err := a.agentRepo.Get(ctx, agent.ID)
+	if err != nil {
+		return err
+	}
+	prevRef := prev.ModelRef
+
+	if err := validateAgentModel(ctx, a.models, a.runner.VisionTools(), agent); err != nil {
+		return err
+	}
+	agent.UpdatedAt = time.Now()
+	if err := a.agentRepo.Update(ctx, agent); err != nil {
+		return err
+	}
+
+	if a.models != nil {
+		for _, ref := range []llmEntity.ModelRef{prevRef, agent.ModelRef} {
+			if ref.ProviderID != "" && ref.ModelID != "" {
+				a.models.InvalidateChatModel(ref)
+			}
+		}
+	}
+	return nil
 }
 
 func (a agentServiceImpl) DeleteAgent(ctx context.Context, id string) error {
 	return a.agentRepo.Delete(ctx, id)
 }
 
+func (a agentServiceImpl) PreviewPrompt(ctx context.Context, id string) (*prompt.AssembleReport, error) {
+	return a.runner.PreviewPrompt(ctx, id)
+}
+
+func (a agentServiceImpl) EffectiveTools(ctx context.Context, id string) ([]prompt.ToolSummary, error) {
+	return a.runner.EffectiveTools(ctx, id)
+}
+
 func (a agentServiceImpl) GetSession(ctx context.Context, id string) (*entity.Session, error) {
 	return a.sessionRepo.Get(ctx, id)
 }
@@ -56,8 +95,15 @@ func (a agentServiceImpl) ListSessionsByAgent(ctx context.Context, agentID strin
 	return a.sessionRepo.ListByAgent(ctx, agentID)
 }
 
+func (a agentServiceImpl) ListSessions(ctx context.Context, agentID string) ([]*entity.Session, error) {
+	return a.sessionRepo.List(ctx, agentID)
+}
+
 func (a agentServiceImpl) DeleteSession(ctx context.Context, id string) error {
-	return a.sessionRepo.Delete(ctx, id)
+	if err := a.sessionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return a.runRepo.DeleteBySession(ctx, id)
 }
 
 func (a agentServiceImpl) Run(ctx context.Context, req *runtime.RunRequest) (*schema.StreamReader[*entity.AgentEvent], error) {
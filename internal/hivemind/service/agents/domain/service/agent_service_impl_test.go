@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+// invalidatingModels records the chat models dropped from the cache.
+type invalidatingModels struct {
+	fakeModels
+	invalidated []string
+}
+
+func (m *invalidatingModels) InvalidateChatModel(ref llmEntity.ModelRef) {
+	m.invalidated = append(m.invalidated, ref.String())
+}
+
+func TestUpdateAgent(t *testing.T) {
+	ref := func(model string) llmEntity.ModelRef { return llmEntity.ModelRef{ProviderID: "p", ModelID: model} }
+	tests := []struct {
+		name            string
+		stored          *entity.Agent
+		update          *entity.Agent
+		wantErr         error
+		wantInvalidated []string
+	}{
+		{"model switched",
+			&entity.Agent{ID: "a", ModelRef: ref("text")},
+			&entity.Agent{ID: "a", ModelRef: ref("vision")},
+			nil, []string{"p/text", "p/vision"}},
+		{"model set",
+			&entity.Agent{ID: "a"},
+			&entity.Agent{ID: "a", ModelRef: ref("text")},
+			nil, []string{"p/text"}},
+		{"model cleared",
+			&entity.Agent{ID: "a", ModelRef: ref("text")},
+			&entity.Agent{ID: "a"},
+			nil, []string{"p/text"}},
+		{"unknown model",
+			&entity.Agent{ID: "a", ModelRef: ref("text")},
+			&entity.Agent{ID: "a", ModelRef: ref("missing")},
+			errno.ErrModelNotFound, nil},
+		{"missing agent",
+			&entity.Agent{ID: "b"},
+			&entity.Agent{ID: "a", ModelRef: ref("text")},
+			errno.ErrAgentNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			agents := inmemory.NewAgentStore()
+			if err := agents.Create(ctx, tt.stored); err != nil {
+				t.Fatal(err)
+			}
+			models := &invalidatingModels{fakeModels: testModels}
+			svc := NewAgentService(agents, nil, nil, nil, models)
+
+			err := svc.UpdateAgent(ctx, tt.update)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateAgent = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(models.invalidated, tt.wantInvalidated) {
+				t.Fatalf("invalidated = %v, want %v", models.invalidated, tt.wantInvalidated)
+			}
+
+			stored, _ := agents.Get(ctx, tt.stored.ID)
+			if updated := stored == tt.update; updated != (err == nil) {
+				t.Fatalf("stored agent replaced = %v after error %v", updated, err)
+			}
+			if err == nil && stored.UpdatedAt.IsZero() {
+				t.Fatal("UpdatedAt not set")
+			}
+		})
+	}
+}
+
+func TestDeleteSessionDeletesRuns(t *testing.T) {
+	ctx := context.Background()
+	sessions, runs := inmemory.NewSessionStore(), inmemory.NewRunStore()
+	for _, id := range []string{"s1", "s2"} {
+		if err := sessions.Create(ctx, &entity.Session{ID: id, AgentID: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := runs.Create(ctx, &entity.Run{ID: "run-" + id, SessionID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewAgentService(inmemory.NewAgentStore(), sessions, runs, nil, testModels)
+
+	if err := svc.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := sessions.Get(ctx, "s1"); err == nil {
+		t.Fatal("session still stored")
+	}
+	if left, _ := runs.ListBySession(ctx, "s1"); len(left) != 0 {
+		t.Fatalf("%d runs of the deleted session left", len(left))
+	}
+	if left, _ := runs.ListBySession(ctx, "s2"); len(left) != 1 {
+		t.Fatalf("runs of another session = %d, want 1", len(left))
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// validateAgentModel cross-checks the agent's requirements against the
+// capabilities of its bound model.
+//
+// An agent that explicitly requests tools (allowlist with entries, MCP servers or tools)
+// is rejected when the primary model cannot call tools. Implicit tool exposure
+// (tool mode "all"/"denylist") and fallback candidates only produce warnings,
+// since the effective tool set depends on which plugins are loaded at run time.
+//
+// Likewise, an agent whose allowlist names one of visionTools (the tools that
+// work on images) is rejected when the primary model has no image
+// understanding, and warned about for each such fallback model.
+func validateAgentModel(ctx context.Context, mm llmService.ModelManager, visionTools []string, agent *entity.Agent) error {
+	if mm == nil || agent.ModelRef.ProviderID == "" || agent.ModelRef.ModelID == "" {
+		return nil
+	}
+
+	toolCapable, err := isToolCapable(ctx, mm, agent.ModelRef)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", errno.ErrModelNotFound, agent.ModelRef, err)
+	}
+
+	requiresTools := len(agent.MCPServers) > 0 || len(agent.MCPTools) > 0 ||
+		(agent.EffectiveToolMode() == entity.ToolModeAllowlist && len(agent.Tools) > 0)
+
+	if !toolCapable {
+		if requiresTools {
+			return fmt.Errorf("%w: agent %q uses tools but model %s does not support function calling",
+				errno.ErrModelNotToolCapable, agent.ID, agent.ModelRef)
+		}
+		if agent.EffectiveToolMode() != entity.ToolModeAllowlist {
+			logger.WarnX(pkg.ModuleName, "[AgentService] agent %q exposes plugin tools (mode=%s) but model %s does not support function calling",
+				agent.ID, agent.EffectiveToolMode(), agent.ModelRef)
+		}
+	}
+
+	if requiresTools {
+		for _, ref := range agent.Fallback.Fallbacks {
+			if ok, err := isToolCapable(ctx, mm, ref); err == nil && !ok {
+				logger.WarnX(pkg.ModuleName, "[AgentService] fallback model %s for agent %q does not support function calling", ref, agent.ID)
+			}
+		}
+	}
+
+	if tool := allowedVisionTool(agent, visionTools); tool != "" {
+		if ok, err := isVisionCapable(ctx, mm, agent.ModelRef); err == nil && !ok {
+			return fmt.Errorf("%w: agent %q uses image tool %q but model %s does not support image input",
+				errno.ErrModelNotVisionCapable, agent.ID, tool, agent.ModelRef)
+		}
+		for _, ref := range agent.Fallback.Fallbacks {
+			if ok, err := isVisionCapable(ctx, mm, ref); err == nil && !ok {
+				logger.WarnX(pkg.ModuleName, "[AgentService] fallback model %s for agent %q does not support image input", ref, agent.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// allowedVisionTool returns the first tool of the agent's allowlist that is
+// one of visionTools, or "" if there is none.
+func allowedVisionTool(agent *entity.Agent, visionTools []string) string {
+	if agent.EffectiveToolMode() != entity.ToolModeAllowlist {
+		return ""
+	}
+	for _, name := range agent.Tools {
+		if slices.Contains(visionTools, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// isVisionCapable reports whether the model advertises image understanding
+// and the resolved compat rules do not disable it.
+func isVisionCapable(ctx context.Context, mm llmService.ModelManager, ref llmEntity.ModelRef) (bool, error) {
+	instance, err := mm.GetModelByRef(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	if !instance.Capability.ImageUnderstanding {
+		return false, nil
+	}
+	if compat, err := mm.ResolveCompat(ctx, ref); err == nil && compat != nil &&
+		compat.SupportsVision != nil && !*compat.SupportsVision {
+		return false, nil
+	}
+	return true, nil
+}
+
+// isToolCapable reports whether the model advertises function calling and the
+// resolved compat rules do not disable it.
+func isToolCapable(ctx context.Context, mm llmService.ModelManager, ref llmEntity.ModelRef) (bool, error) {
+	instance, err := mm.GetModelByRef(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	if !instance.Capability.FunctionCall {
+		return false, nil
+	}
+	if compat, err := mm.ResolveCompat(ctx, ref); err == nil && compat != nil &&
+		compat.SupportsFunctionCall != nil && !*compat.SupportsFunctionCall {
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+)
+
+// fakeModels is a ModelManager knowing models by "provider/model" name.
+type fakeModels struct {
+	llmService.ModelManager
+	models map[string]llmEntity.ModelAbility
+}
+
+func (f fakeModels) GetModelByRef(_ context.Context, ref llmEntity.ModelRef) (*llmEntity.ModelInstance, error) {
+	ability, ok := f.models[ref.String()]
+	if !ok {
+		return nil, fmt.Errorf("model %s not found", ref)
+	}
+	return &llmEntity.ModelInstance{ProviderID: ref.ProviderID, ModelID: ref.ModelID, Capability: ability}, nil
+}
+
+func (f fakeModels) ResolveCompat(context.Context, llmEntity.ModelRef) (*llmEntity.ModelCompatConfig, error) {
+	return nil, nil
+}
+
+var testModels = fakeModels{models: map[string]llmEntity.ModelAbility{
+	"p/vision": {FunctionCall: true, ImageUnderstanding: true},
+	"p/text":   {FunctionCall: true},
+	"p/plain":  {},
+}}
+
+func TestValidateAgentModel(t *testing.T) {
+	ref := func(model string) llmEntity.ModelRef { return llmEntity.ModelRef{ProviderID: "p", ModelID: model} }
+	visionTools := []string{"image_analyze"}
+
+	tests := []struct {
+		name  string
+		agent *entity.Agent
+		want  error
+	}{
+		{"image tool on vision model",
+			&entity.Agent{ID: "a", ModelRef: ref("vision"), ToolMode: entity.ToolModeAllowlist, Tools: []string{"image_analyze"}}, nil},
+		{"image tool on text model",
+			&entity.Agent{ID: "a", ModelRef: ref("text"), ToolMode: entity.ToolModeAllowlist, Tools: []string{"web_search", "image_analyze"}}, errno.ErrModelNotVisionCapable},
+		{"image tool with text fallback",
+			&entity.Agent{ID: "a", ModelRef: ref("vision"), ToolMode: entity.ToolModeAllowlist, Tools: []string{"image_analyze"},
+				Fallback: llmEntity.FallbackConfig{Fallbacks: []llmEntity.ModelRef{ref("text")}}}, nil},
+		{"text tools on text model",
+			&entity.Agent{ID: "a", ModelRef: ref("text"), ToolMode: entity.ToolModeAllowlist, Tools: []string{"web_search"}}, nil},
+		{"all tools on text model",
+			&entity.Agent{ID: "a", ModelRef: ref("text"), ToolMode: entity.ToolModeAll}, nil},
+		{"tools on model without function calling",
+			&entity.Agent{ID: "a", ModelRef: ref("plain"), ToolMode: entity.ToolModeAllowlist, Tools: []string{"web_search"}}, errno.ErrModelNotToolCapable},
+		{"mcp tools on model without function calling",
+			&entity.Agent{ID: "a", ModelRef: ref("plain"), MCPTools: []string{"github__search"}}, errno.ErrModelNotToolCapable},
+		{"mcp tools on text model",
+			&entity.Agent{ID: "a", ModelRef: ref("text"), MCPTools: []string{"github__search"}}, nil},
+		{"unknown model",
+			&entity.Agent{ID: "a", ModelRef: ref("missing")}, errno.ErrModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAgentModel(context.Background(), testModels, visionTools, tt.agent)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("validateAgentModel = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -60,7 +60,7 @@ func (ac *AbortController) Abort() {
 	}
 	ac.down = true
 	ac.cancel()
-	logger.Info("[AbortController] Abort run %s", ac.runID)
+	logger.CtxInfo(ac.ctx, "[AbortController] Abort run %s", ac.runID)
 }
 
 // IsAborted returns true if the run is aborted.
@@ -90,3 +90,52 @@ func (ac *AbortController) CheckAborted() error {
 func (ac *AbortController) CleanUp() {
 	ac.cancel()
 }
+
+// abortRegistry tracks the abort controllers of in-flight runs so that runs
+// can be cancelled by ID from outside the goroutine executing them.
+type abortRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*activeRun
+}
+
+// activeRun is a single registry entry.
+type activeRun struct {
+	abort    *AbortController
+	onFinish func()
+}
+
+func newAbortRegistry() *abortRegistry {
+	return &abortRegistry{runs: make(map[string]*activeRun)}
+}
+
+// register records a started run. onFinish (optional) is invoked by unregister.
+func (r *abortRegistry) register(runID string, abort *AbortController, onFinish func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[runID] = &activeRun{abort: abort, onFinish: onFinish}
+}
+
+// unregister removes a finished run and fires its onFinish callback.
+func (r *abortRegistry) unregister(runID string) {
+	r.mu.Lock()
+	run, ok := r.runs[runID]
+	delete(r.runs, runID)
+	r.mu.Unlock()
+
+	if ok && run.onFinish != nil {
+		run.onFinish()
+	}
+}
+
+// abort cancels an in-flight run.
+func (r *abortRegistry) abort(runID string) error {
+	r.mu.Lock()
+	run, ok := r.runs[runID]
+	r.mu.Unlock()
+
+	if !ok {
+		return errno.ErrRunNotFound
+	}
+	run.abort.Abort()
+	return nil
+}
@@ -67,7 +67,8 @@ func (b *AgentFlowBuilder) buildWithTools(
 		ToolsConfig: compose.ToolsNodeConfig{
 			Tools: tools,
 		},
-		MaxStep: maxTurns,
+		MaxStep:         maxTurns,
+		MessageModifier: recordModelInput,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ReAct agent: %w", err)
@@ -85,7 +86,7 @@ func (b *AgentFlowBuilder) buildWithTools(
 		return nil, fmt.Errorf("failed to compile ReAct agent chain: %w", err)
 	}
 
-	logger.Info("[AgentFlow] built ReAct agent for %q with %d tools, max_turns=%d",
+	logger.CtxInfo(ctx, "[AgentFlow] built ReAct agent for %q with %d tools, max_turns=%d",
 		agent.ID, len(tools), maxTurns)
 
 	return runnable, nil
@@ -122,6 +123,6 @@ func (b *AgentFlowBuilder) buildWithoutTools(
 		return nil, fmt.Errorf("failed to compile simple agent chain: %w", err)
 	}
 
-	logger.Info("[AgentFlow] built simple ChatModel agent (no tools)")
+	logger.CtxInfo(ctx, "[AgentFlow] built simple ChatModel agent (no tools)")
 	return runnable, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components"
@@ -14,23 +15,51 @@ import (
 	"github.com/kiosk404/echoryn/pkg/logger"
 )
 
+// maxToolResultEventChars caps the tool result content carried by an
+// EventToolCallEnd event. The full result still goes to the model.
+const maxToolResultEventChars = 2000
+
 // ReplayChunkCallback is the Eino callbacks.Handler that intercepts streaming
 // events from the execution graph and translates them into AgentEvent entities.
 // It intercepts:
 // - ChatModel stream outputs -> EventTextDelta events
 // - ToolsNode start -> EventToolCall events
-// - ToolsNode end -> EventToolResult events
+// - ToolsNode end -> EventToolCallEnd events
 // - Errors -> EventError events
-// All events are pushed into a schema.StreamWriter[*entity.AgentEvent].
+// - ChatModel token usage -> the usage handler, once per model call
+// All events are pushed into an EventSink (usually a schema.StreamWriter[*entity.AgentEvent]).
 type ReplayChunkCallback struct {
-	sw *schema.StreamWriter[*entity.AgentEvent]
+	sw      EventSink
+	onUsage func(*entity.TokenUsage)
+
+	// wg tracks the stream-consuming goroutines; see Wait.
+	wg sync.WaitGroup
+}
+
+// EventSink receives the AgentEvents produced by ReplayChunkCallback.
+// *schema.StreamWriter[*entity.AgentEvent] satisfies it.
+type EventSink interface {
+	Send(chunk *entity.AgentEvent, err error) (closed bool)
 }
 
 // NewReplayChunkCallback creates a new ReplayChunkCallback.
-func NewReplayChunkCallback(sw *schema.StreamWriter[*entity.AgentEvent]) *ReplayChunkCallback {
+func NewReplayChunkCallback(sw EventSink) *ReplayChunkCallback {
 	return &ReplayChunkCallback{sw: sw}
 }
 
+// WithUsageHandler registers fn to receive the token usage reported by each
+// ChatModel call. fn runs on the callback's stream goroutines.
+func (r *ReplayChunkCallback) WithUsageHandler(fn func(*entity.TokenUsage)) *ReplayChunkCallback {
+	r.onUsage = fn
+	return r
+}
+
+// Wait blocks until every intercepted stream has been fully consumed, so that
+// all events and usage of a completed run have been delivered.
+func (r *ReplayChunkCallback) Wait() {
+	r.wg.Wait()
+}
+
 // Build returns the Eino callbacks.Handler that intercepts streaming events.
 func (r *ReplayChunkCallback) Build() callbacks.Handler {
 	return callbacks.NewHandlerBuilder().
@@ -45,13 +74,17 @@ func (r *ReplayChunkCallback) Build() callbacks.Handler {
 // When a ToolsNode starts, emit a tool_call_start placeholder event.
 func (r *ReplayChunkCallback) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
 	if info.Component == compose.ComponentOfToolsNode {
-		logger.Debug("[AgentFlow/Callback] ToolsNode started: %s", info.Name)
+		logger.CtxDebug(ctx, "[AgentFlow/Callback] ToolsNode started: %s", info.Name)
 	}
 	return ctx
 }
 
 // OnEnd intercepts node completion events.
+// For a non-streaming ToolsNode run, emits ToolCallEnd events for its results.
 func (r *ReplayChunkCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	if info.Component == compose.ComponentOfToolsNode {
+		r.emitToolResults(convToolsNodeOutput(output))
+	}
 	return ctx
 }
 
@@ -62,10 +95,19 @@ func (r *ReplayChunkCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo
 func (r *ReplayChunkCallback) OnEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo, output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
 	switch info.Component {
 	case compose.ComponentOfGraph, components.ComponentOfChatModel:
-		go r.consumeChatModelStream(ctx, output)
+		reportUsage := info.Component == components.ComponentOfChatModel
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.consumeChatModelStream(ctx, output, reportUsage)
+		}()
 
 	case compose.ComponentOfToolsNode:
-		go r.consumeToolsNodeStream(ctx, output)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.consumeToolsNodeStream(ctx, output)
+		}()
 
 	default:
 		if output != nil {
@@ -77,13 +119,28 @@ func (r *ReplayChunkCallback) OnEndWithStreamOutput(ctx context.Context, info *c
 
 // consumeChatModelStream reads streaming chunks from the ChatModel callback
 // output and translates them into TextDelta and ToolCallStart events.
-func (r *ReplayChunkCallback) consumeChatModelStream(_ context.Context, output *schema.StreamReader[callbacks.CallbackOutput]) {
+// When reportUsage is set, the call's final token usage goes to the usage handler.
+func (r *ReplayChunkCallback) consumeChatModelStream(ctx context.Context, output *schema.StreamReader[callbacks.CallbackOutput], reportUsage bool) {
 	if output == nil {
 		return
 	}
+	var usage *model.TokenUsage
 	sr := schema.StreamReaderWithConvert(output, func(t callbacks.CallbackOutput) (*schema.Message, error) {
 		cbOut := model.ConvCallbackOutput(t)
-		if cbOut == nil || cbOut.Message == nil {
+		if cbOut == nil {
+			return nil, nil
+		}
+		if cbOut.TokenUsage != nil {
+			usage = cbOut.TokenUsage
+		} else if cbOut.Message != nil && cbOut.Message.ResponseMeta != nil && cbOut.Message.ResponseMeta.Usage != nil {
+			u := cbOut.Message.ResponseMeta.Usage
+			usage = &model.TokenUsage{
+				PromptTokens:     u.PromptTokens,
+				CompletionTokens: u.CompletionTokens,
+				TotalTokens:      u.TotalTokens,
+			}
+		}
+		if cbOut.Message == nil {
 			return nil, nil
 		}
 		return cbOut.Message, nil
@@ -95,7 +152,7 @@ func (r *ReplayChunkCallback) consumeChatModelStream(_ context.Context, output *
 			break
 		}
 		if err != nil {
-			logger.Warn("[AgentFlow/Callback] ChatModel stream error: %v", err)
+			logger.CtxWarn(ctx, "[AgentFlow/Callback] ChatModel stream error: %v", err)
 			break
 		}
 		if msg == nil {
@@ -120,52 +177,113 @@ func (r *ReplayChunkCallback) consumeChatModelStream(_ context.Context, output *
 			}, nil)
 		}
 	}
+
+	if reportUsage && usage != nil && r.onUsage != nil {
+		r.onUsage(&entity.TokenUsage{
+			PromptTokens:     int64(usage.PromptTokens),
+			CompletionTokens: int64(usage.CompletionTokens),
+			TotalTokens:      int64(usage.TotalTokens),
+		})
+	}
 }
 
 // consumeToolsNodeStream reads tool execution results and emits ToolCallEnd events.
-func (r *ReplayChunkCallback) consumeToolsNodeStream(_ context.Context, output *schema.StreamReader[callbacks.CallbackOutput]) {
+func (r *ReplayChunkCallback) consumeToolsNodeStream(ctx context.Context, output *schema.StreamReader[callbacks.CallbackOutput]) {
 	if output == nil {
 		return
 	}
 
+	// Each chunk carries the ToolsNode's []*schema.Message output (one entry per
+	// tool call); results are emitted once the stream completes.
 	var messages []*schema.Message
-	sr := schema.StreamReaderWithConvert(output, func(t callbacks.CallbackOutput) (*schema.Message, error) {
-		cbOut := model.ConvCallbackOutput(t)
-		if cbOut == nil || cbOut.Message == nil {
-			return nil, nil
-		}
-		return cbOut.Message, nil
-	})
-
 	for {
-		msg, err := sr.Recv()
+		chunk, err := output.Recv()
 		if errors.Is(err, io.EOF) {
 			break
 		}
 		if err != nil {
-			logger.Warn("[AgentFlow/Callback] ToolsNode stream error: %v", err)
+			logger.CtxWarn(ctx, "[AgentFlow/Callback] ToolsNode stream error: %v", err)
 			break
 		}
-		if msg != nil {
-			messages = append(messages, msg)
-		}
+		messages = append(messages, convToolsNodeOutput(chunk)...)
 	}
+	output.Close()
+
+	r.emitToolResults(mergeToolResultChunks(messages))
+}
 
+// emitToolResults sends one ToolCallEnd event per tool message, truncating
+// the content to maxToolResultEventChars.
+func (r *ReplayChunkCallback) emitToolResults(messages []*schema.Message) {
 	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		name := msg.ToolName
+		if name == "" {
+			name = msg.Name
+		}
+		content, truncated := truncateRunes(msg.Content, maxToolResultEventChars)
 		r.sw.Send(&entity.AgentEvent{
 			Type: entity.EventToolCallEnd,
 			ToolResult: &entity.ToolResult{
 				ToolCallID: msg.ToolCallID,
-				Name:       msg.Name,
-				Content:    msg.Content,
+				Name:       name,
+				Content:    content,
+				Truncated:  truncated,
 			},
 		}, nil)
 	}
 }
 
+// convToolsNodeOutput extracts tool messages from a ToolsNode callback output.
+func convToolsNodeOutput(output callbacks.CallbackOutput) []*schema.Message {
+	switch t := output.(type) {
+	case []*schema.Message:
+		return t
+	case *schema.Message:
+		return []*schema.Message{t}
+	}
+	return nil
+}
+
+// mergeToolResultChunks concatenates streamed chunks that belong to the same tool call,
+// preserving the order in which tool calls first appeared.
+func mergeToolResultChunks(chunks []*schema.Message) []*schema.Message {
+	var merged []*schema.Message
+	byID := make(map[string]*schema.Message)
+	for _, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		if prev, ok := byID[chunk.ToolCallID]; ok && chunk.ToolCallID != "" {
+			prev.Content += chunk.Content
+			continue
+		}
+		cp := *chunk
+		merged = append(merged, &cp)
+		if chunk.ToolCallID != "" {
+			byID[chunk.ToolCallID] = &cp
+		}
+	}
+	return merged
+}
+
+// truncateRunes cuts s to at most limit runes.
+func truncateRunes(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s, false
+	}
+	return string(runes[:limit]), true
+}
+
 // OnError intercepts execution errors and emits error events.
 func (r *ReplayChunkCallback) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
-	logger.Warn("[AgentFlow/Callback] error in %s/%s: %v", info.Component, info.Name, err)
+	logger.CtxWarn(ctx, "[AgentFlow/Callback] error in %s/%s: %v", info.Component, info.Name, err)
 	r.sw.Send(&entity.AgentEvent{
 		Type:  entity.EventError,
 		Error: err.Error(),
@@ -0,0 +1,152 @@
+package agentflow
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+)
+
+// recordingSink collects the events sent to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []*entity.AgentEvent
+}
+
+func (s *recordingSink) Send(chunk *entity.AgentEvent, _ error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, chunk)
+	return false
+}
+
+// callbackStream returns a closed stream holding outputs.
+func callbackStream(outputs ...callbacks.CallbackOutput) *schema.StreamReader[callbacks.CallbackOutput] {
+	sr, sw := schema.Pipe[callbacks.CallbackOutput](len(outputs))
+	for _, out := range outputs {
+		sw.Send(out, nil)
+	}
+	sw.Close()
+	return sr
+}
+
+func TestReplayChunkCallbackUsage(t *testing.T) {
+	metaUsage := &schema.Message{Role: schema.Assistant, Content: "lo", ResponseMeta: &schema.ResponseMeta{
+		Usage: &schema.TokenUsage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10},
+	}}
+	tests := []struct {
+		name      string
+		component components.Component
+		outputs   []callbacks.CallbackOutput
+		want      *entity.TokenUsage
+	}{
+		{
+			name:      "callback token usage",
+			component: components.ComponentOfChatModel,
+			outputs: []callbacks.CallbackOutput{
+				&model.CallbackOutput{Message: schema.AssistantMessage("hel", nil)},
+				&model.CallbackOutput{Message: schema.AssistantMessage("lo", nil), TokenUsage: &model.TokenUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}},
+			},
+			want: &entity.TokenUsage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+		},
+		{
+			name:      "response meta usage",
+			component: components.ComponentOfChatModel,
+			outputs: []callbacks.CallbackOutput{
+				&model.CallbackOutput{Message: schema.AssistantMessage("hel", nil)},
+				&model.CallbackOutput{Message: metaUsage},
+			},
+			want: &entity.TokenUsage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10},
+		},
+		{
+			name:      "no usage reported",
+			component: components.ComponentOfChatModel,
+			outputs: []callbacks.CallbackOutput{
+				&model.CallbackOutput{Message: schema.AssistantMessage("hel", nil)},
+				&model.CallbackOutput{Message: schema.AssistantMessage("lo", nil)},
+			},
+		},
+		{
+			// The graph's stream repeats the model's messages; counting it
+			// too would report the same call twice.
+			name:      "graph output",
+			component: compose.ComponentOfGraph,
+			outputs: []callbacks.CallbackOutput{
+				&model.CallbackOutput{Message: schema.AssistantMessage("hel", nil)},
+				&model.CallbackOutput{Message: metaUsage},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			var usages []*entity.TokenUsage
+			r := NewReplayChunkCallback(sink).WithUsageHandler(func(u *entity.TokenUsage) {
+				usages = append(usages, u)
+			})
+			r.OnEndWithStreamOutput(context.Background(), &callbacks.RunInfo{Component: tt.component}, callbackStream(tt.outputs...))
+			r.Wait()
+
+			var text string
+			for _, e := range sink.events {
+				if e.Type == entity.EventTextDelta {
+					text += e.Delta
+				}
+			}
+			if text != "hello" {
+				t.Fatalf("text = %q, want %q", text, "hello")
+			}
+			switch {
+			case tt.want == nil && len(usages) != 0:
+				t.Fatalf("usage = %+v, want none", usages[0])
+			case tt.want != nil && (len(usages) != 1 || *usages[0] != *tt.want):
+				t.Fatalf("usage = %+v, want one report of %+v", usages, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayChunkCallbackToolResults(t *testing.T) {
+	long := strings.Repeat("é", maxToolResultEventChars+1)
+	outputs := []callbacks.CallbackOutput{
+		[]*schema.Message{
+			schema.ToolMessage("sun", "c1", schema.WithToolName("weather")),
+			schema.ToolMessage(long[:2*maxToolResultEventChars], "c2", schema.WithToolName("read")),
+		},
+		[]*schema.Message{
+			schema.ToolMessage("ny", "c1"),
+			schema.ToolMessage(long[2*maxToolResultEventChars:], "c2"),
+		},
+	}
+	want := []entity.ToolResult{
+		{ToolCallID: "c1", Name: "weather", Content: "sunny"},
+		{ToolCallID: "c2", Name: "read", Content: long[:2*maxToolResultEventChars], Truncated: true},
+	}
+
+	sink := &recordingSink{}
+	r := NewReplayChunkCallback(sink)
+	r.OnEndWithStreamOutput(context.Background(), &callbacks.RunInfo{Component: compose.ComponentOfToolsNode}, callbackStream(outputs...))
+	r.Wait()
+
+	var got []entity.ToolResult
+	for _, e := range sink.events {
+		if e.Type == entity.EventToolCallEnd && e.ToolResult != nil {
+			got = append(got, *e.ToolResult)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("tool results = %+v, want %d", got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -3,9 +3,11 @@ package agentflow
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	pluginPkg "github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
 	"github.com/kiosk404/echoryn/pkg/utils/json"
 )
@@ -70,32 +72,61 @@ func (p *PluginTool) IsStream() bool {
 	return false
 }
 
-// AdaptPluginTools converts plugin-registered tools matching the given names to Eino tools.
-// If no names are provided, all registered tools are converted.
-// If toolNames is empty, all registered tools are adapted.
-func AdaptPluginTools(registry *pluginPkg.Registry, toolNames []string) []tool.BaseTool {
+// AdaptPluginTools converts plugin-registered tools to Eino tools according to the tool mode:
+//   - all: every registered tool is adapted; toolNames is ignored.
+//   - allowlist: only tools named in toolNames are adapted (empty list = no tools).
+//   - denylist: every registered tool except those named in toolNames is adapted.
+//
+// Tools named in denied are dropped in every mode. In allowlist mode, names in
+// toolNames that no plugin registered are returned as missing.
+//
+// Callers normally pass agent.EffectiveToolMode() so that an unset mode keeps the
+// legacy semantics (empty toolNames = all tools).
+func AdaptPluginTools(registry *pluginPkg.Registry, mode entity.ToolMode, toolNames, denied []string) (tools []tool.BaseTool, missing []string) {
 	allTools := registry.GetTools()
-	tools := make([]tool.BaseTool, 0, len(toolNames))
-
-	if len(toolNames) == 0 {
-		for _, def := range allTools {
-			tools = append(tools, &PluginTool{def: def})
-		}
-		return tools
-	}
 
 	nameSet := make(map[string]struct{}, len(toolNames))
 	for _, name := range toolNames {
 		nameSet[name] = struct{}{}
 	}
+	deniedSet := make(map[string]struct{}, len(denied))
+	for _, name := range denied {
+		deniedSet[name] = struct{}{}
+	}
 
-	for name, def := range allTools {
-		if _, ok := nameSet[name]; ok {
-			tools = append(tools, &PluginTool{def: def})
+	names := make([]string, 0, len(allTools))
+	for name := range allTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tools = make([]tool.BaseTool, 0, len(allTools))
+	for _, name := range names {
+		if _, ok := deniedSet[name]; ok {
+			continue
+		}
+		_, listed := nameSet[name]
+		switch mode {
+		case entity.ToolModeAllowlist:
+			if !listed {
+				continue
+			}
+		case entity.ToolModeDenylist:
+			if listed {
+				continue
+			}
 		}
+		tools = append(tools, &PluginTool{def: allTools[name]})
 	}
 
-	return tools
+	if mode == entity.ToolModeAllowlist {
+		for _, name := range toolNames {
+			if _, ok := allTools[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return tools, missing
 }
 
 // toSchemaDataType converts a string type name to the corresponding Eino schema.DataType.
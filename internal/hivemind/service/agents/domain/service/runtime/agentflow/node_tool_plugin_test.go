@@ -0,0 +1,78 @@
+package agentflow
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	pluginPkg "github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+)
+
+// toolsPlugin registers a tool per name.
+type toolsPlugin struct{ names []string }
+
+func (p *toolsPlugin) Name() string { return "tools" }
+
+func (p *toolsPlugin) Init(api pluginPkg.PluginAPI) error {
+	for _, name := range p.names {
+		api.RegisterTool(pluginPkg.ToolDefinition{Name: name})
+	}
+	return nil
+}
+
+func newToolsRegistry(t *testing.T, names ...string) *pluginPkg.Registry {
+	t.Helper()
+	fw := (&pluginPkg.Config{}).Complete().New()
+	factory := func(pluginPkg.PluginArgs, pluginPkg.Handle) (pluginPkg.Plugin, error) {
+		return &toolsPlugin{names: names}, nil
+	}
+	if err := fw.RegisterFactory(pluginPkg.Definition{ID: "tools", Name: "tools"}, factory, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return fw.Registry()
+}
+
+func TestAdaptPluginTools(t *testing.T) {
+	registry := newToolsRegistry(t, "memory_search", "web_search", "web_fetch")
+
+	tests := []struct {
+		name        string
+		mode        entity.ToolMode
+		tools       []string
+		denied      []string
+		want        []string
+		wantMissing []string
+	}{
+		{"all", entity.ToolModeAll, nil, nil, []string{"memory_search", "web_fetch", "web_search"}, nil},
+		{"all ignores the list", entity.ToolModeAll, []string{"web_search"}, nil, []string{"memory_search", "web_fetch", "web_search"}, nil},
+		{"allowlist", entity.ToolModeAllowlist, []string{"web_search", "unknown"}, nil, []string{"web_search"}, []string{"unknown"}},
+		{"empty allowlist", entity.ToolModeAllowlist, nil, nil, nil, nil},
+		{"denylist", entity.ToolModeDenylist, []string{"web_search"}, nil, []string{"memory_search", "web_fetch"}, nil},
+		{"empty denylist", entity.ToolModeDenylist, nil, nil, []string{"memory_search", "web_fetch", "web_search"}, nil},
+		{"denied wins over allowlist", entity.ToolModeAllowlist, []string{"web_search", "web_fetch"}, []string{"web_fetch"}, []string{"web_search"}, nil},
+		{"denied in all mode", entity.ToolModeAll, nil, []string{"memory_search"}, []string{"web_fetch", "web_search"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools, missing := AdaptPluginTools(registry, tt.mode, tt.tools, tt.denied)
+			var got []string
+			for _, tl := range tools {
+				info, err := tl.Info(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, info.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("tools = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(missing, tt.wantMissing) {
+				t.Fatalf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
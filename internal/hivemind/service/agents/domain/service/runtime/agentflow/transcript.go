@@ -0,0 +1,47 @@
+package agentflow
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Transcript records the input of the latest ChatModel call of a ReAct run:
+// the conversation plus every tool call and result the model has seen.
+// When the run stops at its step cap, the transcript is what a final
+// tool-less call can answer from.
+type Transcript struct {
+	mu   sync.Mutex
+	msgs []*schema.Message
+}
+
+// NewTranscript creates an empty Transcript.
+func NewTranscript() *Transcript {
+	return &Transcript{}
+}
+
+// Messages returns the recorded messages (nil before the first model call).
+func (t *Transcript) Messages() []*schema.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.msgs
+}
+
+type transcriptKey struct{}
+
+// WithTranscript returns a context whose ReAct runs record into t.
+func WithTranscript(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+// recordModelInput is the ReAct MessageModifier that stores each model input
+// in the context's Transcript, leaving the input unchanged.
+func recordModelInput(ctx context.Context, input []*schema.Message) []*schema.Message {
+	if t, ok := ctx.Value(transcriptKey{}).(*Transcript); ok && t != nil {
+		t.mu.Lock()
+		t.msgs = append([]*schema.Message(nil), input...)
+		t.mu.Unlock()
+	}
+	return input
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/utils/safego"
+)
+
+// choiceResult is the outcome of one choice of a run.
+type choiceResult struct {
+	result *TurnResult
+	err    error
+}
+
+// executeChoices runs n completions of the same built context in parallel.
+//
+// Each choice has its own AbortController derived from the run's: aborting
+// the run stops every choice, while one choice failing leaves the others
+// running. Events of choice i reach sw with Choice set to i. Only choice 0
+// may compact the session on context overflow; the others fail instead, so
+// that concurrent choices never rewrite the same history.
+//
+// Choices do not share tool results: each one calls tools on its own, within
+// its own copy of the agent's ToolBudget (see TurnExecutor.Execute). The
+// number of choices is capped by the gateway, since every tool with side
+// effects may run once per choice.
+func (r *AgentRunner) executeChoices(
+	ctx context.Context,
+	turn TurnRequest,
+	n int,
+	runID string,
+	sw *schema.StreamWriter[*entity.AgentEvent],
+	abort *AbortController,
+) []choiceResult {
+	results := make([]choiceResult, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		safego.Go(ctx, func() {
+			defer wg.Done()
+			// Stays set if the choice panics.
+			results[i] = choiceResult{err: errors.New("choice aborted")}
+
+			choiceAbort := NewAbortController(abort.Context(), runID, 0)
+			defer choiceAbort.CleanUp()
+
+			csr, csw := schema.Pipe[*entity.AgentEvent](20)
+			forwarded := make(chan struct{})
+			go func() {
+				defer close(forwarded)
+				for {
+					event, err := csr.Recv()
+					if err != nil {
+						return
+					}
+					event.Choice = i
+					sw.Send(event, nil)
+				}
+			}()
+
+			req := turn
+			req.EventWriter = csw
+			if i > 0 {
+				req.Session = nil
+			}
+			defer func() {
+				csw.Close()
+				<-forwarded
+			}()
+			result, err := r.turnExecutor.Execute(ctx, &req, choiceAbort)
+			results[i] = choiceResult{result: result, err: err}
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// choicesUsage sums the token usage and cost of the successful choices.
+// The cost is nil when no choice was served by a priced model.
+func (r *AgentRunner) choicesUsage(ctx context.Context, choices []choiceResult) (*entity.TokenUsage, *float64) {
+	var usage *entity.TokenUsage
+	var cost *float64
+	for _, c := range choices {
+		if c.err != nil || c.result.Usage == nil {
+			continue
+		}
+		if usage == nil {
+			usage = &entity.TokenUsage{}
+		}
+		usage.PromptTokens += c.result.Usage.PromptTokens
+		usage.CompletionTokens += c.result.Usage.CompletionTokens
+		usage.TotalTokens += c.result.Usage.TotalTokens
+
+		if cc := r.computeCost(ctx, c.result.ModelRef, c.result.Usage); cc != nil {
+			if cost == nil {
+				cost = new(float64)
+			}
+			*cost += *cc
+		}
+	}
+	return usage, cost
+}
+
+// choiceFailedEvent reports the failure of one choice of a run whose other
+// choices may still succeed.
+func choiceFailedEvent(choice int, err error) *entity.AgentEvent {
+	return &entity.AgentEvent{
+		Type:         entity.EventRunStatus,
+		RunStatus:    entity.RunStatusFailed,
+		Error:        err.Error(),
+		FinishReason: entity.FinishReasonError,
+		Choice:       choice,
+	}
+}
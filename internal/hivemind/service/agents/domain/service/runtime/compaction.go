@@ -24,7 +24,7 @@ import (
 //  1. Context overflow error during LLM execution (reactive)
 //  2. Post-turn threshold check: tokens > compactionThreshold * windowSize (proactive)
 type Compactor struct {
-	estimator           *TokenEstimator
+	tokenizer           Tokenizer
 	compactionThreshold float64
 	keepRecentTurns     int
 }
@@ -49,7 +49,7 @@ func DefaultCompactorConfig() CompactorConfig {
 }
 
 // NewCompactor creates a new Compactor.
-func NewCompactor(estimator *TokenEstimator, cfg CompactorConfig) *Compactor {
+func NewCompactor(tokenizer Tokenizer, cfg CompactorConfig) *Compactor {
 	if cfg.CompactionThreshold <= 0 {
 		cfg.CompactionThreshold = 0.8
 	}
@@ -57,23 +57,55 @@ func NewCompactor(estimator *TokenEstimator, cfg CompactorConfig) *Compactor {
 		cfg.KeepRecentTurns = 3
 	}
 	return &Compactor{
-		estimator:           estimator,
+		tokenizer:           tokenizer,
 		compactionThreshold: cfg.CompactionThreshold,
 		keepRecentTurns:     cfg.KeepRecentTurns,
 	}
 }
 
+// withTokenizer returns a compactor sharing c's config that counts tokens with tk.
+// A nil tk returns c itself.
+func (c *Compactor) withTokenizer(tk Tokenizer) *Compactor {
+	if tk == nil {
+		return c
+	}
+	cp := *c
+	cp.tokenizer = tk
+	return &cp
+}
+
 // ShouldCompact checks if post-turn proactive compaction is needed.
 func (c *Compactor) ShouldCompact(session *entity.Session, windowInfo ContextWindowInfo) bool {
 	if len(session.ActiveMessages()) == 0 {
 		return false
 	}
-	activeSchemaMessages := ToSchemaMessages(session.ActiveMessages())
-	estimated := c.estimator.EstimateMessages(activeSchemaMessages)
-	ratio := float64(estimated) / float64(windowInfo.UsableTokens)
+	ratio := float64(c.ActiveTokens(session, windowInfo)) / float64(windowInfo.UsableTokens)
 	return ratio > c.compactionThreshold
 }
 
+// ActiveTokens estimates the token count of the session's active messages
+// (the part of the history that compaction shrinks).
+func (c *Compactor) ActiveTokens(session *entity.Session, windowInfo ContextWindowInfo) int {
+	active := session.ActiveMessages()
+	if len(active) == 0 {
+		return 0
+	}
+	return c.withTokenizer(windowInfo.Tokenizer).tokenizer.CountMessages(ToSchemaMessages(active))
+}
+
+// compactionEvent builds an EventCompaction event.
+func compactionEvent(reason entity.CompactionReason, status entity.CompactionStatus, before, after int) *entity.AgentEvent {
+	return &entity.AgentEvent{
+		Type: entity.EventCompaction,
+		Compaction: &entity.CompactionInfo{
+			Reason:       reason,
+			Status:       status,
+			TokensBefore: before,
+			TokensAfter:  after,
+		},
+	}
+}
+
 // Compact performs compaction on the session using the provided ChatModel.
 //
 // Flow:
@@ -90,6 +122,8 @@ func (c *Compactor) Compact(
 	chatModel einoModel.BaseChatModel,
 	windowInfo ContextWindowInfo,
 ) (string, error) {
+	c = c.withTokenizer(windowInfo.Tokenizer)
+
 	activeMessages := session.ActiveMessages()
 	if len(activeMessages) == 0 {
 		return "", fmt.Errorf("no messages to compact")
@@ -102,7 +136,7 @@ func (c *Compactor) Compact(
 	}
 
 	messagesToSummarize := activeMessages[:splitIdx]
-	logger.Info("[Compactor] compacting %d messages (keeping last %d), session=%s, compactionCount=%d",
+	logger.CtxInfo(ctx, "[Compactor] compacting %d messages (keeping last %d), session=%s, compactionCount=%d",
 		len(messagesToSummarize), len(activeMessages)-splitIdx, session.ID, session.CompactionCount+1)
 
 	// Build the existing summary prefix (if any previous compaction).
@@ -118,7 +152,7 @@ func (c *Compactor) Compact(
 	absoluteKeptFrom := session.FirstKeptIndex + splitIdx
 	session.ApplyCompaction(summary, absoluteKeptFrom)
 
-	logger.Info("[Compactor] compaction completed: session=%s, summary_len=%d, first_kept=%d, compaction_count=%d",
+	logger.CtxInfo(ctx, "[Compactor] compaction completed: session=%s, summary_len=%d, first_kept=%d, compaction_count=%d",
 		session.ID, len(summary), absoluteKeptFrom, session.CompactionCount)
 
 	return summary, nil
@@ -157,7 +191,7 @@ func (c *Compactor) summarize(
 	windowInfo ContextWindowInfo,
 ) (string, error) {
 	schemaMessages := ToSchemaMessages(messages)
-	totalTokens := c.estimator.EstimateMessages(schemaMessages)
+	totalTokens := c.tokenizer.CountMessages(schemaMessages)
 
 	// Target: summary should fit in ~20% of the usable window.
 	summaryBudget := windowInfo.UsableTokens / 5
@@ -179,7 +213,7 @@ func (c *Compactor) summarize(
 
 	// Multi-stage: split → summarize each chunk → merge.
 	chunks := c.splitIntoChunks(schemaMessages, chunkBudget)
-	logger.Debug("[Compactor] multi-stage: %d chunks from %d messages (%d est. tokens)",
+	logger.CtxDebug(ctx, "[Compactor] multi-stage: %d chunks from %d messages (%d est. tokens)",
 		len(chunks), len(messages), totalTokens)
 
 	var partialSummaries []string
@@ -192,7 +226,7 @@ func (c *Compactor) summarize(
 		partial, err := c.summarizeChunk(ctx, chatModel, chunk, prefix, summaryBudget/len(chunks))
 		if err != nil {
 			// Fallback: if any chunk fails, try a simple description.
-			logger.Warn("[Compactor] chunk %d/%d summarization failed: %v, using simple fallback",
+			logger.CtxWarn(ctx, "[Compactor] chunk %d/%d summarization failed: %v, using simple fallback",
 				i+1, len(chunks), err)
 			partial = fmt.Sprintf("[Summary of %d messages in conversation chunk %d/%d could not be generated]",
 				len(chunk), i+1, len(chunks))
@@ -281,7 +315,7 @@ func (c *Compactor) mergeSummaries(
 	})
 	if err != nil {
 		// Fallback: just concatenate partials.
-		logger.Warn("[Compactor] merge failed: %v, concatenating partials", err)
+		logger.CtxWarn(ctx, "[Compactor] merge failed: %v, concatenating partials", err)
 		return strings.Join(partials, "\n\n---\n\n"), nil
 	}
 
@@ -295,7 +329,7 @@ func (c *Compactor) splitIntoChunks(messages []*schema.Message, chunkBudget int)
 	currentTokens := 0
 
 	for _, msg := range messages {
-		msgTokens := c.estimator.EstimateMessage(msg)
+		msgTokens := c.tokenizer.CountMessages([]*schema.Message{msg})
 		if currentTokens+msgTokens > chunkBudget && len(current) > 0 {
 			chunks = append(chunks, current)
 			current = nil
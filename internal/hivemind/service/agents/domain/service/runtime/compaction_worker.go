@@ -0,0 +1,181 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+const (
+	// DefaultCompactionWorkers is the number of background compaction workers.
+	DefaultCompactionWorkers = 2
+
+	// compactionQueueSize bounds the number of sessions waiting for compaction.
+	compactionQueueSize = 64
+)
+
+// compactionJob is a session queued for proactive compaction.
+type compactionJob struct {
+	agent      *entity.Agent
+	sessionID  string
+	windowInfo ContextWindowInfo
+}
+
+// compactionQueue runs proactive compaction off the request path.
+// A session is queued at most once: it stays marked in progress from
+// enqueue until its worker finishes.
+type compactionQueue struct {
+	jobs chan compactionJob
+	run  func(ctx context.Context, job compactionJob)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	inProgress map[string]struct{}
+	closed     bool
+}
+
+// newCompactionQueue starts workers goroutines calling run for each job.
+func newCompactionQueue(workers int, run func(ctx context.Context, job compactionJob)) *compactionQueue {
+	if workers <= 0 {
+		workers = DefaultCompactionWorkers
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &compactionQueue{
+		jobs:       make(chan compactionJob, compactionQueueSize),
+		run:        run,
+		ctx:        ctx,
+		cancel:     cancel,
+		inProgress: make(map[string]struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// enqueue queues a session for compaction. It reports false when the
+// session is already queued or running, or when the queue is full or closed.
+func (q *compactionQueue) enqueue(job compactionJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if _, ok := q.inProgress[job.sessionID]; ok {
+		return false
+	}
+	select {
+	case q.jobs <- job:
+		q.inProgress[job.sessionID] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *compactionQueue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		q.run(q.ctx, job)
+
+		q.mu.Lock()
+		delete(q.inProgress, job.sessionID)
+		q.mu.Unlock()
+	}
+}
+
+// close cancels running compactions, drops queued ones and waits for the
+// workers to exit.
+func (q *compactionQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+}
+
+// runCompaction compacts a queued session if it is still over the threshold
+// and persists the result. The session is reloaded so that the compaction
+// sees the history as persisted by the run that queued it. The summary is
+// generated without holding the session lock; only the write-back locks the
+// session against runs, and it keeps whatever messages runs appended in the
+// meantime.
+func (r *AgentRunner) runCompaction(ctx context.Context, job compactionJob) {
+	if ctx.Err() != nil {
+		return
+	}
+	ctx = logger.WithFields(ctx,
+		logger.FieldSessionID, job.sessionID,
+		logger.FieldAgentID, job.agent.ID)
+
+	session, err := r.sessionRepo.Get(ctx, job.sessionID)
+	if err != nil {
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction skipped: %v", err)
+		return
+	}
+	if !r.compactor.ShouldCompact(session, job.windowInfo) {
+		return
+	}
+
+	compactModel, _, err := r.llmModule.Fallback.GetChatModelWithFallback(
+		ctx, job.agent.Fallback, job.agent.LLMParams())
+	if err != nil {
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction skipped: no model available: %v", err)
+		return
+	}
+	r.compactAndStore(ctx, job, session, compactModel)
+}
+
+// compactAndStore summarizes session with chatModel and writes the result
+// back to the stored session under the session lock.
+func (r *AgentRunner) compactAndStore(ctx context.Context, job compactionJob, session *entity.Session, chatModel einoModel.BaseChatModel) {
+	tokensBefore := r.compactor.ActiveTokens(session, job.windowInfo)
+	baseCount, baseMessages := session.CompactionCount, len(session.Messages)
+	if _, err := r.compactor.Compact(ctx, session, chatModel, job.windowInfo); err != nil {
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction failed: %v", err)
+		return
+	}
+
+	// Wait for runs on the session, so that neither overwrites the other.
+	release, err := r.sessions.acquire(ctx, job.sessionID, false)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	current, err := r.sessionRepo.Get(ctx, job.sessionID)
+	if err != nil {
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] failed to persist compacted session: %v", err)
+		return
+	}
+	// Runs only append messages, so the summary still covers the prefix it
+	// was made from unless the session was compacted or reset meanwhile.
+	if current.CompactionCount != baseCount || len(current.Messages) < baseMessages {
+		logger.CtxDebugX(ctx, pkg.ModuleName, "[AgentRunner] session %s changed during proactive compaction, dropping the summary", current.ID)
+		return
+	}
+	current.ApplyCompaction(session.CompactionSummary, session.FirstKeptIndex)
+	if err := r.sessionRepo.Update(ctx, current); err != nil {
+		// On a version conflict the session was changed by another writer;
+		// the next turn queues it again if still needed.
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] failed to persist compacted session: %v", err)
+		return
+	}
+
+	logger.CtxInfoX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction completed for session %s (count=%d, tokens %d → %d)",
+		current.ID, current.CompactionCount, tokensBefore, r.compactor.ActiveTokens(current, job.windowInfo))
+}
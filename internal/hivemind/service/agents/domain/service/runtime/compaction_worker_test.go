@@ -0,0 +1,217 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+)
+
+// longSession is a session whose history exceeds a 100-token window.
+func longSession() *entity.Session {
+	s := &entity.Session{ID: "s1", AgentID: "a"}
+	for i := 0; i < 4; i++ {
+		s.AppendMessage(entity.NewUserMessage(strings.Repeat("question ", 50)))
+		s.AppendMessage(entity.NewAssistantMessage(strings.Repeat("answer ", 50)))
+	}
+	return s
+}
+
+// collectEvents closes sw and returns what was sent on it.
+func collectEvents(t *testing.T, sr *schema.StreamReader[*entity.AgentEvent], sw *schema.StreamWriter[*entity.AgentEvent]) []*entity.AgentEvent {
+	t.Helper()
+	sw.Close()
+	var events []*entity.AgentEvent
+	for {
+		e, err := sr.Recv()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		events = append(events, e)
+	}
+}
+
+func TestCheckProactiveCompactionReportsScheduled(t *testing.T) {
+	queued := make(chan compactionJob, 1)
+	release := make(chan struct{})
+	r := &AgentRunner{compactor: NewCompactor(NewTokenEstimator(DefaultCharsPerTokenRatio), CompactorConfig{})}
+	r.compactions = newCompactionQueue(1, func(_ context.Context, job compactionJob) {
+		queued <- job
+		<-release
+	})
+	defer r.compactions.close()
+	defer close(release)
+
+	window := ContextWindowInfo{WindowSize: 200, ReserveTokens: 100, UsableTokens: 100}
+	agent := &entity.Agent{ID: "a"}
+	session := longSession()
+
+	sr, sw := schema.Pipe[*entity.AgentEvent](4)
+	r.checkProactiveCompaction(context.Background(), agent, session, window, sw)
+	<-queued
+	// Already queued: not scheduled, nor reported, twice.
+	r.checkProactiveCompaction(context.Background(), agent, session, window, sw)
+
+	events := collectEvents(t, sr, sw)
+	if len(events) != 1 {
+		t.Fatalf("%d events, want one scheduled compaction", len(events))
+	}
+	info := events[0].Compaction
+	if events[0].Type != entity.EventCompaction || info == nil ||
+		info.Reason != entity.CompactionReasonThreshold || info.Status != entity.CompactionScheduled || info.TokensBefore <= 100 {
+		t.Fatalf("event = %+v, compaction = %+v", events[0], info)
+	}
+}
+
+func TestCheckProactiveCompactionBelowThreshold(t *testing.T) {
+	r := &AgentRunner{compactor: NewCompactor(NewTokenEstimator(DefaultCharsPerTokenRatio), CompactorConfig{})}
+	r.compactions = newCompactionQueue(1, func(context.Context, compactionJob) {
+		t.Error("compaction queued below the threshold")
+	})
+	defer r.compactions.close()
+
+	session := &entity.Session{ID: "s1"}
+	session.AppendMessage(entity.NewUserMessage("hi"))
+	sr, sw := schema.Pipe[*entity.AgentEvent](4)
+	r.checkProactiveCompaction(context.Background(), &entity.Agent{ID: "a"}, session,
+		ContextWindowInfo{WindowSize: 20000, UsableTokens: 10000}, sw)
+	if events := collectEvents(t, sr, sw); len(events) != 0 {
+		t.Fatalf("events = %v, want none", events)
+	}
+}
+
+func TestCompactionQueueDeduplicates(t *testing.T) {
+	started := make(chan string, 4)
+	release := make(chan struct{})
+	q := newCompactionQueue(1, func(_ context.Context, job compactionJob) {
+		started <- job.sessionID
+		<-release
+	})
+
+	if !q.enqueue(compactionJob{sessionID: "s1"}) {
+		t.Fatal("first enqueue refused")
+	}
+	<-started
+	if q.enqueue(compactionJob{sessionID: "s1"}) {
+		t.Fatal("session queued twice while its compaction runs")
+	}
+	if !q.enqueue(compactionJob{sessionID: "s2"}) {
+		t.Fatal("other session refused")
+	}
+	close(release)
+	<-started
+
+	q.close()
+	if q.enqueue(compactionJob{sessionID: "s3"}) {
+		t.Fatal("enqueue after close accepted")
+	}
+}
+
+// blockingSummarizer is a chat model whose replies wait for proceed, so a
+// test can act while a summary is being generated.
+type blockingSummarizer struct {
+	started chan struct{}
+	proceed chan struct{}
+}
+
+func (m *blockingSummarizer) Generate(ctx context.Context, _ []*schema.Message, _ ...einoModel.Option) (*schema.Message, error) {
+	select {
+	case m.started <- struct{}{}:
+	default:
+	}
+	select {
+	case <-m.proceed:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return schema.AssistantMessage("summary", nil), nil
+}
+
+func (m *blockingSummarizer) Stream(context.Context, []*schema.Message, ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	panic("not used")
+}
+
+func TestCompactionDoesNotLockSessionWhileSummarizing(t *testing.T) {
+	tests := []struct {
+		name        string
+		during      func(*entity.Session) // change made by a run while summarizing
+		wantCount   int
+		wantSummary string
+	}{
+		{
+			name: "run appends messages",
+			during: func(s *entity.Session) {
+				s.AppendMessage(entity.NewUserMessage("next"))
+				s.AppendMessage(entity.NewAssistantMessage("reply"))
+			},
+			wantCount:   1,
+			wantSummary: "summary",
+		},
+		{
+			name:        "session compacted meanwhile",
+			during:      func(s *entity.Session) { s.ApplyCompaction("inline", 2) },
+			wantCount:   1,
+			wantSummary: "inline",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := inmemory.NewSessionStore()
+			if err := store.Create(ctx, longSession()); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			r := &AgentRunner{
+				compactor:   NewCompactor(NewTokenEstimator(DefaultCharsPerTokenRatio), CompactorConfig{}),
+				sessionRepo: store,
+				sessions:    newSessionLocks(),
+			}
+			job := compactionJob{
+				sessionID:  "s1",
+				agent:      &entity.Agent{ID: "a"},
+				windowInfo: ContextWindowInfo{WindowSize: 200, ReserveTokens: 100, UsableTokens: 100},
+			}
+			cm := &blockingSummarizer{started: make(chan struct{}, 1), proceed: make(chan struct{})}
+			snapshot, _ := store.Get(ctx, "s1")
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				r.compactAndStore(ctx, job, snapshot, cm)
+			}()
+			<-cm.started
+
+			// A run takes the session while the summary is generated.
+			release, err := r.sessions.acquire(ctx, "s1", true)
+			if err != nil {
+				t.Fatalf("session locked during summarization: %v", err)
+			}
+			current, _ := store.Get(ctx, "s1")
+			tt.during(current)
+			if err := store.Update(ctx, current); err != nil {
+				t.Fatalf("run update: %v", err)
+			}
+			release()
+
+			close(cm.proceed)
+			<-done
+
+			final, _ := store.Get(ctx, "s1")
+			if len(final.Messages) != len(current.Messages) {
+				t.Fatalf("%d messages, want the run's %d kept", len(final.Messages), len(current.Messages))
+			}
+			if final.CompactionCount != tt.wantCount || final.CompactionSummary != tt.wantSummary {
+				t.Fatalf("compaction = %d %q, want %d %q",
+					final.CompactionCount, final.CompactionSummary, tt.wantCount, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -25,7 +25,7 @@ import (
 // This is the Eidolon equivalent of OpenClaw's context building pipeline
 // combined with airi-go's prompt template assembly.
 type ContextBuilder struct {
-	estimator       *TokenEstimator
+	tokenizer       Tokenizer
 	pruner          *ContextPruner
 	maxHistoryTurns int
 	pipeline        *prompt.Pipeline
@@ -34,14 +34,36 @@ type ContextBuilder struct {
 // NewContextBuilder creates a new ContextBuilder.
 // maxHistoryTurns limits how many recent user turns of history to include (0 = no limit).
 // pipeline may be nil for backward compatibility (uses agent.SystemPrompt directly).
-func NewContextBuilder(estimator *TokenEstimator, pruner *ContextPruner, maxHistoryTurns int) *ContextBuilder {
+func NewContextBuilder(tokenizer Tokenizer, pruner *ContextPruner, maxHistoryTurns int) *ContextBuilder {
 	return &ContextBuilder{
-		estimator:       estimator,
+		tokenizer:       tokenizer,
 		pruner:          pruner,
 		maxHistoryTurns: maxHistoryTurns,
 	}
 }
 
+// MaxHistoryTurnsLimit caps a per-run history-turn override (see
+// WithMaxHistoryTurns), so that one request cannot load an unbounded history.
+// A configured limit above it raises the cap to that limit.
+const MaxHistoryTurnsLimit = 500
+
+// WithMaxHistoryTurns returns a ContextBuilder that includes up to n recent
+// user turns of history instead of the configured limit, clamped to the
+// larger of MaxHistoryTurnsLimit and the configured limit. n <= 0 returns cb
+// unchanged.
+func (cb *ContextBuilder) WithMaxHistoryTurns(ctx context.Context, n int) *ContextBuilder {
+	if n <= 0 || n == cb.maxHistoryTurns {
+		return cb
+	}
+	if limit := max(MaxHistoryTurnsLimit, cb.maxHistoryTurns); n > limit {
+		logger.CtxDebug(ctx, "[ContextBuilder] history turn override %d clamped to %d", n, limit)
+		n = limit
+	}
+	cp := *cb
+	cp.maxHistoryTurns = n
+	return &cp
+}
+
 // SetPipeline attaches a PromptPipeline to the ContextBuilder.
 // When set, Build() uses the pipeline to assemble the system prompt
 // instead of using agent.SystemPrompt directly.
@@ -56,11 +78,12 @@ func (cb *ContextBuilder) Pipeline() *prompt.Pipeline {
 
 // BuildResult holds the assembled and pruned context.
 type BuildResult struct {
-	Messages         []*schema.Message
-	EstimatedTokens  int
-	HistoryTrimmed   bool
-	PruneSoftTrimmed int
-	PruneHardCleared int
+	Messages          []*schema.Message
+	EstimatedTokens   int
+	HistoryTrimmed    bool
+	PruneSoftTrimmed  int
+	PruneHardCleared  int
+	PruneDroppedTurns int
 }
 
 // Build assembles the complete message list for LLM consumption.
@@ -69,12 +92,16 @@ type BuildResult struct {
 // assembled dynamically from registered Sections. Otherwise, falls back to
 // using agent.SystemPrompt directly (backward compatibility).
 //
+// input is the current user message (with any image inputs); nil when
+// rebuilding a context that has no new input.
+//
 // The optional promptCtx parameter allows callers to pass a pre-built PromptContext.
 // If nil, a minimal PromptContext is constructed from the agent and session.
 func (cb *ContextBuilder) Build(
+	ctx context.Context,
 	agent *entity.Agent,
 	session *entity.Session,
-	userInput string,
+	input *entity.Message,
 	injectedMessages []*entity.Message,
 	windowInfo ContextWindowInfo,
 	promptCtx ...*prompt.PromptContext,
@@ -82,7 +109,7 @@ func (cb *ContextBuilder) Build(
 	var messages []*schema.Message
 
 	// 1. System prompt — use Pipeline if available, else raw agent.SystemPrompt.
-	systemPrompt := cb.resolveSystemPrompt(agent, session, promptCtx...)
+	systemPrompt := cb.resolveSystemPrompt(ctx, agent, session, promptCtx...)
 	if systemPrompt != "" {
 		messages = append(messages, &schema.Message{
 			Role:    schema.System,
@@ -110,35 +137,38 @@ func (cb *ContextBuilder) Build(
 		if len(activeMessages) > 0 {
 			historyMsgs := activeMessages
 			if cb.maxHistoryTurns > 0 {
-				historyMsgs, historyTrimmed = cb.limitHistoryTurns(activeMessages)
+				historyMsgs, historyTrimmed = cb.limitHistoryTurns(ctx, activeMessages)
 			}
 			messages = append(messages, ToSchemaMessages(historyMsgs)...)
 		}
 	}
 
 	// 5. Current user input.
-	if userInput != "" {
-		messages = append(messages, &schema.Message{
-			Role:    schema.User,
-			Content: userInput,
-		})
+	if input != nil && (input.Content != "" || len(input.Images) > 0) {
+		messages = append(messages, ToSchemaMessage(input))
 	}
 
 	// 6. Apply context pruning.
-	pruneResult := cb.pruner.Prune(messages, windowInfo.UsableTokens)
+	pruneResult := cb.pruner.withTokenizer(windowInfo.Tokenizer).Prune(ctx, messages, windowInfo.UsableTokens)
 
 	if pruneResult.SoftTrimmed > 0 || pruneResult.HardCleared > 0 {
-		logger.Info("[ContextBuilder] pruning applied: soft_trimmed=%d, hard_cleared=%d, tokens=%d/%d",
+		logger.CtxInfo(ctx, "[ContextBuilder] pruning applied: soft_trimmed=%d, hard_cleared=%d, tokens=%d/%d",
 			pruneResult.SoftTrimmed, pruneResult.HardCleared,
 			pruneResult.EstimatedTokens, windowInfo.UsableTokens)
 	}
+	if pruneResult.DroppedTurns > 0 {
+		logger.CtxWarn(ctx, "[ContextBuilder] context still over budget after pruning, dropped %d oldest turns (%d messages), tokens=%d/%d",
+			pruneResult.DroppedTurns, pruneResult.DroppedMessages,
+			pruneResult.EstimatedTokens, windowInfo.UsableTokens)
+	}
 
 	return BuildResult{
-		Messages:         pruneResult.Messages,
-		EstimatedTokens:  pruneResult.EstimatedTokens,
-		HistoryTrimmed:   historyTrimmed,
-		PruneSoftTrimmed: pruneResult.SoftTrimmed,
-		PruneHardCleared: pruneResult.HardCleared,
+		Messages:          pruneResult.Messages,
+		EstimatedTokens:   pruneResult.EstimatedTokens,
+		HistoryTrimmed:    historyTrimmed,
+		PruneSoftTrimmed:  pruneResult.SoftTrimmed,
+		PruneHardCleared:  pruneResult.HardCleared,
+		PruneDroppedTurns: pruneResult.DroppedTurns,
 	}
 }
 
@@ -146,7 +176,7 @@ func (cb *ContextBuilder) Build(
 // between them, including assistant replies and tool calls/results).
 //
 // This is the Eidolon equivalent of OpenClaw's limitHistoryTurns().
-func (cb *ContextBuilder) limitHistoryTurns(messages []*entity.Message) ([]*entity.Message, bool) {
+func (cb *ContextBuilder) limitHistoryTurns(ctx context.Context, messages []*entity.Message) ([]*entity.Message, bool) {
 	if cb.maxHistoryTurns <= 0 {
 		return messages, false
 	}
@@ -168,7 +198,7 @@ func (cb *ContextBuilder) limitHistoryTurns(messages []*entity.Message) ([]*enti
 		return messages, false
 	}
 
-	logger.Debug("[ContextBuilder] history trimmed: keeping last %d turns (%d/%d messages)",
+	logger.CtxDebug(ctx, "[ContextBuilder] history trimmed: keeping last %d turns (%d/%d messages)",
 		cb.maxHistoryTurns, len(messages)-cutoff, len(messages))
 	return messages[cutoff:], true
 }
@@ -178,6 +208,7 @@ func (cb *ContextBuilder) limitHistoryTurns(messages []*entity.Message) ([]*enti
 // When a PromptPipeline is attached, it uses the pipeline to render all sections.
 // Otherwise, falls back to agent.SystemPrompt (backward compatibility).
 func (cb *ContextBuilder) resolveSystemPrompt(
+	ctx context.Context,
 	agent *entity.Agent,
 	session *entity.Session,
 	promptCtx ...*prompt.PromptContext,
@@ -194,9 +225,9 @@ func (cb *ContextBuilder) resolveSystemPrompt(
 		pc = cb.buildPromptContext(agent, session)
 	}
 
-	assembled, err := cb.pipeline.Assemble(context.Background(), pc)
+	assembled, err := cb.pipeline.Assemble(ctx, pc)
 	if err != nil {
-		logger.Warn("[ContextBuilder] prompt pipeline assembly failed: %v, falling back to agent.SystemPrompt", err)
+		logger.CtxWarn(ctx, "[ContextBuilder] prompt pipeline assembly failed: %v, falling back to agent.SystemPrompt", err)
 		return agent.SystemPrompt
 	}
 
@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+)
+
+func TestWithMaxHistoryTurns(t *testing.T) {
+	ctx := context.Background()
+	estimator := NewTokenEstimator(DefaultCharsPerTokenRatio)
+	pruner := NewContextPruner(estimator, DefaultPrunerConfig())
+	window := ContextWindowInfo{WindowSize: 1000000, UsableTokens: 1000000, Tokenizer: estimator}
+
+	session := &entity.Session{ID: "s1", AgentID: "a"}
+	for i := range 600 {
+		session.AppendMessage(entity.NewUserMessage(fmt.Sprintf("question %d", i)))
+		session.AppendMessage(entity.NewAssistantMessage(fmt.Sprintf("answer %d", i)))
+	}
+	agent := &entity.Agent{ID: "a"}
+
+	tests := []struct {
+		name       string
+		configured int
+		override   int
+		wantTurns  int
+	}{
+		{"configured limit", 3, 0, 3},
+		{"override raises", 3, 10, 10},
+		{"override lowers", 10, 2, 2},
+		{"override of unlimited", 0, 5, 5},
+		{"override clamped", 3, 10000, MaxHistoryTurnsLimit},
+		{"clamp follows a larger configured limit", 550, 10000, 550},
+		{"override below a larger configured limit", 550, 520, 520},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb := NewContextBuilder(estimator, pruner, tt.configured)
+			got := cb.WithMaxHistoryTurns(ctx, tt.override).Build(ctx, agent, session, nil, nil, window)
+			if len(got.Messages) != 2*tt.wantTurns {
+				t.Fatalf("messages = %d, want %d turns", len(got.Messages), tt.wantTurns)
+			}
+			if want := fmt.Sprintf("question %d", 600-tt.wantTurns); got.Messages[0].Content != want {
+				t.Fatalf("first message = %q, want %q", got.Messages[0].Content, want)
+			}
+			if !got.HistoryTrimmed {
+				t.Fatal("HistoryTrimmed = false")
+			}
+		})
+	}
+
+	cb := NewContextBuilder(estimator, pruner, 3)
+	if cb.WithMaxHistoryTurns(ctx, 0) != cb || cb.WithMaxHistoryTurns(ctx, 3) != cb {
+		t.Fatal("a missing or unchanged override should return the builder itself")
+	}
+	if cb.WithMaxHistoryTurns(ctx, 10); cb.maxHistoryTurns != 3 {
+		t.Fatalf("override changed the shared builder: %d", cb.maxHistoryTurns)
+	}
+}
+
+func TestBuildReportsDroppedTurns(t *testing.T) {
+	ctx := context.Background()
+	estimator := NewTokenEstimator(DefaultCharsPerTokenRatio)
+	cb := NewContextBuilder(estimator, NewContextPruner(estimator, DefaultPrunerConfig()), 0)
+
+	session := &entity.Session{ID: "s1", AgentID: "a"}
+	for i := range 10 {
+		session.AppendMessage(entity.NewUserMessage(fmt.Sprintf("question %d %s", i, strings.Repeat("x", 400))))
+		session.AppendMessage(entity.NewAssistantMessage(fmt.Sprintf("answer %d %s", i, strings.Repeat("y", 400))))
+	}
+	window := ContextWindowInfo{WindowSize: 1000, UsableTokens: 1000, Tokenizer: estimator}
+
+	got := cb.Build(ctx, &entity.Agent{ID: "a"}, session, entity.NewUserMessage("now"), nil, window)
+	if got.PruneDroppedTurns == 0 {
+		t.Fatalf("PruneDroppedTurns = 0 with %d tokens for a budget of 1000", got.EstimatedTokens)
+	}
+	if got.EstimatedTokens > window.UsableTokens {
+		t.Fatalf("%d tokens after dropping turns, want at most %d", got.EstimatedTokens, window.UsableTokens)
+	}
+	if last := got.Messages[len(got.Messages)-1]; last.Content != "now" {
+		t.Fatalf("last message = %q, want the input", last.Content)
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -15,15 +16,23 @@ import (
 // This is the Eidolon equivalent of OpenClaw's context-pruning/pruner.ts,
 // implementing a two-stage strategy:
 //
-//  1. Soft-trim: Truncate large tool results to head+tail with "..." in between.
-//     Applied when context usage exceeds softTrimRatio of the window.
+//  1. Soft-trim: Truncate large tool results to head+tail with "..." in between
+//     (optionally also oversized user/assistant messages). Applied when context usage exceeds softTrimRatio of the window.
 //
 //  2. Hard-clear: Replace entire tool results with a placeholder.
 //     Applied when context usage exceeds hardClearRatio of the window.
 //
+//  3. Turn drop: If the context still exceeds the window, drop whole oldest
+//     turns instead of sending a context made of placeholders.
+//
 // Protected messages (last N assistant messages) are never pruned.
+//
+// The result always pairs tool calls with tool results: the protection boundary
+// never splits a call from its results, and calls left without a result get a
+// placeholder result while orphaned results are dropped, so providers that
+// validate the sequence do not reject the request.
 type ContextPruner struct {
-	estimator *TokenEstimator
+	tokenizer Tokenizer
 	config    PrunerConfig
 }
 
@@ -48,8 +57,35 @@ type PrunerConfig struct {
 	// KeepLastAssistants: number of recent assistant messages to protect from pruning.
 	// Default: 3.
 	KeepLastAssistants int
+
+	// SoftTrimMarker is inserted between head and tail of a soft-trimmed result.
+	// A %d verb, if present, receives the number of characters removed.
+	// Default: "... [%d characters truncated] ...".
+	SoftTrimMarker string
+
+	// PreserveJSON soft-trims JSON tool results by dropping array elements and
+	// object fields instead of slicing the text, keeping them valid JSON.
+	// Default: true.
+	PreserveJSON bool
+
+	// TrimLargeNonToolMessages also soft-trims user and assistant messages
+	// longer than LargeMessageChars (e.g. a pasted document) to head+tail.
+	// Default: false.
+	TrimLargeNonToolMessages bool
+
+	// LargeMessageChars is the size above which a user or assistant message is
+	// soft-trimmed when TrimLargeNonToolMessages is set.
+	// Default: 20000.
+	LargeMessageChars int
+
+	// MinKeepUserTurns: number of recent user turns never dropped when the
+	// context still overflows after hard-clear. Default: 1 (the current input).
+	MinKeepUserTurns int
 }
 
+// DefaultSoftTrimMarker is the default SoftTrimMarker.
+const DefaultSoftTrimMarker = "... [%d characters truncated] ..."
+
 // DefaultPrunerConfig returns the default pruning configuration (aligned with OpenClaw).
 func DefaultPrunerConfig() PrunerConfig {
 	return PrunerConfig{
@@ -58,11 +94,15 @@ func DefaultPrunerConfig() PrunerConfig {
 		SoftTrimHeadChars:  1500,
 		SoftTrimTailChars:  1500,
 		KeepLastAssistants: 3,
+		SoftTrimMarker:     DefaultSoftTrimMarker,
+		PreserveJSON:       true,
+		LargeMessageChars:  20000,
+		MinKeepUserTurns:   1,
 	}
 }
 
 // NewContextPruner creates a new pruner.
-func NewContextPruner(estimator *TokenEstimator, config PrunerConfig) *ContextPruner {
+func NewContextPruner(tokenizer Tokenizer, config PrunerConfig) *ContextPruner {
 	if config.SoftTrimRatio <= 0 {
 		config.SoftTrimRatio = 0.3
 	}
@@ -78,18 +118,39 @@ func NewContextPruner(estimator *TokenEstimator, config PrunerConfig) *ContextPr
 	if config.KeepLastAssistants <= 0 {
 		config.KeepLastAssistants = 3
 	}
+	if config.LargeMessageChars <= 0 {
+		config.LargeMessageChars = 20000
+	}
+	if config.MinKeepUserTurns <= 0 {
+		config.MinKeepUserTurns = 1
+	}
+	if config.SoftTrimMarker == "" {
+		config.SoftTrimMarker = DefaultSoftTrimMarker
+	}
 	return &ContextPruner{
-		estimator: estimator,
+		tokenizer: tokenizer,
 		config:    config,
 	}
 }
 
+// withTokenizer returns a pruner sharing p's config that counts tokens with tk.
+// A nil tk returns p itself.
+func (p *ContextPruner) withTokenizer(tk Tokenizer) *ContextPruner {
+	if tk == nil {
+		return p
+	}
+	return &ContextPruner{tokenizer: tk, config: p.config}
+}
+
 // PruneResult holds the outcome of a pruning pass.
 type PruneResult struct {
 	Messages        []*schema.Message
 	EstimatedTokens int
 	SoftTrimmed     int
 	HardCleared     int
+	ToolsRepaired   int // placeholder results added + orphaned results dropped
+	DroppedTurns    int // oldest user turns removed because the context still overflowed
+	DroppedMessages int
 }
 
 // Prune applies pruning to fit within usableTokens.
@@ -99,22 +160,32 @@ type PruneResult struct {
 //  1. Estimate total tokens
 //  2. If ratio > softTrimRatio: soft-trim old tool results
 //  3. Re-estimate; if ratio > hardClearRatio: hard-clear old tool results
-//  4. Return pruned message copies
-func (p *ContextPruner) Prune(messages []*schema.Message, usableTokens int) PruneResult {
+//  4. Re-estimate; if still over usableTokens: drop oldest whole turns, keeping
+//     leading system/injected messages, the protected messages and the last
+//     MinKeepUserTurns turns
+//  5. Return pruned message copies
+func (p *ContextPruner) Prune(ctx context.Context, messages []*schema.Message, usableTokens int) PruneResult {
+	messages, repaired := repairToolPairing(messages)
+	if repaired > 0 {
+		logger.CtxDebug(ctx, "[ContextPruner] repaired %d unpaired tool call/result messages", repaired)
+	}
+
 	if usableTokens <= 0 || len(messages) == 0 {
 		return PruneResult{
 			Messages:        messages,
-			EstimatedTokens: p.estimator.EstimateMessages(messages),
+			EstimatedTokens: p.tokenizer.CountMessages(messages),
+			ToolsRepaired:   repaired,
 		}
 	}
 
-	estimated := p.estimator.EstimateMessages(messages)
+	estimated := p.tokenizer.CountMessages(messages)
 	ratio := float64(estimated) / float64(usableTokens)
 
 	if ratio <= p.config.SoftTrimRatio {
 		return PruneResult{
 			Messages:        messages,
 			EstimatedTokens: estimated,
+			ToolsRepaired:   repaired,
 		}
 	}
 
@@ -124,31 +195,41 @@ func (p *ContextPruner) Prune(messages []*schema.Message, usableTokens int) Prun
 	// Determine the protection boundary: protect last N assistant messages.
 	protectFrom := p.findProtectionBoundary(pruned)
 
-	result := PruneResult{}
+	result := PruneResult{ToolsRepaired: repaired}
 
 	// Stage 1: Soft-trim.
 	if ratio > p.config.SoftTrimRatio {
 		result.SoftTrimmed = p.applySoftTrim(pruned, protectFrom)
-		estimated = p.estimator.EstimateMessages(pruned)
+		estimated = p.tokenizer.CountMessages(pruned)
 		ratio = float64(estimated) / float64(usableTokens)
-		logger.Debug("[ContextPruner] after soft-trim: %d tokens (ratio=%.2f), trimmed %d messages",
+		logger.CtxDebug(ctx, "[ContextPruner] after soft-trim: %d tokens (ratio=%.2f), trimmed %d messages",
 			estimated, ratio, result.SoftTrimmed)
 	}
 
 	// Stage 2: Hard-clear.
 	if ratio > p.config.HardClearRatio {
 		result.HardCleared = p.applyHardClear(pruned, protectFrom)
-		estimated = p.estimator.EstimateMessages(pruned)
-		logger.Debug("[ContextPruner] after hard-clear: %d tokens (ratio=%.2f), cleared %d messages",
+		estimated = p.tokenizer.CountMessages(pruned)
+		logger.CtxDebug(ctx, "[ContextPruner] after hard-clear: %d tokens (ratio=%.2f), cleared %d messages",
 			estimated, float64(estimated)/float64(usableTokens), result.HardCleared)
 	}
 
+	// Stage 3: Drop oldest turns.
+	if estimated > usableTokens {
+		pruned, estimated = p.dropOldestTurns(pruned, protectFrom, usableTokens, &result)
+		logger.CtxDebug(ctx, "[ContextPruner] after turn drop: %d tokens, dropped %d turns (%d messages)",
+			estimated, result.DroppedTurns, result.DroppedMessages)
+	}
+
 	result.Messages = pruned
 	result.EstimatedTokens = estimated
 	return result
 }
 
-// applySoftTrim truncates tool-role messages to head+tail with "..." separator.
+// applySoftTrim truncates tool-role messages to head+tail around the marker,
+// or, for JSON results with PreserveJSON, to a smaller valid JSON document.
+// With TrimLargeNonToolMessages, oversized user and assistant messages are
+// truncated to head+tail as well.
 // Returns the number of messages soft-trimmed.
 func (p *ContextPruner) applySoftTrim(messages []*schema.Message, protectFrom int) int {
 	trimmed := 0
@@ -156,6 +237,12 @@ func (p *ContextPruner) applySoftTrim(messages []*schema.Message, protectFrom in
 
 	for i := 0; i < protectFrom; i++ {
 		msg := messages[i]
+		if msg.Role == schema.User || msg.Role == schema.Assistant {
+			if p.trimLargeMessage(msg) {
+				trimmed++
+			}
+			continue
+		}
 		if msg.Role != schema.Tool {
 			continue
 		}
@@ -164,15 +251,52 @@ func (p *ContextPruner) applySoftTrim(messages []*schema.Message, protectFrom in
 			continue
 		}
 
-		head := string(runes[:p.config.SoftTrimHeadChars])
-		tail := string(runes[len(runes)-p.config.SoftTrimTailChars:])
-		msg.Content = fmt.Sprintf("%s\n\n... [%d characters truncated] ...\n\n%s",
-			head, len(runes)-maxKeep, tail)
+		if p.config.PreserveJSON {
+			if trimmedJSON, ok := trimJSON(msg.Content, maxKeep); ok {
+				msg.Content = trimmedJSON
+				trimmed++
+				continue
+			}
+		}
+
+		msg.Content = p.headTail(runes)
 		trimmed++
 	}
 	return trimmed
 }
 
+// trimLargeMessage truncates a user or assistant message above
+// LargeMessageChars to head+tail. Tool calls are left intact.
+func (p *ContextPruner) trimLargeMessage(msg *schema.Message) bool {
+	if !p.config.TrimLargeNonToolMessages {
+		return false
+	}
+	maxKeep := p.config.SoftTrimHeadChars + p.config.SoftTrimTailChars
+	runes := []rune(msg.Content)
+	if len(runes) <= p.config.LargeMessageChars || len(runes) <= maxKeep {
+		return false
+	}
+	msg.Content = p.headTail(runes)
+	return true
+}
+
+// headTail keeps the first SoftTrimHeadChars and last SoftTrimTailChars of
+// runes around the soft-trim marker. The caller ensures runes is longer.
+func (p *ContextPruner) headTail(runes []rune) string {
+	head := string(runes[:p.config.SoftTrimHeadChars])
+	tail := string(runes[len(runes)-p.config.SoftTrimTailChars:])
+	removed := len(runes) - p.config.SoftTrimHeadChars - p.config.SoftTrimTailChars
+	return head + "\n\n" + p.softTrimMarker(removed) + "\n\n" + tail
+}
+
+// softTrimMarker renders the configured marker for n removed characters.
+func (p *ContextPruner) softTrimMarker(n int) string {
+	if strings.Contains(p.config.SoftTrimMarker, "%d") {
+		return fmt.Sprintf(p.config.SoftTrimMarker, n)
+	}
+	return p.config.SoftTrimMarker
+}
+
 // applyHardClear replaces entire tool-role messages with a placeholder.
 // Returns the number of messages hard-cleared.
 func (p *ContextPruner) applyHardClear(messages []*schema.Message, protectFrom int) int {
@@ -191,6 +315,60 @@ func (p *ContextPruner) applyHardClear(messages []*schema.Message, protectFrom i
 	return cleared
 }
 
+// dropOldestTurns removes whole turns (a user message and everything up to the
+// next user message) from the front of the conversation until it fits
+// usableTokens. Messages before the first turn (system prompt, injected
+// memories), messages from protectFrom on, and the last MinKeepUserTurns
+// turns are always kept, so the model still sees the current request with
+// real content. Dropping whole turns keeps tool calls paired with their results.
+func (p *ContextPruner) dropOldestTurns(messages []*schema.Message, protectFrom, usableTokens int, result *PruneResult) ([]*schema.Message, int) {
+	// Turn starts, oldest first.
+	var starts []int
+	for i, msg := range messages {
+		if msg.Role == schema.User {
+			starts = append(starts, i)
+		}
+	}
+	estimated := p.tokenizer.CountMessages(messages)
+	if len(starts) <= p.config.MinKeepUserTurns {
+		return messages, estimated
+	}
+
+	// Only turns that end at or before keepFrom may be dropped.
+	keepFrom := starts[len(starts)-p.config.MinKeepUserTurns]
+	if protectFrom > 0 && protectFrom < keepFrom {
+		keepFrom = protectFrom
+	}
+
+	head := starts[0]
+	cut := head
+	for k := 0; k < len(starts) && estimated > usableTokens; k++ {
+		next := len(messages)
+		if k+1 < len(starts) {
+			next = starts[k+1]
+		}
+		if next > keepFrom {
+			break
+		}
+		cut = next
+		result.DroppedTurns++
+		estimated = p.tokenizer.CountMessages(joinMessages(messages[:head], messages[cut:]))
+	}
+
+	if cut == head {
+		return messages, estimated
+	}
+	result.DroppedMessages = cut - head
+	return joinMessages(messages[:head], messages[cut:]), estimated
+}
+
+// joinMessages returns a new slice holding a followed by b.
+func joinMessages(a, b []*schema.Message) []*schema.Message {
+	out := make([]*schema.Message, 0, len(a)+len(b))
+	out = append(out, a...)
+	return append(out, b...)
+}
+
 // findProtectionBoundary returns the index before which messages can be pruned.
 // The last KeepLastAssistants assistant messages (and everything after them) are protected.
 func (p *ContextPruner) findProtectionBoundary(messages []*schema.Message) int {
@@ -199,7 +377,7 @@ func (p *ContextPruner) findProtectionBoundary(messages []*schema.Message) int {
 		if messages[i].Role == schema.Assistant {
 			assistantCount++
 			if assistantCount >= p.config.KeepLastAssistants {
-				return i
+				return alignToToolGroup(messages, i)
 			}
 		}
 	}
@@ -207,6 +385,105 @@ func (p *ContextPruner) findProtectionBoundary(messages []*schema.Message) int {
 	return 0
 }
 
+// alignToToolGroup moves a boundary that falls on a tool result back to the
+// assistant message that issued the call, so a call and its results are
+// always pruned (or protected) together.
+func alignToToolGroup(messages []*schema.Message, boundary int) int {
+	for boundary > 0 && messages[boundary].Role == schema.Tool {
+		boundary--
+	}
+	return boundary
+}
+
+// missingToolResult is the content of a placeholder result for a tool call
+// that has no result in the history.
+const missingToolResult = "[Tool result unavailable]"
+
+// repairToolPairing makes every assistant tool call answered by a tool message
+// before the next non-tool message, and drops tool messages that answer no
+// preceding call. Such gaps come from history truncation or interrupted runs.
+// Returns the input slice unchanged when it is already consistent.
+func repairToolPairing(messages []*schema.Message) ([]*schema.Message, int) {
+	if !needsToolRepair(messages) {
+		return messages, 0
+	}
+
+	repaired := 0
+	result := make([]*schema.Message, 0, len(messages))
+	var pending []schema.ToolCall // calls of the current group without a result yet
+
+	flush := func() {
+		for _, tc := range pending {
+			result = append(result, schema.ToolMessage(missingToolResult, tc.ID, schema.WithToolName(tc.Function.Name)))
+			repaired++
+		}
+		pending = nil
+	}
+
+	for _, msg := range messages {
+		if msg.Role == schema.Tool {
+			if msg.ToolCallID == "" {
+				result = append(result, msg) // unidentified result, leave as-is
+				continue
+			}
+			idx := -1
+			for i, tc := range pending {
+				if tc.ID == msg.ToolCallID {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				repaired++ // orphaned result: its call is not in the history
+				continue
+			}
+			pending = append(pending[:idx:idx], pending[idx+1:]...)
+			result = append(result, msg)
+			continue
+		}
+
+		flush()
+		result = append(result, msg)
+		if msg.Role == schema.Assistant {
+			for _, tc := range msg.ToolCalls {
+				if tc.ID != "" {
+					pending = append(pending, tc)
+				}
+			}
+		}
+	}
+	flush()
+	return result, repaired
+}
+
+// needsToolRepair reports whether repairToolPairing would change messages.
+func needsToolRepair(messages []*schema.Message) bool {
+	pending := make(map[string]struct{})
+	for _, msg := range messages {
+		if msg.Role == schema.Tool {
+			if msg.ToolCallID == "" {
+				continue
+			}
+			if _, ok := pending[msg.ToolCallID]; !ok {
+				return true
+			}
+			delete(pending, msg.ToolCallID)
+			continue
+		}
+		if len(pending) > 0 {
+			return true
+		}
+		if msg.Role == schema.Assistant {
+			for _, tc := range msg.ToolCalls {
+				if tc.ID != "" {
+					pending[tc.ID] = struct{}{}
+				}
+			}
+		}
+	}
+	return len(pending) > 0
+}
+
 // deepCopyMessages creates a deep copy of the message slice.
 // Only Content is mutable during pruning, so we share ToolCalls by reference.
 func (p *ContextPruner) deepCopyMessages(messages []*schema.Message) []*schema.Message {
@@ -0,0 +1,236 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// callMsg is an assistant message calling the tools with the given IDs.
+func callMsg(ids ...string) *schema.Message {
+	calls := make([]schema.ToolCall, 0, len(ids))
+	for _, id := range ids {
+		calls = append(calls, schema.ToolCall{ID: id, Function: schema.FunctionCall{Name: "tool_" + id}})
+	}
+	return schema.AssistantMessage("", calls)
+}
+
+// describe renders messages as "role" or "role:callID" for tool results,
+// with "!" marking placeholder results.
+func describe(messages []*schema.Message) []string {
+	out := make([]string, 0, len(messages))
+	for _, m := range messages {
+		s := string(m.Role)
+		if m.Role == schema.Tool {
+			s += ":" + m.ToolCallID
+			if m.Content == missingToolResult {
+				s += "!"
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestRepairToolPairing(t *testing.T) {
+	tests := []struct {
+		name         string
+		messages     []*schema.Message
+		want         []string
+		wantRepaired int
+	}{
+		{"consistent",
+			[]*schema.Message{schema.UserMessage("q"), callMsg("1", "2"), schema.ToolMessage("r", "2"), schema.ToolMessage("r", "1"), schema.AssistantMessage("a", nil)},
+			[]string{"user", "assistant", "tool:2", "tool:1", "assistant"}, 0},
+		{"missing result",
+			[]*schema.Message{callMsg("1", "2"), schema.ToolMessage("r", "1"), schema.UserMessage("q")},
+			[]string{"assistant", "tool:1", "tool:2!", "user"}, 1},
+		{"trailing call",
+			[]*schema.Message{schema.UserMessage("q"), callMsg("1")},
+			[]string{"user", "assistant", "tool:1!"}, 1},
+		{"orphaned result",
+			[]*schema.Message{schema.ToolMessage("r", "0"), schema.UserMessage("q"), schema.AssistantMessage("a", nil)},
+			[]string{"user", "assistant"}, 1},
+		{"result after the next message",
+			[]*schema.Message{callMsg("1"), schema.UserMessage("q"), schema.ToolMessage("r", "1")},
+			[]string{"assistant", "tool:1!", "user"}, 2},
+		{"result without call id kept",
+			[]*schema.Message{schema.UserMessage("q"), schema.ToolMessage("r", "")},
+			[]string{"user", "tool:"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, repaired := repairToolPairing(tt.messages)
+			if !slices.Equal(describe(got), tt.want) || repaired != tt.wantRepaired {
+				t.Fatalf("repairToolPairing = %v (%d repaired), want %v (%d)", describe(got), repaired, tt.want, tt.wantRepaired)
+			}
+			if tt.wantRepaired == 0 && &got[0] != &tt.messages[0] {
+				t.Fatal("consistent history was copied")
+			}
+		})
+	}
+}
+
+func TestFindProtectionBoundaryKeepsToolGroups(t *testing.T) {
+	p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), PrunerConfig{KeepLastAssistants: 2})
+	messages := []*schema.Message{
+		schema.UserMessage("q"),           // 0
+		callMsg("1"),                      // 1
+		schema.ToolMessage("r", "1"),      // 2
+		schema.AssistantMessage("a", nil), // 3
+	}
+	// The second-to-last assistant message is the call at 1.
+	if got := p.findProtectionBoundary(messages); got != 1 {
+		t.Fatalf("boundary = %d, want 1", got)
+	}
+	if got := alignToToolGroup(messages, 2); got != 1 {
+		t.Fatalf("alignToToolGroup(tool result) = %d, want the call at 1", got)
+	}
+	if got := alignToToolGroup(messages, 3); got != 3 {
+		t.Fatalf("alignToToolGroup(assistant) = %d, want 3", got)
+	}
+}
+
+func TestPruneRepairsToolPairing(t *testing.T) {
+	p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), DefaultPrunerConfig())
+	messages := []*schema.Message{schema.UserMessage("q"), callMsg("1"), schema.UserMessage("again")}
+	result := p.Prune(context.Background(), messages, 100_000)
+	if result.ToolsRepaired != 1 || !slices.Equal(describe(result.Messages), []string{"user", "assistant", "tool:1!", "user"}) {
+		t.Fatalf("prune = %v (%d repaired)", describe(result.Messages), result.ToolsRepaired)
+	}
+	if len(messages) != 3 {
+		t.Fatal("input messages modified")
+	}
+}
+
+func TestApplySoftTrimMarker(t *testing.T) {
+	long := strings.Repeat("a", 20) + strings.Repeat("b", 20)
+	jsonResult := `{"rows":[` + strings.Repeat(`"row",`, 20) + `"row"]}`
+	tests := []struct {
+		name    string
+		config  PrunerConfig
+		content string
+		want    string
+	}{
+		{"default marker", PrunerConfig{SoftTrimHeadChars: 5, SoftTrimTailChars: 5}, long,
+			"aaaaa\n\n... [30 characters truncated] ...\n\nbbbbb"},
+		{"custom marker with count", PrunerConfig{SoftTrimHeadChars: 5, SoftTrimTailChars: 5, SoftTrimMarker: "<%d cut>"}, long,
+			"aaaaa\n\n<30 cut>\n\nbbbbb"},
+		{"custom marker without count", PrunerConfig{SoftTrimHeadChars: 5, SoftTrimTailChars: 5, SoftTrimMarker: "[snip]"}, long,
+			"aaaaa\n\n[snip]\n\nbbbbb"},
+		{"JSON sliced without PreserveJSON", PrunerConfig{SoftTrimHeadChars: 5, SoftTrimTailChars: 5, SoftTrimMarker: "[snip]"}, jsonResult,
+			"{\"row\n\n[snip]\n\now\"]}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), tt.config)
+			messages := []*schema.Message{schema.ToolMessage(tt.content, "1")}
+			if n := p.applySoftTrim(messages, 1); n != 1 || messages[0].Content != tt.want {
+				t.Fatalf("soft-trimmed %d: %q, want %q", n, messages[0].Content, tt.want)
+			}
+		})
+	}
+
+	p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), PrunerConfig{SoftTrimHeadChars: 20, SoftTrimTailChars: 20, PreserveJSON: true})
+	messages := []*schema.Message{schema.ToolMessage(jsonResult, "1")}
+	if p.applySoftTrim(messages, 1) != 1 || !json.Valid([]byte(messages[0].Content)) {
+		t.Fatalf("PreserveJSON result is not valid JSON: %s", messages[0].Content)
+	}
+}
+
+func TestSoftTrimLargeNonToolMessages(t *testing.T) {
+	large := strings.Repeat("a", 30) + strings.Repeat("b", 30)
+	trimmed := "aaaaa\n\n... [50 characters truncated] ...\n\nbbbbb"
+	tests := []struct {
+		name    string
+		enabled bool
+		msg     *schema.Message
+		protect bool
+		want    string
+	}{
+		{"disabled", false, schema.UserMessage(large), false, large},
+		{"large user message", true, schema.UserMessage(large), false, trimmed},
+		{"large assistant message", true, schema.AssistantMessage(large, nil), false, trimmed},
+		{"below the threshold", true, schema.UserMessage(large[:40]), false, large[:40]},
+		{"protected", true, schema.UserMessage(large), true, large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), PrunerConfig{
+				SoftTrimHeadChars:        5,
+				SoftTrimTailChars:        5,
+				TrimLargeNonToolMessages: tt.enabled,
+				LargeMessageChars:        50,
+			})
+			protectFrom := 1
+			if tt.protect {
+				protectFrom = 0
+			}
+			messages := []*schema.Message{tt.msg}
+			p.applySoftTrim(messages, protectFrom)
+			if messages[0].Content != tt.want {
+				t.Fatalf("content = %q, want %q", messages[0].Content, tt.want)
+			}
+		})
+	}
+
+	// The tool calls of a trimmed assistant message are kept.
+	p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), PrunerConfig{
+		SoftTrimHeadChars: 5, SoftTrimTailChars: 5, TrimLargeNonToolMessages: true, LargeMessageChars: 50,
+	})
+	msg := callMsg("1")
+	msg.Content = large
+	if p.applySoftTrim([]*schema.Message{msg}, 1) != 1 || len(msg.ToolCalls) != 1 {
+		t.Fatalf("trimmed call message = %q with %d calls", msg.Content, len(msg.ToolCalls))
+	}
+}
+
+func TestPruneDropsOldestTurns(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	history := func() []*schema.Message {
+		return []*schema.Message{
+			schema.SystemMessage("sys"),
+			schema.UserMessage(long), callMsg("1"), schema.ToolMessage(long, "1"), schema.AssistantMessage(long, nil),
+			schema.UserMessage(long), schema.AssistantMessage(long, nil),
+			schema.UserMessage("now"),
+		}
+	}
+	tests := []struct {
+		name        string
+		usable      int
+		want        []string
+		wantDropped int
+		wantFit     bool
+	}{
+		{"fits", 10_000, []string{"system", "user", "assistant", "tool:1", "assistant", "user", "assistant", "user"}, 0, true},
+		{"oldest turn dropped", 300, []string{"system", "user", "assistant", "user"}, 1, true},
+		// The protected assistant message and the current input always survive.
+		{"budget below the protected messages", 10, []string{"system", "user", "assistant", "user"}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewContextPruner(NewTokenEstimator(DefaultCharsPerTokenRatio), PrunerConfig{KeepLastAssistants: 1})
+			result := p.Prune(context.Background(), history(), tt.usable)
+			if got := describe(result.Messages); !slices.Equal(got, tt.want) {
+				t.Fatalf("messages = %v, want %v", got, tt.want)
+			}
+			if result.DroppedTurns != tt.wantDropped || result.DroppedMessages != len(history())-len(tt.want) {
+				t.Fatalf("dropped %d turns (%d messages), want %d (%d)",
+					result.DroppedTurns, result.DroppedMessages, tt.wantDropped, len(history())-len(tt.want))
+			}
+			if fit := result.EstimatedTokens <= tt.usable; fit != tt.wantFit {
+				t.Fatalf("%d tokens for a budget of %d, want fit %v", result.EstimatedTokens, tt.usable, tt.wantFit)
+			}
+			if last := result.Messages[len(result.Messages)-1]; last.Content != "now" {
+				t.Fatalf("last message = %q, want the current input", last.Content)
+			}
+			if tt.wantDropped > 0 && result.Messages[1].Content != long {
+				t.Fatal("the kept turn lost its content")
+			}
+		})
+	}
+}
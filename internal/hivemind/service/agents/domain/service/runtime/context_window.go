@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/tokenizer"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg"
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
@@ -18,12 +19,19 @@ import (
 // - Provides the effective window for downstream pruning/compaction decisions
 //
 // Resolution priority:
-// 1. Model's ContextWindow from ModelInstance metadata
-// 2. Configured default from module config
-// 3. Hardcoded fallback (200,000 -> Claude Opus 4.5 level)
+// 1. Model's ProbedContextWindow discovered by the ModelProber
+// 2. Model's ContextWindow from ModelInstance metadata
+// 3. Configured default from module config
+// 4. Hardcoded fallback (200,000 -> Claude Opus 4.5 level)
 type ContextWindowGuard struct {
 	modelManager  llmService.ModelManager
 	defaultWindow int
+
+	// tokenizers holds exact tokenizers per model family (see SetTokenizer).
+	tokenizers map[llmEntity.ModelClass]Tokenizer
+
+	// encodings holds exact tokenizers per tiktoken encoding (see SetEncodingTokenizer).
+	encodings map[string]Tokenizer
 }
 
 const (
@@ -37,6 +45,10 @@ const (
 	// DefaultContextWindow is the default context window size used when no other
 	// configuration is available.
 	DefaultContextWindow = 200_000
+
+	// DefaultReserveTokens is the reserve used when neither the agent nor
+	// the model specifies one.
+	DefaultReserveTokens = 4096
 )
 
 // NewContextWindowGuard creates a new ContextWindowGuard.
@@ -50,6 +62,41 @@ func NewContextWindowGuard(modelManager llmService.ModelManager, defaultWindow i
 	}
 }
 
+// SetTokenizer registers an exact tokenizer for a model family, used instead
+// of the char-based estimate. Must be called before the guard is in use.
+func (g *ContextWindowGuard) SetTokenizer(class llmEntity.ModelClass, tk Tokenizer) {
+	if g.tokenizers == nil {
+		g.tokenizers = make(map[llmEntity.ModelClass]Tokenizer)
+	}
+	g.tokenizers[class] = tk
+}
+
+// SetEncodingTokenizer registers an exact tokenizer for a tiktoken encoding.
+// It takes precedence over SetTokenizer for models whose name maps to the
+// encoding (see tokenizer.EncodingForModel), whatever provider serves them.
+// Must be called before the guard is in use.
+func (g *ContextWindowGuard) SetEncodingTokenizer(encoding string, tk Tokenizer) {
+	if g.encodings == nil {
+		g.encodings = make(map[string]Tokenizer)
+	}
+	g.encodings[encoding] = tk
+}
+
+// tokenizerFor selects the tokenizer for a model: the tokenizer of its
+// tiktoken encoding, then the tokenizer of its family, and finally an
+// estimator with the family's chars-per-token ratios.
+func (g *ContextWindowGuard) tokenizerFor(class llmEntity.ModelClass, modelID string) Tokenizer {
+	if enc := tokenizer.EncodingForModel(modelID); enc != "" {
+		if tk, ok := g.encodings[enc]; ok {
+			return tk
+		}
+	}
+	if tk, ok := g.tokenizers[class]; ok {
+		return tk
+	}
+	return NewTokenEstimator(CharsPerTokenForClass(class))
+}
+
 // ContextWindowInfo holds the resolved context window parameters.
 type ContextWindowInfo struct {
 	// WindowSize is the total context window in tokens.
@@ -61,47 +108,76 @@ type ContextWindowInfo struct {
 
 	// UsableTokens is the number of tokens available for actual agent input/output.
 	UsableTokens int
+
+	// Tokenizer counts tokens for this model: an exact tokenizer when one is
+	// registered for the model family, otherwise a TokenEstimator with ratios
+	// tuned to it. nil means the component's default.
+	Tokenizer Tokenizer
 }
 
 // Resolve determines the effective context window size for the given model reference.
-func (g *ContextWindowGuard) Resolve(ctx context.Context, ref llmEntity.ModelRef) ContextWindowInfo {
+//
+// reserveOverride is the agent's ReserveTokens setting. When non-nil it replaces
+// the model-derived reserve (and the half-window cap), clamped to [0, WindowSize]
+// so that UsableTokens never goes negative.
+func (g *ContextWindowGuard) Resolve(ctx context.Context, ref llmEntity.ModelRef, reserveOverride *int) ContextWindowInfo {
 	windowSize := g.defaultWindow
-	reserveTokens := 4096
+	reserveTokens := DefaultReserveTokens
+	modelClass := llmEntity.ModelClass_Other
 
 	if g.modelManager != nil {
 		model, err := g.modelManager.GetModelByRef(ctx, ref)
 		if err == nil && model != nil {
-			if model.ContextWindow > 0 {
+			switch {
+			case model.ProbedContextWindow > 0:
+				windowSize = model.ProbedContextWindow
+			case model.ContextWindow > 0:
 				windowSize = model.ContextWindow
 			}
 			if model.MaxTokens > 0 {
 				reserveTokens = model.MaxTokens
 			}
+			if provider, err := g.modelManager.GetProvider(ctx, ref.ProviderID); err == nil && provider != nil {
+				modelClass = provider.ModelClass
+			}
 		} else if err != nil {
-			logger.WarnX(pkg.ModuleName, "[ContextWindowGuard] failed to resolve model context window, err: %v", "modelRef",
-				ref, "err", err)
+			logger.CtxWarnX(ctx, pkg.ModuleName, "[ContextWindowGuard] failed to resolve context window of model %s, err: %v", ref, err)
 		}
 	}
 	if windowSize < HardMinimumContextWindow {
-		logger.WarnX(pkg.ModuleName, "[ContextWindowGuard] resolved window size %d is below hard minimum %d, using default %d",
-			"windowSize", windowSize, "hardMinimum", HardMinimumContextWindow, "defaultWindow", g.defaultWindow)
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[ContextWindowGuard] resolved window size %d is below hard minimum %d, using default %d",
+			windowSize, HardMinimumContextWindow, g.defaultWindow)
 		windowSize = HardMinimumContextWindow
 	} else if windowSize < WarnContextWindow {
-		logger.WarnX(pkg.ModuleName, "[ContextWindowGuard] resolved window size %d is below warn threshold %d, using default %d",
-			"windowSize", windowSize, "warnThreshold", WarnContextWindow, "defaultWindow", g.defaultWindow)
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[ContextWindowGuard] resolved window size %d is below warn threshold %d, using default %d",
+			windowSize, WarnContextWindow, g.defaultWindow)
 	}
 
-	// Ensure reserve doesn't execute more than half the window.
-	if reserveTokens > windowSize/2 {
+	if reserveOverride != nil {
+		reserveTokens = clampReserve(*reserveOverride, windowSize)
+	} else if reserveTokens > windowSize/2 {
+		// Ensure reserve doesn't execute more than half the window.
 		reserveTokens = windowSize / 2
 	}
 
-	logger.DebugX(pkg.ModuleName, "[ContextWindowGuard] resolved window size %d, reserve tokens %d, usable tokens %d",
-		"windowSize", windowSize, "reserveTokens", reserveTokens, "usableTokens", windowSize-reserveTokens)
+	logger.CtxDebugX(ctx, pkg.ModuleName, "[ContextWindowGuard] resolved window size %d, reserve tokens %d, usable tokens %d",
+		windowSize, reserveTokens, windowSize-reserveTokens)
 
 	return ContextWindowInfo{
 		WindowSize:    windowSize,
 		ReserveTokens: reserveTokens,
 		UsableTokens:  windowSize - reserveTokens,
+		Tokenizer:     g.tokenizerFor(modelClass, ref.ModelID),
+	}
+}
+
+// clampReserve bounds reserve to [0, windowSize].
+func clampReserve(reserve, windowSize int) int {
+	if reserve < 0 {
+		return 0
+	}
+	if reserve > windowSize {
+		return windowSize
 	}
+	return reserve
 }
@@ -0,0 +1,170 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/tokenizer"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+)
+
+// windowModels is a ModelManager serving a single model instance.
+type windowModels struct {
+	llmService.ModelManager
+	model *llmEntity.ModelInstance
+}
+
+func (m windowModels) GetModelByRef(context.Context, llmEntity.ModelRef) (*llmEntity.ModelInstance, error) {
+	return m.model, nil
+}
+
+func (m windowModels) GetProvider(context.Context, string) (*llmEntity.ModelProvider, error) {
+	return nil, errors.New("no provider")
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestContextWindowGuardReserve(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxTokens   int
+		override    *int
+		wantReserve int
+	}{
+		{"default", 0, nil, DefaultReserveTokens},
+		{"model max tokens", 8000, nil, 8000},
+		{"model reserve capped at half", 40_000, nil, 32_000},
+		{"override", 8000, intPtr(1000), 1000},
+		{"override beyond half", 0, intPtr(50_000), 50_000},
+		{"override zero", 8000, intPtr(0), 0},
+		{"negative override", 0, intPtr(-5), 0},
+		{"override beyond window", 0, intPtr(100_000), 64_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewContextWindowGuard(windowModels{model: &llmEntity.ModelInstance{ContextWindow: 64_000, MaxTokens: tt.maxTokens}}, 0)
+			info := g.Resolve(context.Background(), llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}, tt.override)
+			if info.WindowSize != 64_000 || info.ReserveTokens != tt.wantReserve || info.UsableTokens != 64_000-tt.wantReserve {
+				t.Fatalf("window info = %+v, want reserve %d of 64000", info, tt.wantReserve)
+			}
+		})
+	}
+}
+
+func TestContextWindowGuardPrefersProbedWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      *llmEntity.ModelInstance
+		wantWindow int
+	}{
+		{"probed", &llmEntity.ModelInstance{ContextWindow: 128_000, ProbedContextWindow: 32_768}, 32_768},
+		{"configured", &llmEntity.ModelInstance{ContextWindow: 128_000}, 128_000},
+		{"neither", &llmEntity.ModelInstance{}, 50_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewContextWindowGuard(windowModels{model: tt.model}, 50_000)
+			info := g.Resolve(context.Background(), llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}, intPtr(0))
+			if info.WindowSize != tt.wantWindow {
+				t.Fatalf("window = %d, want %d", info.WindowSize, tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestResolveWindowInfoWithoutGuard(t *testing.T) {
+	tests := []struct {
+		name        string
+		reserve     *int
+		wantReserve int
+	}{
+		{"default", nil, DefaultReserveTokens},
+		{"agent override", intPtr(1000), 1000},
+		{"override beyond window", intPtr(DefaultContextWindow + 1), DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := (&AgentRunner{}).resolveWindowInfo(context.Background(), &entity.Agent{ReserveTokens: tt.reserve})
+			if info.ReserveTokens != tt.wantReserve || info.UsableTokens != DefaultContextWindow-tt.wantReserve {
+				t.Fatalf("window info = %+v, want reserve %d", info, tt.wantReserve)
+			}
+		})
+	}
+}
+
+// namedTokenizer is a Tokenizer told apart by name.
+type namedTokenizer struct {
+	Tokenizer
+	name string
+}
+
+func TestContextWindowGuardTokenizerFor(t *testing.T) {
+	gpt, o200k := &namedTokenizer{name: "gpt"}, &namedTokenizer{name: "o200k"}
+	g := NewContextWindowGuard(windowModels{model: &llmEntity.ModelInstance{ContextWindow: 64_000}}, 0)
+	g.SetTokenizer(llmEntity.ModelClass_GPT, gpt)
+	g.SetEncodingTokenizer(tokenizer.EncodingO200K, o200k)
+
+	tests := []struct {
+		name  string
+		class llmEntity.ModelClass
+		model string
+		want  Tokenizer
+	}{
+		{"encoding of the model", llmEntity.ModelClass_GPT, "gpt-4o", o200k},
+		{"encoding on another provider", llmEntity.ModelClass_Other, "openai/gpt-4o-mini", o200k},
+		{"family without encoding tokenizer", llmEntity.ModelClass_GPT, "gpt-4-turbo", gpt},
+		{"unknown model of the family", llmEntity.ModelClass_GPT, "custom", gpt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.tokenizerFor(tt.class, tt.model); got != tt.want {
+				t.Fatalf("tokenizer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Models without an exact tokenizer are estimated with the family's ratio.
+	est, ok := g.tokenizerFor(llmEntity.ModelClass_Claude, "claude-3-5-sonnet").(*TokenEstimator)
+	if !ok || est.ratio != CharsPerTokenForClass(llmEntity.ModelClass_Claude) {
+		t.Fatalf("tokenizer = %v, want the Claude char-ratio estimator", est)
+	}
+	if info := g.Resolve(context.Background(), llmEntity.ModelRef{ProviderID: "p", ModelID: "gpt-4o"}, nil); info.Tokenizer != o200k {
+		t.Fatalf("Resolve tokenizer = %v, want the o200k tokenizer", info.Tokenizer)
+	}
+}
+
+func TestConfigureTokenizers(t *testing.T) {
+	dir := t.TempDir()
+	ranks := filepath.Join(dir, "ranks.tiktoken")
+	// Ranks "a" 0 and "b" 1.
+	if err := os.WriteFile(ranks, []byte("YQ== 0\nYg== 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewContextWindowGuard(windowModels{}, 0)
+	configureTokenizers(g, AgentRunnerConfig{
+		TokenizerFile: ranks,
+		TokenizerFiles: map[string]string{
+			tokenizer.EncodingO200K: ranks,
+			"p50k_base":             filepath.Join(dir, "missing.tiktoken"),
+		},
+	})
+
+	if _, ok := g.tokenizers[llmEntity.ModelClass_GPT].(*tokenizer.Tiktoken); !ok {
+		t.Fatal("TokenizerFile not registered for OpenAI models")
+	}
+	for _, enc := range []string{tokenizer.EncodingCL100K, tokenizer.EncodingO200K} {
+		tk, ok := g.encodings[enc].(*tokenizer.Tiktoken)
+		if !ok || tk.Encoding() != enc {
+			t.Fatalf("encoding %s tokenizer = %v", enc, g.encodings[enc])
+		}
+	}
+	if _, ok := g.encodings["p50k_base"]; ok {
+		t.Fatal("a file that failed to load was registered")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	einoModel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
@@ -13,6 +14,7 @@ import (
 	"github.com/cloudwego/eino/schema"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/agentflow"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
 	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
@@ -69,6 +71,21 @@ type TurnRequest struct {
 	// Compactor performs compaction when context overflow is detected.
 	// May be nil if compaction is not configured.
 	Compactor *Compactor
+
+	// Params are the resolved LLM params for this turn (agent params plus
+	// per-request overrides). nil means the agent's own params.
+	Params *llmEntity.LLMParams
+
+	// MaxHistoryTurns is the run's history-turn override, applied when the
+	// context is rebuilt after compaction. 0 keeps the configured limit.
+	MaxHistoryTurns int
+
+	// Input, InjectedMessages and PromptContext are what Messages was built
+	// from. The context rebuilt after compaction uses them again, so the
+	// retry still carries the user's input and its images.
+	Input            *entity.Message
+	InjectedMessages []*entity.Message
+	PromptContext    *prompt.PromptContext
 }
 
 // TurnResult is the output of a successful turn execution.
@@ -77,6 +94,9 @@ type TurnResult struct {
 	ModelRef     llmEntity.ModelRef
 	Usage        *entity.TokenUsage
 	Compacted    bool
+
+	// MaxTurnsReached is set when the tool loop hit TurnRequest.MaxTurns.
+	MaxTurnsReached bool
 }
 
 // Execute runs a single agent turn with fallback and retry logic.
@@ -84,30 +104,68 @@ type TurnResult struct {
 // The flow:
 //  1. Use FallbackExecutor to try models in order
 //  2. For each model: build AgentFlow → stream execute → collect result
-//  3. On context overflow: compact session history, rebuild context, retry
-//  4. On abort: return immediately
+//  3. On a failover-worthy error after output was streamed: emit EventModelSwitch
+//     and restart the turn on the next candidate, dropping the failed attempt's output
+//  4. On context overflow: compact session history, rebuild context, retry
+//  5. On abort: return immediately
+//  6. On reaching MaxTurns: end the turn per the agent's MaxTurnsBehavior
 func (te *TurnExecutor) Execute(
 	ctx context.Context,
 	req *TurnRequest,
 	abort *AbortController,
 ) (*TurnResult, error) {
-	params := req.Agent.LLMParams()
+	params := req.Params
+	if params == nil {
+		params = req.Agent.LLMParams()
+	}
 	compactionAttempted := false
+	meter := &usageMeter{}
+
+	// Budgeted once per Execute, so retries and fallback attempts share the
+	// counts, while each choice of an n > 1 run has its own.
+	req.Tools = withToolBudget(ctx, req.Tools, req.Agent.ToolBudget)
 
 	for attempt := 0; attempt < te.maxRetries; attempt++ {
 		if err := abort.CheckAborted(); err != nil {
 			return nil, err
 		}
 
+		// State of the most recent attempt, shared by the run and onError callbacks
+		// (RunWithFallback invokes them sequentially).
+		var last *attemptSink
+
 		result := llmService.RunWithFallback(
 			abort.Context(),
 			te.fallbackExec,
 			req.Agent.Fallback,
 			params,
 			func(ctx context.Context, cm einoModel.BaseChatModel) (*TurnResult, error) {
-				return te.executeSingleAttempt(ctx, req, cm)
+				last = newAttemptSink(req.EventWriter)
+				res, err := te.executeSingleAttempt(ctx, req, cm, last, meter)
+				if err != nil {
+					// Late events from the failed attempt's callbacks must not reach the client.
+					last.detach()
+				} else {
+					last.flushErrors()
+				}
+				return res, err
 			},
 			func(attempt llmEntity.FallbackAttempt, attemptNum, total int) {
+				sink := last
+				last = nil
+
+				if sink != nil && sink.streamed() && attempt.Reason.ShouldFailover() && attemptNum < total {
+					logger.CtxInfo(ctx, "[TurnExecutor] model %s failed mid-stream (%s), switching to next candidate", attempt.Ref, attempt.Reason)
+					req.EventWriter.Send(&entity.AgentEvent{
+						Type:  entity.EventModelSwitch,
+						Error: fmt.Sprintf("model %s failed (%s), switching model...", attempt.Ref, attempt.Reason),
+					}, nil)
+					return
+				}
+
+				if sink != nil {
+					sink.flushErrors()
+				}
 				req.EventWriter.Send(&entity.AgentEvent{
 					Type:  entity.EventError,
 					Error: fmt.Sprintf("model %s failed (attempt %d/%d): %s", attempt.Ref, attemptNum, total, attempt.Error),
@@ -117,6 +175,7 @@ func (te *TurnExecutor) Execute(
 
 		if result.OK {
 			result.Value.ModelRef = result.Ref
+			result.Value.Usage = meter.total()
 			return result.Value, nil
 		}
 
@@ -127,26 +186,32 @@ func (te *TurnExecutor) Execute(
 			compactionAttempted = true
 
 			if req.Compactor != nil && req.Session != nil {
-				logger.Info("[TurnExecutor] context overflow on attempt %d, running compaction...", attempt+1)
+				logger.CtxInfo(ctx, "[TurnExecutor] context overflow on attempt %d, running compaction...", attempt+1)
 
 				// Get a ChatModel for compaction (use fallback to get the first available).
+				// Compaction uses the agent's own params: per-request overrides such as
+				// JSON response_format must not apply to the summary.
 				compactModel, _, err := te.fallbackExec.GetChatModelWithFallback(
-					abort.Context(), req.Agent.Fallback, params)
+					abort.Context(), req.Agent.Fallback, req.Agent.LLMParams())
 				if err != nil {
-					logger.Warn("[TurnExecutor] failed to get model for compaction: %v", err)
+					logger.CtxWarn(ctx, "[TurnExecutor] failed to get model for compaction: %v", err)
 					return nil, fmt.Errorf("context overflow and compaction model unavailable: %w", combinedErr)
 				}
 
+				tokensBefore := req.Compactor.ActiveTokens(req.Session, req.WindowInfo)
+				req.EventWriter.Send(compactionEvent(entity.CompactionReasonOverflow, entity.CompactionStarted, tokensBefore, 0), nil)
+
 				_, compactErr := req.Compactor.Compact(abort.Context(), req.Session, compactModel, req.WindowInfo)
 				if compactErr != nil {
-					logger.Warn("[TurnExecutor] compaction failed: %v", compactErr)
+					logger.CtxWarn(ctx, "[TurnExecutor] compaction failed: %v", compactErr)
+					req.EventWriter.Send(compactionEvent(entity.CompactionReasonOverflow, entity.CompactionFailed, tokensBefore, 0), nil)
 					return nil, fmt.Errorf("context overflow and compaction failed: %w", combinedErr)
 				}
+				req.EventWriter.Send(compactionEvent(entity.CompactionReasonOverflow, entity.CompactionCompleted,
+					tokensBefore, req.Compactor.ActiveTokens(req.Session, req.WindowInfo)), nil)
 
 				// Rebuild context with compacted session.
-				newBuild := te.contextBuilder.Build(
-					req.Agent, req.Session, "", nil, req.WindowInfo,
-				)
+				newBuild := te.rebuildContext(ctx, req)
 				req.Messages = newBuild.Messages
 
 				req.EventWriter.Send(&entity.AgentEvent{
@@ -154,11 +219,11 @@ func (te *TurnExecutor) Execute(
 					Error: "context compacted, retrying...",
 				}, nil)
 
-				logger.Info("[TurnExecutor] compaction succeeded, retrying with %d tokens", newBuild.EstimatedTokens)
+				logger.CtxInfo(ctx, "[TurnExecutor] compaction succeeded, retrying with %d tokens", newBuild.EstimatedTokens)
 				continue
 			}
 
-			logger.Warn("[TurnExecutor] context overflow on attempt %d, compaction not available", attempt+1)
+			logger.CtxWarn(ctx, "[TurnExecutor] context overflow on attempt %d, compaction not available", attempt+1)
 			return nil, fmt.Errorf("context overflow (compaction not configured): %w", combinedErr)
 		}
 
@@ -168,36 +233,253 @@ func (te *TurnExecutor) Execute(
 	return nil, fmt.Errorf("max retries (%d) exceeded", te.maxRetries)
 }
 
-// executeSingleAttempt runs the AgentFlow with a specific ChatModel.
+// executeSingleAttempt runs the AgentFlow with a specific ChatModel,
+// streaming its events into sink and recording token usage in meter.
 func (te *TurnExecutor) executeSingleAttempt(
 	ctx context.Context,
 	req *TurnRequest,
 	cm einoModel.BaseChatModel,
+	sink *attemptSink,
+	meter *usageMeter,
 ) (*TurnResult, error) {
 	runnable, err := te.flowBuilder.Build(ctx, req.Agent, cm, req.Tools, req.MaxTurns)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build agent flow: %w", err)
 	}
 
-	clb := agentflow.NewReplayChunkCallback(req.EventWriter)
+	transcript := agentflow.NewTranscript()
+	clb := newAttemptCallback(sink, meter)
 
-	sr, err := runnable.Stream(ctx, req.Messages,
+	sr, err := runnable.Stream(agentflow.WithTranscript(ctx, transcript), req.Messages,
 		compose.WithCallbacks(clb.Build()),
 	)
 	if err != nil {
+		if errors.Is(err, compose.ErrExceedMaxSteps) {
+			clb.Wait()
+			return te.finishAtMaxTurns(ctx, req, cm, sink, meter, transcript)
+		}
 		return nil, fmt.Errorf("agent flow stream failed: %w", err)
 	}
 
 	finalMsg, err := collectStreamResult(sr)
 	if err != nil {
+		if errors.Is(err, compose.ErrExceedMaxSteps) {
+			clb.Wait()
+			return te.finishAtMaxTurns(ctx, req, cm, sink, meter, transcript)
+		}
 		return nil, err
 	}
 
+	// Let the callbacks deliver the last events and usage before the turn ends.
+	clb.Wait()
+
 	return &TurnResult{
 		FinalMessage: finalMsg,
 	}, nil
 }
 
+// newAttemptCallback creates the callback that replays an attempt's events
+// into sink and reports each LLM call's usage.
+func newAttemptCallback(sink *attemptSink, meter *usageMeter) *agentflow.ReplayChunkCallback {
+	return agentflow.NewReplayChunkCallback(sink).WithUsageHandler(func(usage *entity.TokenUsage) {
+		// Usage of failed attempts still counts: those tokens were consumed.
+		total := meter.add(usage)
+		sink.Send(&entity.AgentEvent{
+			Type:       entity.EventUsageDelta,
+			Usage:      usage,
+			TotalUsage: total,
+		}, nil)
+	})
+}
+
+// maxTurnsFinalPrompt asks the model for a final answer once the tool loop
+// has reached its cap.
+const maxTurnsFinalPrompt = "You have reached the maximum number of tool calls for this request. " +
+	"Do not call any more tools. Answer the user now with the information gathered so far, " +
+	"and say briefly what is still missing, if anything."
+
+// finishAtMaxTurns ends a turn whose tool loop reached req.MaxTurns. The
+// graph's raw step-limit error is replaced by a clear one; with
+// MaxTurnsForceFinalAnswer, one more tool-less LLM call answers from the
+// messages the model last saw.
+func (te *TurnExecutor) finishAtMaxTurns(
+	ctx context.Context,
+	req *TurnRequest,
+	cm einoModel.BaseChatModel,
+	sink *attemptSink,
+	meter *usageMeter,
+	transcript *agentflow.Transcript,
+) (*TurnResult, error) {
+	sink.dropErrors()
+	behavior := req.Agent.EffectiveMaxTurnsBehavior()
+	logger.CtxWarn(ctx, "[TurnExecutor] agent %s reached max turns (%d), behavior=%s", req.Agent.ID, req.MaxTurns, behavior)
+
+	if behavior != entity.MaxTurnsForceFinalAnswer {
+		notice := fmt.Sprintf("max turns (%d) reached before the agent produced a final answer", req.MaxTurns)
+		sink.Send(&entity.AgentEvent{
+			Type:  entity.EventError,
+			Error: notice,
+		}, nil)
+		return &TurnResult{
+			FinalMessage:    schema.AssistantMessage(hardStopContent(transcript.Messages(), notice), nil),
+			MaxTurnsReached: true,
+		}, nil
+	}
+
+	history := transcript.Messages()
+	if len(history) == 0 {
+		history = req.Messages
+	}
+	messages := make([]*schema.Message, 0, len(history)+1)
+	messages = append(messages, history...)
+	messages = append(messages, schema.UserMessage(maxTurnsFinalPrompt))
+
+	runnable, err := te.flowBuilder.Build(ctx, req.Agent, cm, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build final answer flow: %w", err)
+	}
+	clb := newAttemptCallback(sink, meter)
+	sr, err := runnable.Stream(ctx, messages, compose.WithCallbacks(clb.Build()))
+	if err != nil {
+		return nil, fmt.Errorf("final answer after max turns failed: %w", err)
+	}
+	finalMsg, err := collectStreamResult(sr)
+	if err != nil {
+		return nil, fmt.Errorf("final answer after max turns failed: %w", err)
+	}
+	clb.Wait()
+
+	return &TurnResult{
+		FinalMessage:    finalMsg,
+		MaxTurnsReached: true,
+	}, nil
+}
+
+// rebuildContext builds req's context again from the (compacted) session.
+func (te *TurnExecutor) rebuildContext(ctx context.Context, req *TurnRequest) BuildResult {
+	return te.contextBuilder.WithMaxHistoryTurns(ctx, req.MaxHistoryTurns).Build(
+		ctx, req.Agent, req.Session, req.Input, req.InjectedMessages, req.WindowInfo, req.PromptContext,
+	)
+}
+
+// hardStopContent is the final content of a turn stopped at its step cap:
+// the text the model wrote alongside its tool calls since the last user
+// message, followed by a notice that the answer is incomplete.
+func hardStopContent(transcript []*schema.Message, notice string) string {
+	start := 0
+	for i, msg := range transcript {
+		if msg.Role == schema.User {
+			start = i + 1
+		}
+	}
+	var parts []string
+	for _, msg := range transcript[start:] {
+		if msg.Role == schema.Assistant && strings.TrimSpace(msg.Content) != "" {
+			parts = append(parts, strings.TrimSpace(msg.Content))
+		}
+	}
+	parts = append(parts, "[Stopped: "+notice+".]")
+	return strings.Join(parts, "\n\n")
+}
+
+// usageMeter accumulates token usage across the LLM calls of a turn.
+type usageMeter struct {
+	mu  sync.Mutex
+	sum entity.TokenUsage
+}
+
+// add records one call's usage and returns a snapshot of the running total.
+func (m *usageMeter) add(u *entity.TokenUsage) *entity.TokenUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sum.PromptTokens += u.PromptTokens
+	m.sum.CompletionTokens += u.CompletionTokens
+	m.sum.TotalTokens += u.TotalTokens
+	total := m.sum
+	return &total
+}
+
+// total returns the accumulated usage, or nil when no call reported usage.
+func (m *usageMeter) total() *entity.TokenUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sum == (entity.TokenUsage{}) {
+		return nil
+	}
+	total := m.sum
+	return &total
+}
+
+// attemptSink forwards the events of a single model attempt to the run's
+// event writer. It records whether any output reached the client and holds
+// back error events until the attempt's failure has been classified, so that
+// a mid-stream failover shows a switch notice rather than an error.
+type attemptSink struct {
+	sw *schema.StreamWriter[*entity.AgentEvent]
+
+	mu       sync.Mutex
+	output   bool
+	detached bool
+	errs     []*entity.AgentEvent
+}
+
+func newAttemptSink(sw *schema.StreamWriter[*entity.AgentEvent]) *attemptSink {
+	return &attemptSink{sw: sw}
+}
+
+// Send implements agentflow.EventSink.
+func (s *attemptSink) Send(event *entity.AgentEvent, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.detached {
+		return true
+	}
+	if event != nil {
+		switch event.Type {
+		case entity.EventError:
+			s.errs = append(s.errs, event)
+			return false
+		case entity.EventTextDelta, entity.EventToolCallStart, entity.EventToolCallEnd:
+			s.output = true
+		}
+	}
+	return s.sw.Send(event, err)
+}
+
+// streamed reports whether the attempt sent any text or tool events.
+func (s *attemptSink) streamed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.output
+}
+
+// detach stops forwarding; later events from the attempt are dropped.
+func (s *attemptSink) detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detached = true
+}
+
+// dropErrors discards the error events held back so far.
+func (s *attemptSink) dropErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = nil
+}
+
+// flushErrors forwards the error events held back during the attempt.
+func (s *attemptSink) flushErrors() {
+	s.mu.Lock()
+	errs := s.errs
+	s.errs = nil
+	s.mu.Unlock()
+
+	for _, e := range errs {
+		s.sw.Send(e, nil)
+	}
+}
+
 // collectStreamResult reads from the stream and concatenates all message chunks.
 func collectStreamResult(sr *schema.StreamReader[*schema.Message]) (*schema.Message, error) {
 	if sr == nil {
@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+)
+
+func TestHardStopContent(t *testing.T) {
+	const notice = "max turns (2) reached before the agent produced a final answer"
+	stop := "[Stopped: " + notice + ".]"
+	call := []schema.ToolCall{{ID: "c1", Function: schema.FunctionCall{Name: "web_search"}}}
+	tests := []struct {
+		name       string
+		transcript []*schema.Message
+		want       string
+	}{
+		{"no transcript", nil, stop},
+		{"tool calls without text", []*schema.Message{
+			schema.UserMessage("find it"),
+			schema.AssistantMessage("", call),
+			schema.ToolMessage("result", "c1"),
+		}, stop},
+		{"partial text kept", []*schema.Message{
+			schema.SystemMessage("be brief"),
+			schema.UserMessage("find it"),
+			schema.AssistantMessage("Searching the docs first.", call),
+			schema.ToolMessage("result", "c1"),
+			schema.AssistantMessage("  Found part of it: v2 is current. ", call),
+			schema.ToolMessage("result", "c1"),
+		}, "Searching the docs first.\n\nFound part of it: v2 is current.\n\n" + stop},
+		{"earlier turns ignored", []*schema.Message{
+			schema.UserMessage("hi"),
+			schema.AssistantMessage("Hello!", nil),
+			schema.UserMessage("find it"),
+			schema.AssistantMessage("Looking.", call),
+		}, "Looking.\n\n" + stop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hardStopContent(tt.transcript, notice); got != tt.want {
+				t.Fatalf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildContextKeepsInputImages(t *testing.T) {
+	estimator := NewTokenEstimator(DefaultCharsPerTokenRatio)
+	te := &TurnExecutor{contextBuilder: NewContextBuilder(estimator, NewContextPruner(estimator, DefaultPrunerConfig()), 0)}
+
+	session := &entity.Session{ID: "s1", AgentID: "a"}
+	session.AppendMessage(entity.NewUserMessage("earlier question"))
+	session.AppendMessage(entity.NewAssistantMessage("earlier answer"))
+	session.ApplyCompaction("they talked before", len(session.Messages))
+
+	input := entity.NewUserMessage("what is in this picture?")
+	input.Images = []*entity.ImageContent{{URL: "https://example.com/cat.png", Detail: "low"}}
+	req := &TurnRequest{
+		Agent:      &entity.Agent{ID: "a", SystemPrompt: "You are helpful."},
+		Session:    session,
+		WindowInfo: ContextWindowInfo{WindowSize: 100000, UsableTokens: 100000, Tokenizer: estimator},
+		Input:      input,
+	}
+
+	msgs := te.rebuildContext(context.Background(), req).Messages
+	if len(msgs) == 0 {
+		t.Fatal("rebuilt context is empty")
+	}
+	last := msgs[len(msgs)-1]
+	if last.Role != schema.User {
+		t.Fatalf("last message role = %s, want the user input", last.Role)
+	}
+	var image *schema.MessageInputImage
+	for _, part := range last.UserInputMultiContent {
+		if part.Type == schema.ChatMessagePartTypeImageURL {
+			image = part.Image
+		}
+	}
+	if image == nil || image.URL == nil || *image.URL != "https://example.com/cat.png" {
+		t.Fatalf("rebuilt input lost its image: %+v", last)
+	}
+}
+
+func TestUsageMeter(t *testing.T) {
+	m := &usageMeter{}
+	if total := m.total(); total != nil {
+		t.Fatalf("total before any call = %+v, want nil", total)
+	}
+
+	first := m.add(&entity.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12})
+	second := m.add(&entity.TokenUsage{PromptTokens: 15, CompletionTokens: 5, TotalTokens: 20})
+	if want := (entity.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}); *first != want {
+		t.Fatalf("first running total = %+v, want %+v", *first, want)
+	}
+	want := entity.TokenUsage{PromptTokens: 25, CompletionTokens: 7, TotalTokens: 32}
+	if *second != want {
+		t.Fatalf("second running total = %+v, want %+v", *second, want)
+	}
+	if total := m.total(); total == nil || *total != want {
+		t.Fatalf("total = %+v, want %+v", total, want)
+	}
+}
+
+// overflowModel rejects its first streamed call as too long, then answers.
+// Generate (used by compaction) returns a summary.
+type overflowModel struct {
+	mu       sync.Mutex
+	rejected bool
+}
+
+func (m *overflowModel) Generate(context.Context, []*schema.Message, ...einoModel.Option) (*schema.Message, error) {
+	return schema.AssistantMessage("summary of earlier turns", nil), nil
+}
+
+func (m *overflowModel) Stream(_ context.Context, _ []*schema.Message, _ ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.rejected {
+		m.rejected = true
+		return nil, errors.New("context_length_exceeded: prompt is too long")
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage("answer", nil)}), nil
+}
+
+func (m *overflowModel) WithTools([]*schema.ToolInfo) (einoModel.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// overflowModels is a ModelManager building every model as overflow.
+type overflowModels struct {
+	gatedModels
+	overflow *overflowModel
+}
+
+func (f overflowModels) BuildChatModel(context.Context, llmEntity.ModelRef, *llmEntity.LLMParams) (einoModel.BaseChatModel, error) {
+	return f.overflow, nil
+}
+
+func TestOverflowCompactionEvents(t *testing.T) {
+	ctx := context.Background()
+	agents, sessions := inmemory.NewAgentStore(), inmemory.NewSessionStore()
+	if err := agents.Create(ctx, &entity.Agent{ID: "a", ModelRef: llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Create(ctx, longSession()); err != nil {
+		t.Fatal(err)
+	}
+	models := overflowModels{
+		gatedModels: gatedModels{capabilityModels: capabilityModels{models: map[string]llmEntity.ModelAbility{"p/m": {}}}},
+		overflow:    &overflowModel{},
+	}
+	r := NewAgentRunner(agents, sessions, inmemory.NewRunStore(),
+		&llm.Module{Manager: models, Fallback: llmService.NewFallbackExecutor(nil, models)},
+		(&plugin.Config{}).Complete().New(), nil, AgentRunnerConfig{})
+	t.Cleanup(r.Close)
+
+	sr, err := r.Run(ctx, &RunRequest{AgentID: "a", SessionID: "s1", Input: "next"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sr.Close()
+	var compactions []*entity.CompactionInfo
+	for {
+		e, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if e.Type == entity.EventCompaction {
+			compactions = append(compactions, e.Compaction)
+		}
+	}
+
+	if len(compactions) != 2 {
+		t.Fatalf("%d compaction events, want started and completed", len(compactions))
+	}
+	started, completed := compactions[0], compactions[1]
+	if started.Reason != entity.CompactionReasonOverflow || started.Status != entity.CompactionStarted ||
+		started.TokensBefore == 0 || started.TokensAfter != 0 {
+		t.Fatalf("started = %+v", started)
+	}
+	if completed.Reason != entity.CompactionReasonOverflow || completed.Status != entity.CompactionCompleted ||
+		completed.TokensBefore != started.TokensBefore || completed.TokensAfter >= completed.TokensBefore {
+		t.Fatalf("completed = %+v, want fewer tokens than %d", completed, started.TokensBefore)
+	}
+}
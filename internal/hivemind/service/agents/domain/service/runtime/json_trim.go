@@ -0,0 +1,187 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// trimJSON shrinks a JSON document to roughly maxBytes while keeping it valid
+// JSON, so a model can still parse a soft-trimmed tool result.
+//
+// Whitespace is compacted first; if that is not enough, arrays keep their
+// head and tail elements around a marker string, objects keep their leading
+// fields plus a "_truncated" field, and long strings are cut with a marker
+// suffix. Returns false when content is not JSON.
+func trimJSON(content string, maxBytes int) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid([]byte(trimmed)) {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(trimmed)); err != nil {
+		return "", false
+	}
+	return string(shrinkJSON(buf.Bytes(), maxBytes)), true
+}
+
+// shrinkJSON returns raw (compact, valid JSON) reduced to about budget bytes.
+func shrinkJSON(raw json.RawMessage, budget int) json.RawMessage {
+	if len(raw) <= budget {
+		return raw
+	}
+	switch raw[0] {
+	case '[':
+		return shrinkJSONArray(raw, budget)
+	case '{':
+		return shrinkJSONObject(raw, budget)
+	case '"':
+		return shrinkJSONString(raw, budget)
+	default:
+		return raw // numbers, booleans and null cannot shrink
+	}
+}
+
+// shrinkJSONArray keeps elements from the head and the tail of the array,
+// each within half of the budget, replacing the middle with a marker string.
+func shrinkJSONArray(raw json.RawMessage, budget int) json.RawMessage {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		return raw
+	}
+
+	half := budget / 2
+	var head, tail []json.RawMessage
+	used := 0
+	for _, e := range elems {
+		if used+len(e)+1 > half {
+			break
+		}
+		head = append(head, e)
+		used += len(e) + 1
+	}
+	if len(head) == 0 && len(elems) > 0 {
+		// A single oversized element: shrink it rather than drop everything.
+		head = append(head, shrinkJSON(elems[0], half))
+	}
+	used = 0
+	for i := len(elems) - 1; i >= len(head); i-- {
+		if used+len(elems[i])+1 > half {
+			break
+		}
+		tail = append([]json.RawMessage{elems[i]}, tail...)
+		used += len(elems[i]) + 1
+	}
+
+	out := head
+	if dropped := len(elems) - len(head) - len(tail); dropped > 0 {
+		marker, _ := json.Marshal(fmt.Sprintf("... [%d items truncated] ...", dropped))
+		out = append(out, marker)
+	}
+	out = append(out, tail...)
+
+	result, err := json.Marshal(out)
+	if err != nil {
+		return raw
+	}
+	return result
+}
+
+// jsonField is one member of a JSON object, in document order.
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// shrinkJSONObject keeps the leading fields that fit the budget and records
+// the number of dropped fields in a "_truncated" field.
+func shrinkJSONObject(raw json.RawMessage, budget int) json.RawMessage {
+	fields, err := decodeJSONObject(raw)
+	if err != nil {
+		return raw
+	}
+
+	var kept []jsonField
+	used := 2 // braces
+	for _, f := range fields {
+		size := len(f.key) + len(f.value) + 4 // quotes, colon, comma
+		if used+size > budget {
+			if len(kept) == 0 {
+				// A single oversized field: shrink its value rather than drop it.
+				kept = append(kept, jsonField{key: f.key, value: shrinkJSON(f.value, budget-len(f.key)-6)})
+			}
+			break
+		}
+		kept = append(kept, f)
+		used += size
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range kept {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	if dropped := len(fields) - len(kept); dropped > 0 {
+		if len(kept) > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"_truncated":"%d fields truncated"`, dropped)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// decodeJSONObject splits a JSON object into its fields, preserving order.
+func decodeJSONObject(raw json.RawMessage) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // opening brace
+		return nil, err
+	}
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object key %v", tok)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: key, value: value})
+	}
+	return fields, nil
+}
+
+// shrinkJSONString cuts a string value to about budget bytes, noting how many
+// characters were removed.
+func shrinkJSONString(raw json.RawMessage, budget int) json.RawMessage {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return raw
+	}
+	runes := []rune(s)
+	keep := budget - 40 // room for quotes, escapes and the marker
+	if keep < 0 {
+		keep = 0
+	}
+	if keep >= len(runes) {
+		return raw
+	}
+	result, err := json.Marshal(fmt.Sprintf("%s... [%d characters truncated]", string(runes[:keep]), len(runes)-keep))
+	if err != nil {
+		return raw
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrimJSONNotJSON(t *testing.T) {
+	for _, content := range []string{"", "plain text", `"a string"`, "42", `{"a":`, "[1,2"} {
+		if _, ok := trimJSON(content, 10); ok {
+			t.Errorf("trimJSON(%q) treated as JSON", content)
+		}
+	}
+}
+
+func TestTrimJSON(t *testing.T) {
+	items := make([]string, 50)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id":%d,"name":"item-%d"}`, i, i)
+	}
+	array := "[\n  " + strings.Join(items, ",\n  ") + "\n]"
+
+	fields := make([]string, 30)
+	for i := range fields {
+		fields[i] = fmt.Sprintf(`"field_%02d": "value number %d"`, i, i)
+	}
+	object := "{" + strings.Join(fields, ", ") + "}"
+
+	tests := []struct {
+		name     string
+		content  string
+		maxBytes int
+		want     []string // substrings of the result
+		wantNot  []string
+	}{
+		{"fits after compacting", "{\n  \"a\": 1,\n  \"b\": [1, 2]\n}", 100,
+			[]string{`{"a":1,"b":[1,2]}`}, nil},
+		{"array keeps head and tail", array, 300,
+			[]string{`{"id":0,`, `{"id":49,`, `items truncated] ...`}, []string{`{"id":25,`}},
+		{"object keeps leading fields", object, 200,
+			[]string{`"field_00":"value number 0"`, `"_truncated":"`, ` fields truncated"`}, []string{`"field_29"`}},
+		{"oversized string field", `{"log":"` + strings.Repeat("x", 5000) + `"}`, 200,
+			[]string{`{"log":"xxx`, `characters truncated]"}`}, nil},
+		{"oversized single element", `[` + `"` + strings.Repeat("y", 5000) + `"` + `]`, 200,
+			[]string{`["yyy`, `characters truncated]"]`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := trimJSON(tt.content, tt.maxBytes)
+			if !ok {
+				t.Fatal("content not treated as JSON")
+			}
+			if !json.Valid([]byte(got)) {
+				t.Fatalf("result is not valid JSON: %s", got)
+			}
+			if len(got) > tt.maxBytes+100 {
+				t.Fatalf("result is %d bytes, budget %d: %s", len(got), tt.maxBytes, got)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Fatalf("result lacks %q: %s", s, got)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(got, s) {
+					t.Fatalf("result keeps %q: %s", s, got)
+				}
+			}
+		})
+	}
+}
@@ -23,6 +23,13 @@ func ToSchemaMessage(msg *entity.Message) *schema.Message {
 		ToolCallID: msg.ToolCallID,
 	}
 
+	if msg.Role == entity.RoleUser && len(msg.Images) > 0 {
+		// Content and multi-content are mutually exclusive for providers,
+		// so the text moves into the first part.
+		sm.Content = ""
+		sm.UserInputMultiContent = toInputParts(msg.Content, msg.Images)
+	}
+
 	if len(msg.ToolCalls) > 0 {
 		sm.ToolCalls = make([]schema.ToolCall, 0, len(msg.ToolCalls))
 		for _, tc := range msg.ToolCalls {
@@ -38,6 +45,25 @@ func ToSchemaMessage(msg *entity.Message) *schema.Message {
 	return sm
 }
 
+// toInputParts builds multimodal input parts from a text and its images.
+func toInputParts(text string, images []*entity.ImageContent) []schema.MessageInputPart {
+	parts := make([]schema.MessageInputPart, 0, len(images)+1)
+	if text != "" {
+		parts = append(parts, schema.MessageInputPart{Type: schema.ChatMessagePartTypeText, Text: text})
+	}
+	for _, img := range images {
+		url := img.URL
+		parts = append(parts, schema.MessageInputPart{
+			Type: schema.ChatMessagePartTypeImageURL,
+			Image: &schema.MessageInputImage{
+				MessagePartCommon: schema.MessagePartCommon{URL: &url},
+				Detail:            schema.ImageURLDetail(img.Detail),
+			},
+		})
+	}
+	return parts
+}
+
 func FromSchemaMessage(sm *schema.Message) *entity.Message {
 	if sm == nil {
 		return nil
@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// --- RedactionMutator (Priority: 10000) ---
+//
+// Masks secrets (API keys, tokens, private keys) in the assembled prompt
+// before it is sent to the LLM provider. Workspace files and injected memory
+// may contain credentials pasted by the user; they never need to reach the model.
+// Runs after every other mutator so that text added by plugins is covered too.
+
+// RedactedPlaceholder replaces every redacted match.
+const RedactedPlaceholder = "[REDACTED]"
+
+// DefaultRedactionPatterns cover common API key and token formats.
+var DefaultRedactionPatterns = []string{
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`, // PEM private keys
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`,                                    // OpenAI / Anthropic
+	`\bAKIA[0-9A-Z]{16}\b`,                                               // AWS access key ID
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,                                     // GitHub tokens
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,                                   // GitHub fine-grained tokens
+	`\bxox[abprs]-[A-Za-z0-9\-]{10,}`,                                    // Slack tokens
+	`\bAIza[0-9A-Za-z_\-]{35}\b`,                                         // Google API keys
+	`\beyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`, // JWTs
+	`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{20,}=*`,                           // Bearer tokens
+}
+
+// RedactionMutator replaces matches of its patterns with RedactedPlaceholder.
+type RedactionMutator struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactionMutator compiles patterns into a RedactionMutator.
+// A nil patterns slice uses DefaultRedactionPatterns.
+func NewRedactionMutator(patterns []string) (*RedactionMutator, error) {
+	if patterns == nil {
+		patterns = DefaultRedactionPatterns
+	}
+	m := &RedactionMutator{patterns: make([]*regexp.Regexp, 0, len(patterns))}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+func (m *RedactionMutator) Name() string  { return "redaction" }
+func (m *RedactionMutator) Priority() int { return 10000 }
+
+func (m *RedactionMutator) Mutate(_ context.Context, _ *PromptContext, assembled string) (string, error) {
+	for _, re := range m.patterns {
+		assembled = re.ReplaceAllLiteralString(assembled, RedactedPlaceholder)
+	}
+	return assembled, nil
+}
@@ -48,23 +48,39 @@ func (p *Pipeline) GetWorkspaceLoader() *WorkspaceLoader {
 	return p.workspaceLoader
 }
 
-// ensureSorted sorts sections and mutators by priority.
+// ensureSorted sorts sections and mutators by priority, then name.
 // Called once lazily before first assembly.
 func (p *Pipeline) ensureSorted() {
 	if p.sorted {
 		return
 	}
-	sort.Slice(p.sections, func(i, j int) bool {
-		return p.sections[i].Priority() < p.sections[j].Priority()
-	})
-	sort.Slice(p.mutators, func(i, j int) bool {
-		return p.mutators[i].Priority() < p.mutators[j].Priority()
+	sortSections(p.sections)
+	sort.SliceStable(p.mutators, func(i, j int) bool {
+		a, b := p.mutators[i], p.mutators[j]
+		if a.Priority() != b.Priority() {
+			return a.Priority() < b.Priority()
+		}
+		return a.Name() < b.Name()
 	})
 	p.sorted = true
 }
 
+// sortSections orders sections by priority. Ties are broken by name so that
+// sections registered by different plugins with the same priority always
+// assemble in the same order.
+func sortSections(sections []PromptSection) {
+	sort.SliceStable(sections, func(i, j int) bool {
+		a, b := sections[i], sections[j]
+		if a.Priority() != b.Priority() {
+			return a.Priority() < b.Priority()
+		}
+		return a.Name() < b.Name()
+	})
+}
+
 // priorityThreshold defines the maximum section priority included for each PromptMode.
-// Sections with priority above the threshold are excluded.
+// Sections with priority above the threshold are excluded. It only applies to
+// sections that do not implement ModeScopedSection.
 //
 //   - PromptModeNone:    only priority <= 100 (identity line)
 //   - PromptModeMinimal: only priority <= 500 (core sections)
@@ -80,17 +96,49 @@ func priorityThreshold(mode PromptMode) int {
 	}
 }
 
+// modeRank orders prompt modes from leanest to richest.
+// Unknown modes (including "") rank as full.
+func modeRank(mode PromptMode) int {
+	switch mode {
+	case PromptModeNone:
+		return 0
+	case PromptModeMinimal:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// includedInMode reports whether section belongs in a prompt assembled for mode.
+func includedInMode(section PromptSection, mode PromptMode) bool {
+	if ms, ok := section.(ModeScopedSection); ok {
+		return modeRank(mode) >= modeRank(ms.MinPromptMode())
+	}
+	return section.Priority() <= priorityThreshold(mode)
+}
+
 // Assemble executes the full prompt assembly pipeline and returns the system prompt text.
 //
 // Flow:
 //  1. Merge static sections + dynamic workspace sections
-//  2. Sort all sections/mutators by priority (lazy, once for static; always for merged)
+//  2. Sort all sections/mutators by priority, then name (lazy, once for static; always for merged)
 //  3. For each section: check Enabled + PromptMode threshold → Render
 //  4. Apply mutators in order
 //  5. Return final assembled text
 //
 // Individual section failures are logged and skipped (K8s failurePolicy: Ignore).
 func (p *Pipeline) Assemble(ctx context.Context, pc *PromptContext) (string, error) {
+	report, err := p.Preview(ctx, pc)
+	if err != nil {
+		return "", err
+	}
+	return report.Text, nil
+}
+
+// Preview assembles the prompt like Assemble and additionally reports, for
+// every candidate section, whether it was included and why not. It is meant
+// for debugging; the agent runtime uses Assemble.
+func (p *Pipeline) Preview(ctx context.Context, pc *PromptContext) (*AssembleReport, error) {
 	p.ensureSorted()
 
 	// Merge workspace sections dynamically (they may change at runtime via fsnotify).
@@ -102,23 +150,28 @@ func (p *Pipeline) Assemble(ctx context.Context, pc *PromptContext) (string, err
 			allSections = append(allSections, p.sections...)
 			allSections = append(allSections, wsSections...)
 			// Re-sort the merged list.
-			sort.Slice(allSections, func(i, j int) bool {
-				return allSections[i].Priority() < allSections[j].Priority()
-			})
+			sortSections(allSections)
 		}
 	}
 
-	threshold := priorityThreshold(pc.Mode)
+	report := &AssembleReport{Sections: make([]SectionReport, 0, len(allSections))}
 	var buf strings.Builder
 
 	for _, section := range allSections {
-		// PromptMode filter: skip sections above the threshold.
-		if section.Priority() > threshold {
+		sr := SectionReport{Name: section.Name(), Priority: section.Priority()}
+
+		// PromptMode filter: skip sections not meant for this mode.
+		if !includedInMode(section, pc.Mode) {
+			sr.SkipReason = SkipReasonPromptMode
+			report.Sections = append(report.Sections, sr)
 			continue
 		}
 
 		// Dynamic enable check.
-		if !section.Enabled(ctx, pc) {
+		sr.Enabled = section.Enabled(ctx, pc)
+		if !sr.Enabled {
+			sr.SkipReason = SkipReasonDisabled
+			report.Sections = append(report.Sections, sr)
 			continue
 		}
 
@@ -126,12 +179,21 @@ func (p *Pipeline) Assemble(ctx context.Context, pc *PromptContext) (string, err
 		if err != nil {
 			// K8s failurePolicy: Ignore — log and continue.
 			logger.Warn("[PromptPipeline] section %q render failed: %v", section.Name(), err)
+			sr.SkipReason = SkipReasonRenderError
+			sr.Error = err.Error()
+			report.Sections = append(report.Sections, sr)
 			continue
 		}
 		if text == "" {
+			sr.SkipReason = SkipReasonEmpty
+			report.Sections = append(report.Sections, sr)
 			continue
 		}
 
+		sr.Included = true
+		sr.Length = len(text)
+		report.Sections = append(report.Sections, sr)
+
 		buf.WriteString(text)
 		buf.WriteString("\n\n")
 	}
@@ -148,7 +210,8 @@ func (p *Pipeline) Assemble(ctx context.Context, pc *PromptContext) (string, err
 		result = mutated
 	}
 
-	return result, nil
+	report.Text = result
+	return report, nil
 }
 
 // SectionCount returns the number of registered sections.
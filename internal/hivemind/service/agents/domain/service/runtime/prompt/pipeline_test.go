@@ -0,0 +1,201 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// textSection is a PromptSection rendering its own name.
+type textSection struct {
+	name     string
+	priority int
+}
+
+func (s *textSection) Name() string                                     { return s.name }
+func (s *textSection) Priority() int                                    { return s.priority }
+func (s *textSection) Enabled(_ context.Context, _ *PromptContext) bool { return true }
+func (s *textSection) Render(_ context.Context, _ *PromptContext) (string, error) {
+	return s.name, nil
+}
+
+// scopedSection is a textSection declaring its minimum PromptMode.
+type scopedSection struct {
+	textSection
+	minMode PromptMode
+}
+
+func (s *scopedSection) MinPromptMode() PromptMode { return s.minMode }
+
+func TestIncludedInMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		section PromptSection
+		mode    PromptMode
+		want    bool
+	}{
+		{"priority within none", &textSection{"a", 100}, PromptModeNone, true},
+		{"priority above none", &textSection{"a", 150}, PromptModeNone, false},
+		{"priority within minimal", &textSection{"a", 500}, PromptModeMinimal, true},
+		{"priority above minimal", &textSection{"a", 501}, PromptModeMinimal, false},
+		{"any priority in full", &textSection{"a", 5000}, PromptModeFull, true},
+		{"scoped minimal in none", &scopedSection{textSection{"a", 50}, PromptModeMinimal}, PromptModeNone, false},
+		{"scoped minimal in minimal", &scopedSection{textSection{"a", 900}, PromptModeMinimal}, PromptModeMinimal, true},
+		{"scoped full in minimal", &scopedSection{textSection{"a", 150}, PromptModeFull}, PromptModeMinimal, false},
+		{"scoped full in unset mode", &scopedSection{textSection{"a", 150}, PromptModeFull}, "", true},
+		{"scoped none in none", &scopedSection{textSection{"a", 900}, PromptModeNone}, PromptModeNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := includedInMode(tt.section, tt.mode); got != tt.want {
+				t.Fatalf("includedInMode(%s) = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewPromptModeSkips(t *testing.T) {
+	p := NewPipeline()
+	p.RegisterSection(&textSection{"identity", 100})
+	p.RegisterSection(&scopedSection{textSection{"workspace", 900}, PromptModeMinimal})
+	p.RegisterSection(&scopedSection{textSection{"cluster", 150}, PromptModeFull})
+
+	report, err := p.Preview(context.Background(), &PromptContext{Mode: PromptModeMinimal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]SectionSkipReason{"identity": "", "cluster": SkipReasonPromptMode, "workspace": ""}
+	for _, sr := range report.Sections {
+		if sr.SkipReason != want[sr.Name] || sr.Included != (want[sr.Name] == "") {
+			t.Fatalf("%s: included = %v, skip reason = %q", sr.Name, sr.Included, sr.SkipReason)
+		}
+	}
+	if len(report.Sections) != len(want) {
+		t.Fatalf("sections = %+v", report.Sections)
+	}
+}
+
+// stubSection is a PromptSection with a fixed enabled state and output.
+type stubSection struct {
+	textSection
+	enabled bool
+	text    string
+	err     error
+}
+
+func (s *stubSection) Enabled(_ context.Context, _ *PromptContext) bool { return s.enabled }
+func (s *stubSection) Render(_ context.Context, _ *PromptContext) (string, error) {
+	return s.text, s.err
+}
+
+func TestPreviewSectionReports(t *testing.T) {
+	p := NewPipeline()
+	p.RegisterSection(&stubSection{textSection{"identity", 100}, true, "You are A.", nil})
+	p.RegisterSection(&stubSection{textSection{"skills", 200}, false, "unused", nil})
+	p.RegisterSection(&stubSection{textSection{"tools", 300}, true, "", errors.New("boom")})
+	p.RegisterSection(&stubSection{textSection{"memory", 400}, true, "", nil})
+
+	pc := &PromptContext{Mode: PromptModeFull}
+	report, err := p.Preview(context.Background(), pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SectionReport{
+		{Name: "identity", Priority: 100, Enabled: true, Included: true, Length: len("You are A.")},
+		{Name: "skills", Priority: 200, SkipReason: SkipReasonDisabled},
+		{Name: "tools", Priority: 300, Enabled: true, SkipReason: SkipReasonRenderError, Error: "boom"},
+		{Name: "memory", Priority: 400, Enabled: true, SkipReason: SkipReasonEmpty},
+	}
+	if !slices.Equal(report.Sections, want) {
+		t.Fatalf("sections = %+v, want %+v", report.Sections, want)
+	}
+	text, err := p.Assemble(context.Background(), pc)
+	if err != nil || report.Text != text || !strings.Contains(text, "You are A.") {
+		t.Fatalf("preview text %q, assembled %q (%v); want the same prompt", report.Text, text, err)
+	}
+}
+
+func TestDefaultSectionModes(t *testing.T) {
+	tests := []struct {
+		section PromptSection
+		want    PromptMode
+	}{
+		{&IdentitySection{}, PromptModeNone},
+		{&PersonaSection{}, PromptModeMinimal},
+		{&RuntimeSection{}, PromptModeMinimal},
+		{&WorkspaceSection{name: "SOUL.md"}, PromptModeMinimal},
+		{&WorkspaceSection{name: "extra:style.md"}, PromptModeFull},
+		{&ToolingSection{}, PromptModeFull},
+		{&ClusterAwarenessSection{}, PromptModeFull},
+	}
+	for _, tt := range tests {
+		ms, ok := tt.section.(ModeScopedSection)
+		if !ok || ms.MinPromptMode() != tt.want {
+			t.Errorf("%s: not scoped to %s", tt.section.Name(), tt.want)
+		}
+	}
+}
+
+// appendMutator appends "|<name>" to the assembled prompt.
+type appendMutator struct {
+	name     string
+	priority int
+}
+
+func (m *appendMutator) Name() string  { return m.name }
+func (m *appendMutator) Priority() int { return m.priority }
+func (m *appendMutator) Mutate(_ context.Context, _ *PromptContext, assembled string) (string, error) {
+	return assembled + "|" + m.name, nil
+}
+
+func TestSortSections(t *testing.T) {
+	sections := []PromptSection{
+		&textSection{"plugin-b", 1000},
+		&textSection{"runtime", 300},
+		&textSection{"plugin-a", 1000},
+		&textSection{"identity", 100},
+		&textSection{"plugin-c", 1000},
+	}
+	sortSections(sections)
+	var got []string
+	for _, s := range sections {
+		got = append(got, s.Name())
+	}
+	if want := []string{"identity", "runtime", "plugin-a", "plugin-b", "plugin-c"}; !slices.Equal(got, want) {
+		t.Fatalf("order = %q, want %q", got, want)
+	}
+}
+
+func TestAssembleTiesIndependentOfRegistration(t *testing.T) {
+	assemble := func(reverse bool) string {
+		sections := []PromptSection{&textSection{"beta", 1000}, &textSection{"alpha", 1000}, &textSection{"core", 100}}
+		mutators := []PromptMutator{&appendMutator{"y", 10}, &appendMutator{"x", 10}}
+		if reverse {
+			slices.Reverse(sections)
+			slices.Reverse(mutators)
+		}
+		p := NewPipeline()
+		for _, s := range sections {
+			p.RegisterSection(s)
+		}
+		for _, m := range mutators {
+			p.RegisterMutator(m)
+		}
+		out, err := p.Assemble(context.Background(), &PromptContext{Mode: PromptModeFull})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	forward, reversed := assemble(false), assemble(true)
+	if forward != reversed {
+		t.Fatalf("prompt depends on registration order:\n%q\n%q", forward, reversed)
+	}
+	core, alpha, beta := strings.Index(forward, "core"), strings.Index(forward, "alpha"), strings.Index(forward, "beta")
+	if !(core < alpha && alpha < beta) || !strings.HasSuffix(forward, "|x|y") {
+		t.Fatalf("prompt = %q, want core, alpha, beta, then mutators x and y", forward)
+	}
+}
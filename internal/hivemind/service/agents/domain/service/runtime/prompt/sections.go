@@ -2,9 +2,13 @@ package prompt
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/kiosk404/echoryn/pkg/version"
 )
@@ -23,6 +27,7 @@ type IdentitySection struct{}
 
 func (s *IdentitySection) Name() string                                     { return "identity" }
 func (s *IdentitySection) Priority() int                                    { return 100 }
+func (s *IdentitySection) MinPromptMode() PromptMode                        { return PromptModeNone }
 func (s *IdentitySection) Enabled(_ context.Context, _ *PromptContext) bool { return true }
 
 func (s *IdentitySection) Render(_ context.Context, pc *PromptContext) (string, error) {
@@ -66,8 +71,9 @@ func (s *IdentitySection) Render(_ context.Context, pc *PromptContext) (string,
 // ClusterAwarenessSection renders connected Golem node information.
 type ClusterAwarenessSection struct{}
 
-func (s *ClusterAwarenessSection) Name() string  { return "cluster_awareness" }
-func (s *ClusterAwarenessSection) Priority() int { return 150 }
+func (s *ClusterAwarenessSection) Name() string              { return "cluster_awareness" }
+func (s *ClusterAwarenessSection) Priority() int             { return 150 }
+func (s *ClusterAwarenessSection) MinPromptMode() PromptMode { return PromptModeFull }
 
 func (s *ClusterAwarenessSection) Enabled(_ context.Context, pc *PromptContext) bool {
 	return pc.ClusterInfo != nil && len(pc.ClusterInfo.Golems) > 0
@@ -87,6 +93,11 @@ func (s *ClusterAwarenessSection) Render(_ context.Context, pc *PromptContext) (
 		buf.WriteString(fmt.Sprintf("- **%s** [%s]: %s\n", g.Name, g.Status, skills))
 	}
 
+	if routes := renderGolemRoutes(pc.ClusterInfo.Golems); routes != "" {
+		buf.WriteString("\nRouting guide (Ready nodes only):\n")
+		buf.WriteString(routes)
+	}
+
 	buf.WriteString("\nWhen a task requires capabilities beyond direct LLM interaction ")
 	buf.WriteString("(e.g., browsing the web, editing code on a remote machine), ")
 	buf.WriteString("you can dispatch it to the appropriate Golem node. ")
@@ -95,19 +106,86 @@ func (s *ClusterAwarenessSection) Render(_ context.Context, pc *PromptContext) (
 	return buf.String(), nil
 }
 
+// golemTaskTypes describes the task types served by well-known Golem skills.
+// Skills not listed here are routed under their own name.
+var golemTaskTypes = map[string]string{
+	"browser":   "Web browsing and page interaction",
+	"code_edit": "Editing code on a remote machine",
+	"terminal":  "Running shell commands",
+}
+
+// golemRouteLabels are the labels shown when several nodes share a skill,
+// to help the model choose between them.
+var golemRouteLabels = []string{"region", "load"}
+
+// renderGolemRoutes renders one line per skill offered by a Ready Golem,
+// naming the nodes that can take the task. Nodes in any other status
+// (NotReady, unknown or empty) are left out.
+func renderGolemRoutes(golems []GolemInfo) string {
+	bySkill := make(map[string][]GolemInfo)
+	var skills []string
+	for _, g := range golems {
+		if g.Status != GolemStatusReady {
+			continue
+		}
+		for _, skill := range g.Skills {
+			if _, seen := bySkill[skill]; !seen {
+				skills = append(skills, skill)
+			}
+			bySkill[skill] = append(bySkill[skill], g)
+		}
+	}
+	sort.Strings(skills)
+
+	var buf strings.Builder
+	for _, skill := range skills {
+		nodes := bySkill[skill]
+		names := make([]string, 0, len(nodes))
+		for _, g := range nodes {
+			name := g.Name
+			if len(nodes) > 1 {
+				if labels := golemLabelSummary(g); labels != "" {
+					name += " (" + labels + ")"
+				}
+			}
+			names = append(names, name)
+		}
+		task := golemTaskTypes[skill]
+		if task == "" {
+			task = "Tasks needing " + skill
+		}
+		buf.WriteString(fmt.Sprintf("- %s (`%s`) → %s\n", task, skill, strings.Join(names, ", ")))
+	}
+	return buf.String()
+}
+
+// golemLabelSummary renders the placement labels of g, e.g. "region=eu, load=0.4".
+func golemLabelSummary(g GolemInfo) string {
+	var parts []string
+	for _, key := range golemRouteLabels {
+		if v, ok := g.Labels[key]; ok && v != "" {
+			parts = append(parts, key+"="+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // --- ToolingSection (Priority: 200) ---
 //
 // Enumerates available tools (Plugin + MCP) in the system prompt.
 // This is the Eidolon equivalent of OpenClaw's "## Tooling" section.
+// Dropped in minimal mode: the tools stay bound to the model, only their
+// listing in the prompt is left out.
 
 // ToolingSection renders the list of available tools.
 type ToolingSection struct{}
 
-func (s *ToolingSection) Name() string  { return "tooling" }
-func (s *ToolingSection) Priority() int { return 200 }
+func (s *ToolingSection) Name() string              { return "tooling" }
+func (s *ToolingSection) Priority() int             { return 200 }
+func (s *ToolingSection) MinPromptMode() PromptMode { return PromptModeFull }
 
 func (s *ToolingSection) Enabled(_ context.Context, pc *PromptContext) bool {
-	return len(pc.Tools) > 0
+	return len(pc.Tools) > 0 || len(pc.UnavailableMCPServers) > 0
 }
 
 func (s *ToolingSection) Render(_ context.Context, pc *PromptContext) (string, error) {
@@ -145,10 +223,29 @@ func (s *ToolingSection) Render(_ context.Context, pc *PromptContext) (string, e
 			if desc == "" {
 				desc = "(no description)"
 			}
+			// Namespaced tools: show the readable name and server, plus the name to call.
+			if t.DisplayName != "" && t.DisplayName != t.Name {
+				buf.WriteString(fmt.Sprintf("- `%s` (%s from server `%s`) — %s\n", t.Name, t.DisplayName, t.Server, desc))
+				continue
+			}
 			buf.WriteString(fmt.Sprintf("- `%s` — %s\n", t.Name, desc))
 		}
 	}
 
+	if len(pc.UnavailableMCPServers) > 0 {
+		if len(mcpTools) > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("**Unavailable MCP Servers** (their tools cannot be used right now):\n")
+		for _, srv := range pc.UnavailableMCPServers {
+			if srv.Reason != "" {
+				buf.WriteString(fmt.Sprintf("- `%s` — %s\n", srv.Name, srv.Reason))
+				continue
+			}
+			buf.WriteString(fmt.Sprintf("- `%s`\n", srv.Name))
+		}
+	}
+
 	return buf.String(), nil
 }
 
@@ -165,8 +262,9 @@ func (s *ToolingSection) Render(_ context.Context, pc *PromptContext) (string, e
 // PersonaSection renders the Agent's user-defined system prompt.
 type PersonaSection struct{}
 
-func (s *PersonaSection) Name() string  { return "persona" }
-func (s *PersonaSection) Priority() int { return 300 }
+func (s *PersonaSection) Name() string              { return "persona" }
+func (s *PersonaSection) Priority() int             { return 300 }
+func (s *PersonaSection) MinPromptMode() PromptMode { return PromptModeMinimal }
 
 func (s *PersonaSection) Enabled(_ context.Context, pc *PromptContext) bool {
 	return pc.Agent != nil && pc.Agent.SystemPrompt != ""
@@ -176,17 +274,110 @@ func (s *PersonaSection) Render(_ context.Context, pc *PromptContext) (string, e
 	return pc.Agent.SystemPrompt, nil
 }
 
+// --- ExtraSection (Priority: 800) ---
+//
+// Renders PromptContext.Extra as a key/value list, e.g. request metadata
+// sent by the API caller. Sits just before the runtime line so that
+// per-request context stays close to the end of the prompt.
+
+// DefaultExtraHeading is the heading ExtraSection uses when none is set.
+const DefaultExtraHeading = "## Request Context"
+
+// DefaultExtraMaxValueLen is the number of runes ExtraSection keeps of a
+// value when MaxValueLen is not set.
+const DefaultExtraMaxValueLen = 256
+
+// ExtraSection renders the entries of PromptContext.Extra under Heading.
+//
+// Keys and values come from API callers, so they are flattened onto one
+// line and values are cut at MaxValueLen: an entry cannot open a new
+// heading or list item of its own.
+type ExtraSection struct {
+	// Heading is the Markdown heading above the entries.
+	// Empty uses DefaultExtraHeading.
+	Heading string
+
+	// MaxValueLen caps each rendered value in runes; longer values end
+	// with "…". 0 uses DefaultExtraMaxValueLen.
+	MaxValueLen int
+}
+
+func (s *ExtraSection) Name() string              { return "extra" }
+func (s *ExtraSection) Priority() int             { return 800 }
+func (s *ExtraSection) MinPromptMode() PromptMode { return PromptModeMinimal }
+
+func (s *ExtraSection) Enabled(_ context.Context, pc *PromptContext) bool {
+	return len(pc.Extra) > 0
+}
+
+func (s *ExtraSection) Render(_ context.Context, pc *PromptContext) (string, error) {
+	keys := make([]string, 0, len(pc.Extra))
+	for k := range pc.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	heading := s.Heading
+	if heading == "" {
+		heading = DefaultExtraHeading
+	}
+	maxLen := s.MaxValueLen
+	if maxLen <= 0 {
+		maxLen = DefaultExtraMaxValueLen
+	}
+
+	var buf strings.Builder
+	buf.WriteString(heading)
+	buf.WriteString("\n\n")
+	for _, k := range keys {
+		value := truncateRunes(flattenLine(formatExtraValue(pc.Extra[k])), maxLen)
+		buf.WriteString(fmt.Sprintf("- %s: %s\n", flattenLine(k), value))
+	}
+	return buf.String(), nil
+}
+
+// flattenLine joins the lines of s with single spaces.
+func flattenLine(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' }), " ")
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// formatExtraValue renders strings as-is and other values as JSON.
+func formatExtraValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
 // --- RuntimeSection (Priority: 900) ---
 //
-// A one-liner with runtime metadata (time, model, version).
+// A one-liner with runtime metadata (time, model, host, workspace, version).
 // This is the Eidolon equivalent of OpenClaw's "## Runtime" section.
 // Always the last major section — provides temporal and environmental awareness.
+// Kept in minimal mode: sub-agents need the current time as much as the main Agent.
+// The host environment (OS, workspace) is only disclosed in full mode.
 
 // RuntimeSection renders a one-line runtime information block.
 type RuntimeSection struct{}
 
-func (s *RuntimeSection) Name() string  { return "runtime" }
-func (s *RuntimeSection) Priority() int { return 900 }
+func (s *RuntimeSection) Name() string              { return "runtime" }
+func (s *RuntimeSection) Priority() int             { return 900 }
+func (s *RuntimeSection) MinPromptMode() PromptMode { return PromptModeMinimal }
 
 func (s *RuntimeSection) Enabled(_ context.Context, _ *PromptContext) bool { return true }
 
@@ -211,6 +402,19 @@ func (s *RuntimeSection) Render(_ context.Context, pc *PromptContext) (string, e
 		parts = append(parts, fmt.Sprintf("Model: %s", pc.ModelName))
 	}
 
+	// Host environment (OpenClaw's environment block), useful to coding agents.
+	full := modeRank(pc.Mode) == modeRank(PromptModeFull)
+	if full && pc.OS != "" {
+		host := pc.OS
+		if pc.Arch != "" {
+			host += "/" + pc.Arch
+		}
+		parts = append(parts, fmt.Sprintf("OS: %s", host))
+	}
+	if full && pc.WorkspaceDir != "" {
+		parts = append(parts, fmt.Sprintf("Workspace: %s", pc.WorkspaceDir))
+	}
+
 	// Version.
 	v := version.Get()
 	parts = append(parts, fmt.Sprintf("Eidolon: %s", v.GitVersion))
@@ -233,18 +437,53 @@ func (s *RuntimeSection) Render(_ context.Context, pc *PromptContext) (string, e
 //	330 — WorkspaceSection:agents_file   (AGENTS.md, via WorkspaceLoader, conditional)
 //	350+— WorkspaceSection:extra:*       (prompts/*.md, via WorkspaceLoader, conditional)
 //	400 — MemorySection           (memory recall instructions, via memorycore plugin)
+//	800 — ExtraSection            (PromptContext.Extra entries, conditional)
 //	900 — RuntimeSection          (runtime metadata, always on)
 //
 // WorkspaceSections (310-350+) are dynamically injected by WorkspaceLoader
 // at assemble time — they do not need to be registered here.
 //
+// Builtin mutators:
+//
+//	10000 — RedactionMutator (masks secrets, DefaultRedactionPatterns)
+//
+// PromptMode filtering (via ModeScopedSection):
+//
+//	none    — identity
+//	minimal — identity, persona, workspace persona files (SOUL.md, IDENTITY.md,
+//	          AGENTS.md), extra, runtime
+//	full    — all sections (adds cluster awareness, tooling, prompts/*.md, memory)
+//
 // Plugins can register additional sections via PromptProvider interface.
-func NewDefaultPipeline() *Pipeline {
+func NewDefaultPipeline(opts PipelineOptions) (*Pipeline, error) {
+	redaction, err := NewRedactionMutator(append(slices.Clone(DefaultRedactionPatterns), opts.RedactionPatterns...))
+	if err != nil {
+		return nil, err
+	}
+
 	p := NewPipeline()
 	p.RegisterSection(&IdentitySection{})
 	p.RegisterSection(&ClusterAwarenessSection{})
 	p.RegisterSection(&ToolingSection{})
 	p.RegisterSection(&PersonaSection{})
+	p.RegisterSection(&ExtraSection{Heading: opts.ExtraHeading, MaxValueLen: opts.ExtraMaxValueLen})
 	p.RegisterSection(&RuntimeSection{})
-	return p
+	p.RegisterMutator(redaction)
+	return p, nil
+}
+
+// PipelineOptions configures the built-in sections and mutators of
+// NewDefaultPipeline.
+type PipelineOptions struct {
+	// RedactionPatterns are regular expressions masked in the assembled
+	// prompt in addition to DefaultRedactionPatterns.
+	RedactionPatterns []string
+
+	// ExtraHeading is the heading of the request context section.
+	// Empty uses DefaultExtraHeading.
+	ExtraHeading string
+
+	// ExtraMaxValueLen caps each request context value in runes.
+	// 0 uses DefaultExtraMaxValueLen.
+	ExtraMaxValueLen int
 }
@@ -28,6 +28,9 @@ type AgentPromptInfo struct {
 
 	// Persona carries identity and prompt config.
 	Persona *AgentPersonaInfo
+
+	// MemoryDisabled is set when the agent opted out of the memory plugin.
+	MemoryDisabled bool
 }
 
 // AgentPersonaInfo mirrors entity.AgentPersona without importing entity.
@@ -50,7 +53,8 @@ type AgentIdentityInfo struct {
 // OpenClaw's "full" / "minimal" / "none" prompt modes.
 //
 //   - Full: all sections included (main Agent)
-//   - Minimal: only core sections (sub-Agent, lightweight tasks)
+//   - Minimal: identity, persona and runtime only (sub-Agent, lightweight tasks);
+//     the tool list, cluster topology and extra workspace prompts are dropped
 //   - None: identity line only (extreme minimalism)
 type PromptMode string
 
@@ -72,6 +76,8 @@ type PromptSection interface {
 	Name() string
 
 	// Priority determines assembly order (lower = earlier in prompt).
+	// Sections of equal priority are ordered by Name, so the prompt does
+	// not depend on registration order.
 	// Builtin sections use 100-999; plugin sections should use 1000+.
 	Priority() int
 
@@ -86,6 +92,16 @@ type PromptSection interface {
 	Render(ctx context.Context, pc *PromptContext) (string, error)
 }
 
+// ModeScopedSection is optionally implemented by a PromptSection to declare
+// the leanest PromptMode it appears in. A section returning PromptModeMinimal
+// is included in "minimal" and "full" but not in "none".
+//
+// Sections that do not implement it are filtered by priority threshold
+// (see priorityThreshold).
+type ModeScopedSection interface {
+	MinPromptMode() PromptMode
+}
+
 // PromptMutator allows plugins to transform the fully assembled prompt text.
 // This is analogous to K8s MutatingWebhook — applied after all sections
 // have been rendered, before token budget validation.
@@ -94,18 +110,30 @@ type PromptMutator interface {
 	Name() string
 
 	// Priority determines execution order among mutators (lower = first).
+	// Mutators of equal priority run in Name order.
 	Priority() int
 
 	// Mutate receives the assembled prompt and returns the transformed version.
 	Mutate(ctx context.Context, pc *PromptContext, assembled string) (string, error)
 }
 
+// UnavailableServer is an MCP server that is disconnected or failing.
+type UnavailableServer struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // ToolSummary is a lightweight description of an available tool,
 // used by ToolingSection to enumerate capabilities in the system prompt.
 type ToolSummary struct {
-	Name        string `json:"name"`
+	Name        string `json:"name"` // name the model calls the tool by
 	Description string `json:"description"`
 	Source      string `json:"source"` // "plugin" or "mcp"
+
+	// Server and DisplayName are set for MCP tools: the server the tool
+	// belongs to and its original, un-prefixed name.
+	Server      string `json:"server,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
 }
 
 // ClusterInfo carries Hivemind-Golem cluster topology information.
@@ -125,6 +153,9 @@ type ClusterInfo struct {
 	Golems []GolemInfo `json:"golems,omitempty"`
 }
 
+// GolemStatusReady is the GolemInfo.Status of a node that accepts tasks.
+const GolemStatusReady = "Ready"
+
 // GolemInfo describes a connected Golem worker node.
 type GolemInfo struct {
 	ID     string            `json:"id"`
@@ -161,7 +192,8 @@ type PromptContext struct {
 
 	// --- Runtime metadata ---
 
-	// Timezone is the server timezone name (e.g., "Asia/Shanghai").
+	// Timezone is the IANA zone name for the current time (e.g., "Asia/Shanghai").
+	// Empty means server local time.
 	Timezone string
 
 	// Now is the current server time (set once per prompt assembly).
@@ -170,14 +202,75 @@ type PromptContext struct {
 	// ModelName is the primary model being used for this run.
 	ModelName string
 
+	// OS and Arch describe the host the agent runs on (e.g., "linux", "amd64").
+	// Empty values are omitted from the runtime line.
+	OS   string
+	Arch string
+
+	// WorkspaceDir is the agent's configured persona workspace. Empty when
+	// none is configured; the server's working directory is never shown.
+	WorkspaceDir string
+
 	// --- Module contributions ---
 
 	// Tools lists all available tools (plugin + MCP) with short descriptions.
 	Tools []ToolSummary
 
+	// UnavailableMCPServers lists MCP servers the agent uses that are
+	// currently down, so the model knows their tools are missing.
+	UnavailableMCPServers []UnavailableServer
+
 	// --- Extensibility ---
 
 	// Extra holds additional key-value data that custom sections may need.
 	// Analogous to K8s annotations — unstructured extensibility.
 	Extra map[string]interface{}
 }
+
+// SectionSkipReason explains why a section did not contribute to the prompt.
+type SectionSkipReason string
+
+const (
+	// SkipReasonPromptMode means the section priority exceeds the PromptMode threshold.
+	SkipReasonPromptMode SectionSkipReason = "prompt_mode"
+	// SkipReasonDisabled means the section's Enabled check returned false.
+	SkipReasonDisabled SectionSkipReason = "disabled"
+	// SkipReasonRenderError means Render failed; the error is ignored at runtime.
+	SkipReasonRenderError SectionSkipReason = "render_error"
+	// SkipReasonEmpty means Render returned no text.
+	SkipReasonEmpty SectionSkipReason = "empty"
+)
+
+// SectionReport describes how a single section fared during assembly.
+type SectionReport struct {
+	// Name is the section name.
+	Name string
+
+	// Priority is the section's ordering priority.
+	Priority int
+
+	// Enabled is the result of the section's Enabled check
+	// (false when the section was filtered out by PromptMode before the check).
+	Enabled bool
+
+	// Included reports whether the section's text is part of the prompt.
+	Included bool
+
+	// SkipReason is set when Included is false.
+	SkipReason SectionSkipReason
+
+	// Length is the rendered text length in bytes (before mutators).
+	Length int
+
+	// Error is the render error, if any.
+	Error string
+}
+
+// AssembleReport is the result of Pipeline.Preview.
+type AssembleReport struct {
+	// Text is the fully assembled prompt, identical to Assemble's output.
+	Text string
+
+	// Sections lists every candidate section in assembly order.
+	Sections []SectionReport
+}
@@ -33,6 +33,13 @@ var conventionFiles = []conventionFile{
 // found under the `prompts/` subdirectory.
 const extraSectionBasePriority = 350
 
+// promptsSubdir is the workspace subdirectory holding extra prompt files.
+const promptsSubdir = "prompts"
+
+// workspacePollInterval is how often a loader checks whether its missing
+// workspace directory has been created.
+var workspacePollInterval = 5 * time.Second
+
 // WorkspaceLoader watches a workspace directory and provides PromptSections
 // from convention files (SOUL.md, IDENTITY.md, AGENTS.md) and extra prompts.
 type WorkspaceLoader struct {
@@ -46,7 +53,8 @@ type WorkspaceLoader struct {
 
 // NewWorkspaceLoader creates a WorkspaceLoader for the given directory.
 // It performs an initial scan and starts a background fsnotify watcher.
-// If dir is empty or does not exist, returns nil (no-op).
+// If dir does not exist yet, the loader has no sections until the directory
+// appears; it is then scanned and watched. If dir is empty, returns nil (no-op).
 func NewWorkspaceLoader(dir string) *WorkspaceLoader {
 	if dir == "" {
 		return nil
@@ -59,27 +67,49 @@ func NewWorkspaceLoader(dir string) *WorkspaceLoader {
 		return nil
 	}
 
-	info, err := os.Stat(absDir)
-	if err != nil || !info.IsDir() {
-		logger.Debug("[WorkspaceLoader] directory %q does not exist, skipping", absDir)
-		return nil
-	}
-
 	wl := &WorkspaceLoader{
 		dir:     absDir,
 		content: make(map[string]string),
 		closeCh: make(chan struct{}),
 	}
 
-	// Initial scan.
+	if !isDir(absDir) {
+		logger.Debug("[WorkspaceLoader] directory %q does not exist yet, waiting for it", absDir)
+		go wl.waitForDir(workspacePollInterval)
+		return wl
+	}
+
+	wl.start()
+	return wl
+}
+
+// start performs the initial scan and starts the watcher.
+func (wl *WorkspaceLoader) start() {
 	wl.reload()
 
-	// Start watcher.
 	if err := wl.startWatcher(); err != nil {
 		logger.Warn("[WorkspaceLoader] failed to start watcher: %v, content loaded statically", err)
 	}
+}
 
-	return wl
+// waitForDir polls every interval until the workspace directory exists, then
+// starts the loader. It gives up when the loader is closed.
+func (wl *WorkspaceLoader) waitForDir(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if isDir(wl.dir) {
+				logger.Debug("[WorkspaceLoader] directory %q appeared", wl.dir)
+				wl.start()
+				return
+			}
+		case <-wl.closeCh:
+			return
+		}
+	}
 }
 
 // Sections returns PromptSections for all loaded workspace files.
@@ -189,8 +219,8 @@ func (wl *WorkspaceLoader) reload() {
 	}
 
 	// Load extra prompt files from prompts/ subdirectory.
-	promptsDir := filepath.Join(wl.dir, "prompts")
-	if info, err := os.Stat(promptsDir); err == nil && info.IsDir() {
+	promptsDir := filepath.Join(wl.dir, promptsSubdir)
+	if isDir(promptsDir) {
 		entries, err := os.ReadDir(promptsDir)
 		if err == nil {
 			for _, entry := range entries {
@@ -226,7 +256,6 @@ func (wl *WorkspaceLoader) startWatcher() error {
 	if err != nil {
 		return fmt.Errorf("create watcher: %w", err)
 	}
-	wl.watcher = watcher
 
 	// Watch the root directory for convention files.
 	if err := watcher.Add(wl.dir); err != nil {
@@ -234,20 +263,30 @@ func (wl *WorkspaceLoader) startWatcher() error {
 		return fmt.Errorf("watch %q: %w", wl.dir, err)
 	}
 
-	// Watch prompts/ subdirectory if it exists.
-	promptsDir := filepath.Join(wl.dir, "prompts")
-	if info, err := os.Stat(promptsDir); err == nil && info.IsDir() {
+	// Watch prompts/ subdirectory if it exists. If it is created later,
+	// watchLoop adds it.
+	promptsDir := filepath.Join(wl.dir, promptsSubdir)
+	if isDir(promptsDir) {
 		_ = watcher.Add(promptsDir)
 	}
 
-	go wl.watchLoop()
+	wl.mu.Lock()
+	if wl.closed {
+		wl.mu.Unlock()
+		watcher.Close()
+		return nil
+	}
+	wl.watcher = watcher
+	wl.mu.Unlock()
+
+	go wl.watchLoop(watcher)
 
 	logger.Debug("[WorkspaceLoader] watcher started for %s", wl.dir)
 	return nil
 }
 
 // watchLoop runs the fsnotify event loop with debounce.
-func (wl *WorkspaceLoader) watchLoop() {
+func (wl *WorkspaceLoader) watchLoop(watcher *fsnotify.Watcher) {
 	// Debounce: wait 500ms after the last event before reloading.
 	const debounceMs = 500
 
@@ -257,19 +296,31 @@ func (wl *WorkspaceLoader) watchLoop() {
 	})
 	defer debounceTimer.stop()
 
+	promptsDir := filepath.Join(wl.dir, promptsSubdir)
 	for {
 		select {
-		case event, ok := <-wl.watcher.Events:
+		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
-			// Only react to write/create/remove of .md files.
-			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) != 0 {
+			// A prompts/ directory created after startup: watch it and
+			// reload, since it may already hold files (e.g. moved in).
+			if event.Name == promptsDir {
+				if event.Op&fsnotify.Create != 0 && isDir(promptsDir) {
+					if err := watcher.Add(promptsDir); err != nil {
+						logger.Warn("[WorkspaceLoader] failed to watch %s: %v", promptsDir, err)
+					}
+				}
+				debounceTimer.trigger()
+				continue
+			}
+			// Otherwise only react to changes of .md files.
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
 				if strings.HasSuffix(event.Name, ".md") {
 					debounceTimer.trigger()
 				}
 			}
-		case _, ok := <-wl.watcher.Errors:
+		case _, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
@@ -279,6 +330,12 @@ func (wl *WorkspaceLoader) watchLoop() {
 	}
 }
 
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 // --- WorkspaceSection ---
 
 // WorkspaceSection is a dynamic PromptSection backed by a WorkspaceLoader.
@@ -293,6 +350,15 @@ type WorkspaceSection struct {
 func (s *WorkspaceSection) Name() string  { return "workspace:" + s.name }
 func (s *WorkspaceSection) Priority() int { return s.priority }
 
+// MinPromptMode keeps workspace persona files (SOUL.md, IDENTITY.md, ...) in
+// minimal mode; extra prompts from prompts/ are only included in full mode.
+func (s *WorkspaceSection) MinPromptMode() PromptMode {
+	if strings.HasPrefix(s.name, "extra:") {
+		return PromptModeFull
+	}
+	return PromptModeMinimal
+}
+
 func (s *WorkspaceSection) Enabled(_ context.Context, _ *PromptContext) bool {
 	return s.loader.GetContent(s.name) != ""
 }
@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForContent waits for the loader to hold want for section.
+func waitForContent(t *testing.T, wl *WorkspaceLoader, section, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for wl.GetContent(section) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("section %q = %q, want %q", section, wl.GetContent(section), want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkspaceLoaderWaitsForMissingDir(t *testing.T) {
+	defer func(d time.Duration) { workspacePollInterval = d }(workspacePollInterval)
+	workspacePollInterval = 10 * time.Millisecond
+
+	dir := filepath.Join(t.TempDir(), "workspace")
+	wl := NewWorkspaceLoader(dir)
+	if wl == nil {
+		t.Fatal("no loader for a missing directory")
+	}
+	defer wl.Close()
+	if sections := wl.Sections(); sections != nil {
+		t.Fatalf("sections before the directory exists = %v", sections)
+	}
+
+	writeFile(t, filepath.Join(dir, "SOUL.md"), "Be kind.")
+	waitForContent(t, wl, "soul", "Be kind.")
+
+	// Once started, the loader watches the directory.
+	writeFile(t, filepath.Join(dir, "SOUL.md"), "Be brief.")
+	waitForContent(t, wl, "soul", "Be brief.")
+}
+
+func TestWorkspaceLoaderCloseStopsWaiting(t *testing.T) {
+	defer func(d time.Duration) { workspacePollInterval = d }(workspacePollInterval)
+	workspacePollInterval = 10 * time.Millisecond
+
+	dir := filepath.Join(t.TempDir(), "workspace")
+	wl := NewWorkspaceLoader(dir)
+	wl.Close()
+
+	writeFile(t, filepath.Join(dir, "SOUL.md"), "Be kind.")
+	time.Sleep(50 * time.Millisecond)
+	if got := wl.GetContent("soul"); got != "" {
+		t.Fatalf("closed loader loaded %q", got)
+	}
+}
+
+func TestWorkspaceLoaderWatchesNewPromptsDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		create func(t *testing.T, dir string)
+	}{
+		{"created then written", func(t *testing.T, dir string) {
+			if err := os.Mkdir(filepath.Join(dir, promptsSubdir), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			// Give the watcher time to add the new directory before the file
+			// lands in it.
+			time.Sleep(100 * time.Millisecond)
+			writeFile(t, filepath.Join(dir, promptsSubdir, "style.md"), "Use bullet points.")
+		}},
+		{"moved in with files", func(t *testing.T, dir string) {
+			staged := filepath.Join(t.TempDir(), "staged")
+			writeFile(t, filepath.Join(staged, "style.md"), "Use bullet points.")
+			if err := os.Rename(staged, filepath.Join(dir, promptsSubdir)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wl := NewWorkspaceLoader(dir)
+			defer wl.Close()
+
+			tt.create(t, dir)
+			waitForContent(t, wl, "extra:style", "Use bullet points.")
+
+			writeFile(t, filepath.Join(dir, promptsSubdir, "style.md"), "Use tables.")
+			waitForContent(t, wl, "extra:style", "Use tables.")
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"time"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
@@ -27,41 +28,41 @@ func NewRunStateMachine(run *entity.Run, runRepo repo.RunRepository) *RunStateMa
 }
 
 // TransitionToInProgress transitions the run to the InProgress state.
-func (sm *RunStateMachine) TransitionToInProgress() error {
+func (sm *RunStateMachine) TransitionToInProgress(ctx context.Context) error {
 	if sm.run.Status != entity.RunStatusCreated {
 		return errno.ErrRunAlreadyDone
 	}
 	sm.run.Status = entity.RunStatusInProgress
-	logger.InfoX(pkg.ModuleName, "[RunState] run %s -> in_progress", "runID", sm.run.ID)
+	logger.CtxInfoX(ctx, pkg.ModuleName, "[RunState] run %s -> in_progress", sm.run.ID)
 	return nil
 }
 
 // TransitionToCompleted transitions the run to the Completed state.
-func (sm *RunStateMachine) TransitionToCompleted(output string, usage *entity.TokenUsage) error {
+func (sm *RunStateMachine) TransitionToCompleted(ctx context.Context, output string, usage *entity.TokenUsage) error {
 	now := time.Now()
 	sm.run.CompletedAt = &now
 	sm.run.Status = entity.RunStatusCompleted
 	sm.run.Output = output
 	sm.run.Usage = usage
-	logger.InfoX(pkg.ModuleName, "[RunState] run %s -> completed", "runID", sm.run.ID)
+	logger.CtxInfoX(ctx, pkg.ModuleName, "[RunState] run %s -> completed", sm.run.ID)
 	return nil
 }
 
 // TransitionToFailed transitions the run to the Failed state.
-func (sm *RunStateMachine) TransitionToFailed(code, message string) {
+func (sm *RunStateMachine) TransitionToFailed(ctx context.Context, code, message string) {
 	now := time.Now()
 	sm.run.CompletedAt = &now
 	sm.run.Status = entity.RunStatusFailed
 	sm.run.Error = &entity.RunError{Code: code, Message: message}
-	logger.ErrorX(pkg.ModuleName, "[RunState] run %s -> failed, err: %v", "runID", sm.run.ID, sm.run.Error)
+	logger.CtxErrorX(ctx, pkg.ModuleName, "[RunState] run %s -> failed, err: %v", sm.run.ID, sm.run.Error)
 }
 
 // TransitionToCancelled transitions the run to the Cancelled state.
-func (sm *RunStateMachine) TransitionToCancelled() {
+func (sm *RunStateMachine) TransitionToCancelled(ctx context.Context) {
 	now := time.Now()
 	sm.run.CompletedAt = &now
 	sm.run.Status = entity.RunStatusCancelled
-	logger.InfoX(pkg.ModuleName, "[RunState] run %s -> cancelled", "runID", sm.run.ID)
+	logger.CtxInfoX(ctx, pkg.ModuleName, "[RunState] run %s -> cancelled", sm.run.ID)
 }
 
 // Run returns the current run.
@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	goruntime "runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudwego/eino/components/tool"
@@ -13,9 +17,11 @@ import (
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/repo"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/agentflow"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/tokenizer"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
 	"github.com/kiosk404/echoryn/pkg/logger"
@@ -32,6 +38,35 @@ type RunRequest struct {
 
 	// Input is the user message text.
 	Input string
+
+	// Images are image inputs sent with Input. They reach the model for this
+	// run only; session history keeps the text.
+	Images []*entity.ImageContent
+
+	// LLMOverrides holds per-request LLM params (e.g. response_format) applied
+	// on top of the agent's own params for this run only. May be nil.
+	LLMOverrides *llmEntity.LLMParams
+
+	// PromptMode, when set, overrides the agent's prompt mode ("full",
+	// "minimal", "none") for this run only, e.g. for delegated sub-agent runs.
+	PromptMode string
+
+	// PromptExtra is merged into PromptContext.Extra for this run only,
+	// e.g. request metadata rendered by prompt.ExtraSection. May be nil.
+	PromptExtra map[string]interface{}
+
+	// MaxHistoryTurns, when positive, overrides the module's MaxHistoryTurns
+	// for this run only (clamped as by ContextBuilder.WithMaxHistoryTurns).
+	MaxHistoryTurns int
+
+	// Choices is the number of independent completions to generate from the
+	// same context (OpenAI "n"). Values below 2 run a single completion.
+	// The first successful choice is appended to the session history.
+	Choices int
+
+	// OnFinish, when set, is called once after the run's goroutine exits.
+	// It is not called when Run itself returns an error.
+	OnFinish func()
 }
 
 // AgentRunner is the top-level orchestrator for agent execution.
@@ -50,7 +85,7 @@ type RunRequest struct {
 //  7. Apply context pruning (soft-trim / hard-clear)
 //  8. Create schema.Pipe[AgentEvent] for streaming
 //  9. Launch async goroutine → TurnExecutor.Execute → Eino graph.Stream
-//  10. Post-turn: proactive compaction check
+//  10. Post-turn: queue proactive compaction (background compactionQueue)
 //  11. Return StreamReader immediately for client consumption
 type AgentRunner struct {
 	agentRepo       repo.AgentRepository
@@ -63,8 +98,14 @@ type AgentRunner struct {
 	contextBuilder  *ContextBuilder
 	windowGuard     *ContextWindowGuard
 	compactor       *Compactor
+	compactions     *compactionQueue
 	defaultMaxTurns int
 	runTimeout      time.Duration
+	timezone        string
+	aborts          *abortRegistry
+	sessions        *sessionLocks
+	rejectBusy      bool     // fail runs on a busy session instead of queueing them
+	warnedTools     sync.Map // "<agentID>/<tool>" → struct{}, see resolveTools
 }
 
 // AgentRunnerConfig holds configuration for the AgentRunner.
@@ -75,6 +116,44 @@ type AgentRunnerConfig struct {
 	MaxHistoryTurns     int
 	CompactionThreshold float64
 	KeepRecentTurns     int
+	CompactionWorkers   int               // background proactive compaction workers (default 2)
+	Timezone            string            // default IANA zone for prompts; "" = server local
+	TokenizerFile       string            // tiktoken ranks file for OpenAI models; "" = char estimate
+	TokenizerEncoding   string            // encoding of TokenizerFile (default cl100k_base)
+	TokenizerFiles      map[string]string // encoding → tiktoken ranks file, selected per model
+
+	// RejectConcurrentSessionRuns fails a run with errno.ErrSessionBusy while
+	// another run holds its session, instead of queueing it behind that run.
+	RejectConcurrentSessionRuns bool
+}
+
+// configureTokenizers loads the configured tiktoken files into the window guard.
+// TokenizerFile is the default for every OpenAI model; TokenizerFiles add exact
+// tokenizers selected by the model's encoding. Files that fail to load are
+// skipped, leaving those models on the char-based estimate.
+func configureTokenizers(guard *ContextWindowGuard, cfg AgentRunnerConfig) {
+	if cfg.TokenizerFile != "" {
+		if cfg.TokenizerEncoding == "" {
+			cfg.TokenizerEncoding = tokenizer.EncodingCL100K
+		}
+		tk, err := tokenizer.LoadTiktoken(cfg.TokenizerFile, cfg.TokenizerEncoding)
+		if err != nil {
+			logger.Warn("[AgentRunner] failed to load tokenizer %s, using char-based estimate: %v", cfg.TokenizerFile, err)
+		} else {
+			guard.SetTokenizer(llmEntity.ModelClass_GPT, tk)
+			guard.SetEncodingTokenizer(tk.Encoding(), tk)
+			logger.Info("[AgentRunner] using %s tokenizer for OpenAI models", tk.Encoding())
+		}
+	}
+	for encoding, path := range cfg.TokenizerFiles {
+		tk, err := tokenizer.LoadTiktoken(path, encoding)
+		if err != nil {
+			logger.Warn("[AgentRunner] failed to load %s tokenizer %s, using char-based estimate: %v", encoding, path, err)
+			continue
+		}
+		guard.SetEncodingTokenizer(encoding, tk)
+		logger.Info("[AgentRunner] using %s tokenizer for models with that encoding", encoding)
+	}
 }
 
 // NewAgentRunner creates a new AgentRunner with all dependencies.
@@ -94,7 +173,7 @@ func NewAgentRunner(
 		cfg.RunTimeout = 5 * time.Minute
 	}
 
-	estimator := NewTokenEstimator(DefaultCharsPerToken)
+	estimator := NewTokenEstimator(DefaultCharsPerTokenRatio)
 	pruner := NewContextPruner(estimator, DefaultPrunerConfig())
 	contextBuilder := NewContextBuilder(estimator, pruner, cfg.MaxHistoryTurns)
 
@@ -104,13 +183,18 @@ func NewAgentRunner(
 	if pluginFramework != nil && pluginFramework.PromptPipeline() != nil {
 		pipeline = pluginFramework.PromptPipeline()
 	} else {
-		pipeline = prompt.NewDefaultPipeline()
+		var err error
+		if pipeline, err = prompt.NewDefaultPipeline(prompt.PipelineOptions{}); err != nil {
+			logger.Warn("[AgentRunner] default prompt pipeline unavailable, using an empty one: %v", err)
+			pipeline = prompt.NewPipeline()
+		}
 	}
 	contextBuilder.SetPipeline(pipeline)
 
 	var windowGuard *ContextWindowGuard
 	if llmModule != nil {
 		windowGuard = NewContextWindowGuard(llmModule.Manager, DefaultContextWindow)
+		configureTokenizers(windowGuard, cfg)
 	}
 
 	compactorCfg := DefaultCompactorConfig()
@@ -125,7 +209,7 @@ func NewAgentRunner(
 	flowBuilder := agentflow.NewAgentFlowBuilder()
 	turnExecutor := NewTurnExecutor(flowBuilder, llmModule.Fallback, contextBuilder, cfg.MaxRetries)
 
-	return &AgentRunner{
+	r := &AgentRunner{
 		agentRepo:       agentRepo,
 		sessionRepo:     sessionRepo,
 		runRepo:         runRepo,
@@ -138,24 +222,65 @@ func NewAgentRunner(
 		compactor:       compactor,
 		defaultMaxTurns: cfg.DefaultMaxTurns,
 		runTimeout:      cfg.RunTimeout,
+		timezone:        cfg.Timezone,
+		aborts:          newAbortRegistry(),
+		sessions:        newSessionLocks(),
+		rejectBusy:      cfg.RejectConcurrentSessionRuns,
 	}
+	r.compactions = newCompactionQueue(cfg.CompactionWorkers, r.runCompaction)
+	return r
+}
+
+// Close stops the background compaction workers. Compactions still running
+// are canceled; queued ones are dropped.
+func (r *AgentRunner) Close() {
+	r.compactions.close()
 }
 
 // Run executes an agent interaction and returns a streaming event reader.
 //
+// Runs on the same session are serialized: Run waits until earlier runs of
+// req.SessionID have persisted their result, or fails with
+// errno.ErrSessionBusy when RejectConcurrentSessionRuns is set. Compactions
+// of the session take the same lock.
+//
 // Callers consume events via sr.Recv() until io.EOF.
-func (r *AgentRunner) Run(ctx context.Context, req *RunRequest) (*schema.StreamReader[*entity.AgentEvent], error) {
+func (r *AgentRunner) Run(ctx context.Context, req *RunRequest) (_ *schema.StreamReader[*entity.AgentEvent], err error) {
 	// 1. Resolve agent.
 	agent, err := r.agentRepo.Get(ctx, req.AgentID)
 	if err != nil {
 		return nil, fmt.Errorf("agent %q: %w", req.AgentID, err)
 	}
 
-	// 2. Load or create session.
+	// 2. Lock, then load or create the session. The lock is held until the
+	// run's result is persisted (see executeRun).
+	releaseSession := func() {}
+	if req.SessionID != "" {
+		releaseSession, err = r.sessions.acquire(ctx, req.SessionID, r.rejectBusy)
+		if err != nil {
+			return nil, fmt.Errorf("session %q: %w", req.SessionID, err)
+		}
+	}
+	defer func() {
+		if err != nil {
+			releaseSession()
+		}
+	}()
+
 	session, err := r.resolveSession(ctx, agent, req.SessionID)
 	if err != nil {
 		return nil, fmt.Errorf("session resolution failed: %w", err)
 	}
+	if session.ID != req.SessionID {
+		// A new session was created under a fresh ID. Hold the lock on that
+		// ID instead, the one compactions of this session take.
+		release, err := r.sessions.acquire(ctx, session.ID, false)
+		if err != nil {
+			return nil, fmt.Errorf("session %q: %w", session.ID, err)
+		}
+		releaseSession()
+		releaseSession = release
+	}
 
 	// 3. Create run record.
 	run := &entity.Run{
@@ -170,36 +295,49 @@ func (r *AgentRunner) Run(ctx context.Context, req *RunRequest) (*schema.StreamR
 		return nil, fmt.Errorf("failed to create run: %w", err)
 	}
 
+	// Correlate every log line of this run, including those of the async
+	// execution, hooks and plugins, which inherit ctx.
+	ctx = logger.WithFields(ctx,
+		logger.FieldRunID, run.ID,
+		logger.FieldSessionID, session.ID,
+		logger.FieldAgentID, agent.ID)
+
 	// 4. Create state machine.
 	stateMachine := NewRunStateMachine(run, r.runRepo)
-	if err := stateMachine.TransitionToInProgress(); err != nil {
+	if err := stateMachine.TransitionToInProgress(ctx); err != nil {
 		return nil, err
 	}
 
 	// 5. Create abort controller.
 	abort := NewAbortController(ctx, run.ID, r.runTimeout)
+	r.aborts.register(run.ID, abort, req.OnFinish)
 
 	// 6. Create streaming event pipe (airi-go schema.Pipe pattern).
 	sr, sw := schema.Pipe[*entity.AgentEvent](20)
 
-	// 7. Launch async execution.
+	// 7. Emit initial run status event. It is sent before the execution
+	// starts, which may finish and close sw at any time.
+	sw.Send(&entity.AgentEvent{
+		Type:      entity.EventRunStatus,
+		RunStatus: entity.RunStatusInProgress,
+	}, nil)
+
+	// 8. Launch async execution.
 	safego.Go(abort.Context(), func() {
+		defer releaseSession()
+		defer r.aborts.unregister(run.ID)
 		defer abort.CleanUp()
 		defer sw.Close()
 
-		r.executeRun(abort.Context(), agent, session, run, stateMachine, sw, abort, req.Input)
+		r.executeRun(abort.Context(), agent, session, run, stateMachine, sw, abort, req, releaseSession)
 	})
 
-	// 8. Emit initial run status event.
-	sw.Send(&entity.AgentEvent{
-		Type:      entity.EventRunStatus,
-		RunStatus: entity.RunStatusInProgress,
-	}, nil)
-
 	return sr, nil
 }
 
 // executeRun is the async execution body running inside safego.Go.
+// releaseSession is called as soon as the session no longer changes, so that
+// the agent_end hook and the done events do not hold up the next run.
 func (r *AgentRunner) executeRun(
 	ctx context.Context,
 	agent *entity.Agent,
@@ -208,49 +346,52 @@ func (r *AgentRunner) executeRun(
 	stateMachine *RunStateMachine,
 	sw *schema.StreamWriter[*entity.AgentEvent],
 	abort *AbortController,
-	userInput string,
+	req *RunRequest,
+	releaseSession func(),
 ) {
+	userInput := req.Input
+
+	// Bind the agent ID so plugin tools and hooks can scope per-agent state.
+	ctx = plugin.WithAgentID(ctx, agent.ID)
+
 	// Fire before_agent_start hook (memory injection, etc.).
 	injectedMessages := r.fireBeforeAgentStart(ctx, agent, session)
 
 	// Resolve context window.
 	windowInfo := r.resolveWindowInfo(ctx, agent)
 
-	// Adapt plugin tools to Eino tools.
-	pluginTools := agentflow.AdaptPluginTools(r.pluginFramework.Registry(), agent.Tools)
+	// Resolve plugin + MCP tools available to this agent.
+	tools := r.resolveTools(ctx, agent)
 
-	// Merge MCP tools, filtered by agent.MCPServers (empty = all servers).
-	tools := pluginTools
-	var mcpToolsList []tool.BaseTool
-	if r.mcpManager != nil {
-		if len(agent.MCPServers) == 0 {
-			mcpToolsList = r.mcpManager.GetAllTools()
-		} else {
-			for _, name := range agent.MCPServers {
-				mcpToolsList = append(mcpToolsList, r.mcpManager.GetToolsByServer(name)...)
-			}
+	// Build PromptContext with tool summaries for the PromptPipeline.
+	promptCtx := r.buildPromptContext(ctx, agent, session, tools)
+	if req.PromptMode != "" {
+		promptCtx.Mode = prompt.PromptMode(req.PromptMode)
+	}
+	if len(req.PromptExtra) > 0 {
+		if promptCtx.Extra == nil {
+			promptCtx.Extra = make(map[string]interface{}, len(req.PromptExtra))
 		}
-		if len(mcpToolsList) > 0 {
-			tools = append(tools, mcpToolsList...)
-			logger.DebugX(pkg.ModuleName, "[AgentRunner] merged %d plugin tools + %d MCP tools", len(pluginTools), len(mcpToolsList))
+		for k, v := range req.PromptExtra {
+			promptCtx.Extra[k] = v
 		}
 	}
 
-	// Build PromptContext with tool summaries for the PromptPipeline.
-	promptCtx := r.buildPromptContext(agent, session, tools)
-
 	// Build LLM context with pruning.
-	buildResult := r.contextBuilder.Build(agent, session, userInput, injectedMessages, windowInfo, promptCtx)
+	input := entity.NewUserMessage(userInput)
+	input.Images = req.Images
+	buildResult := r.contextBuilder.WithMaxHistoryTurns(ctx, req.MaxHistoryTurns).Build(ctx, agent, session, input, injectedMessages, windowInfo, promptCtx)
 	messages := buildResult.Messages
 
-	logger.DebugX(pkg.ModuleName, "[AgentRunner] context built: %d messages, ~%d tokens, window=%d usable=%d",
+	logger.CtxDebugX(ctx, pkg.ModuleName, "[AgentRunner] context built: %d messages, ~%d tokens, window=%d usable=%d",
 		len(messages), buildResult.EstimatedTokens, windowInfo.WindowSize, windowInfo.UsableTokens)
 
 	maxTurns := agent.EffectiveMaxTurns(r.defaultMaxTurns)
 
-	// Execute the turn.
-	result, err := r.turnExecutor.Execute(ctx, &TurnRequest{
+	// Execute the turn, once per requested choice.
+	turn := TurnRequest{
 		Agent:       agent,
+		Params:      mergeLLMParams(agent.LLMParams(), req.LLMOverrides),
 		Messages:    messages,
 		Tools:       tools,
 		MaxTurns:    maxTurns,
@@ -258,16 +399,53 @@ func (r *AgentRunner) executeRun(
 		Session:     session,
 		WindowInfo:  windowInfo,
 		Compactor:   r.compactor,
-	}, abort)
 
-	if err != nil {
-		logger.Warn("[AgentRunner] run %s failed: %v", run.ID, err)
-		stateMachine.TransitionToFailed("execution_error", err.Error())
+		MaxHistoryTurns:  req.MaxHistoryTurns,
+		Input:            input,
+		InjectedMessages: injectedMessages,
+		PromptContext:    promptCtx,
+	}
+	var choices []choiceResult
+	if req.Choices > 1 {
+		choices = r.executeChoices(ctx, turn, req.Choices, run.ID, sw, abort)
+	} else {
+		result, err := r.turnExecutor.Execute(ctx, &turn, abort)
+		choices = []choiceResult{{result: result, err: err}}
+	}
+
+	// The first successful choice continues the session.
+	primary := -1
+	for i, c := range choices {
+		if c.err == nil {
+			primary = i
+			break
+		}
+	}
+	// Report failed choices. When every choice failed, choice 0 is reported
+	// below as the failure of the run.
+	for i, c := range choices[1:] {
+		if c.err == nil {
+			continue
+		}
+		logger.CtxWarn(ctx, "[AgentRunner] run %s choice %d failed: %v", run.ID, i+1, c.err)
+		sw.Send(choiceFailedEvent(i+1, c.err), nil)
+	}
+	if primary > 0 {
+		logger.CtxWarn(ctx, "[AgentRunner] run %s choice 0 failed: %v", run.ID, choices[0].err)
+		sw.Send(choiceFailedEvent(0, choices[0].err), nil)
+	}
+
+	if primary < 0 {
+		err := choices[0].err
+		logger.CtxWarn(ctx, "[AgentRunner] run %s failed: %v", run.ID, err)
+		releaseSession()
+		stateMachine.TransitionToFailed(ctx, "execution_error", err.Error())
 
 		sw.Send(&entity.AgentEvent{
-			Type:      entity.EventRunStatus,
-			RunStatus: entity.RunStatusFailed,
-			Error:     err.Error(),
+			Type:         entity.EventRunStatus,
+			RunStatus:    entity.RunStatusFailed,
+			Error:        err.Error(),
+			FinishReason: entity.FinishReasonError,
 		}, nil)
 
 		r.fireAgentEnd(ctx, agent, session, run)
@@ -276,19 +454,30 @@ func (r *AgentRunner) executeRun(
 	}
 
 	// Success: extract final message.
+	result := choices[primary].result
 	finalContent := ""
 	if result.FinalMessage != nil {
 		finalContent = result.FinalMessage.Content
 	}
 
-	stateMachine.TransitionToCompleted(finalContent, result.Usage)
+	usage, cost := result.Usage, r.computeCost(ctx, result.ModelRef, result.Usage)
+	if len(choices) > 1 {
+		usage, cost = r.choicesUsage(ctx, choices)
+	}
+	stateMachine.TransitionToCompleted(ctx, finalContent, usage)
 	run.ModelRef = result.ModelRef.String()
+	run.Cost = cost
 
 	// Persist: update session history.
-	session.AppendMessage(entity.NewUserMessage(userInput))
-	session.AppendMessage(entity.NewAssistantMessage(finalContent))
-	session.AddUsage(result.Usage)
-	_ = r.sessionRepo.Update(ctx, session)
+	exchange := []*entity.Message{entity.NewUserMessage(userInput), entity.NewAssistantMessage(finalContent)}
+	if err := r.updateSession(ctx, session, func(s *entity.Session) {
+		s.AppendMessages(exchange)
+		s.AddUsage(usage)
+		s.AddCost(run.Cost)
+	}); err != nil {
+		logger.CtxWarn(ctx, "[AgentRunner] run %s: failed to persist session %s: %v", run.ID, session.ID, err)
+	}
+	releaseSession()
 
 	// Persist: update run.
 	_ = r.runRepo.Update(ctx, run)
@@ -296,33 +485,114 @@ func (r *AgentRunner) executeRun(
 	// Proactive compaction check (OpenClaw equivalent: post-turn threshold maintenance).
 	r.checkProactiveCompaction(ctx, agent, session, windowInfo, sw)
 
-	// Emit done event.
-	sw.Send(&entity.AgentEvent{
-		Type:      entity.EventDone,
-		RunStatus: entity.RunStatusCompleted,
-		Usage:     result.Usage,
-	}, nil)
+	// Emit done events, one per successful choice.
+	for i, c := range choices {
+		if c.err != nil {
+			continue
+		}
+		done := &entity.AgentEvent{
+			Type:      entity.EventDone,
+			RunStatus: entity.RunStatusCompleted,
+			Usage:     c.result.Usage,
+			Choice:    i,
+		}
+		if c.result.MaxTurnsReached {
+			done.FinishReason = entity.FinishReasonMaxTurns
+		} else {
+			done.FinishReason = modelFinishReason(c.result.FinalMessage)
+		}
+		sw.Send(done, nil)
+	}
 
 	// Fire agent_end hook.
 	r.fireAgentEnd(ctx, agent, session, run)
 
-	logger.InfoX(pkg.ModuleName, "[AgentRunner] run %s completed (model=%s)", run.ID, run.ModelRef)
+	logger.CtxInfoX(ctx, pkg.ModuleName, "[AgentRunner] run %s completed (model=%s)", run.ID, run.ModelRef)
+}
+
+// modelFinishReason maps the provider finish reason of the final reply to a
+// run finish reason. Normal completions ("stop", "end_turn", ...) map to "".
+func modelFinishReason(msg *schema.Message) entity.FinishReason {
+	if msg == nil || msg.ResponseMeta == nil {
+		return ""
+	}
+	switch strings.ToLower(msg.ResponseMeta.FinishReason) {
+	case "length", "max_tokens", "max_output_tokens":
+		return entity.FinishReasonLength
+	case "content_filter", "safety", "recitation":
+		return entity.FinishReasonContentFilter
+	}
+	return ""
+}
+
+// mergeLLMParams applies the non-zero fields of overrides onto base.
+func mergeLLMParams(base, overrides *llmEntity.LLMParams) *llmEntity.LLMParams {
+	if overrides == nil {
+		return base
+	}
+	if overrides.Temperature != nil {
+		base.Temperature = overrides.Temperature
+	}
+	if overrides.MaxTokens != 0 {
+		base.MaxTokens = overrides.MaxTokens
+	}
+	if overrides.TopP != nil {
+		base.TopP = overrides.TopP
+	}
+	if overrides.FrequencyPenalty != nil {
+		base.FrequencyPenalty = overrides.FrequencyPenalty
+	}
+	if overrides.PresencePenalty != nil {
+		base.PresencePenalty = overrides.PresencePenalty
+	}
+	if overrides.Seed != nil {
+		base.Seed = overrides.Seed
+	}
+	if len(overrides.Stop) > 0 {
+		base.Stop = overrides.Stop
+	}
+	if overrides.ResponseFormat != llmEntity.ModelResponseFormatText {
+		base.ResponseFormat = overrides.ResponseFormat
+		base.JSONSchema = overrides.JSONSchema
+	}
+	return base
+}
+
+// computeCost prices the run's token usage with the serving model's cost info.
+// Returns nil when usage is unknown or the model has no pricing configured.
+func (r *AgentRunner) computeCost(ctx context.Context, ref llmEntity.ModelRef, usage *entity.TokenUsage) *float64 {
+	if usage == nil || r.llmModule == nil || r.llmModule.Manager == nil {
+		return nil
+	}
+	instance, err := r.llmModule.Manager.GetModelByRef(ctx, ref)
+	if err != nil || instance == nil || !instance.Cost.IsSet() {
+		return nil
+	}
+	cost := instance.Cost.Compute(usage.PromptTokens, usage.CompletionTokens)
+	return &cost
 }
 
 // resolveWindowInfo resolves context window using the guard, or returns defaults.
 func (r *AgentRunner) resolveWindowInfo(ctx context.Context, agent *entity.Agent) ContextWindowInfo {
 	if r.windowGuard != nil {
-		return r.windowGuard.Resolve(ctx, agent.ModelRef)
+		return r.windowGuard.Resolve(ctx, agent.ModelRef, agent.ReserveTokens)
+	}
+	reserve := DefaultReserveTokens
+	if agent.ReserveTokens != nil {
+		reserve = clampReserve(*agent.ReserveTokens, DefaultContextWindow)
 	}
 	return ContextWindowInfo{
 		WindowSize:    DefaultContextWindow,
-		ReserveTokens: 4096,
-		UsableTokens:  DefaultContextWindow - 4096,
+		ReserveTokens: reserve,
+		UsableTokens:  DefaultContextWindow - reserve,
 	}
 }
 
-// checkProactiveCompaction checks if the session needs compaction after a successful turn
-// and performs it if necessary (OpenClaw's post-turn threshold maintenance).
+// checkProactiveCompaction queues the session for background compaction when
+// it crossed the threshold after a successful turn, so that the done event is
+// not held up by the summarization call. A queued compaction is reported as
+// scheduled on sw. Overflow compaction stays inline in the TurnExecutor since
+// the retry needs its result.
 func (r *AgentRunner) checkProactiveCompaction(
 	ctx context.Context,
 	agent *entity.Agent,
@@ -330,34 +600,47 @@ func (r *AgentRunner) checkProactiveCompaction(
 	windowInfo ContextWindowInfo,
 	sw *schema.StreamWriter[*entity.AgentEvent],
 ) {
-	if r.compactor == nil {
+	if r.compactor == nil || !r.compactor.ShouldCompact(session, windowInfo) {
 		return
 	}
 
-	if !r.compactor.ShouldCompact(session, windowInfo) {
-		return
+	if r.compactions.enqueue(compactionJob{agent: agent, sessionID: session.ID, windowInfo: windowInfo}) {
+		logger.CtxInfoX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction queued for session %s", session.ID)
+		sw.Send(compactionEvent(entity.CompactionReasonThreshold, entity.CompactionScheduled,
+			r.compactor.ActiveTokens(session, windowInfo), 0), nil)
+	} else {
+		logger.CtxDebugX(ctx, pkg.ModuleName, "[AgentRunner] proactive compaction not queued for session %s (already queued or queue full)", session.ID)
 	}
+}
 
-	logger.InfoX(pkg.ModuleName, "[AgentRunner] proactive compaction triggered for session %s", session.ID)
+// maxSessionUpdateAttempts bounds how often updateSession reloads a session
+// that keeps being modified concurrently.
+const maxSessionUpdateAttempts = 3
 
-	params := agent.LLMParams()
-	compactModel, _, err := r.llmModule.Fallback.GetChatModelWithFallback(
-		ctx, agent.Fallback, params)
-	if err != nil {
-		logger.WarnX(pkg.ModuleName, "[AgentRunner] proactive compaction skipped: no model available: %v", err)
-		return
-	}
-
-	_, err = r.compactor.Compact(ctx, session, compactModel, windowInfo)
-	if err != nil {
-		logger.WarnX(pkg.ModuleName, "[AgentRunner] proactive compaction failed: %v", err)
-		return
+// updateSession applies apply to session and stores it. If the session was
+// modified concurrently (errno.ErrVersionConflict), it reloads the session,
+// re-applies apply to the stored state and tries again; on success *session
+// holds the stored state. Changes made to session before the call (e.g. an
+// inline compaction) are lost when a reload happens.
+func (r *AgentRunner) updateSession(ctx context.Context, session *entity.Session, apply func(*entity.Session)) error {
+	current := session
+	for attempt := 1; ; attempt++ {
+		apply(current)
+		err := r.sessionRepo.Update(ctx, current)
+		if err == nil {
+			if current != session {
+				*session = *current
+			}
+			return nil
+		}
+		if !errors.Is(err, errno.ErrVersionConflict) || attempt == maxSessionUpdateAttempts {
+			return err
+		}
+		logger.CtxDebugX(ctx, pkg.ModuleName, "[AgentRunner] session %s was modified concurrently, reloading (attempt %d)", session.ID, attempt)
+		if current, err = r.sessionRepo.Get(ctx, session.ID); err != nil {
+			return err
+		}
 	}
-
-	_ = r.sessionRepo.Update(ctx, session)
-
-	logger.InfoX(pkg.ModuleName, "[AgentRunner] proactive compaction completed for session %s (count=%d)",
-		session.ID, session.CompactionCount)
 }
 
 // resolveSession loads an existing session or creates a new one.
@@ -393,7 +676,7 @@ func (r *AgentRunner) fireBeforeAgentStart(ctx context.Context, agent *entity.Ag
 		"session": session,
 	}
 	if err := plugin.FireHooks(ctx, r.pluginFramework.Registry(), plugin.HookBeforeAgentStart, hookData); err != nil {
-		logger.WarnX(pkg.ModuleName, "[AgentRunner] before_agent_start hook error: %v", err)
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] before_agent_start hook error: %v", err)
 	}
 
 	if injected, ok := hookData["injected_messages"].([]*entity.Message); ok {
@@ -410,42 +693,274 @@ func (r *AgentRunner) fireAgentEnd(ctx context.Context, agent *entity.Agent, ses
 		"run":     run,
 	}
 	if err := plugin.FireHooks(ctx, r.pluginFramework.Registry(), plugin.HookAgentEnd, hookData); err != nil {
-		logger.WarnX(pkg.ModuleName, "[AgentRunner] agent_end hook error: %v", err)
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] agent_end hook error: %v", err)
 	}
 }
 
 // Abort cancels a running agent execution by run ID.
-// Note: In the current implementation, abort controllers are not tracked externally.
-// This is a placeholder for future implementation where run→abort mappings are maintained.
-func (r *AgentRunner) Abort(_ context.Context, _ string) error {
-	return fmt.Errorf("abort not yet implemented for external callers")
+// Returns errno.ErrRunNotFound when no run with that ID is in flight.
+func (r *AgentRunner) Abort(_ context.Context, runID string) error {
+	return r.aborts.abort(runID)
+}
+
+// VisionTools returns the names of the plugin tools that work on images
+// (RequiresVision). Agents whose model has no image understanding do not get them.
+func (r *AgentRunner) VisionTools() []string {
+	if r == nil || r.pluginFramework == nil {
+		return nil
+	}
+	return r.pluginFramework.Registry().VisionTools()
+}
+
+// EffectiveTools returns the tools an agent would receive at run time: its
+// plugin tools and MCP tools after tool mode, allow/deny lists, MCP server and
+// model capability filtering. No run or session is created.
+func (r *AgentRunner) EffectiveTools(ctx context.Context, agentID string) ([]prompt.ToolSummary, error) {
+	agent, err := r.agentRepo.Get(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: %w", agentID, err)
+	}
+	return summarizeTools(r.resolveTools(ctx, agent)), nil
+}
+
+// PreviewPrompt assembles the system prompt an agent would receive for a new
+// session, together with a per-section breakdown. No run or session is created.
+func (r *AgentRunner) PreviewPrompt(ctx context.Context, agentID string) (*prompt.AssembleReport, error) {
+	agent, err := r.agentRepo.Get(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: %w", agentID, err)
+	}
+
+	pc := r.buildPromptContext(ctx, agent, nil, r.resolveTools(ctx, agent))
+
+	pipeline := r.contextBuilder.Pipeline()
+	if pipeline == nil {
+		return &prompt.AssembleReport{Text: agent.SystemPrompt}, nil
+	}
+
+	report, err := pipeline.Preview(plugin.WithAgentID(ctx, agent.ID), pc)
+	if err != nil {
+		return nil, fmt.Errorf("prompt assembly failed: %w", err)
+	}
+	// Mirror ContextBuilder: an empty pipeline result falls back to the raw prompt.
+	if report.Text == "" {
+		report.Text = agent.SystemPrompt
+	}
+	return report, nil
+}
+
+// resolveTools adapts the agent's plugin tools to Eino tools and merges
+// MCP tools, filtered by agent.MCPServers (empty = all servers) and then by
+// agent.MCPTools (empty = all tools of those servers).
+// agent.DeniedTools is applied to both plugin and MCP tools. MCP tools whose
+// names collide with a plugin tool are renamed (see mcp.DisambiguateTools).
+//
+// Tools are gated by the capabilities of the agent's models: a primary model
+// without function calling gets no tools at all, and tools that work on images
+// (plugin tools marked RequiresVision, MCP tools listed in a server's
+// VisionTools) are withheld unless every model of the fallback chain has image
+// understanding, since any of them may end up answering the run.
+func (r *AgentRunner) resolveTools(ctx context.Context, agent *entity.Agent) []tool.BaseTool {
+	functionCall, vision := r.modelToolSupport(ctx, agent)
+	if !functionCall {
+		if _, warned := r.warnedTools.LoadOrStore(agent.ID+"/"+agent.ModelRef.String()+"#function_call", struct{}{}); !warned {
+			logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] model of agent %q does not support function calling, running without tools", agent.ID)
+		}
+		return nil
+	}
+
+	denied := agent.DeniedTools
+	if !agent.MemoryEnabled() {
+		denied = append(slices.Clone(denied), r.pluginFramework.Registry().ToolsOfKind(memoryPluginKind)...)
+	}
+	if !vision {
+		denied = append(slices.Clone(denied), r.pluginFramework.Registry().VisionTools()...)
+	}
+	if unavailable := r.pluginFramework.Registry().UnavailableTools(ctx); len(unavailable) > 0 {
+		denied = append(slices.Clone(denied), unavailable...)
+	}
+	pluginTools, missing := agentflow.AdaptPluginTools(r.pluginFramework.Registry(), agent.EffectiveToolMode(), agent.Tools, denied)
+	for _, name := range missing {
+		// Warn once per agent/tool rather than on every run.
+		if _, warned := r.warnedTools.LoadOrStore(agent.ID+"/"+name, struct{}{}); !warned {
+			logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] agent %q allows tool %q, but no plugin registers it", agent.ID, name)
+		}
+	}
+
+	tools := pluginTools
+	var mcpToolsList []tool.BaseTool
+	if r.mcpManager != nil {
+		if len(agent.MCPServers) == 0 {
+			mcpToolsList = r.mcpManager.GetAllTools()
+		} else {
+			for _, name := range agent.MCPServers {
+				mcpToolsList = append(mcpToolsList, r.mcpManager.GetToolsByServer(name)...)
+			}
+		}
+		mcpToolsList = filterAllowedMCPTools(mcpToolsList, agent.MCPTools)
+		mcpToolsList = filterDeniedMCPTools(mcpToolsList, agent.DeniedTools)
+		if !vision {
+			mcpToolsList = slices.DeleteFunc(mcpToolsList, mcp.RequiresVision)
+		}
+		mcpToolsList = mcp.DisambiguateTools(ctx, mcpToolsList, toolNameSet(pluginTools))
+		if len(mcpToolsList) > 0 {
+			tools = append(tools, mcpToolsList...)
+			logger.CtxDebugX(ctx, pkg.ModuleName, "[AgentRunner] merged %d plugin tools + %d MCP tools", len(pluginTools), len(mcpToolsList))
+		}
+	}
+	return tools
+}
+
+// modelToolSupport reports whether the agent's primary model (its ModelRef,
+// else its fallback primary) supports function calling, and whether every
+// model it may run on (the primary and its fallbacks) supports image input,
+// per their capabilities and resolved compat rules. A model that cannot be
+// resolved is assumed to support both, leaving errors to the provider.
+func (r *AgentRunner) modelToolSupport(ctx context.Context, agent *entity.Agent) (functionCall, vision bool) {
+	if r.llmModule == nil || r.llmModule.Manager == nil {
+		return true, true
+	}
+	ref := agent.ModelRef
+	if ref.ProviderID == "" || ref.ModelID == "" {
+		ref = agent.Fallback.Primary
+	}
+	functionCall, vision = r.modelSupport(ctx, ref)
+	for _, fb := range agent.Fallback.Candidates() {
+		if fb == ref {
+			continue
+		}
+		if _, ok := r.modelSupport(ctx, fb); !ok {
+			vision = false
+		}
+	}
+	return functionCall, vision
+}
+
+// modelSupport reports whether the model supports function calling and
+// image input. An unknown model is assumed to support both.
+func (r *AgentRunner) modelSupport(ctx context.Context, ref llmEntity.ModelRef) (functionCall, vision bool) {
+	if ref.ProviderID == "" || ref.ModelID == "" {
+		return true, true
+	}
+	instance, err := r.llmModule.Manager.GetModelByRef(ctx, ref)
+	if err != nil || instance == nil {
+		return true, true
+	}
+
+	functionCall = instance.Capability.FunctionCall
+	vision = instance.Capability.ImageUnderstanding
+	if compat, err := r.llmModule.Manager.ResolveCompat(ctx, ref); err == nil && compat != nil {
+		if compat.SupportsFunctionCall != nil && !*compat.SupportsFunctionCall {
+			functionCall = false
+		}
+		if compat.SupportsVision != nil && !*compat.SupportsVision {
+			vision = false
+		}
+	}
+	return functionCall, vision
+}
+
+// memoryPluginKind is the plugin kind whose tools an agent with memory
+// disabled does not get.
+const memoryPluginKind = "memory"
+
+// toolNameSet returns the names of tools.
+func toolNameSet(tools []tool.BaseTool) map[string]struct{} {
+	names := make(map[string]struct{}, len(tools))
+	for _, t := range tools {
+		if info, err := t.Info(context.Background()); err == nil && info != nil {
+			names[info.Name] = struct{}{}
+		}
+	}
+	return names
+}
+
+// filterAllowedMCPTools keeps only MCP tools whose namespaced or bare name is
+// allowed. An empty allowed list keeps all tools.
+func filterAllowedMCPTools(tools []tool.BaseTool, allowed []string) []tool.BaseTool {
+	if len(allowed) == 0 {
+		return tools
+	}
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = struct{}{}
+	}
+
+	kept := tools[:0:0]
+	for _, t := range tools {
+		if mcpToolNamed(t, allowedSet) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// filterDeniedMCPTools drops MCP tools whose namespaced or bare name is denied.
+func filterDeniedMCPTools(tools []tool.BaseTool, denied []string) []tool.BaseTool {
+	if len(denied) == 0 {
+		return tools
+	}
+	deniedSet := make(map[string]struct{}, len(denied))
+	for _, name := range denied {
+		deniedSet[name] = struct{}{}
+	}
+
+	kept := tools[:0:0]
+	for _, t := range tools {
+		if !mcpToolNamed(t, deniedSet) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// mcpToolNamed reports whether t's bare MCP name or its exposed (namespaced)
+// name is in names.
+func mcpToolNamed(t tool.BaseTool, names map[string]struct{}) bool {
+	if _, name, ok := mcp.ToolOrigin(t); ok {
+		if _, hit := names[name]; hit {
+			return true
+		}
+	}
+	if info, err := t.Info(context.Background()); err == nil && info != nil {
+		if _, hit := names[info.Name]; hit {
+			return true
+		}
+	}
+	return false
 }
 
 // buildPromptContext creates a PromptContext from the current run state.
 // This bridges entity types and the prompt package's cycle-free types,
 // and enriches the context with tool summaries for the ToolingSection.
 func (r *AgentRunner) buildPromptContext(
+	ctx context.Context,
 	agent *entity.Agent,
 	session *entity.Session,
 	tools []tool.BaseTool,
 ) *prompt.PromptContext {
 	pc := &prompt.PromptContext{
-		Mode: prompt.PromptMode(agent.EffectivePromptMode()),
-		Now:  time.Now(),
+		Mode:     prompt.PromptMode(agent.EffectivePromptMode()),
+		Timezone: r.resolveTimezone(ctx, agent),
+		Now:      time.Now(),
+		OS:       goruntime.GOOS,
+		Arch:     goruntime.GOARCH,
 	}
 
 	// Map Agent → AgentPromptInfo.
 	if agent != nil {
 		info := &prompt.AgentPromptInfo{
-			ID:           agent.ID,
-			Name:         agent.Name,
-			SystemPrompt: agent.SystemPrompt,
+			ID:             agent.ID,
+			Name:           agent.Name,
+			SystemPrompt:   agent.SystemPrompt,
+			MemoryDisabled: !agent.MemoryEnabled(),
 		}
 		if agent.Persona != nil {
 			info.Persona = &prompt.AgentPersonaInfo{
 				PromptMode:   agent.Persona.PromptMode,
 				WorkspaceDir: agent.Persona.WorkspaceDir,
 			}
+			pc.WorkspaceDir = agent.Persona.WorkspaceDir
 			if agent.Persona.Identity != nil {
 				info.Persona.Identity = &prompt.AgentIdentityInfo{
 					Name:     agent.Persona.Identity.Name,
@@ -465,21 +980,73 @@ func (r *AgentRunner) buildPromptContext(
 		pc.SessionID = session.ID
 	}
 
+	pc.UnavailableMCPServers = r.unavailableMCPServers(agent)
+
 	// Build tool summaries from Eino tools for the ToolingSection.
+	pc.Tools = summarizeTools(tools)
+
+	return pc
+}
+
+// summarizeTools describes Eino tools by name, description and source.
+// Tools without a name are skipped.
+func summarizeTools(tools []tool.BaseTool) []prompt.ToolSummary {
+	var summaries []prompt.ToolSummary
 	for _, t := range tools {
 		info, err := t.Info(context.Background())
 		if err != nil || info == nil {
 			continue
 		}
-		source := "plugin"
-		if len(info.Name) > 0 {
-			pc.Tools = append(pc.Tools, prompt.ToolSummary{
-				Name:        info.Name,
-				Description: info.Desc,
-				Source:      source,
-			})
+		if len(info.Name) == 0 {
+			continue
+		}
+		summary := prompt.ToolSummary{
+			Name:        info.Name,
+			Description: info.Desc,
+			Source:      "plugin",
 		}
+		if server, name, ok := mcp.ToolOrigin(t); ok {
+			summary.Source = "mcp"
+			summary.Server = server
+			summary.DisplayName = name
+		}
+		summaries = append(summaries, summary)
 	}
+	return summaries
+}
 
-	return pc
+// unavailableMCPServers lists the agent's MCP servers (all servers when
+// agent.MCPServers is empty) that are not connected. Their tools are simply
+// absent from the run; the prompt tells the model why.
+func (r *AgentRunner) unavailableMCPServers(agent *entity.Agent) []prompt.UnavailableServer {
+	if r.mcpManager == nil || agent == nil {
+		return nil
+	}
+	wanted := make(map[string]bool, len(agent.MCPServers))
+	for _, name := range agent.MCPServers {
+		wanted[name] = true
+	}
+
+	var down []prompt.UnavailableServer
+	for _, h := range r.mcpManager.Health() {
+		if h.Status == mcp.ServerStatusConnected || (len(wanted) > 0 && !wanted[h.Name]) {
+			continue
+		}
+		down = append(down, prompt.UnavailableServer{Name: h.Name, Reason: h.Reason})
+	}
+	return down
+}
+
+// resolveTimezone returns the agent's persona timezone when it names a valid
+// zone, otherwise the runner default.
+func (r *AgentRunner) resolveTimezone(ctx context.Context, agent *entity.Agent) string {
+	if agent == nil || agent.Persona == nil || agent.Persona.Timezone == "" {
+		return r.timezone
+	}
+	if _, err := time.LoadLocation(agent.Persona.Timezone); err != nil {
+		logger.CtxWarnX(ctx, pkg.ModuleName, "[AgentRunner] agent %q has invalid timezone %q, using default: %v",
+			agent.ID, agent.Persona.Timezone, err)
+		return r.timezone
+	}
+	return agent.Persona.Timezone
 }
@@ -0,0 +1,378 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
+)
+
+func f32(v float32) *float32 { return &v }
+
+func f64(v float64) *float64 { return &v }
+
+func TestMergeLLMParamsPenalties(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides *llmEntity.LLMParams
+		wantFreq  *float32
+		wantPres  *float32
+	}{
+		{"no overrides", nil, f32(0.5), f32(0.7)},
+		{"unset keeps agent values", &llmEntity.LLMParams{}, f32(0.5), f32(0.7)},
+		{"explicit zero resets", &llmEntity.LLMParams{FrequencyPenalty: f32(0), PresencePenalty: f32(0)}, f32(0), f32(0)},
+		{"negative", &llmEntity.LLMParams{FrequencyPenalty: f32(-1)}, f32(-1), f32(0.7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &llmEntity.LLMParams{FrequencyPenalty: f32(0.5), PresencePenalty: f32(0.7)}
+			got := mergeLLMParams(base, tt.overrides)
+			if got.FrequencyPenalty == nil || *got.FrequencyPenalty != *tt.wantFreq {
+				t.Fatalf("frequency penalty = %v, want %v", got.FrequencyPenalty, *tt.wantFreq)
+			}
+			if got.PresencePenalty == nil || *got.PresencePenalty != *tt.wantPres {
+				t.Fatalf("presence penalty = %v, want %v", got.PresencePenalty, *tt.wantPres)
+			}
+		})
+	}
+}
+
+func TestMergeLLMParamsSeed(t *testing.T) {
+	agentSeed, requestSeed := 1, 2
+	tests := []struct {
+		name      string
+		base      *int
+		overrides *llmEntity.LLMParams
+		want      *int
+	}{
+		{"no overrides", &agentSeed, nil, &agentSeed},
+		{"unset keeps agent seed", &agentSeed, &llmEntity.LLMParams{}, &agentSeed},
+		{"request seed wins", &agentSeed, &llmEntity.LLMParams{Seed: &requestSeed}, &requestSeed},
+		{"request seed only", nil, &llmEntity.LLMParams{Seed: &requestSeed}, &requestSeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeLLMParams(&llmEntity.LLMParams{Seed: tt.base}, tt.overrides)
+			if got.Seed != tt.want {
+				t.Fatalf("seed = %v, want %v", got.Seed, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPromptContextWorkspaceDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		persona *entity.AgentPersona
+		want    string
+	}{
+		{"no persona", nil, ""},
+		{"no workspace", &entity.AgentPersona{}, ""},
+		{"configured workspace", &entity.AgentPersona{WorkspaceDir: "/srv/agents/main"}, "/srv/agents/main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &AgentRunner{}
+			pc := r.buildPromptContext(context.Background(), &entity.Agent{ID: "main", Persona: tt.persona}, nil, nil)
+			if pc.WorkspaceDir != tt.want {
+				t.Fatalf("WorkspaceDir = %q, want %q", pc.WorkspaceDir, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPromptContextTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  string
+		persona *entity.AgentPersona
+		want    string
+	}{
+		{"server local", "", nil, ""},
+		{"server default", "Asia/Shanghai", &entity.AgentPersona{}, "Asia/Shanghai"},
+		{"agent override", "Asia/Shanghai", &entity.AgentPersona{Timezone: "Europe/Berlin"}, "Europe/Berlin"},
+		{"invalid agent zone", "Asia/Shanghai", &entity.AgentPersona{Timezone: "Mars/Olympus"}, "Asia/Shanghai"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &AgentRunner{timezone: tt.server}
+			pc := r.buildPromptContext(context.Background(), &entity.Agent{ID: "main", Persona: tt.persona}, nil, nil)
+			if pc.Timezone != tt.want {
+				t.Fatalf("Timezone = %q, want %q", pc.Timezone, tt.want)
+			}
+		})
+	}
+}
+
+// racingSessionRepo stores sessions in memory and lets a concurrent writer
+// append a message before the first conflicts updates.
+type racingSessionRepo struct {
+	*inmemory.SessionStore
+
+	conflicts int   // updates preceded by a concurrent write
+	updateErr error // returned by every update when set
+	updates   int
+}
+
+func (r *racingSessionRepo) Update(ctx context.Context, session *entity.Session) error {
+	r.updates++
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	if r.conflicts > 0 {
+		r.conflicts--
+		other, err := r.SessionStore.Get(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		other.AppendMessage(entity.NewUserMessage("concurrent"))
+		if err := r.SessionStore.Update(ctx, other); err != nil {
+			return err
+		}
+	}
+	return r.SessionStore.Update(ctx, session)
+}
+
+func TestUpdateSession(t *testing.T) {
+	failure := errors.New("disk full")
+	tests := []struct {
+		name        string
+		conflicts   int
+		updateErr   error
+		wantErr     error
+		wantUpdates int
+		wantStored  []string
+	}{
+		{"no conflict", 0, nil, nil, 1, []string{"reply"}},
+		{"retried after a conflict", 1, nil, nil, 2, []string{"concurrent", "reply"}},
+		{"retried twice", 2, nil, nil, 3, []string{"concurrent", "concurrent", "reply"}},
+		{"gives up", maxSessionUpdateAttempts, nil, errno.ErrVersionConflict, maxSessionUpdateAttempts,
+			[]string{"concurrent", "concurrent", "concurrent"}},
+		{"other errors are not retried", 0, failure, failure, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sessions := &racingSessionRepo{SessionStore: inmemory.NewSessionStore(), conflicts: tt.conflicts, updateErr: tt.updateErr}
+			if err := sessions.Create(ctx, &entity.Session{ID: "s", AgentID: "a"}); err != nil {
+				t.Fatal(err)
+			}
+			r := &AgentRunner{sessionRepo: sessions}
+
+			session, _ := sessions.Get(ctx, "s")
+			err := r.updateSession(ctx, session, func(s *entity.Session) {
+				s.AppendMessage(entity.NewAssistantMessage("reply"))
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("updateSession = %v, want %v", err, tt.wantErr)
+			}
+			if sessions.updates != tt.wantUpdates {
+				t.Fatalf("updates = %d, want %d", sessions.updates, tt.wantUpdates)
+			}
+
+			stored, _ := sessions.Get(ctx, "s")
+			var contents []string
+			for _, m := range stored.Messages {
+				contents = append(contents, m.Content)
+			}
+			if len(contents) != len(tt.wantStored) {
+				t.Fatalf("stored messages = %q, want %q", contents, tt.wantStored)
+			}
+			for i := range contents {
+				if contents[i] != tt.wantStored[i] {
+					t.Fatalf("stored messages = %q, want %q", contents, tt.wantStored)
+				}
+			}
+			if err == nil && (session.Version != stored.Version || len(session.Messages) != len(stored.Messages)) {
+				t.Fatalf("caller's session = version %d with %d messages, want the stored state (version %d, %d messages)",
+					session.Version, len(session.Messages), stored.Version, len(stored.Messages))
+			}
+		})
+	}
+}
+
+func TestModelFinishReason(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *schema.Message
+		want entity.FinishReason
+	}{
+		{"nil message", nil, ""},
+		{"no response meta", schema.AssistantMessage("hi", nil), ""},
+		{"stop", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "stop"}}, ""},
+		{"end turn", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "end_turn"}}, ""},
+		{"openai length", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "length"}}, entity.FinishReasonLength},
+		{"anthropic max tokens", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "max_tokens"}}, entity.FinishReasonLength},
+		{"gemini max tokens", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "MAX_TOKENS"}}, entity.FinishReasonLength},
+		{"content filter", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "content_filter"}}, entity.FinishReasonContentFilter},
+		{"gemini safety", &schema.Message{ResponseMeta: &schema.ResponseMeta{FinishReason: "SAFETY"}}, entity.FinishReasonContentFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modelFinishReason(tt.msg); got != tt.want {
+				t.Fatalf("modelFinishReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// pricedModels is a ModelManager knowing models by "provider/model" name
+// with the given pricing.
+type pricedModels struct {
+	llmService.ModelManager
+	costs map[string]llmEntity.ModelCostInfo
+}
+
+func (f pricedModels) GetModelByRef(_ context.Context, ref llmEntity.ModelRef) (*llmEntity.ModelInstance, error) {
+	cost, ok := f.costs[ref.String()]
+	if !ok {
+		return nil, errors.New("model not found")
+	}
+	return &llmEntity.ModelInstance{ProviderID: ref.ProviderID, ModelID: ref.ModelID, Cost: cost}, nil
+}
+
+func TestComputeCost(t *testing.T) {
+	r := &AgentRunner{llmModule: &llm.Module{Manager: pricedModels{costs: map[string]llmEntity.ModelCostInfo{
+		"p/priced":   {Input: 3, Output: 15},
+		"p/unpriced": {},
+	}}}}
+	usage := &entity.TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 200_000}
+
+	tests := []struct {
+		name  string
+		model string
+		usage *entity.TokenUsage
+		want  *float64
+	}{
+		{"priced model", "priced", usage, f64(6.0)},
+		{"unpriced model", "unpriced", usage, nil},
+		{"unknown model", "gone", usage, nil},
+		{"no usage", "priced", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.computeCost(context.Background(), llmEntity.ModelRef{ProviderID: "p", ModelID: tt.model}, tt.usage)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("computeCost = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionAddCost(t *testing.T) {
+	s := &entity.Session{}
+	s.AddCost(nil)
+	if s.TotalCost != nil {
+		t.Fatalf("TotalCost = %v after an unpriced run, want nil", *s.TotalCost)
+	}
+	s.AddCost(f64(0.25))
+	s.AddCost(nil)
+	s.AddCost(f64(0.5))
+	if s.TotalCost == nil || *s.TotalCost != 0.75 {
+		t.Fatalf("TotalCost = %v, want 0.75", s.TotalCost)
+	}
+}
+
+func TestFilterAllowedMCPTools(t *testing.T) {
+	var tools []tool.BaseTool
+	for _, name := range []string{"github__search", "github__issues", "jira__search", "jira__create"} {
+		tools = append(tools, &countingTool{name: name})
+	}
+
+	// Bare MCP names match through mcp.ToolOrigin, which only knows tools
+	// obtained from a connected server; these tests match exposed names.
+	tests := []struct {
+		name    string
+		allowed []string
+		want    []string
+	}{
+		{"empty keeps all", nil, []string{"github__search", "github__issues", "jira__search", "jira__create"}},
+		{"namespaced", []string{"github__search"}, []string{"github__search"}},
+		{"several", []string{"jira__create", "github__issues"}, []string{"github__issues", "jira__create"}},
+		{"unknown", []string{"github__delete"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tl := range filterAllowedMCPTools(tools, tt.allowed) {
+				info, _ := tl.Info(context.Background())
+				got = append(got, info.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Allow and deny lists compose: the deny list wins.
+	kept := filterDeniedMCPTools(filterAllowedMCPTools(tools, []string{"github__search", "jira__search"}), []string{"jira__search"})
+	if len(kept) != 1 {
+		t.Fatalf("kept %d tools, want only github__search", len(kept))
+	}
+	if info, _ := kept[0].Info(context.Background()); info.Name != "github__search" {
+		t.Fatalf("kept %s, want github__search", info.Name)
+	}
+}
+
+func TestSummarizeTools(t *testing.T) {
+	tools := []tool.BaseTool{&countingTool{name: "web_search"}, &countingTool{}, &countingTool{name: "read_file"}}
+	got := summarizeTools(tools)
+	want := []prompt.ToolSummary{
+		{Name: "web_search", Source: "plugin"},
+		{Name: "read_file", Source: "plugin"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("summaries = %+v, want %+v", got, want)
+	}
+}
+
+// healthMCP is an MCP manager reporting fixed server health.
+type healthMCP struct {
+	mcp.Manager
+	health []mcp.ServerHealth
+}
+
+func (m healthMCP) Health() []mcp.ServerHealth { return m.health }
+
+func TestUnavailableMCPServers(t *testing.T) {
+	r := &AgentRunner{mcpManager: healthMCP{health: []mcp.ServerHealth{
+		{Name: "github", Status: mcp.ServerStatusConnected},
+		{Name: "jira", Status: mcp.ServerStatusError, Reason: "connection refused"},
+		{Name: "db", Status: mcp.ServerStatusDisconnected},
+	}}}
+	tests := []struct {
+		name    string
+		servers []string
+		want    []prompt.UnavailableServer
+	}{
+		{"all servers", nil, []prompt.UnavailableServer{{Name: "jira", Reason: "connection refused"}, {Name: "db"}}},
+		{"agent servers only", []string{"github", "jira"}, []prompt.UnavailableServer{{Name: "jira", Reason: "connection refused"}}},
+		{"all connected", []string{"github"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.unavailableMCPServers(&entity.Agent{MCPServers: tt.servers})
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("unavailable = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveToolsUnknownAgent(t *testing.T) {
+	r := &AgentRunner{agentRepo: inmemory.NewAgentStore()}
+	if _, err := r.EffectiveTools(context.Background(), "missing"); !errors.Is(err, errno.ErrAgentNotFound) {
+		t.Fatalf("err = %v, want ErrAgentNotFound", err)
+	}
+}
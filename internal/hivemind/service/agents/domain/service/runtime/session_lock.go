@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+)
+
+// sessionLocks serializes work on a session: each run holds its session
+// from loading the history until the result is persisted, so concurrent
+// requests for one session cannot lose or interleave messages. Different
+// sessions never wait for each other.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	sem  chan struct{} // holds one token while the session is locked
+	refs int           // holders plus waiters; the entry is dropped at 0
+}
+
+func newSessionLocks() *sessionLocks {
+	return &sessionLocks{locks: make(map[string]*sessionLock)}
+}
+
+// acquire locks sessionID, waiting for the current holder unless reject is
+// set, in which case a busy session fails with errno.ErrSessionBusy.
+// The returned release must be called exactly once.
+func (s *sessionLocks) acquire(ctx context.Context, sessionID string, reject bool) (release func(), err error) {
+	s.mu.Lock()
+	l, ok := s.locks[sessionID]
+	if !ok {
+		l = &sessionLock{sem: make(chan struct{}, 1)}
+		s.locks[sessionID] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	if reject {
+		select {
+		case l.sem <- struct{}{}:
+		default:
+			s.unref(sessionID, l)
+			return nil, errno.ErrSessionBusy
+		}
+	} else {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			s.unref(sessionID, l)
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.sem
+			s.unref(sessionID, l)
+		})
+	}, nil
+}
+
+func (s *sessionLocks) unref(sessionID string, l *sessionLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(s.locks, sessionID)
+	}
+}
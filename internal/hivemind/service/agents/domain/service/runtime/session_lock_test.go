@@ -0,0 +1,226 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+)
+
+// gatedModel answers each call once the test sends on proceed, reporting the
+// messages it was called with on calls.
+type gatedModel struct {
+	calls   chan []*schema.Message
+	proceed chan struct{}
+}
+
+func newGatedModel() *gatedModel {
+	return &gatedModel{calls: make(chan []*schema.Message, 4), proceed: make(chan struct{}, 4)}
+}
+
+func (m *gatedModel) Generate(ctx context.Context, msgs []*schema.Message, _ ...einoModel.Option) (*schema.Message, error) {
+	m.calls <- msgs
+	select {
+	case <-m.proceed:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return schema.AssistantMessage("re: "+msgs[len(msgs)-1].Content, nil), nil
+}
+
+func (m *gatedModel) Stream(ctx context.Context, msgs []*schema.Message, opts ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	msg, err := m.Generate(ctx, msgs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+}
+
+func (m *gatedModel) WithTools([]*schema.ToolInfo) (einoModel.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// gatedModels is a ModelManager building every model as cm.
+type gatedModels struct {
+	capabilityModels
+	cm *gatedModel
+}
+
+func (f gatedModels) ResolveAlias(ref llmEntity.ModelRef) llmEntity.ModelRef { return ref }
+
+func (f gatedModels) GetProvider(_ context.Context, id string) (*llmEntity.ModelProvider, error) {
+	return nil, fmt.Errorf("provider %s not found", id)
+}
+
+func (f gatedModels) BuildChatModel(context.Context, llmEntity.ModelRef, *llmEntity.LLMParams) (einoModel.BaseChatModel, error) {
+	return f.cm, nil
+}
+
+// newGatedRunner returns a runner for agent "a" whose model is cm.
+func newGatedRunner(t *testing.T, cm *gatedModel) (*AgentRunner, *inmemory.SessionStore) {
+	t.Helper()
+	ctx := context.Background()
+	agents, sessions := inmemory.NewAgentStore(), inmemory.NewSessionStore()
+	if err := agents.Create(ctx, &entity.Agent{ID: "a", ModelRef: llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}}); err != nil {
+		t.Fatal(err)
+	}
+	models := gatedModels{
+		capabilityModels: capabilityModels{models: map[string]llmEntity.ModelAbility{"p/m": {}}},
+		cm:               cm,
+	}
+	r := NewAgentRunner(agents, sessions, inmemory.NewRunStore(),
+		&llm.Module{Manager: models, Fallback: llmService.NewFallbackExecutor(nil, models)},
+		(&plugin.Config{}).Complete().New(), nil, AgentRunnerConfig{})
+	t.Cleanup(r.Close)
+	return r, sessions
+}
+
+// drainRun reads a run's events until the stream ends.
+func drainRun(t *testing.T, sr *schema.StreamReader[*entity.AgentEvent]) {
+	t.Helper()
+	defer sr.Close()
+	for {
+		if _, err := sr.Recv(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("recv: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// waitCall returns the messages of the next model call.
+func waitCall(t *testing.T, cm *gatedModel) []*schema.Message {
+	t.Helper()
+	select {
+	case msgs := <-cm.calls:
+		return msgs
+	case <-time.After(5 * time.Second):
+		t.Fatal("model not called")
+		return nil
+	}
+}
+
+func TestConcurrentSessionRuns(t *testing.T) {
+	ctx := context.Background()
+	cm := newGatedModel()
+	r, sessions := newGatedRunner(t, cm)
+	if err := sessions.Create(ctx, &entity.Session{ID: "s1", AgentID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := r.Run(ctx, &RunRequest{AgentID: "a", SessionID: "s1", Input: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCall(t, cm)
+
+	type started struct {
+		sr  *schema.StreamReader[*entity.AgentEvent]
+		err error
+	}
+	second := make(chan started, 1)
+	go func() {
+		sr, err := r.Run(ctx, &RunRequest{AgentID: "a", SessionID: "s1", Input: "two"})
+		second <- started{sr, err}
+	}()
+
+	// The second run waits for the first to persist its exchange.
+	select {
+	case msgs := <-cm.calls:
+		t.Fatalf("second run reached the model while the first was running (%d messages)", len(msgs))
+	case <-time.After(50 * time.Millisecond):
+	}
+	cm.proceed <- struct{}{}
+	drainRun(t, first)
+
+	msgs := waitCall(t, cm)
+	var history []string
+	for _, m := range msgs {
+		if m.Role != schema.System {
+			history = append(history, m.Content)
+		}
+	}
+	if want := []string{"one", "re: one", "two"}; !slices.Equal(history, want) {
+		t.Fatalf("second run saw %q, want %q", history, want)
+	}
+	cm.proceed <- struct{}{}
+	s := <-second
+	if s.err != nil {
+		t.Fatal(s.err)
+	}
+	drainRun(t, s.sr)
+
+	final, err := sessions.Get(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range final.Messages {
+		got = append(got, m.Content)
+	}
+	if want := []string{"one", "re: one", "two", "re: two"}; !slices.Equal(got, want) {
+		t.Fatalf("history = %q, want %q", got, want)
+	}
+}
+
+func TestNewSessionRunLocksSessionID(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+	}{
+		{"no session id", ""},
+		{"unknown session id", "gone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cm := newGatedModel()
+			r, sessions := newGatedRunner(t, cm)
+
+			sr, err := r.Run(ctx, &RunRequest{AgentID: "a", SessionID: tt.sessionID, Input: "hi"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitCall(t, cm)
+
+			created, err := sessions.ListByAgent(ctx, "a")
+			if err != nil || len(created) != 1 {
+				t.Fatalf("sessions = %v, %v; want the one created by the run", created, err)
+			}
+			id := created[0].ID
+			// Compactions lock the session by its real ID.
+			if _, err := r.sessions.acquire(ctx, id, true); !errors.Is(err, errno.ErrSessionBusy) {
+				t.Fatalf("acquire %s during the run: %v, want ErrSessionBusy", id, err)
+			}
+			if tt.sessionID != "" {
+				release, err := r.sessions.acquire(ctx, tt.sessionID, true)
+				if err != nil {
+					t.Fatalf("requested id %q still locked: %v", tt.sessionID, err)
+				}
+				release()
+			}
+
+			cm.proceed <- struct{}{}
+			drainRun(t, sr)
+			release, err := r.sessions.acquire(ctx, id, true)
+			if err != nil {
+				t.Fatalf("session still locked after the run: %v", err)
+			}
+			release()
+		})
+	}
+}
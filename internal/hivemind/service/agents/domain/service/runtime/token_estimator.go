@@ -1,40 +1,83 @@
 package runtime
 
 import (
+	"unicode"
+
 	"github.com/cloudwego/eino/schema"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 )
 
-// TokenEstimator estimates token counts for messages.
+// TokenEstimator estimates token counts for messages. It is the default Tokenizer.
 //
 // Since the project has no local tokenizer (token, etc.), we use a
-// character-based heuristic: ~4 characters per token for English,
-// ~2 characters per token for CJK, blended to ~3.5 chars/token on average.
+// character-based heuristic. CJK characters and other text tokenize very
+// differently (~1-1.5 vs ~4 chars/token), so runes are counted per script
+// and each bucket is divided by its own ratio.
 //
 // This follows OpenClaw's approach of approximate token counting for
 // context window management — the exact count comes from the LLM API response.
 //
-// The ratio is configurable to allow tuning per model family.
+// The ratios are configurable per model family (see CharsPerTokenForClass).
 type TokenEstimator struct {
-	charsPerToken float64
+	ratio CharsPerToken
+}
+
+// CharsPerToken holds the chars-per-token ratios of a tokenizer family.
+type CharsPerToken struct {
+	// Latin applies to everything that is not CJK (prose, code, punctuation).
+	Latin float64
+	// CJK applies to Han, Hiragana, Katakana and Hangul characters.
+	CJK float64
 }
 
 const (
-	// DefaultCharsPerToken is a reasonable default for mixed-language content.
-	// English ~4, CJK ~2, blended ~3.5. We use 3.5 for safety.
+	// DefaultCharsPerToken is the non-CJK ratio used when the model family is unknown.
+	// English ~4, code ~3. We use 3.5 for safety.
 	DefaultCharsPerToken = 3.5
 
+	// DefaultCJKCharsPerToken is the CJK ratio used when the model family is unknown.
+	// Most tokenizers spend about one token per CJK character.
+	DefaultCJKCharsPerToken = 1.0
+
 	// PerMessageOverhead accounts for message framing overhead
 	// (role tokens, delimiters, etc.) per message.
 	PerMessageOverhead = 4
 )
 
-// NewTokenEstimator creates a new estimator with the given chars-per-token ratio.
-// If ratio <= 0, DefaultCharsPerToken is used.
-func NewTokenEstimator(charsPerToken float64) *TokenEstimator {
-	if charsPerToken <= 0 {
-		charsPerToken = DefaultCharsPerToken
+// DefaultCharsPerTokenRatio is the ratio set for unknown model families.
+var DefaultCharsPerTokenRatio = CharsPerToken{Latin: DefaultCharsPerToken, CJK: DefaultCJKCharsPerToken}
+
+// modelClassCharsPerToken holds per-family ratios. Vendors with Chinese-first
+// tokenizers pack noticeably more CJK characters into a token.
+var modelClassCharsPerToken = map[llmEntity.ModelClass]CharsPerToken{
+	llmEntity.ModelClass_GPT:      {Latin: 4.0, CJK: 1.0},
+	llmEntity.ModelClass_Claude:   {Latin: 3.5, CJK: 1.0},
+	llmEntity.ModelClass_Gemini:   {Latin: 4.0, CJK: 1.2},
+	llmEntity.ModelClass_QWen:     {Latin: 4.0, CJK: 1.5},
+	llmEntity.ModelClass_DeepSeek: {Latin: 4.0, CJK: 1.4},
+	llmEntity.ModelClass_Kimi:     {Latin: 4.0, CJK: 1.5},
+	llmEntity.ModelClass_GLM:      {Latin: 4.0, CJK: 1.6},
+}
+
+// CharsPerTokenForClass returns the ratios for a model family,
+// or DefaultCharsPerTokenRatio when the family is unknown.
+func CharsPerTokenForClass(class llmEntity.ModelClass) CharsPerToken {
+	if r, ok := modelClassCharsPerToken[class]; ok {
+		return r
+	}
+	return DefaultCharsPerTokenRatio
+}
+
+// NewTokenEstimator creates a new estimator with the given ratios.
+// Non-positive ratios fall back to the defaults.
+func NewTokenEstimator(ratio CharsPerToken) *TokenEstimator {
+	if ratio.Latin <= 0 {
+		ratio.Latin = DefaultCharsPerToken
+	}
+	if ratio.CJK <= 0 {
+		ratio.CJK = DefaultCJKCharsPerToken
 	}
-	return &TokenEstimator{charsPerToken: charsPerToken}
+	return &TokenEstimator{ratio: ratio}
 }
 
 // EstimateString estimates tokens for a raw string.
@@ -42,12 +85,24 @@ func (te *TokenEstimator) EstimateString(s string) int {
 	if len(s) == 0 {
 		return 0
 	}
-	// Use rune count for Unicode awareness.
-	runeCount := 0
-	for range s {
-		runeCount++
+	// Use rune counts for Unicode awareness.
+	latin, cjk := 0, 0
+	for _, r := range s {
+		if isCJK(r) {
+			cjk++
+		} else {
+			latin++
+		}
+	}
+	return int(float64(latin)/te.ratio.Latin+float64(cjk)/te.ratio.CJK) + 1
+}
+
+// isCJK reports whether r is a CJK ideograph, kana or hangul syllable.
+func isCJK(r rune) bool {
+	if r < 0x2E80 {
+		return false
 	}
-	return int(float64(runeCount)/te.charsPerToken) + 1
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
 }
 
 // EstimateMessage estimates tokens for a single Eino schema.Message.
@@ -67,6 +122,16 @@ func (te *TokenEstimator) EstimateMessage(msg *schema.Message) int {
 	return tokens
 }
 
+// Count implements Tokenizer.
+func (te *TokenEstimator) Count(s string) int {
+	return te.EstimateString(s)
+}
+
+// CountMessages implements Tokenizer.
+func (te *TokenEstimator) CountMessages(msgs []*schema.Message) int {
+	return te.EstimateMessages(msgs)
+}
+
 // EstimateMessages estimates total tokens for a slice of messages.
 func (te *TokenEstimator) EstimateMessages(msgs []*schema.Message) int {
 	total := 0
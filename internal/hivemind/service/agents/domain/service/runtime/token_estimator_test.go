@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+func TestEstimateString(t *testing.T) {
+	te := NewTokenEstimator(DefaultCharsPerTokenRatio)
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{"empty", "", 0},
+		{"latin", "hello world!", 4},                  // 12 / 3.5 + 1
+		{"han", "今天天气很好", 7},                          // 6 / 1.0 + 1
+		{"kana and hangul", "こんにちは안녕", 8},             // 7 / 1.0 + 1
+		{"mixed", "deploy 到生产环境", 8},                  // 7 / 3.5 + 5 / 1.0 + 1, not 12 / 3.5 + 1
+		{"fullwidth punctuation is not CJK", "，。", 1}, // 2 / 3.5 + 1
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := te.EstimateString(tt.s); got != tt.want {
+				t.Fatalf("EstimateString(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateStringUsesClassRatios(t *testing.T) {
+	const zh = "我们明天上午十点在会议室讨论这个项目的进展情况" // 23 Han characters
+	gpt := NewTokenEstimator(CharsPerTokenForClass(llmEntity.ModelClass_GPT))
+	glm := NewTokenEstimator(CharsPerTokenForClass(llmEntity.ModelClass_GLM))
+	if g, z := gpt.EstimateString(zh), glm.EstimateString(zh); g != 24 || z != 15 {
+		t.Fatalf("GPT, GLM estimates = %d, %d; want 24, 15", g, z)
+	}
+}
+
+func TestCharsPerTokenForClass(t *testing.T) {
+	if got := CharsPerTokenForClass(llmEntity.ModelClass_QWen); got != (CharsPerToken{Latin: 4.0, CJK: 1.5}) {
+		t.Fatalf("QWen ratios = %+v", got)
+	}
+	if got := CharsPerTokenForClass(llmEntity.ModelClass_Other); got != DefaultCharsPerTokenRatio {
+		t.Fatalf("unknown class ratios = %+v, want the defaults", got)
+	}
+}
+
+func TestNewTokenEstimatorDefaults(t *testing.T) {
+	te := NewTokenEstimator(CharsPerToken{Latin: -1})
+	if te.ratio != DefaultCharsPerTokenRatio {
+		t.Fatalf("ratio = %+v, want the defaults", te.ratio)
+	}
+}
+
+func TestEstimateMessage(t *testing.T) {
+	te := NewTokenEstimator(DefaultCharsPerTokenRatio)
+	msg := schema.AssistantMessage("hello world!", []schema.ToolCall{{
+		Function: schema.FunctionCall{Name: "web_search", Arguments: `{"q":"go"}`},
+	}})
+	// overhead 4 + content 4 + empty name 0 + tool name 3 + arguments 3 + framing 4
+	if got := te.EstimateMessage(msg); got != 18 {
+		t.Fatalf("EstimateMessage = %d, want 18", got)
+	}
+	if got := te.EstimateMessages([]*schema.Message{msg, nil, msg}); got != 36 {
+		t.Fatalf("EstimateMessages = %d, want 36", got)
+	}
+}
@@ -0,0 +1,23 @@
+package runtime
+
+import (
+	"github.com/cloudwego/eino/schema"
+)
+
+// Tokenizer counts tokens for context window management.
+//
+// Pruning and compaction decisions are driven by these counts. The default
+// implementation is the char-based TokenEstimator; an exact BPE tokenizer
+// (see the tokenizer package) can be registered per model family via
+// ContextWindowGuard.SetTokenizer.
+type Tokenizer interface {
+	// Count returns the number of tokens in s.
+	Count(s string) int
+
+	// CountMessages returns the number of prompt tokens the messages occupy,
+	// including message framing.
+	CountMessages(msgs []*schema.Message) int
+}
+
+// Compile-time interface check.
+var _ Tokenizer = (*TokenEstimator)(nil)
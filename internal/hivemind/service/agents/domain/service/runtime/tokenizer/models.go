@@ -0,0 +1,31 @@
+package tokenizer
+
+import "strings"
+
+// o200kPrefixes are model name prefixes served with the o200k_base encoding.
+var o200kPrefixes = []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"}
+
+// cl100kPrefixes are model name prefixes served with the cl100k_base encoding.
+var cl100kPrefixes = []string{"gpt-4", "gpt-3.5", "text-embedding-3", "text-embedding-ada-002"}
+
+// EncodingForModel returns the tiktoken encoding used by an OpenAI model, or ""
+// when the model is not a known OpenAI model. A routing prefix such as
+// "openai/gpt-4o" is ignored.
+func EncodingForModel(model string) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	// o200k prefixes first: "gpt-4o" must not match the cl100k "gpt-4".
+	for _, p := range o200kPrefixes {
+		if strings.HasPrefix(name, p) {
+			return EncodingO200K
+		}
+	}
+	for _, p := range cl100kPrefixes {
+		if strings.HasPrefix(name, p) {
+			return EncodingCL100K
+		}
+	}
+	return ""
+}
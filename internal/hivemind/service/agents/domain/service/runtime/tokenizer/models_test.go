@@ -0,0 +1,26 @@
+package tokenizer
+
+import "testing"
+
+func TestEncodingForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", EncodingO200K},
+		{"openai/GPT-4o", EncodingO200K},
+		{"o3-mini", EncodingO200K},
+		{"gpt-4-turbo", EncodingCL100K},
+		{"gpt-3.5-turbo", EncodingCL100K},
+		{"text-embedding-3-small", EncodingCL100K},
+		{"claude-3-5-sonnet", ""},
+		{"qwen-max", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := EncodingForModel(tt.model); got != tt.want {
+				t.Fatalf("EncodingForModel(%q) = %q, want %q", tt.model, got, tt.want)
+			}
+		})
+	}
+}
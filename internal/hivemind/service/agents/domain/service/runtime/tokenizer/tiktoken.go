@@ -0,0 +1,202 @@
+// Package tokenizer provides exact BPE token counting compatible with
+// OpenAI's tiktoken encodings.
+//
+// Ranks are loaded from a tiktoken ranks file (e.g. cl100k_base.tiktoken,
+// one "<base64 token> <rank>" pair per line) so that no network access is
+// needed at run time.
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/dlclark/regexp2"
+)
+
+const (
+	// EncodingCL100K is the encoding of GPT-4 / GPT-3.5 models.
+	EncodingCL100K = "cl100k_base"
+	// EncodingO200K is the encoding of GPT-4o and newer models.
+	EncodingO200K = "o200k_base"
+)
+
+// Pre-tokenization patterns from tiktoken.
+var splitPatterns = map[string]string{
+	EncodingCL100K: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+	EncodingO200K: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+}
+
+const (
+	// tokensPerMessage is the chat framing cost of each message
+	// (<|start|>role<|message|> ... <|end|>), per OpenAI's counting guide.
+	tokensPerMessage = 3
+	// tokensPerName is the extra cost when a message carries a name.
+	tokensPerName = 1
+	// tokensReplyPriming is added once for the assistant reply header.
+	tokensReplyPriming = 3
+	// tokensPerToolCall approximates the framing of a tool call.
+	tokensPerToolCall = 4
+)
+
+// Tiktoken counts tokens with a tiktoken BPE encoding.
+// It is safe for concurrent use.
+type Tiktoken struct {
+	encoding string
+	ranks    map[string]int
+	split    *regexp2.Regexp
+}
+
+// LoadTiktoken reads a tiktoken ranks file for the given encoding
+// (EncodingCL100K or EncodingO200K).
+func LoadTiktoken(path, encoding string) (*Tiktoken, error) {
+	pattern, ok := splitPatterns[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tiktoken encoding %q", encoding)
+	}
+	split, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s split pattern: %w", encoding, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ranks file: %w", err)
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int, 200_000)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		tokenB64, rankStr, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("ranks file line %d: expected \"<token> <rank>\"", lineNo)
+		}
+		token, err := base64.StdEncoding.DecodeString(tokenB64)
+		if err != nil {
+			return nil, fmt.Errorf("ranks file line %d: %w", lineNo, err)
+		}
+		rank, err := strconv.Atoi(rankStr)
+		if err != nil {
+			return nil, fmt.Errorf("ranks file line %d: %w", lineNo, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ranks file: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("ranks file %s is empty", path)
+	}
+
+	return &Tiktoken{encoding: encoding, ranks: ranks, split: split}, nil
+}
+
+// Encoding returns the encoding name.
+func (t *Tiktoken) Encoding() string {
+	return t.encoding
+}
+
+// Count returns the number of tokens in s. Special tokens are counted as plain text.
+func (t *Tiktoken) Count(s string) int {
+	if s == "" {
+		return 0
+	}
+	count := 0
+	m, _ := t.split.FindStringMatch(s)
+	for m != nil {
+		count += t.countPiece([]byte(m.String()))
+		m, _ = t.split.FindNextMatch(m)
+	}
+	return count
+}
+
+// CountMessages returns the prompt tokens of a chat message list,
+// including per-message framing and the reply priming.
+func (t *Tiktoken) CountMessages(msgs []*schema.Message) int {
+	if len(msgs) == 0 {
+		return 0
+	}
+	total := tokensReplyPriming
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		total += tokensPerMessage
+		total += t.Count(string(msg.Role))
+		total += t.Count(msg.Content)
+		if msg.Name != "" {
+			total += tokensPerName + t.Count(msg.Name)
+		}
+		for _, tc := range msg.ToolCalls {
+			total += tokensPerToolCall
+			total += t.Count(tc.Function.Name)
+			total += t.Count(tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// countPiece returns the number of BPE tokens a pre-tokenized piece encodes to,
+// following tiktoken's byte_pair_merge.
+func (t *Tiktoken) countPiece(piece []byte) int {
+	if _, ok := t.ranks[string(piece)]; ok {
+		return 1
+	}
+	if len(piece) == 1 {
+		return 1
+	}
+
+	// parts[i] is the start of the i-th token; rank[i] is the rank of
+	// merging token i with token i+1.
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+	rankOf := func(i int) int {
+		if i+2 >= len(parts) {
+			return math.MaxInt
+		}
+		if r, ok := t.ranks[string(piece[parts[i]:parts[i+2]])]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+	rank := make([]int, len(parts))
+	for i := range rank {
+		rank[i] = rankOf(i)
+	}
+
+	for len(parts) > 2 {
+		minIdx, minRank := -1, math.MaxInt
+		for i := 0; i < len(rank)-1; i++ {
+			if rank[i] < minRank {
+				minIdx, minRank = i, rank[i]
+			}
+		}
+		if minIdx < 0 {
+			break
+		}
+
+		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
+		rank = append(rank[:minIdx+1], rank[minIdx+2:]...)
+		rank[minIdx] = rankOf(minIdx)
+		if minIdx > 0 {
+			rank[minIdx-1] = rankOf(minIdx - 1)
+		}
+	}
+	return len(parts) - 1
+}
@@ -0,0 +1,106 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// writeRanks writes a tiktoken ranks file ranking tokens in order.
+func writeRanks(t *testing.T, tokens ...string) string {
+	t.Helper()
+	var b strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTestTiktoken(t *testing.T) *Tiktoken {
+	t.Helper()
+	tk, err := LoadTiktoken(writeRanks(t, "a", "b", "c", " ", "ab", "abc"), EncodingCL100K)
+	if err != nil {
+		t.Fatalf("LoadTiktoken: %v", err)
+	}
+	return tk
+}
+
+func TestTiktokenCount(t *testing.T) {
+	tk := loadTestTiktoken(t)
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},      // a ranked token
+		{"abab", 2},     // ab + ab
+		{"abcab", 2},    // ab + c + ab, then abc + ab
+		{"xyz", 3},      // unranked bytes count one each
+		{"abc abc", 3},  // "abc" + " abc" -> " " + abc
+		{"ab\n\nab", 4}, // "ab" + "\n\n" (two unranked bytes) + "ab"
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := tk.Count(tt.s); got != tt.want {
+				t.Fatalf("Count(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTiktokenCountMessages(t *testing.T) {
+	tk := loadTestTiktoken(t)
+	if got := tk.CountMessages(nil); got != 0 {
+		t.Fatalf("CountMessages(nil) = %d, want 0", got)
+	}
+
+	user := schema.UserMessage("abc")
+	user.Name = "ab"
+	call := schema.AssistantMessage("", []schema.ToolCall{{Function: schema.FunctionCall{Name: "abc", Arguments: "ab"}}})
+	// priming 3
+	// + user: framing 3 + role "user" 4 + content 1 + name 1+1
+	// + assistant: framing 3 + role "assistant" 9 + tool call 4+1+1
+	want := 3 + (3 + 4 + 1 + 2) + (3 + 9 + 6)
+	if got := tk.CountMessages([]*schema.Message{user, nil, call}); got != want {
+		t.Fatalf("CountMessages = %d, want %d", got, want)
+	}
+}
+
+func TestLoadTiktokenErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		name     string
+		path     string
+		encoding string
+	}{
+		{"unsupported encoding", writeRanks(t, "a"), "p50k_base"},
+		{"missing file", filepath.Join(dir, "missing.tiktoken"), EncodingO200K},
+		{"no rank", write("norank.tiktoken", "YQ==\n"), EncodingCL100K},
+		{"bad base64", write("base64.tiktoken", "!!! 0\n"), EncodingCL100K},
+		{"bad rank", write("rank.tiktoken", "YQ== first\n"), EncodingCL100K},
+		{"empty", write("empty.tiktoken", "\n\n"), EncodingCL100K},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadTiktoken(tt.path, tt.encoding); err == nil {
+				t.Fatal("LoadTiktoken succeeded")
+			}
+		})
+	}
+}
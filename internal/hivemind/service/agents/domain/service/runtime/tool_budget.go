@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// toolBudget counts the tool calls of one run (one choice of an n > 1 run)
+// against the agent's ToolBudget. The counts span every model attempt.
+type toolBudget struct {
+	limits *entity.ToolBudget
+
+	mu      sync.Mutex
+	total   int
+	perTool map[string]int
+}
+
+// acquire records a call of the named tool, or returns the exceeded limit
+// without recording it.
+func (b *toolBudget) acquire(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limits.MaxCalls > 0 && b.total >= b.limits.MaxCalls {
+		return fmt.Errorf("tool call budget exhausted: this run may call tools at most %d times; answer with the information gathered so far", b.limits.MaxCalls)
+	}
+	if limit, ok := b.limits.PerTool[name]; ok && limit > 0 && b.perTool[name] >= limit {
+		return fmt.Errorf("tool %q call budget exhausted: it may be called at most %d times per run; use other tools or answer with the information gathered so far", name, limit)
+	}
+	b.total++
+	b.perTool[name]++
+	return nil
+}
+
+// budgetedTool enforces a run's toolBudget on an invokable tool. A call over
+// budget is not executed; its result is the limit error, so the model sees
+// it as the tool's output and can adapt instead of the run failing.
+type budgetedTool struct {
+	tool.InvokableTool
+	name   string
+	budget *toolBudget
+}
+
+func (t *budgetedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if err := t.budget.acquire(t.name); err != nil {
+		out, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(out), nil
+	}
+	return t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+}
+
+// withToolBudget wraps the invokable tools of a run with a shared budget.
+// Tools are returned unchanged when the agent has no budget.
+func withToolBudget(ctx context.Context, tools []tool.BaseTool, limits *entity.ToolBudget) []tool.BaseTool {
+	if limits == nil || (limits.MaxCalls <= 0 && len(limits.PerTool) == 0) {
+		return tools
+	}
+	budget := &toolBudget{limits: limits, perTool: make(map[string]int)}
+
+	wrapped := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil || info == nil {
+			wrapped = append(wrapped, t)
+			continue
+		}
+		wrapped = append(wrapped, &budgetedTool{InvokableTool: invokable, name: info.Name, budget: budget})
+	}
+	return wrapped
+}
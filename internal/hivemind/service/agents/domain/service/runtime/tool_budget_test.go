@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+)
+
+// countingTool counts the calls that reach it.
+type countingTool struct {
+	name  string
+	calls int
+}
+
+func (c *countingTool) Info(context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: c.name}, nil
+}
+
+func (c *countingTool) InvokableRun(context.Context, string, ...tool.Option) (string, error) {
+	c.calls++
+	return "ok", nil
+}
+
+func invoke(t *testing.T, tools []tool.BaseTool) string {
+	t.Helper()
+	out, err := tools[0].(tool.InvokableTool).InvokableRun(context.Background(), "{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestToolBudgetLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  *entity.ToolBudget
+		allowed int
+	}{
+		{"no budget", nil, 5},
+		{"max calls", &entity.ToolBudget{MaxCalls: 2}, 2},
+		{"per tool", &entity.ToolBudget{PerTool: map[string]int{"send_mail": 1}}, 1},
+		{"per tool of another tool", &entity.ToolBudget{PerTool: map[string]int{"other": 1}}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := &countingTool{name: "send_mail"}
+			tools := withToolBudget(context.Background(), []tool.BaseTool{ct}, tt.limits)
+			for i := 0; i < 5; i++ {
+				out := invoke(t, tools)
+				if over := strings.Contains(out, "budget exhausted"); over != (i >= tt.allowed) {
+					t.Fatalf("call %d: result %q", i, out)
+				}
+			}
+			if ct.calls != tt.allowed {
+				t.Fatalf("%d calls reached the tool, want %d", ct.calls, tt.allowed)
+			}
+		})
+	}
+}
+
+// Every choice of a run (see executeChoices) goes through Execute, which
+// budgets its tools separately: the choices do not drain a shared budget.
+func TestToolBudgetPerChoice(t *testing.T) {
+	ct := &countingTool{name: "send_mail"}
+	limits := &entity.ToolBudget{MaxCalls: 1}
+	choice0 := withToolBudget(context.Background(), []tool.BaseTool{ct}, limits)
+	choice1 := withToolBudget(context.Background(), []tool.BaseTool{ct}, limits)
+
+	if out := invoke(t, choice0); out != "ok" {
+		t.Fatalf("choice 0: %q", out)
+	}
+	if out := invoke(t, choice1); out != "ok" {
+		t.Fatalf("choice 1 limited by choice 0's calls: %q", out)
+	}
+	if out := invoke(t, choice0); !strings.Contains(out, "budget exhausted") {
+		t.Fatalf("choice 0 over budget: %q", out)
+	}
+	if ct.calls != 2 {
+		t.Fatalf("%d calls, want one per choice", ct.calls)
+	}
+}
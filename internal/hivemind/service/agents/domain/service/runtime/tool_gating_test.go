@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
+	llmEntity "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	llmService "github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+)
+
+// capabilityModels is a ModelManager knowing models by "provider/model" name.
+type capabilityModels struct {
+	llmService.ModelManager
+	models map[string]llmEntity.ModelAbility
+}
+
+func (f capabilityModels) GetModelByRef(_ context.Context, ref llmEntity.ModelRef) (*llmEntity.ModelInstance, error) {
+	ability, ok := f.models[ref.String()]
+	if !ok {
+		return nil, fmt.Errorf("model %s not found", ref)
+	}
+	return &llmEntity.ModelInstance{ProviderID: ref.ProviderID, ModelID: ref.ModelID, Capability: ability}, nil
+}
+
+func (f capabilityModels) ResolveCompat(context.Context, llmEntity.ModelRef) (*llmEntity.ModelCompatConfig, error) {
+	return nil, nil
+}
+
+func TestModelToolSupportChecksFallbackChain(t *testing.T) {
+	r := &AgentRunner{llmModule: &llm.Module{Manager: capabilityModels{models: map[string]llmEntity.ModelAbility{
+		"p/vision": {FunctionCall: true, ImageUnderstanding: true},
+		"p/text":   {FunctionCall: true},
+		"p/plain":  {},
+	}}}}
+	ref := func(model string) llmEntity.ModelRef { return llmEntity.ModelRef{ProviderID: "p", ModelID: model} }
+
+	tests := []struct {
+		name               string
+		agent              *entity.Agent
+		wantFC, wantVision bool
+	}{
+		{"vision model", &entity.Agent{ModelRef: ref("vision")}, true, true},
+		{"text model", &entity.Agent{ModelRef: ref("text")}, true, false},
+		{"vision primary, text fallback",
+			&entity.Agent{ModelRef: ref("vision"), Fallback: llmEntity.FallbackConfig{Primary: ref("vision"), Fallbacks: []llmEntity.ModelRef{ref("text")}}}, true, false},
+		{"vision chain",
+			&entity.Agent{ModelRef: ref("vision"), Fallback: llmEntity.FallbackConfig{Primary: ref("vision"), Fallbacks: []llmEntity.ModelRef{ref("vision")}}}, true, true},
+		{"fallback primary only", &entity.Agent{Fallback: llmEntity.FallbackConfig{Primary: ref("plain")}}, false, false},
+		{"unknown model", &entity.Agent{ModelRef: ref("missing")}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, vision := r.modelToolSupport(context.Background(), tt.agent)
+			if fc != tt.wantFC || vision != tt.wantVision {
+				t.Fatalf("function call, vision = %v, %v; want %v, %v", fc, vision, tt.wantFC, tt.wantVision)
+			}
+		})
+	}
+}
+
+// toolsPlugin registers a tool per name.
+type toolsPlugin struct{ names []string }
+
+func (p *toolsPlugin) Name() string { return "tools" }
+
+func (p *toolsPlugin) Init(api plugin.PluginAPI) error {
+	for _, name := range p.names {
+		api.RegisterTool(plugin.ToolDefinition{Name: name})
+	}
+	return nil
+}
+
+// newToolsFramework returns a plugin framework whose one plugin registers names.
+func newToolsFramework(t *testing.T, names ...string) *plugin.Framework {
+	t.Helper()
+	fw := (&plugin.Config{}).Complete().New()
+	factory := func(plugin.PluginArgs, plugin.Handle) (plugin.Plugin, error) {
+		return &toolsPlugin{names: names}, nil
+	}
+	if err := fw.RegisterFactory(plugin.Definition{ID: "tools", Name: "tools"}, factory, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return fw
+}
+
+// toolsMCP is an MCP manager serving fixed tools.
+type toolsMCP struct {
+	mcp.Manager
+	tools []tool.BaseTool
+}
+
+func (m toolsMCP) GetAllTools() []tool.BaseTool { return m.tools }
+
+func TestResolveToolsPolicy(t *testing.T) {
+	r := &AgentRunner{
+		llmModule:       &llm.Module{Manager: capabilityModels{models: map[string]llmEntity.ModelAbility{"p/m": {FunctionCall: true}}}},
+		pluginFramework: newToolsFramework(t, "web_search", "web_fetch", "read_file"),
+		mcpManager:      toolsMCP{tools: []tool.BaseTool{&countingTool{name: "github__search"}, &countingTool{name: "github__issues"}}},
+	}
+	ref := llmEntity.ModelRef{ProviderID: "p", ModelID: "m"}
+
+	tests := []struct {
+		name  string
+		agent *entity.Agent
+		want  []string
+	}{
+		{"all tools", &entity.Agent{ModelRef: ref},
+			[]string{"read_file", "web_fetch", "web_search", "github__search", "github__issues"}},
+		{"allowlist", &entity.Agent{ModelRef: ref, ToolMode: entity.ToolModeAllowlist, Tools: []string{"web_search", "unregistered"}},
+			[]string{"web_search", "github__search", "github__issues"}},
+		{"denied plugin and MCP tools", &entity.Agent{ModelRef: ref, DeniedTools: []string{"web_fetch", "github__issues"}},
+			[]string{"read_file", "web_search", "github__search"}},
+		{"denied wins over allowlist", &entity.Agent{ModelRef: ref, ToolMode: entity.ToolModeAllowlist, Tools: []string{"web_search", "read_file"}, DeniedTools: []string{"read_file"}},
+			[]string{"web_search", "github__search", "github__issues"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tl := range r.resolveTools(context.Background(), tt.agent) {
+				info, err := tl.Info(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, info.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("tools = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
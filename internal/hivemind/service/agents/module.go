@@ -10,6 +10,7 @@ import (
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime"
 	boltdbStore "github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/boltdb"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/store/sqlstore"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/mcp"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
@@ -43,15 +44,53 @@ type Config struct {
 	// Default: 3.
 	KeepRecentTurns int `json:"keep_recent_turns,omitempty"`
 
+	// CompactionWorkers is the number of background workers running
+	// proactive compaction after turns. Default: 2.
+	CompactionWorkers int `json:"compaction_workers,omitempty"`
+
+	// Timezone is the IANA zone (e.g., "Asia/Shanghai") used for the current
+	// time in system prompts. Agents may override it via AgentPersona.Timezone.
+	// Default: "" (server local time).
+	Timezone string `json:"timezone,omitempty"`
+
+	// TokenizerFile is a tiktoken ranks file used for exact token counts on
+	// OpenAI models. Default: "" (char-based estimate for all models).
+	TokenizerFile string `json:"tokenizer_file,omitempty"`
+
+	// TokenizerEncoding names the encoding of TokenizerFile.
+	// Default: "cl100k_base".
+	TokenizerEncoding string `json:"tokenizer_encoding,omitempty"`
+
+	// TokenizerFiles maps a tiktoken encoding (cl100k_base, o200k_base) to its
+	// ranks file. Each model uses the file of its encoding when present, so
+	// e.g. GPT-4o and GPT-4 are both counted exactly. Default: none.
+	TokenizerFiles map[string]string `json:"tokenizer_files,omitempty"`
+
+	// RejectConcurrentSessionRuns fails a request for a session that already
+	// has a run in progress (HTTP 429) instead of queueing it until that run
+	// has finished. Default: false (queue).
+	RejectConcurrentSessionRuns bool `json:"reject_concurrent_session_runs,omitempty"`
+
 	// --- Storage (P0) ---
 
-	// StoreType selects the persistence backend: "inmemory" or "boltdb".
+	// StoreType selects the persistence backend: "inmemory", "boltdb",
+	// "sqlite" or "postgres". A postgres store can be shared by several
+	// Hivemind instances, and concurrent session updates across them are
+	// detected through Session.Version. Other state stays per process:
+	// session run serialization, the idempotency cache and rate limits, so a
+	// session's requests should still be routed to one instance (sticky
+	// sessions) to queue instead of failing with a version conflict.
 	// Default: "inmemory".
 	StoreType string `json:"store_type,omitempty"`
 
 	// BoltDBPath is the file path for BoltDB storage (when StoreType="boltdb").
 	// Default: "data/eidolon.db".
 	BoltDBPath string `json:"boltdb_path,omitempty"`
+
+	// DSN is the database to connect to when StoreType is "sqlite" (a file
+	// path or go-sqlite3 DSN; default: "data/eidolon.sqlite") or "postgres"
+	// (a pgx connection string; required).
+	DSN string `json:"dsn,omitempty"`
 }
 
 // CompletedConfig is the validated and completed configuration.
@@ -79,12 +118,24 @@ func (c *Config) Complete() CompletedConfig {
 	if c.KeepRecentTurns <= 0 {
 		c.KeepRecentTurns = 3
 	}
+	if c.CompactionWorkers <= 0 {
+		c.CompactionWorkers = runtime.DefaultCompactionWorkers
+	}
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			logger.Warn("[Agents] invalid timezone %q, using server local time: %v", c.Timezone, err)
+			c.Timezone = ""
+		}
+	}
 	if c.StoreType == "" {
 		c.StoreType = "inmemory"
 	}
 	if c.BoltDBPath == "" {
 		c.BoltDBPath = "data/eidolon.db"
 	}
+	if c.DSN == "" && c.StoreType == sqlstore.DialectSQLite {
+		c.DSN = "data/eidolon.sqlite"
+	}
 	return CompletedConfig{c}
 }
 
@@ -103,19 +154,25 @@ type Dependencies struct {
 type Module struct {
 	Service service.AgentService
 	Runner  *runtime.AgentRunner
-	boltDB  *boltdbStore.DB // nil when using inmemory store
+	boltDB  *boltdbStore.DB // nil unless using the boltdb store
+	sqlDB   *sqlstore.DB    // nil unless using a SQL store
 }
 
 // Close releases resources held by the module (e.g., BoltDB handle).
+// Background compaction is stopped first, as it writes to the store.
 func (m *Module) Close() error {
+	m.Runner.Close()
 	if m.boltDB != nil {
 		return m.boltDB.Close()
 	}
+	if m.sqlDB != nil {
+		return m.sqlDB.Close()
+	}
 	return nil
 }
 
 // New creates and initializes the Agents module from a completed config.
-func (c CompletedConfig) New(_ context.Context, deps Dependencies) (*Module, error) {
+func (c CompletedConfig) New(ctx context.Context, deps Dependencies) (*Module, error) {
 	logger.Info("[Agents] creating Agents module...")
 
 	if deps.LLM == nil {
@@ -131,6 +188,7 @@ func (c CompletedConfig) New(_ context.Context, deps Dependencies) (*Module, err
 		sessionStore repo.SessionRepository
 		runStore     repo.RunRepository
 		boltDB       *boltdbStore.DB
+		sqlDB        *sqlstore.DB
 	)
 
 	switch c.StoreType {
@@ -144,6 +202,16 @@ func (c CompletedConfig) New(_ context.Context, deps Dependencies) (*Module, err
 		sessionStore = boltdbStore.NewSessionStore(boltDB)
 		runStore = boltdbStore.NewRunStore(boltDB)
 		logger.Info("[Agents] using BoltDB store at %s", c.BoltDBPath)
+	case sqlstore.DialectSQLite, sqlstore.DialectPostgres:
+		var err error
+		sqlDB, err = sqlstore.Open(ctx, c.StoreType, c.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s store: %w", c.StoreType, err)
+		}
+		agentStore = sqlstore.NewAgentStore(sqlDB)
+		sessionStore = sqlstore.NewSessionStore(sqlDB)
+		runStore = sqlstore.NewRunStore(sqlDB)
+		logger.Info("[Agents] using %s store", c.StoreType)
 	default:
 		agentStore = inmemory.NewAgentStore()
 		sessionStore = inmemory.NewSessionStore()
@@ -166,11 +234,18 @@ func (c CompletedConfig) New(_ context.Context, deps Dependencies) (*Module, err
 			MaxHistoryTurns:     c.MaxHistoryTurns,
 			CompactionThreshold: c.CompactionThreshold,
 			KeepRecentTurns:     c.KeepRecentTurns,
+			CompactionWorkers:   c.CompactionWorkers,
+			Timezone:            c.Timezone,
+			TokenizerFile:       c.TokenizerFile,
+			TokenizerEncoding:   c.TokenizerEncoding,
+			TokenizerFiles:      c.TokenizerFiles,
+
+			RejectConcurrentSessionRuns: c.RejectConcurrentSessionRuns,
 		},
 	)
 
 	// Application service layer.
-	svc := service.NewAgentService(agentStore, sessionStore, runStore, runner)
+	svc := service.NewAgentService(agentStore, sessionStore, runStore, runner, deps.LLM.Manager)
 
 	logger.Info("[Agents] Agents module initialized (store=%s, max_turns=%d, timeout=%s, retries=%d, history_limit=%d, compaction_threshold=%.1f)",
 		c.StoreType, c.DefaultMaxTurns, c.RunTimeout, c.MaxRetries, c.MaxHistoryTurns, c.CompactionThreshold)
@@ -179,5 +254,6 @@ func (c CompletedConfig) New(_ context.Context, deps Dependencies) (*Module, err
 		Service: svc,
 		Runner:  runner,
 		boltDB:  boltDB,
+		sqlDB:   sqlDB,
 	}, nil
 }
@@ -5,13 +5,17 @@ import (
 )
 
 var (
-	ErrAgentNotFound       = errors.New("agent not found")
-	ErrSessionNotFound     = errors.New("session not found")
-	ErrRunNotFound         = errors.New("run not found")
-	ErrRunAlreadyDone      = errors.New("run already done")
-	ErrNoToolsAvailable    = errors.New("no tools available")
-	ErrMaxTurnsExceeded    = errors.New("max turns exceeded")
-	ErrAborted             = errors.New("run aborted")
-	ErrContextOverflow     = errors.New("context overflow")
-	ErrModelNotToolCapable = errors.New("model not tool capable")
+	ErrAgentNotFound         = errors.New("agent not found")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrRunNotFound           = errors.New("run not found")
+	ErrRunAlreadyDone        = errors.New("run already done")
+	ErrNoToolsAvailable      = errors.New("no tools available")
+	ErrMaxTurnsExceeded      = errors.New("max turns exceeded")
+	ErrAborted               = errors.New("run aborted")
+	ErrContextOverflow       = errors.New("context overflow")
+	ErrModelNotToolCapable   = errors.New("model not tool capable")
+	ErrModelNotVisionCapable = errors.New("model not vision capable")
+	ErrModelNotFound         = errors.New("model not found")
+	ErrSessionBusy           = errors.New("session has a run in progress")
+	ErrVersionConflict       = errors.New("session was modified concurrently")
 )
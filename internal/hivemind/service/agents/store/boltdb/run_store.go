@@ -80,3 +80,34 @@ func (s *RunStore) ListBySession(_ context.Context, sessionID string) ([]*entity
 	}
 	return runs, nil
 }
+
+func (s *RunStore) DeleteBySession(_ context.Context, sessionID string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRunStore)
+		var keys [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			var r entity.Run
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("failed to unmarshal run: %w", err)
+			}
+			if r.SessionID == sessionID {
+				// Keys are only valid for the life of the transaction and must
+				// not be deleted while iterating, so collect copies first.
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete runs by session %q: %w", sessionID, err)
+	}
+	return nil
+}
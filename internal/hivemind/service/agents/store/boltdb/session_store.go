@@ -3,9 +3,11 @@ package boltdb
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/boltdb/bolt"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
 	"github.com/kiosk404/echoryn/pkg/utils/json"
 )
 
@@ -47,17 +49,34 @@ func (s *SessionStore) Get(_ context.Context, id string) (*entity.Session, error
 }
 
 func (s *SessionStore) Update(_ context.Context, session *entity.Session) error {
-	return s.boltDB.Update(func(tx *bolt.Tx) error {
+	next := *session
+	next.Version++
+	err := s.boltDB.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bucketSessionStore)
-		if b.Get([]byte(session.ID)) == nil {
+		existing := b.Get([]byte(session.ID))
+		if existing == nil {
 			return fmt.Errorf("session %q not found", session.ID)
 		}
-		data, err := json.Marshal(session)
+		var stored struct {
+			Version int64 `json:"version"`
+		}
+		if err := json.Unmarshal(existing, &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+		if stored.Version != session.Version {
+			return errno.ErrVersionConflict
+		}
+		data, err := json.Marshal(&next)
 		if err != nil {
 			return fmt.Errorf("failed to marshal session: %w", err)
 		}
 		return b.Put([]byte(session.ID), data)
 	})
+	if err != nil {
+		return err
+	}
+	session.Version = next.Version
+	return nil
 }
 
 func (s *SessionStore) Delete(_ context.Context, id string) error {
@@ -87,3 +106,27 @@ func (s *SessionStore) ListByAgent(_ context.Context, agentID string) ([]*entity
 	}
 	return sessions, nil
 }
+
+func (s *SessionStore) List(_ context.Context, agentID string) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	err := s.boltDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSessionStore)
+		return b.ForEach(func(k, v []byte) error {
+			var session entity.Session
+			if err := json.Unmarshal(v, &session); err != nil {
+				return fmt.Errorf("failed to unmarshal session: %w", err)
+			}
+			if agentID == "" || session.AgentID == agentID {
+				sessions = append(sessions, &session)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
@@ -54,3 +54,14 @@ func (s *RunStore) ListBySession(_ context.Context, sessionID string) ([]*entity
 	}
 	return runs, nil
 }
+
+func (s *RunStore) DeleteBySession(_ context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, run := range s.runs {
+		if run.SessionID == sessionID {
+			delete(s.runs, id)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package inmemory
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
@@ -9,6 +10,8 @@ import (
 )
 
 // SessionStore is an in-memory implementation of the SessionStore interface.
+// It keeps copies of the sessions, so that changes reach the store only
+// through Update.
 type SessionStore struct {
 	mu       sync.RWMutex
 	sessions map[string]*entity.Session
@@ -24,7 +27,7 @@ func NewSessionStore() *SessionStore {
 func (s *SessionStore) Create(_ context.Context, session *entity.Session) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.sessions[session.ID] = session
+	s.sessions[session.ID] = session.Clone()
 	return nil
 }
 
@@ -35,17 +38,22 @@ func (s *SessionStore) Get(_ context.Context, id string) (*entity.Session, error
 	if !ok {
 		return nil, errno.ErrSessionNotFound
 	}
-	return session, nil
+	return session.Clone(), nil
 }
 
 func (s *SessionStore) Update(_ context.Context, session *entity.Session) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	// Check if the session exists
-	if _, ok := s.sessions[session.ID]; !ok {
+	stored, ok := s.sessions[session.ID]
+	if !ok {
 		return errno.ErrSessionNotFound
 	}
-	s.sessions[session.ID] = session
+	if stored.Version != session.Version {
+		return errno.ErrVersionConflict
+	}
+	session.Version++
+	s.sessions[session.ID] = session.Clone()
 	return nil
 }
 
@@ -66,8 +74,23 @@ func (s *SessionStore) ListByAgent(_ context.Context, agentID string) ([]*entity
 	sessions := make([]*entity.Session, 0)
 	for _, session := range s.sessions {
 		if session.AgentID == agentID {
-			sessions = append(sessions, session)
+			sessions = append(sessions, session.Clone())
+		}
+	}
+	return sessions, nil
+}
+
+func (s *SessionStore) List(_ context.Context, agentID string) ([]*entity.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := make([]*entity.Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if agentID == "" || session.AgentID == agentID {
+			sessions = append(sessions, session.Clone())
 		}
 	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
 	return sessions, nil
 }
@@ -0,0 +1,138 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+)
+
+func TestSessionStoreVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewSessionStore()
+	if err := s.Create(ctx, &entity.Session{ID: "s", AgentID: "a", UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Two writers load the same version.
+	first, _ := s.Get(ctx, "s")
+	second, _ := s.Get(ctx, "s")
+
+	first.AppendMessage(entity.NewUserMessage("from first"))
+	if err := s.Update(ctx, first); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if first.Version != 1 {
+		t.Fatalf("version after update = %d, want 1", first.Version)
+	}
+
+	second.AppendMessage(entity.NewUserMessage("from second"))
+	if err := s.Update(ctx, second); !errors.Is(err, errno.ErrVersionConflict) {
+		t.Fatalf("stale update: %v, want ErrVersionConflict", err)
+	}
+	if second.Version != 0 {
+		t.Fatalf("rejected update changed the caller's version to %d", second.Version)
+	}
+
+	// The retry reloads and re-applies its change.
+	retry, _ := s.Get(ctx, "s")
+	retry.AppendMessage(entity.NewUserMessage("from second"))
+	if err := s.Update(ctx, retry); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	final, _ := s.Get(ctx, "s")
+	if final.Version != 2 || len(final.Messages) != 2 ||
+		final.Messages[0].Content != "from first" || final.Messages[1].Content != "from second" {
+		t.Fatalf("final = version %d, %d messages", final.Version, len(final.Messages))
+	}
+
+	if err := s.Update(ctx, &entity.Session{ID: "missing"}); !errors.Is(err, errno.ErrSessionNotFound) {
+		t.Fatalf("update missing: %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSessionStoreKeepsCopies(t *testing.T) {
+	ctx := context.Background()
+	s := NewSessionStore()
+	session := &entity.Session{ID: "s", AgentID: "a"}
+	if err := s.Create(ctx, session); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Changes reach the store only through Update.
+	session.AppendMessage(entity.NewUserMessage("unsaved"))
+	got, _ := s.Get(ctx, "s")
+	if len(got.Messages) != 0 {
+		t.Fatalf("stored session changed without Update: %d messages", len(got.Messages))
+	}
+	got.AppendMessage(entity.NewUserMessage("also unsaved"))
+	if again, _ := s.Get(ctx, "s"); len(again.Messages) != 0 {
+		t.Fatalf("Get returned the stored session itself: %d messages", len(again.Messages))
+	}
+}
+
+func TestSessionStoreList(t *testing.T) {
+	ctx := context.Background()
+	s := NewSessionStore()
+	now := time.Now()
+	for _, session := range []*entity.Session{
+		{ID: "old", AgentID: "a", UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: "new", AgentID: "a", UpdatedAt: now},
+		{ID: "other", AgentID: "b", UpdatedAt: now.Add(-time.Hour)},
+	} {
+		if err := s.Create(ctx, session); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	tests := []struct {
+		agentID string
+		want    []string
+	}{
+		{"", []string{"new", "other", "old"}},
+		{"a", []string{"new", "old"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		sessions, err := s.List(ctx, tt.agentID)
+		if err != nil {
+			t.Fatalf("list %q: %v", tt.agentID, err)
+		}
+		var got []string
+		for _, session := range sessions {
+			got = append(got, session.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("List(%q) = %v, want %v", tt.agentID, got, tt.want)
+		}
+	}
+}
+
+func TestRunStoreDeleteBySession(t *testing.T) {
+	ctx := context.Background()
+	s := NewRunStore()
+	for _, run := range []*entity.Run{
+		{ID: "r1", SessionID: "s1"},
+		{ID: "r2", SessionID: "s1"},
+		{ID: "r3", SessionID: "s2"},
+	} {
+		if err := s.Create(ctx, run); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	if err := s.DeleteBySession(ctx, "s1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if runs, _ := s.ListBySession(ctx, "s1"); len(runs) != 0 {
+		t.Fatalf("%d runs of the deleted session left", len(runs))
+	}
+	if runs, _ := s.ListBySession(ctx, "s2"); len(runs) != 1 || runs[0].ID != "r3" {
+		t.Fatalf("runs of another session = %v, want [r3]", runs)
+	}
+}
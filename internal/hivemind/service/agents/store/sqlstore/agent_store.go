@@ -0,0 +1,103 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// AgentStore implements the AgentRepository interface on a SQL database.
+type AgentStore struct {
+	db *DB
+}
+
+// NewAgentStore creates a new SQL-backed AgentStore.
+func NewAgentStore(db *DB) *AgentStore {
+	return &AgentStore{db: db}
+}
+
+// Create adds a new agent to the store.
+func (s *AgentStore) Create(ctx context.Context, agent *entity.Agent) error {
+	data, err := json.Marshal(agent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent: %w", err)
+	}
+	_, err = s.db.exec(ctx,
+		`INSERT INTO `+tableAgents+` (id, data, created_at) VALUES (?, ?, ?)`,
+		agent.ID, string(data), agent.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create agent %q: %w", agent.ID, err)
+	}
+	return nil
+}
+
+// Get retrieves an agent by its ID.
+func (s *AgentStore) Get(ctx context.Context, id string) (*entity.Agent, error) {
+	var data []byte
+	err := s.db.queryRow(ctx, `SELECT data FROM `+tableAgents+` WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errno.ErrAgentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent %q: %w", id, err)
+	}
+	var agent entity.Agent
+	if err := json.Unmarshal(data, &agent); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal agent: %w", err)
+	}
+	return &agent, nil
+}
+
+// Update replaces an existing agent.
+func (s *AgentStore) Update(ctx context.Context, agent *entity.Agent) error {
+	data, err := json.Marshal(agent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent: %w", err)
+	}
+	res, err := s.db.exec(ctx, `UPDATE `+tableAgents+` SET data = ? WHERE id = ?`, string(data), agent.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update agent %q: %w", agent.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errno.ErrAgentNotFound
+	}
+	return nil
+}
+
+// Delete removes an agent by its ID.
+func (s *AgentStore) Delete(ctx context.Context, id string) error {
+	res, err := s.db.exec(ctx, `DELETE FROM `+tableAgents+` WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete agent %q: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errno.ErrAgentNotFound
+	}
+	return nil
+}
+
+// List returns all agents, oldest first.
+func (s *AgentStore) List(ctx context.Context) ([]*entity.Agent, error) {
+	rows, err := s.db.query(ctx, `SELECT data FROM `+tableAgents+` ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	agents := make([]*entity.Agent, 0)
+	err = scanAll(rows, func(data []byte) error {
+		var agent entity.Agent
+		if err := json.Unmarshal(data, &agent); err != nil {
+			return fmt.Errorf("failed to unmarshal agent: %w", err)
+		}
+		agents = append(agents, &agent)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	return agents, nil
+}
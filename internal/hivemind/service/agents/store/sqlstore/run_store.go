@@ -0,0 +1,95 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// RunStore is a SQL-backed store for agent runs.
+type RunStore struct {
+	db *DB
+}
+
+// NewRunStore creates a new RunStore.
+func NewRunStore(db *DB) *RunStore {
+	return &RunStore{db: db}
+}
+
+func (s *RunStore) Create(ctx context.Context, run *entity.Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	_, err = s.db.exec(ctx,
+		`INSERT INTO `+tableRuns+` (id, session_id, data, created_at) VALUES (?, ?, ?, ?)`,
+		run.ID, run.SessionID, string(data), run.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create run %q: %w", run.ID, err)
+	}
+	return nil
+}
+
+func (s *RunStore) Get(ctx context.Context, id string) (*entity.Run, error) {
+	var data []byte
+	err := s.db.queryRow(ctx, `SELECT data FROM `+tableRuns+` WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errno.ErrRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run %q: %w", id, err)
+	}
+	var run entity.Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run: %w", err)
+	}
+	return &run, nil
+}
+
+func (s *RunStore) Update(ctx context.Context, run *entity.Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+	res, err := s.db.exec(ctx, `UPDATE `+tableRuns+` SET data = ? WHERE id = ?`, string(data), run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update run %q: %w", run.ID, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errno.ErrRunNotFound
+	}
+	return nil
+}
+
+func (s *RunStore) ListBySession(ctx context.Context, sessionID string) ([]*entity.Run, error) {
+	rows, err := s.db.query(ctx,
+		`SELECT data FROM `+tableRuns+` WHERE session_id = ? ORDER BY created_at`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs by session %q: %w", sessionID, err)
+	}
+	var runs []*entity.Run
+	err = scanAll(rows, func(data []byte) error {
+		var r entity.Run
+		if err := json.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("failed to unmarshal run: %w", err)
+		}
+		runs = append(runs, &r)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs by session %q: %w", sessionID, err)
+	}
+	return runs, nil
+}
+
+func (s *RunStore) DeleteBySession(ctx context.Context, sessionID string) error {
+	if _, err := s.db.exec(ctx, `DELETE FROM `+tableRuns+` WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete runs by session %q: %w", sessionID, err)
+	}
+	return nil
+}
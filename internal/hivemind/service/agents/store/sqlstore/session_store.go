@@ -0,0 +1,146 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// SessionStore implements the SessionRepository interface on a SQL database.
+// Sessions are stored with their messages serialized; Update is a
+// compare-and-swap on the version column, so concurrent writers on different
+// instances are detected.
+type SessionStore struct {
+	db *DB
+}
+
+// NewSessionStore creates a new SQL-backed SessionStore.
+func NewSessionStore(db *DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+func (s *SessionStore) Create(ctx context.Context, session *entity.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	_, err = s.db.exec(ctx,
+		`INSERT INTO `+tableSessions+` (id, agent_id, version, data, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		session.ID, session.AgentID, session.Version, string(data), session.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to create session %q: %w", session.ID, err)
+	}
+	return nil
+}
+
+func (s *SessionStore) Get(ctx context.Context, id string) (*entity.Session, error) {
+	var data []byte
+	err := s.db.queryRow(ctx, `SELECT data FROM `+tableSessions+` WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errno.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session %q: %w", id, err)
+	}
+	var session entity.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+func (s *SessionStore) Update(ctx context.Context, session *entity.Session) error {
+	next := *session
+	next.Version++
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	res, err := s.db.exec(ctx,
+		`UPDATE `+tableSessions+` SET agent_id = ?, version = ?, data = ?, updated_at = ? WHERE id = ? AND version = ?`,
+		next.AgentID, next.Version, string(data), next.UpdatedAt.UnixMilli(), session.ID, session.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update session %q: %w", session.ID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update session %q: %w", session.ID, err)
+	}
+	if n == 0 {
+		// Either the session is gone or another writer bumped its version.
+		var exists int
+		err := s.db.queryRow(ctx, `SELECT 1 FROM `+tableSessions+` WHERE id = ?`, session.ID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errno.ErrSessionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update session %q: %w", session.ID, err)
+		}
+		return errno.ErrVersionConflict
+	}
+	session.Version = next.Version
+	return nil
+}
+
+func (s *SessionStore) Delete(ctx context.Context, id string) error {
+	res, err := s.db.exec(ctx, `DELETE FROM `+tableSessions+` WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session %q: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errno.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *SessionStore) ListByAgent(ctx context.Context, agentID string) ([]*entity.Session, error) {
+	rows, err := s.db.query(ctx,
+		`SELECT data FROM `+tableSessions+` WHERE agent_id = ? ORDER BY updated_at DESC`, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions by agent %q: %w", agentID, err)
+	}
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions by agent %q: %w", agentID, err)
+	}
+	return sessions, nil
+}
+
+func (s *SessionStore) List(ctx context.Context, agentID string) ([]*entity.Session, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if agentID == "" {
+		rows, err = s.db.query(ctx, `SELECT data FROM `+tableSessions+` ORDER BY updated_at DESC`)
+	} else {
+		rows, err = s.db.query(ctx,
+			`SELECT data FROM `+tableSessions+` WHERE agent_id = ? ORDER BY updated_at DESC`, agentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func scanSessions(rows *sql.Rows) ([]*entity.Session, error) {
+	sessions := make([]*entity.Session, 0)
+	err := scanAll(rows, func(data []byte) error {
+		var session entity.Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return fmt.Errorf("failed to unmarshal session: %w", err)
+		}
+		sessions = append(sessions, &session)
+		return nil
+	})
+	return sessions, err
+}
@@ -0,0 +1,237 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/pkg/errno"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(context.Background(), DialectSQLite, ":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAgentStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewAgentStore(openTestDB(t))
+
+	now := time.Now()
+	a := &entity.Agent{ID: "a1", Name: "first", CreatedAt: now}
+	b := &entity.Agent{ID: "a2", Name: "second", CreatedAt: now.Add(time.Second)}
+	for _, agent := range []*entity.Agent{b, a} {
+		if err := s.Create(ctx, agent); err != nil {
+			t.Fatalf("create %s: %v", agent.ID, err)
+		}
+	}
+
+	got, err := s.Get(ctx, "a1")
+	if err != nil || got.Name != "first" {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+
+	a.Name = "renamed"
+	if err := s.Update(ctx, a); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := s.Get(ctx, "a1"); got.Name != "renamed" {
+		t.Fatalf("name after update = %q", got.Name)
+	}
+
+	list, err := s.List(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != "a1" || list[1].ID != "a2" {
+		t.Fatalf("list = %v, %v; want a1, a2 by creation time", list, err)
+	}
+
+	if err := s.Delete(ctx, "a1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "a1"); !errors.Is(err, errno.ErrAgentNotFound) {
+		t.Fatalf("get deleted: %v, want ErrAgentNotFound", err)
+	}
+	if err := s.Update(ctx, a); !errors.Is(err, errno.ErrAgentNotFound) {
+		t.Fatalf("update deleted: %v, want ErrAgentNotFound", err)
+	}
+	if err := s.Delete(ctx, "a1"); !errors.Is(err, errno.ErrAgentNotFound) {
+		t.Fatalf("delete deleted: %v, want ErrAgentNotFound", err)
+	}
+}
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewSessionStore(openTestDB(t))
+
+	now := time.Now()
+	s1 := &entity.Session{ID: "s1", AgentID: "a", CreatedAt: now, UpdatedAt: now}
+	s2 := &entity.Session{ID: "s2", AgentID: "b", CreatedAt: now, UpdatedAt: now.Add(-time.Second)}
+	for _, sess := range []*entity.Session{s1, s2} {
+		if err := s.Create(ctx, sess); err != nil {
+			t.Fatalf("create %s: %v", sess.ID, err)
+		}
+	}
+
+	got, err := s.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got.AppendMessage(entity.NewUserMessage("hello"))
+	got.AppendMessage(entity.NewAssistantMessage("hi"))
+	got.AddUsage(&entity.TokenUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	if err := s.Update(ctx, got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got.Version != 1 {
+		t.Fatalf("version after update = %d, want 1", got.Version)
+	}
+
+	reloaded, err := s.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.Messages) != 2 || reloaded.Messages[1].Content != "hi" {
+		t.Fatalf("messages = %+v", reloaded.Messages)
+	}
+	if reloaded.Usage == nil || reloaded.Usage.TotalTokens != 5 || reloaded.Version != 1 {
+		t.Fatalf("usage = %+v, version = %d", reloaded.Usage, reloaded.Version)
+	}
+
+	all, err := s.List(ctx, "")
+	if err != nil || len(all) != 2 || all[0].ID != "s1" {
+		t.Fatalf("list = %v, %v; want s1 (updated last) first", all, err)
+	}
+	byAgent, err := s.ListByAgent(ctx, "b")
+	if err != nil || len(byAgent) != 1 || byAgent[0].ID != "s2" {
+		t.Fatalf("list by agent = %v, %v", byAgent, err)
+	}
+	if filtered, _ := s.List(ctx, "a"); len(filtered) != 1 || filtered[0].ID != "s1" {
+		t.Fatalf("list filtered = %v", filtered)
+	}
+
+	if err := s.Delete(ctx, "s2"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "s2"); !errors.Is(err, errno.ErrSessionNotFound) {
+		t.Fatalf("get deleted: %v, want ErrSessionNotFound", err)
+	}
+	if err := s.Delete(ctx, "s2"); !errors.Is(err, errno.ErrSessionNotFound) {
+		t.Fatalf("delete deleted: %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSessionStoreVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewSessionStore(openTestDB(t))
+	if err := s.Create(ctx, &entity.Session{ID: "s", AgentID: "a", UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Two writers load the same version.
+	first, _ := s.Get(ctx, "s")
+	second, _ := s.Get(ctx, "s")
+
+	first.AppendMessage(entity.NewUserMessage("from first"))
+	if err := s.Update(ctx, first); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+
+	second.AppendMessage(entity.NewUserMessage("from second"))
+	if err := s.Update(ctx, second); !errors.Is(err, errno.ErrVersionConflict) {
+		t.Fatalf("stale update: %v, want ErrVersionConflict", err)
+	}
+	if second.Version != 0 {
+		t.Fatalf("rejected update changed the caller's version to %d", second.Version)
+	}
+
+	// The retry reloads and re-applies its change.
+	retry, _ := s.Get(ctx, "s")
+	retry.AppendMessage(entity.NewUserMessage("from second"))
+	if err := s.Update(ctx, retry); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	final, _ := s.Get(ctx, "s")
+	if final.Version != 2 || len(final.Messages) != 2 ||
+		final.Messages[0].Content != "from first" || final.Messages[1].Content != "from second" {
+		t.Fatalf("final = version %d, %d messages", final.Version, len(final.Messages))
+	}
+
+	if err := s.Update(ctx, &entity.Session{ID: "missing"}); !errors.Is(err, errno.ErrSessionNotFound) {
+		t.Fatalf("update missing: %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestRunStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewRunStore(openTestDB(t))
+
+	now := time.Now()
+	runs := []*entity.Run{
+		{ID: "r2", SessionID: "s", Status: entity.RunStatusCreated, CreatedAt: now.Add(time.Second)},
+		{ID: "r1", SessionID: "s", Status: entity.RunStatusCreated, CreatedAt: now},
+		{ID: "r3", SessionID: "other", Status: entity.RunStatusCreated, CreatedAt: now},
+	}
+	for _, r := range runs {
+		if err := s.Create(ctx, r); err != nil {
+			t.Fatalf("create %s: %v", r.ID, err)
+		}
+	}
+
+	runs[1].Status = entity.RunStatusCompleted
+	runs[1].Output = "done"
+	if err := s.Update(ctx, runs[1]); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := s.Get(ctx, "r1")
+	if err != nil || got.Status != entity.RunStatusCompleted || got.Output != "done" {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+
+	list, err := s.ListBySession(ctx, "s")
+	if err != nil || len(list) != 2 || list[0].ID != "r1" || list[1].ID != "r2" {
+		t.Fatalf("list = %v, %v; want r1, r2", list, err)
+	}
+
+	if err := s.DeleteBySession(ctx, "s"); err != nil {
+		t.Fatalf("delete by session: %v", err)
+	}
+	if list, _ := s.ListBySession(ctx, "s"); len(list) != 0 {
+		t.Fatalf("runs left after delete: %v", list)
+	}
+	if _, err := s.Get(ctx, "r3"); err != nil {
+		t.Fatalf("run of another session deleted: %v", err)
+	}
+	if _, err := s.Get(ctx, "r1"); !errors.Is(err, errno.ErrRunNotFound) {
+		t.Fatalf("get deleted: %v, want ErrRunNotFound", err)
+	}
+	if err := s.Update(ctx, runs[1]); !errors.Is(err, errno.ErrRunNotFound) {
+		t.Fatalf("update deleted: %v, want ErrRunNotFound", err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	q := `SELECT data FROM t WHERE a = ? AND b = ?`
+	if got := (&DB{dialect: DialectSQLite}).rebind(q); got != q {
+		t.Fatalf("sqlite rebind = %q", got)
+	}
+	if got := (&DB{dialect: DialectPostgres}).rebind(q); got != `SELECT data FROM t WHERE a = $1 AND b = $2` {
+		t.Fatalf("postgres rebind = %q", got)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Open(ctx, "mysql", "x"); err == nil {
+		t.Fatal("unsupported dialect accepted")
+	}
+	if _, err := Open(ctx, DialectPostgres, ""); err == nil {
+		t.Fatal("empty DSN accepted")
+	}
+}
@@ -59,6 +59,16 @@ func (r FailoverReason) String() string {
 	}
 }
 
+// ParseFailoverReason returns the FailoverReason named by s (the String form).
+func ParseFailoverReason(s string) (FailoverReason, bool) {
+	for r := FailoverReason_Unknown; r <= FailoverReason_ServerError; r++ {
+		if r.String() == s {
+			return r, true
+		}
+	}
+	return FailoverReason_Unknown, false
+}
+
 // IsRetryable returns whether this failure reason suggests a retry with the same model
 // might succeed (transient errors).
 func (r FailoverReason) IsRetryable() bool {
@@ -1,14 +1,32 @@
 package entity
 
+import "encoding/json"
+
 type LLMParams struct {
 	Temperature      *float32            `json:"temperature,omitempty"`
-	FrequencyPenalty float32             `json:"frequency_penalty,omitempty"`
-	PresencePenalty  float32             `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32            `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32            `json:"presence_penalty,omitempty"`
 	MaxTokens        int                 `json:"max_tokens,omitempty"`
 	TopP             *float32            `json:"top_p,omitempty"`
 	TopK             *int32              `json:"top_k,omitempty"`
 	ResponseFormat   ModelResponseFormat `json:"response_format"`
+	JSONSchema       *ResponseJSONSchema `json:"json_schema,omitempty"`
 	EnableThinking   *bool               `json:"enable_thinking,omitempty"`
+
+	// Seed requests deterministic sampling. Providers without seed support ignore it.
+	Seed *int `json:"seed,omitempty"`
+
+	// Stop lists sequences at which generation stops. Dropped for models whose
+	// compat config has StopSequenceSupported=false.
+	Stop []string `json:"stop,omitempty"`
+}
+
+// ResponseJSONSchema is the schema enforced when ResponseFormat is ModelResponseFormatJSONSchema.
+type ResponseJSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	Strict      bool            `json:"strict,omitempty"`
 }
 
 // ModelResponseFormat defines the format of the model's response.
@@ -18,6 +36,7 @@ const (
 	ModelResponseFormatText ModelResponseFormat = iota
 	ModelResponseFormatJSON
 	ModelResponseFormatMarkdown
+	ModelResponseFormatJSONSchema
 )
 
 func (f ModelResponseFormat) String() string {
@@ -28,7 +47,14 @@ func (f ModelResponseFormat) String() string {
 		return "json"
 	case ModelResponseFormatMarkdown:
 		return "markdown"
+	case ModelResponseFormatJSONSchema:
+		return "json_schema"
 	default:
 		return "text"
 	}
 }
+
+// IsStructured reports whether the format requires JSON output.
+func (f ModelResponseFormat) IsStructured() bool {
+	return f == ModelResponseFormatJSON || f == ModelResponseFormatJSONSchema
+}
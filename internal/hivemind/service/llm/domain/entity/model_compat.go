@@ -25,6 +25,10 @@ type ModelCompatConfig struct {
 	// SupportsVision indicates whether the model supports image/vision input.
 	SupportsVision *bool `json:"supports_vision,omitempty"`
 
+	// SupportsJSONMode indicates whether the model can be constrained to JSON output
+	// (response_format json_object / json_schema).
+	SupportsJSONMode *bool `json:"supports_json_mode,omitempty"`
+
 	// MaxTokensFieldName overrides the field name used for max output tokens.
 	// Some providers use "max_tokens", others use "max_completion_tokens".
 	MaxTokensFieldName string `json:"max_tokens_field_name,omitempty"`
@@ -46,6 +50,11 @@ type FloatRange struct {
 	Max float32 `json:"max"`
 }
 
+// Clamp returns v limited to [Min, Max].
+func (r FloatRange) Clamp(v float32) float32 {
+	return min(max(v, r.Min), r.Max)
+}
+
 // GetBoolOrDefault returns the value of a *bool pointer, or the default if nil.
 func GetBoolOrDefault(ptr *bool, defaultVal bool) bool {
 	if ptr != nil {
@@ -2,6 +2,8 @@ package entity
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 )
 
@@ -21,6 +23,13 @@ type FallbackConfig struct {
 
 	// SkipOnCooldown skips models whose status is ModelStatus_Cooldown.
 	SkipOnCooldown bool `json:"skip_on_cooldown,omitempty"`
+
+	// Weights assigns a load-balancing weight to candidates, keyed by ModelRef.String()
+	// ("provider/model"). Consecutive weighted candidates form one tier: within a tier
+	// the attempt order is weighted-random, across tiers it stays in declaration order.
+	// Candidates without a positive weight form a tier of their own.
+	// Empty means fully deterministic order.
+	Weights map[string]int `json:"weights,omitempty"`
 }
 
 // Candidates returns the ordered list of all candidate models (primary first, then fallbacks).
@@ -46,6 +55,43 @@ func (c *FallbackConfig) Candidates() []ModelRef {
 	return candidates
 }
 
+// OrderedCandidates returns Candidates with each weighted tier shuffled by weight.
+// rnd must return values in [0, 1). Without weights the order is unchanged.
+//
+// Uses weighted sampling without replacement (Efraimidis-Spirakis): each candidate
+// gets the key rnd()^(1/weight) and a tier is sorted by descending key. Skipping an
+// unavailable candidate (e.g. cooldown) therefore leaves the remaining ones
+// selected in proportion to their weights.
+func (c *FallbackConfig) OrderedCandidates(rnd func() float64) []ModelRef {
+	candidates := c.Candidates()
+	if len(c.Weights) == 0 || rnd == nil {
+		return candidates
+	}
+
+	for start := 0; start < len(candidates); {
+		end := start
+		for end < len(candidates) && c.Weights[candidates[end].String()] > 0 {
+			end++
+		}
+		if end-start > 1 {
+			tier := candidates[start:end]
+			keys := make(map[string]float64, len(tier))
+			for _, ref := range tier {
+				keys[ref.String()] = math.Pow(rnd(), 1/float64(c.Weights[ref.String()]))
+			}
+			sort.SliceStable(tier, func(i, j int) bool {
+				return keys[tier[i].String()] > keys[tier[j].String()]
+			})
+		}
+		if end == start {
+			end++
+		}
+		start = end
+	}
+
+	return candidates
+}
+
 // EffectiveMaxAttempts returns the maximum number of attempts to make.
 func (c *FallbackConfig) EffectiveMaxAttempts() int {
 	total := 1 + len(c.Fallbacks)
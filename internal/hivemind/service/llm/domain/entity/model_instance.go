@@ -31,6 +31,9 @@ type ModelInstance struct {
 	Cost ModelCostInfo `json:"cost"`
 	// ContextWindow is the maximum number of tokens the model can process in a single request.
 	ContextWindow int `json:"context_window"`
+	// ProbedContextWindow is the context window discovered by the model prober.
+	// When set, it takes precedence over ContextWindow.
+	ProbedContextWindow int `json:"probed_context_window,omitempty"`
 	// MaxTokens is the maximum number of tokens the model can generate in a single request.
 	MaxTokens int `json:"max_tokens"`
 	// Reasoning indicates whether this model supports reasoning tasks.
@@ -87,6 +90,16 @@ type ModelCostInfo struct {
 	CacheWrite float64
 }
 
+// IsSet reports whether input or output pricing has been configured.
+func (c ModelCostInfo) IsSet() bool {
+	return c.Input > 0 || c.Output > 0
+}
+
+// Compute returns the cost of the given prompt/completion token counts.
+func (c ModelCostInfo) Compute(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*c.Input + float64(completionTokens)*c.Output) / 1_000_000
+}
+
 // ModelStatus indicates the current operational state of the model.
 type ModelStatus int32
 
@@ -122,6 +135,15 @@ func (r ModelRef) String() string {
 	return fmt.Sprintf("%s/%s", r.ProviderID, r.ModelID)
 }
 
+// AliasProvider is the pseudo provider of alias references: "alias/smart"
+// refers to whichever model the "smart" alias currently points to.
+const AliasProvider = "alias"
+
+// IsAlias reports whether r references a model alias.
+func (r ModelRef) IsAlias() bool {
+	return r.ProviderID == AliasProvider
+}
+
 func ModelClassFromString(s string) ModelClass {
 	switch s {
 	case "gpt", "openai":
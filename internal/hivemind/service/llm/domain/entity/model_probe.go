@@ -23,6 +23,10 @@ type ProbeResult struct {
 	// ProbeType indicates what capability was probed.
 	ProbeType ProbeType `json:"probe_type"`
 
+	// ContextWindow is the context window discovered by a context-window probe.
+	// 0 when the provider did not report a limit.
+	ContextWindow int `json:"context_window,omitempty"`
+
 	// Timestamp is when the probe was performed.
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -42,6 +46,11 @@ const (
 
 	// ProbeType_Streaming probes streaming response capability.
 	ProbeType_Streaming ProbeType = 3
+
+	// ProbeType_ContextWindow discovers the effective context window by sending
+	// a deliberately oversized prompt and parsing the provider's rejection.
+	// Opt-in only: a provider that accepts the prompt bills for it.
+	ProbeType_ContextWindow ProbeType = 4
 )
 
 func (p ProbeType) String() string {
@@ -54,6 +63,8 @@ func (p ProbeType) String() string {
 		return "vision"
 	case ProbeType_Streaming:
 		return "streaming"
+	case ProbeType_ContextWindow:
+		return "context_window"
 	default:
 		return fmt.Sprintf("ProbeType(%d)", p)
 	}
@@ -14,6 +14,7 @@ type ModelProvider struct {
 	API         ModelAPI          `json:"api"`
 	AuthHeader  bool              `json:"auth_header"`
 	Headers     map[string]string `json:"headers"`
+	ProxyURL    string            `json:"proxy_url,omitempty"`
 	Enabled     bool              `json:"enabled"`
 }
 
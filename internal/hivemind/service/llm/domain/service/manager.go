@@ -5,6 +5,7 @@ import (
 
 	"github.com/cloudwego/eino/components/model"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/pkg/options"
 )
 
 type ModelManager interface {
@@ -24,6 +25,7 @@ type ModelManager interface {
 	// GetModelByID retrieves the LLM model by its ID.
 	GetModelByID(ctx context.Context, id int64) (*entity.ModelInstance, error)
 	// GetModelByRef retrieves a model by provider+model reference.
+	// Alias references are resolved first.
 	GetModelByRef(ctx context.Context, ref entity.ModelRef) (*entity.ModelInstance, error)
 	// GetDefaultModel retrieves the default LLM model.
 	GetDefaultModel(ctx context.Context) (*entity.ModelInstance, error)
@@ -36,6 +38,11 @@ type ModelManager interface {
 	// ListAllModels lists all registered LLM models.
 	ListAllModels(ctx context.Context) ([]*entity.ModelInstance, error)
 
+	// ResolveAlias returns the model an alias reference ("alias/<name>")
+	// currently points to. Other references, and unknown aliases, are
+	// returned unchanged.
+	ResolveAlias(ref entity.ModelRef) entity.ModelRef
+
 	// --- ChatModel (Eino) ---
 
 	// GetChatModel returns a cached Eino BaseChatModel for the given model reference.
@@ -52,6 +59,10 @@ type ModelManager interface {
 	// GetDefaultChatModel returns the Eino BaseChatModel for the default model.
 	GetDefaultChatModel(ctx context.Context) (model.BaseChatModel, error)
 
+	// InvalidateChatModel drops the cached ChatModel for the given reference,
+	// so the next GetChatModel call rebuilds it.
+	InvalidateChatModel(ref entity.ModelRef)
+
 	// --- Model Status ---
 
 	// ResolveCompat resolves the compatibility rules for the given model reference.
@@ -68,4 +79,9 @@ type ModelManager interface {
 	// Initialize initializes the model manager.
 	// This should be called once after all providers are registered.
 	Initialize(ctx context.Context) error
+
+	// Reload re-applies the given model options without a restart: providers and
+	// models are added, removed or replaced to match, and affected cached
+	// ChatModels are invalidated. Safe to call while requests are in flight.
+	Reload(ctx context.Context, opts *options.ModelOptions) error
 }
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 	"sync"
+	"sync/atomic"
 
 	einoModel "github.com/cloudwego/eino/components/model"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
@@ -35,6 +39,20 @@ type modelManagerImpl struct {
 	// pluginCache caches provider plugin instances to avoid repeated factory calls.
 	// Key: providerID (string), Value: spi.ProviderPlugin.
 	pluginCache sync.Map
+
+	// reloadMu serializes Initialize and Reload.
+	reloadMu sync.Mutex
+
+	// cacheGen is bumped by Reload. GetChatModel only caches instances built
+	// within a single generation, so a build racing a reload is not cached.
+	cacheGen atomic.Uint64
+
+	// aliases maps alias names to concrete refs; replaced wholesale by Reload.
+	aliases atomic.Pointer[map[string]entity.ModelRef]
+
+	// debugLog receives request/response records when ModelOptions.Debug is set; nil otherwise.
+	// Replaced by Reload when the debug settings change.
+	debugLog atomic.Pointer[debugLogWriter]
 }
 
 // NewModelManager creates a new ModelManager with the given dependencies.
@@ -46,13 +64,48 @@ func NewModelManager(
 	providerRepo repo.ProviderRepository,
 	registry *provider.Registry,
 ) ModelManager {
-	return &modelManagerImpl{
+	m := &modelManagerImpl{
 		opts:         opts,
 		modelRepo:    modelRepo,
 		providerRepo: providerRepo,
 		registry:     registry,
 		compatMgr:    NewCompatManager(registry),
 	}
+	m.setAliases(opts)
+	if opts != nil {
+		m.setDebugLog(opts)
+	}
+	return m
+}
+
+// setDebugLog opens the provider debug log described by opts, or disables it,
+// and closes the previous log. It reports whether the log changed.
+func (m *modelManagerImpl) setDebugLog(opts *options.ModelOptions) bool {
+	prev := m.debugLog.Load()
+	if !opts.Debug {
+		if prev == nil {
+			return false
+		}
+		m.debugLog.Store(nil)
+		prev.Close()
+		logger.Info("[LLM] provider debug logging disabled")
+		return true
+	}
+	if prev != nil && prev.matches(opts.DebugLogFile, opts.DebugLogMaxSizeMB, opts.DebugLogMaxBackups) {
+		return false
+	}
+
+	w, err := newDebugLogWriter(opts.DebugLogFile, opts.DebugLogMaxSizeMB, opts.DebugLogMaxBackups)
+	if err != nil {
+		logger.Warn("[LLM] provider debug logging disabled: %v", err)
+	} else {
+		logger.Warn("[LLM] provider debug logging enabled, writing to %s", opts.DebugLogFile)
+	}
+	m.debugLog.Store(w)
+	if prev != nil {
+		prev.Close()
+	}
+	return prev != nil || w != nil
 }
 
 // --- Provider Management ---
@@ -106,7 +159,7 @@ func (m *modelManagerImpl) GetModelByID(ctx context.Context, id int64) (*entity.
 }
 
 func (m *modelManagerImpl) GetModelByRef(ctx context.Context, ref entity.ModelRef) (*entity.ModelInstance, error) {
-	return m.modelRepo.FindByRef(ctx, ref)
+	return m.modelRepo.FindByRef(ctx, m.ResolveAlias(ref))
 }
 
 func (m *modelManagerImpl) GetDefaultModel(ctx context.Context) (*entity.ModelInstance, error) {
@@ -129,12 +182,40 @@ func (m *modelManagerImpl) ListAllModels(ctx context.Context) ([]*entity.ModelIn
 	return m.modelRepo.FindAll(ctx)
 }
 
+// --- Aliases ---
+
+// ResolveAlias returns the target of an "alias/<name>" reference.
+func (m *modelManagerImpl) ResolveAlias(ref entity.ModelRef) entity.ModelRef {
+	if !ref.IsAlias() {
+		return ref
+	}
+	if aliases := m.aliases.Load(); aliases != nil {
+		if target, ok := (*aliases)[ref.ModelID]; ok {
+			return target
+		}
+	}
+	return ref
+}
+
+// setAliases installs the alias table of opts.
+func (m *modelManagerImpl) setAliases(opts *options.ModelOptions) {
+	aliases := make(map[string]entity.ModelRef)
+	if opts != nil {
+		for name, target := range opts.Aliases {
+			aliases[name] = entity.ModelRef{ProviderID: target.Provider, ModelID: target.Model}
+		}
+	}
+	m.aliases.Store(&aliases)
+}
+
 // --- ChatModel (Eino) ---
 
 // GetChatModel returns a cached or newly-created Eino ChatModel for the given ModelRef.
 // The creation is lazy: ChatModel instances are only created on first access (with nil params), then cached.
 // For custom LLM params, use BuildChatModel instead (which always creates a fresh instance).
+// Aliases are resolved before the cache lookup, so a re-pointed alias takes effect at once.
 func (m *modelManagerImpl) GetChatModel(ctx context.Context, ref entity.ModelRef) (einoModel.BaseChatModel, error) {
+	ref = m.ResolveAlias(ref)
 	cacheKey := ref.String()
 
 	// Fast path: check cache.
@@ -143,21 +224,32 @@ func (m *modelManagerImpl) GetChatModel(ctx context.Context, ref entity.ModelRef
 	}
 
 	// Slow path: build with nil params (provider defaults) and cache.
+	gen := m.cacheGen.Load()
 	cm, err := m.BuildChatModel(ctx, ref, nil)
 	if err != nil {
 		return nil, err
 	}
+	if m.cacheGen.Load() != gen {
+		// A reload happened mid-build; the instance may use stale config.
+		return cm, nil
+	}
 
 	// Cache for future use (LoadOrStore handles race conditions).
 	actual, _ := m.chatModelCache.LoadOrStore(cacheKey, cm)
 	return actual.(einoModel.BaseChatModel), nil
 }
 
+// InvalidateChatModel drops the cached ChatModel for ref, if any.
+func (m *modelManagerImpl) InvalidateChatModel(ref entity.ModelRef) {
+	m.chatModelCache.Delete(m.ResolveAlias(ref).String())
+}
+
 // BuildChatModel builds a fresh Eino ChatModel with the given LLM params.
 // Unlike GetChatModel, this always creates a new instance (not cached),
 // because different params produce different model configurations.
 // params may be nil, in which case provider defaults are used.
 func (m *modelManagerImpl) BuildChatModel(ctx context.Context, ref entity.ModelRef, params *entity.LLMParams) (einoModel.BaseChatModel, error) {
+	ref = m.ResolveAlias(ref)
 	instance, err := m.modelRepo.FindByRef(ctx, ref)
 	if err != nil {
 		return nil, fmt.Errorf("model %s not found: %w", ref, err)
@@ -174,11 +266,19 @@ func (m *modelManagerImpl) BuildChatModel(ctx context.Context, ref entity.ModelR
 		return nil, err
 	}
 
+	if params != nil {
+		params = adaptParamsToCompat(ref, m.compatMgr.ResolveCompat(instance, prov), params)
+	}
+
 	cm, err := chatPlugin.BuildChatModel(ctx, instance, prov, params)
 	if err != nil {
 		return nil, fmt.Errorf("build chat model for %s: %w", ref, err)
 	}
 
+	if w := m.debugLog.Load(); w != nil {
+		cm = wrapDebugChatModel(cm, ref, prov, params, w)
+	}
+
 	return cm, nil
 }
 
@@ -229,6 +329,7 @@ func (m *modelManagerImpl) GetDefaultChatModel(ctx context.Context) (einoModel.B
 
 // ResolveCompat returns the resolved compatibility configuration for a model.
 func (m *modelManagerImpl) ResolveCompat(ctx context.Context, ref entity.ModelRef) (*entity.ModelCompatConfig, error) {
+	ref = m.ResolveAlias(ref)
 	instance, err := m.modelRepo.FindByRef(ctx, ref)
 	if err != nil {
 		return nil, fmt.Errorf("model %s not found: %w", ref, err)
@@ -246,6 +347,7 @@ func (m *modelManagerImpl) ResolveCompat(ctx context.Context, ref entity.ModelRe
 
 // SetModelStatus updates the runtime status of a model.
 func (m *modelManagerImpl) SetModelStatus(ctx context.Context, ref entity.ModelRef, status entity.ModelStatus) error {
+	ref = m.ResolveAlias(ref)
 	instance, err := m.modelRepo.FindByRef(ctx, ref)
 	if err != nil {
 		return fmt.Errorf("model %s not found: %w", ref, err)
@@ -265,6 +367,13 @@ func (m *modelManagerImpl) SetModelStatus(ctx context.Context, ref entity.ModelR
 
 // --- Lifecycle ---
 
+// providerBuild is a provider and its models as produced by a provider plugin,
+// before they are written to the repositories.
+type providerBuild struct {
+	provider *entity.ModelProvider
+	models   []*entity.ModelInstance
+}
+
 // Initialize loads all providers and models from configuration + registry.
 //
 // The flow mirrors K8S scheduler initialization:
@@ -279,38 +388,28 @@ func (m *modelManagerImpl) Initialize(ctx context.Context) error {
 		return nil
 	}
 
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
 	logger.Info("[LLM] initializing model manager (mode=%s, user_providers=%d, registry_plugins=%d)",
 		m.opts.Mode, len(m.opts.Providers), m.registry.Len())
 
-	// Phase 1: Register built-in providers from Registry (if mode is "merge").
-	if m.opts.Mode != "replace" {
-		if err := m.registerFromRegistry(ctx); err != nil {
-			return fmt.Errorf("register from registry: %w", err)
-		}
-	}
-
-	// Phase 2: Register user-configured providers.
-	for providerID, providerCfg := range m.opts.Providers {
-		if err := m.registerProviderFromConfig(ctx, providerID, providerCfg); err != nil {
-			logger.Warn("[LLM] failed to register user provider %q: %v", providerID, err)
+	// Phase 1 + 2: Build providers from Registry (if mode is "merge") and user config.
+	for _, b := range m.buildProviders(m.opts) {
+		if err := m.RegisterProvider(ctx, b.provider); err != nil {
+			logger.Warn("[LLM] failed to register provider %q: %v", b.provider.ID, err)
 			continue
 		}
-	}
-
-	// Phase 3: Set default model if configured.
-	if m.opts.DefaultProvider != "" && m.opts.DefaultModel != "" {
-		ref := entity.ModelRef{ProviderID: m.opts.DefaultProvider, ModelID: m.opts.DefaultModel}
-		if inst, err := m.modelRepo.FindByRef(ctx, ref); err == nil {
-			if err := m.modelRepo.SetDefault(ctx, inst.ID); err != nil {
-				logger.Warn("[LLM] failed to set default model %s: %v", ref, err)
-			} else {
-				logger.Info("[LLM] default model set to: %s", ref)
+		for _, model := range b.models {
+			if _, err := m.RegisterModel(ctx, model); err != nil {
+				logger.Warn("[LLM] failed to register model %s/%s: %v", b.provider.ID, model.ModelID, err)
 			}
-		} else {
-			logger.Warn("[LLM] configured default model %s not found", ref)
 		}
 	}
 
+	// Phase 3: Set default model if configured.
+	m.applyDefaultModel(ctx, m.opts)
+
 	// Log summary.
 	allModels, _ := m.modelRepo.FindAll(ctx)
 	allProviders, _ := m.providerRepo.FindAll(ctx)
@@ -322,12 +421,213 @@ func (m *modelManagerImpl) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// registerFromRegistry walks the provider.Registry, instantiates each plugin,
+// Reload re-runs the registration phases against opts and reconciles the result
+// with the current state: new providers/models are added, missing ones removed
+// and changed ones replaced. Runtime state of surviving models (status, probed
+// context window, default flag) is kept. Cached ChatModels of every touched
+// model are dropped, and builds that were in flight during the reload are not
+// cached, so callers pick up the new config on their next GetChatModel.
+func (m *modelManagerImpl) Reload(ctx context.Context, opts *options.ModelOptions) error {
+	if opts == nil {
+		return fmt.Errorf("model options are required")
+	}
+	if errs := opts.Validate(); len(errs) > 0 {
+		return fmt.Errorf("invalid model options: %w", errors.Join(errs...))
+	}
+
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	// Builds that started before this point may carry the old config; they
+	// must not be cached once the change below is under way.
+	m.cacheGen.Add(1)
+
+	if m.setDebugLog(opts) {
+		// Cached instances write to the previous log.
+		m.chatModelCache.Clear()
+	}
+
+	desired := make(map[string]*providerBuild)
+	for _, b := range m.buildProviders(opts) {
+		desired[b.provider.ID] = b
+	}
+
+	var added, updated, removed int
+
+	current, err := m.providerRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("list providers: %w", err)
+	}
+	for _, p := range current {
+		if _, ok := desired[p.ID]; ok {
+			continue
+		}
+		removed += m.removeProviderModels(ctx, p.ID, nil)
+		if err := m.providerRepo.Delete(ctx, p.ID); err != nil {
+			logger.Warn("[LLM] reload: failed to remove provider %q: %v", p.ID, err)
+		}
+		m.pluginCache.Delete(p.ID)
+		logger.Info("[LLM] reload: removed provider %s", p.ID)
+	}
+
+	for id, b := range desired {
+		prev, _ := m.providerRepo.FindByID(ctx, id)
+		providerChanged := prev == nil || !sameProvider(prev, b.provider)
+		if providerChanged {
+			if err := m.RegisterProvider(ctx, b.provider); err != nil {
+				logger.Warn("[LLM] reload: failed to register provider %q: %v", id, err)
+				continue
+			}
+		}
+
+		keep := make(map[string]struct{}, len(b.models))
+		for _, model := range b.models {
+			keep[model.ModelID] = struct{}{}
+			ref := entity.ModelRef{ProviderID: id, ModelID: model.ModelID}
+
+			old, err := m.modelRepo.FindByRef(ctx, ref)
+			existed := err == nil
+			if existed {
+				model.ID = old.ID
+				model.IsDefault = old.IsDefault
+				model.Status = old.Status
+				model.ProbedContextWindow = old.ProbedContextWindow
+				if !providerChanged && reflect.DeepEqual(old, model) {
+					continue
+				}
+			}
+
+			if _, err := m.RegisterModel(ctx, model); err != nil {
+				logger.Warn("[LLM] reload: failed to register model %s: %v", ref, err)
+				continue
+			}
+			m.chatModelCache.Delete(ref.String())
+			if existed {
+				updated++
+			} else {
+				added++
+			}
+		}
+		removed += m.removeProviderModels(ctx, id, keep)
+	}
+
+	m.applyDefaultModel(ctx, opts)
+	m.setAliases(opts)
+	m.opts = opts
+
+	// Builds that started during the reload may have read part of the old config.
+	m.cacheGen.Add(1)
+
+	logger.Info("[LLM] reload complete: %d models added, %d updated, %d removed", added, updated, removed)
+	return nil
+}
+
+// sameProvider reports whether two providers carry the same connection
+// settings. Nil and empty header maps and the display name pointer identity
+// do not count as changes.
+func sameProvider(a, b *entity.ModelProvider) bool {
+	return a.ID == b.ID &&
+		a.BaseURL == b.BaseURL &&
+		a.ModelClass == b.ModelClass &&
+		a.APIKey == b.APIKey &&
+		a.API == b.API &&
+		a.AuthHeader == b.AuthHeader &&
+		a.ProxyURL == b.ProxyURL &&
+		a.Enabled == b.Enabled &&
+		maps.Equal(a.Headers, b.Headers) &&
+		reflect.DeepEqual(a.Name, b.Name) &&
+		reflect.DeepEqual(a.Description, b.Description)
+}
+
+// removeProviderModels deletes the provider's models that are not in keep
+// (nil keep removes all) and drops their cached ChatModels.
+func (m *modelManagerImpl) removeProviderModels(ctx context.Context, providerID string, keep map[string]struct{}) int {
+	models, err := m.modelRepo.FindAllByProvider(ctx, providerID)
+	if err != nil {
+		logger.Warn("[LLM] reload: failed to list models of %q: %v", providerID, err)
+		return 0
+	}
+
+	removed := 0
+	for _, model := range models {
+		if _, ok := keep[model.ModelID]; ok {
+			continue
+		}
+		ref := entity.ModelRef{ProviderID: providerID, ModelID: model.ModelID}
+		if err := m.modelRepo.Delete(ctx, model.ID); err != nil {
+			logger.Warn("[LLM] reload: failed to remove model %s: %v", ref, err)
+			continue
+		}
+		m.chatModelCache.Delete(ref.String())
+		logger.Info("[LLM] reload: removed model %s", ref)
+		removed++
+	}
+	return removed
+}
+
+// applyDefaultModel marks the configured default model, if any.
+func (m *modelManagerImpl) applyDefaultModel(ctx context.Context, opts *options.ModelOptions) {
+	if opts.DefaultProvider == "" || opts.DefaultModel == "" {
+		return
+	}
+
+	ref := entity.ModelRef{ProviderID: opts.DefaultProvider, ModelID: opts.DefaultModel}
+	inst, err := m.modelRepo.FindByRef(ctx, ref)
+	if err != nil {
+		logger.Warn("[LLM] configured default model %s not found", ref)
+		return
+	}
+	if inst.IsDefault {
+		return
+	}
+	if inst.Status == entity.ModelStatus_Disabled {
+		logger.Warn("[LLM] configured default model %s is disabled, keeping the current default", ref)
+		return
+	}
+
+	// Clear the flag on the previous default so that listings stay consistent.
+	if prev, err := m.modelRepo.FindDefault(ctx); err == nil && prev.ID != inst.ID {
+		cleared := *prev
+		cleared.IsDefault = false
+		if err := m.modelRepo.Save(ctx, &cleared); err != nil {
+			logger.Warn("[LLM] failed to clear previous default model %s/%s: %v", prev.ProviderID, prev.ModelID, err)
+		}
+	}
+
+	if err := m.modelRepo.SetDefault(ctx, inst.ID); err != nil {
+		logger.Warn("[LLM] failed to set default model %s: %v", ref, err)
+	} else {
+		logger.Info("[LLM] default model set to: %s", ref)
+	}
+}
+
+// buildProviders runs the registry discovery (merge mode only) and the
+// user-config phase, returning the resulting providers without registering them.
+// User-configured providers come last so they override registry defaults.
+func (m *modelManagerImpl) buildProviders(opts *options.ModelOptions) []*providerBuild {
+	var builds []*providerBuild
+	if opts.Mode != "replace" {
+		builds = append(builds, m.buildFromRegistry(opts)...)
+	}
+
+	for providerID, providerCfg := range opts.Providers {
+		b, err := m.buildFromConfig(providerID, providerCfg)
+		if err != nil {
+			logger.Warn("[LLM] failed to register user provider %q: %v", providerID, err)
+			continue
+		}
+		builds = append(builds, b)
+	}
+	return builds
+}
+
+// buildFromRegistry walks the provider.Registry, instantiates each plugin,
 // and auto-discovers providers whose API keys are available in the environment.
-func (m *modelManagerImpl) registerFromRegistry(ctx context.Context) error {
+func (m *modelManagerImpl) buildFromRegistry(opts *options.ModelOptions) []*providerBuild {
+	var builds []*providerBuild
 	m.registry.Range(func(name string, factory spi.PluginFactory) bool {
 		// Skip if user has explicitly configured this provider.
-		if _, exists := m.opts.Providers[name]; exists {
+		if _, exists := opts.Providers[name]; exists {
 			logger.Info("[LLM] skipping registry plugin %q (overridden by user config)", name)
 			return true
 		}
@@ -356,34 +656,24 @@ func (m *modelManagerImpl) registerFromRegistry(ctx context.Context) error {
 			return true
 		}
 
-		if err := m.RegisterProvider(ctx, providerEntity); err != nil {
-			logger.Warn("[LLM] failed to register provider %q: %v", name, err)
-			return true
-		}
-
-		// Build and register all models via the plugin.
+		// Build all models via the plugin.
 		models, err := plugin.BuildModels(providerEntity, defaultCfg)
 		if err != nil {
 			logger.Warn("[LLM] failed to build models for %q: %v", name, err)
-			return true
-		}
-
-		for _, model := range models {
-			if _, err := m.RegisterModel(ctx, model); err != nil {
-				logger.Warn("[LLM] failed to register model %s/%s: %v", name, model.ModelID, err)
-			}
+			models = nil
 		}
 
+		builds = append(builds, &providerBuild{provider: providerEntity, models: models})
 		return true
 	})
 
-	return nil
+	return builds
 }
 
-// registerProviderFromConfig handles user-provided ProviderConfig.
+// buildFromConfig handles user-provided ProviderConfig.
 // It checks whether a matching plugin exists in the Registry for enhanced behavior,
 // otherwise falls back to generic construction via helper.BasePlugin.
-func (m *modelManagerImpl) registerProviderFromConfig(ctx context.Context, providerID string, cfg *options.ProviderConfig) error {
+func (m *modelManagerImpl) buildFromConfig(providerID string, cfg *options.ProviderConfig) (*providerBuild, error) {
 	cfg.APIKey = helper.ResolveEnvValue(cfg.APIKey)
 
 	// Try to find a matching plugin in the registry for provider-specific behavior.
@@ -400,24 +690,44 @@ func (m *modelManagerImpl) registerProviderFromConfig(ctx context.Context, provi
 	// Build provider entity.
 	providerEntity, buildErr := plugin.BuildProvider(cfg)
 	if buildErr != nil {
-		return fmt.Errorf("build provider %q: %w", providerID, buildErr)
-	}
-
-	if err := m.RegisterProvider(ctx, providerEntity); err != nil {
-		return err
+		return nil, fmt.Errorf("build provider %q: %w", providerID, buildErr)
 	}
 
-	// Build and register all models.
+	// Build all models.
 	models, buildErr := plugin.BuildModels(providerEntity, cfg)
 	if buildErr != nil {
-		return fmt.Errorf("build models for %q: %w", providerID, buildErr)
+		return nil, fmt.Errorf("build models for %q: %w", providerID, buildErr)
 	}
 
-	for _, model := range models {
-		if _, err := m.RegisterModel(ctx, model); err != nil {
-			logger.Warn("[LLM] failed to register model %s/%s: %v", providerID, model.ModelID, err)
+	return &providerBuild{provider: providerEntity, models: models}, nil
+}
+
+// adaptParamsToCompat drops or clamps the params a model's compat config
+// rules out: stop sequences for models without stop support, and a
+// temperature outside the model's range. params is copied when changed.
+func adaptParamsToCompat(ref entity.ModelRef, compat *entity.ModelCompatConfig, params *entity.LLMParams) *entity.LLMParams {
+	if compat == nil {
+		return params
+	}
+	p := *params
+	changed := false
+
+	if len(p.Stop) > 0 && !entity.GetBoolOrDefault(compat.StopSequenceSupported, true) {
+		logger.Warn("[LLM] model %s does not support stop sequences, dropping %d stop sequence(s)", ref, len(p.Stop))
+		p.Stop = nil
+		changed = true
+	}
+	if p.Temperature != nil && compat.TemperatureRange != nil {
+		if t := compat.TemperatureRange.Clamp(*p.Temperature); t != *p.Temperature {
+			logger.Debug("[LLM] model %s accepts temperature in [%g, %g], clamping %g to %g",
+				ref, compat.TemperatureRange.Min, compat.TemperatureRange.Max, *p.Temperature, t)
+			p.Temperature = &t
+			changed = true
 		}
 	}
 
-	return nil
+	if !changed {
+		return params
+	}
+	return &p
 }
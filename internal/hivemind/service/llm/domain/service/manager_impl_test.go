@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/helper"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/spi"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/pkg/options"
+)
+
+// stubChatModel answers every call with "ok".
+type stubChatModel struct{ baseURL string }
+
+func (s *stubChatModel) Generate(context.Context, []*schema.Message, ...einoModel.Option) (*schema.Message, error) {
+	return schema.AssistantMessage("ok", nil), nil
+}
+
+func (s *stubChatModel) Stream(context.Context, []*schema.Message, ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage("ok", nil)}), nil
+}
+
+// stubPlugin builds stubChatModels and counts the builds.
+type stubPlugin struct {
+	helper.BasePlugin
+	builds *int
+}
+
+func (p *stubPlugin) BuildChatModel(_ context.Context, _ *entity.ModelInstance, prov *entity.ModelProvider, _ *entity.LLMParams) (einoModel.BaseChatModel, error) {
+	*p.builds++
+	return &stubChatModel{baseURL: prov.BaseURL}, nil
+}
+
+// stubModelOptions returns options with a single "stub/m1" model.
+func stubModelOptions() *options.ModelOptions {
+	return &options.ModelOptions{
+		Mode: "replace",
+		Providers: map[string]*options.ProviderConfig{
+			"stub": {
+				BaseURL: "http://stub.local/v1",
+				APIKey:  "sk-test",
+				API:     "openai-completions",
+				Models:  []options.ModelDefinition{{ID: "m1", Name: "Model One", ContextWindow: 8192}},
+			},
+		},
+	}
+}
+
+// newStubManager returns an initialized manager over stubModelOptions and
+// the counter of chat model builds.
+func newStubManager(t *testing.T) (*modelManagerImpl, *int) {
+	t.Helper()
+	builds := 0
+	registry := provider.NewRegistry()
+	registry.MustRegister("stub", func() spi.ProviderPlugin {
+		return &stubPlugin{BasePlugin: helper.BasePlugin{PluginName: "stub"}, builds: &builds}
+	})
+	m := NewModelManager(stubModelOptions(), inmemory.NewModelStore(), inmemory.NewProviderStore(), registry).(*modelManagerImpl)
+	if err := m.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return m, &builds
+}
+
+var stubRef = entity.ModelRef{ProviderID: "stub", ModelID: "m1"}
+
+func TestReloadKeepsUnchangedModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*options.ProviderConfig)
+		rebuild bool
+	}{
+		{"same config", func(*options.ProviderConfig) {}, false},
+		{"base url", func(c *options.ProviderConfig) { c.BaseURL = "http://other.local/v1" }, true},
+		{"api key", func(c *options.ProviderConfig) { c.APIKey = "sk-rotated" }, true},
+		{"headers", func(c *options.ProviderConfig) { c.Headers = map[string]string{"X-Team": "a"} }, true},
+		{"context window", func(c *options.ProviderConfig) { c.Models[0].ContextWindow = 4096 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, builds := newStubManager(t)
+			first, err := m.GetChatModel(ctx, stubRef)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := stubModelOptions()
+			tt.mutate(opts.Providers["stub"])
+			if err := m.Reload(ctx, opts); err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			second, err := m.GetChatModel(ctx, stubRef)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rebuilt := second != first; rebuilt != tt.rebuild {
+				t.Fatalf("rebuilt = %v, want %v (builds = %d)", rebuilt, tt.rebuild, *builds)
+			}
+		})
+	}
+}
+
+func TestReloadDebugLog(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newStubManager(t)
+	if _, err := m.GetChatModel(ctx, stubRef); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := stubModelOptions()
+	opts.Debug = true
+	opts.DebugLogFile = filepath.Join(t.TempDir(), "llm-debug.log")
+	if err := m.Reload(ctx, opts); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	cm, err := m.GetChatModel(ctx, stubRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.(*debugChatModel); !ok {
+		t.Fatalf("chat model = %T after enabling debug, want the debug wrapper", cm)
+	}
+	w := m.debugLog.Load()
+
+	// Reloading the same settings keeps the log open.
+	if err := m.Reload(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	if m.debugLog.Load() != w {
+		t.Fatal("unchanged debug settings reopened the log")
+	}
+
+	if err := m.Reload(ctx, stubModelOptions()); err != nil {
+		t.Fatal(err)
+	}
+	cm, err = m.GetChatModel(ctx, stubRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.(*stubChatModel); !ok {
+		t.Fatalf("chat model = %T after disabling debug, want the plain model", cm)
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Fatal("previous debug log still open")
+	}
+}
+
+func TestReloadBumpsCacheGeneration(t *testing.T) {
+	m, _ := newStubManager(t)
+	gen := m.cacheGen.Load()
+	if err := m.Reload(context.Background(), stubModelOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if m.cacheGen.Load() == gen {
+		t.Fatal("reload did not bump the cache generation")
+	}
+}
+
+func TestResolveAlias(t *testing.T) {
+	m, _ := newStubManager(t)
+	opts := twoModelOptions()
+	opts.Aliases = map[string]options.ModelRef{"smart": {Provider: "stub", Model: "m2"}}
+	if err := m.Reload(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref  entity.ModelRef
+		want entity.ModelRef
+	}{
+		{entity.ModelRef{ProviderID: entity.AliasProvider, ModelID: "smart"}, entity.ModelRef{ProviderID: "stub", ModelID: "m2"}},
+		{entity.ModelRef{ProviderID: entity.AliasProvider, ModelID: "unknown"}, entity.ModelRef{ProviderID: entity.AliasProvider, ModelID: "unknown"}},
+		{stubRef, stubRef},
+		{entity.ModelRef{ProviderID: "stub", ModelID: "smart"}, entity.ModelRef{ProviderID: "stub", ModelID: "smart"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref.String(), func(t *testing.T) {
+			if got := m.ResolveAlias(tt.ref); got != tt.want {
+				t.Fatalf("ResolveAlias(%s) = %s, want %s", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasFollowsReload(t *testing.T) {
+	ctx := context.Background()
+	m, builds := newStubManager(t)
+	alias := entity.ModelRef{ProviderID: entity.AliasProvider, ModelID: "fast"}
+
+	opts := twoModelOptions()
+	opts.Aliases = map[string]options.ModelRef{"fast": {Provider: "stub", Model: "m1"}}
+	if err := m.Reload(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	direct, err := m.GetChatModel(ctx, stubRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viaAlias, err := m.GetChatModel(ctx, alias)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viaAlias != direct || *builds != 1 {
+		t.Fatalf("alias did not share the cached model of its target (builds = %d)", *builds)
+	}
+	if inst, err := m.GetModelByRef(ctx, alias); err != nil || inst.ModelID != stubRef.ModelID {
+		t.Fatalf("GetModelByRef(alias) = %v, %v; want %s", inst, err, stubRef)
+	}
+
+	opts = twoModelOptions()
+	opts.Aliases = map[string]options.ModelRef{"fast": {Provider: "stub", Model: "m2"}}
+	if err := m.Reload(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := entity.ModelRef{ProviderID: "stub", ModelID: "m2"}
+	if got := m.ResolveAlias(alias); got != want {
+		t.Fatalf("after reload alias resolves to %s, want %s", got, want)
+	}
+	if inst, err := m.GetModelByRef(ctx, alias); err != nil || inst.ModelID != want.ModelID {
+		t.Fatalf("GetModelByRef(alias) = %v, %v; want %s", inst, err, want)
+	}
+
+	if err := m.Reload(ctx, twoModelOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.ResolveAlias(alias); got != alias {
+		t.Fatalf("removed alias resolves to %s, want it unchanged", got)
+	}
+}
+
+func TestAdaptParamsToCompatStop(t *testing.T) {
+	unsupported, supported := false, true
+	tests := []struct {
+		name   string
+		compat *entity.ModelCompatConfig
+		want   []string
+	}{
+		{"no compat", nil, []string{"END"}},
+		{"unspecified", &entity.ModelCompatConfig{}, []string{"END"}},
+		{"supported", &entity.ModelCompatConfig{StopSequenceSupported: &supported}, []string{"END"}},
+		{"unsupported", &entity.ModelCompatConfig{StopSequenceSupported: &unsupported}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &entity.LLMParams{Stop: []string{"END"}}
+			got := adaptParamsToCompat(stubRef, tt.compat, params)
+			if !slices.Equal(got.Stop, tt.want) {
+				t.Fatalf("Stop = %q, want %q", got.Stop, tt.want)
+			}
+			if len(params.Stop) != 1 {
+				t.Fatal("caller's params were modified")
+			}
+		})
+	}
+}
@@ -115,6 +115,9 @@ func mergeCompat(dst, src *entity.ModelCompatConfig) {
 	if src.SupportsVision != nil {
 		dst.SupportsVision = src.SupportsVision
 	}
+	if src.SupportsJSONMode != nil {
+		dst.SupportsJSONMode = src.SupportsJSONMode
+	}
 	if src.MaxTokensFieldName != "" {
 		dst.MaxTokensFieldName = src.MaxTokensFieldName
 	}
@@ -178,6 +181,16 @@ func builtinCompatRules() []entity.ModelCompatRule {
 				RequiresMaxTokens: true,
 			},
 		},
+		{
+			Name:        "anthropic-no-json-mode",
+			Description: "Anthropic Messages API has no response_format; JSON output cannot be enforced.",
+			Matcher: entity.ModelCompatMatcher{
+				APITypes: []entity.ModelAPI{entity.ModelAPI_AnthropicMessages},
+			},
+			Patches: entity.ModelCompatConfig{
+				SupportsJSONMode: &boolFalse,
+			},
+		},
 		{
 			Name:        "gemini-temperature-range",
 			Description: "Gemini models accept temperature in 0.0-2.0 range.",
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// CooldownPolicy controls when a failing fallback candidate is put into
+// cooldown (ModelStatus_CoolDown) and for how long.
+type CooldownPolicy struct {
+	// FailureThreshold is the number of consecutive failed attempts that puts
+	// a model into cooldown. 0 disables cooldowns.
+	FailureThreshold int
+
+	// Duration is the cooldown length for reasons without an entry in Reasons.
+	Duration time.Duration
+
+	// Reasons overrides Duration per failure reason, e.g. a long cooldown for
+	// rate limits and a short one for timeouts.
+	Reasons map[entity.FailoverReason]time.Duration
+}
+
+// DefaultCooldownPolicy returns the policy used when none is configured.
+func DefaultCooldownPolicy() CooldownPolicy {
+	return CooldownPolicy{
+		FailureThreshold: 3,
+		Duration:         time.Minute,
+	}
+}
+
+// durationFor returns the cooldown length for a failure reason.
+func (p CooldownPolicy) durationFor(reason entity.FailoverReason) time.Duration {
+	if d, ok := p.Reasons[reason]; ok {
+		return d
+	}
+	return p.Duration
+}
+
+// cooldownTracker counts consecutive failures per model and remembers when
+// each cooldown ends.
+type cooldownTracker struct {
+	mu     sync.Mutex
+	policy CooldownPolicy
+	states map[string]*cooldownState // key: ModelRef.String()
+	now    func() time.Time
+}
+
+type cooldownState struct {
+	failures int
+	until    time.Time
+}
+
+func newCooldownTracker(policy CooldownPolicy) *cooldownTracker {
+	return &cooldownTracker{
+		policy: policy,
+		states: make(map[string]*cooldownState),
+		now:    time.Now,
+	}
+}
+
+// SetCooldownPolicy replaces the cooldown policy. Running cooldowns keep
+// their original end time.
+func (e *FallbackExecutor) SetCooldownPolicy(policy CooldownPolicy) {
+	e.cooldowns.mu.Lock()
+	defer e.cooldowns.mu.Unlock()
+	e.cooldowns.policy = policy
+}
+
+// recordFailure counts a failed attempt of ref and puts the model into
+// cooldown once the policy threshold is reached. Format errors do not count:
+// the request, not the model, is at fault.
+func (e *FallbackExecutor) recordFailure(ctx context.Context, ref entity.ModelRef, reason entity.FailoverReason) {
+	if !reason.ShouldFailover() || ctx.Err() != nil {
+		return
+	}
+
+	t := e.cooldowns
+	t.mu.Lock()
+	if t.policy.FailureThreshold <= 0 {
+		t.mu.Unlock()
+		return
+	}
+	st, ok := t.states[ref.String()]
+	if !ok {
+		st = &cooldownState{}
+		t.states[ref.String()] = st
+	}
+	st.failures++
+	if st.failures < t.policy.FailureThreshold {
+		t.mu.Unlock()
+		return
+	}
+	d := t.policy.durationFor(reason)
+	st.failures = 0
+	st.until = t.now().Add(d)
+	t.mu.Unlock()
+
+	if err := e.manager.SetModelStatus(ctx, ref, entity.ModelStatus_CoolDown); err != nil {
+		logger.Warn("[Fallback] failed to put %s into cooldown: %v", ref, err)
+		return
+	}
+	logger.Warn("[Fallback] %s put into cooldown for %s after repeated failures [reason=%s]", ref, d, reason)
+}
+
+// recordSuccess resets the failure count of ref.
+func (e *FallbackExecutor) recordSuccess(ref entity.ModelRef) {
+	t := e.cooldowns
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if st, ok := t.states[ref.String()]; ok {
+		st.failures = 0
+	}
+}
+
+// inCooldown reports whether instance is in a running cooldown. An expired
+// cooldown is lifted: the model goes back to ModelStatus_Ready. A cooldown
+// status set outside the executor has no end time and is honored as is.
+func (e *FallbackExecutor) inCooldown(ctx context.Context, ref entity.ModelRef, instance *entity.ModelInstance) bool {
+	if instance.Status != entity.ModelStatus_CoolDown {
+		return false
+	}
+
+	t := e.cooldowns
+	t.mu.Lock()
+	st, ok := t.states[ref.String()]
+	if !ok || st.until.IsZero() {
+		t.mu.Unlock()
+		return true
+	}
+	if t.now().Before(st.until) {
+		t.mu.Unlock()
+		return true
+	}
+	st.until = time.Time{}
+	t.mu.Unlock()
+
+	if err := e.manager.SetModelStatus(ctx, ref, entity.ModelStatus_Ready); err != nil {
+		logger.Warn("[Fallback] failed to lift cooldown of %s: %v", ref, err)
+	} else {
+		logger.Info("[Fallback] cooldown of %s expired", ref)
+	}
+	return false
+}
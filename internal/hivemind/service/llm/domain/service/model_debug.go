@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components"
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/utils/json"
+)
+
+// debugChatModel is a logging decorator around an Eino BaseChatModel.
+//
+// It records the final messages, resolved params and raw errors of every
+// Generate/Stream call to a rotating debug log. It is only installed when
+// ModelOptions.Debug is enabled.
+//
+// The decorator forwards components.Typer and components.Checker so that Eino
+// does not inject a second layer of callbacks around the wrapped model.
+type debugChatModel struct {
+	inner    einoModel.BaseChatModel
+	ref      entity.ModelRef
+	baseURL  string
+	params   *entity.LLMParams
+	redactor *strings.Replacer
+	log      *debugLogWriter
+}
+
+// debugToolCallingChatModel preserves the ToolCallingChatModel interface so that
+// downstream type assertions (react agent, probeToolCall) keep working.
+type debugToolCallingChatModel struct {
+	*debugChatModel
+	tcm einoModel.ToolCallingChatModel
+}
+
+var (
+	_ einoModel.BaseChatModel        = (*debugChatModel)(nil)
+	_ einoModel.ToolCallingChatModel = (*debugToolCallingChatModel)(nil)
+)
+
+// wrapDebugChatModel wraps cm with the debug logger, keeping its tool-calling capability.
+func wrapDebugChatModel(cm einoModel.BaseChatModel, ref entity.ModelRef, prov *entity.ModelProvider, params *entity.LLMParams, w *debugLogWriter) einoModel.BaseChatModel {
+	var secrets []string
+	if prov != nil && prov.APIKey != "" {
+		secrets = append(secrets, prov.APIKey, "***")
+	}
+
+	base := &debugChatModel{
+		inner:    cm,
+		ref:      ref,
+		params:   params,
+		redactor: strings.NewReplacer(secrets...),
+		log:      w,
+	}
+	if prov != nil {
+		base.baseURL = prov.BaseURL
+	}
+
+	if tcm, ok := cm.(einoModel.ToolCallingChatModel); ok {
+		return &debugToolCallingChatModel{debugChatModel: base, tcm: tcm}
+	}
+	return base
+}
+
+func (d *debugChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...einoModel.Option) (*schema.Message, error) {
+	start := time.Now()
+	out, err := d.inner.Generate(ctx, input, opts...)
+	d.record("generate", input, opts, out, err, time.Since(start))
+	return out, err
+}
+
+func (d *debugChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	start := time.Now()
+	sr, err := d.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		d.record("stream", input, opts, nil, err, time.Since(start))
+		return nil, err
+	}
+
+	// Tee the stream: one copy for the caller, one drained in the background
+	// to log the concatenated response once the stream completes.
+	copies := sr.Copy(2)
+	go func() {
+		out, concatErr := schema.ConcatMessageStream(copies[1])
+		d.record("stream", input, opts, out, concatErr, time.Since(start))
+	}()
+	return copies[0], nil
+}
+
+// GetType forwards the wrapped model's component type.
+func (d *debugChatModel) GetType() string {
+	if t, ok := components.GetType(d.inner); ok {
+		return t
+	}
+	return "DebugChatModel"
+}
+
+// IsCallbacksEnabled forwards the wrapped model's callback handling.
+func (d *debugChatModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(d.inner)
+}
+
+func (d *debugToolCallingChatModel) WithTools(tools []*schema.ToolInfo) (einoModel.ToolCallingChatModel, error) {
+	next, err := d.tcm.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &debugToolCallingChatModel{
+		debugChatModel: &debugChatModel{
+			inner:    next,
+			ref:      d.ref,
+			baseURL:  d.baseURL,
+			params:   d.params,
+			redactor: d.redactor,
+			log:      d.log,
+		},
+		tcm: next,
+	}, nil
+}
+
+// debugRecord is a single JSON line in the debug log.
+type debugRecord struct {
+	Time       string             `json:"time"`
+	Model      string             `json:"model"`
+	BaseURL    string             `json:"base_url,omitempty"`
+	Call       string             `json:"call"`
+	DurationMs int64              `json:"duration_ms"`
+	Params     *entity.LLMParams  `json:"params,omitempty"`
+	Options    *einoModel.Options `json:"options,omitempty"`
+	Messages   []*schema.Message  `json:"messages"`
+	Response   *schema.Message    `json:"response,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+func (d *debugChatModel) record(call string, input []*schema.Message, opts []einoModel.Option, out *schema.Message, err error, elapsed time.Duration) {
+	rec := debugRecord{
+		Time:       time.Now().Format(time.RFC3339Nano),
+		Model:      d.ref.String(),
+		BaseURL:    d.baseURL,
+		Call:       call,
+		DurationMs: elapsed.Milliseconds(),
+		Params:     d.params,
+		Options:    einoModel.GetCommonOptions(nil, opts...),
+		Messages:   input,
+		Response:   out,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	data, mErr := json.Marshal(rec)
+	if mErr != nil {
+		data = []byte(fmt.Sprintf(`{"model":%q,"call":%q,"error":"marshal debug record: %v"}`, rec.Model, call, mErr))
+	}
+	line := d.redactor.Replace(string(data)) + "\n"
+	_, _ = d.log.Write([]byte(line))
+}
+
+// debugLogWriter is a size-based rotating file writer.
+// When the file exceeds maxBytes it is renamed to <path>.1 (shifting older
+// backups up to maxBackups) and a fresh file is opened.
+type debugLogWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// newDebugLogWriter opens (or creates) the debug log at path.
+func newDebugLogWriter(path string, maxSizeMB, maxBackups int) (*debugLogWriter, error) {
+	maxBytes, maxBackups := debugLogLimits(maxSizeMB, maxBackups)
+	w := &debugLogWriter{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *debugLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *debugLogWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("create debug log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open debug log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat debug log: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *debugLogWriter) rotate() error {
+	if w.file != nil {
+		w.file.Close()
+	}
+	if w.maxBackups > 0 {
+		for i := w.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		_ = os.Rename(w.path, w.path+".1")
+	} else {
+		_ = os.Remove(w.path)
+	}
+	return w.open()
+}
+
+// debugLogLimits applies the defaults to the configured size and backup limits.
+func debugLogLimits(maxSizeMB, maxBackups int) (int64, int) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = 50
+	}
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+	return int64(maxSizeMB) * 1024 * 1024, maxBackups
+}
+
+// matches reports whether the writer was opened with the given settings.
+func (w *debugLogWriter) matches(path string, maxSizeMB, maxBackups int) bool {
+	maxBytes, maxBackups := debugLogLimits(maxSizeMB, maxBackups)
+	return w.path == path && w.maxBytes == maxBytes && w.maxBackups == maxBackups
+}
+
+// Close closes the log file. Later writes fail with os.ErrClosed.
+func (w *debugLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
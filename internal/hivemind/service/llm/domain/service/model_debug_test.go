@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+// toolStubChatModel is a stubChatModel that can bind tools.
+type toolStubChatModel struct{ stubChatModel }
+
+func (s *toolStubChatModel) WithTools([]*schema.ToolInfo) (einoModel.ToolCallingChatModel, error) {
+	return s, nil
+}
+
+// failingChatModel fails every call with err.
+type failingChatModel struct{ err error }
+
+func (f *failingChatModel) Generate(context.Context, []*schema.Message, ...einoModel.Option) (*schema.Message, error) {
+	return nil, f.err
+}
+
+func (f *failingChatModel) Stream(context.Context, []*schema.Message, ...einoModel.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, f.err
+}
+
+func newTestDebugLog(t *testing.T) (*debugLogWriter, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "llm-debug.log")
+	w, err := newDebugLogWriter(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, path
+}
+
+func readDebugLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDebugChatModelKeepsToolCalling(t *testing.T) {
+	w, _ := newTestDebugLog(t)
+	prov := &entity.ModelProvider{APIKey: "sk-secret"}
+
+	if _, ok := wrapDebugChatModel(&stubChatModel{}, stubRef, prov, nil, w).(einoModel.ToolCallingChatModel); ok {
+		t.Fatal("wrapper of a plain chat model claims tool calling")
+	}
+	cm, ok := wrapDebugChatModel(&toolStubChatModel{}, stubRef, prov, nil, w).(einoModel.ToolCallingChatModel)
+	if !ok {
+		t.Fatal("wrapper dropped the ToolCallingChatModel interface")
+	}
+	bound, err := cm.WithTools([]*schema.ToolInfo{{Name: "search"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bound.(*debugToolCallingChatModel); !ok {
+		t.Fatalf("WithTools = %T, want the debug wrapper", bound)
+	}
+}
+
+func TestDebugChatModelRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		model einoModel.BaseChatModel
+		call  func(context.Context, einoModel.BaseChatModel) error
+		want  []string
+	}{
+		{
+			name:  "generate",
+			model: &stubChatModel{},
+			call: func(ctx context.Context, cm einoModel.BaseChatModel) error {
+				_, err := cm.Generate(ctx, []*schema.Message{schema.UserMessage("hello")})
+				return err
+			},
+			want: []string{`"call":"generate"`, `"model":"stub/m1"`, `"hello"`, `"ok"`, `"temperature":0.5`},
+		},
+		{
+			name:  "stream",
+			model: &stubChatModel{},
+			call: func(ctx context.Context, cm einoModel.BaseChatModel) error {
+				sr, err := cm.Stream(ctx, []*schema.Message{schema.UserMessage("hello")})
+				if err != nil {
+					return err
+				}
+				defer sr.Close()
+				for {
+					if _, err := sr.Recv(); err != nil {
+						if errors.Is(err, io.EOF) {
+							return nil
+						}
+						return err
+					}
+				}
+			},
+			want: []string{`"call":"stream"`, `"hello"`, `"ok"`},
+		},
+		{
+			name:  "provider error",
+			model: &failingChatModel{err: errors.New("401: invalid key sk-secret")},
+			call: func(ctx context.Context, cm einoModel.BaseChatModel) error {
+				if _, err := cm.Generate(ctx, []*schema.Message{schema.UserMessage("hello")}); err == nil {
+					return errors.New("failing model succeeded")
+				}
+				return nil
+			},
+			want: []string{`"error":"401: invalid key ***"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, path := newTestDebugLog(t)
+			temp := float32(0.5)
+			prov := &entity.ModelProvider{APIKey: "sk-secret", BaseURL: "http://stub.local/v1"}
+			cm := wrapDebugChatModel(tt.model, stubRef, prov, &entity.LLMParams{Temperature: &temp}, w)
+			if err := tt.call(context.Background(), cm); err != nil {
+				t.Fatal(err)
+			}
+
+			// Streams are logged once the background copy is drained.
+			var got string
+			for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				if got = readDebugLog(t, path); got != "" {
+					break
+				}
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("debug log missing %s:\n%s", want, got)
+				}
+			}
+			if strings.Contains(got, "sk-secret") {
+				t.Errorf("debug log leaks the API key:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestDebugLogWriterRotates(t *testing.T) {
+	w, path := newTestDebugLog(t)
+	line := []byte(strings.Repeat("x", 600*1024) + "\n")
+	for range 2 {
+		if _, err := w.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readDebugLog(t, path+".1"); got != string(line) {
+		t.Fatalf("backup holds %d bytes, want the first record", len(got))
+	}
+	if got := readDebugLog(t, path); got != string(line) {
+		t.Fatalf("log holds %d bytes, want only the second record", len(got))
+	}
+}
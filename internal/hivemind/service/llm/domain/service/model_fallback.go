@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 
 	einoModel "github.com/cloudwego/eino/components/model"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
@@ -13,13 +14,15 @@ import (
 type FallbackExecutor struct {
 	modelRepo repo.ModelRepository
 	manager   ModelManager
+	cooldowns *cooldownTracker
 }
 
-// NewFallbackExecutor creates a new FallbackExecutor.
+// NewFallbackExecutor creates a new FallbackExecutor with the default cooldown policy.
 func NewFallbackExecutor(modelRepo repo.ModelRepository, manager ModelManager) *FallbackExecutor {
 	return &FallbackExecutor{
 		modelRepo: modelRepo,
 		manager:   manager,
+		cooldowns: newCooldownTracker(DefaultCooldownPolicy()),
 	}
 }
 
@@ -45,33 +48,32 @@ func RunWithFallback[T any](
 	run RunFunc[T],
 	onError OnErrorFunc,
 ) *entity.FallbackResult[T] {
-	candidates := config.Candidates()
+	candidates := config.OrderedCandidates(rand.Float64)
 	maxAttempts := config.EffectiveMaxAttempts()
 
 	result := &entity.FallbackResult[T]{
 		Attempts: make([]entity.FallbackAttempt, 0, len(candidates)),
 	}
 
+	if len(candidates) > maxAttempts {
+		candidates = candidates[:maxAttempts]
+	}
+	skip := executor.cooldownSkips(ctx, config, candidates)
+
 	for i, ref := range candidates {
-		if i >= maxAttempts {
-			break
-		}
+		// Attempts and the result carry the concrete model, not the alias.
+		ref = executor.manager.ResolveAlias(ref)
 
-		// Check cooldown status if configured.
-		if config.SkipOnCooldown {
-			if instance, err := executor.modelRepo.FindByRef(ctx, ref); err == nil {
-				if instance.Status == entity.ModelStatus_CoolDown {
-					attempt := entity.FallbackAttempt{
-						Ref:        ref,
-						Skipped:    true,
-						SkipReason: fmt.Sprintf("provider %s is in cooldown", ref.ProviderID),
-						Reason:     entity.FailoverReason_RateLimit,
-					}
-					result.Attempts = append(result.Attempts, attempt)
-					logger.Info("[Fallback] skipping %s (cooldown)", ref)
-					continue
-				}
+		if skip[i] {
+			attempt := entity.FallbackAttempt{
+				Ref:        ref,
+				Skipped:    true,
+				SkipReason: fmt.Sprintf("provider %s is in cooldown", ref.ProviderID),
+				Reason:     entity.FailoverReason_RateLimit,
 			}
+			result.Attempts = append(result.Attempts, attempt)
+			logger.Info("[Fallback] skipping %s (cooldown)", ref)
+			continue
 		}
 
 		// Build ChatModel for this candidate.
@@ -103,6 +105,7 @@ func RunWithFallback[T any](
 				StatusCode: fe.StatusCode,
 			}
 			result.Attempts = append(result.Attempts, attempt)
+			executor.recordFailure(ctx, ref, fe.Reason)
 
 			logger.Warn("[Fallback] attempt %d/%d failed (%s): %s [reason=%s]",
 				i+1, len(candidates), ref, fe.Message, fe.Reason)
@@ -119,6 +122,7 @@ func RunWithFallback[T any](
 		}
 
 		// Success!
+		executor.recordSuccess(ref)
 		result.Value = value
 		result.Ref = ref
 		result.OK = true
@@ -146,23 +150,22 @@ func (e *FallbackExecutor) RunChatWithFallback(
 }
 
 // GetChatModelWithFallback tries to get a usable BaseChatModel from the candidate list.
-// Returns the first successfully built model and its ref.
+// Returns the first successfully built model and its ref. When config.Weights is set,
+// available candidates of the same tier are picked weighted-randomly.
 // Returns BaseChatModel; callers needing ToolCallingChatModel can assert the returned value.
 func (e *FallbackExecutor) GetChatModelWithFallback(
 	ctx context.Context,
 	config entity.FallbackConfig,
 	params *entity.LLMParams,
 ) (einoModel.BaseChatModel, entity.ModelRef, error) {
-	candidates := config.Candidates()
+	candidates := config.OrderedCandidates(rand.Float64)
+	skip := e.cooldownSkips(ctx, config, candidates)
 
 	for i, ref := range candidates {
-		if config.SkipOnCooldown {
-			if instance, err := e.modelRepo.FindByRef(ctx, ref); err == nil {
-				if instance.Status == entity.ModelStatus_CoolDown {
-					logger.Info("[Fallback] skipping %s (cooldown)", ref)
-					continue
-				}
-			}
+		ref = e.manager.ResolveAlias(ref)
+		if skip[i] {
+			logger.Info("[Fallback] skipping %s (cooldown)", ref)
+			continue
 		}
 
 		cm, err := e.manager.BuildChatModel(ctx, ref, params)
@@ -177,3 +180,28 @@ func (e *FallbackExecutor) GetChatModelWithFallback(
 
 	return nil, entity.ModelRef{}, fmt.Errorf("no usable model found in %d candidates", len(candidates))
 }
+
+// cooldownSkips reports, per candidate, whether it is skipped for being in
+// cooldown (only with config.SkipOnCooldown). A cooldown never takes away the
+// last usable model: when every candidate is cooling down, none is skipped,
+// so a single-model agent keeps working through a run of failures.
+func (e *FallbackExecutor) cooldownSkips(ctx context.Context, config entity.FallbackConfig, candidates []entity.ModelRef) []bool {
+	skip := make([]bool, len(candidates))
+	if !config.SkipOnCooldown {
+		return skip
+	}
+	usable := false
+	for i, ref := range candidates {
+		ref = e.manager.ResolveAlias(ref)
+		if instance, err := e.modelRepo.FindByRef(ctx, ref); err == nil && e.inCooldown(ctx, ref, instance) {
+			skip[i] = true
+			continue
+		}
+		usable = true
+	}
+	if !usable {
+		logger.Warn("[Fallback] every candidate is in cooldown, trying them anyway")
+		clear(skip)
+	}
+	return skip
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/pkg/options"
+)
+
+// twoModelOptions returns stubModelOptions with a second model "stub/m2".
+func twoModelOptions() *options.ModelOptions {
+	opts := stubModelOptions()
+	cfg := opts.Providers["stub"]
+	cfg.Models = append(cfg.Models, options.ModelDefinition{ID: "m2", Name: "Model Two", ContextWindow: 8192})
+	return opts
+}
+
+func TestCooldownKeepsLastUsableModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		fallbacks []entity.ModelRef
+		want      string
+	}{
+		{"single model", nil, "stub/m1"},
+		{"fallback available", []entity.ModelRef{{ProviderID: "stub", ModelID: "m2"}}, "stub/m2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, _ := newStubManager(t)
+			if err := m.Reload(ctx, twoModelOptions()); err != nil {
+				t.Fatal(err)
+			}
+			e := NewFallbackExecutor(m.modelRepo, m)
+			e.SetCooldownPolicy(CooldownPolicy{FailureThreshold: 1, Duration: time.Hour})
+			config := entity.FallbackConfig{Primary: stubRef, Fallbacks: tt.fallbacks, SkipOnCooldown: true}
+
+			e.recordFailure(ctx, stubRef, entity.FailoverReason_RateLimit)
+			if inst, _ := m.modelRepo.FindByRef(ctx, stubRef); inst.Status != entity.ModelStatus_CoolDown {
+				t.Fatalf("status = %s, want cooldown", inst.Status)
+			}
+
+			var used string
+			res := RunWithFallback(ctx, e, config, nil, func(context.Context, einoModel.BaseChatModel) (string, error) {
+				return "ok", nil
+			}, nil)
+			if res.OK {
+				used = res.Ref.String()
+			}
+			if used != tt.want {
+				t.Fatalf("answered by %q, want %q (attempts %+v)", used, tt.want, res.Attempts)
+			}
+
+			if _, ref, err := e.GetChatModelWithFallback(ctx, config, nil); err != nil || ref.String() != tt.want {
+				t.Fatalf("GetChatModelWithFallback = %s, %v; want %s", ref, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackResolvesAliases(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newStubManager(t)
+	opts := twoModelOptions()
+	opts.Aliases = map[string]options.ModelRef{"smart": {Provider: "stub", Model: "m2"}}
+	if err := m.Reload(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	e := NewFallbackExecutor(m.modelRepo, m)
+	config := entity.FallbackConfig{Primary: entity.ModelRef{ProviderID: entity.AliasProvider, ModelID: "smart"}}
+
+	res := RunWithFallback(ctx, e, config, nil, func(context.Context, einoModel.BaseChatModel) (string, error) {
+		return "ok", nil
+	}, nil)
+	if !res.OK || res.Ref.String() != "stub/m2" {
+		t.Fatalf("RunWithFallback answered by %s (ok %v), want stub/m2", res.Ref, res.OK)
+	}
+	if _, ref, err := e.GetChatModelWithFallback(ctx, config, nil); err != nil || ref.String() != "stub/m2" {
+		t.Fatalf("GetChatModelWithFallback = %s, %v; want stub/m2", ref, err)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -92,6 +93,15 @@ func (p *ModelProber) ProbeModel(ctx context.Context, spec entity.ModelProbeSpec
 	if probeResult := p.probeViaPlugin(ctx, instance, prov, timeout); probeResult != nil {
 		result.Results[entity.ProbeType_Chat] = probeResult
 		result.Available = probeResult.OK
+
+		// Provider plugins only cover liveness; the context window is still
+		// probed through the chat model when requested.
+		if slices.Contains(probeTypes, entity.ProbeType_ContextWindow) {
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			result.Results[entity.ProbeType_ContextWindow] = p.probeByType(probeCtx, instance, prov, entity.ProbeType_ContextWindow)
+			cancel()
+		}
+		p.recordContextWindow(ctx, instance, result)
 		return result, nil
 	}
 
@@ -107,22 +117,70 @@ func (p *ModelProber) ProbeModel(ctx context.Context, spec entity.ModelProbeSpec
 	if chatResult, ok := result.Results[entity.ProbeType_Chat]; ok {
 		result.Available = chatResult.OK
 	}
+	p.recordContextWindow(ctx, instance, result)
 
 	return result, nil
 }
 
+// ScanOption customizes a ScanModels call.
+type ScanOption func(*scanOptions)
+
+type scanOptions struct {
+	probeTimeout time.Duration
+	concurrency  int
+	onResult     func(*entity.ModelScanResult)
+}
+
+// WithProbeTimeout sets the per-probe timeout of specs that don't set TimeoutMs.
+// Default: 10s.
+func WithProbeTimeout(d time.Duration) ScanOption {
+	return func(o *scanOptions) {
+		o.probeTimeout = d
+	}
+}
+
+// WithConcurrency lowers the prober's maximum number of parallel probes for
+// this scan. Values above the prober's own limit are clamped to it.
+func WithConcurrency(n int) ScanOption {
+	return func(o *scanOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithResultCallback registers fn to receive each model's result as soon as
+// its probe finishes, e.g. to print results live. Calls are serialized, each
+// precedes the onProgress call counting that result, and all of them happen
+// before ScanModels returns.
+func WithResultCallback(fn func(*entity.ModelScanResult)) ScanOption {
+	return func(o *scanOptions) {
+		o.onResult = fn
+	}
+}
+
 // ScanModels probes multiple models concurrently.
 // Modeled after OpenClaw's scanOpenRouterModels with mapWithConcurrency pattern.
-func (p *ModelProber) ScanModels(ctx context.Context, specs []entity.ModelProbeSpec, onProgress func(completed, total int)) ([]*entity.ModelScanResult, error) {
+//
+// The results are returned in spec order once every probe has finished;
+// onProgress and the WithResultCallback callback report them as they complete.
+func (p *ModelProber) ScanModels(ctx context.Context, specs []entity.ModelProbeSpec, onProgress func(completed, total int), opts ...ScanOption) ([]*entity.ModelScanResult, error) {
 	if len(specs) == 0 {
 		return nil, nil
 	}
 
+	var o scanOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	results := make([]*entity.ModelScanResult, len(specs))
 	var mu sync.Mutex
 	var completed int
 
-	sem := make(chan struct{}, p.concurrency)
+	concurrency := p.concurrency
+	if o.concurrency > 0 {
+		concurrency = min(o.concurrency, p.concurrency)
+	}
+	sem := make(chan struct{}, concurrency)
 	var wg sync.WaitGroup
 
 	for i, spec := range specs {
@@ -133,6 +191,9 @@ func (p *ModelProber) ScanModels(ctx context.Context, specs []entity.ModelProbeS
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
+			if s.TimeoutMs <= 0 && o.probeTimeout > 0 {
+				s.TimeoutMs = o.probeTimeout.Milliseconds()
+			}
 			result, err := p.ProbeModel(ctx, s)
 			if err != nil {
 				result = &entity.ModelScanResult{
@@ -152,12 +213,14 @@ func (p *ModelProber) ScanModels(ctx context.Context, specs []entity.ModelProbeS
 
 			results[idx] = result
 
+			mu.Lock()
+			defer mu.Unlock()
+			completed++
+			if o.onResult != nil {
+				o.onResult(result)
+			}
 			if onProgress != nil {
-				mu.Lock()
-				completed++
-				c := completed
-				mu.Unlock()
-				onProgress(c, len(specs))
+				onProgress(completed, len(specs))
 			}
 		}(i, spec)
 	}
@@ -167,7 +230,7 @@ func (p *ModelProber) ScanModels(ctx context.Context, specs []entity.ModelProbeS
 }
 
 // ScanAllModels scans all registered models of a given type.
-func (p *ModelProber) ScanAllModels(ctx context.Context, modelType entity.ModelType, probeTypes []entity.ProbeType, onProgress func(completed, total int)) ([]*entity.ModelScanResult, error) {
+func (p *ModelProber) ScanAllModels(ctx context.Context, modelType entity.ModelType, probeTypes []entity.ProbeType, onProgress func(completed, total int), opts ...ScanOption) ([]*entity.ModelScanResult, error) {
 	models, err := p.modelRepo.FindAllByType(ctx, modelType)
 	if err != nil {
 		return nil, err
@@ -187,7 +250,7 @@ func (p *ModelProber) ScanAllModels(ctx context.Context, modelType entity.ModelT
 		})
 	}
 
-	return p.ScanModels(ctx, specs, onProgress)
+	return p.ScanModels(ctx, specs, onProgress, opts...)
 }
 
 // probeViaPlugin checks if the provider plugin implements ProbePlugin and uses it.
@@ -258,6 +321,8 @@ func (p *ModelProber) probeByType(ctx context.Context, instance *entity.ModelIns
 		}
 	case entity.ProbeType_Streaming:
 		result = p.probeStreaming(ctx, cm, start)
+	case entity.ProbeType_ContextWindow:
+		result = p.probeContextWindow(ctx, cm, start)
 	default:
 		result = p.probeChat(ctx, cm, start)
 	}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/helper"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/spi"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/store/inmemory"
+)
+
+// probingPlugin is a stubPlugin with a provider probe that records the time
+// left before each probe's deadline and fails for model "m2".
+type probingPlugin struct {
+	stubPlugin
+
+	mu        *sync.Mutex
+	deadlines map[string]time.Duration
+}
+
+func (p *probingPlugin) Probe(ctx context.Context, instance *entity.ModelInstance, _ *entity.ModelProvider) (*entity.ProbeResult, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		p.mu.Lock()
+		p.deadlines[instance.ModelID] = time.Until(deadline)
+		p.mu.Unlock()
+	}
+	if instance.ModelID == "m2" {
+		return nil, errors.New("unreachable")
+	}
+	return &entity.ProbeResult{OK: true, ProbeType: entity.ProbeType_Chat, Timestamp: time.Now()}, nil
+}
+
+// newTestProber returns a prober over the "stub/m1" and "stub/m2" models
+// and the probe deadlines recorded by probingPlugin.
+func newTestProber(t *testing.T) (*ModelProber, map[string]time.Duration) {
+	t.Helper()
+	builds := 0
+	deadlines := make(map[string]time.Duration)
+	registry := provider.NewRegistry()
+	registry.MustRegister("stub", func() spi.ProviderPlugin {
+		return &probingPlugin{
+			stubPlugin: stubPlugin{BasePlugin: helper.BasePlugin{PluginName: "stub"}, builds: &builds},
+			mu:         &sync.Mutex{},
+			deadlines:  deadlines,
+		}
+	})
+	models, providers := inmemory.NewModelStore(), inmemory.NewProviderStore()
+	m := NewModelManager(twoModelOptions(), models, providers, registry)
+	if err := m.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return NewModelProber(models, providers, registry, m), deadlines
+}
+
+func TestScanModelsProbeTimeout(t *testing.T) {
+	m1 := entity.ModelRef{ProviderID: "stub", ModelID: "m1"}
+	m2 := entity.ModelRef{ProviderID: "stub", ModelID: "m2"}
+	tests := []struct {
+		name  string
+		opts  []ScanOption
+		specs []entity.ModelProbeSpec
+		want  map[string]time.Duration
+	}{
+		{"default", nil,
+			[]entity.ModelProbeSpec{{Ref: m1}},
+			map[string]time.Duration{"m1": defaultProbeTimeout}},
+		{"option", []ScanOption{WithProbeTimeout(2 * time.Second)},
+			[]entity.ModelProbeSpec{{Ref: m1}},
+			map[string]time.Duration{"m1": 2 * time.Second}},
+		{"spec timeout wins", []ScanOption{WithProbeTimeout(2 * time.Second)},
+			[]entity.ModelProbeSpec{{Ref: m1}, {Ref: m2, TimeoutMs: 5000}},
+			map[string]time.Duration{"m1": 2 * time.Second, "m2": 5 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober, deadlines := newTestProber(t)
+			if _, err := prober.ScanModels(context.Background(), tt.specs, nil, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			for model, want := range tt.want {
+				if got := deadlines[model]; got > want || got < want-time.Second {
+					t.Errorf("%s probed with %v left, want about %v", model, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestScanModelsResultCallback(t *testing.T) {
+	prober, _ := newTestProber(t)
+	specs := []entity.ModelProbeSpec{
+		{Ref: entity.ModelRef{ProviderID: "stub", ModelID: "m1"}},
+		{Ref: entity.ModelRef{ProviderID: "stub", ModelID: "m2"}},
+		{Ref: entity.ModelRef{ProviderID: "stub", ModelID: "missing"}},
+	}
+
+	// Calls are serialized, so neither slice needs a lock.
+	var reported []string
+	var progress []int
+	results, err := prober.ScanModels(context.Background(), specs,
+		func(completed, total int) {
+			if total != len(specs) {
+				t.Errorf("total = %d, want %d", total, len(specs))
+			}
+			progress = append(progress, completed)
+		},
+		WithResultCallback(func(r *entity.ModelScanResult) { reported = append(reported, r.Ref.String()) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reported) != len(specs) || len(progress) != len(specs) {
+		t.Fatalf("reported %q, progress %v; want %d of each before ScanModels returns", reported, progress, len(specs))
+	}
+	for i, c := range progress {
+		if c != i+1 {
+			t.Fatalf("progress = %v, want 1..%d", progress, len(specs))
+		}
+	}
+	for i, want := range []bool{true, false, false} {
+		if results[i].Ref != specs[i].Ref || results[i].Available != want {
+			t.Errorf("result %d = %s available %v, want %s available %v",
+				i, results[i].Ref, results[i].Available, specs[i].Ref, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// contextProbeTokens is the approximate size of the oversized prompt sent by
+// the context-window probe. It must exceed any window we expect to discover.
+const contextProbeTokens = 1 << 21
+
+// contextLimitPatterns extract the context window from provider errors, e.g.:
+//   - OpenAI / vLLM / DeepSeek: "This model's maximum context length is 128000 tokens."
+//   - Anthropic: "prompt is too long: 2097160 tokens > 200000 maximum"
+//   - Gemini: "The input token count (2097160) exceeds the maximum number of tokens allowed (1048576)."
+//   - llama.cpp: "the request exceeds the available context size (8192 tokens)"
+var contextLimitPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)maximum context length is (\d+)`),
+	regexp.MustCompile(`(?i)\d+ tokens > (\d+) maximum`),
+	regexp.MustCompile(`(?i)maximum number of tokens allowed \((\d+)\)`),
+	regexp.MustCompile(`(?i)context size \((\d+) tokens\)`),
+}
+
+// probeContextWindow sends a prompt larger than any plausible window and
+// parses the limit from the provider's rejection.
+//
+// The probe succeeds only when a limit was parsed. A provider that accepts the
+// prompt (window larger than the probe) or rejects it without a recognizable
+// message yields a failed result without a ContextWindow.
+func (p *ModelProber) probeContextWindow(ctx context.Context, cm einoModel.BaseChatModel, start time.Time) *entity.ProbeResult {
+	// "hi " encodes to roughly one token per repetition on common tokenizers.
+	prompt := strings.Repeat("hi ", contextProbeTokens)
+
+	_, err := cm.Generate(ctx, []*schema.Message{
+		{Role: schema.User, Content: prompt},
+	})
+	if err == nil {
+		return &entity.ProbeResult{
+			OK:        false,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Error:     "oversized prompt was accepted; context window exceeds the probe size",
+		}
+	}
+
+	window := parseContextLimit(err.Error())
+	if window <= 0 {
+		return &entity.ProbeResult{
+			OK:        false,
+			LatencyMs: time.Since(start).Milliseconds(),
+			Error:     "no context limit in provider error: " + err.Error(),
+		}
+	}
+	return &entity.ProbeResult{
+		OK:            true,
+		LatencyMs:     time.Since(start).Milliseconds(),
+		ContextWindow: window,
+	}
+}
+
+// parseContextLimit returns the context window reported in a provider error
+// message, or 0 when none of the known formats match.
+func parseContextLimit(msg string) int {
+	for _, re := range contextLimitPatterns {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// recordContextWindow caches a discovered context window onto the model
+// instance so that ContextWindowGuard picks it up on subsequent runs.
+func (p *ModelProber) recordContextWindow(ctx context.Context, instance *entity.ModelInstance, result *entity.ModelScanResult) {
+	pr, ok := result.Results[entity.ProbeType_ContextWindow]
+	if !ok || !pr.OK || pr.ContextWindow <= 0 || instance.ProbedContextWindow == pr.ContextWindow {
+		return
+	}
+
+	instance.ProbedContextWindow = pr.ContextWindow
+	if err := p.modelRepo.Save(ctx, instance); err != nil {
+		logger.Warn("[Prober] failed to save probed context window for %s/%s: %v", instance.ProviderID, instance.ModelID, err)
+		return
+	}
+	logger.Info("[Prober] discovered context window %d for %s/%s (configured %d)",
+		pr.ContextWindow, instance.ProviderID, instance.ModelID, instance.ContextWindow)
+}
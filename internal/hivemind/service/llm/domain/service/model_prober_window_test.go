@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	einoModel "github.com/cloudwego/eino/components/model"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+func TestParseContextLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want int
+	}{
+		{"openai", "This model's maximum context length is 128000 tokens. However, your messages resulted in 2097152 tokens.", 128000},
+		{"anthropic", "prompt is too long: 2097160 tokens > 200000 maximum", 200000},
+		{"gemini", "The input token count (2097160) exceeds the maximum number of tokens allowed (1048576).", 1048576},
+		{"llama.cpp", "the request exceeds the available context size (8192 tokens)", 8192},
+		{"unrelated error", "401 unauthorized", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseContextLimit(tt.msg); got != tt.want {
+				t.Fatalf("parseContextLimit = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeContextWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      einoModel.BaseChatModel
+		wantOK     bool
+		wantWindow int
+	}{
+		{"limit reported", &failingChatModel{err: errors.New("maximum context length is 32768 tokens")}, true, 32768},
+		{"unrecognized error", &failingChatModel{err: errors.New("bad request")}, false, 0},
+		{"prompt accepted", &stubChatModel{}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := (&ModelProber{}).probeContextWindow(context.Background(), tt.model, time.Now())
+			if res.OK != tt.wantOK || res.ContextWindow != tt.wantWindow {
+				t.Fatalf("probe = %+v, want ok %v, window %d", res, tt.wantOK, tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestRecordContextWindow(t *testing.T) {
+	ctx := context.Background()
+	prober, _ := newTestProber(t)
+	instance, err := prober.modelRepo.FindByRef(ctx, stubRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := &entity.ModelScanResult{Results: map[entity.ProbeType]*entity.ProbeResult{
+		entity.ProbeType_ContextWindow: {OK: false},
+	}}
+	prober.recordContextWindow(ctx, instance, failed)
+	if instance.ProbedContextWindow != 0 {
+		t.Fatalf("failed probe recorded window %d", instance.ProbedContextWindow)
+	}
+
+	found := &entity.ModelScanResult{Results: map[entity.ProbeType]*entity.ProbeResult{
+		entity.ProbeType_ContextWindow: {OK: true, ContextWindow: 4096},
+	}}
+	prober.recordContextWindow(ctx, instance, found)
+	stored, err := prober.modelRepo.FindByRef(ctx, stubRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ProbedContextWindow != 4096 || stored.ContextWindow != 8192 {
+		t.Fatalf("stored windows = probed %d, configured %d; want 4096, 8192", stored.ProbedContextWindow, stored.ContextWindow)
+	}
+}
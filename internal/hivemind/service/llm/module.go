@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cloudwego/eino/components/model"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
@@ -60,6 +61,7 @@ type Module struct {
 // 4. Create ModelManager with Registry injection
 // 5. Initialize: Registry-based provider discovery + user config + compat rules
 // 6. Create auxiliary services: Prober, FallbackExecutor
+// 7. Optionally probe auto-discovered providers and disable unreachable ones
 func (c CompletedConfig) New(ctx context.Context) (*Module, error) {
 	logger.Info("[LLM] creating LLM module...")
 
@@ -87,6 +89,11 @@ func (c CompletedConfig) New(ctx context.Context) (*Module, error) {
 	// Auxiliary domain services.
 	prober := service.NewModelProber(modelStore, providerStore, registry, manager)
 	fallback := service.NewFallbackExecutor(modelStore, manager)
+	fallback.SetCooldownPolicy(cooldownPolicy(c.ModelOptions.Cooldown))
+
+	if c.ModelOptions.ProbeOnStart {
+		probeOnStart(ctx, c.ModelOptions, registry, manager, prober, modelStore)
+	}
 
 	return &Module{
 		Manager:  manager,
@@ -134,8 +141,8 @@ func (m *Module) ProbeModel(ctx context.Context, ref entity.ModelProbeSpec) (*en
 }
 
 // ScanModels probes multiple models in parallel and returns their availability status.
-func (m *Module) ScanModels(ctx context.Context, specs []entity.ModelProbeSpec, onProcess func(completed, total int)) ([]*entity.ModelScanResult, error) {
-	return m.Prober.ScanModels(ctx, specs, onProcess)
+func (m *Module) ScanModels(ctx context.Context, specs []entity.ModelProbeSpec, onProcess func(completed, total int), opts ...service.ScanOption) ([]*entity.ModelScanResult, error) {
+	return m.Prober.ScanModels(ctx, specs, onProcess, opts...)
 }
 
 // -- Compat convenience methods --
@@ -144,9 +151,37 @@ func (m *Module) ResolveCompat(ctx context.Context, provider entity.ModelRef) (*
 	return m.Manager.ResolveCompat(ctx, provider)
 }
 
+// -- Lifecycle methods --
+
+// Reload applies new model options to the running module without a restart.
+func (m *Module) Reload(ctx context.Context, opts *options.ModelOptions) error {
+	if err := m.Manager.Reload(ctx, opts); err != nil {
+		return err
+	}
+	m.Fallback.SetCooldownPolicy(cooldownPolicy(opts.Cooldown))
+	return nil
+}
+
 // -- Status management methods --
 
 // UpdateModelStatus updates the status of a model in the repository.
 func (m *Module) UpdateModelStatus(ctx context.Context, ref entity.ModelRef, status entity.ModelStatus) error {
 	return m.Manager.SetModelStatus(ctx, ref, status)
 }
+
+// cooldownPolicy converts the configured cooldown options into a CooldownPolicy.
+func cooldownPolicy(opts options.CooldownOptions) service.CooldownPolicy {
+	policy := service.CooldownPolicy{
+		FailureThreshold: opts.FailureThreshold,
+		Duration:         opts.Duration,
+	}
+	for name, d := range opts.Reasons {
+		if reason, ok := entity.ParseFailoverReason(name); ok {
+			if policy.Reasons == nil {
+				policy.Reasons = make(map[entity.FailoverReason]time.Duration)
+			}
+			policy.Reasons[reason] = d
+		}
+	}
+	return policy
+}
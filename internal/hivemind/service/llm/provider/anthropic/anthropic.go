@@ -42,6 +42,12 @@ func (p Plugin) BuildChatModel(ctx context.Context, instance *entity.ModelInstan
 		cfg.BaseURL = &conn.BaseURL
 	}
 
+	httpClient, err := helper.NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	cfg.HTTPClient = httpClient
+
 	// apply runtime LLM params
 	applyParamsToClaudeConfig(cfg, params)
 
@@ -62,6 +68,11 @@ func applyParamsToClaudeConfig(conf *einoClaude.Config, params *entity.LLMParams
 	if params.TopP != nil {
 		conf.TopP = params.TopP
 	}
+	if len(params.Stop) > 0 {
+		conf.StopSequences = params.Stop
+	}
+	helper.IgnoreSeed("anthropic", params)
+	helper.IgnorePenalties("anthropic", params)
 }
 
 func (p *Plugin) DefaultConfig() *options.ProviderConfig {
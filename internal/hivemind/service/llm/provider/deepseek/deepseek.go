@@ -46,6 +46,12 @@ func (p *Plugin) BuildChatModel(ctx context.Context, instance *entity.ModelInsta
 		conf.BaseURL = conn.BaseURL
 	}
 
+	httpClient, err := helper.NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	conf.HTTPClient = httpClient
+
 	applyParamsToDeepseekConfig(conf, params)
 
 	return einoDeepseek.NewChatModel(ctx, conf)
@@ -65,15 +71,19 @@ func applyParamsToDeepseekConfig(conf *einoDeepseek.ChatModelConfig, params *ent
 		conf.MaxTokens = params.MaxTokens
 	}
 
-	if params.FrequencyPenalty != 0 {
-		conf.FrequencyPenalty = params.FrequencyPenalty
+	if params.FrequencyPenalty != nil {
+		conf.FrequencyPenalty = *params.FrequencyPenalty
 	}
 
-	if params.PresencePenalty != 0 {
-		conf.PresencePenalty = params.PresencePenalty
+	if params.PresencePenalty != nil {
+		conf.PresencePenalty = *params.PresencePenalty
 	}
 
-	if params.ResponseFormat == entity.ModelResponseFormatJSON {
+	conf.Stop = params.Stop
+	helper.IgnoreSeed("deepseek", params)
+
+	// DeepSeek only supports json_object; a json_schema request degrades to it.
+	if params.ResponseFormat.IsStructured() {
 		conf.ResponseFormatType = einoDeepseek.ResponseFormatTypeJSONObject
 	} else {
 		conf.ResponseFormatType = einoDeepseek.ResponseFormatTypeText
@@ -6,6 +6,7 @@ import (
 
 	einoGemini "github.com/cloudwego/eino-ext/components/model/gemini"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/eino-contrib/jsonschema"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/helper"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/spi"
@@ -61,6 +62,12 @@ func (p *Plugin) BuildChatModel(ctx context.Context, instance *entity.ModelInsta
 		clientCfg.Location = g.Location
 	}
 
+	httpClient, err := helper.NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	clientCfg.HTTPClient = httpClient
+
 	client, err := genai.NewClient(ctx, clientCfg)
 	if err != nil {
 		return nil, fmt.Errorf("create genai client for %s/%s: %w", provider.ID, instance.ModelID, err)
@@ -98,15 +105,17 @@ func applyParamsToGeminiConfig(conf *einoGemini.Config, params *entity.LLMParams
 
 	conf.TopK = params.TopK
 	conf.TopP = params.TopP
+	helper.IgnoreSeed("gemini", params)
+	helper.IgnoreStop("gemini", params)
+	helper.IgnorePenalties("gemini", params)
 
 	if params.Temperature != nil {
 		t := *params.Temperature
 		conf.Temperature = &t
 	}
-
 	if params.MaxTokens != 0 {
-		mt := params.MaxTokens
-		conf.MaxTokens = &mt
+		n := params.MaxTokens
+		conf.MaxTokens = &n
 	}
 
 	if params.EnableThinking != nil {
@@ -114,6 +123,15 @@ func applyParamsToGeminiConfig(conf *einoGemini.Config, params *entity.LLMParams
 			IncludeThoughts: *params.EnableThinking,
 		}
 	}
+
+	// Gemini enforces JSON through a response schema; plain JSON mode uses a bare object schema.
+	if params.ResponseFormat.IsStructured() {
+		js, err := helper.ParseJSONSchema(params.JSONSchema)
+		if err != nil || js == nil {
+			js = &jsonschema.Schema{Type: "object"}
+		}
+		conf.ResponseJSONSchema = js
+	}
 }
 
 func (p *Plugin) DefaultConfig() *options.ProviderConfig {
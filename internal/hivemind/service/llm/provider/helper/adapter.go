@@ -2,12 +2,15 @@ package helper
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/bytedance/gg/gptr"
 	einoOpenAI "github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
+	"github.com/eino-contrib/jsonschema"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/pkg/logger"
 )
 
 // NewOpenAICompatibleChatModel creates an Eino ChatModel using the OpenAI-compatible API.
@@ -39,6 +42,12 @@ func NewOpenAICompatibleChatModel(ctx context.Context, instance *entity.ModelIns
 		cfg.APIVersion = instance.Connection.Openai.APIVersion
 	}
 
+	httpClient, err := NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	cfg.HTTPClient = httpClient
+
 	applyParamsToOpenAIChatModelConfig(cfg, params)
 
 	return einoOpenAI.NewChatModel(ctx, cfg)
@@ -56,19 +65,88 @@ func applyParamsToOpenAIChatModelConfig(cfg *einoOpenAI.ChatModelConfig, params
 		cfg.MaxTokens = gptr.Of(params.MaxTokens)
 	}
 
-	if params.FrequencyPenalty != 0 {
-		cfg.FrequencyPenalty = gptr.Of(params.FrequencyPenalty)
+	if params.FrequencyPenalty != nil {
+		cfg.FrequencyPenalty = params.FrequencyPenalty
 	}
 
-	if params.PresencePenalty != 0 {
-		cfg.PresencePenalty = gptr.Of(params.PresencePenalty)
+	if params.PresencePenalty != nil {
+		cfg.PresencePenalty = params.PresencePenalty
 	}
 
 	cfg.TopP = params.TopP
+	cfg.Seed = params.Seed
+	cfg.Stop = params.Stop
+
+	if rf := OpenAIResponseFormat(params); rf != nil {
+		cfg.ResponseFormat = rf
+	}
+}
+
+// IgnoreSeed logs that a provider drops the requested seed.
+func IgnoreSeed(provider string, params *entity.LLMParams) {
+	if params != nil && params.Seed != nil {
+		logger.Debug("[LLM] provider %s does not support seed, ignoring seed=%d", provider, *params.Seed)
+	}
+}
+
+// IgnoreStop logs that a provider drops the requested stop sequences.
+func IgnoreStop(provider string, params *entity.LLMParams) {
+	if params != nil && len(params.Stop) > 0 {
+		logger.Debug("[LLM] provider %s does not support stop sequences, ignoring %d stop sequence(s)", provider, len(params.Stop))
+	}
+}
 
-	if params.ResponseFormat == entity.ModelResponseFormatJSON {
-		cfg.ResponseFormat = &einoOpenAI.ChatCompletionResponseFormat{
+// IgnorePenalties logs that a provider drops the requested frequency and
+// presence penalties.
+func IgnorePenalties(provider string, params *entity.LLMParams) {
+	if params != nil && (params.FrequencyPenalty != nil || params.PresencePenalty != nil) {
+		logger.Debug("[LLM] provider %s does not support frequency/presence penalties, ignoring them", provider)
+	}
+}
+
+// OpenAIResponseFormat maps the structured-output params to the OpenAI
+// response_format. Returns nil for plain text.
+//
+// A json_schema whose schema cannot be parsed degrades to json_object.
+func OpenAIResponseFormat(params *entity.LLMParams) *einoOpenAI.ChatCompletionResponseFormat {
+	if params == nil {
+		return nil
+	}
+	switch params.ResponseFormat {
+	case entity.ModelResponseFormatJSON:
+		return &einoOpenAI.ChatCompletionResponseFormat{
 			Type: einoOpenAI.ChatCompletionResponseFormatTypeJSONObject,
 		}
+	case entity.ModelResponseFormatJSONSchema:
+		js, err := ParseJSONSchema(params.JSONSchema)
+		if err != nil || js == nil {
+			logger.Warn("[LLM] invalid json_schema response format, falling back to json_object: %v", err)
+			return &einoOpenAI.ChatCompletionResponseFormat{
+				Type: einoOpenAI.ChatCompletionResponseFormatTypeJSONObject,
+			}
+		}
+		return &einoOpenAI.ChatCompletionResponseFormat{
+			Type: einoOpenAI.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &einoOpenAI.ChatCompletionResponseFormatJSONSchema{
+				Name:        params.JSONSchema.Name,
+				Description: params.JSONSchema.Description,
+				JSONSchema:  js,
+				Strict:      params.JSONSchema.Strict,
+			},
+		}
+	}
+	return nil
+}
+
+// ParseJSONSchema decodes the raw schema of a json_schema response format.
+// Returns nil when no schema is given.
+func ParseJSONSchema(rs *entity.ResponseJSONSchema) (*jsonschema.Schema, error) {
+	if rs == nil || len(rs.Schema) == 0 {
+		return nil, nil
+	}
+	js := &jsonschema.Schema{}
+	if err := json.Unmarshal(rs.Schema, js); err != nil {
+		return nil, fmt.Errorf("parse json schema %q: %w", rs.Name, err)
 	}
+	return js, nil
 }
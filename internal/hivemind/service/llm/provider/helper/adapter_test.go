@@ -0,0 +1,30 @@
+package helper
+
+import (
+	"testing"
+
+	einoOpenAI "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+func TestApplyParamsToOpenAIChatModelConfigSeed(t *testing.T) {
+	seed := 7
+	tests := []struct {
+		name   string
+		params *entity.LLMParams
+		want   *int
+	}{
+		{"nil params", nil, nil},
+		{"no seed", &entity.LLMParams{}, nil},
+		{"seed", &entity.LLMParams{Seed: &seed}, &seed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &einoOpenAI.ChatModelConfig{}
+			applyParamsToOpenAIChatModelConfig(cfg, tt.params)
+			if (cfg.Seed == nil) != (tt.want == nil) || (cfg.Seed != nil && *cfg.Seed != *tt.want) {
+				t.Fatalf("Seed = %v, want %v", cfg.Seed, tt.want)
+			}
+		})
+	}
+}
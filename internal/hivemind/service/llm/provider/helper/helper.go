@@ -35,6 +35,14 @@ func (b *BasePlugin) BuildProvider(cfg *options.ProviderConfig) (*entity.ModelPr
 		authHeader = *cfg.AuthHeader
 	}
 
+	headers := make(map[string]string, len(cfg.DefaultHeaders)+len(cfg.Headers))
+	for k, v := range cfg.DefaultHeaders {
+		headers[k] = v
+	}
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+
 	return &entity.ModelProvider{
 		ID:         b.PluginName,
 		ModelClass: entity.ModelClassFromString(b.PluginName),
@@ -42,7 +50,8 @@ func (b *BasePlugin) BuildProvider(cfg *options.ProviderConfig) (*entity.ModelPr
 		APIKey:     apiKey,
 		API:        api,
 		AuthHeader: authHeader,
-		Headers:    cfg.Headers,
+		Headers:    headers,
+		ProxyURL:   cfg.ProxyURL,
 		Enabled:    true,
 		Name: &entity.I18nText{
 			EnUs: b.PluginName,
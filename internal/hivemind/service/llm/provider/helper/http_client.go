@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+// NewProviderHTTPClient builds the HTTP client a provider plugin hands to its SDK.
+//
+// It applies the provider's ProxyURL and injects its Headers on every outgoing
+// request (without overriding headers the SDK already set, e.g. Authorization).
+// Returns nil when neither is configured, so SDKs keep their own default client.
+func NewProviderHTTPClient(provider *entity.ModelProvider) (*http.Client, error) {
+	if provider == nil || (len(provider.Headers) == 0 && provider.ProxyURL == "") {
+		return nil, nil
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if provider.ProxyURL != "" {
+		proxyURL, err := url.Parse(provider.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url for provider %q: %w", provider.ID, err)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	var rt http.RoundTripper = base
+	if len(provider.Headers) > 0 {
+		rt = &headerTransport{base: base, headers: provider.Headers}
+	}
+
+	return &http.Client{Transport: rt}, nil
+}
+
+// headerTransport adds default headers to each request before delegating to base.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, ResolveEnvValue(v))
+		}
+	}
+	return t.base.RoundTrip(req)
+}
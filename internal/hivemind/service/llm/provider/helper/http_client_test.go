@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+)
+
+func TestNewProviderHTTPClientDefaults(t *testing.T) {
+	for _, provider := range []*entity.ModelProvider{nil, {ID: "p"}} {
+		client, err := NewProviderHTTPClient(provider)
+		if err != nil || client != nil {
+			t.Fatalf("NewProviderHTTPClient(%+v) = %v, %v; want nil so the SDK keeps its client", provider, client, err)
+		}
+	}
+}
+
+func TestNewProviderHTTPClientHeaders(t *testing.T) {
+	t.Setenv("ECHORYN_TEST_ORG", "org-from-env")
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client, err := NewProviderHTTPClient(&entity.ModelProvider{ID: "p", Headers: map[string]string{
+		"X-Team":        "search",
+		"X-Org":         "${ECHORYN_TEST_ORG}",
+		"Authorization": "Bearer configured",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer sdk")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"X-Team", "search"},
+		{"X-Org", "org-from-env"},
+		{"Authorization", "Bearer sdk"},
+	}
+	for _, tt := range tests {
+		if v := got.Get(tt.header); v != tt.want {
+			t.Errorf("%s = %q, want %q", tt.header, v, tt.want)
+		}
+	}
+	if req.Header.Get("X-Team") != "" {
+		t.Error("the caller's request was modified")
+	}
+}
+
+func TestNewProviderHTTPClientProxy(t *testing.T) {
+	client, err := NewProviderHTTPClient(&entity.ModelProvider{ID: "p", ProxyURL: "http://proxy.internal:3128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport = %T, want *http.Transport without headers", client.Transport)
+	}
+	proxy, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "api.example.com"}})
+	if err != nil || proxy == nil || proxy.Host != "proxy.internal:3128" {
+		t.Fatalf("proxy = %v, %v", proxy, err)
+	}
+
+	if _, err := NewProviderHTTPClient(&entity.ModelProvider{ID: "p", ProxyURL: "://bad"}); err == nil {
+		t.Fatal("invalid proxy url accepted")
+	}
+}
@@ -53,6 +53,12 @@ func (p *Plugin) BuildChatModel(ctx context.Context, instance *entity.ModelInsta
 		}
 	}
 
+	httpClient, err := helper.NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	conf.HTTPClient = httpClient
+
 	applyParamsToOllamaConfig(conf, params)
 
 	return einoOllama.NewChatModel(ctx, conf)
@@ -73,17 +79,35 @@ func applyParamsToOllamaConfig(conf *einoOllama.ChatModelConfig, params *entity.
 	if params.TopK != nil {
 		conf.Options.TopK = int(*params.TopK)
 	}
-	if params.FrequencyPenalty != 0 {
-		conf.Options.FrequencyPenalty = params.FrequencyPenalty
+	if params.Seed != nil {
+		conf.Options.Seed = *params.Seed
+	}
+	if len(params.Stop) > 0 {
+		conf.Options.Stop = params.Stop
+	}
+	if params.FrequencyPenalty != nil {
+		conf.Options.FrequencyPenalty = *params.FrequencyPenalty
 	}
-	if params.PresencePenalty != 0 {
-		conf.Options.PresencePenalty = params.PresencePenalty
+	if params.PresencePenalty != nil {
+		conf.Options.PresencePenalty = *params.PresencePenalty
 	}
 	if params.EnableThinking != nil {
 		conf.Thinking = &einoOllama.ThinkValue{
 			Value: params.EnableThinking,
 		}
 	}
+
+	// Ollama's format accepts "json" or a JSON schema object.
+	switch params.ResponseFormat {
+	case entity.ModelResponseFormatJSON:
+		conf.Format = []byte(`"json"`)
+	case entity.ModelResponseFormatJSONSchema:
+		if params.JSONSchema != nil && len(params.JSONSchema.Schema) > 0 {
+			conf.Format = params.JSONSchema.Schema
+		} else {
+			conf.Format = []byte(`"json"`)
+		}
+	}
 }
 
 func (p *Plugin) DefaultConfig() *options.ProviderConfig {
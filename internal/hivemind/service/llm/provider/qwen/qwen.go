@@ -59,6 +59,12 @@ func (p *Plugin) BuildChatModel(ctx context.Context, instance *entity.ModelInsta
 		conf.EnableThinking = gptr.Of(false)
 	}
 
+	httpClient, err := helper.NewProviderHTTPClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	conf.HTTPClient = httpClient
+
 	applyParamsToQwenConfig(conf, params)
 
 	return einoQwen.NewChatModel(ctx, conf)
@@ -71,6 +77,8 @@ func applyParamsToQwenConfig(conf *einoQwen.ChatModelConfig, params *entity.LLMP
 	}
 
 	conf.TopP = params.TopP
+	conf.Seed = params.Seed
+	conf.Stop = params.Stop
 
 	if params.Temperature != nil {
 		conf.Temperature = gptr.Of(*params.Temperature)
@@ -80,17 +88,21 @@ func applyParamsToQwenConfig(conf *einoQwen.ChatModelConfig, params *entity.LLMP
 		conf.MaxTokens = gptr.Of(params.MaxTokens)
 	}
 
-	if params.FrequencyPenalty != 0 {
-		conf.FrequencyPenalty = gptr.Of(params.FrequencyPenalty)
+	if params.FrequencyPenalty != nil {
+		conf.FrequencyPenalty = params.FrequencyPenalty
 	}
 
-	if params.PresencePenalty != 0 {
-		conf.PresencePenalty = gptr.Of(params.PresencePenalty)
+	if params.PresencePenalty != nil {
+		conf.PresencePenalty = params.PresencePenalty
 	}
 
 	if params.EnableThinking != nil {
 		conf.EnableThinking = params.EnableThinking
 	}
+
+	if rf := helper.OpenAIResponseFormat(params); rf != nil {
+		conf.ResponseFormat = rf
+	}
 }
 
 func (p *Plugin) DefaultConfig() *options.ProviderConfig {
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/repo"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider"
+	"github.com/kiosk404/echoryn/internal/pkg/options"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// probeOnStart checks the providers that were auto-discovered from the
+// registry (API key present in the environment) by probing each one's default
+// model, the first model of its plugin config. Every model of an unreachable
+// provider is marked Disabled, so a bad key surfaces at startup rather than on
+// the first chat. If the default model ends up disabled, another ready LLM
+// takes its place.
+func probeOnStart(
+	ctx context.Context,
+	opts *options.ModelOptions,
+	registry *provider.Registry,
+	manager service.ModelManager,
+	prober *service.ModelProber,
+	modelRepo repo.ModelRepository,
+) {
+	if opts.Mode == "replace" {
+		return
+	}
+
+	providers, err := manager.ListProviders(ctx)
+	if err != nil {
+		logger.Warn("[LLM] startup probe: failed to list providers: %v", err)
+		return
+	}
+
+	var specs []entity.ModelProbeSpec
+	for _, p := range providers {
+		if _, userConfigured := opts.Providers[p.ID]; userConfigured {
+			continue
+		}
+		factory, err := registry.Get(p.ID)
+		if err != nil {
+			continue
+		}
+		cfg := factory().DefaultConfig()
+		if cfg == nil || len(cfg.Models) == 0 {
+			continue
+		}
+		specs = append(specs, entity.ModelProbeSpec{
+			Ref: entity.ModelRef{ProviderID: p.ID, ModelID: cfg.Models[0].ID},
+		})
+	}
+	if len(specs) == 0 {
+		return
+	}
+
+	logger.Info("[LLM] startup probe: checking %d auto-discovered providers", len(specs))
+	results, _ := prober.ScanModels(ctx, specs, nil)
+
+	for _, r := range results {
+		if r.Available {
+			continue
+		}
+		reason := "probe failed"
+		if chat := r.Results[entity.ProbeType_Chat]; chat != nil && chat.Error != "" {
+			reason = chat.Error
+		}
+		logger.Warn("[LLM] startup probe: provider %s is unreachable (%s), disabling its models", r.Ref.ProviderID, reason)
+
+		models, _ := manager.ListModelsByProvider(ctx, r.Ref.ProviderID)
+		for _, m := range models {
+			ref := entity.ModelRef{ProviderID: m.ProviderID, ModelID: m.ModelID}
+			if err := manager.SetModelStatus(ctx, ref, entity.ModelStatus_Disabled); err != nil {
+				logger.Warn("[LLM] startup probe: failed to disable %s: %v", ref, err)
+			}
+		}
+	}
+
+	reselectDefault(ctx, modelRepo)
+}
+
+// reselectDefault moves the default off a disabled model, onto the first
+// ready LLM in provider/model order. The default is left alone when no other
+// model is ready.
+func reselectDefault(ctx context.Context, modelRepo repo.ModelRepository) {
+	current, err := modelRepo.FindDefault(ctx)
+	if err != nil || current.Status != entity.ModelStatus_Disabled {
+		return
+	}
+
+	all, err := modelRepo.FindAllByType(ctx, entity.ModelType_LLM)
+	if err != nil {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].ProviderID != all[j].ProviderID {
+			return all[i].ProviderID < all[j].ProviderID
+		}
+		return all[i].ModelID < all[j].ModelID
+	})
+
+	for _, m := range all {
+		if m.Status != entity.ModelStatus_Ready {
+			continue
+		}
+		cleared := *current
+		cleared.IsDefault = false
+		if err := modelRepo.Save(ctx, &cleared); err != nil {
+			logger.Warn("[LLM] failed to clear default flag of %s/%s: %v", current.ProviderID, current.ModelID, err)
+		}
+		if err := modelRepo.SetDefault(ctx, m.ID); err != nil {
+			logger.Warn("[LLM] failed to set default model %s/%s: %v", m.ProviderID, m.ModelID, err)
+			return
+		}
+		logger.Warn("[LLM] default model %s/%s is disabled, using %s/%s instead",
+			current.ProviderID, current.ModelID, m.ProviderID, m.ModelID)
+		return
+	}
+	logger.Warn("[LLM] default model %s/%s is disabled and no other model is ready",
+		current.ProviderID, current.ModelID)
+}
@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/domain/service"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/helper"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/provider/spi"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/llm/store/inmemory"
+	"github.com/kiosk404/echoryn/internal/pkg/options"
+)
+
+// probePlugin is an auto-discoverable provider with models "<name>-1" and
+// "<name>-2" whose probe fails when reachable is false.
+type probePlugin struct {
+	helper.BasePlugin
+	reachable bool
+}
+
+func (p *probePlugin) DefaultConfig() *options.ProviderConfig {
+	return &options.ProviderConfig{
+		BaseURL: "http://" + p.PluginName + ".local/v1",
+		APIKey:  "sk-test",
+		API:     "openai-completions",
+		Models: []options.ModelDefinition{
+			{ID: p.PluginName + "-1"},
+			{ID: p.PluginName + "-2"},
+		},
+	}
+}
+
+func (p *probePlugin) Probe(context.Context, *entity.ModelInstance, *entity.ModelProvider) (*entity.ProbeResult, error) {
+	if !p.reachable {
+		return nil, errors.New("401 invalid api key")
+	}
+	return &entity.ProbeResult{OK: true, ProbeType: entity.ProbeType_Chat, Timestamp: time.Now()}, nil
+}
+
+// newProbeSetup registers the "good" and "bad" providers, with bad-1 as the
+// default model, and returns the pieces probeOnStart needs.
+func newProbeSetup(t *testing.T, opts *options.ModelOptions) (*provider.Registry, service.ModelManager, *service.ModelProber, *inmemory.ModelStore) {
+	t.Helper()
+	registry := provider.NewRegistry()
+	for name, reachable := range map[string]bool{"good": true, "bad": false} {
+		registry.MustRegister(name, func() spi.ProviderPlugin {
+			return &probePlugin{BasePlugin: helper.BasePlugin{PluginName: name}, reachable: reachable}
+		})
+	}
+	models, providers := inmemory.NewModelStore(), inmemory.NewProviderStore()
+	// The in-memory store reads a default ID of 0 as "unset", so keep that ID
+	// away from the registered models, whose order follows the registry map.
+	if err := models.Save(context.Background(), &entity.ModelInstance{ProviderID: "placeholder", ModelID: "embed", Type: entity.ModelType_TextEmbedding}); err != nil {
+		t.Fatal(err)
+	}
+	manager := service.NewModelManager(opts, models, providers, registry)
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return registry, manager, service.NewModelProber(models, providers, registry, manager), models
+}
+
+func modelStatus(t *testing.T, models *inmemory.ModelStore, providerID, modelID string) entity.ModelStatus {
+	t.Helper()
+	ref := entity.ModelRef{ProviderID: providerID, ModelID: modelID}
+	inst, err := models.FindByRef(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("%s: %v", ref, err)
+	}
+	return inst.Status
+}
+
+func TestProbeOnStart(t *testing.T) {
+	ctx := context.Background()
+	opts := &options.ModelOptions{Mode: "merge", DefaultProvider: "bad", DefaultModel: "bad-1"}
+	registry, manager, prober, models := newProbeSetup(t, opts)
+
+	probeOnStart(ctx, opts, registry, manager, prober, models)
+
+	tests := []struct {
+		provider, model string
+		want            entity.ModelStatus
+	}{
+		{"bad", "bad-1", entity.ModelStatus_Disabled},
+		{"bad", "bad-2", entity.ModelStatus_Disabled},
+		{"good", "good-1", entity.ModelStatus_Ready},
+		{"good", "good-2", entity.ModelStatus_Ready},
+	}
+	for _, tt := range tests {
+		if got := modelStatus(t, models, tt.provider, tt.model); got != tt.want {
+			t.Errorf("%s/%s status = %s, want %s", tt.provider, tt.model, got, tt.want)
+		}
+	}
+
+	def, err := models.FindDefault(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.ProviderID != "good" || def.ModelID != "good-1" {
+		t.Fatalf("default = %s/%s, want good/good-1", def.ProviderID, def.ModelID)
+	}
+	if old, _ := models.FindByRef(ctx, entity.ModelRef{ProviderID: "bad", ModelID: "bad-1"}); old.IsDefault {
+		t.Fatal("disabled model still flagged as default")
+	}
+}
+
+func TestProbeOnStartSkips(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *options.ModelOptions
+	}{
+		{"replace mode", &options.ModelOptions{Mode: "replace", Providers: map[string]*options.ProviderConfig{
+			"bad": {BaseURL: "http://bad.local/v1", APIKey: "sk-test", API: "openai-completions",
+				Models: []options.ModelDefinition{{ID: "bad-1"}}},
+		}}},
+		{"user configured", &options.ModelOptions{Mode: "merge", Providers: map[string]*options.ProviderConfig{
+			"bad": {BaseURL: "http://bad.local/v1", APIKey: "sk-test", API: "openai-completions",
+				Models: []options.ModelDefinition{{ID: "bad-1"}}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, manager, prober, models := newProbeSetup(t, tt.opts)
+			probeOnStart(context.Background(), tt.opts, registry, manager, prober, models)
+			if got := modelStatus(t, models, "bad", "bad-1"); got == entity.ModelStatus_Disabled {
+				t.Fatal("user-configured provider was disabled by the startup probe")
+			}
+		})
+	}
+}
+
+func TestReselectDefaultKeepsDefaultWithoutReadyModel(t *testing.T) {
+	ctx := context.Background()
+	models := inmemory.NewModelStore()
+	for _, inst := range []*entity.ModelInstance{
+		{ProviderID: "a", ModelID: "m1", Type: entity.ModelType_LLM, Status: entity.ModelStatus_Disabled},
+		{ProviderID: "a", ModelID: "m2", Type: entity.ModelType_LLM, Status: entity.ModelStatus_Disabled},
+	} {
+		if err := models.Save(ctx, inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current, _ := models.FindByRef(ctx, entity.ModelRef{ProviderID: "a", ModelID: "m2"})
+	if err := models.SetDefault(ctx, current.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reselectDefault(ctx, models)
+
+	def, err := models.FindDefault(ctx)
+	if err != nil || def.ModelID != "m2" {
+		t.Fatalf("default = %v, %v; want a/m2 kept", def, err)
+	}
+}
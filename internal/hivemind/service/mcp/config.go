@@ -3,6 +3,7 @@ package mcp
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/kiosk404/echoryn/pkg/utils/json"
 )
@@ -13,6 +14,7 @@ import (
 // File format (mcp.json):
 //
 //	{
+//	  "toolNameSeparator": "__",
 //	  "mcpServers": {
 //	    "server-name": {
 //	      "transport": "stdio",
@@ -25,6 +27,49 @@ type MCPConfig struct {
 	// MCPServers maps server name → server configuration.
 	// Uses "mcpServers" key for Claude Desktop compatibility.
 	MCPServers map[string]*ServerConfig `json:"mcpServers"`
+
+	// ToolNameSeparator joins server and tool names in the tool names exposed
+	// to the model ("<server><sep><tool>"). Default: "__".
+	ToolNameSeparator string `json:"toolNameSeparator,omitempty"`
+
+	// CircuitBreakerThreshold is the number of consecutive tool calls failing
+	// on the server itself (timeouts, connection errors, 5xx replies) after
+	// which a server is marked unhealthy: its tools are withdrawn and it is
+	// reconnected in the background. Default: 3.
+	CircuitBreakerThreshold int `json:"circuitBreakerThreshold,omitempty"`
+
+	// ToolBreakerThreshold is the number of consecutive failed calls of a
+	// single tool, within ToolBreakerWindowSeconds, after which the tool's
+	// circuit opens: calls return a "temporarily unavailable" result to the
+	// model without reaching the server, until a probe call after
+	// ToolBreakerCooldownSeconds succeeds. Default: 3; negative disables.
+	ToolBreakerThreshold int `json:"toolBreakerThreshold,omitempty"`
+
+	// ToolBreakerWindowSeconds bounds a tool's failure streak. Default: 60.
+	ToolBreakerWindowSeconds int `json:"toolBreakerWindowSeconds,omitempty"`
+
+	// ToolBreakerCooldownSeconds is how long an open tool circuit
+	// short-circuits calls before probing. Default: 30.
+	ToolBreakerCooldownSeconds int `json:"toolBreakerCooldownSeconds,omitempty"`
+}
+
+// DefaultCircuitBreakerThreshold is the default MCPConfig.CircuitBreakerThreshold.
+const DefaultCircuitBreakerThreshold = 3
+
+// Defaults for the per-tool circuit breakers (see MCPConfig.ToolBreaker*).
+const (
+	DefaultToolBreakerThreshold       = 3
+	DefaultToolBreakerWindowSeconds   = 60
+	DefaultToolBreakerCooldownSeconds = 30
+)
+
+// ToolBreaker returns the per-tool circuit breaker configuration.
+func (c *MCPConfig) ToolBreaker() ToolBreakerConfig {
+	return ToolBreakerConfig{
+		Threshold: c.ToolBreakerThreshold,
+		Window:    time.Duration(c.ToolBreakerWindowSeconds) * time.Second,
+		Cooldown:  time.Duration(c.ToolBreakerCooldownSeconds) * time.Second,
+	}
 }
 
 // ServerConfig defines the configuration for a single MCP server.
@@ -58,6 +103,11 @@ type ServerConfig struct {
 	// ToolFilter is an optional list of tool names to expose.
 	// If empty, all tools from the MCP server are exposed.
 	ToolFilter []string `json:"toolFilter,omitempty"`
+
+	// VisionTools lists the server's tools that return or work on images
+	// (e.g. screenshots). They are not offered to agents whose models have
+	// no image understanding.
+	VisionTools []string `json:"visionTools,omitempty"`
 }
 
 // LoadMCPConfig loads the MCP configuration from a JSON file.
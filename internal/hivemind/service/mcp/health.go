@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+const (
+	// healthCheckInterval is how often connected servers are pinged.
+	healthCheckInterval = 30 * time.Second
+
+	// healthCheckTimeout bounds a single ping.
+	healthCheckTimeout = 10 * time.Second
+
+	// reconnectTimeout bounds a single reconnect attempt.
+	reconnectTimeout = 30 * time.Second
+
+	// reconnectBaseDelay and reconnectMaxDelay bound the exponential backoff
+	// between reconnect attempts.
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 2 * time.Minute
+)
+
+// startHealthMonitor launches the background loop that pings connected
+// servers and reconnects failed ones. It stops when the manager is closed.
+func (m *managerImpl) startHealthMonitor() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.bgCtx.Done():
+				return
+			case <-ticker.C:
+				m.checkHealth()
+			}
+		}
+	}()
+}
+
+// checkHealth pings every connected server and schedules a reconnect for
+// servers that failed the ping or are already in an error state.
+func (m *managerImpl) checkHealth() {
+	for _, srv := range m.serverList() {
+		switch srv.Status() {
+		case ServerStatusConnected:
+			ctx, cancel := context.WithTimeout(m.bgCtx, healthCheckTimeout)
+			err := srv.Ping(ctx)
+			cancel()
+			if err == nil {
+				continue
+			}
+			logger.Warn("[MCP] server %q failed health check, marking unhealthy: %v", srv.Name(), err)
+			srv.markUnhealthy(err)
+			m.scheduleReconnect(srv)
+		case ServerStatusError:
+			m.scheduleReconnect(srv)
+		}
+	}
+}
+
+// scheduleReconnect starts a background reconnect loop with exponential
+// backoff for srv, unless one is already running.
+func (m *managerImpl) scheduleReconnect(srv *MCPServer) {
+	if !srv.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer srv.reconnecting.Store(false)
+
+		delay := reconnectBaseDelay
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-m.bgCtx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			ctx, cancel := context.WithTimeout(m.bgCtx, reconnectTimeout)
+			err := srv.Reconnect(ctx)
+			cancel()
+			if m.bgCtx.Err() != nil {
+				return
+			}
+			if err == nil {
+				logger.Info("[MCP] server %q reconnected after %d attempt(s), %d tools available",
+					srv.Name(), attempt, len(srv.Tools()))
+				return
+			}
+
+			delay = nextReconnectDelay(delay)
+			logger.Warn("[MCP] server %q reconnect attempt %d failed, retrying in %s: %v", srv.Name(), attempt, delay, err)
+		}
+	}()
+}
+
+// nextReconnectDelay doubles delay, capped at reconnectMaxDelay.
+func nextReconnectDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > reconnectMaxDelay {
+		delay = reconnectMaxDelay
+	}
+	return delay
+}
+
+// serverList returns the servers in config order.
+func (m *managerImpl) serverList() []*MCPServer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*MCPServer, 0, len(m.order))
+	for _, name := range m.order {
+		list = append(list, m.servers[name])
+	}
+	return list
+}
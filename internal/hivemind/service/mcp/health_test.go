@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+func TestNextReconnectDelay(t *testing.T) {
+	var got []time.Duration
+	delay := reconnectBaseDelay
+	for range 9 {
+		delay = nextReconnectDelay(delay)
+		got = append(got, delay)
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, 64 * time.Second, reconnectMaxDelay, reconnectMaxDelay, reconnectMaxDelay}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCheckHealthMarksDroppedServer(t *testing.T) {
+	m := newManager(&MCPConfig{MCPServers: map[string]*ServerConfig{
+		"failed": {Transport: "stdio"},
+		"down":   {Transport: "stdio"},
+	}})
+	m.servers["failed"].status = ServerStatusError
+	// "down" claims to be connected but has lost its client.
+	down := m.servers["down"]
+	down.status = ServerStatusConnected
+	down.tools = []tool.BaseTool{&fakeTool{name: "search"}}
+
+	m.checkHealth()
+
+	health := make(map[string]ServerHealth)
+	for _, h := range m.Health() {
+		health[h.Name] = h
+	}
+	h, ok := health["down"]
+	if !ok {
+		t.Fatalf("Health() = %+v, want the unhealthy server listed", m.Health())
+	}
+	if h.Status != ServerStatusError || !strings.Contains(h.Reason, "not connected") {
+		t.Fatalf("down = %+v, want error status with the ping failure as reason", h)
+	}
+	if !h.Reconnecting || h.ToolCount != 0 {
+		t.Fatalf("down = %+v, want a reconnect scheduled and its tools dropped", h)
+	}
+	// Servers already in an error state are retried as well.
+	if !health["failed"].Reconnecting {
+		t.Fatalf("failed = %+v, want a reconnect scheduled", health["failed"])
+	}
+
+	// Close stops the reconnect loops without waiting out the backoff.
+	done := make(chan struct{})
+	go func() {
+		m.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked on the reconnect loops")
+	}
+	if down.reconnecting.Load() {
+		t.Fatal("reconnect loop still running after Close")
+	}
+}
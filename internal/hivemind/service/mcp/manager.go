@@ -6,6 +6,24 @@ import (
 	"github.com/cloudwego/eino/components/tool"
 )
 
+// ServerHealth describes the connection health of one MCP server.
+type ServerHealth struct {
+	Name   string
+	Status ServerStatus
+	// Reason is the last connection error; empty when connected.
+	Reason string
+	// Reconnecting is true while a background reconnect is in progress.
+	Reconnecting bool
+	// ToolCount is the number of tools currently exposed by the server.
+	ToolCount int
+	// ConsecutiveFailures counts tool calls that failed in a row; the circuit
+	// opens (Status becomes Error) when it reaches the configured threshold.
+	ConsecutiveFailures int
+	// ToolBreakers lists the server's tools whose circuit is open or
+	// half-open, or that have failures counting toward opening it.
+	ToolBreakers []ToolBreakerStatus
+}
+
 // Manager manages multiple MCP server connections and providers
 // a unified tool discovery interface for the agent execution pipeline.
 type Manager interface {
@@ -27,6 +45,10 @@ type Manager interface {
 	// ServerStatus returns the current status of a specific server.
 	ServerStatus(serverName string) ServerStatus
 
+	// Health returns the health of all configured servers in config order,
+	// including unhealthy ones with the reason.
+	Health() []ServerHealth
+
 	// Close closes all MCP server connections.
 	Close() error
 }
@@ -14,19 +14,31 @@ type managerImpl struct {
 	mu      sync.RWMutex
 	servers map[string]*MCPServer
 	order   []string // preserves config order
+
+	// bgCtx is cancelled on Close to stop the health monitor and reconnect
+	// loops; wg tracks them.
+	bgCtx    context.Context
+	bgCancel context.CancelFunc
+	wg       sync.WaitGroup
 }
 
 // Ensure managerImpl implements Manager.
 var _ Manager = (*managerImpl)(nil)
 
 func newManager(cfg *MCPConfig) *managerImpl {
+	bgCtx, bgCancel := context.WithCancel(context.Background())
 	m := &managerImpl{
-		servers: make(map[string]*MCPServer, len(cfg.MCPServers)),
-		order:   make([]string, 0, len(cfg.MCPServers)),
+		servers:  make(map[string]*MCPServer, len(cfg.MCPServers)),
+		order:    make([]string, 0, len(cfg.MCPServers)),
+		bgCtx:    bgCtx,
+		bgCancel: bgCancel,
 	}
 
 	for name, srvCfg := range cfg.MCPServers {
-		m.servers[name] = NewMCPServer(name, srvCfg)
+		srv := NewMCPServer(name, srvCfg, cfg.ToolNameSeparator)
+		srv.setCircuitBreaker(cfg.CircuitBreakerThreshold, m.scheduleReconnect)
+		srv.toolBreakers = newToolBreakers(cfg.ToolBreaker())
+		m.servers[name] = srv
 		m.order = append(m.order, name)
 	}
 
@@ -35,6 +47,8 @@ func newManager(cfg *MCPConfig) *managerImpl {
 
 // Initialize connects to all configured MCP servers concurrently.
 // Individual server failures are logged but don't prevent other servers from connecting.
+// Failed servers are retried in the background, and connected servers are
+// health-checked periodically and reconnected when they drop.
 func (m *managerImpl) Initialize(ctx context.Context) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -43,6 +57,7 @@ func (m *managerImpl) Initialize(ctx context.Context) error {
 		logger.Info("[MCP] no MCP servers configured, skipping initialization")
 		return nil
 	}
+	defer m.startHealthMonitor()
 
 	logger.Info("[MCP] initializing %d MCP servers...", len(m.servers))
 
@@ -65,11 +80,13 @@ func (m *managerImpl) Initialize(ctx context.Context) error {
 
 	wg.Wait()
 
-	// Count successes.
+	// Count successes; retry the failed servers in the background.
 	connected := 0
 	for _, srv := range m.servers {
 		if srv.Status() == ServerStatusConnected {
 			connected++
+		} else {
+			m.scheduleReconnect(srv)
 		}
 	}
 
@@ -97,13 +114,14 @@ func (m *managerImpl) GetAllTools() []tool.BaseTool {
 	return all
 }
 
-// GetToolsByServer returns tools from a specific server.
+// GetToolsByServer returns tools from a specific server, or nil while the
+// server is not connected (e.g. its circuit breaker is open).
 func (m *managerImpl) GetToolsByServer(serverName string) []tool.BaseTool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	srv, ok := m.servers[serverName]
-	if !ok {
+	if !ok || srv.Status() != ServerStatusConnected {
 		return nil
 	}
 	return srv.Tools()
@@ -144,8 +162,32 @@ func (m *managerImpl) ServerStatus(serverName string) ServerStatus {
 	return srv.Status()
 }
 
-// Close closes all MCP server connections.
+// Health returns the health of all configured servers in config order.
+func (m *managerImpl) Health() []ServerHealth {
+	servers := m.serverList()
+	result := make([]ServerHealth, 0, len(servers))
+	for _, srv := range servers {
+		h := ServerHealth{
+			Name:                srv.Name(),
+			Status:              srv.Status(),
+			Reconnecting:        srv.reconnecting.Load(),
+			ToolCount:           len(srv.Tools()),
+			ConsecutiveFailures: int(srv.failures.Load()),
+			ToolBreakers:        srv.toolBreakers.status(),
+		}
+		if err := srv.Err(); err != nil {
+			h.Reason = err.Error()
+		}
+		result = append(result, h)
+	}
+	return result
+}
+
+// Close stops background reconnects and closes all MCP server connections.
 func (m *managerImpl) Close() error {
+	m.bgCancel()
+	m.wg.Wait()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -22,6 +22,21 @@ func (c *Config) Complete() CompletedConfig {
 	}
 
 	// Fill default values.
+	if c.MCPConfig.ToolNameSeparator == "" {
+		c.MCPConfig.ToolNameSeparator = DefaultToolNameSeparator
+	}
+	if c.MCPConfig.CircuitBreakerThreshold <= 0 {
+		c.MCPConfig.CircuitBreakerThreshold = DefaultCircuitBreakerThreshold
+	}
+	if c.MCPConfig.ToolBreakerThreshold == 0 {
+		c.MCPConfig.ToolBreakerThreshold = DefaultToolBreakerThreshold
+	}
+	if c.MCPConfig.ToolBreakerWindowSeconds <= 0 {
+		c.MCPConfig.ToolBreakerWindowSeconds = DefaultToolBreakerWindowSeconds
+	}
+	if c.MCPConfig.ToolBreakerCooldownSeconds <= 0 {
+		c.MCPConfig.ToolBreakerCooldownSeconds = DefaultToolBreakerCooldownSeconds
+	}
 	for _, srv := range c.MCPConfig.MCPServers {
 		if srv.Transport == "" {
 			srv.Transport = "stdio"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	mcpTool "github.com/cloudwego/eino-ext/components/tool/mcp"
 	"github.com/cloudwego/eino/components/tool"
@@ -39,23 +40,70 @@ func (s ServerStatus) String() string {
 
 // MCPServer represents an MCP server instance.
 type MCPServer struct {
-	name   string
-	config *ServerConfig
+	name      string
+	config    *ServerConfig
+	separator string // tool name separator, see MCPConfig.ToolNameSeparator
 
 	mu     sync.RWMutex
 	client client.MCPClient
 	tools  []tool.BaseTool
 	status ServerStatus
 	err    error
+
+	// reconnecting is set while a background reconnect loop owns the server.
+	reconnecting atomic.Bool
+
+	// Circuit breaker: failures counts consecutive tool calls that did not
+	// reach the server or got a 5xx reply; at threshold (0 disables) the
+	// server is marked unhealthy and onTrip is called to schedule a reconnect.
+	failures  atomic.Int32
+	threshold int
+	onTrip    func(*MCPServer)
+
+	// toolBreakers isolate single failing tools; nil disables them.
+	toolBreakers *toolBreakers
 }
 
 // NewMCPServer creates a new MCP server instance.
-func NewMCPServer(name string, cfg *ServerConfig) *MCPServer {
-
+// Its tools are exposed as "<name><separator><tool>"; an empty separator
+// uses DefaultToolNameSeparator.
+func NewMCPServer(name string, cfg *ServerConfig, separator string) *MCPServer {
+	if separator == "" {
+		separator = DefaultToolNameSeparator
+	}
 	return &MCPServer{
-		name:   name,
-		status: ServerStatusDisconnected,
-		config: cfg,
+		name:      name,
+		status:    ServerStatusDisconnected,
+		config:    cfg,
+		separator: separator,
+	}
+}
+
+// setCircuitBreaker configures the consecutive-failure threshold and the
+// callback invoked when the circuit opens.
+func (s *MCPServer) setCircuitBreaker(threshold int, onTrip func(*MCPServer)) {
+	s.threshold = threshold
+	s.onTrip = onTrip
+}
+
+// recordSuccess closes the circuit after a successful tool call.
+func (s *MCPServer) recordSuccess() {
+	s.failures.Store(0)
+}
+
+// recordFailure counts a tool call that failed on the server (see
+// isServerFailure) and opens the circuit when the
+// threshold is reached: the server's tools are withdrawn until a background
+// reconnect succeeds.
+func (s *MCPServer) recordFailure(err error) {
+	n := s.failures.Add(1)
+	if s.threshold <= 0 || int(n) != s.threshold {
+		return
+	}
+	logger.Warn("[MCP] server %q failed %d tool calls in a row, marking unhealthy: %v", s.name, n, err)
+	s.markUnhealthy(fmt.Errorf("circuit open after %d consecutive tool failures: %w", n, err))
+	if s.onTrip != nil {
+		s.onTrip(s)
 	}
 }
 
@@ -71,7 +119,42 @@ func (s *MCPServer) Status() ServerStatus {
 	return s.status
 }
 
-// Tools returns the discovered tools (empty if not connected).
+// Err returns the reason the server is unhealthy (nil when connected).
+func (s *MCPServer) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// Ping checks that the server connection is alive.
+func (s *MCPServer) Ping(ctx context.Context) error {
+	s.mu.RLock()
+	cli := s.client
+	s.mu.RUnlock()
+
+	if cli == nil {
+		return fmt.Errorf("[MCP] server %q: not connected", s.name)
+	}
+	return cli.Ping(ctx)
+}
+
+// markUnhealthy drops the connection and the server's tools after a failed
+// health check, keeping err as the reason.
+func (s *MCPServer) markUnhealthy(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		_ = s.client.Close()
+		s.client = nil
+	}
+	s.tools = nil
+	s.status = ServerStatusError
+	s.err = err
+}
+
+// Tools returns the discovered tools (empty if not connected), named
+// "<server><separator><tool>".
 func (s *MCPServer) Tools() []tool.BaseTool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -123,8 +206,9 @@ func (s *MCPServer) Connect(ctx context.Context) error {
 	}
 
 	s.client = cli
-	s.tools = tools
+	s.tools = namespaceTools(ctx, s, tools)
 	s.status = ServerStatusConnected
+	s.failures.Store(0)
 
 	return nil
 }
@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// BreakerState is the state of a per-tool circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets calls through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen short-circuits calls until the cooldown has passed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe call through to test recovery.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// ToolBreakerConfig configures the per-tool circuit breakers.
+type ToolBreakerConfig struct {
+	// Threshold is the number of consecutive failures that opens a tool's
+	// circuit. 0 disables per-tool breakers.
+	Threshold int
+	// Window bounds a failure streak: failures further apart than Window
+	// from the first failure of the streak start a new streak.
+	Window time.Duration
+	// Cooldown is how long an open circuit short-circuits calls before a
+	// probe call is let through.
+	Cooldown time.Duration
+}
+
+// ToolBreakerStatus describes a tool circuit that is not closed, or that has
+// failures counting toward opening it.
+type ToolBreakerStatus struct {
+	Tool                string
+	State               BreakerState
+	ConsecutiveFailures int
+	// OpenUntil is when an open circuit half-opens; zero otherwise.
+	OpenUntil time.Time
+}
+
+// toolBreakers holds the circuit breakers of one server's tools by bare tool
+// name. They outlive reconnects, so a tool that keeps failing stays isolated
+// after its server is reconnected.
+type toolBreakers struct {
+	cfg ToolBreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*toolBreaker
+}
+
+type toolBreaker struct {
+	state        BreakerState
+	failures     int
+	firstFailure time.Time
+	openUntil    time.Time
+	probing      bool // a half-open probe call is in flight
+}
+
+func newToolBreakers(cfg ToolBreakerConfig) *toolBreakers {
+	return &toolBreakers{cfg: cfg, now: time.Now, breakers: make(map[string]*toolBreaker)}
+}
+
+// allow reports whether a call to name may proceed. An open circuit whose
+// cooldown has passed half-opens and admits one probe call; calls arriving
+// while the probe is in flight are rejected. retryIn is the remaining
+// cooldown of a rejected call.
+func (b *toolBreakers) allow(name string) (ok bool, retryIn time.Duration) {
+	if b == nil || b.cfg.Threshold <= 0 {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.breakers[name]
+	if br == nil {
+		return true, 0
+	}
+	switch br.state {
+	case BreakerOpen:
+		now := b.now()
+		if now.Before(br.openUntil) {
+			return false, br.openUntil.Sub(now)
+		}
+		br.state = BreakerHalfOpen
+		br.probing = true
+		return true, 0
+	case BreakerHalfOpen:
+		if br.probing {
+			return false, 0
+		}
+		br.probing = true
+		return true, 0
+	}
+	return true, 0
+}
+
+// recordSuccess closes name's circuit.
+func (b *toolBreakers) recordSuccess(name string) {
+	if b == nil || b.cfg.Threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br := b.breakers[name]; br != nil {
+		if br.state != BreakerClosed {
+			logger.Info("[MCP] tool %q recovered, closing its circuit", name)
+		}
+		delete(b.breakers, name)
+	}
+}
+
+// recordFailure counts a failed call of name. A failed probe reopens the
+// circuit; a streak reaching Threshold within Window opens it.
+func (b *toolBreakers) recordFailure(server, name string, err error) {
+	if b == nil || b.cfg.Threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	br := b.breakers[name]
+	if br == nil {
+		br = &toolBreaker{state: BreakerClosed}
+		b.breakers[name] = br
+	}
+
+	if br.state == BreakerHalfOpen {
+		br.failures++
+		br.state = BreakerOpen
+		br.probing = false
+		br.openUntil = now.Add(b.cfg.Cooldown)
+		logger.Warn("[MCP] tool %q of server %q failed its recovery probe, reopening circuit for %s: %v",
+			name, server, b.cfg.Cooldown, err)
+		return
+	}
+
+	if br.failures == 0 || (b.cfg.Window > 0 && now.Sub(br.firstFailure) > b.cfg.Window) {
+		br.failures = 0
+		br.firstFailure = now
+	}
+	br.failures++
+	if br.state == BreakerClosed && br.failures >= b.cfg.Threshold {
+		br.state = BreakerOpen
+		br.openUntil = now.Add(b.cfg.Cooldown)
+		logger.Warn("[MCP] tool %q of server %q failed %d calls in a row, opening circuit for %s: %v",
+			name, server, br.failures, b.cfg.Cooldown, err)
+	}
+}
+
+// release ends a half-open probe whose outcome did not count (e.g. the
+// caller canceled), so that another call may probe.
+func (b *toolBreakers) release(name string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br := b.breakers[name]; br != nil && br.state == BreakerHalfOpen {
+		br.probing = false
+	}
+}
+
+// status lists the tools with an open or half-open circuit or a pending
+// failure streak, sorted by name.
+func (b *toolBreakers) status() []ToolBreakerStatus {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]ToolBreakerStatus, 0, len(b.breakers))
+	for name, br := range b.breakers {
+		st := ToolBreakerStatus{Tool: name, State: br.state, ConsecutiveFailures: br.failures}
+		if br.state == BreakerOpen {
+			st.OpenUntil = br.openUntil
+		}
+		result = append(result, st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tool < result[j].Tool })
+	return result
+}
+
+// unavailableResult is the tool result fed to the model while a tool's
+// circuit is open, so that the run goes on without the tool.
+func unavailableResult(fullName string, retryIn time.Duration) string {
+	if retryIn > 0 {
+		return fmt.Sprintf("Tool %q is temporarily unavailable after repeated failures. It will be retried in about %s; continue without it for now.",
+			fullName, retryIn.Round(time.Second))
+	}
+	return fmt.Sprintf("Tool %q is temporarily unavailable after repeated failures; continue without it for now.", fullName)
+}
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeTool is an MCP tool whose calls fail with err (nil succeeds).
+type fakeTool struct {
+	name string
+	err  error
+}
+
+func (f *fakeTool) Info(context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{Name: f.name}, nil
+}
+
+func (f *fakeTool) InvokableRun(context.Context, string, ...tool.Option) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return "ok", nil
+}
+
+// newBreakerServer returns a connected server with a server breaker at
+// threshold 2 and tool breakers at threshold 2, and a counter of trips.
+func newBreakerServer() (*MCPServer, *int) {
+	srv := NewMCPServer("srv", &ServerConfig{}, "")
+	srv.status = ServerStatusConnected
+	trips := 0
+	srv.setCircuitBreaker(2, func(*MCPServer) { trips++ })
+	srv.toolBreakers = newToolBreakers(ToolBreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	return srv, &trips
+}
+
+func wrap(srv *MCPServer, ft *fakeTool) *namespacedTool {
+	return &namespacedTool{InvokableTool: ft, srv: srv, server: srv.name, name: ft.name, fullName: srv.name + "__" + ft.name}
+}
+
+func TestToolFailuresDoNotTripServer(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"error result", errors.New("failed to call mcp tool, mcp server return error: {\"isError\":true}")},
+		{"invalid params", fmt.Errorf("failed to call mcp tool: %w", fmt.Errorf("%w: missing field", mcp.ErrInvalidParams))},
+		{"method not found", fmt.Errorf("failed to call mcp tool: %w", mcp.ErrMethodNotFound)},
+		{"error result about a connection", errors.New("failed to call mcp tool, mcp server return error: upstream connection refused")},
+		{"client error status", fmt.Errorf("failed to call mcp tool: %w", errors.New("request failed with status 400: bad request"))},
+		{"unknown error", errors.New("failed to call mcp tool: unexpected content type: text/html")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv, trips := newBreakerServer()
+			flaky := wrap(srv, &fakeTool{name: "flaky", err: tt.err})
+			healthy := wrap(srv, &fakeTool{name: "healthy"})
+
+			for i := 0; i < 3; i++ {
+				_, _ = flaky.InvokableRun(ctx, "{}")
+			}
+			if srv.Status() != ServerStatusConnected || *trips != 0 {
+				t.Fatalf("server status = %s after %d trips, want connected", srv.Status(), *trips)
+			}
+			if n := srv.failures.Load(); n != 0 {
+				t.Fatalf("server failures = %d, want 0", n)
+			}
+
+			// The flaky tool's circuit is open; the other tool still runs.
+			out, err := flaky.InvokableRun(ctx, "{}")
+			if err != nil || out == "" || out == "ok" {
+				t.Fatalf("open circuit call = %q, %v; want the unavailable result", out, err)
+			}
+			if out, err := healthy.InvokableRun(ctx, "{}"); err != nil || out != "ok" {
+				t.Fatalf("healthy tool = %q, %v", out, err)
+			}
+			st := srv.toolBreakers.status()
+			if len(st) != 1 || st[0].Tool != "flaky" || st[0].State != BreakerOpen {
+				t.Fatalf("tool breakers = %+v, want only flaky open", st)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestServerFailuresDoNotOpenToolCircuit(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"server error status", errors.New("request failed with status 502: bad gateway")},
+		{"deadline", fmt.Errorf("transport error: %w", context.DeadlineExceeded)},
+		{"net timeout", &url.Error{Op: "Post", URL: "http://mcp.local", Err: timeoutError{}}},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}},
+		{"connection reset", fmt.Errorf("failed to send request: %w", syscall.ECONNRESET)},
+		{"closed transport", errors.New("transport has been closed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv, trips := newBreakerServer()
+			down := wrap(srv, &fakeTool{name: "search", err: fmt.Errorf("failed to call mcp tool: %w", tt.err)})
+
+			for i := 0; i < 2; i++ {
+				if _, err := down.InvokableRun(ctx, "{}"); err == nil {
+					t.Fatalf("call %d succeeded", i)
+				}
+			}
+			if srv.Status() != ServerStatusError || *trips != 1 {
+				t.Fatalf("server status = %s, trips = %d; want error after one trip", srv.Status(), *trips)
+			}
+			if st := srv.toolBreakers.status(); len(st) != 0 {
+				t.Fatalf("tool breakers = %+v, want none for a server failure", st)
+			}
+		})
+	}
+}
+
+func TestIsServerFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"5xx", errors.New("request failed with status 503: unavailable"), true},
+		{"4xx", errors.New("request failed with status 404: not found"), false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"eof", fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF), true},
+		{"invalid params", fmt.Errorf("%w: missing field", mcp.ErrInvalidParams), false},
+		{"unknown", errors.New("something odd"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isServerFailure(tt.err); got != tt.want {
+				t.Fatalf("isServerFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolFailureResetsServerStreak(t *testing.T) {
+	ctx := context.Background()
+	srv, trips := newBreakerServer()
+	transport := wrap(srv, &fakeTool{name: "a", err: errors.New("failed to call mcp tool: connection refused")})
+	badArgs := wrap(srv, &fakeTool{name: "b", err: fmt.Errorf("failed to call mcp tool: %w", mcp.ErrInvalidParams)})
+
+	// A reply from the server in between proves it reachable.
+	_, _ = transport.InvokableRun(ctx, "{}")
+	_, _ = badArgs.InvokableRun(ctx, "{}")
+	_, _ = transport.InvokableRun(ctx, "{}")
+	if srv.Status() != ServerStatusConnected || *trips != 0 {
+		t.Fatalf("server status = %s after %d trips, want connected", srv.Status(), *trips)
+	}
+}
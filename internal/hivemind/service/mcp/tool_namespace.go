@@ -0,0 +1,284 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/pkg/logger"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultToolNameSeparator joins the server name and the tool name in the
+// name exposed to the model, e.g. "github__search".
+const DefaultToolNameSeparator = "__"
+
+// namespacedTool exposes an MCP tool under "<server><sep><tool>" so that tools
+// with the same name on different servers (or plugins) do not collide.
+// Calls are forwarded to the wrapped tool, which belongs to the right server,
+// and their outcome feeds the server's or the tool's circuit breaker.
+type namespacedTool struct {
+	tool.InvokableTool
+	srv      *MCPServer
+	server   string
+	name     string
+	fullName string
+	// requiresVision is set for tools listed in ServerConfig.VisionTools.
+	requiresVision bool
+}
+
+var _ tool.InvokableTool = (*namespacedTool)(nil)
+
+// Info returns the wrapped tool's info under the namespaced name.
+func (t *namespacedTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	info, err := t.InvokableTool.Info(ctx)
+	if err != nil || info == nil {
+		return info, err
+	}
+	cp := *info
+	cp.Name = t.fullName
+	return &cp, nil
+}
+
+// InvokableRun forwards the call, failing fast while the server's circuit is
+// open. While the tool's own circuit is open, the call is answered with a
+// "temporarily unavailable" result instead. A failure the tool itself
+// reported (see isToolFailure) counts toward the tool's breaker only, since
+// the server answered; any other failure counts toward the server's breaker
+// only. Caller cancellation counts toward neither.
+func (t *namespacedTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if status := t.srv.Status(); status != ServerStatusConnected {
+		if err := t.srv.Err(); err != nil {
+			return "", fmt.Errorf("[MCP] server %q is unavailable: %w", t.server, err)
+		}
+		return "", fmt.Errorf("[MCP] server %q is unavailable (%s)", t.server, status)
+	}
+	if ok, retryIn := t.srv.toolBreakers.allow(t.name); !ok {
+		return unavailableResult(t.fullName, retryIn), nil
+	}
+	out, err := t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	switch {
+	case err == nil:
+		t.srv.recordSuccess()
+		t.srv.toolBreakers.recordSuccess(t.name)
+	case ctx.Err() != nil:
+		t.srv.toolBreakers.release(t.name)
+	case isToolFailure(err):
+		// The server answered, so it is reachable.
+		t.srv.recordSuccess()
+		t.srv.toolBreakers.recordFailure(t.server, t.name, err)
+	case isServerFailure(err):
+		t.srv.recordFailure(err)
+		t.srv.toolBreakers.release(t.name)
+	default:
+		// Not known to be the server's fault; only this tool is penalized.
+		t.srv.toolBreakers.recordFailure(t.server, t.name, err)
+	}
+	return out, err
+}
+
+// toolResultErrorMarker is part of the error the eino MCP tool returns for a
+// call result flagged IsError.
+const toolResultErrorMarker = "mcp server return error"
+
+// toolErrors are the JSON-RPC errors a server answers a single bad call with.
+var toolErrors = []error{
+	mcp.ErrParseError,
+	mcp.ErrInvalidRequest,
+	mcp.ErrMethodNotFound,
+	mcp.ErrInvalidParams,
+	mcp.ErrInternalError,
+	mcp.ErrResourceNotFound,
+}
+
+// isToolFailure reports whether err was reported by the server about this
+// call, i.e. an error result or a JSON-RPC error reply, rather than a failure
+// to reach the server.
+func isToolFailure(err error) bool {
+	if strings.Contains(err.Error(), toolResultErrorMarker) {
+		return true
+	}
+	for _, target := range toolErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverErrorStatus matches the error the HTTP transports return for a 5xx reply.
+var serverErrorStatus = regexp.MustCompile(`request failed with status 5\d\d\b`)
+
+// connectionErrors are the syscall errors of a lost or refused connection.
+var connectionErrors = []error{
+	syscall.ECONNREFUSED,
+	syscall.ECONNRESET,
+	syscall.ECONNABORTED,
+	syscall.EPIPE,
+	io.EOF,
+	io.ErrUnexpectedEOF,
+	io.ErrClosedPipe,
+	net.ErrClosed,
+}
+
+// connectionErrorMarkers are parts of the messages of transport errors that
+// are not wrapped, e.g. the stdio and SSE transports' closed-connection errors.
+var connectionErrorMarkers = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"connection has been closed",
+	"transport has been closed",
+	"client not started",
+}
+
+// isServerFailure reports whether err means the server could not be reached
+// or failed as a whole: a timeout, a connection error or a 5xx reply. Errors
+// about the call itself, such as invalid params, are not server failures.
+func isServerFailure(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	for _, target := range connectionErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	msg := err.Error()
+	if serverErrorStatus.MatchString(msg) {
+		return true
+	}
+	for _, marker := range connectionErrorMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceTools wraps the tools discovered on srv with namespaced names.
+// Tools that cannot be invoked or described are returned unchanged.
+func namespaceTools(ctx context.Context, srv *MCPServer, tools []tool.BaseTool) []tool.BaseTool {
+	server := srv.name
+	prefix := sanitizeToolName(server) + srv.separator
+	result := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		it, ok := t.(tool.InvokableTool)
+		if !ok {
+			result = append(result, t)
+			continue
+		}
+		info, err := it.Info(ctx)
+		if err != nil || info == nil {
+			result = append(result, t)
+			continue
+		}
+		result = append(result, &namespacedTool{
+			InvokableTool:  it,
+			srv:            srv,
+			server:         server,
+			name:           info.Name,
+			fullName:       prefix + info.Name,
+			requiresVision: srv.config != nil && slices.Contains(srv.config.VisionTools, info.Name),
+		})
+	}
+	return result
+}
+
+// CollisionPrefix is prepended (with the server's separator) to an MCP tool
+// name that collides with a plugin tool or another MCP tool, e.g.
+// "mcp__github__search".
+const CollisionPrefix = "mcp"
+
+// warnedCollisions records renamed tools already logged, so a collision is
+// reported once rather than on every run.
+var warnedCollisions sync.Map
+
+// DisambiguateTools renames MCP tools whose names are already taken, either by
+// a name in reserved (plugin tools) or by an earlier tool in the list, so every
+// tool the model sees has a distinct callable name. The renamed tool reports
+// the new name from Info, so the prompt and the dispatcher agree. A numeric
+// suffix is added if the prefixed name is also taken. reserved is extended
+// with the resulting names. Non-MCP tools are returned unchanged.
+func DisambiguateTools(ctx context.Context, tools []tool.BaseTool, reserved map[string]struct{}) []tool.BaseTool {
+	result := make([]tool.BaseTool, 0, len(tools))
+	for _, t := range tools {
+		nt, ok := t.(*namespacedTool)
+		if !ok {
+			if info, err := t.Info(ctx); err == nil && info != nil {
+				reserved[info.Name] = struct{}{}
+			}
+			result = append(result, t)
+			continue
+		}
+		if _, taken := reserved[nt.fullName]; !taken {
+			reserved[nt.fullName] = struct{}{}
+			result = append(result, nt)
+			continue
+		}
+
+		base := CollisionPrefix + nt.srv.separator + nt.fullName
+		name := base
+		for i := 2; ; i++ {
+			if _, taken := reserved[name]; !taken {
+				break
+			}
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		if _, warned := warnedCollisions.LoadOrStore(name, struct{}{}); !warned {
+			logger.Warn("[MCP] tool %q of server %q collides with another tool, exposing it as %q", nt.fullName, nt.server, name)
+		}
+		renamed := *nt
+		renamed.fullName = name
+		reserved[name] = struct{}{}
+		result = append(result, &renamed)
+	}
+	return result
+}
+
+// ToolOrigin returns the MCP server and the original (un-prefixed) tool name
+// of a tool obtained from the Manager. ok is false for non-MCP tools.
+func ToolOrigin(t tool.BaseTool) (server, name string, ok bool) {
+	nt, ok := t.(*namespacedTool)
+	if !ok {
+		return "", "", false
+	}
+	return nt.server, nt.name, true
+}
+
+// RequiresVision reports whether t is an MCP tool its server config lists
+// in VisionTools.
+func RequiresVision(t tool.BaseTool) bool {
+	nt, ok := t.(*namespacedTool)
+	return ok && nt.requiresVision
+}
+
+// sanitizeToolName replaces characters that providers reject in function
+// names (anything outside [A-Za-z0-9_-]) with '_'.
+func sanitizeToolName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/tool"
+)
+
+func TestNamespaceTools(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    string
+		separator string
+		want      string
+	}{
+		{"default separator", "github", "", "github__search"},
+		{"custom separator", "github", "-", "github-search"},
+		{"sanitized server name", "my.git hub", "", "my_git_hub__search"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			srv := NewMCPServer(tt.server, &ServerConfig{}, tt.separator)
+			tools := namespaceTools(ctx, srv, []tool.BaseTool{&fakeTool{name: "search"}})
+			info, err := tools[0].Info(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Name != tt.want {
+				t.Fatalf("name = %q, want %q", info.Name, tt.want)
+			}
+			// The display name and server stay readable.
+			if server, name, ok := ToolOrigin(tools[0]); !ok || server != tt.server || name != "search" {
+				t.Fatalf("ToolOrigin = %q, %q, %v", server, name, ok)
+			}
+		})
+	}
+	if _, _, ok := ToolOrigin(&fakeTool{name: "search"}); ok {
+		t.Fatal("a plugin tool reports an MCP origin")
+	}
+}
+
+func TestNamespacedToolRoutesToServer(t *testing.T) {
+	ctx := context.Background()
+	var tools []tool.BaseTool
+	for _, name := range []string{"a", "b"} {
+		srv := NewMCPServer(name, &ServerConfig{}, "")
+		srv.status = ServerStatusConnected
+		// Both servers expose "search"; only b's call fails.
+		ft := &fakeTool{name: "search"}
+		if name == "b" {
+			ft.err = errors.New("served by b")
+		}
+		tools = append(tools, namespaceTools(ctx, srv, []tool.BaseTool{ft})...)
+	}
+
+	if out, err := tools[0].(tool.InvokableTool).InvokableRun(ctx, "{}"); err != nil || out != "ok" {
+		t.Fatalf("a__search = %q, %v; want a's result", out, err)
+	}
+	if _, err := tools[1].(tool.InvokableTool).InvokableRun(ctx, "{}"); err == nil || err.Error() != "served by b" {
+		t.Fatalf("b__search error = %v, want b's", err)
+	}
+}
+
+func TestRequiresVision(t *testing.T) {
+	srv := NewMCPServer("browser", &ServerConfig{VisionTools: []string{"screenshot"}}, "")
+	tools := namespaceTools(context.Background(), srv, []tool.BaseTool{
+		&fakeTool{name: "screenshot"},
+		&fakeTool{name: "navigate"},
+	})
+
+	want := map[string]bool{"browser__screenshot": true, "browser__navigate": false}
+	for _, tl := range tools {
+		info, _ := tl.Info(context.Background())
+		if got := RequiresVision(tl); got != want[info.Name] {
+			t.Fatalf("RequiresVision(%s) = %v, want %v", info.Name, got, want[info.Name])
+		}
+	}
+	if RequiresVision(&fakeTool{name: "screenshot"}) {
+		t.Fatal("a tool not obtained from a server requires vision")
+	}
+}
+
+func TestDisambiguateTools(t *testing.T) {
+	ctx := context.Background()
+	github := namespaceTools(ctx, NewMCPServer("github", &ServerConfig{}, ""), []tool.BaseTool{
+		&fakeTool{name: "search"},
+		&fakeTool{name: "issues"},
+	})
+	// "my_db", "my.db" and "my db" sanitize to the same prefix.
+	dbA := namespaceTools(ctx, NewMCPServer("my_db", &ServerConfig{}, ""), []tool.BaseTool{&fakeTool{name: "query"}})
+	dbB := namespaceTools(ctx, NewMCPServer("my.db", &ServerConfig{}, ""), []tool.BaseTool{&fakeTool{name: "query"}})
+	dbC := namespaceTools(ctx, NewMCPServer("my db", &ServerConfig{}, ""), []tool.BaseTool{&fakeTool{name: "query"}})
+
+	var tools []tool.BaseTool
+	tools = append(tools, github...)
+	tools = append(tools, dbA...)
+	tools = append(tools, dbB...)
+	tools = append(tools, dbC...)
+	tools = append(tools, &fakeTool{name: "local"})
+
+	reserved := map[string]struct{}{"github__search": {}, "mcp__my_db__query": {}}
+	got := DisambiguateTools(ctx, tools, reserved)
+
+	want := []string{"mcp__github__search", "github__issues", "my_db__query", "mcp__my_db__query_2", "mcp__my_db__query_3", "local"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tools, want %d", len(got), len(want))
+	}
+	for i, tl := range got {
+		info, _ := tl.Info(ctx)
+		if info.Name != want[i] {
+			t.Errorf("tool %d = %q, want %q", i, info.Name, want[i])
+		}
+		if _, ok := reserved[info.Name]; !ok {
+			t.Errorf("%q not added to reserved", info.Name)
+		}
+	}
+
+	// Renamed tools still report where they came from, and the originals keep their names.
+	if server, name, ok := ToolOrigin(got[3]); !ok || server != "my.db" || name != "query" {
+		t.Fatalf("ToolOrigin = %q, %q, %v", server, name, ok)
+	}
+	if info, _ := dbB[0].Info(ctx); info.Name != "my_db__query" {
+		t.Fatalf("input tool renamed to %q", info.Name)
+	}
+}
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/cloudwego/eino/components/model"
 )
@@ -12,6 +13,10 @@ type RuntimeAPI interface {
 	// ModelManager returns the LLM model manager for building/retrieving chat models.
 	// Return nil if the LLM module is not available.
 	ModelManager() ModelManager
+
+	// AgentRunner runs other agents on behalf of a plugin (e.g. delegation).
+	// Returns nil until the Agents module has been bound with SetAgentRunner.
+	AgentRunner() AgentRunner
 }
 
 // ModelManager is a plugin-facing subset of the LLM ModelManager interface.
@@ -27,10 +32,23 @@ type ModelManager interface {
 	GetDefaultChatModel(ctx context.Context) (model.BaseChatModel, error)
 }
 
+// AgentRunner is a plugin-facing subset of the agent service. It is bound
+// after plugin initialization, because the Agents module depends on the
+// plugin framework.
+type AgentRunner interface {
+	// RunAgent runs an agent on input in a fresh session and blocks until
+	// the run ends, returning its final answer. A non-empty promptMode
+	// ("full", "minimal", "none") overrides the agent's prompt mode.
+	// Cancelling ctx aborts the run.
+	RunAgent(ctx context.Context, agentID, input, promptMode string) (string, error)
+}
+
 // runtimeAPIImpl creates a RuntimeAPI with the given dependencies.
-// It implements the RuntimeAPI interface, exposing only the ModelManager.
+// It implements the RuntimeAPI interface, exposing the ModelManager and the
+// late-bound AgentRunner.
 type runtimeAPIImpl struct {
 	modelManager ModelManager
+	agentRunner  atomic.Pointer[AgentRunner]
 }
 
 var _ RuntimeAPI = (*runtimeAPIImpl)(nil)
@@ -41,10 +59,25 @@ func NewRuntimeAPI(modelManager ModelManager) RuntimeAPI {
 	return &runtimeAPIImpl{modelManager: modelManager}
 }
 
-func (r runtimeAPIImpl) ModelManager() ModelManager {
+func (r *runtimeAPIImpl) ModelManager() ModelManager {
 	return r.modelManager
 }
 
+func (r *runtimeAPIImpl) AgentRunner() AgentRunner {
+	if runner := r.agentRunner.Load(); runner != nil {
+		return *runner
+	}
+	return nil
+}
+
+// SetAgentRunner binds the agent runner exposed by a RuntimeAPI created with
+// NewRuntimeAPI. It is a no-op for other RuntimeAPI implementations.
+func SetAgentRunner(api RuntimeAPI, runner AgentRunner) {
+	if impl, ok := api.(*runtimeAPIImpl); ok {
+		impl.agentRunner.Store(&runner)
+	}
+}
+
 // PluginAPI is the registration interface given to plugins during Init().
 // Through this API, plugins register their capabilities: Tool, CLI, Hook, Service.
 //
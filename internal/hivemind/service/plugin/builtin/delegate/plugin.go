@@ -0,0 +1,130 @@
+package delegate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+const (
+	// PluginName is the unique identifier for this plugin.
+	PluginName = "delegate"
+
+	// DefaultMaxDepth is the default limit of nested delegations: a delegated
+	// agent may not delegate further.
+	DefaultMaxDepth = 1
+
+	// promptMode is the prompt mode of delegated runs: the sub-agent gets a
+	// clean, focused context instead of the full system prompt.
+	promptMode = "minimal"
+)
+
+// PluginDefinition returns the static metadata for this plugin.
+func PluginDefinition() plugin.Definition {
+	return plugin.Definition{
+		ID:          PluginName,
+		Name:        "Delegate",
+		Description: "Lets an agent hand a subtask to another agent and use its answer",
+	}
+}
+
+// Config holds the configuration for the delegate plugin.
+type Config struct {
+	// MaxDepth limits nested delegations (agent → sub-agent → ...).
+	// A run at depth MaxDepth cannot delegate. Default: DefaultMaxDepth.
+	MaxDepth int
+}
+
+// delegatePlugin is the runtime instance of the delegate plugin.
+type delegatePlugin struct {
+	cfg     Config
+	runtime plugin.RuntimeAPI
+}
+
+// Factory is the PluginFactory for delegate.
+func Factory(args plugin.PluginArgs, handle plugin.Handle) (plugin.Plugin, error) {
+	cfg := Config{MaxDepth: DefaultMaxDepth}
+	if raw, ok := args["config"]; ok {
+		c, ok := raw.(Config)
+		if !ok {
+			return nil, fmt.Errorf("delegate: 'config' must be delegate.Config, got %T", raw)
+		}
+		if c.MaxDepth > 0 {
+			cfg.MaxDepth = c.MaxDepth
+		}
+	}
+
+	var runtime plugin.RuntimeAPI
+	if handle != nil {
+		runtime = handle.RuntimeAPI()
+	}
+	return &delegatePlugin{cfg: cfg, runtime: runtime}, nil
+}
+
+// Name implements plugin.Plugin.
+func (p *delegatePlugin) Name() string {
+	return PluginName
+}
+
+// Init implements plugin.InitPlugin.
+func (p *delegatePlugin) Init(api plugin.PluginAPI) error {
+	api.RegisterTool(plugin.ToolDefinition{
+		Name:        "delegate",
+		Description: "Delegate a focused subtask to another agent. The agent runs in a fresh session without the current conversation, so the task must be self-contained. Returns the agent's final answer.",
+		Parameters: []plugin.ParameterDef{
+			{Name: "agent_id", Type: "string", Description: "ID of the agent to delegate to", Required: true},
+			{Name: "task", Type: "string", Description: "Complete, self-contained description of the subtask", Required: true},
+		},
+		Handler:   p.handleDelegate,
+		Available: p.canDelegate,
+	})
+	return nil
+}
+
+// canDelegate hides the delegate tool from runs that reached the depth limit.
+func (p *delegatePlugin) canDelegate(ctx context.Context) bool {
+	return plugin.DelegationDepthFromContext(ctx) < p.cfg.MaxDepth
+}
+
+func (p *delegatePlugin) handleDelegate(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	agentID, ok := params["agent_id"].(string)
+	if !ok || agentID == "" {
+		return nil, fmt.Errorf("parameter 'agent_id' is required and must be a string")
+	}
+	task, ok := params["task"].(string)
+	if !ok || task == "" {
+		return nil, fmt.Errorf("parameter 'task' is required and must be a non-empty string")
+	}
+
+	var runner plugin.AgentRunner
+	if p.runtime != nil {
+		runner = p.runtime.AgentRunner()
+	}
+	if runner == nil {
+		return nil, fmt.Errorf("agent runtime is not available")
+	}
+
+	parentID := plugin.AgentIDFromContext(ctx)
+	if agentID == parentID {
+		return nil, fmt.Errorf("an agent cannot delegate to itself")
+	}
+	depth := plugin.DelegationDepthFromContext(ctx)
+	if depth >= p.cfg.MaxDepth {
+		return nil, fmt.Errorf("delegation depth limit (%d) reached, handle the task directly", p.cfg.MaxDepth)
+	}
+
+	logger.CtxInfo(ctx, "[Delegate] agent %q delegating to %q (depth=%d)", parentID, agentID, depth+1)
+
+	// The sub-run inherits ctx, so aborting the parent run aborts it too.
+	answer, err := runner.RunAgent(plugin.WithDelegationDepth(ctx, depth+1), agentID, task, promptMode)
+	if err != nil {
+		return nil, fmt.Errorf("delegation to %q failed: %w", agentID, err)
+	}
+
+	return map[string]interface{}{
+		"agent_id": agentID,
+		"answer":   answer,
+	}, nil
+}
@@ -0,0 +1,69 @@
+package delegate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+)
+
+func TestDelegateHiddenAtMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDepth int
+		depth    int
+		want     bool
+	}{
+		{"default top-level", 0, 0, true},
+		{"default delegated", 0, 1, false},
+		{"deeper limit", 2, 1, true},
+		{"deeper limit reached", 2, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Factory(plugin.PluginArgs{"config": Config{MaxDepth: tt.maxDepth}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := plugin.WithDelegationDepth(context.Background(), tt.depth)
+			if got := p.(*delegatePlugin).canDelegate(ctx); got != tt.want {
+				t.Fatalf("canDelegate at depth %d = %v, want %v", tt.depth, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeRuntime exposes a runner that records the depth of each delegation.
+type fakeRuntime struct {
+	plugin.RuntimeAPI
+	depths []int
+}
+
+func (f *fakeRuntime) AgentRunner() plugin.AgentRunner { return f }
+
+func (f *fakeRuntime) RunAgent(ctx context.Context, agentID, input, promptMode string) (string, error) {
+	f.depths = append(f.depths, plugin.DelegationDepthFromContext(ctx))
+	return "done", nil
+}
+
+func TestHandleDelegateDepth(t *testing.T) {
+	rt := &fakeRuntime{}
+	p := &delegatePlugin{cfg: Config{MaxDepth: DefaultMaxDepth}, runtime: rt}
+	params := map[string]interface{}{"agent_id": "b", "task": "summarize the report"}
+
+	if _, err := p.handleDelegate(plugin.WithAgentID(context.Background(), "a"), params); err != nil {
+		t.Fatalf("top-level delegation: %v", err)
+	}
+	if len(rt.depths) != 1 || rt.depths[0] != 1 {
+		t.Fatalf("sub-run depths = %v, want [1]", rt.depths)
+	}
+
+	ctx := plugin.WithDelegationDepth(plugin.WithAgentID(context.Background(), "c"), DefaultMaxDepth)
+	if _, err := p.handleDelegate(ctx, params); err == nil || !strings.Contains(err.Error(), "depth limit") {
+		t.Fatalf("err = %v, want the depth limit refusal", err)
+	}
+	if len(rt.depths) != 1 {
+		t.Fatalf("delegation ran past the depth limit: %v", rt.depths)
+	}
+}
@@ -0,0 +1,306 @@
+package memory_core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	meminternal "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+const (
+	// digestTimeout bounds one digest model call.
+	digestTimeout = 2 * time.Minute
+
+	// digestMaxInputChars caps the notes handed to the digest model per
+	// call; longer months are summarized in parts.
+	digestMaxInputChars = 60000
+
+	// digestMaxMergeLevels bounds how often partial digests are merged again.
+	digestMaxMergeLevels = 3
+
+	// digestInstruction is the system prompt for monthly digests.
+	digestInstruction = `You merge daily memory notes of one month into a single long-term memory digest.
+Keep every durable fact: information about the user, stated preferences, decisions, and open commitments or follow-ups.
+Merge duplicates, drop chatter and details that no longer matter, and prefer the latest note when notes contradict each other.
+Keep dates for decisions and commitments.
+
+Reply with the digest only, as Markdown bullets ("- ..."), optionally grouped under "## " headings by topic.`
+)
+
+// digestGroup is the set of daily files of one month in one directory.
+type digestGroup struct {
+	dir   string // slash-separated directory, e.g. "memory/agents/main"
+	month string // "YYYY-MM"
+	files []meminternal.DatedMemoryFile
+}
+
+// digestPath is the monthly digest file of the group.
+func (g *digestGroup) digestPath() string {
+	return path.Join(g.dir, g.month+".md")
+}
+
+// startDigest runs the digest job every DigestConfig.IntervalMinutes until
+// the plugin is stopped.
+func (p *memoryCorePlugin) startDigest() {
+	interval := time.Duration(p.cfg.Digest.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		logger.Warn("[MemoryCore] memory digest enabled without an interval, not scheduled")
+		return
+	}
+	stop := make(chan struct{})
+	p.digestStop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.runDigest(context.Background(), time.Now()); err != nil {
+					logger.Warn("[MemoryCore] memory digest failed: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	logger.Info("[MemoryCore] memory digest scheduled every %s (min age %d days)", interval, p.cfg.Digest.MinAgeDays)
+}
+
+// runDigest merges the daily files older than DigestConfig.MinAgeDays into
+// monthly digests, deletes them and reindexes. A month is only rewritten
+// while no sync is running, since the sync would index files the job is
+// about to delete; the job stops at the first month that meets a sync.
+func (p *memoryCorePlugin) runDigest(ctx context.Context, now time.Time) error {
+	if p.manager == nil {
+		return nil
+	}
+
+	files, err := meminternal.ListDatedMemoryFiles(p.cfg.WorkspaceDir)
+	if err != nil {
+		return fmt.Errorf("list daily memory files: %w", err)
+	}
+	groups := groupDigestFiles(files, now.AddDate(0, 0, -p.cfg.Digest.MinAgeDays))
+	if len(groups) == 0 {
+		return nil
+	}
+
+	merged := 0
+	for _, g := range groups {
+		if err := p.digestMonth(ctx, g); err != nil {
+			if errors.Is(err, errDigestSyncRunning) {
+				logger.Debug("[MemoryCore] memory digest paused: sync in progress")
+				break
+			}
+			logger.Warn("[MemoryCore] memory digest of %s failed: %v", g.digestPath(), err)
+			continue
+		}
+		merged += len(g.files)
+		logger.Info("[MemoryCore] memory digest: merged %d daily files into %s", len(g.files), g.digestPath())
+	}
+	if merged == 0 {
+		return nil
+	}
+	return p.manager.SyncPending(ctx, "memory-digest")
+}
+
+// errDigestSyncRunning is returned by digestMonth when a sync kept it from
+// rewriting the month's files.
+var errDigestSyncRunning = errors.New("sync in progress")
+
+// digestMonth summarizes a group into its monthly digest, folding in the
+// existing digest if any, then deletes the daily files. Nothing is written
+// or deleted if a daily file changed while it was being summarized.
+func (p *memoryCorePlugin) digestMonth(ctx context.Context, g *digestGroup) error {
+	var sections []string
+	digestPath := g.digestPath()
+	if p.manager.Exists(digestPath) {
+		existing, err := p.manager.ReadFile(digestPath, 0, 0)
+		if err != nil {
+			return fmt.Errorf("read existing digest: %w", err)
+		}
+		sections = append(sections, fmt.Sprintf("# Existing digest\n\n%s\n\n", strings.TrimSpace(existing)))
+	}
+	originals := make(map[string]string, len(g.files))
+	for _, f := range g.files {
+		content, err := p.manager.ReadFile(f.RelPath, 0, 0)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f.RelPath, err)
+		}
+		originals[f.RelPath] = content
+		sections = append(sections, fmt.Sprintf("# %s\n\n%s\n\n", f.Date.Format("2006-01-02"), strings.TrimSpace(content)))
+	}
+
+	summary, err := p.summarizeMonth(ctx, g.month, sections)
+	if err != nil {
+		return err
+	}
+
+	digest := fmt.Sprintf("# Memory digest %s\n\n%s\n", g.month, strings.TrimSpace(summary))
+	ran, err := p.manager.TryExclusive(func() error {
+		for _, f := range g.files {
+			content, err := p.manager.ReadFile(f.RelPath, 0, 0)
+			if err != nil {
+				return fmt.Errorf("re-read %s: %w", f.RelPath, err)
+			}
+			if content != originals[f.RelPath] {
+				return fmt.Errorf("%s changed while it was summarized", f.RelPath)
+			}
+		}
+		if err := p.manager.WriteMemory(ctx, digestPath, digest, false); err != nil {
+			return fmt.Errorf("write digest: %w", err)
+		}
+		for _, f := range g.files {
+			if err := p.manager.DeleteMemory(f.RelPath); err != nil {
+				return fmt.Errorf("delete %s: %w", f.RelPath, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ran {
+		return errDigestSyncRunning
+	}
+	return nil
+}
+
+// summarizeMonth asks the digest model to merge a month of notes, given as
+// the sections of the digest input. Input longer than digestMaxInputChars is
+// summarized in parts whose partial digests are merged in turn, so no note
+// is cut off.
+func (p *memoryCorePlugin) summarizeMonth(ctx context.Context, month string, sections []string) (string, error) {
+	ref := p.cfg.Digest.Model
+	if ref == "" {
+		ref = p.cfg.Flush.Model
+	}
+	cm, err := p.chatModel(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	for level := 0; ; level++ {
+		chunks := packDigestChunks(sections, digestMaxInputChars)
+		if len(chunks) == 1 {
+			return generateDigest(ctx, cm, fmt.Sprintf("Daily notes of %s:\n\n%s", month, chunks[0]))
+		}
+		if level == digestMaxMergeLevels {
+			return "", fmt.Errorf("notes of %s still span %d parts after %d merge levels", month, len(chunks), level)
+		}
+
+		partials := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			partial, err := generateDigest(ctx, cm,
+				fmt.Sprintf("Daily notes of %s, part %d of %d:\n\n%s", month, i+1, len(chunks), chunk))
+			if err != nil {
+				return "", fmt.Errorf("part %d of %d: %w", i+1, len(chunks), err)
+			}
+			partials = append(partials, fmt.Sprintf("# Partial digest %d\n\n%s\n\n", i+1, strings.TrimSpace(partial)))
+		}
+		sections = partials
+	}
+}
+
+// generateDigest runs one digest model call.
+func generateDigest(ctx context.Context, cm model.BaseChatModel, input string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, digestTimeout)
+	defer cancel()
+
+	out, err := cm.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(digestInstruction),
+		schema.UserMessage(input),
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate: %w", err)
+	}
+	if out == nil {
+		return "", fmt.Errorf("digest model returned no message")
+	}
+	if strings.TrimSpace(out.Content) == "" {
+		return "", fmt.Errorf("digest model returned an empty digest")
+	}
+	return out.Content, nil
+}
+
+// packDigestChunks packs sections, in order, into chunks of at most limit
+// runes. A section longer than limit is split at line breaks.
+func packDigestChunks(sections []string, limit int) []string {
+	var (
+		chunks []string
+		cur    strings.Builder
+		curLen int
+	)
+	for _, section := range sections {
+		for _, piece := range splitAtLines(section, limit) {
+			n := utf8.RuneCountInString(piece)
+			if curLen > 0 && curLen+n > limit {
+				chunks = append(chunks, cur.String())
+				cur.Reset()
+				curLen = 0
+			}
+			cur.WriteString(piece)
+			curLen += n
+		}
+	}
+	if curLen > 0 || len(chunks) == 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// splitAtLines splits s into pieces of at most limit runes, cutting after the
+// last line break that fits, or mid-line when a single line is longer.
+func splitAtLines(s string, limit int) []string {
+	r := []rune(s)
+	var pieces []string
+	for len(r) > limit {
+		cut := limit
+		for i := limit - 1; i > 0; i-- {
+			if r[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		pieces = append(pieces, string(r[:cut]))
+		r = r[cut:]
+	}
+	return append(pieces, string(r))
+}
+
+// groupDigestFiles groups the daily files dated before cutoff by directory
+// and month, in path order.
+func groupDigestFiles(files []meminternal.DatedMemoryFile, cutoff time.Time) []*digestGroup {
+	byKey := make(map[string]*digestGroup)
+	for _, f := range files {
+		if !f.Date.Before(cutoff) {
+			continue
+		}
+		dir, month := path.Dir(f.RelPath), f.Date.Format("2006-01")
+		key := dir + "\x00" + month
+		g, ok := byKey[key]
+		if !ok {
+			g = &digestGroup{dir: dir, month: month}
+			byKey[key] = g
+		}
+		g.files = append(g.files, f)
+	}
+
+	groups := make([]*digestGroup, 0, len(byKey))
+	for _, g := range byKey {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].digestPath() < groups[j].digestPath() })
+	return groups
+}
@@ -0,0 +1,255 @@
+package memory_core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
+)
+
+// stubChatModel replies to every Generate call with reply(input), where
+// input is the user message, and records the inputs.
+type stubChatModel struct {
+	mu     sync.Mutex
+	inputs []string
+	reply  func(input string) (string, error)
+}
+
+func (s *stubChatModel) Generate(_ context.Context, msgs []*schema.Message, _ ...model.Option) (*schema.Message, error) {
+	input := msgs[len(msgs)-1].Content
+	s.mu.Lock()
+	s.inputs = append(s.inputs, input)
+	s.mu.Unlock()
+	out, err := s.reply(input)
+	if err != nil {
+		return nil, err
+	}
+	return schema.AssistantMessage(out, nil), nil
+}
+
+func (s *stubChatModel) Stream(context.Context, []*schema.Message, ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubChatModel) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.inputs...)
+}
+
+// stubModels serves cm as every chat model.
+type stubModels struct{ cm model.BaseChatModel }
+
+func (s stubModels) GetChatModel(context.Context, string, string) (model.BaseChatModel, error) {
+	return s.cm, nil
+}
+
+func (s stubModels) GetDefaultChatModel(context.Context) (model.BaseChatModel, error) {
+	return s.cm, nil
+}
+
+// newTestManager creates a manager over a temporary workspace whose
+// embeddings come from a local stub of the OpenAI embeddings API.
+func newTestManager(t *testing.T) (*manager.Manager, *entity.MemoryConfig) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		resp := struct {
+			Data []item `json:"data"`
+		}{}
+		for i := range req.Input {
+			resp.Data = append(resp.Data, item{Index: i, Embedding: []float32{1, float32(i), 0}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := entity.DefaultMemoryConfig()
+	cfg.WorkspaceDir = t.TempDir()
+	cfg.Embedding.Remote = &entity.RemoteEmbeddingConfig{APIKey: "test", BaseURL: srv.URL}
+	cfg.Sync.Watch = false
+	cfg.Sync.WriteDebounceMs = 0
+
+	m, err := manager.Get(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m, cfg
+}
+
+// newDigestPlugin returns a plugin whose digest model is cm, with the given
+// daily files (workspace-relative path -> content) written.
+func newDigestPlugin(t *testing.T, cm model.BaseChatModel, files map[string]string) *memoryCorePlugin {
+	t.Helper()
+	m, cfg := newTestManager(t)
+	for rel, content := range files {
+		abs := filepath.Join(cfg.WorkspaceDir, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Sync(context.Background(), manager.SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	return &memoryCorePlugin{cfg: cfg, models: stubModels{cm: cm}, manager: m}
+}
+
+var digestNow = time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+
+func TestRunDigestMergesMonth(t *testing.T) {
+	cm := &stubChatModel{reply: func(string) (string, error) { return "- prefers tea", nil }}
+	p := newDigestPlugin(t, cm, map[string]string{
+		"memory/2026-01-05.md": "likes tea",
+		"memory/2026-01-06.md": "still likes tea",
+		"memory/2026-03-14.md": "too recent",
+	})
+
+	if err := p.runDigest(context.Background(), digestNow); err != nil {
+		t.Fatalf("runDigest: %v", err)
+	}
+
+	calls := cm.calls()
+	if len(calls) != 1 || !strings.Contains(calls[0], "# 2026-01-05") || !strings.Contains(calls[0], "still likes tea") {
+		t.Fatalf("model calls = %q", calls)
+	}
+	digest, err := p.manager.ReadFile("memory/2026-01.md", 0, 0)
+	if err != nil || !strings.Contains(digest, "# Memory digest 2026-01") || !strings.Contains(digest, "- prefers tea") {
+		t.Fatalf("digest = %q, %v", digest, err)
+	}
+	for rel, want := range map[string]bool{"memory/2026-01-05.md": false, "memory/2026-01-06.md": false, "memory/2026-03-14.md": true} {
+		if got := p.manager.Exists(rel); got != want {
+			t.Fatalf("%s exists = %v, want %v", rel, got, want)
+		}
+	}
+
+	indexed, _, err := p.manager.ListFiles("", 0, 0)
+	if err != nil {
+		t.Fatalf("list files: %v", err)
+	}
+	paths := make(map[string]bool)
+	for _, f := range indexed {
+		paths[f.Path] = true
+	}
+	if !paths["memory/2026-01.md"] || paths["memory/2026-01-05.md"] {
+		t.Fatalf("indexed files = %v, want the digest and not the merged daily files", paths)
+	}
+}
+
+func TestRunDigestSummarizesLongMonthInParts(t *testing.T) {
+	day := strings.Repeat("a fact worth keeping\n", 2000) // ~42k runes
+	cm := &stubChatModel{reply: func(input string) (string, error) {
+		if strings.Contains(input, "part ") {
+			return "- partial", nil
+		}
+		return "- merged", nil
+	}}
+	p := newDigestPlugin(t, cm, map[string]string{
+		"memory/2026-01-05.md": day,
+		"memory/2026-01-06.md": day + "last line of the month",
+	})
+
+	if err := p.runDigest(context.Background(), digestNow); err != nil {
+		t.Fatalf("runDigest: %v", err)
+	}
+
+	calls := cm.calls()
+	if len(calls) != 3 {
+		t.Fatalf("%d model calls, want two parts and one merge", len(calls))
+	}
+	if !strings.Contains(calls[1], "last line of the month") {
+		t.Fatal("the end of the month never reached the model")
+	}
+	if !strings.Contains(calls[2], "# Partial digest 1") || !strings.Contains(calls[2], "# Partial digest 2") {
+		t.Fatalf("merge input = %q", calls[2])
+	}
+	for i, in := range calls {
+		if n := len([]rune(in)); n > digestMaxInputChars+100 {
+			t.Fatalf("call %d has %d runes", i, n)
+		}
+	}
+	if digest, _ := p.manager.ReadFile("memory/2026-01.md", 0, 0); !strings.Contains(digest, "- merged") {
+		t.Fatalf("digest = %q", digest)
+	}
+}
+
+func TestRunDigestKeepsFilesOnFailure(t *testing.T) {
+	cm := &stubChatModel{reply: func(string) (string, error) { return "", errors.New("model down") }}
+	p := newDigestPlugin(t, cm, map[string]string{"memory/2026-01-05.md": "likes tea"})
+
+	if err := p.runDigest(context.Background(), digestNow); err != nil {
+		t.Fatalf("runDigest: %v", err)
+	}
+	if !p.manager.Exists("memory/2026-01-05.md") || p.manager.Exists("memory/2026-01.md") {
+		t.Fatal("a failed digest changed the memory files")
+	}
+}
+
+func TestRunDigestWaitsForSync(t *testing.T) {
+	cm := &stubChatModel{reply: func(string) (string, error) { return "- prefers tea", nil }}
+	p := newDigestPlugin(t, cm, map[string]string{"memory/2026-01-05.md": "likes tea"})
+
+	// A job holding off syncs stands in for a running sync.
+	ran, err := p.manager.TryExclusive(func() error {
+		return p.runDigest(context.Background(), digestNow)
+	})
+	if !ran || err != nil {
+		t.Fatalf("TryExclusive = %v, %v", ran, err)
+	}
+	if !p.manager.Exists("memory/2026-01-05.md") || p.manager.Exists("memory/2026-01.md") {
+		t.Fatal("digest rewrote files while a sync was running")
+	}
+
+	if err := p.runDigest(context.Background(), digestNow); err != nil {
+		t.Fatalf("runDigest: %v", err)
+	}
+	if p.manager.Exists("memory/2026-01-05.md") || !p.manager.Exists("memory/2026-01.md") {
+		t.Fatal("digest did not run once the sync finished")
+	}
+}
+
+func TestPackDigestChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []string
+		limit    int
+		want     []string
+	}{
+		{"fits", []string{"ab", "cd"}, 10, []string{"abcd"}},
+		{"empty", nil, 10, []string{""}},
+		{"packs in order", []string{"abc", "def", "gh"}, 6, []string{"abcdef", "gh"}},
+		{"splits at line breaks", []string{"ab\ncd\nef"}, 6, []string{"ab\ncd\n", "ef"}},
+		{"splits long lines", []string{"abcdefgh"}, 3, []string{"abc", "def", "gh"}},
+		{"counts runes", []string{"日本", "語"}, 2, []string{"日本", "語"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := packDigestChunks(tt.sections, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limit", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"server error", fmt.Errorf("embed: %w", &APIError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"bad credentials", &APIError{StatusCode: http.StatusUnauthorized}, false},
+		{"bad input", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"deadline", fmt.Errorf("http request: %w", context.DeadlineExceeded), true},
+		{"canceled", fmt.Errorf("http request: %w", context.Canceled), false},
+		{"network", fmt.Errorf("http request: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), true},
+		{"other", errors.New("unmarshal response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Fatalf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,61 +4,87 @@ import (
 	"fmt"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/pkg/logger"
 )
 
 // NewProvider creates a new embedding provider based on the configuration.
+// The runtime fallback chain (cfg.Fallbacks) is built as well; entries that
+// cannot be created are logged and left out.
 func NewProvider(cfg entity.EmbeddingConfig) (*ProviderResult, error) {
 	requested := cfg.Provider
 
-	var createByID func(id string) (Provider, error)
-	createByID = func(id string) (Provider, error) {
-		switch id {
-		case "openai":
-			apiKey := ""
-			baseURL := ""
-			if cfg.Remote != nil {
-				apiKey = cfg.Remote.APIKey
-				baseURL = cfg.Remote.BaseURL
-			}
-			if apiKey == "" {
-				return nil, fmt.Errorf("no API key found for provider openai")
-			}
-			return NewOpenAIProvider(OpenAIOptions{
-				APIKey:  apiKey,
-				BaseURL: baseURL,
-				Model:   cfg.Model,
-			}), nil
-		case "auto":
-			p, err := createByID("openai")
-			if err == nil {
-				return p, nil
-			}
-			return nil, fmt.Errorf("no embedding provider available (tried openai): %w", err)
-		default:
-			return nil, fmt.Errorf("unsupported embedding provider: %s", id)
+	result, err := newPrimaryProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fb := range cfg.Fallbacks {
+		remote := fb.Remote
+		if remote == nil {
+			remote = cfg.Remote
 		}
+		p, err := createByID(fb.Provider, fb.Model, remote)
+		if err != nil {
+			logger.Warn("[Memory] skipping embedding fallback %s/%s: %v", fb.Provider, fb.Model, err)
+			continue
+		}
+		result.Fallbacks = append(result.Fallbacks, p)
 	}
 
-	provider, err := createByID(requested)
+	result.RequestedBackend = requested
+	return result, nil
+}
+
+// newPrimaryProvider creates the configured provider, falling back to
+// cfg.Fallback when it cannot be created.
+func newPrimaryProvider(cfg entity.EmbeddingConfig) (*ProviderResult, error) {
+	requested := cfg.Provider
+
+	provider, err := createByID(requested, cfg.Model, cfg.Remote)
 	if err != nil {
 		// Try fallback
 		if cfg.Fallback != "" && cfg.Fallback != "none" && cfg.Fallback != requested {
-			fallbackProvider, fallbackErr := createByID(cfg.Fallback)
+			fallbackProvider, fallbackErr := createByID(cfg.Fallback, cfg.Model, cfg.Remote)
 			if fallbackErr != nil {
 				return nil, fmt.Errorf("no fallback embedding provider available (tried %s): %w", cfg.Fallback, fallbackErr)
 			}
 			return &ProviderResult{
-				Provider:         fallbackProvider,
-				RequestedBackend: requested,
-				FallbackFrom:     requested,
-				FallbackReason:   err.Error(),
+				Provider:       fallbackProvider,
+				FallbackFrom:   requested,
+				FallbackReason: err.Error(),
 			}, nil
 		}
 		return nil, err
 	}
 
-	return &ProviderResult{
-		Provider:         provider,
-		RequestedBackend: requested,
-	}, nil
+	return &ProviderResult{Provider: provider}, nil
+}
+
+// createByID creates the provider backend with the given ID.
+func createByID(id, model string, remote *entity.RemoteEmbeddingConfig) (Provider, error) {
+	switch id {
+	case "openai":
+		apiKey := ""
+		baseURL := ""
+		if remote != nil {
+			apiKey = remote.APIKey
+			baseURL = remote.BaseURL
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("no API key found for provider openai")
+		}
+		return NewOpenAIProvider(OpenAIOptions{
+			APIKey:  apiKey,
+			BaseURL: baseURL,
+			Model:   model,
+		}), nil
+	case "auto":
+		p, err := createByID("openai", model, remote)
+		if err == nil {
+			return p, nil
+		}
+		return nil, fmt.Errorf("no embedding provider available (tried openai): %w", err)
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", id)
+	}
 }
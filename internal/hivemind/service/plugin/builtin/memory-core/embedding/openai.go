@@ -94,7 +94,7 @@ func (p *openAIProvider) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{Provider: p.ID(), StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	var result openAIEmbeddingResponse
@@ -26,6 +26,8 @@ type ProviderResult struct {
 	FallbackFrom string
 	// FallbackReason is the reason why a fallback was used.
 	FallbackReason string
+	// Fallbacks are the runtime failover candidates, in order.
+	Fallbacks []Provider
 }
 
 // ProviderKey returns a stable key identifier for the provider.
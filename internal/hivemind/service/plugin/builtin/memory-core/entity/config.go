@@ -9,12 +9,18 @@ type MemoryConfig struct {
 	// WorkspaceDir is the root directory for memory files.
 	WorkspaceDir string `json:"workspace_dir"`
 
-	// Sources defines which sources to index: "memory", "sessions".
+	// Sources defines which sources to index: "memory", "sessions", "code".
 	Sources []MemorySource `json:"sources"`
 
 	// ExtraPaths are additional directories/files to index.
 	ExtraPaths []string `json:"extra_paths"`
 
+	// MaxFileBytes caps the size of memory files written in append mode.
+	// An append that would exceed it goes to a new segment instead
+	// (2026-02-13.md → 2026-02-13.1.md → 2026-02-13.2.md ...), so that each
+	// file re-indexes in bounded time. 0 means no limit.
+	MaxFileBytes int64 `json:"max_file_bytes,omitempty"`
+
 	// Embedding holds the embedding provider configuration.
 	Embedding EmbeddingConfig `json:"embedding"`
 
@@ -24,6 +30,12 @@ type MemoryConfig struct {
 	// Chunking holds the chunking parameters.
 	Chunking ChunkingConfig `json:"chunking"`
 
+	// Code configures indexing of source-code files (source "code").
+	Code CodeConfig `json:"code"`
+
+	// Indexing holds options applied while chunks are written to the index.
+	Indexing IndexingConfig `json:"indexing"`
+
 	// Sync holds the synchronization strategy.
 	Sync SyncConfig `json:"sync"`
 
@@ -32,6 +44,12 @@ type MemoryConfig struct {
 
 	// Cache holds the embedding cache configuration.
 	Cache CacheConfig `json:"cache"`
+
+	// Flush controls which conversation turns are persisted on agent_end.
+	Flush FlushConfig `json:"flush"`
+
+	// Digest controls the background job merging old daily memory files.
+	Digest DigestConfig `json:"digest"`
 }
 
 // EmbeddingConfig configures the embedding provider.
@@ -45,8 +63,30 @@ type EmbeddingConfig struct {
 	// Fallback is the fallback provider if primary fails.
 	Fallback string `json:"fallback"`
 
+	// Normalize L2-normalizes document and query embeddings, for providers
+	// that return unnormalized vectors. Brute-force cosine search does not
+	// depend on it, but vec0 distances do. Changing it rebuilds the index.
+	Normalize bool `json:"normalize,omitempty"`
+
 	// Remote holds remote API configuration.
 	Remote *RemoteEmbeddingConfig `json:"remote,omitempty"`
+
+	// Fallbacks are providers to fail over to, in order, when the active
+	// provider returns a transient error (rate limit, 5xx, timeout) while
+	// indexing. A switch changes the embedding model, so it rebuilds the index.
+	Fallbacks []EmbeddingProviderConfig `json:"fallbacks,omitempty"`
+}
+
+// EmbeddingProviderConfig describes one provider of the runtime fallback chain.
+type EmbeddingProviderConfig struct {
+	// Provider is the embedding backend (e.g. "openai").
+	Provider string `json:"provider"`
+
+	// Model is the model name; empty uses the provider default.
+	Model string `json:"model"`
+
+	// Remote holds remote API configuration; nil inherits EmbeddingConfig.Remote.
+	Remote *RemoteEmbeddingConfig `json:"remote,omitempty"`
 }
 
 // RemoteEmbeddingConfig holds configuration for remote embedding APIs.
@@ -71,6 +111,11 @@ type StoreConfig struct {
 
 	// Vector holds vector extension configuration.
 	Vector VectorConfig `json:"vector"`
+
+	// WALCheckpointIntervalMinutes is the interval for periodic
+	// PRAGMA wal_checkpoint(TRUNCATE) runs. 0 disables the periodic checkpoint;
+	// a checkpoint is still attempted on Close.
+	WALCheckpointIntervalMinutes int `json:"wal_checkpoint_interval_minutes,omitempty"`
 }
 
 // VectorConfig configures the sqlite-vec extension.
@@ -98,6 +143,18 @@ type SyncConfig struct {
 
 	// IntervalMinutes is the periodic sync interval.
 	IntervalMinutes int `json:"interval_minutes"`
+
+	// WriteDebounceMs delays the sync after a memory write so that a burst of
+	// writes is indexed once. 0 syncs inline after every write.
+	WriteDebounceMs int `json:"write_debounce_ms"`
+}
+
+// IndexingConfig configures chunk indexing.
+type IndexingConfig struct {
+	// DedupThreshold skips inserting a chunk whose embedding similarity to a
+	// recently indexed chunk of the same file reaches this value (0-1),
+	// e.g. 0.97. 0 disables deduplication.
+	DedupThreshold float64 `json:"dedup_threshold"`
 }
 
 // CacheConfig configures the embedding cache.
@@ -107,6 +164,127 @@ type CacheConfig struct {
 
 	// MaxEntries is the maximum number of cached embeddings.
 	MaxEntries int `json:"max_entries,omitempty"`
+
+	// WarmOnStart preloads recently cached embeddings and reads the index
+	// tables at startup, so that the first searches do not start cold.
+	WarmOnStart bool `json:"warm_on_start,omitempty"`
+
+	// WarmQueries are common queries embedded during the warm-up, so that
+	// their first search needs no embedding call. Only used with WarmOnStart.
+	WarmQueries []string `json:"warm_queries,omitempty"`
+
+	// WarmTimeoutSeconds bounds the warm-up; whatever is not done by then
+	// is skipped. Default: 10.
+	WarmTimeoutSeconds int `json:"warm_timeout_seconds,omitempty"`
+}
+
+// FlushMode selects how a conversation turn is turned into a memory entry.
+type FlushMode string
+
+const (
+	// FlushModeRaw stores truncated user/assistant text (no LLM call).
+	FlushModeRaw FlushMode = "raw"
+	// FlushModeExtracted asks a model to extract durable facts, preferences
+	// and decisions, falling back to raw on error.
+	FlushModeExtracted FlushMode = "extracted"
+)
+
+// FlushConfig controls the automatic memory flush on agent_end.
+type FlushConfig struct {
+	// Mode is "raw" (default) or "extracted".
+	Mode FlushMode `json:"mode"`
+
+	// Model is the "provider/model" used by the extracted mode.
+	// Empty uses the runtime's default chat model.
+	Model string `json:"model,omitempty"`
+
+	// MinMessages is the minimum number of active session messages before a
+	// turn is flushed. A negative value disables the automatic flush.
+	MinMessages int `json:"min_messages"`
+
+	// MinUserChars skips the flush when the last user message is shorter
+	// than this many characters. 0 disables the check.
+	MinUserChars int `json:"min_user_chars"`
+
+	// MinAssistantChars skips the flush when the last assistant reply is
+	// shorter than this many characters. 0 disables the check.
+	MinAssistantChars int `json:"min_assistant_chars"`
+
+	// DedupThreshold skips flushing content whose similarity to an already
+	// indexed memory reaches this value (0-1). 0 disables deduplication.
+	DedupThreshold float64 `json:"dedup_threshold"`
+}
+
+// DigestConfig controls the background memory digest: daily files
+// (memory/YYYY-MM-DD.md) older than MinAgeDays are summarized into one
+// monthly file (memory/YYYY-MM.md) in the same directory and then deleted.
+type DigestConfig struct {
+	// Enabled turns the background digest job on.
+	Enabled bool `json:"enabled"`
+
+	// IntervalMinutes is how often the job looks for files to merge.
+	IntervalMinutes int `json:"interval_minutes"`
+
+	// MinAgeDays is the age a daily file must reach before it is merged.
+	MinAgeDays int `json:"min_age_days"`
+
+	// Model is the "provider/model" that writes the digest.
+	// Empty uses Flush.Model, then the runtime's default chat model.
+	Model string `json:"model,omitempty"`
+}
+
+// DefaultDigestConfig returns the default (disabled) digest configuration.
+func DefaultDigestConfig() DigestConfig {
+	return DigestConfig{
+		IntervalMinutes: 6 * 60,
+		MinAgeDays:      30,
+	}
+}
+
+// CodeConfig configures the "code" memory source.
+type CodeConfig struct {
+	// Paths are the files/directories to index, relative to WorkspaceDir or absolute.
+	Paths []string `json:"paths"`
+
+	// Extensions restricts indexing to these file extensions (e.g. ".go").
+	// Empty uses DefaultCodeExtensions.
+	Extensions []string `json:"extensions"`
+
+	// MaxFileBytes skips files larger than this. 0 means no limit.
+	MaxFileBytes int64 `json:"max_file_bytes"`
+}
+
+// DefaultCodeExtensions are the file extensions indexed by the "code" source
+// when CodeConfig.Extensions is empty.
+var DefaultCodeExtensions = []string{
+	".go", ".py", ".js", ".jsx", ".mjs", ".ts", ".tsx",
+	".java", ".kt", ".rs", ".rb", ".php", ".c", ".h", ".cpp", ".hpp", ".cs",
+}
+
+// DefaultCodeConfig returns the default code indexing configuration.
+func DefaultCodeConfig() CodeConfig {
+	return CodeConfig{MaxFileBytes: 256 * 1024}
+}
+
+// HasSource reports whether source is enabled in cfg.Sources.
+func (cfg *MemoryConfig) HasSource(source MemorySource) bool {
+	for _, s := range cfg.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultFlushConfig returns the default memory flush thresholds.
+func DefaultFlushConfig() FlushConfig {
+	return FlushConfig{
+		Mode:              FlushModeRaw,
+		MinMessages:       4,
+		MinUserChars:      10,
+		MinAssistantChars: 50,
+		DedupThreshold:    0.95,
+	}
 }
 
 // DefaultMemoryConfig returns a sensible default memory configuration.
@@ -126,17 +304,22 @@ func DefaultMemoryConfig() *MemoryConfig {
 			Vector: VectorConfig{Enabled: false},
 		},
 		Chunking: DefaultChunkingConfig(),
+		Code:     DefaultCodeConfig(),
 		Sync: SyncConfig{
 			OnSessionStart:  true,
 			OnSearch:        true,
 			Watch:           true,
 			WatchDebounceMs: 1500,
 			IntervalMinutes: 0,
+			WriteDebounceMs: 1000,
 		},
 		Query: DefaultQueryConfig(),
 		Cache: CacheConfig{
-			Enabled:    true,
-			MaxEntries: 10000,
+			Enabled:            true,
+			MaxEntries:         10000,
+			WarmTimeoutSeconds: 10,
 		},
+		Flush:  DefaultFlushConfig(),
+		Digest: DefaultDigestConfig(),
 	}
 }
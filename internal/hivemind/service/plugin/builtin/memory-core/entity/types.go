@@ -6,6 +6,7 @@ type MemorySource string
 const (
 	MemorySourceMemory   MemorySource = "memory"
 	MemorySourceSessions MemorySource = "sessions"
+	MemorySourceCode     MemorySource = "code"
 )
 
 // MemoryFileEntry represents a scanned memory file with its metadata and content hash.
@@ -37,10 +38,22 @@ type MemoryChunk struct {
 	// Text is the chunk content.
 	Text string
 
-	// Hash is the SHA-256 hash of the chunk text.
+	// EmbedText is the text to embed when it differs from Text, e.g. Text
+	// prefixed with its heading breadcrumb. Empty means Text.
+	EmbedText string
+
+	// Hash is the SHA-256 hash of the embedded text.
 	Hash string
 }
 
+// EmbeddingText returns the text to embed for this chunk.
+func (c MemoryChunk) EmbeddingText() string {
+	if c.EmbedText != "" {
+		return c.EmbedText
+	}
+	return c.Text
+}
+
 // MemorySearchResult is the final result returned by a memory search.
 type MemorySearchResult struct {
 	// Path is the file relative path.
@@ -55,7 +68,12 @@ type MemorySearchResult struct {
 	// Score is the relevance score (0-1).
 	Score float64 `json:"score"`
 
-	// Snippet is the truncated text snippet (<= SnippetMaxChars).
+	// VectorScore is the raw embedding similarity (0 when only matched by keyword).
+	// Unlike Score it does not depend on the merge strategy.
+	VectorScore float64 `json:"vector_score,omitempty"`
+
+	// Snippet is the truncated text snippet (<= QueryConfig.SnippetMaxChars),
+	// optionally expanded with surrounding source lines.
 	Snippet string `json:"snippet"`
 
 	// Source indicates the origin (memory or sessions).
@@ -90,6 +108,10 @@ type ChunkingConfig struct {
 
 	// Overlap is the overlap tokens between consecutive chunks.
 	Overlap int `json:"overlap"`
+
+	// IncludeHeadingContext prefixes the embedded text of each chunk with the
+	// chain of enclosing Markdown headings. Stored text and line ranges stay raw.
+	IncludeHeadingContext bool `json:"include_heading_context"`
 }
 
 // DefaultChunkingConfig returns the default chunking parameters matching OpenClaw defaults.
@@ -108,8 +130,28 @@ type QueryConfig struct {
 	// MinScore is the minimum relevance score threshold.
 	MinScore float64 `json:"min_score"`
 
+	// AdaptiveCutoff, when in (0, 1], replaces MinScore with a threshold
+	// relative to the best result: results scoring below
+	// topScore * AdaptiveCutoff are dropped. 0 keeps the absolute MinScore.
+	AdaptiveCutoff float64 `json:"adaptive_cutoff,omitempty"`
+
 	// Hybrid contains hybrid search weights.
 	Hybrid HybridConfig `json:"hybrid"`
+
+	// VectorEarlyStopScore enables early termination of the brute-force vector scan
+	// once every top-K candidate scores at or above this value. 0 disables it (exact top-K).
+	VectorEarlyStopScore float64 `json:"vector_early_stop_score,omitempty"`
+
+	// SnippetMaxChars caps the length of result snippets. 0 means the default (700).
+	SnippetMaxChars int `json:"snippet_max_chars,omitempty"`
+
+	// SnippetContextLines expands each returned snippet with this many source
+	// lines before and after the matched chunk. 0 disables expansion.
+	SnippetContextLines int `json:"snippet_context_lines,omitempty"`
+
+	// Namespace restricts a search to shared memories plus those of one agent.
+	// Set per call via manager.WithNamespace; never read from configuration.
+	Namespace string `json:"-"`
 }
 
 // HybridConfig holds the weights for hybrid search merge.
@@ -125,8 +167,29 @@ type HybridConfig struct {
 
 	// CandidateMultiplier controls how many extra candidates to fetch.
 	CandidateMultiplier float64 `json:"candidate_multiplier"`
+
+	// Strategy selects how vector and keyword results are combined:
+	// "linear" (default) or "rrf" (reciprocal rank fusion).
+	Strategy MergeStrategy `json:"strategy,omitempty"`
+
+	// RRFK is the RRF rank constant k (default 60). Only used with Strategy "rrf".
+	RRFK int `json:"rrf_k,omitempty"`
 }
 
+// MergeStrategy selects the hybrid merge algorithm.
+type MergeStrategy string
+
+const (
+	// MergeStrategyLinear combines raw scores: vectorWeight*vectorScore + textWeight*textScore.
+	MergeStrategyLinear MergeStrategy = "linear"
+	// MergeStrategyRRF combines rank positions: sum of weight/(k+rank).
+	// Insensitive to score scale differences between the two searches.
+	MergeStrategyRRF MergeStrategy = "rrf"
+)
+
+// DefaultRRFK is the conventional RRF rank constant.
+const DefaultRRFK = 60
+
 // DefaultQueryConfig returns the default query config matching OpenClaw defaults.
 func DefaultQueryConfig() QueryConfig {
 	return QueryConfig{
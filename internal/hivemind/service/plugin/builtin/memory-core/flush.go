@@ -0,0 +1,254 @@
+package memory_core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+const (
+	// extractTimeout bounds the LLM call made by the extracted flush mode.
+	extractTimeout = 30 * time.Second
+
+	// flushTimeout bounds a whole background flush: extraction,
+	// deduplication searches and the write.
+	flushTimeout = 2 * time.Minute
+
+	// extractMaxInputChars caps each message handed to the extraction model.
+	extractMaxInputChars = 4000
+
+	// dedupCandidates is the number of search hits inspected for duplicates.
+	dedupCandidates = 5
+
+	// extractNone is the reply the extraction model gives when nothing is worth keeping.
+	extractNone = "NONE"
+
+	// extractInstruction is the system prompt for memory extraction.
+	extractInstruction = `You extract long-term memories from a single conversation turn.
+List only durable information worth recalling in future conversations:
+facts about the user, stated preferences, decisions made, and commitments or follow-ups.
+Ignore greetings, small talk, and details that only matter to this turn.
+
+Reply with one Markdown bullet per item ("- ..."), each a short self-contained sentence.
+If there is nothing worth remembering, reply with exactly: ` + extractNone
+)
+
+// startFlush writes the exchange to datePath in the background. The flush
+// keeps ctx's values (log fields, agent ID) but not its cancellation, since
+// the run that fired agent_end is over by then. Flushes started after Stop
+// are dropped.
+func (p *memoryCorePlugin) startFlush(ctx context.Context, namespace, datePath string, now time.Time, userMsg, assistantMsg string) {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+	if p.flushStopped {
+		return
+	}
+	p.flushes.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	go func() {
+		defer p.flushes.Done()
+		defer cancel()
+		p.flushTurn(ctx, namespace, datePath, now, userMsg, assistantMsg)
+	}()
+}
+
+// waitFlushes stops new flushes and waits for running ones until ctx is done.
+func (p *memoryCorePlugin) waitFlushes(ctx context.Context) {
+	p.flushMu.Lock()
+	p.flushStopped = true
+	p.flushMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.flushes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("[MemoryCore] stopping with memory flushes still running: %v", ctx.Err())
+	}
+}
+
+// flushTurn appends the exchange to datePath: the facts extracted from it in
+// the extracted mode, the truncated exchange otherwise or when the
+// extraction fails. Content already present in memory is skipped.
+func (p *memoryCorePlugin) flushTurn(ctx context.Context, namespace, datePath string, now time.Time, userMsg, assistantMsg string) {
+	var entry string
+	if p.cfg.Flush.Mode == entity.FlushModeExtracted {
+		facts, err := p.extractFacts(ctx, userMsg, assistantMsg)
+		switch {
+		case err != nil:
+			logger.CtxWarn(ctx, "[MemoryCore] memory extraction failed, falling back to raw flush: %v", err)
+		case len(facts) == 0:
+			logger.CtxDebug(ctx, "[MemoryCore] memory flush skipped: nothing durable extracted")
+			return
+		default:
+			facts = p.dropDuplicateFacts(ctx, namespace, facts)
+			if len(facts) == 0 {
+				logger.CtxDebug(ctx, "[MemoryCore] memory flush skipped: all extracted facts already in memory")
+				return
+			}
+			entry = formatExtractedEntry(now, facts)
+		}
+	}
+	if entry == "" {
+		entry = formatRawEntry(now, userMsg, assistantMsg)
+		if p.isDuplicateMemory(ctx, namespace, entry) {
+			logger.CtxDebug(ctx, "[MemoryCore] memory flush skipped: near-identical entry already in memory")
+			return
+		}
+	}
+
+	if err := p.manager.WriteMemory(ctx, datePath, entry, true); err != nil {
+		logger.CtxWarn(ctx, "[MemoryCore] memory flush failed: %v", err)
+		return
+	}
+	logger.CtxInfo(ctx, "[MemoryCore] memory flush: appended conversation entry to %s", datePath)
+}
+
+// extractFacts asks the configured flush model to distill durable facts from
+// the latest user/assistant exchange. An empty result means nothing was worth keeping.
+func (p *memoryCorePlugin) extractFacts(ctx context.Context, userMsg, assistantMsg string) ([]string, error) {
+	cm, err := p.flushModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	turn := fmt.Sprintf("User:\n%s\n\nAssistant:\n%s",
+		clipRunes(userMsg, extractMaxInputChars),
+		clipRunes(assistantMsg, extractMaxInputChars),
+	)
+	out, err := cm.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(extractInstruction),
+		schema.UserMessage(turn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generate: %w", err)
+	}
+	if out == nil {
+		return nil, fmt.Errorf("extraction model returned no message")
+	}
+	return parseExtractedFacts(out.Content), nil
+}
+
+// flushModel resolves the chat model used by the extracted flush mode.
+func (p *memoryCorePlugin) flushModel(ctx context.Context) (model.BaseChatModel, error) {
+	return p.chatModel(ctx, p.cfg.Flush.Model)
+}
+
+// chatModel resolves a "provider/model" reference; empty uses the runtime's
+// default chat model.
+func (p *memoryCorePlugin) chatModel(ctx context.Context, ref string) (model.BaseChatModel, error) {
+	if p.models == nil {
+		return nil, fmt.Errorf("model manager is not available")
+	}
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return p.models.GetDefaultChatModel(ctx)
+	}
+	providerID, modelID, ok := strings.Cut(ref, "/")
+	if !ok || providerID == "" || modelID == "" {
+		return nil, fmt.Errorf("invalid model %q, expected \"provider/model\"", ref)
+	}
+	return p.models.GetChatModel(ctx, providerID, modelID)
+}
+
+// parseExtractedFacts turns the model reply into a list of facts.
+// Bullet markers are stripped; the NONE sentinel yields no facts.
+func parseExtractedFacts(reply string) []string {
+	var facts []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.EqualFold(strings.Trim(line, ".*` "), extractNone) {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*• "))
+		if line != "" {
+			facts = append(facts, line)
+		}
+	}
+	return facts
+}
+
+// dropDuplicateFacts removes facts that are already present in the agent's memory.
+func (p *memoryCorePlugin) dropDuplicateFacts(ctx context.Context, namespace string, facts []string) []string {
+	kept := facts[:0]
+	for _, fact := range facts {
+		if !p.isDuplicateMemory(ctx, namespace, fact) {
+			kept = append(kept, fact)
+		}
+	}
+	return kept
+}
+
+// isDuplicateMemory reports whether text closely matches an indexed memory
+// visible to namespace, per FlushConfig.DedupThreshold. Search failures are
+// treated as "not a duplicate" so that the flush still happens.
+func (p *memoryCorePlugin) isDuplicateMemory(ctx context.Context, namespace, text string) bool {
+	threshold := p.cfg.Flush.DedupThreshold
+	if threshold <= 0 {
+		return false
+	}
+
+	results, err := p.manager.Search(ctx, text,
+		manager.WithNamespace(namespace),
+		manager.WithMaxResults(dedupCandidates),
+		manager.WithMinScore(0),
+	)
+	if err != nil {
+		logger.CtxDebug(ctx, "[MemoryCore] dedup search failed: %v", err)
+		return false
+	}
+	for _, r := range results {
+		// Prefer raw embedding similarity: merged scores depend on the merge
+		// strategy (RRF scores are rank-based, not similarity-based).
+		similarity := r.VectorScore
+		if similarity == 0 {
+			similarity = r.Score
+		}
+		if similarity >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// formatExtractedEntry renders extracted facts as a timestamped Markdown section.
+func formatExtractedEntry(now time.Time, facts []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s\n\n", now.Format("15:04:05"))
+	for _, fact := range facts {
+		fmt.Fprintf(&b, "- %s\n", fact)
+	}
+	return b.String()
+}
+
+// formatRawEntry renders the truncated user/assistant exchange (no LLM call).
+func formatRawEntry(now time.Time, userMsg, assistantMsg string) string {
+	return fmt.Sprintf("\n## %s\n\n- **User**: %s\n- **Assistant**: %s\n",
+		now.Format("15:04:05"),
+		truncate(userMsg, 200),
+		truncate(assistantMsg, 400),
+	)
+}
+
+// clipRunes shortens s to at most n runes.
+func clipRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,197 @@
+package memory_core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	agentEntity "github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	meminternal "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
+)
+
+// newFlushPlugin returns a plugin flushing every turn in the extracted mode
+// with cm as the extraction model.
+func newFlushPlugin(t *testing.T, cm *stubChatModel) *memoryCorePlugin {
+	t.Helper()
+	m, cfg := newTestManager(t)
+	cfg.Flush = entity.FlushConfig{Mode: entity.FlushModeExtracted, MinMessages: 2}
+	return &memoryCorePlugin{cfg: cfg, models: stubModels{cm: cm}, manager: m}
+}
+
+// agentEnd fires onAgentEnd for a session of agent "a" ending with one exchange.
+func agentEnd(t *testing.T, ctx context.Context, p *memoryCorePlugin) {
+	t.Helper()
+	session := &agentEntity.Session{ID: "s1", AgentID: "a"}
+	session.AppendMessage(agentEntity.NewUserMessage("I always drink green tea in the morning"))
+	session.AppendMessage(agentEntity.NewAssistantMessage("Noted, green tea it is"))
+	if err := p.onAgentEnd(ctx, map[string]interface{}{"session": session}); err != nil {
+		t.Fatalf("onAgentEnd: %v", err)
+	}
+}
+
+// todaysMemory returns agent "a"'s daily memory file, "" if missing.
+func todaysMemory(t *testing.T, p *memoryCorePlugin) string {
+	t.Helper()
+	path, err := meminternal.ScopeMemoryPath("a", fmt.Sprintf("memory/%s.md", time.Now().Format("2006-01-02")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := p.manager.ReadFile(path, 0, 0)
+	return content
+}
+
+func TestOnAgentEndFlushesInBackground(t *testing.T) {
+	proceed := make(chan struct{})
+	cm := &stubChatModel{reply: func(string) (string, error) {
+		<-proceed
+		return "- drinks green tea in the morning", nil
+	}}
+	p := newFlushPlugin(t, cm)
+
+	// The hook returns while the extraction is still waiting for the model,
+	// and the flush outlives the run's context.
+	ctx, cancel := context.WithCancel(context.Background())
+	agentEnd(t, ctx, p)
+	cancel()
+	if got := todaysMemory(t, p); got != "" {
+		t.Fatalf("memory written before the extraction finished: %q", got)
+	}
+
+	close(proceed)
+	p.waitFlushes(context.Background())
+	if got := todaysMemory(t, p); !strings.Contains(got, "- drinks green tea in the morning") {
+		t.Fatalf("memory = %q, want the extracted fact", got)
+	}
+}
+
+func TestFlushTurnModes(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply func(string) (string, error)
+		want  string // "" = nothing written
+	}{
+		{"extracted facts", func(string) (string, error) { return "- drinks green tea", nil }, "- drinks green tea"},
+		{"nothing durable", func(string) (string, error) { return "NONE", nil }, ""},
+		{"extraction fails", func(string) (string, error) { return "", errors.New("model down") }, "**User**: I always drink green tea"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFlushPlugin(t, &stubChatModel{reply: tt.reply})
+			agentEnd(t, context.Background(), p)
+			p.waitFlushes(context.Background())
+
+			got := todaysMemory(t, p)
+			if tt.want == "" && got != "" {
+				t.Fatalf("memory = %q, want nothing", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("memory = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlushAfterStopDropped(t *testing.T) {
+	cm := &stubChatModel{reply: func(string) (string, error) { return "- late", nil }}
+	p := newFlushPlugin(t, cm)
+	p.waitFlushes(context.Background())
+
+	agentEnd(t, context.Background(), p)
+	p.flushes.Wait()
+	if calls := cm.calls(); len(calls) != 0 {
+		t.Fatalf("flush ran after stop: %q", calls)
+	}
+}
+
+func TestWaitFlushesGivesUp(t *testing.T) {
+	proceed := make(chan struct{})
+	defer close(proceed)
+	p := newFlushPlugin(t, &stubChatModel{reply: func(string) (string, error) {
+		<-proceed
+		return "NONE", nil
+	}})
+	agentEnd(t, context.Background(), p)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	p.waitFlushes(ctx)
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("waitFlushes blocked %s past its deadline", waited)
+	}
+}
+
+// storeMemory writes content to a memory file in namespace and indexes it.
+func storeMemory(t *testing.T, p *memoryCorePlugin, namespace, content string) {
+	t.Helper()
+	path, err := meminternal.ScopeMemoryPath(namespace, "memory/notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := p.manager.WriteMemory(ctx, path, content, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.manager.Sync(ctx, manager.SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+}
+
+func TestIsDuplicateMemory(t *testing.T) {
+	// The stub embeds every query and single-chunk file as [1 0 0], so any
+	// indexed memory the namespace can see is a perfect match.
+	tests := []struct {
+		name      string
+		threshold float64
+		storedIn  string // namespace holding the memory; "" = none stored
+		want      bool
+	}{
+		{"same agent", 0.95, "a", true},
+		{"dedup disabled", 0, "a", false},
+		{"nothing indexed", 0.95, "", false},
+		{"other agent", 0.95, "b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFlushPlugin(t, &stubChatModel{})
+			p.cfg.Flush.DedupThreshold = tt.threshold
+			if tt.storedIn != "" {
+				storeMemory(t, p, tt.storedIn, "# Notes\n\n- drinks green tea\n")
+			}
+			if got := p.isDuplicateMemory(context.Background(), "a", "- drinks green tea"); got != tt.want {
+				t.Fatalf("isDuplicateMemory = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlushSkipsDuplicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		wantWrite bool
+	}{
+		{"duplicate", 0.95, false},
+		{"dedup disabled", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFlushPlugin(t, &stubChatModel{reply: func(string) (string, error) {
+				return "- drinks green tea", nil
+			}})
+			p.cfg.Flush.DedupThreshold = tt.threshold
+			storeMemory(t, p, "a", "# Notes\n\n- drinks green tea\n")
+
+			agentEnd(t, context.Background(), p)
+			p.waitFlushes(context.Background())
+			if got := todaysMemory(t, p); (got != "") != tt.wantWrite {
+				t.Fatalf("memory = %q, want written = %v", got, tt.wantWrite)
+			}
+		})
+	}
+}
@@ -12,6 +12,9 @@ import (
 //   - overlapChars = max(0, overlap * 4)
 //   - Lines that exceed maxChars are split into segments
 //   - On flush, the last overlapChars are carried into the next chunk
+//
+// With cfg.IncludeHeadingContext, each chunk's EmbedText is prefixed with the
+// chain of headings enclosing its first line (e.g. "Project X > Decisions > Auth").
 func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChunk {
 	lines := strings.Split(content, "\n")
 	if len(lines) == 0 {
@@ -24,6 +27,8 @@ func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChu
 	type lineEntry struct {
 		line   string
 		lineNo int
+		// headings is the heading breadcrumb enclosing the line.
+		headings string
 	}
 
 	var chunks []entity.MemoryChunk
@@ -46,12 +51,16 @@ func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChu
 		}
 
 		text := sb.String()
-		chunks = append(chunks, entity.MemoryChunk{
+		chunk := entity.MemoryChunk{
 			StartLine: first.lineNo,
 			EndLine:   last.lineNo,
 			Text:      text,
-			Hash:      HashText(text),
-		})
+		}
+		if cfg.IncludeHeadingContext && first.headings != "" {
+			chunk.EmbedText = first.headings + "\n\n" + text
+		}
+		chunk.Hash = HashText(chunk.EmbeddingText())
+		chunks = append(chunks, chunk)
 	}
 
 	carryOverlap := func() {
@@ -77,8 +86,13 @@ func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChu
 		}
 	}
 
+	var headings headingTracker
 	for i, line := range lines {
 		lineNo := i + 1
+		breadcrumb := headings.breadcrumb()
+		if cfg.IncludeHeadingContext {
+			headings.observe(line)
+		}
 
 		// Split long lines into segments of maxChars.
 		var segments []string
@@ -100,7 +114,7 @@ func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChu
 				flush()
 				carryOverlap()
 			}
-			current = append(current, lineEntry{line: segment, lineNo: lineNo})
+			current = append(current, lineEntry{line: segment, lineNo: lineNo, headings: breadcrumb})
 			currentChars += lineSize
 		}
 	}
@@ -108,3 +122,64 @@ func ChunkMarkdown(content string, cfg entity.ChunkingConfig) []entity.MemoryChu
 	flush()
 	return chunks
 }
+
+// headingTracker follows the chain of enclosing ATX headings ("# Title")
+// while scanning a Markdown document line by line. Lines inside fenced code
+// blocks are ignored.
+type headingTracker struct {
+	stack   []string // stack[i] is the current heading of level i+1
+	inFence string   // fence marker of the open code block, if any
+}
+
+// observe updates the tracker with the next line.
+func (t *headingTracker) observe(line string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return // indented code
+	}
+	for _, fence := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, fence) {
+			switch t.inFence {
+			case "":
+				t.inFence = fence
+			case fence:
+				t.inFence = ""
+			}
+			return
+		}
+	}
+	if t.inFence != "" {
+		return
+	}
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t') {
+		return
+	}
+	title := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+	if title == "" {
+		return
+	}
+
+	if len(t.stack) >= level {
+		t.stack = t.stack[:level-1]
+	}
+	for len(t.stack) < level-1 {
+		t.stack = append(t.stack, "") // skipped levels, e.g. "#" then "###"
+	}
+	t.stack = append(t.stack, title)
+}
+
+// breadcrumb returns the current heading chain joined with " > ".
+func (t *headingTracker) breadcrumb() string {
+	parts := make([]string, 0, len(t.stack))
+	for _, h := range t.stack {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
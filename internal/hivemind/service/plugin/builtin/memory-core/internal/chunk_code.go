@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+)
+
+// declPatterns match lines that start a top-level declaration (function,
+// type, class, ...), keyed by file extension. A chunk boundary is placed
+// before each match so declarations are not split across chunks.
+var declPatterns = func() map[string]*regexp.Regexp {
+	goDecl := regexp.MustCompile(`^(func|type|var|const)\b`)
+	pyDecl := regexp.MustCompile(`^(async\s+def|def|class)\s`)
+	jsDecl := regexp.MustCompile(`^(export\s+(default\s+)?)?(declare\s+)?(async\s+)?(function\*?|class|abstract\s+class|interface|type|enum|const|let|namespace)\s`)
+	jvmDecl := regexp.MustCompile(`^(\t|    )?(((public|private|protected|internal|static|final|abstract|sealed|override|open|data|suspend)\s+)+\S|(class|interface|enum|record|object|fun)\s)`)
+	rsDecl := regexp.MustCompile(`^(\t|    )?(pub(\([^)]*\))?\s+)?((async|unsafe|const|extern)\s+)*(fn|struct|enum|trait|impl|mod|macro_rules!)\b`)
+	rbDecl := regexp.MustCompile(`^\s{0,2}(def|class|module)\s`)
+	phpDecl := regexp.MustCompile(`^(\t|    )?((public|private|protected|static|abstract|final)\s+)*(function|class|interface|trait|enum)\s`)
+	cDecl := regexp.MustCompile(`^((struct|class|namespace|enum|union|typedef|template)\b|[A-Za-z_][\w\s\*&:<>,]*[\s\*&][\w:~]+\s*\([^;]*$)`)
+
+	return map[string]*regexp.Regexp{
+		".go":   goDecl,
+		".py":   pyDecl,
+		".js":   jsDecl,
+		".jsx":  jsDecl,
+		".mjs":  jsDecl,
+		".ts":   jsDecl,
+		".tsx":  jsDecl,
+		".java": jvmDecl,
+		".kt":   jvmDecl,
+		".cs":   jvmDecl,
+		".rs":   rsDecl,
+		".rb":   rbDecl,
+		".php":  phpDecl,
+		".c":    cDecl,
+		".h":    cDecl,
+		".cpp":  cDecl,
+		".hpp":  cDecl,
+	}
+}()
+
+// ChunkCode splits a source file into chunks along declaration boundaries.
+//   - Lines are grouped into blocks, each starting at a top-level declaration
+//     together with the comments/decorators directly above it
+//   - Consecutive blocks are packed into one chunk up to maxChars (tokens * 4)
+//   - A block larger than maxChars is split with the fixed-size ChunkMarkdown
+//     algorithm, as is any file whose language is not recognized
+//
+// With cfg.IncludeHeadingContext, each chunk's EmbedText is prefixed with the
+// file path, the code counterpart of the Markdown heading breadcrumb.
+func ChunkCode(content, path string, cfg entity.ChunkingConfig) []entity.MemoryChunk {
+	plain := cfg
+	plain.IncludeHeadingContext = false
+
+	var chunks []entity.MemoryChunk
+	pattern := declPatterns[strings.ToLower(filepath.Ext(path))]
+	if pattern == nil {
+		chunks = ChunkMarkdown(content, plain)
+	} else {
+		chunks = chunkDeclarations(strings.Split(content, "\n"), pattern, plain)
+	}
+
+	if cfg.IncludeHeadingContext && path != "" {
+		for i := range chunks {
+			chunks[i].EmbedText = path + "\n\n" + chunks[i].Text
+			chunks[i].Hash = HashText(chunks[i].EmbeddingText())
+		}
+	}
+	return chunks
+}
+
+// chunkDeclarations packs declaration blocks of lines into chunks.
+func chunkDeclarations(lines []string, pattern *regexp.Regexp, cfg entity.ChunkingConfig) []entity.MemoryChunk {
+	maxChars := max(32, cfg.Tokens*4)
+
+	// Block k spans lines[starts[k]:starts[k+1]].
+	starts := []int{0}
+	for i := 1; i < len(lines); i++ {
+		if !pattern.MatchString(lines[i]) {
+			continue
+		}
+		start := i
+		for start > starts[len(starts)-1] && isLeadingComment(lines[start-1]) {
+			start--
+		}
+		if start > starts[len(starts)-1] {
+			starts = append(starts, start)
+		}
+	}
+
+	var chunks []entity.MemoryChunk
+	emit := func(from, to int) {
+		text := strings.Join(lines[from:to], "\n")
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		if len(text) <= maxChars {
+			chunks = append(chunks, entity.MemoryChunk{
+				StartLine: from + 1,
+				EndLine:   to,
+				Text:      text,
+				Hash:      HashText(text),
+			})
+			return
+		}
+		// Oversized declaration: fall back to fixed-size chunks, shifted to file line numbers.
+		for _, sub := range ChunkMarkdown(text, cfg) {
+			sub.StartLine += from
+			sub.EndLine += from
+			chunks = append(chunks, sub)
+		}
+	}
+
+	packStart, packChars := 0, 0
+	for k, start := range starts {
+		end := len(lines)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		size := 0
+		for _, line := range lines[start:end] {
+			size += len(line) + 1
+		}
+		if packChars > 0 && packChars+size > maxChars {
+			emit(packStart, start)
+			packStart, packChars = start, 0
+		}
+		packChars += size
+	}
+	emit(packStart, len(lines))
+	return chunks
+}
+
+// isLeadingComment reports whether a line is a comment, doc comment,
+// decorator or attribute that belongs to the declaration below it.
+func isLeadingComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
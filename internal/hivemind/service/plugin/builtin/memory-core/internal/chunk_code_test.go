@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+)
+
+const goSource = `package demo
+
+// Add sums a and b.
+func Add(a, b int) int {
+	return a + b
+}
+
+type Point struct{ X, Y int }`
+
+// chunkLines returns the [start, end] line range of each chunk.
+func chunkLines(chunks []entity.MemoryChunk) [][2]int {
+	var got [][2]int
+	for _, c := range chunks {
+		got = append(got, [2]int{c.StartLine, c.EndLine})
+	}
+	return got
+}
+
+func TestChunkCodeDeclarationBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		tokens  int
+		want    [][2]int
+	}{
+		{"go split", "demo.go", goSource, 17, [][2]int{{1, 2}, {3, 7}, {8, 8}}},
+		{"go packed", "demo.go", goSource, 20, [][2]int{{1, 7}, {8, 8}}},
+		{"python decorator", "demo.py",
+			"import functools\n\n@functools.cache\ndef fib(n):\n    return n if n < 2 else fib(n-1) + fib(n-2)\n\nclass Tree:\n    pass",
+			20, [][2]int{{1, 2}, {3, 6}, {7, 8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkCode(tt.content, tt.path, entity.ChunkingConfig{Tokens: tt.tokens})
+			if got := chunkLines(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("chunk lines = %v, want %v", got, tt.want)
+			}
+			lines := strings.Split(tt.content, "\n")
+			for _, c := range chunks {
+				if want := strings.Join(lines[c.StartLine-1:c.EndLine], "\n"); c.Text != want {
+					t.Fatalf("chunk %d-%d text = %q, want %q", c.StartLine, c.EndLine, c.Text, want)
+				}
+				if c.Hash != HashText(c.Text) {
+					t.Fatalf("chunk %d-%d hash does not match its text", c.StartLine, c.EndLine)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkCodeOversizedDeclaration(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package demo\n\nfunc Long() {\n")
+	for i := 0; i < 20; i++ {
+		b.WriteString("\tprintln(\"a fairly long line of code\")\n")
+	}
+	b.WriteString("}")
+	content := b.String()
+	total := strings.Count(content, "\n") + 1
+
+	chunks := ChunkCode(content, "long.go", entity.ChunkingConfig{Tokens: 16})
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want the oversized function split", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 2 {
+		t.Fatalf("first chunk = lines %d-%d, want the package clause 1-2", chunks[0].StartLine, chunks[0].EndLine)
+	}
+	for _, c := range chunks[1:] {
+		if c.StartLine < 3 || c.EndLine > total {
+			t.Fatalf("sub-chunk lines %d-%d outside the function (3-%d)", c.StartLine, c.EndLine, total)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != total {
+		t.Fatalf("last chunk ends at line %d, want %d", last.EndLine, total)
+	}
+}
+
+func TestChunkCodeUnknownLanguage(t *testing.T) {
+	cfg := entity.ChunkingConfig{Tokens: 16}
+	content := "first paragraph of plain text\n\nsecond paragraph of plain text\n\nthird"
+	if got, want := ChunkCode(content, "notes.txt", cfg), ChunkMarkdown(content, cfg); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ChunkCode = %+v, want the ChunkMarkdown result %+v", got, want)
+	}
+}
+
+func TestChunkCodePathContext(t *testing.T) {
+	cfg := entity.ChunkingConfig{Tokens: 17, IncludeHeadingContext: true}
+	chunks := ChunkCode(goSource, "pkg/demo.go", cfg)
+	plain := ChunkCode(goSource, "pkg/demo.go", entity.ChunkingConfig{Tokens: 17})
+	if len(chunks) != len(plain) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(plain))
+	}
+	for i, c := range chunks {
+		if c.Text != plain[i].Text {
+			t.Fatalf("chunk %d text changed by the path context", i)
+		}
+		if want := "pkg/demo.go\n\n" + c.Text; c.EmbedText != want {
+			t.Fatalf("chunk %d EmbedText = %q, want %q", i, c.EmbedText, want)
+		}
+		if c.Hash != HashText(c.EmbedText) {
+			t.Fatalf("chunk %d hash does not cover the embedded text", i)
+		}
+	}
+}
+
+func TestIsLeadingComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"// doc", true},
+		{"  # python comment", true},
+		{"/**", true},
+		{" * javadoc line", true},
+		{"@Override", true},
+		{"", false},
+		{"x := 1", false},
+	}
+	for _, tt := range tests {
+		if got := isLeadingComment(tt.line); got != tt.want {
+			t.Errorf("isLeadingComment(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
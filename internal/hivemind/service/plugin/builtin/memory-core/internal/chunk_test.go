@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+)
+
+const headedDoc = `# Project X
+Intro line.
+## Decisions
+### Auth
+Use OAuth.
+` + "```sh" + `
+# not a heading
+` + "```" + `
+## Risks
+#### Deep
+Vendor lock-in.`
+
+func TestChunkMarkdownHeadingContext(t *testing.T) {
+	// Four tokens (16 chars) per chunk and no overlap: a few lines each.
+	cfg := entity.ChunkingConfig{Tokens: 4, IncludeHeadingContext: true}
+	chunks := ChunkMarkdown(headedDoc, cfg)
+
+	crumbs := make(map[string]string)
+	lines := strings.Split(headedDoc, "\n")
+	for _, c := range chunks {
+		// Stored text and line ranges map to the raw source.
+		if want := strings.Join(lines[c.StartLine-1:c.EndLine], "\n"); c.Text != want {
+			t.Fatalf("chunk %d-%d text = %q, want the raw lines %q", c.StartLine, c.EndLine, c.Text, want)
+		}
+		if c.Hash != HashText(c.EmbeddingText()) {
+			t.Fatalf("chunk %d-%d hash is not the hash of the embedded text", c.StartLine, c.EndLine)
+		}
+		if c.EmbedText != "" {
+			crumb, rest, _ := strings.Cut(c.EmbedText, "\n\n")
+			if rest != c.Text {
+				t.Fatalf("embedded text %q does not end with the chunk text", c.EmbedText)
+			}
+			crumbs[lines[c.StartLine-1]] = crumb
+		}
+	}
+
+	// A chunk gets the headings enclosing its first line; a heading inside a
+	// code fence is not one.
+	want := map[string]string{
+		"## Decisions":    "Project X",
+		"Use OAuth.":      "Project X > Decisions > Auth",
+		"# not a heading": "Project X > Decisions > Auth",
+		"#### Deep":       "Project X > Risks",
+	}
+	for line, crumb := range want {
+		if crumbs[line] != crumb {
+			t.Errorf("breadcrumb of chunk starting at %q = %q, want %q", line, crumbs[line], crumb)
+		}
+	}
+	if _, ok := crumbs["# Project X"]; ok {
+		t.Error("the first chunk has a breadcrumb although no heading precedes it")
+	}
+
+	cfg.IncludeHeadingContext = false
+	for _, c := range ChunkMarkdown(headedDoc, cfg) {
+		if c.EmbedText != "" || c.Hash != HashText(c.Text) {
+			t.Fatalf("chunk %d-%d embeds %q with heading context disabled", c.StartLine, c.EndLine, c.EmbedText)
+		}
+	}
+}
+
+func TestHeadingTracker(t *testing.T) {
+	var tr headingTracker
+	var got []string
+	for _, line := range []string{"# A", "text", "### C", "#nospace", "    # indented", "## B ##", "# "} {
+		tr.observe(line)
+		got = append(got, tr.breadcrumb())
+	}
+	want := []string{"A", "A", "A > C", "A > C", "A > C", "A > B", "A > B"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("breadcrumbs = %q, want %q", got, want)
+	}
+}
@@ -1,10 +1,15 @@
 package internal
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
 )
@@ -22,6 +27,57 @@ func IsMemoryPath(relPath string) bool {
 	return strings.HasPrefix(normalized, "memory/")
 }
 
+// AgentMemoryPrefix is the workspace-relative directory holding agent-scoped
+// memory files. Files under memory/agents/<agentID>/ belong to that agent's
+// namespace; every other memory file is shared by all agents.
+const AgentMemoryPrefix = "memory/agents/"
+
+// NamespaceForPath returns the agent namespace a memory file belongs to,
+// or "" for shared files.
+func NamespaceForPath(relPath string) string {
+	normalized := NormalizeRelPath(relPath)
+	rest, ok := strings.CutPrefix(normalized, AgentMemoryPrefix)
+	if !ok {
+		return ""
+	}
+	ns, _, found := strings.Cut(rest, "/")
+	if !found {
+		return ""
+	}
+	return ns
+}
+
+// ScopeMemoryPath maps a memory path requested by an agent into that agent's
+// namespace: "memory/x.md" becomes "memory/agents/<agentID>/x.md". Paths already
+// inside the agent's own directory and shared root files (MEMORY.md) are kept
+// as-is; paths inside another agent's directory are rejected.
+// An empty agentID leaves the path unchanged.
+func ScopeMemoryPath(agentID, relPath string) (string, error) {
+	if agentID == "" {
+		return relPath, nil
+	}
+	if !ValidNamespace(agentID) {
+		return "", fmt.Errorf("agent id %q cannot be used as a memory namespace", agentID)
+	}
+
+	normalized := NormalizeRelPath(relPath)
+	if strings.HasPrefix(normalized, AgentMemoryPrefix) {
+		if ns := NamespaceForPath(normalized); ns != agentID {
+			return "", fmt.Errorf("path %q belongs to another agent's memory", relPath)
+		}
+		return normalized, nil
+	}
+	if rest, ok := strings.CutPrefix(normalized, "memory/"); ok {
+		return AgentMemoryPrefix + agentID + "/" + rest, nil
+	}
+	return normalized, nil
+}
+
+// ValidNamespace reports whether ns is usable as a single directory name.
+func ValidNamespace(ns string) bool {
+	return ns != "" && ns != "." && ns != ".." && !strings.ContainsAny(ns, `/\`)
+}
+
 // NormalizeRelPath trims leading dots/slashes and normalizes separators.
 func NormalizeRelPath(value string) string {
 	trimmed := strings.TrimLeft(strings.TrimSpace(value), "./")
@@ -126,6 +182,87 @@ func ListMemoryFiles(workspaceDir string, extraPaths []string) ([]string, error)
 	return deduped, nil
 }
 
+// IsCodeFile reports whether path has one of the given code file extensions
+// (DefaultCodeExtensions when exts is empty).
+func IsCodeFile(path string, exts []string) bool {
+	if len(exts) == 0 {
+		exts = entity.DefaultCodeExtensions
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return false
+	}
+	for _, e := range exts {
+		if strings.EqualFold(e, ext) || strings.EqualFold("."+e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListCodeFiles scans the configured code paths for source files.
+// Hidden directories and common dependency/build directories are skipped.
+func ListCodeFiles(workspaceDir string, cfg entity.CodeConfig) ([]string, error) {
+	var result []string
+	seen := make(map[string]struct{})
+
+	add := func(absPath string, info fs.FileInfo) {
+		if !info.Mode().IsRegular() || !IsCodeFile(absPath, cfg.Extensions) {
+			return
+		}
+		if cfg.MaxFileBytes > 0 && info.Size() > cfg.MaxFileBytes {
+			return
+		}
+		if _, ok := seen[absPath]; ok {
+			return
+		}
+		seen[absPath] = struct{}{}
+		result = append(result, absPath)
+	}
+
+	for _, root := range NormalizeExtraMemoryPaths(workspaceDir, cfg.Paths) {
+		info, err := os.Lstat(root)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if !info.IsDir() {
+			add(root, info)
+			continue
+		}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // skip errors
+			}
+			if d.Type()&os.ModeSymlink != 0 {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && skipCodeDir(d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				add(path, info)
+			}
+			return nil
+		})
+	}
+	return result, nil
+}
+
+// skipCodeDir reports whether a directory should not be scanned for code.
+func skipCodeDir(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	switch name {
+	case "node_modules", "vendor", "dist", "build", "target", "__pycache__":
+		return true
+	}
+	return false
+}
+
 // BuildFileEntry builds a MemoryFileEntry from an absolute file path.
 func BuildFileEntry(absPath, workspaceDir string) (*entity.MemoryFileEntry, error) {
 	info, err := os.Stat(absPath)
@@ -153,6 +290,75 @@ func BuildFileEntry(absPath, workspaceDir string) (*entity.MemoryFileEntry, erro
 	}, nil
 }
 
+// DatedMemoryFile is a daily memory file, named YYYY-MM-DD.md, or one of its
+// rotated segments YYYY-MM-DD.<part>.md.
+type DatedMemoryFile struct {
+	RelPath string    // workspace-relative, slash-separated
+	Date    time.Time // the date in the file name
+	Part    int       // segment number; 0 for the first file of the day
+}
+
+// MemoryFilePart returns the segment number of a rotated memory file
+// ("2026-02-13.2.md" → 2), or 0 for an unrotated one.
+func MemoryFilePart(relPath string) int {
+	name := strings.TrimSuffix(filepath.Base(relPath), ".md")
+	dot := strings.LastIndexByte(name, '.')
+	if dot < 0 {
+		return 0
+	}
+	part, err := strconv.Atoi(name[dot+1:])
+	if err != nil || part <= 0 {
+		return 0
+	}
+	return part
+}
+
+// ListDatedMemoryFiles returns the daily memory files under memory/,
+// including those in agent namespaces and rotated segments, sorted by
+// directory, date and segment.
+func ListDatedMemoryFiles(workspaceDir string) ([]DatedMemoryFile, error) {
+	memoryDir := filepath.Join(workspaceDir, "memory")
+	info, err := os.Lstat(memoryDir)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return nil, nil
+	}
+
+	var paths []string
+	if err := walkDir(memoryDir, &paths); err != nil {
+		return nil, err
+	}
+
+	var result []DatedMemoryFile
+	for _, absPath := range paths {
+		name := strings.TrimSuffix(filepath.Base(absPath), ".md")
+		part := MemoryFilePart(absPath)
+		if part > 0 {
+			name = name[:strings.LastIndexByte(name, '.')]
+		}
+		date, err := time.ParseInLocation("2006-01-02", name, time.Local)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(workspaceDir, absPath)
+		if err != nil {
+			continue
+		}
+		result = append(result, DatedMemoryFile{RelPath: filepath.ToSlash(rel), Date: date, Part: part})
+	}
+	// Sort by directory, then chronologically with segments in order.
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if da, db := path.Dir(a.RelPath), path.Dir(b.RelPath); da != db {
+			return da < db
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Part < b.Part
+	})
+	return result, nil
+}
+
 // walkDir recursively collects .md files from a directory, skipping symlinks.
 func walkDir(dir string, result *[]string) error {
 	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+)
+
+func TestNamespaceForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"MEMORY.md", ""},
+		{"memory/2026-01-05.md", ""},
+		{"memory/agents/notes.md", ""},
+		{"memory/agents/bot/notes.md", "bot"},
+		{"memory/agents/bot/deep/notes.md", "bot"},
+		{"./memory/agents/bot/notes.md", "bot"},
+		{`memory\agents\bot\notes.md`, "bot"},
+	}
+	for _, tt := range tests {
+		if got := NamespaceForPath(tt.path); got != tt.want {
+			t.Errorf("NamespaceForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestScopeMemoryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		agentID string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"no agent", "", "memory/x.md", "memory/x.md", false},
+		{"scoped", "bot", "memory/x.md", "memory/agents/bot/x.md", false},
+		{"nested", "bot", "memory/projects/x.md", "memory/agents/bot/projects/x.md", false},
+		{"already scoped", "bot", "memory/agents/bot/x.md", "memory/agents/bot/x.md", false},
+		{"normalized", "bot", "./memory/x.md", "memory/agents/bot/x.md", false},
+		{"shared root file", "bot", "MEMORY.md", "MEMORY.md", false},
+		{"other agent", "bot", "memory/agents/other/x.md", "", true},
+		{"agents dir file", "bot", "memory/agents/x.md", "", true},
+		{"invalid agent id", "../bot", "memory/x.md", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScopeMemoryPath(tt.agentID, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScopeMemoryPath(%q, %q) error = %v, wantErr %v", tt.agentID, tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ScopeMemoryPath(%q, %q) = %q, want %q", tt.agentID, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidNamespace(t *testing.T) {
+	tests := []struct {
+		ns   string
+		want bool
+	}{
+		{"bot", true},
+		{"bot-2_x", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{`a\b`, false},
+	}
+	for _, tt := range tests {
+		if got := ValidNamespace(tt.ns); got != tt.want {
+			t.Errorf("ValidNamespace(%q) = %v, want %v", tt.ns, got, tt.want)
+		}
+	}
+}
+
+func TestIsCodeFile(t *testing.T) {
+	tests := []struct {
+		path string
+		exts []string
+		want bool
+	}{
+		{"main.go", nil, true},
+		{"App.TSX", nil, true},
+		{"notes.md", nil, false},
+		{"Makefile", nil, false},
+		{"main.go", []string{".py"}, false},
+		{"tool.py", []string{"py"}, true},
+		{"tool.PY", []string{".py"}, true},
+	}
+	for _, tt := range tests {
+		if got := IsCodeFile(tt.path, tt.exts); got != tt.want {
+			t.Errorf("IsCodeFile(%q, %q) = %v, want %v", tt.path, tt.exts, got, tt.want)
+		}
+	}
+}
+
+func TestListCodeFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/main.go":             "package main",
+		"src/big.go":              "package main // padded past the size limit",
+		"src/README.md":           "# readme",
+		"src/pkg/util.py":         "def f(): pass",
+		"src/node_modules/dep.js": "module.exports = 1",
+		"src/vendor/lib/lib.go":   "package lib",
+		"src/.cache/gen.go":       "package gen",
+		"scripts/deploy.sh":       "echo hi",
+		"single.ts":               "export const x = 1",
+	}
+	for rel, content := range files {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ListCodeFiles(dir, entity.CodeConfig{
+		Paths:        []string{"src", "single.ts", "src", "missing"},
+		MaxFileBytes: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		got[i], _ = filepath.Rel(dir, got[i])
+	}
+	slices.Sort(got)
+	want := []string{"single.ts", "src/main.go", "src/pkg/util.py"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ListCodeFiles = %q, want %q", got, want)
+	}
+}
+
+func TestMemoryFilePart(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"memory/2026-02-13.md", 0},
+		{"memory/2026-02-13.1.md", 1},
+		{"memory/agents/bot/2026-02-13.12.md", 12},
+		{"memory/2026-02-13.x.md", 0},
+		{"memory/2026-02-13.0.md", 0},
+		{"memory/notes.v2.md", 0},
+	}
+	for _, tt := range tests {
+		if got := MemoryFilePart(tt.path); got != tt.want {
+			t.Errorf("MemoryFilePart(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestListDatedMemoryFilesSegments(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{
+		"memory/2026-02-14.md",
+		"memory/2026-02-13.10.md",
+		"memory/2026-02-13.2.md",
+		"memory/2026-02-13.md",
+		"memory/2026-02-13.1.md",
+		"memory/2026-02-13.x.md",
+		"memory/notes.md",
+		"memory/agents/bot/2026-02-12.md",
+	} {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("entry"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ListDatedMemoryFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.RelPath)
+		if f.Part != MemoryFilePart(f.RelPath) {
+			t.Errorf("%s: part = %d", f.RelPath, f.Part)
+		}
+	}
+	want := []string{
+		"memory/2026-02-13.md",
+		"memory/2026-02-13.1.md",
+		"memory/2026-02-13.2.md",
+		"memory/2026-02-13.10.md",
+		"memory/2026-02-14.md",
+		"memory/agents/bot/2026-02-12.md",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ListDatedMemoryFiles = %q, want %q", got, want)
+	}
+}
@@ -74,23 +74,34 @@ func BM25RankToScore(rank float64) float64 {
 	return 1.0 / (1.0 + normalized)
 }
 
-// MergeResults merges vector and keyword search results using weighted scoring.
-// Matches OpenClaw's mergeHybridResults.
-func MergeResults(vector []VectorResult, keyword []KeywordResult, vectorWeight, textWeight float64) []entity.MemorySearchResult {
-	type merged struct {
-		id          string
-		path        string
-		startLine   int
-		endLine     int
-		source      entity.MemorySource
-		snippet     string
-		vectorScore float64
-		textScore   float64
-	}
+// merged is a search hit combined from the vector and keyword result lists.
+type merged struct {
+	id          string
+	path        string
+	startLine   int
+	endLine     int
+	source      entity.MemorySource
+	snippet     string
+	vectorScore float64
+	textScore   float64
+	vectorRank  int // 1-based; 0 when absent from vector results
+	textRank    int // 1-based; 0 when absent from keyword results
+}
 
+// mergeByID joins vector and keyword results by chunk ID.
+// Ranks are the 1-based positions in each list ordered by descending score.
+func mergeByID(vector []VectorResult, keyword []KeywordResult) map[string]*merged {
 	byID := make(map[string]*merged)
 
-	for _, r := range vector {
+	vectorOrder := make([]int, len(vector))
+	for i := range vectorOrder {
+		vectorOrder[i] = i
+	}
+	sort.SliceStable(vectorOrder, func(a, b int) bool {
+		return vector[vectorOrder[a]].VectorScore > vector[vectorOrder[b]].VectorScore
+	})
+	for rank, idx := range vectorOrder {
+		r := vector[idx]
 		byID[r.ID] = &merged{
 			id:          r.ID,
 			path:        r.Path,
@@ -99,12 +110,22 @@ func MergeResults(vector []VectorResult, keyword []KeywordResult, vectorWeight,
 			source:      r.Source,
 			snippet:     r.Snippet,
 			vectorScore: r.VectorScore,
+			vectorRank:  rank + 1,
 		}
 	}
 
-	for _, r := range keyword {
+	keywordOrder := make([]int, len(keyword))
+	for i := range keywordOrder {
+		keywordOrder[i] = i
+	}
+	sort.SliceStable(keywordOrder, func(a, b int) bool {
+		return keyword[keywordOrder[a]].TextScore > keyword[keywordOrder[b]].TextScore
+	})
+	for rank, idx := range keywordOrder {
+		r := keyword[idx]
 		if existing, ok := byID[r.ID]; ok {
 			existing.textScore = r.TextScore
+			existing.textRank = rank + 1
 			if r.Snippet != "" {
 				existing.snippet = r.Snippet
 			}
@@ -117,20 +138,62 @@ func MergeResults(vector []VectorResult, keyword []KeywordResult, vectorWeight,
 				source:    r.Source,
 				snippet:   r.Snippet,
 				textScore: r.TextScore,
+				textRank:  rank + 1,
 			}
 		}
 	}
 
+	return byID
+}
+
+// MergeResults merges vector and keyword search results using weighted scoring.
+// Matches OpenClaw's mergeHybridResults.
+func MergeResults(vector []VectorResult, keyword []KeywordResult, vectorWeight, textWeight float64) []entity.MemorySearchResult {
+	return toSortedResults(mergeByID(vector, keyword), func(entry *merged) float64 {
+		return vectorWeight*entry.vectorScore + textWeight*entry.textScore
+	})
+}
+
+// MergeResultsRRF merges vector and keyword search results using reciprocal rank
+// fusion: score = vectorWeight/(k+vectorRank) + textWeight/(k+textRank), where a
+// list the hit is absent from contributes nothing.
+//
+// The fused score is normalized by its maximum (rank 1 in both lists) so it
+// stays in [0, 1] and remains comparable with QueryConfig.MinScore.
+func MergeResultsRRF(vector []VectorResult, keyword []KeywordResult, vectorWeight, textWeight float64, k int) []entity.MemorySearchResult {
+	if k <= 0 {
+		k = entity.DefaultRRFK
+	}
+	maxScore := (vectorWeight + textWeight) / float64(k+1)
+
+	return toSortedResults(mergeByID(vector, keyword), func(entry *merged) float64 {
+		var score float64
+		if entry.vectorRank > 0 {
+			score += vectorWeight / float64(k+entry.vectorRank)
+		}
+		if entry.textRank > 0 {
+			score += textWeight / float64(k+entry.textRank)
+		}
+		if maxScore > 0 {
+			score /= maxScore
+		}
+		return score
+	})
+}
+
+// toSortedResults scores each merged entry and returns them by descending score.
+func toSortedResults(byID map[string]*merged, scoreFn func(*merged) float64) []entity.MemorySearchResult {
 	results := make([]entity.MemorySearchResult, 0, len(byID))
 	for _, entry := range byID {
-		score := vectorWeight*entry.vectorScore + textWeight*entry.textScore
+		score := scoreFn(entry)
 		results = append(results, entity.MemorySearchResult{
-			Path:      entry.path,
-			StartLine: entry.startLine,
-			EndLine:   entry.endLine,
-			Score:     score,
-			Snippet:   entry.snippet,
-			Source:    entry.source,
+			Path:        entry.path,
+			StartLine:   entry.startLine,
+			EndLine:     entry.endLine,
+			Score:       score,
+			VectorScore: entry.vectorScore,
+			Snippet:     entry.snippet,
+			Source:      entry.source,
 		})
 	}
 
@@ -0,0 +1,107 @@
+package hybrid
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+)
+
+func resultPaths(results []entity.MemorySearchResult) []string {
+	paths := make([]string, 0, len(results))
+	for _, r := range results {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+func TestMergeResultsRRF(t *testing.T) {
+	// Vector ranks: a, b, c. Keyword ranks: c, a, d. The input order is not
+	// the rank order, which comes from the scores.
+	vector := []VectorResult{
+		{ID: "c", Path: "c.md", VectorScore: 0.2},
+		{ID: "a", Path: "a.md", VectorScore: 0.9},
+		{ID: "b", Path: "b.md", VectorScore: 0.5},
+	}
+	keyword := []KeywordResult{
+		{ID: "a", Path: "a.md", TextScore: 0.4},
+		{ID: "c", Path: "c.md", TextScore: 0.8},
+		{ID: "d", Path: "d.md", TextScore: 0.1},
+	}
+
+	tests := []struct {
+		name       string
+		vectorW    float64
+		textW      float64
+		k          int
+		wantOrder  []string
+		wantScores map[string]float64
+	}{
+		{
+			name: "equal weights", vectorW: 1, textW: 1, k: 60,
+			wantOrder: []string{"a.md", "c.md", "b.md", "d.md"},
+			wantScores: map[string]float64{
+				"a.md": (1.0/61 + 1.0/62) / (2.0 / 61),
+				"c.md": (1.0/63 + 1.0/61) / (2.0 / 61),
+				"b.md": (1.0 / 62) / (2.0 / 61),
+				"d.md": (1.0 / 63) / (2.0 / 61),
+			},
+		},
+		{
+			name: "keyword weighted", vectorW: 0.2, textW: 0.8, k: 1,
+			wantOrder: []string{"c.md", "a.md", "d.md", "b.md"},
+		},
+		{
+			name: "default k", vectorW: 1, textW: 1, k: 0,
+			wantOrder: []string{"a.md", "c.md", "b.md", "d.md"},
+			wantScores: map[string]float64{
+				"b.md": (1.0 / float64(entity.DefaultRRFK+2)) / (2.0 / float64(entity.DefaultRRFK+1)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeResultsRRF(vector, keyword, tt.vectorW, tt.textW, tt.k)
+			if paths := resultPaths(got); !slices.Equal(paths, tt.wantOrder) {
+				t.Fatalf("order = %v, want %v", paths, tt.wantOrder)
+			}
+			for _, r := range got {
+				if r.Score <= 0 || r.Score > 1 {
+					t.Fatalf("%s score = %v, want it in (0, 1]", r.Path, r.Score)
+				}
+				if want, ok := tt.wantScores[r.Path]; ok && math.Abs(r.Score-want) > 1e-9 {
+					t.Fatalf("%s score = %v, want %v", r.Path, r.Score, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeResultsRRFTopInBothIsOne(t *testing.T) {
+	got := MergeResultsRRF(
+		[]VectorResult{{ID: "a", Path: "a.md", VectorScore: 0.3}},
+		[]KeywordResult{{ID: "a", Path: "a.md", TextScore: 0.01}},
+		0.7, 0.3, 60)
+	if len(got) != 1 || math.Abs(got[0].Score-1) > 1e-9 {
+		t.Fatalf("results = %+v, want a single hit scoring 1", got)
+	}
+	if got[0].VectorScore != 0.3 {
+		t.Fatalf("vector score = %v, want the raw 0.3 kept", got[0].VectorScore)
+	}
+}
+
+func TestMergeResultsWeighted(t *testing.T) {
+	// Unlike RRF, weighted merging uses the raw scores: a keyword hit far
+	// ahead of the rest outweighs the vector ranks.
+	got := MergeResults(
+		[]VectorResult{{ID: "a", Path: "a.md", VectorScore: 0.5}, {ID: "b", Path: "b.md", VectorScore: 0.4}},
+		[]KeywordResult{{ID: "b", Path: "b.md", TextScore: 0.9, Snippet: "keyword snippet"}},
+		0.5, 0.5)
+	if paths := resultPaths(got); !slices.Equal(paths, []string{"b.md", "a.md"}) {
+		t.Fatalf("order = %v, want [b.md a.md]", paths)
+	}
+	if math.Abs(got[0].Score-0.65) > 1e-9 || got[0].Snippet != "keyword snippet" {
+		t.Fatalf("b = %+v, want score 0.65 with the keyword snippet", got[0])
+	}
+}
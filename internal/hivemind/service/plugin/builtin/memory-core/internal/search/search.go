@@ -1,9 +1,9 @@
 package search
 
 import (
+	"container/heap"
 	"database/sql"
 	"fmt"
-	"sort"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
 	meminternal "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal"
@@ -12,67 +12,149 @@ import (
 )
 
 const (
+	// SnippetMaxChars is the default snippet length in characters.
 	SnippetMaxChars = 700
 )
 
+// snippetLimit returns n, or SnippetMaxChars when n is not positive.
+func snippetLimit(n int) int {
+	if n <= 0 {
+		return SnippetMaxChars
+	}
+	return n
+}
+
 type SearchVectorParams struct {
 	DB            *sql.DB
 	ProviderModel string
 	QueryVec      []float32
 	Limit         int
 	SourceFilter  []entity.MemorySource
+
+	// Namespace restricts results to shared chunks plus those owned by this
+	// agent namespace. Empty disables namespace filtering.
+	Namespace string
+
+	// SnippetMaxChars caps result snippets. 0 means SnippetMaxChars.
+	SnippetMaxChars int
+
+	// EarlyStopScore enables approximate early termination for large indexes.
+	// Once Limit candidates have been collected and the weakest of them scores
+	// at or above this threshold, the scan stops. 0 disables early termination
+	// and keeps the result identical to an exhaustive top-K.
+	EarlyStopScore float64
 }
 
 // SearchVector performs a vector similarity search against the chunks table.
 // Currently, uses pure Go cosine similarity. (no sqlite-vec extension required)
+//
+// Chunks are streamed from the database and scored one by one into a bounded
+// min-heap of size Limit, so neither a full intermediate slice nor a full sort
+// is needed. Chunks whose embedding dimension differs from the query are skipped.
 func SearchVector(params SearchVectorParams) ([]hybrid.VectorResult, error) {
-	if len(params.QueryVec) == 0 || params.Limit <= 0 {
-		return nil, nil
-	}
-
-	// Load all chunks and compute cosine similarity in Go.
-	chunks, err := listChunks(params.DB, params.ProviderModel, params.SourceFilter)
+	results, err := SearchVectorMulti(params, [][]float32{params.QueryVec})
 	if err != nil {
 		return nil, err
 	}
+	return results[0], nil
+}
 
-	type scored struct {
-		chunk chunkRow
-		score float64
+// SearchVectorMulti is SearchVector for several query vectors: the chunks are
+// scanned once and scored against every query. params.QueryVec is ignored;
+// the results are in queryVecs order, nil for an empty query vector. With
+// EarlyStopScore, the scan stops once every query's top-K qualifies.
+func SearchVectorMulti(params SearchVectorParams, queryVecs [][]float32) ([][]hybrid.VectorResult, error) {
+	results := make([][]hybrid.VectorResult, len(queryVecs))
+	if params.Limit <= 0 {
+		return results, nil
 	}
 
-	var scoredResults []scored
-	for _, chunk := range chunks {
-		s := meminternal.CosineSimilarity(params.QueryVec, chunk.embedding)
-		if s > 0 {
-			scoredResults = append(scoredResults, scored{chunk: chunk, score: s})
+	tops := make([]scoredHeap, len(queryVecs))
+	active := 0
+	for i, vec := range queryVecs {
+		if len(vec) > 0 {
+			tops[i] = make(scoredHeap, 0, params.Limit)
+			active++
 		}
 	}
+	if active == 0 {
+		return results, nil
+	}
 
-	sort.Slice(scoredResults, func(i, j int) bool {
-		return scoredResults[i].score > scoredResults[j].score
+	err := scanChunks(params.DB, params.ProviderModel, params.SourceFilter, params.Namespace, func(chunk chunkRow) bool {
+		settled := 0
+		for i, vec := range queryVecs {
+			if len(vec) == 0 {
+				continue
+			}
+			top := &tops[i]
+			if len(chunk.embedding) == len(vec) {
+				if s := meminternal.CosineSimilarity(vec, chunk.embedding); s > 0 {
+					if len(*top) < params.Limit {
+						heap.Push(top, scoredChunk{chunk: chunk, score: s})
+					} else if s > (*top)[0].score {
+						(*top)[0] = scoredChunk{chunk: chunk, score: s}
+						heap.Fix(top, 0)
+					}
+				}
+			}
+			if params.EarlyStopScore > 0 && len(*top) == params.Limit && (*top)[0].score >= params.EarlyStopScore {
+				settled++
+			}
+		}
+		return settled < active
 	})
-
-	limit := params.Limit
-	if limit > len(scoredResults) {
-		limit = len(scoredResults)
+	if err != nil {
+		return nil, err
 	}
 
-	results := make([]hybrid.VectorResult, 0, limit)
-	for _, entry := range scoredResults[:limit] {
-		results = append(results, hybrid.VectorResult{
-			ID:          entry.chunk.id,
-			Path:        entry.chunk.path,
-			StartLine:   entry.chunk.startLine,
-			EndLine:     entry.chunk.endLine,
-			VectorScore: entry.score,
-			Snippet:     meminternal.TruncateUTF8Safe(entry.chunk.text, SnippetMaxChars),
-			Source:      entry.chunk.source,
-		})
+	for i := range tops {
+		top := tops[i]
+		if len(top) == 0 {
+			continue
+		}
+		// Pop in ascending order and fill from the back to get descending scores.
+		out := make([]hybrid.VectorResult, len(top))
+		for j := len(top) - 1; j >= 0; j-- {
+			entry := heap.Pop(&top).(scoredChunk)
+			out[j] = hybrid.VectorResult{
+				ID:          entry.chunk.id,
+				Path:        entry.chunk.path,
+				StartLine:   entry.chunk.startLine,
+				EndLine:     entry.chunk.endLine,
+				VectorScore: entry.score,
+				Snippet:     meminternal.TruncateUTF8Safe(entry.chunk.text, snippetLimit(params.SnippetMaxChars)),
+				Source:      entry.chunk.source,
+			}
+		}
+		results[i] = out
 	}
 	return results, nil
 }
 
+// scoredChunk is a chunk paired with its similarity to the query.
+type scoredChunk struct {
+	chunk chunkRow
+	score float64
+}
+
+// scoredHeap is a min-heap on score, used to keep the top-K chunks.
+type scoredHeap []scoredChunk
+
+func (h scoredHeap) Len() int           { return len(h) }
+func (h scoredHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h scoredHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *scoredHeap) Push(x interface{}) { *h = append(*h, x.(scoredChunk)) }
+
+func (h *scoredHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
 // SearchVectorVec performs a KNN vector search using the sqlite-vec (vec0) virtual table.
 // This is much faster than brute-force cosine similarity for large datasets.
 // It queries vec0 for nearest neighbors, then joins with chunks table for metadata.
@@ -101,12 +183,17 @@ func SearchVectorVec(params SearchVectorVecParams) ([]hybrid.VectorResult, error
 	var results []hybrid.VectorResult
 	for _, vr := range vecResults {
 		row := params.DB.QueryRow(
-			`SELECT id, path, source, start_line, end_line, text FROM `+store.TableChunks+` WHERE id = ?`,
+			`SELECT id, path, source, namespace, start_line, end_line, text FROM `+store.TableChunks+` WHERE id = ?`,
 			vr.ChunkID,
 		)
-		var id, path, source, text string
+		var id, path, source, namespace, text string
 		var startLine, endLine int
-		if err := row.Scan(&id, &path, &source, &startLine, &endLine, &text); err != nil {
+		if err := row.Scan(&id, &path, &source, &namespace, &startLine, &endLine, &text); err != nil {
+			continue
+		}
+
+		// Apply namespace filter.
+		if params.Namespace != "" && namespace != "" && namespace != params.Namespace {
 			continue
 		}
 
@@ -127,7 +214,7 @@ func SearchVectorVec(params SearchVectorVecParams) ([]hybrid.VectorResult, error
 			StartLine:   startLine,
 			EndLine:     endLine,
 			VectorScore: score,
-			Snippet:     meminternal.TruncateUTF8Safe(text, SnippetMaxChars),
+			Snippet:     meminternal.TruncateUTF8Safe(text, snippetLimit(params.SnippetMaxChars)),
 			Source:      entity.MemorySource(source),
 		})
 
@@ -145,6 +232,13 @@ type SearchVectorVecParams struct {
 	QueryVec     []float32
 	Limit        int
 	SourceFilter []entity.MemorySource
+
+	// Namespace restricts results to shared chunks plus those owned by this
+	// agent namespace. Empty disables namespace filtering.
+	Namespace string
+
+	// SnippetMaxChars caps result snippets. 0 means SnippetMaxChars.
+	SnippetMaxChars int
 }
 
 // SearchKeywordParams holds the parameters for a keyword search.
@@ -154,6 +248,13 @@ type SearchKeywordParams struct {
 	Query         string
 	Limit         int
 	SourceFilter  []entity.MemorySource
+
+	// Namespace restricts results to shared chunks plus those owned by this
+	// agent namespace. Empty disables namespace filtering.
+	Namespace string
+
+	// SnippetMaxChars caps result snippets. 0 means SnippetMaxChars.
+	SnippetMaxChars int
 }
 
 // SearchKeyword performs a keyword search using FTS5.
@@ -170,14 +271,22 @@ func SearchKeyword(params SearchKeywordParams) ([]hybrid.KeywordResult, error) {
 	// Build source filter SQL.
 	sourceSQL, sourceArgs := buildSourceFilter(params.SourceFilter)
 
+	// The FTS table has no namespace column; filter through the chunks table.
+	namespaceSQL, namespaceArgs := "", []interface{}(nil)
+	if params.Namespace != "" {
+		namespaceSQL = " AND id IN (SELECT id FROM " + store.TableChunks + " WHERE namespace IN ('', ?))"
+		namespaceArgs = []interface{}{params.Namespace}
+	}
+
 	query := fmt.Sprintf(
-		`SELECT id, path, source, start_line, end_line, text, bm25(%s) AS rank FROM %s WHERE %s MATCH ? AND model = ?%s ORDER BY rank ASC LIMIT ?`,
-		store.TableChunksFTS, store.TableChunksFTS, store.TableChunksFTS, sourceSQL,
+		`SELECT id, path, source, start_line, end_line, text, bm25(%s) AS rank FROM %s WHERE %s MATCH ? AND model = ?%s%s ORDER BY rank ASC LIMIT ?`,
+		store.TableChunksFTS, store.TableChunksFTS, store.TableChunksFTS, sourceSQL, namespaceSQL,
 	)
 
 	args := make([]interface{}, 0)
 	args = append(args, ftsQuery, params.ProviderModel)
 	args = append(args, sourceArgs...)
+	args = append(args, namespaceArgs...)
 	args = append(args, params.Limit)
 
 	rows, err := params.DB.Query(query, args...)
@@ -201,7 +310,7 @@ func SearchKeyword(params SearchKeywordParams) ([]hybrid.KeywordResult, error) {
 			StartLine: startLine,
 			EndLine:   endLine,
 			TextScore: textScore,
-			Snippet:   meminternal.TruncateUTF8Safe(text, SnippetMaxChars),
+			Snippet:   meminternal.TruncateUTF8Safe(text, snippetLimit(params.SnippetMaxChars)),
 			Source:    entity.MemorySource(source),
 		})
 	}
@@ -219,9 +328,14 @@ type chunkRow struct {
 	source    entity.MemorySource
 }
 
-// listChunks loads all chunks from the database for a given model and source filter.
-func listChunks(db *sql.DB, providerModel string, sourceFilter []entity.MemorySource) ([]chunkRow, error) {
+// scanChunks streams all chunks for a given model, source and namespace filter, calling fn for each.
+// Iteration stops early when fn returns false.
+func scanChunks(db *sql.DB, providerModel string, sourceFilter []entity.MemorySource, namespace string, fn func(chunkRow) bool) error {
 	sourceSQL, sourceArgs := buildSourceFilter(sourceFilter)
+	if namespace != "" {
+		sourceSQL += " AND namespace IN ('', ?)"
+		sourceArgs = append(sourceArgs, namespace)
+	}
 
 	query := fmt.Sprintf(
 		`SELECT id, path, start_line, end_line, text, embedding, source FROM %s WHERE model = ?%s`,
@@ -234,11 +348,10 @@ func listChunks(db *sql.DB, providerModel string, sourceFilter []entity.MemorySo
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var chunks []chunkRow
 	for rows.Next() {
 		var id, path, text, embeddingStr, source string
 		var startLine, endLine int
@@ -246,7 +359,7 @@ func listChunks(db *sql.DB, providerModel string, sourceFilter []entity.MemorySo
 			continue
 		}
 		embedding := meminternal.ParseEmbedding(embeddingStr)
-		chunks = append(chunks, chunkRow{
+		if !fn(chunkRow{
 			id:        id,
 			path:      path,
 			startLine: startLine,
@@ -254,9 +367,11 @@ func listChunks(db *sql.DB, providerModel string, sourceFilter []entity.MemorySo
 			text:      text,
 			embedding: embedding,
 			source:    entity.MemorySource(source),
-		})
+		}) {
+			break
+		}
 	}
-	return chunks, nil
+	return rows.Err()
 }
 
 // buildSourceFilter generates the SQL clause and args for source filtering.
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal/hybrid"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/store"
+)
+
+const testModel = "test-model"
+
+// openVectorTestDB returns a database holding one chunk per embedding, in
+// order, with ids "c0", "c1", ...
+func openVectorTestDB(t *testing.T, embeddings ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := store.EnsureSchema(db, false, nil); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	for i, emb := range embeddings {
+		id := fmt.Sprintf("c%d", i)
+		if err := store.InsertChunk(db, id, "memory/a.md", entity.MemorySourceMemory, "", i+1, i+1, "h"+id, testModel, "text "+id, emb); err != nil {
+			t.Fatalf("insert %s: %v", id, err)
+		}
+	}
+	return db
+}
+
+func resultIDs(results []hybrid.VectorResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearchVector(t *testing.T) {
+	db := openVectorTestDB(t,
+		"[1,0]",     // c0: 1
+		"[0.6,0.8]", // c1: 0.6
+		"[0,1]",     // c2: 0, not similar
+		"[1,0,0]",   // c3: other dimension
+		"[0.8,0.6]", // c4: 0.8
+		"[-1,0]",    // c5: opposite
+	)
+
+	tests := []struct {
+		name  string
+		limit int
+		query []float32
+		want  []string
+	}{
+		{"top-k by score", 2, []float32{1, 0}, []string{"c0", "c4"}},
+		{"only positive scores", 10, []float32{1, 0}, []string{"c0", "c4", "c1"}},
+		{"other dimension", 10, []float32{1, 0, 0}, []string{"c3"}},
+		{"no limit", 0, []float32{1, 0}, nil},
+		{"no query", 5, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SearchVector(SearchVectorParams{DB: db, ProviderModel: testModel, QueryVec: tt.query, Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := resultIDs(got); !slices.Equal(ids, tt.want) {
+				t.Fatalf("results = %v, want %v", ids, tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].VectorScore > got[i-1].VectorScore {
+					t.Fatalf("results not sorted by score: %v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchVectorEarlyStop(t *testing.T) {
+	// The best match comes last in scan order.
+	db := openVectorTestDB(t, "[0.8,0.6]", "[0.6,0.8]", "[1,0]")
+	query := []float32{1, 0}
+
+	tests := []struct {
+		name      string
+		earlyStop float64
+		want      []string
+	}{
+		{"exhaustive", 0, []string{"c2"}},
+		{"threshold reached", 0.7, []string{"c0"}},
+		{"threshold not reached", 0.9, []string{"c2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SearchVector(SearchVectorParams{DB: db, ProviderModel: testModel, QueryVec: query, Limit: 1, EarlyStopScore: tt.earlyStop})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := resultIDs(got); !slices.Equal(ids, tt.want) {
+				t.Fatalf("results = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchVectorMulti(t *testing.T) {
+	db := openVectorTestDB(t, "[1,0]", "[0,1]")
+	got, err := SearchVectorMulti(SearchVectorParams{DB: db, ProviderModel: testModel, Limit: 1},
+		[][]float32{{0, 1}, nil, {1, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d result lists, want 3", len(got))
+	}
+	if ids := resultIDs(got[0]); !slices.Equal(ids, []string{"c1"}) {
+		t.Fatalf("first query = %v, want [c1]", ids)
+	}
+	if got[1] != nil {
+		t.Fatalf("empty query = %v, want nil", got[1])
+	}
+	if ids := resultIDs(got[2]); !slices.Equal(ids, []string{"c0"}) {
+		t.Fatalf("third query = %v, want [c0]", ids)
+	}
+}
+
+func TestSearchVectorNamespace(t *testing.T) {
+	db := openVectorTestDB(t)
+	for _, c := range []struct{ id, path, namespace string }{
+		{"shared", "memory/a.md", ""},
+		{"own", "memory/agents/bot/a.md", "bot"},
+		{"other", "memory/agents/other/a.md", "other"},
+	} {
+		if err := store.InsertChunk(db, c.id, c.path, entity.MemorySourceMemory, c.namespace, 1, 1, "h"+c.id, testModel, "text", "[1,0]"); err != nil {
+			t.Fatalf("insert %s: %v", c.id, err)
+		}
+	}
+
+	tests := []struct {
+		namespace string
+		want      []string
+	}{
+		{"", []string{"other", "own", "shared"}},
+		{"bot", []string{"own", "shared"}},
+		{"nobody", []string{"shared"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			got, err := SearchVector(SearchVectorParams{DB: db, ProviderModel: testModel, QueryVec: []float32{1, 0}, Limit: 10, Namespace: tt.namespace})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := resultIDs(got)
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Fatalf("results = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchVectorSnippetLimit(t *testing.T) {
+	db := openVectorTestDB(t)
+	text := strings.Repeat("a", 1000)
+	if err := store.InsertChunk(db, "long", "memory/a.md", entity.MemorySourceMemory, "", 1, 1, "hlong", testModel, text, "[1,0]"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		maxChars int
+		want     int
+	}{
+		{"default", 0, SnippetMaxChars},
+		{"custom", 50, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SearchVector(SearchVectorParams{DB: db, ProviderModel: testModel, QueryVec: []float32{1, 0}, Limit: 1, SnippetMaxChars: tt.maxChars})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d results, want 1", len(got))
+			}
+			if n := len(got[0].Snippet); n != tt.want {
+				t.Fatalf("snippet of %d chars, want %d", n, tt.want)
+			}
+		})
+	}
+}
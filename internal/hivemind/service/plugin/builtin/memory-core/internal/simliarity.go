@@ -34,6 +34,24 @@ func CosineSimilarity(a, b []float32) float64 {
 	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
+// NormalizeL2 returns a copy of v scaled to unit length.
+// A zero vector is returned unchanged.
+func NormalizeL2(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
 // ParseEmbedding parses a JSON-encoded string into a slice of float32 values.
 //
 // If the parsing fails, an error is logged and nil is returned.
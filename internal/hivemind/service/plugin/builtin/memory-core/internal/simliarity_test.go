@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeL2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []float32
+		want []float32
+	}{
+		{"scaled", []float32{3, 4}, []float32{0.6, 0.8}},
+		{"negative", []float32{0, -2, 0}, []float32{0, -1, 0}},
+		{"unit", []float32{1, 0}, []float32{1, 0}},
+		{"zero", []float32{0, 0}, []float32{0, 0}},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]float32(nil), tt.in...)
+			got := NormalizeL2(in)
+			if len(got) != len(tt.want) {
+				t.Fatalf("NormalizeL2(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("NormalizeL2(%v) = %v, want %v", tt.in, got, tt.want)
+				}
+			}
+			for i := range in {
+				if in[i] != tt.in[i] {
+					t.Fatalf("input modified to %v", in)
+				}
+			}
+		})
+	}
+}
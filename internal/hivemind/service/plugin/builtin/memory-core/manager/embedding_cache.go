@@ -0,0 +1,225 @@
+package manager
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/embedding"
+	meminternal "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/store"
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// embeddingKey identifies a cached embedding, mirroring the primary key of
+// the SQLite embedding_cache table.
+type embeddingKey struct {
+	provider    string
+	model       string
+	providerKey string
+	hash        string
+}
+
+type embeddingLRUEntry struct {
+	key       embeddingKey
+	embedding string // JSON-encoded vector, as stored in SQLite
+}
+
+// embeddingLRU is a bounded in-process LRU of embeddings kept in front of the
+// SQLite embedding cache, so repeated syncs don't query SQLite chunk by chunk.
+// A nil *embeddingLRU is a valid, always-empty cache.
+type embeddingLRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front = most recently used
+	items      map[embeddingKey]*list.Element
+}
+
+// newEmbeddingLRU returns an LRU holding up to maxEntries embeddings,
+// or nil when maxEntries is not positive.
+func newEmbeddingLRU(maxEntries int) *embeddingLRU {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &embeddingLRU{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[embeddingKey]*list.Element),
+	}
+}
+
+func (c *embeddingLRU) get(key embeddingKey) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*embeddingLRUEntry).embedding, true
+}
+
+func (c *embeddingLRU) add(key embeddingKey, embedding string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*embeddingLRUEntry).embedding = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&embeddingLRUEntry{key: key, embedding: embedding})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*embeddingLRUEntry).key)
+	}
+}
+
+// purge drops every entry; used whenever the SQLite cache is wiped.
+func (c *embeddingLRU) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+}
+
+// embeddingCacheStore is the persistent embedding cache behind the LRU.
+type embeddingCacheStore interface {
+	load(provider, model, providerKey string, hashes []string) (map[string]string, error)
+	upsert(provider, model, providerKey, hash, embedding string, dims int) error
+}
+
+// sqliteEmbeddingCache is the embedding_cache table of the index database.
+type sqliteEmbeddingCache struct {
+	db *sql.DB
+}
+
+func (c sqliteEmbeddingCache) load(provider, model, providerKey string, hashes []string) (map[string]string, error) {
+	return store.LoadEmbeddingCache(c.db, provider, model, providerKey, hashes)
+}
+
+func (c sqliteEmbeddingCache) upsert(provider, model, providerKey, hash, embedding string, dims int) error {
+	return store.UpsertEmbeddingCache(c.db, provider, model, providerKey, hash, embedding, dims)
+}
+
+// loadEmbeddings returns the cached embeddings (JSON) of the given chunk
+// hashes. The LRU is consulted first; only misses are read from SQLite, and
+// the rows found there are promoted into the LRU.
+func (m *Manager) loadEmbeddings(provider embedding.Provider, providerKey string, hashes []string) map[string]string {
+	result := make(map[string]string, len(hashes))
+	var misses []string
+	for _, h := range hashes {
+		key := embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: h}
+		if emb, ok := m.embeddingLRU.get(key); ok {
+			result[h] = emb
+			continue
+		}
+		misses = append(misses, h)
+	}
+	if len(misses) == 0 {
+		return result
+	}
+
+	stored, _ := m.cacheStore.load(provider.ID(), provider.Model(), providerKey, misses)
+	for h, emb := range stored {
+		result[h] = emb
+		m.embeddingLRU.add(embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: h}, emb)
+	}
+	return result
+}
+
+// storeEmbedding writes a new embedding through the LRU to SQLite.
+func (m *Manager) storeEmbedding(provider embedding.Provider, providerKey, hash string, vec []float32) {
+	embJSON, _ := json.Marshal(vec)
+	m.embeddingLRU.add(embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: hash}, string(embJSON))
+	m.cacheStore.upsert(provider.ID(), provider.Model(), providerKey, hash, string(embJSON), len(vec))
+}
+
+// queryCacheKey is the LRU key of a query embedding. Query keys are kept
+// apart from chunk hashes, as providers may embed queries differently from
+// documents. Query embeddings live in the LRU only, not in SQLite.
+func queryCacheKey(provider embedding.Provider, query string) embeddingKey {
+	return embeddingKey{
+		provider:    provider.ID(),
+		model:       provider.Model(),
+		providerKey: embedding.ProviderKey(provider),
+		hash:        "query:" + meminternal.HashText(query),
+	}
+}
+
+// cachedQueryEmbedding returns the cached embedding of query, as returned by
+// the provider (not normalized).
+func (m *Manager) cachedQueryEmbedding(provider embedding.Provider, query string) ([]float32, bool) {
+	emb, ok := m.embeddingLRU.get(queryCacheKey(provider, query))
+	if !ok {
+		return nil, false
+	}
+	vec := meminternal.ParseEmbedding(emb)
+	return vec, len(vec) > 0
+}
+
+// cacheQueryEmbedding keeps the embedding of query for later searches.
+func (m *Manager) cacheQueryEmbedding(provider embedding.Provider, query string, vec []float32) {
+	if len(vec) == 0 {
+		return
+	}
+	embJSON, _ := json.Marshal(vec)
+	m.embeddingLRU.add(queryCacheKey(provider, query), string(embJSON))
+}
+
+// Warm prepares the manager for its first searches: it loads the most
+// recently cached embeddings into the in-process LRU, reads the index tables
+// so that SQLite has their pages cached, and embeds queries into the query
+// cache. It stops early when ctx is done; the work done so far is kept.
+func (m *Manager) Warm(ctx context.Context, queries []string) error {
+	provider := m.activeProvider()
+	providerKey := embedding.ProviderKey(provider)
+
+	loaded := 0
+	if m.embeddingLRU != nil {
+		stored, err := store.LoadRecentEmbeddingCache(ctx, m.db, provider.ID(), provider.Model(), providerKey, m.embeddingLRU.maxEntries)
+		if err != nil {
+			return fmt.Errorf("load embedding cache: %w", err)
+		}
+		for h, emb := range stored {
+			m.embeddingLRU.add(embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: h}, emb)
+		}
+		loaded = len(stored)
+	}
+
+	// Full scans pull the table pages into the SQLite page cache.
+	scans := []string{`SELECT coalesce(sum(length(embedding)), 0) FROM ` + store.TableChunks}
+	if m.ftsAvailable {
+		// The FTS table reads its content from chunks; its index lives in
+		// the _data shadow table.
+		scans = append(scans, `SELECT coalesce(sum(length(block)), 0) FROM `+store.TableChunksFTS+`_data`)
+	}
+	if m.vecAvailable.Load() {
+		scans = append(scans, `SELECT count(*) FROM `+store.TableChunksVec)
+	}
+	for _, q := range scans {
+		var n int64
+		if err := m.db.QueryRowContext(ctx, q).Scan(&n); err != nil {
+			return fmt.Errorf("warm index: %w", err)
+		}
+	}
+
+	if len(queries) > 0 && ctx.Err() == nil {
+		m.embedQueries(ctx, provider, queries)
+	}
+
+	logger.Info("[Memory] warmed up: %d cached embeddings loaded, %d warm queries", loaded, len(queries))
+	return ctx.Err()
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/embedding"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/store"
+)
+
+func TestEmbeddingLRU(t *testing.T) {
+	key := func(hash string) embeddingKey { return embeddingKey{provider: "p", model: "m", hash: hash} }
+	c := newEmbeddingLRU(2)
+	c.add(key("a"), "[1]")
+	c.add(key("b"), "[2]")
+	if _, ok := c.get(key("a")); !ok {
+		t.Fatal("a missing")
+	}
+
+	// b is now the least recently used entry.
+	c.add(key("c"), "[3]")
+	if _, ok := c.get(key("b")); ok {
+		t.Fatal("b not evicted")
+	}
+	c.add(key("a"), "[1.5]")
+	if emb, ok := c.get(key("a")); !ok || emb != "[1.5]" {
+		t.Fatalf("a = %q, %v; want the updated embedding", emb, ok)
+	}
+	if c.order.Len() != 2 || len(c.items) != 2 {
+		t.Fatalf("size = %d/%d, want 2", c.order.Len(), len(c.items))
+	}
+	if _, ok := c.get(embeddingKey{provider: "p", model: "other", hash: "a"}); ok {
+		t.Fatal("hit for another model")
+	}
+
+	c.purge()
+	if _, ok := c.get(key("a")); ok || c.order.Len() != 0 {
+		t.Fatal("entries left after purge")
+	}
+}
+
+func TestEmbeddingLRUDisabled(t *testing.T) {
+	c := newEmbeddingLRU(0)
+	if c != nil {
+		t.Fatal("want nil cache for max entries 0")
+	}
+	c.add(embeddingKey{hash: "a"}, "[1]")
+	if _, ok := c.get(embeddingKey{hash: "a"}); ok {
+		t.Fatal("nil cache returned an entry")
+	}
+	c.purge()
+}
+
+func TestLoadEmbeddingsThroughLRU(t *testing.T) {
+	m := newTestManager(t, testConfig(t, newEmbeddingStub(t)))
+	provider := m.activeProvider()
+	providerKey := embedding.ProviderKey(provider)
+
+	m.storeEmbedding(provider, providerKey, "h1", []float32{1, 2})
+	if stored, _ := store.LoadEmbeddingCache(m.db, provider.ID(), provider.Model(), providerKey, []string{"h1"}); stored["h1"] != "[1,2]" {
+		t.Fatalf("SQLite cache = %q, want the embedding written through", stored["h1"])
+	}
+
+	// A miss is read from SQLite and promoted into the LRU.
+	m.embeddingLRU.purge()
+	if got := m.loadEmbeddings(provider, providerKey, []string{"h1", "h2"}); len(got) != 1 || got["h1"] != "[1,2]" {
+		t.Fatalf("loadEmbeddings = %v, want only h1 from SQLite", got)
+	}
+	key := embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: "h1"}
+	if _, ok := m.embeddingLRU.get(key); !ok {
+		t.Fatal("h1 not promoted into the LRU")
+	}
+
+	// A hit is served by the LRU without reading SQLite.
+	m.embeddingLRU.add(key, "[9,9]")
+	if got := m.loadEmbeddings(provider, providerKey, []string{"h1"}); got["h1"] != "[9,9]" {
+		t.Fatalf("h1 = %q, want the LRU entry", got["h1"])
+	}
+}
+
+// countingCacheStore counts the lookups reaching the wrapped cache store.
+type countingCacheStore struct {
+	embeddingCacheStore
+	loads atomic.Int64
+}
+
+func (c *countingCacheStore) load(provider, model, providerKey string, hashes []string) (map[string]string, error) {
+	c.loads.Add(1)
+	return c.embeddingCacheStore.load(provider, model, providerKey, hashes)
+}
+
+func TestResyncServedByLRU(t *testing.T) {
+	cfg := testConfig(t, newEmbeddingStub(t))
+	writeWorkspaceFile(t, cfg, "MEMORY.md", "# Notes\n\nThe deploy runs on Fridays.\n")
+	writeWorkspaceFile(t, cfg, "memory/2026-01-02.md", "Met the platform team about quotas.\n")
+	m := newTestManager(t, cfg)
+	counting := &countingCacheStore{embeddingCacheStore: m.cacheStore}
+	m.cacheStore = counting
+
+	ctx := context.Background()
+	if err := m.Sync(ctx, SyncOpts{Reason: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if counting.loads.Load() == 0 {
+		t.Fatal("first sync read nothing from the cache store")
+	}
+
+	// Force re-embeds the unchanged files; every embedding is in the LRU.
+	counting.loads.Store(0)
+	if err := m.Sync(ctx, SyncOpts{Reason: "test", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n := counting.loads.Load(); n != 0 {
+		t.Fatalf("second sync read the cache store %d times, want 0", n)
+	}
+	if chunkCount(t, m) == 0 {
+		t.Fatal("second sync left no chunks")
+	}
+}
+
+func TestWarm(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	m := newTestManager(t, testConfig(t, stub))
+	provider := m.activeProvider()
+	providerKey := embedding.ProviderKey(provider)
+	m.storeEmbedding(provider, providerKey, "h1", []float32{1, 2})
+	m.storeEmbedding(provider, providerKey, "h2", []float32{3, 4})
+	m.embeddingLRU.purge()
+
+	if err := m.Warm(context.Background(), []string{"deploy day", "quotas"}); err != nil {
+		t.Fatalf("warm: %v", err)
+	}
+	for _, h := range []string{"h1", "h2"} {
+		if _, ok := m.embeddingLRU.get(embeddingKey{provider: provider.ID(), model: provider.Model(), providerKey: providerKey, hash: h}); !ok {
+			t.Fatalf("%s not loaded into the LRU", h)
+		}
+	}
+	if n := stub.requests.Load(); n != 1 {
+		t.Fatalf("%d embedding requests, want the warm queries in one batch", n)
+	}
+
+	// Searching a warm query needs no embedding call.
+	if _, err := m.Search(context.Background(), "quotas"); err != nil {
+		t.Fatal(err)
+	}
+	if n := stub.requests.Load(); n != 1 {
+		t.Fatalf("%d embedding requests after searching a warm query, want 1", n)
+	}
+}
+
+func TestWarmStopsWhenCancelled(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	m := newTestManager(t, testConfig(t, stub))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := m.Warm(ctx, []string{"deploy day"}); err == nil {
+		t.Fatal("cancelled warm-up reported success")
+	}
+	if n := stub.requests.Load(); n != 0 {
+		t.Fatalf("%d embedding requests after cancellation, want 0", n)
+	}
+}
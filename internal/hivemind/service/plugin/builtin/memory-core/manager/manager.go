@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -27,9 +29,15 @@ import (
 // Manager is the core memory index manager.
 // It maintains a SQLite database with indexed memory chunks and provides hybrid search.
 type Manager struct {
-	cfg      *entity.MemoryConfig
-	provider embedding.Provider
-	db       *sql.DB
+	cfg *entity.MemoryConfig
+	db  *sql.DB
+
+	// providers is the embedding fallback chain: the configured provider
+	// followed by cfg.Embedding.Fallbacks. active indexes the one in use.
+	providers      []embedding.Provider
+	active         atomic.Int32
+	failoverMu     sync.Mutex
+	failoverReason string // guarded by failoverMu
 
 	watcher *fsnotify.Watcher
 	closeCh chan struct{}
@@ -38,8 +46,21 @@ type Manager struct {
 	syncing atomic.Bool
 	closed  atomic.Bool
 
+	// syncMu is held by a running sync and by work that must not overlap
+	// one (Rebuild, TryExclusive).
+	syncMu sync.Mutex
+
+	// syncTimer is the pending debounced background sync, if any.
+	syncTimerMu sync.Mutex
+	syncTimer   *time.Timer
+
 	ftsAvailable bool
-	vecAvailable bool
+	ftsTriggers  bool // FTS rows are maintained by triggers on the chunks table
+
+	// embeddingLRU fronts the SQLite embedding cache; nil when caching is off.
+	embeddingLRU *embeddingLRU
+	cacheStore   embeddingCacheStore
+	vecAvailable atomic.Bool
 
 	mu sync.RWMutex
 }
@@ -138,37 +159,67 @@ func newManager(ctx context.Context, cfg *entity.MemoryConfig) (*Manager, error)
 	prevModel, _ := store.GetMeta(db, store.MetaKeyModel)
 	providerKey := embedding.ProviderKey(providerResult.Provider)
 
-	needsFullReindex := prevProvider != providerResult.Provider.ID() || prevModel != providerResult.Provider.Model()
+	// Indexes created before the normalize key existed hold raw embeddings.
+	prevNormalize, _ := store.GetMeta(db, store.MetaKeyNormalize)
+	normalize := strconv.FormatBool(cfg.Embedding.Normalize)
+	if prevNormalize == "" {
+		prevNormalize = strconv.FormatBool(false)
+	}
+
+	manualFTS := schemaResult.FTSAvailable && !schemaResult.FTSTriggers
+	modelChanged := prevProvider != providerResult.Provider.ID() || prevModel != providerResult.Provider.Model()
+	needsFullReindex := modelChanged || prevNormalize != normalize
 	if needsFullReindex && prevProvider != "" {
-		logger.Info("[Memory] provider/model changed (%s/%s -> %s/%s), performing atomic rebuild...",
-			prevProvider, prevModel, providerResult.Provider.ID(), providerResult.Provider.Model())
+		logger.Info("[Memory] provider/model/normalize changed (%s/%s/%s -> %s/%s/%s), performing atomic rebuild...",
+			prevProvider, prevModel, prevNormalize, providerResult.Provider.ID(), providerResult.Provider.Model(), normalize)
 
 		// Atomic rebuild: wipe all chunks/FTS/vec data and rebuild.
-		// This ensures no stale embeddings from the old model remain.
-		if err := atomicClearIndex(db, schemaResult.FTSAvailable); err != nil {
+		// This ensures no stale embeddings from the old model remain. The
+		// cache holds raw provider embeddings, so it survives a normalize change.
+		if err := atomicClearIndex(db, manualFTS, !modelChanged); err != nil {
+			logger.Warn("[Memory] atomic rebuild cleanup failed: %v", err)
+		}
+	} else if schemaResult.VecRecreated {
+		// Same model, but the vector index was recreated at a new dimension:
+		// reindex so every chunk gets a vector row again. Cached embeddings
+		// are still valid.
+		logger.Info("[Memory] vector index recreated at %d dimensions, reindexing...", vecConfig.Dimensions)
+		if err := atomicClearIndex(db, manualFTS, true); err != nil {
 			logger.Warn("[Memory] atomic rebuild cleanup failed: %v", err)
 		}
+		needsFullReindex = true
 	}
 
 	// Update meta.
 	store.SetMeta(db, store.MetaKeyProvider, providerResult.Provider.ID())
 	store.SetMeta(db, store.MetaKeyModel, providerResult.Provider.Model())
+	store.SetMeta(db, store.MetaKeyNormalize, normalize)
 	_ = providerKey
 
 	m := &Manager{
 		cfg:          cfg,
-		provider:     providerResult.Provider,
+		providers:    append([]embedding.Provider{providerResult.Provider}, providerResult.Fallbacks...),
 		db:           db,
 		closeCh:      make(chan struct{}),
 		ftsAvailable: schemaResult.FTSAvailable,
-		vecAvailable: schemaResult.VecAvailable,
+		ftsTriggers:  schemaResult.FTSTriggers,
+		cacheStore:   sqliteEmbeddingCache{db: db},
+	}
+	if cfg.Cache.Enabled {
+		m.embeddingLRU = newEmbeddingLRU(cfg.Cache.MaxEntries)
 	}
+	m.vecAvailable.Store(schemaResult.VecAvailable)
 
 	// Mark dirty for initial sync if needed.
 	if needsFullReindex {
 		m.dirty.Store(true)
 	}
 
+	// Start periodic WAL checkpoint.
+	if cfg.Store.WALCheckpointIntervalMinutes > 0 {
+		m.startCheckpointer(time.Duration(cfg.Store.WALCheckpointIntervalMinutes) * time.Minute)
+	}
+
 	// Start file watcher.
 	if cfg.Sync.Watch {
 		if err := m.startWatcher(); err != nil {
@@ -176,19 +227,36 @@ func newManager(ctx context.Context, cfg *entity.MemoryConfig) (*Manager, error)
 		}
 	}
 
-	logger.Info("[Memory] manager created (fts=%v, vec=%v, reindex=%v)", m.ftsAvailable, m.vecAvailable, needsFullReindex)
+	if m.manualFTS() {
+		logger.Warn("[Memory] fts triggers unavailable, falling back to manual fts writes")
+	}
+	logger.Info("[Memory] manager created (fts=%v, vec=%v, reindex=%v)", m.ftsAvailable, m.vecAvailable.Load(), needsFullReindex)
 	return m, nil
 }
 
 // Search performs a hybrid search (vector + keyword) on the memory index.
 // Matches OpenClaw's MemoryIndexManager.search().
 func (m *Manager) Search(ctx context.Context, query string, opts ...SearchOption) ([]entity.MemorySearchResult, error) {
+	results, err := m.SearchMulti(ctx, []string{query}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// SearchMulti runs Search for several queries at once and returns their
+// results in query order. The queries are embedded in a single batch call,
+// and the brute-force vector scan loads the chunks once for all of them.
+func (m *Manager) SearchMulti(ctx context.Context, queries []string, opts ...SearchOption) ([][]entity.MemorySearchResult, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	if m.closed.Load() {
 		return nil, fmt.Errorf("manager is closed")
 	}
+	if len(queries) == 0 {
+		return nil, nil
+	}
 
 	cfg := m.cfg.Query
 	for _, opt := range opts {
@@ -198,7 +266,7 @@ func (m *Manager) Search(ctx context.Context, query string, opts ...SearchOption
 	// Sync if dirty and onSearch is enabled.
 	if m.cfg.Sync.OnSearch && m.dirty.Load() {
 		m.mu.RUnlock()
-		_ = m.Sync(ctx, SyncOpts{Reason: "search"})
+		_ = m.SyncPending(ctx, "search")
 		m.mu.RLock()
 	}
 
@@ -209,52 +277,76 @@ func (m *Manager) Search(ctx context.Context, query string, opts ...SearchOption
 
 	sourceFilter := m.cfg.Sources
 
-	// Embed the query.
-	queryVec, err := m.embedQueryWithTimeout(ctx, query)
-	if err != nil {
-		logger.Warn("[Memory] failed to embed query: %v", err)
-	}
+	provider := m.activeProvider()
+
+	// Embed the queries; a query without embedding falls back to keyword search.
+	queryVecs := m.embedQueries(ctx, provider, queries)
 
 	// Vector search — use sqlite-vec KNN if available, otherwise brute-force.
-	var vectorResults []hybrid.VectorResult
-	if len(queryVec) > 0 {
-		if m.vecAvailable {
-			vectorResults, _ = search.SearchVectorVec(search.SearchVectorVecParams{
-				DB:           m.db,
-				QueryVec:     queryVec,
-				Limit:        candidateLimit,
-				SourceFilter: sourceFilter,
-			})
-		} else {
-			vectorResults, _ = search.SearchVector(search.SearchVectorParams{
-				DB:            m.db,
-				ProviderModel: m.provider.Model(),
-				QueryVec:      queryVec,
-				Limit:         candidateLimit,
-				SourceFilter:  sourceFilter,
+	vectorResults := make([][]hybrid.VectorResult, len(queries))
+	if m.vecAvailable.Load() {
+		for i, queryVec := range queryVecs {
+			if len(queryVec) == 0 {
+				continue
+			}
+			vectorResults[i], _ = search.SearchVectorVec(search.SearchVectorVecParams{
+				DB:              m.db,
+				QueryVec:        queryVec,
+				Limit:           candidateLimit,
+				SourceFilter:    sourceFilter,
+				Namespace:       cfg.Namespace,
+				SnippetMaxChars: cfg.SnippetMaxChars,
 			})
 		}
+	} else {
+		if multi, err := search.SearchVectorMulti(search.SearchVectorParams{
+			DB:              m.db,
+			ProviderModel:   provider.Model(),
+			Limit:           candidateLimit,
+			SourceFilter:    sourceFilter,
+			Namespace:       cfg.Namespace,
+			EarlyStopScore:  cfg.VectorEarlyStopScore,
+			SnippetMaxChars: cfg.SnippetMaxChars,
+		}, queryVecs); err == nil {
+			vectorResults = multi
+		}
 	}
 
-	// Keyword search (FTS).
-	var keywordResults []hybrid.KeywordResult
-	if m.ftsAvailable && cfg.Hybrid.Enabled {
-		keywordResults, _ = search.SearchKeyword(search.SearchKeywordParams{
-			DB:            m.db,
-			ProviderModel: m.provider.Model(),
-			Query:         query,
-			Limit:         candidateLimit,
-			SourceFilter:  sourceFilter,
-		})
+	results := make([][]entity.MemorySearchResult, len(queries))
+	for i, query := range queries {
+		// Keyword search (FTS).
+		var keywordResults []hybrid.KeywordResult
+		if m.ftsAvailable && cfg.Hybrid.Enabled {
+			keywordResults, _ = search.SearchKeyword(search.SearchKeywordParams{
+				DB:              m.db,
+				ProviderModel:   provider.Model(),
+				Query:           query,
+				Limit:           candidateLimit,
+				SourceFilter:    sourceFilter,
+				Namespace:       cfg.Namespace,
+				SnippetMaxChars: cfg.SnippetMaxChars,
+			})
+		}
+		results[i] = m.mergeResults(cfg, vectorResults[i], keywordResults)
 	}
+	return results, nil
+}
 
-	// Merge hybrid results.
-	merged := hybrid.MergeResults(vectorResults, keywordResults, cfg.Hybrid.VectorWeight, cfg.Hybrid.TextWeight)
+// mergeResults merges the vector and keyword candidates of one query, then
+// applies the score threshold, result limit and snippet expansion.
+func (m *Manager) mergeResults(cfg entity.QueryConfig, vectorResults []hybrid.VectorResult, keywordResults []hybrid.KeywordResult) []entity.MemorySearchResult {
+	var merged []entity.MemorySearchResult
+	if cfg.Hybrid.Strategy == entity.MergeStrategyRRF {
+		merged = hybrid.MergeResultsRRF(vectorResults, keywordResults, cfg.Hybrid.VectorWeight, cfg.Hybrid.TextWeight, cfg.Hybrid.RRFK)
+	} else {
+		merged = hybrid.MergeResults(vectorResults, keywordResults, cfg.Hybrid.VectorWeight, cfg.Hybrid.TextWeight)
+	}
 
 	// Filter by min score and limit.
+	minScore := relevanceFloor(cfg, merged)
 	var filtered []entity.MemorySearchResult
 	for _, r := range merged {
-		if r.Score >= cfg.MinScore {
+		if r.Score >= minScore {
 			filtered = append(filtered, r)
 		}
 		if len(filtered) >= cfg.MaxResults {
@@ -262,28 +354,96 @@ func (m *Manager) Search(ctx context.Context, query string, opts ...SearchOption
 		}
 	}
 
-	return filtered, nil
+	if cfg.SnippetContextLines > 0 {
+		m.expandSnippets(filtered, cfg.SnippetMaxChars, cfg.SnippetContextLines)
+	}
+	return filtered
+}
+
+// relevanceFloor returns the score below which merged results are dropped:
+// MinScore, or with an adaptive cutoff, the top score times the cutoff ratio.
+// merged is sorted by descending score.
+func relevanceFloor(cfg entity.QueryConfig, merged []entity.MemorySearchResult) float64 {
+	ratio := cfg.AdaptiveCutoff
+	if ratio <= 0 || len(merged) == 0 {
+		return cfg.MinScore
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+	return merged[0].Score * ratio
+}
+
+// expandSnippets replaces the snippets of memory-file results with the matched
+// lines plus contextLines of surrounding source on each side. Only the final
+// top-K results are expanded, so the extra file reads stay cheap. Memory and
+// code results are expanded; session results are left as-is: their chunks are
+// not line-aligned with the transcript.
+func (m *Manager) expandSnippets(results []entity.MemorySearchResult, maxChars, contextLines int) {
+	if maxChars <= 0 {
+		maxChars = search.SnippetMaxChars
+	}
+	for i := range results {
+		r := &results[i]
+		if (r.Source != entity.MemorySourceMemory && r.Source != entity.MemorySourceCode) || r.StartLine <= 0 {
+			continue
+		}
+		from := r.StartLine - contextLines
+		if from < 1 {
+			from = 1
+		}
+		text, err := m.ReadFile(r.Path, from, r.EndLine+contextLines-from+1)
+		if err != nil || text == "" {
+			logger.Debug("[Memory] snippet expansion skipped for %s: %v", r.Path, err)
+			continue
+		}
+		r.Snippet = meminternal.TruncateUTF8Safe(text, maxChars)
+	}
 }
 
 // Sync synchronizes the memory index with the filesystem.
 // Matches OpenClaw's MemoryIndexManager.sync().
+// It is skipped when another sync, a Rebuild or a TryExclusive job is running.
 func (m *Manager) Sync(ctx context.Context, opts SyncOpts) error {
-	// CAS to prevent concurrent syncs.
-	if !m.syncing.CompareAndSwap(false, true) {
+	if !m.syncMu.TryLock() {
 		return nil
 	}
+	defer m.syncMu.Unlock()
+	return m.syncLocked(ctx, opts)
+}
+
+// syncLocked runs a sync; the caller holds syncMu.
+func (m *Manager) syncLocked(ctx context.Context, opts SyncOpts) error {
+	m.syncing.Store(true)
 	defer m.syncing.Store(false)
 
 	logger.Info("[Memory] starting sync (reason=%s)", opts.Reason)
 	start := time.Now()
 
-	if err := m.runSync(ctx, opts); err != nil {
+	// Cleared up front so that writes landing during the sync mark it dirty again.
+	m.dirty.Store(false)
+
+	err := m.runSync(ctx, opts)
+	for errors.Is(err, errEmbeddingFailover) {
+		// The index was cleared for the new provider; re-index everything.
+		// The full re-index covers the dirty mark set by the failover.
+		opts.Force = true
+		m.dirty.Store(false)
+		err = m.runSync(ctx, opts)
+	}
+	if err != nil {
+		if store.IsLockedError(err) {
+			// Transient contention (e.g. a concurrent checkpoint): keep the index
+			// dirty so the next trigger retries, but don't fail the caller.
+			logger.Warn("[Memory] sync deferred, database is locked (reason=%s): %v", opts.Reason, err)
+			m.dirty.Store(true)
+			return nil
+		}
 		logger.Warn("[Memory] sync failed: %v", err)
+		m.dirty.Store(true)
 		return err
 	}
 
-	m.dirty.Store(false)
-
 	elapsed := time.Since(start)
 	fileCount, _ := store.CountFiles(m.db)
 	chunkCount, _ := store.CountChunks(m.db)
@@ -300,8 +460,41 @@ func (m *Manager) runSync(ctx context.Context, opts SyncOpts) error {
 	if err != nil {
 		return fmt.Errorf("list memory files: %w", err)
 	}
+	lockedErr := m.syncSource(ctx, opts, files, entity.MemorySourceMemory)
+	if errors.Is(lockedErr, errEmbeddingFailover) {
+		return lockedErr
+	}
+
+	// Sync source-code files.
+	if m.cfg.HasSource(entity.MemorySourceCode) {
+		codeFiles, err := meminternal.ListCodeFiles(m.cfg.WorkspaceDir, m.cfg.Code)
+		if err != nil {
+			return fmt.Errorf("list code files: %w", err)
+		}
+		codeErr := m.syncSource(ctx, opts, codeFiles, entity.MemorySourceCode)
+		if errors.Is(codeErr, errEmbeddingFailover) {
+			return codeErr
+		}
+		if lockedErr == nil {
+			lockedErr = codeErr
+		}
+	}
+
+	// Prune embedding cache.
+	if m.cfg.Cache.Enabled && m.cfg.Cache.MaxEntries > 0 {
+		store.PruneEmbeddingCache(m.db, m.cfg.Cache.MaxEntries)
+	}
 
+	// A file skipped due to lock contention must be retried on the next sync.
+	return lockedErr
+}
+
+// syncSource indexes the changed files of one source and removes the index
+// entries of files that no longer exist. It returns errEmbeddingFailover, which
+// aborts the sync, or the error of a file skipped due to lock contention.
+func (m *Manager) syncSource(ctx context.Context, opts SyncOpts, files []string, source entity.MemorySource) error {
 	activePaths := make(map[string]struct{})
+	var lockedErr error
 	for _, absPath := range files {
 		entry, err := meminternal.BuildFileEntry(absPath, m.cfg.WorkspaceDir)
 		if err != nil {
@@ -311,31 +504,82 @@ func (m *Manager) runSync(ctx context.Context, opts SyncOpts) error {
 		activePaths[entry.Path] = struct{}{}
 
 		// Check if file is unchanged.
-		existingHash, found, _ := store.GetFileRecord(m.db, entry.Path, entity.MemorySourceMemory)
+		existingHash, found, _ := store.GetFileRecord(m.db, entry.Path, source)
 		if found && existingHash == entry.Hash && !opts.Force {
 			continue
 		}
 
 		// Index this file.
-		if err := m.indexFile(ctx, entry, entity.MemorySourceMemory); err != nil {
+		if err := m.indexFile(ctx, entry, source); err != nil {
+			if errors.Is(err, errEmbeddingFailover) {
+				return err
+			}
+			if store.IsLockedError(err) {
+				lockedErr = fmt.Errorf("index %s: %w", entry.Path, err)
+			}
 			logger.Warn("[Memory] failed to index %s: %v", entry.Path, err)
 		}
 	}
 
 	// Clean stale entries.
-	stalePaths, _ := store.GetStalePaths(m.db, entity.MemorySourceMemory)
+	stalePaths, _ := store.GetStalePaths(m.db, source)
 	for _, stalePath := range stalePaths {
 		if _, ok := activePaths[stalePath]; !ok {
-			store.DeleteFileAndChunks(m.db, stalePath, entity.MemorySourceMemory, m.provider.Model(), m.ftsAvailable)
+			store.DeleteFileAndChunks(m.db, stalePath, source, m.manualFTS())
 		}
 	}
+	return lockedErr
+}
 
-	// Prune embedding cache.
-	if m.cfg.Cache.Enabled && m.cfg.Cache.MaxEntries > 0 {
-		store.PruneEmbeddingCache(m.db, m.cfg.Cache.MaxEntries)
+// Rebuild clears the chunk index (chunks, FTS, vec, file records) and re-indexes
+// every file with Force, without changing the embedding provider/model.
+//
+// The embedding cache is kept: it is keyed by chunk text hash, so chunks whose
+// text is unchanged by the new chunking reuse their cached embeddings and only
+// genuinely new chunk boundaries are sent to the embedding provider.
+//
+// A running sync (or TryExclusive job) is waited for rather than skipped, so
+// the cleared index is always rebuilt.
+func (m *Manager) Rebuild(ctx context.Context, opts RebuildOpts) error {
+	if m.closed.Load() {
+		return fmt.Errorf("manager is closed")
 	}
 
-	return nil
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if opts.Chunking != nil {
+		m.cfg.Chunking = *opts.Chunking
+	}
+
+	reason := opts.Reason
+	if reason == "" {
+		reason = "rebuild"
+	}
+
+	logger.Info("[Memory] rebuilding index (reason=%s, chunk_tokens=%d, overlap=%d)",
+		reason, m.cfg.Chunking.Tokens, m.cfg.Chunking.Overlap)
+
+	if err := atomicClearIndex(m.db, m.manualFTS(), true); err != nil {
+		return fmt.Errorf("clear index: %w", err)
+	}
+
+	m.dirty.Store(true)
+	return m.syncLocked(ctx, SyncOpts{Reason: reason, Force: true})
+}
+
+// TryExclusive runs fn while no sync can start, so fn can rewrite or delete
+// memory files without a sync indexing them halfway. It returns false without
+// calling fn when a sync is running. Writes made by fn are indexed by the
+// next sync, e.g. SyncPending once TryExclusive returns.
+func (m *Manager) TryExclusive(fn func() error) (bool, error) {
+	if !m.syncMu.TryLock() {
+		return false, nil
+	}
+	defer m.syncMu.Unlock()
+	return true, fn()
 }
 
 // indexFile indexes a single memory file: chunk → embed → store.
@@ -345,26 +589,34 @@ func (m *Manager) indexFile(ctx context.Context, entry *entity.MemoryFileEntry,
 		return fmt.Errorf("read file: %w", err)
 	}
 
-	// Chunk the content.
-	chunks := meminternal.ChunkMarkdown(string(content), m.cfg.Chunking)
+	// Chunk the content: source code along declarations, everything else as Markdown.
+	var chunks []entity.MemoryChunk
+	if source == entity.MemorySourceCode {
+		chunks = meminternal.ChunkCode(string(content), entry.Path, m.cfg.Chunking)
+	} else {
+		chunks = meminternal.ChunkMarkdown(string(content), m.cfg.Chunking)
+	}
 	if len(chunks) == 0 {
 		return nil
 	}
 
-	// Prepare texts for batch embedding.
+	// Prepare texts for batch embedding (with heading context, if enabled).
 	texts := make([]string, len(chunks))
 	for i, chunk := range chunks {
-		texts[i] = chunk.Text
+		texts[i] = chunk.EmbeddingText()
 	}
 
+	// Use one provider for the whole file, even if another sync fails over meanwhile.
+	provider := m.activeProvider()
+
 	// Check embedding cache.
-	providerKey := embedding.ProviderKey(m.provider)
+	providerKey := embedding.ProviderKey(provider)
 	hashes := make([]string, len(chunks))
 	for i, chunk := range chunks {
 		hashes[i] = chunk.Hash
 	}
 
-	cachedEmbeddings, _ := store.LoadEmbeddingCache(m.db, m.provider.ID(), m.provider.Model(), providerKey, hashes)
+	cachedEmbeddings := m.loadEmbeddings(provider, providerKey, hashes)
 
 	// Find uncached texts.
 	var uncachedIndices []int
@@ -380,21 +632,27 @@ func (m *Manager) indexFile(ctx context.Context, entry *entity.MemoryFileEntry,
 	var newEmbeddings [][]float32
 	if len(uncachedTexts) > 0 {
 		var embedErr error
-		newEmbeddings, embedErr = m.provider.EmbedBatch(ctx, uncachedTexts)
+		newEmbeddings, embedErr = provider.EmbedBatch(ctx, uncachedTexts)
 		if embedErr != nil {
+			if embedding.IsTransient(embedErr) && m.failover(provider, embedErr) {
+				return fmt.Errorf("embed batch: %w", errEmbeddingFailover)
+			}
 			return fmt.Errorf("embed batch: %w", embedErr)
 		}
 	}
 
 	// Delete old chunks for this file.
-	if m.vecAvailable {
+	if m.vecAvailable.Load() {
 		store.DeleteVecChunksByPath(m.db, entry.Path, string(source))
 	}
-	store.DeleteFileAndChunks(m.db, entry.Path, source, m.provider.Model(), m.ftsAvailable)
+	store.DeleteFileAndChunks(m.db, entry.Path, source, m.manualFTS())
 
 	// Insert new chunks.
+	namespace := meminternal.NamespaceForPath(entry.Path)
 	embeddingIdx := 0
-	for i, chunk := range chunks {
+	var recent [][]float32 // embeddings of recently inserted chunks, for dedup
+	skipped := 0
+	for _, chunk := range chunks {
 		chunkID := uuid.New().String()
 
 		var embeddingVec []float32
@@ -406,37 +664,78 @@ func (m *Manager) indexFile(ctx context.Context, entry *entity.MemoryFileEntry,
 
 			// Cache the new embedding.
 			if m.cfg.Cache.Enabled {
-				embJSON, _ := json.Marshal(embeddingVec)
-				store.UpsertEmbeddingCache(m.db, m.provider.ID(), m.provider.Model(), providerKey, chunk.Hash, string(embJSON), len(embeddingVec))
+				m.storeEmbedding(provider, providerKey, chunk.Hash, embeddingVec)
+			}
+		}
+
+		embeddingVec = m.normalizeEmbedding(embeddingVec)
+
+		if m.isDuplicateChunk(embeddingVec, recent) {
+			skipped++
+			continue
+		}
+		if len(embeddingVec) > 0 && m.cfg.Indexing.DedupThreshold > 0 {
+			recent = append(recent, embeddingVec)
+			if len(recent) > chunkDedupWindow {
+				recent = recent[1:]
 			}
 		}
 
 		embJSON, _ := json.Marshal(embeddingVec)
-		if err := store.InsertChunk(m.db, chunkID, entry.Path, source,
-			chunk.StartLine, chunk.EndLine, chunk.Hash, m.provider.Model(),
-			texts[i], string(embJSON)); err != nil {
+		if err := store.InsertChunk(m.db, chunkID, entry.Path, source, namespace,
+			chunk.StartLine, chunk.EndLine, chunk.Hash, provider.Model(),
+			chunk.Text, string(embJSON)); err != nil {
+			if store.IsLockedError(err) {
+				// Leave the file record stale so the next sync re-indexes this file.
+				return fmt.Errorf("insert chunk: %w", err)
+			}
 			logger.Warn("[Memory] failed to insert chunk: %v", err)
 			continue
 		}
 
-		// Insert into FTS.
-		if m.ftsAvailable {
-			store.InsertFTSChunk(m.db, texts[i], chunkID, entry.Path, source,
-				m.provider.Model(), chunk.StartLine, chunk.EndLine)
+		// Insert into FTS unless the insert trigger already did.
+		if m.manualFTS() {
+			store.InsertFTSChunk(m.db, chunk.Text, chunkID, entry.Path, source,
+				provider.Model(), chunk.StartLine, chunk.EndLine)
 		}
 
 		// Insert into vec0 table.
-		if m.vecAvailable && len(embeddingVec) > 0 {
+		if m.vecAvailable.Load() && len(embeddingVec) > 0 {
 			store.InsertVecChunk(m.db, chunkID, embeddingVec)
 		}
 	}
 
+	if skipped > 0 {
+		logger.Debug("[Memory] skipped %d near-duplicate chunks in %s", skipped, entry.Path)
+	}
+
 	// Update file record.
-	store.UpsertFileRecord(m.db, entry, source)
+	if err := store.UpsertFileRecord(m.db, entry, source); err != nil {
+		return fmt.Errorf("update file record: %w", err)
+	}
 
 	return nil
 }
 
+// chunkDedupWindow bounds how many previously inserted chunks of the same
+// file a new chunk is compared against, keeping dedup linear in file size.
+const chunkDedupWindow = 64
+
+// isDuplicateChunk reports whether vec is near-identical to one of the recent
+// embeddings, per cfg.Indexing.DedupThreshold.
+func (m *Manager) isDuplicateChunk(vec []float32, recent [][]float32) bool {
+	threshold := m.cfg.Indexing.DedupThreshold
+	if threshold <= 0 || len(vec) == 0 {
+		return false
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		if meminternal.CosineSimilarity(vec, recent[i]) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadFile reads a memory file and returns lines within the specified range.
 // Matches OpenClaw's MemoryIndexManager.readFile().
 func (m *Manager) ReadFile(path string, from, lines int) (string, error) {
@@ -452,7 +751,7 @@ func (m *Manager) ReadFile(path string, from, lines int) (string, error) {
 		return "", fmt.Errorf("resolve path: %w", err)
 	}
 	wsResolved, _ := filepath.Abs(m.cfg.WorkspaceDir)
-	if len(resolved) < len(wsResolved) || resolved[:len(wsResolved)] != wsResolved {
+	if !strings.HasPrefix(resolved, wsResolved+string(filepath.Separator)) && resolved != wsResolved {
 		return "", fmt.Errorf("path %q is outside workspace", path)
 	}
 
@@ -500,6 +799,18 @@ func (m *Manager) WriteMemory(ctx context.Context, relPath, content string, appe
 		return fmt.Errorf("manager is closed")
 	}
 
+	// Appends past MaxFileBytes go to the file's next segment.
+	if appendMode && m.cfg.MaxFileBytes > 0 {
+		target, err := m.appendTarget(relPath, len(content))
+		if err != nil {
+			return err
+		}
+		if target != relPath {
+			logger.Debug("[Memory] %s is full, appending to %s", relPath, target)
+		}
+		relPath = target
+	}
+
 	// Security: validate path is within workspace/memory.
 	absPath, err := m.resolveMemoryPath(relPath)
 	if err != nil {
@@ -526,13 +837,164 @@ func (m *Manager) WriteMemory(ctx context.Context, relPath, content string, appe
 		}
 	}
 
-	// Mark dirty and re-sync to index the new content.
-	m.dirty.Store(true)
-	_ = m.Sync(ctx, SyncOpts{Reason: "memory-write"})
+	// Index the new content: coalesce bursts of writes into one background
+	// sync, or sync inline when debouncing is disabled.
+	if debounce := m.cfg.Sync.WriteDebounceMs; debounce > 0 {
+		m.scheduleSync("memory-write", time.Duration(debounce)*time.Millisecond)
+	} else {
+		m.dirty.Store(true)
+		_ = m.Sync(ctx, SyncOpts{Reason: "memory-write"})
+	}
 
 	return nil
 }
 
+// appendTarget returns the file an append of n bytes to relPath goes to:
+// the latest existing segment of relPath (relPath itself, or
+// "<name>.<i>.md"), or the next segment if the append would grow the latest
+// one past MaxFileBytes. An empty file always takes the append.
+func (m *Manager) appendTarget(relPath string, n int) (string, error) {
+	target := relPath
+	for i := 1; ; i++ {
+		next := rotatedMemoryPath(relPath, i)
+		absNext, err := m.resolveMemoryPath(next)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(absNext); err != nil {
+			break
+		}
+		target = next
+	}
+
+	absTarget, err := m.resolveMemoryPath(target)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absTarget)
+	if err != nil || info.Size() == 0 || info.Size()+int64(n) <= m.cfg.MaxFileBytes {
+		return target, nil
+	}
+	return rotatedMemoryPath(relPath, meminternal.MemoryFilePart(target)+1), nil
+}
+
+// rotatedMemoryPath returns segment i of a memory file:
+// "memory/2026-02-13.md", 2 → "memory/2026-02-13.2.md".
+func rotatedMemoryPath(relPath string, i int) string {
+	ext := filepath.Ext(relPath)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(relPath, ext), i, ext)
+}
+
+// scheduleSync marks the index dirty and runs a background sync once no
+// further call has arrived for delay. Each call restarts the delay, so a burst
+// of writes (or watcher events) results in a single sync.
+func (m *Manager) scheduleSync(reason string, delay time.Duration) {
+	m.dirty.Store(true)
+
+	m.syncTimerMu.Lock()
+	defer m.syncTimerMu.Unlock()
+	if m.closed.Load() {
+		return
+	}
+	if m.syncTimer != nil {
+		m.syncTimer.Stop()
+	}
+	m.syncTimer = time.AfterFunc(delay, func() {
+		if m.closed.Load() {
+			return
+		}
+		if !m.syncMu.TryLock() {
+			// Another sync is running and would skip this one; try again later.
+			m.scheduleSync(reason, delay)
+			return
+		}
+		defer m.syncMu.Unlock()
+		_ = m.syncLocked(context.Background(), SyncOpts{Reason: reason})
+	})
+}
+
+// cancelScheduledSync stops the pending background sync, if any.
+func (m *Manager) cancelScheduledSync() {
+	m.syncTimerMu.Lock()
+	defer m.syncTimerMu.Unlock()
+	if m.syncTimer != nil {
+		m.syncTimer.Stop()
+		m.syncTimer = nil
+	}
+}
+
+// SyncPending synchronously indexes writes still waiting for their debounced
+// sync. Callers that need the index to reflect the latest writes immediately
+// (e.g. before a search) use it instead of waiting for the debounce.
+func (m *Manager) SyncPending(ctx context.Context, reason string) error {
+	if !m.dirty.Load() {
+		return nil
+	}
+	m.cancelScheduledSync()
+	return m.Sync(ctx, SyncOpts{Reason: reason})
+}
+
+// Exists reports whether a workspace-relative memory file exists.
+func (m *Manager) Exists(relPath string) bool {
+	absPath, err := m.resolveMemoryPath(relPath)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(absPath)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// IndexedFile is a file in the memory index.
+type IndexedFile struct {
+	Path      string    `json:"path"`
+	Source    string    `json:"source"`
+	Chunks    int       `json:"chunks"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Page sizes of ListFiles.
+const (
+	DefaultListFilesLimit = 100
+	MaxListFilesLimit     = 1000
+)
+
+// ListFiles returns a page of the indexed files visible to namespace: shared
+// files plus those of that agent. An empty namespace lists every file. limit
+// defaults to DefaultListFilesLimit and is capped at MaxListFilesLimit. The
+// second result is the number of visible files in total.
+func (m *Manager) ListFiles(namespace string, offset, limit int) ([]IndexedFile, int, error) {
+	if m.closed.Load() {
+		return nil, 0, fmt.Errorf("manager is closed")
+	}
+	if limit <= 0 {
+		limit = DefaultListFilesLimit
+	}
+	limit = min(limit, MaxListFilesLimit)
+
+	params := store.ListFileStatsParams{Offset: offset, Limit: limit}
+	if namespace != "" {
+		params.HiddenPrefix = meminternal.AgentMemoryPrefix
+		params.VisiblePrefix = meminternal.AgentMemoryPrefix + namespace + "/"
+	}
+
+	m.mu.RLock()
+	stats, total, err := store.ListFileStats(m.db, params)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, 0, fmt.Errorf("list indexed files: %w", err)
+	}
+	files := make([]IndexedFile, 0, len(stats))
+	for _, s := range stats {
+		files = append(files, IndexedFile{
+			Path:      s.Path,
+			Source:    s.Source,
+			Chunks:    s.Chunks,
+			UpdatedAt: time.UnixMilli(s.UpdatedAt),
+		})
+	}
+	return files, total, nil
+}
+
 // DeleteMemory deletes a memory file and its associated index entries.
 // The path must be relative and within the memory directory.
 func (m *Manager) DeleteMemory(relPath string) error {
@@ -551,7 +1013,7 @@ func (m *Manager) DeleteMemory(relPath string) error {
 	}
 
 	// Clean up index.
-	store.DeleteFileAndChunks(m.db, relPath, entity.MemorySourceMemory, m.provider.Model(), m.ftsAvailable)
+	store.DeleteFileAndChunks(m.db, relPath, entity.MemorySourceMemory, m.manualFTS())
 
 	return nil
 }
@@ -588,12 +1050,14 @@ func (m *Manager) Close() error {
 	}
 
 	close(m.closeCh)
+	m.cancelScheduledSync()
 
 	if m.watcher != nil {
 		m.watcher.Close()
 	}
 
 	if m.db != nil {
+		m.checkpoint("close")
 		return m.db.Close()
 	}
 	return nil
@@ -603,28 +1067,42 @@ func (m *Manager) Close() error {
 func (m *Manager) Status() ManagerStatus {
 	fileCount, _ := store.CountFiles(m.db)
 	chunkCount, _ := store.CountChunks(m.db)
+	provider := m.activeProvider()
+
+	m.failoverMu.Lock()
+	reason := m.failoverReason
+	m.failoverMu.Unlock()
+
 	return ManagerStatus{
-		Provider:     m.provider.ID(),
-		Model:        m.provider.Model(),
-		FTSAvailable: m.ftsAvailable,
-		VecAvailable: m.vecAvailable,
-		FileCount:    fileCount,
-		ChunkCount:   chunkCount,
-		Syncing:      m.syncing.Load(),
-		Dirty:        m.dirty.Load(),
+		Provider:       provider.ID(),
+		Model:          provider.Model(),
+		ProviderIndex:  int(m.active.Load()),
+		FailoverReason: reason,
+		FTSAvailable:   m.ftsAvailable,
+		VecAvailable:   m.vecAvailable.Load(),
+		FileCount:      fileCount,
+		ChunkCount:     chunkCount,
+		Syncing:        m.syncing.Load(),
+		Dirty:          m.dirty.Load(),
 	}
 }
 
 // ManagerStatus holds the current state of the memory manager.
 type ManagerStatus struct {
-	Provider     string `json:"provider"`
-	Model        string `json:"model"`
-	FTSAvailable bool   `json:"fts_available"`
-	VecAvailable bool   `json:"vec_available"`
-	FileCount    int    `json:"file_count"`
-	ChunkCount   int    `json:"chunk_count"`
-	Syncing      bool   `json:"syncing"`
-	Dirty        bool   `json:"dirty"`
+	// Provider and Model describe the active embedding provider.
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// ProviderIndex is the position of the active provider in the fallback
+	// chain (0 = configured provider); FailoverReason is the error that
+	// caused the latest switch.
+	ProviderIndex  int    `json:"provider_index"`
+	FailoverReason string `json:"failover_reason,omitempty"`
+	FTSAvailable   bool   `json:"fts_available"`
+	VecAvailable   bool   `json:"vec_available"`
+	FileCount      int    `json:"file_count"`
+	ChunkCount     int    `json:"chunk_count"`
+	Syncing        bool   `json:"syncing"`
+	Dirty          bool   `json:"dirty"`
 }
 
 // --- File Watcher ---
@@ -649,16 +1127,21 @@ func (m *Manager) startWatcher() error {
 		watcher.Add(filepath.Dir(memoryMD))
 	}
 
+	// Watch the top-level code directories (non-recursive, like memory/).
+	if m.cfg.HasSource(entity.MemorySourceCode) {
+		for _, codePath := range meminternal.NormalizeExtraMemoryPaths(m.cfg.WorkspaceDir, m.cfg.Code.Paths) {
+			if info, err := os.Stat(codePath); err == nil && info.IsDir() {
+				watcher.Add(codePath)
+			}
+		}
+	}
+
 	debounceMs := m.cfg.Sync.WatchDebounceMs
 	if debounceMs <= 0 {
 		debounceMs = 1500
 	}
 
 	go func() {
-		timer := time.NewTimer(0)
-		timer.Stop()
-		defer timer.Stop()
-
 		for {
 			select {
 			case event, ok := <-watcher.Events:
@@ -666,11 +1149,10 @@ func (m *Manager) startWatcher() error {
 					return
 				}
 				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) != 0 {
-					timer.Reset(time.Duration(debounceMs) * time.Millisecond)
+					// Shares the debounce timer with WriteMemory, so a write and
+					// the watcher event it causes are indexed by one sync.
+					m.scheduleSync("watcher", time.Duration(debounceMs)*time.Millisecond)
 				}
-			case <-timer.C:
-				m.dirty.Store(true)
-				_ = m.Sync(context.Background(), SyncOpts{Reason: "watcher"})
 			case _, ok := <-watcher.Errors:
 				if !ok {
 					return
@@ -685,11 +1167,164 @@ func (m *Manager) startWatcher() error {
 	return nil
 }
 
+// --- WAL Checkpoint ---
+
+// startCheckpointer runs a WAL checkpoint every interval until the manager is closed.
+func (m *Manager) startCheckpointer(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.checkpoint("interval")
+			case <-m.closeCh:
+				return
+			}
+		}
+	}()
+
+	logger.Info("[Memory] WAL checkpoint scheduled every %s", interval)
+}
+
+// checkpoint truncates the -wal file. Lock contention is not an error:
+// the WAL is left as is and the next checkpoint retries.
+func (m *Manager) checkpoint(reason string) {
+	res, err := store.CheckpointWAL(m.db)
+	switch {
+	case err != nil && store.IsLockedError(err):
+		logger.Debug("[Memory] WAL checkpoint skipped, database is locked (reason=%s)", reason)
+	case err != nil:
+		logger.Warn("[Memory] WAL checkpoint failed (reason=%s): %v", reason, err)
+	case res.Busy:
+		logger.Debug("[Memory] WAL checkpoint incomplete, database busy (reason=%s, frames=%d/%d)",
+			reason, res.CheckpointedFrames, res.LogFrames)
+	default:
+		logger.Debug("[Memory] WAL checkpoint done (reason=%s, frames=%d)", reason, res.CheckpointedFrames)
+	}
+}
+
 // embedQueryWithTimeout embeds a query with a timeout.
-func (m *Manager) embedQueryWithTimeout(ctx context.Context, query string) ([]float32, error) {
+func (m *Manager) embedQueryWithTimeout(ctx context.Context, provider embedding.Provider, query string) ([]float32, error) {
+	if vec, ok := m.cachedQueryEmbedding(provider, query); ok {
+		return m.normalizeEmbedding(vec), nil
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
-	return m.provider.EmbedQuery(ctx, query)
+	vec, err := provider.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	m.cacheQueryEmbedding(provider, query, vec)
+	return m.normalizeEmbedding(vec), nil
+}
+
+// normalizeEmbedding L2-normalizes vec when EmbeddingConfig.Normalize is set.
+// Cached embeddings are stored as returned by the provider and normalized
+// when used, so the cache stays valid if the setting changes.
+func (m *Manager) normalizeEmbedding(vec []float32) []float32 {
+	if !m.cfg.Embedding.Normalize || len(vec) == 0 {
+		return vec
+	}
+	return meminternal.NormalizeL2(vec)
+}
+
+// embedQueries embeds search queries, in one batch call when there are
+// several; cached query embeddings are reused. On failure the vectors of the
+// uncached queries are nil and those queries fall back to keyword search.
+func (m *Manager) embedQueries(ctx context.Context, provider embedding.Provider, queries []string) [][]float32 {
+	if len(queries) == 1 {
+		vec, err := m.embedQueryWithTimeout(ctx, provider, queries[0])
+		if err != nil {
+			logger.Warn("[Memory] failed to embed query: %v", err)
+		}
+		return [][]float32{vec}
+	}
+
+	vecs := make([][]float32, len(queries))
+	var missIdx []int
+	var missQueries []string
+	for i, q := range queries {
+		if vec, ok := m.cachedQueryEmbedding(provider, q); ok {
+			vecs[i] = m.normalizeEmbedding(vec)
+			continue
+		}
+		missIdx = append(missIdx, i)
+		missQueries = append(missQueries, q)
+	}
+	if len(missQueries) == 0 {
+		return vecs
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	embedded, err := provider.EmbedBatch(ctx, missQueries)
+	if err == nil && len(embedded) != len(missQueries) {
+		err = fmt.Errorf("got %d embeddings for %d queries", len(embedded), len(missQueries))
+	}
+	if err != nil {
+		logger.Warn("[Memory] failed to embed %d queries: %v", len(missQueries), err)
+		return vecs
+	}
+	for j, i := range missIdx {
+		m.cacheQueryEmbedding(provider, missQueries[j], embedded[j])
+		vecs[i] = m.normalizeEmbedding(embedded[j])
+	}
+	return vecs
+}
+
+// --- Embedding Failover ---
+
+// errEmbeddingFailover aborts a sync after the embedding provider was switched;
+// Sync then re-indexes everything with the new provider.
+var errEmbeddingFailover = errors.New("embedding provider switched")
+
+// activeProvider returns the embedding provider currently in use.
+func (m *Manager) activeProvider() embedding.Provider {
+	return m.providers[m.active.Load()]
+}
+
+// failover switches from the failing provider to the next one in the chain.
+// Embeddings of different models are not comparable, so the switch clears
+// the index and the embedding cache (the atomic rebuild path) and records the
+// new provider in the index meta. It returns false when there is no provider
+// left to try; if another caller already switched away from failed, it
+// returns true without switching again.
+func (m *Manager) failover(failed embedding.Provider, cause error) bool {
+	m.failoverMu.Lock()
+	defer m.failoverMu.Unlock()
+
+	idx := int(m.active.Load())
+	if m.providers[idx] != failed {
+		return true
+	}
+	if idx+1 >= len(m.providers) {
+		return false
+	}
+	next := m.providers[idx+1]
+
+	logger.Warn("[Memory] embedding provider %s failed (%v), failing over to %s and rebuilding the index",
+		embedding.ProviderKey(failed), cause, embedding.ProviderKey(next))
+
+	if err := atomicClearIndex(m.db, m.manualFTS(), false); err != nil {
+		logger.Warn("[Memory] atomic rebuild cleanup failed: %v", err)
+	}
+	m.embeddingLRU.purge()
+	store.SetMeta(m.db, store.MetaKeyProvider, next.ID())
+	store.SetMeta(m.db, store.MetaKeyModel, next.Model())
+
+	if m.vecAvailable.Load() && embeddingDimensions(next.Model()) != embeddingDimensions(failed.Model()) {
+		// The vec0 table has a fixed dimension; use brute-force search instead.
+		logger.Warn("[Memory] %s has a different embedding dimension, disabling sqlite-vec search", next.Model())
+		m.vecAvailable.Store(false)
+	}
+
+	m.active.Store(int32(idx + 1))
+	m.failoverReason = cause.Error()
+	m.dirty.Store(true)
+	return true
 }
 
 // --- Options ---
@@ -705,9 +1340,39 @@ func WithMaxResults(n int) SearchOption {
 }
 
 // WithMinScore sets the minimum score threshold.
+// It turns off an adaptive cutoff set by configuration.
 func WithMinScore(s float64) SearchOption {
 	return func(cfg *entity.QueryConfig) {
 		cfg.MinScore = s
+		cfg.AdaptiveCutoff = 0
+	}
+}
+
+// WithAdaptiveCutoff drops results scoring below ratio times the top score,
+// instead of applying the absolute MinScore. ratio <= 0 restores MinScore.
+func WithAdaptiveCutoff(ratio float64) SearchOption {
+	return func(cfg *entity.QueryConfig) {
+		cfg.AdaptiveCutoff = ratio
+	}
+}
+
+// WithSnippet sets the snippet length cap and the number of source lines to
+// include before and after each matched chunk. maxChars <= 0 keeps the
+// configured cap (default 700); contextLines <= 0 disables expansion.
+func WithSnippet(maxChars, contextLines int) SearchOption {
+	return func(cfg *entity.QueryConfig) {
+		if maxChars > 0 {
+			cfg.SnippetMaxChars = maxChars
+		}
+		cfg.SnippetContextLines = contextLines
+	}
+}
+
+// WithNamespace restricts a search to shared memories plus those written by
+// the given agent. An empty namespace searches every memory.
+func WithNamespace(ns string) SearchOption {
+	return func(cfg *entity.QueryConfig) {
+		cfg.Namespace = ns
 	}
 }
 
@@ -720,6 +1385,16 @@ type SyncOpts struct {
 	Force bool
 }
 
+// RebuildOpts holds options for a rebuild operation.
+type RebuildOpts struct {
+	// Reason describes what triggered the rebuild.
+	Reason string
+
+	// Chunking optionally replaces the chunking parameters before re-chunking.
+	// nil keeps the current configuration.
+	Chunking *entity.ChunkingConfig
+}
+
 // --- Cache Key ---
 
 // cacheKey generates a stable key for the manager cache.
@@ -755,31 +1430,41 @@ func ClearCache() {
 
 // VecAvailable returns whether the sqlite-vec extension is active.
 func (m *Manager) VecAvailable() bool {
-	return m.vecAvailable
+	return m.vecAvailable.Load()
+}
+
+// manualFTS reports whether FTS rows must be written alongside chunk rows
+// because no sync triggers are installed.
+func (m *Manager) manualFTS() bool {
+	return m.ftsAvailable && !m.ftsTriggers
 }
 
 // atomicClearIndex wipes all chunk data (chunks, FTS, vec, files) so that
 // a full re-sync with the new embedding model can be performed cleanly.
 // This is the "atomic rebuild" approach — clear the old index in-place,
 // then the next Sync() will re-index everything with the new model.
-func atomicClearIndex(db *sql.DB, ftsAvailable bool) error {
-	stmts := []string{
-		`DELETE FROM ` + store.TableChunks,
-		`DELETE FROM ` + store.TableFiles,
-		`DELETE FROM ` + store.TableEmbeddingCache,
-	}
-	if ftsAvailable {
+//
+// manualFTS clears the FTS table too; otherwise the chunks delete trigger
+// does. keepEmbeddingCache preserves cached embeddings, which is only safe
+// when the provider/model is unchanged (e.g. re-chunking via Rebuild).
+func atomicClearIndex(db *sql.DB, manualFTS, keepEmbeddingCache bool) error {
+	var stmts []string
+	if manualFTS {
 		stmts = append(stmts, `DELETE FROM `+store.TableChunksFTS)
 	}
-	// Try to clear vec table (may not exist).
-	stmts = append(stmts, `DELETE FROM `+store.TableChunksVec)
-
+	stmts = append(stmts,
+		`DELETE FROM `+store.TableChunks,
+		`DELETE FROM `+store.TableFiles,
+	)
+	if !keepEmbeddingCache {
+		stmts = append(stmts, `DELETE FROM `+store.TableEmbeddingCache)
+	}
 	for _, stmt := range stmts {
-		if _, err := db.Exec(stmt); err != nil {
-			// Non-fatal: vec table may not exist.
-			continue
-		}
+		db.Exec(stmt)
 	}
+
+	// Try to clear vec table (may not exist).
+	db.Exec(`DELETE FROM ` + store.TableChunksVec)
 	return nil
 }
 
@@ -0,0 +1,676 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal/hybrid"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/store"
+)
+
+// embeddingStub serves the OpenAI embeddings API with a fixed vector per
+// input and counts the requests it answered and the inputs it embedded.
+// While status is set, requests fail with that HTTP status instead.
+type embeddingStub struct {
+	srv      *httptest.Server
+	requests atomic.Int64
+	inputs   atomic.Int64
+	status   atomic.Int32
+	vector   func(input string) []float32
+}
+
+func newEmbeddingStub(t *testing.T) *embeddingStub {
+	t.Helper()
+	s := &embeddingStub{vector: func(string) []float32 { return []float32{1, 0, 0} }}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if code := s.status.Load(); code != 0 {
+			http.Error(w, "stub failure", int(code))
+			return
+		}
+		s.requests.Add(1)
+		s.inputs.Add(int64(len(req.Input)))
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		resp := struct {
+			Data []item `json:"data"`
+		}{}
+		for i, in := range req.Input {
+			resp.Data = append(resp.Data, item{Index: i, Embedding: s.vector(in)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// testConfig is a memory config over a temporary workspace, embedding with
+// stub and syncing writes inline.
+func testConfig(t *testing.T, stub *embeddingStub) *entity.MemoryConfig {
+	t.Helper()
+	cfg := entity.DefaultMemoryConfig()
+	cfg.WorkspaceDir = t.TempDir()
+	cfg.Embedding.Remote = &entity.RemoteEmbeddingConfig{APIKey: "test", BaseURL: stub.srv.URL}
+	cfg.Sync.Watch = false
+	cfg.Sync.WriteDebounceMs = 0
+	return cfg
+}
+
+func newTestManager(t *testing.T, cfg *entity.MemoryConfig) *Manager {
+	t.Helper()
+	m, err := newManager(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// writeWorkspaceFile writes a workspace-relative file.
+func writeWorkspaceFile(t *testing.T, cfg *entity.MemoryConfig, rel, content string) {
+	t.Helper()
+	abs := filepath.Join(cfg.WorkspaceDir, rel)
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chunkCount(t *testing.T, m *Manager) int {
+	t.Helper()
+	n, err := store.CountChunks(m.db)
+	if err != nil {
+		t.Fatalf("count chunks: %v", err)
+	}
+	return n
+}
+
+func TestRebuildReindexes(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+	if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	before := chunkCount(t, m)
+	if before == 0 {
+		t.Fatal("nothing indexed")
+	}
+
+	if err := m.Rebuild(context.Background(), RebuildOpts{Reason: "test"}); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if after := chunkCount(t, m); after != before {
+		t.Fatalf("%d chunks after rebuild, want %d", after, before)
+	}
+	if m.Status().Dirty {
+		t.Fatal("index still dirty after rebuild")
+	}
+}
+
+func TestRebuildWaitsForRunningSync(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+
+	// A job holding off syncs stands in for a sync that is running when
+	// Rebuild is called.
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	go m.TryExclusive(func() error {
+		close(holding)
+		<-release
+		return nil
+	})
+	<-holding
+
+	done := make(chan error, 1)
+	go func() { done <- m.Rebuild(context.Background(), RebuildOpts{Reason: "test"}) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("rebuild returned (%v) while a sync was running", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("rebuild: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("rebuild did not finish after the sync")
+	}
+	if chunkCount(t, m) == 0 {
+		t.Fatal("rebuild left the index empty")
+	}
+}
+
+func TestSyncSkippedDuringRebuildOrExclusive(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+
+	ran, err := m.TryExclusive(func() error {
+		if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+			return err
+		}
+		if ok, _ := m.TryExclusive(func() error { return nil }); ok {
+			t.Error("nested TryExclusive ran")
+		}
+		return nil
+	})
+	if !ran || err != nil {
+		t.Fatalf("TryExclusive = %v, %v", ran, err)
+	}
+	if chunkCount(t, m) != 0 {
+		t.Fatal("sync ran inside an exclusive job")
+	}
+
+	if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if chunkCount(t, m) == 0 {
+		t.Fatal("sync after the job indexed nothing")
+	}
+}
+
+func TestExpandSnippets(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "l1\nl2\nl3\nl4\nl5\nl6\nl7\n")
+	writeWorkspaceFile(t, cfg, "src/main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n")
+	// A sibling directory sharing the workspace's name as a prefix.
+	writeWorkspaceFile(t, cfg, "../"+filepath.Base(cfg.WorkspaceDir)+"-other/x.md", "secret\n")
+
+	tests := []struct {
+		name   string
+		result entity.MemorySearchResult
+		lines  int
+		want   string
+	}{
+		{"memory", entity.MemorySearchResult{Path: "memory/notes.md", Source: entity.MemorySourceMemory, StartLine: 4, EndLine: 4, Snippet: "l4"}, 1, "l3\nl4\nl5"},
+		{"memory at file start", entity.MemorySearchResult{Path: "memory/notes.md", Source: entity.MemorySourceMemory, StartLine: 1, EndLine: 2, Snippet: "l1\nl2"}, 2, "l1\nl2\nl3\nl4"},
+		{"code", entity.MemorySearchResult{Path: "src/main.go", Source: entity.MemorySourceCode, StartLine: 6, EndLine: 6, Snippet: "fmt.Println(1)"}, 1, "func main() {\n\tfmt.Println(1)\n}"},
+		{"session left as-is", entity.MemorySearchResult{Path: "sessions/s1.jsonl", Source: entity.MemorySourceSessions, StartLine: 3, EndLine: 3, Snippet: "hi"}, 2, "hi"},
+		{"outside workspace left as-is", entity.MemorySearchResult{Path: "../" + filepath.Base(cfg.WorkspaceDir) + "-other/x.md", Source: entity.MemorySourceMemory, StartLine: 1, EndLine: 1, Snippet: "x"}, 1, "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []entity.MemorySearchResult{tt.result}
+			m.expandSnippets(results, 0, tt.lines)
+			if got := strings.TrimRight(results[0].Snippet, "\n"); got != tt.want {
+				t.Fatalf("snippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSnippetKeepsConfiguredCap(t *testing.T) {
+	cfg := entity.QueryConfig{SnippetMaxChars: 300}
+	WithSnippet(0, 3)(&cfg)
+	if cfg.SnippetMaxChars != 300 || cfg.SnippetContextLines != 3 {
+		t.Fatalf("config = %+v, want cap 300 and 3 context lines", cfg)
+	}
+	WithSnippet(120, 0)(&cfg)
+	if cfg.SnippetMaxChars != 120 || cfg.SnippetContextLines != 0 {
+		t.Fatalf("config = %+v, want cap 120 and no expansion", cfg)
+	}
+}
+
+func TestMergeResultsAdaptiveCutoff(t *testing.T) {
+	strong := []hybrid.VectorResult{
+		{ID: "a", Path: "a.md", VectorScore: 0.9},
+		{ID: "b", Path: "b.md", VectorScore: 0.6},
+		{ID: "c", Path: "c.md", VectorScore: 0.3},
+	}
+	weak := []hybrid.VectorResult{
+		{ID: "a", Path: "a.md", VectorScore: 0.2},
+		{ID: "b", Path: "b.md", VectorScore: 0.15},
+	}
+	tests := []struct {
+		name     string
+		vector   []hybrid.VectorResult
+		minScore float64
+		cutoff   float64
+		want     []string
+	}{
+		{"absolute min score", strong, 0.8, 0, []string{"a.md"}},
+		{"cutoff replaces min score", strong, 0.8, 0.5, []string{"a.md", "b.md"}},
+		{"loose cutoff", strong, 0.8, 0.3, []string{"a.md", "b.md", "c.md"}},
+		{"cutoff above 1 is clamped", strong, 0, 2, []string{"a.md"}},
+		{"weak results below min score", weak, 0.35, 0, nil},
+		{"weak results kept relative to the top", weak, 0.35, 0.5, []string{"a.md", "b.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := entity.QueryConfig{
+				MaxResults:     10,
+				MinScore:       tt.minScore,
+				AdaptiveCutoff: tt.cutoff,
+				Hybrid:         entity.HybridConfig{VectorWeight: 1, Strategy: entity.MergeStrategyLinear},
+			}
+			var got []string
+			for _, r := range (&Manager{}).mergeResults(cfg, tt.vector, nil) {
+				got = append(got, r.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveCutoffOptions(t *testing.T) {
+	cfg := entity.QueryConfig{MinScore: 0.35}
+	WithAdaptiveCutoff(0.6)(&cfg)
+	if cfg.AdaptiveCutoff != 0.6 || cfg.MinScore != 0.35 {
+		t.Fatalf("config = %+v, want cutoff 0.6 over min score 0.35", cfg)
+	}
+	WithMinScore(0.2)(&cfg)
+	if cfg.AdaptiveCutoff != 0 || cfg.MinScore != 0.2 {
+		t.Fatalf("config = %+v, want an explicit min score to turn the cutoff off", cfg)
+	}
+}
+
+// chunkEmbeddings returns the stored embeddings of all chunks.
+func chunkEmbeddings(t *testing.T, m *Manager) []string {
+	t.Helper()
+	rows, err := m.db.Query(`SELECT embedding FROM ` + store.TableChunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var embeddings []string
+	for rows.Next() {
+		var emb string
+		if err := rows.Scan(&emb); err != nil {
+			t.Fatal(err)
+		}
+		embeddings = append(embeddings, emb)
+	}
+	return embeddings
+}
+
+func TestNormalizeChangeRebuilds(t *testing.T) {
+	ctx := context.Background()
+	stub := newEmbeddingStub(t)
+	stub.vector = func(string) []float32 { return []float32{3, 4, 0} }
+	cfg := testConfig(t, stub)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+
+	m := newTestManager(t, cfg)
+	if err := m.Sync(ctx, SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := chunkEmbeddings(t, m); len(got) == 0 || got[0] != "[3,4,0]" {
+		t.Fatalf("embeddings = %q, want raw [3,4,0]", got)
+	}
+	if v, _ := store.GetMeta(m.db, store.MetaKeyNormalize); v != "false" {
+		t.Fatalf("normalize meta = %q, want false", v)
+	}
+	m.Close()
+	embedded := stub.inputs.Load()
+
+	cfg.Embedding.Normalize = true
+	m = newTestManager(t, cfg)
+	if n := chunkCount(t, m); n != 0 {
+		t.Fatalf("%d chunks kept after enabling normalization, want a rebuild", n)
+	}
+	if v, _ := store.GetMeta(m.db, store.MetaKeyNormalize); v != "true" {
+		t.Fatalf("normalize meta = %q, want true", v)
+	}
+	if err := m.Sync(ctx, SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := chunkEmbeddings(t, m); len(got) == 0 || got[0] != "[0.6,0.8,0]" {
+		t.Fatalf("embeddings = %q, want normalized [0.6,0.8,0]", got)
+	}
+	if stub.inputs.Load() != embedded {
+		t.Fatal("chunks re-embedded, want the raw cached embeddings reused")
+	}
+
+	vec, err := m.embedQueryWithTimeout(ctx, m.activeProvider(), "tea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vec) != 3 || vec[0] != 0.6 || vec[1] != 0.8 {
+		t.Fatalf("query embedding = %v, want normalized", vec)
+	}
+}
+
+func TestNormalizeMissingMetaKeepsIndex(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig(t, newEmbeddingStub(t))
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+
+	m := newTestManager(t, cfg)
+	if err := m.Sync(ctx, SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	before := chunkCount(t, m)
+	// Indexes from before the normalize key hold raw embeddings.
+	if _, err := m.db.Exec(`DELETE FROM `+store.TableMeta+` WHERE key = ?`, store.MetaKeyNormalize); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	m = newTestManager(t, cfg)
+	if after := chunkCount(t, m); after != before || before == 0 {
+		t.Fatalf("%d chunks after reopening, want the %d indexed ones kept", after, before)
+	}
+}
+
+func TestIndexSkipsNearDuplicateChunks(t *testing.T) {
+	content := strings.Join([]string{
+		"Standup notes: the deploy moved to Friday.",
+		"Standup notes: the deploy moved to Friday!",
+		"Quota review with the platform team is due.",
+	}, "\n\n") + "\n"
+	tests := []struct {
+		name       string
+		threshold  float64
+		wantChunks int
+	}{
+		{"disabled", 0, 3},
+		{"near duplicates skipped", 0.97, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newEmbeddingStub(t)
+			stub.vector = func(input string) []float32 {
+				if strings.HasPrefix(input, "Standup") {
+					return []float32{1, 0.01, 0}
+				}
+				return []float32{0, 0, 1}
+			}
+			cfg := testConfig(t, stub)
+			cfg.Chunking = entity.ChunkingConfig{Tokens: 12, Overlap: 0}
+			cfg.Indexing.DedupThreshold = tt.threshold
+			m := newTestManager(t, cfg)
+			writeWorkspaceFile(t, cfg, "memory/2026-01-02.md", content)
+
+			if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+				t.Fatalf("sync: %v", err)
+			}
+			if n := chunkCount(t, m); n != tt.wantChunks {
+				t.Fatalf("%d chunks indexed, want %d", n, tt.wantChunks)
+			}
+		})
+	}
+}
+
+func TestSyncFailsOverToFallbackProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantFailover bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, true},
+		{"server error", http.StatusBadGateway, true},
+		{"bad credentials", http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, fallback := newEmbeddingStub(t), newEmbeddingStub(t)
+			primary.status.Store(int32(tt.status))
+			cfg := testConfig(t, primary)
+			cfg.Embedding.Fallbacks = []entity.EmbeddingProviderConfig{{
+				Provider: "openai",
+				Model:    "text-embedding-3-large",
+				Remote:   &entity.RemoteEmbeddingConfig{APIKey: "test", BaseURL: fallback.srv.URL},
+			}}
+			m := newTestManager(t, cfg)
+			writeWorkspaceFile(t, cfg, "memory/notes.md", "# Notes\n\nThe user prefers tea.\n")
+
+			_ = m.Sync(context.Background(), SyncOpts{Reason: "test"})
+			status := m.Status()
+			if !tt.wantFailover {
+				if status.ProviderIndex != 0 || fallback.inputs.Load() != 0 {
+					t.Fatalf("status = %+v after a %d, want the configured provider kept", status, tt.status)
+				}
+				return
+			}
+			if status.ProviderIndex != 1 || status.Model != "text-embedding-3-large" {
+				t.Fatalf("status = %+v, want the fallback provider active", status)
+			}
+			if !strings.Contains(status.FailoverReason, "stub failure") {
+				t.Fatalf("failover reason = %q, want the primary's error", status.FailoverReason)
+			}
+			if fallback.inputs.Load() == 0 || chunkCount(t, m) == 0 {
+				t.Fatal("index not rebuilt with the fallback provider")
+			}
+			if status.Dirty {
+				t.Fatal("index still dirty after the failover sync")
+			}
+			if model, _ := store.GetMeta(m.db, store.MetaKeyModel); model != "text-embedding-3-large" {
+				t.Fatalf("index meta model = %q, want the fallback's", model)
+			}
+		})
+	}
+}
+
+func TestSearchWithSnippet(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	cfg.Chunking.Tokens = 8
+	cfg.Chunking.Overlap = 0
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/notes.md", "alpha one\nbeta two\ngamma three\ndelta four\nepsilon five\n")
+	if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	plain, err := m.Search(context.Background(), "gamma", WithMinScore(0))
+	if err != nil || len(plain) == 0 {
+		t.Fatalf("search = %v, %v; want results", plain, err)
+	}
+	expanded, err := m.Search(context.Background(), "gamma", WithMinScore(0), WithSnippet(0, 1))
+	if err != nil || len(expanded) != len(plain) {
+		t.Fatalf("expanded search = %v, %v; want %d results", expanded, err, len(plain))
+	}
+	// The stub embeds every chunk alike, so tied results may come back in
+	// any order: match them by start line rather than by rank.
+	plainSnippets := make(map[int]string)
+	for _, r := range plain {
+		plainSnippets[r.StartLine] = r.Snippet
+	}
+	for _, r := range expanded {
+		want, ok := plainSnippets[r.StartLine]
+		if !ok || len(r.Snippet) <= len(want) || !strings.Contains(r.Snippet, strings.TrimSpace(want)) {
+			t.Fatalf("expanded snippet %q does not extend %q", r.Snippet, want)
+		}
+	}
+
+	capped, err := m.Search(context.Background(), "gamma", WithMinScore(0), WithSnippet(5, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range capped {
+		if len(r.Snippet) > 5 {
+			t.Fatalf("snippet %q exceeds the 5-char cap", r.Snippet)
+		}
+	}
+}
+
+func TestSearchMulti(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	stub.vector = func(in string) []float32 {
+		if strings.Contains(in, "tea") {
+			return []float32{1, 0, 0}
+		}
+		return []float32{0, 1, 0}
+	}
+	cfg := testConfig(t, stub)
+	m := newTestManager(t, cfg)
+	writeWorkspaceFile(t, cfg, "memory/tea.md", "The user drinks green tea.\n")
+	writeWorkspaceFile(t, cfg, "memory/coffee.md", "The user avoids coffee.\n")
+	if err := m.Sync(context.Background(), SyncOpts{Reason: "test"}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	queries := []string{"tea", "coffee"}
+	requests, inputs := stub.requests.Load(), stub.inputs.Load()
+	multi, err := m.SearchMulti(context.Background(), queries, WithMinScore(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := stub.requests.Load() - requests; n != 1 {
+		t.Fatalf("%d embedding requests, want the queries in one batch", n)
+	}
+	if n := stub.inputs.Load() - inputs; n != int64(len(queries)) {
+		t.Fatalf("%d inputs embedded, want %d", n, len(queries))
+	}
+	if len(multi) != len(queries) {
+		t.Fatalf("got %d result lists, want %d", len(multi), len(queries))
+	}
+	for i, q := range queries {
+		if len(multi[i]) == 0 || multi[i][0].Path != "memory/"+q+".md" {
+			t.Fatalf("query %q: results = %+v, want memory/%s.md first", q, multi[i], q)
+		}
+		single, err := m.Search(context.Background(), q, WithMinScore(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(single) != len(multi[i]) || single[0].Path != multi[i][0].Path || single[0].Score != multi[i][0].Score {
+			t.Fatalf("query %q: Search = %+v, SearchMulti = %+v", q, single, multi[i])
+		}
+	}
+
+	// Without embeddings, the queries fall back to keyword search.
+	if !m.ftsAvailable {
+		t.Skip("sqlite built without fts5")
+	}
+	stub.status.Store(http.StatusInternalServerError)
+	multi, err = m.SearchMulti(context.Background(), []string{"green", "avoids"}, WithMinScore(0))
+	if err != nil {
+		t.Fatalf("search with failing embeddings: %v", err)
+	}
+	if len(multi) != 2 || len(multi[0]) == 0 || multi[0][0].Path != "memory/tea.md" ||
+		len(multi[1]) == 0 || multi[1][0].Path != "memory/coffee.md" {
+		t.Fatalf("keyword fallback = %+v", multi)
+	}
+}
+
+func TestWriteMemoryDebouncesSync(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	cfg.Sync.WriteDebounceMs = 50
+	m := newTestManager(t, cfg)
+	ctx := context.Background()
+
+	for i := range 3 {
+		if err := m.WriteMemory(ctx, "memory/log.md", fmt.Sprintf("entry %d\n", i), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !m.Status().Dirty || chunkCount(t, m) != 0 {
+		t.Fatal("writes were indexed inline despite the debounce")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for (chunkCount(t, m) == 0 || m.Status().Syncing) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := m.Status(); status.Dirty || status.ChunkCount == 0 {
+		t.Fatalf("status = %+v, want the debounced sync to have indexed the writes", status)
+	}
+	// One sync embedded the file as it was after the last write.
+	if n := stub.inputs.Load(); n != 1 {
+		t.Fatalf("embedded %d inputs, want one sync of the one chunk", n)
+	}
+}
+
+func TestWriteMemoryRotatesFullFiles(t *testing.T) {
+	cfg := testConfig(t, newEmbeddingStub(t))
+	cfg.MaxFileBytes = 20
+	m := newTestManager(t, cfg)
+	ctx := context.Background()
+
+	for _, w := range []struct {
+		path, content string
+	}{
+		{"memory/2026-02-13.md", "entry one\n"},
+		{"memory/2026-02-13.md", "entry two\n"},   // fills the file exactly
+		{"memory/2026-02-13.md", "entry three\n"}, // rotates
+		{"memory/2026-02-13.md", "entry four\n"},  // too big for .1 as well
+		{"memory/big.md", strings.Repeat("x", 30) + "\n"},
+		{"memory/big.md", "small\n"},
+	} {
+		if err := m.WriteMemory(ctx, w.path, w.content, true); err != nil {
+			t.Fatalf("append to %s: %v", w.path, err)
+		}
+	}
+	// Overwrites ignore the limit.
+	if err := m.WriteMemory(ctx, "memory/notes.md", strings.Repeat("y", 40), false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"memory/2026-02-13.md":   "entry one\nentry two\n",
+		"memory/2026-02-13.1.md": "entry three\n",
+		"memory/2026-02-13.2.md": "entry four\n",
+		"memory/big.md":          strings.Repeat("x", 30) + "\n", // an empty file takes any append
+		"memory/big.1.md":        "small\n",
+		"memory/notes.md":        strings.Repeat("y", 40),
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(cfg.WorkspaceDir, rel))
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v; want %q", rel, data, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.WorkspaceDir, "memory/notes.1.md")); err == nil {
+		t.Error("an overwrite was rotated")
+	}
+}
+
+func TestSyncPendingIndexesImmediately(t *testing.T) {
+	stub := newEmbeddingStub(t)
+	cfg := testConfig(t, stub)
+	cfg.Sync.WriteDebounceMs = int(time.Hour / time.Millisecond)
+	m := newTestManager(t, cfg)
+	ctx := context.Background()
+
+	if err := m.SyncPending(ctx, "test"); err != nil || stub.inputs.Load() != 0 {
+		t.Fatalf("SyncPending on a clean index: %v, %d inputs embedded", err, stub.inputs.Load())
+	}
+	if err := m.WriteMemory(ctx, "memory/notes.md", "The user prefers tea.\n", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SyncPending(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Status().Dirty || chunkCount(t, m) == 0 {
+		t.Fatal("SyncPending did not index the pending write")
+	}
+	m.syncTimerMu.Lock()
+	pending := m.syncTimer != nil
+	m.syncTimerMu.Unlock()
+	if pending {
+		t.Fatal("the debounced sync is still scheduled")
+	}
+}
@@ -2,14 +2,18 @@ package memory_core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	agentEntity "github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/entity"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/agents/domain/service/runtime/prompt"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	meminternal "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/internal"
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/manager"
 	"github.com/kiosk404/echoryn/pkg/logger"
 )
@@ -21,10 +25,6 @@ const (
 	// Kind groups this plugin under the "memory" slot.
 	Kind = "memory"
 
-	// minFlushMessages is the minimum number of messages in a session
-	// before memory flush is triggered on agent_end.
-	minFlushMessages = 4
-
 	// memoryRecallInstruction is the system-level instruction injected
 	// before agent start to guide the agent to use memory tools.
 	memoryRecallInstruction = `## Memory System
@@ -53,8 +53,16 @@ type Args struct {
 // memoryCorePlugin is the runtime instance of the memory-core plugin.
 type memoryCorePlugin struct {
 	cfg                  *entity.MemoryConfig
+	models               plugin.ModelManager // may be nil; used by the extracted flush mode
 	manager              *manager.Manager
-	promptPipelineActive bool // set to true when PromptSections() is a called by the agent
+	promptPipelineActive bool          // set to true when PromptSections() is a called by the agent
+	digestStop           chan struct{} // closed on Stop; nil when the digest job is not running
+
+	// Memory flushes run in the background after agent_end; Stop waits for
+	// them before closing the manager.
+	flushMu      sync.Mutex
+	flushStopped bool
+	flushes      sync.WaitGroup
 }
 
 // Factory is the PluginFactory for memory-core.
@@ -70,8 +78,14 @@ func Factory(args plugin.PluginArgs, handle plugin.Handle) (plugin.Plugin, error
 		return nil, fmt.Errorf("memory-core: 'config' must be *entity.MemoryConfig, got %T", cfgRaw)
 	}
 
+	var models plugin.ModelManager
+	if handle != nil && handle.RuntimeAPI() != nil {
+		models = handle.RuntimeAPI().ModelManager()
+	}
+
 	return &memoryCorePlugin{
-		cfg: memCfg,
+		cfg:    memCfg,
+		models: models,
 	}, nil
 }
 
@@ -89,6 +103,7 @@ func (p *memoryCorePlugin) Init(api plugin.PluginAPI) error {
 		Description: "Search memory files using hybrid vector + keyword search. Returns relevant code/text snippets from indexed memory files.",
 		Parameters: []plugin.ParameterDef{
 			{Name: "query", Type: "string", Description: "The search query text", Required: true},
+			{Name: "context_lines", Type: "number", Description: "Number of surrounding source lines to include before and after each snippet (default: configured value)", Required: false},
 		},
 		Handler: p.handleMemorySearch,
 	})
@@ -105,10 +120,21 @@ func (p *memoryCorePlugin) Init(api plugin.PluginAPI) error {
 		Handler: p.handleMemoryRead,
 	})
 
+	// Register memory_list tool.
+	api.RegisterTool(plugin.ToolDefinition{
+		Name:        "memory_list",
+		Description: "List the indexed memory files with their chunk counts and last-updated times, ordered by path. Use this to see what is in memory before searching.",
+		Parameters: []plugin.ParameterDef{
+			{Name: "offset", Type: "number", Description: "Number of files to skip (default: 0)", Required: false},
+			{Name: "limit", Type: "number", Description: "Maximum number of files to return (default: 100, max: 1000)", Required: false},
+		},
+		Handler: p.handleMemoryList,
+	})
+
 	// Register memory_write tool.
 	api.RegisterTool(plugin.ToolDefinition{
 		Name:        "memory_write",
-		Description: "Write or append content to a memory file. Use this to save important information, decisions, user preferences, and key facts for future reference. Files are Markdown format under the memory/ directory.",
+		Description: "Write or append content to a memory file. Use this to save important information, decisions, user preferences, and key facts for future reference. Files are Markdown format under the memory/ directory and are private to the calling agent.",
 		Parameters: []plugin.ParameterDef{
 			{Name: "path", Type: "string", Description: "Relative file path within workspace (e.g., 'memory/2026-02-13.md'). Must be under the memory/ directory.", Required: true},
 			{Name: "content", Type: "string", Description: "The Markdown content to write", Required: true},
@@ -127,6 +153,17 @@ func (p *memoryCorePlugin) Init(api plugin.PluginAPI) error {
 		Handler: p.handleMemoryDelete,
 	})
 
+	// Register memory_reindex diagnostic tool.
+	api.RegisterTool(plugin.ToolDefinition{
+		Name:        "memory_reindex",
+		Description: "Diagnostic: rebuild the memory index by re-chunking all memory files with the current (or given) chunking parameters. The embedding model is unchanged and cached embeddings are reused.",
+		Parameters: []plugin.ParameterDef{
+			{Name: "tokens", Type: "number", Description: "Max tokens per chunk (default: current config)", Required: false},
+			{Name: "overlap", Type: "number", Description: "Overlap tokens between chunks (default: current config)", Required: false},
+		},
+		Handler: p.handleMemoryReindex,
+	})
+
 	// Register lifecycle hooks.
 	api.RegisterHook(plugin.HookBeforeAgentStart, p.onBeforeAgentStart)
 	api.RegisterHook(plugin.HookAgentEnd, p.onAgentEnd)
@@ -156,6 +193,14 @@ func (p *memoryCorePlugin) Start(ctx context.Context) error {
 		// Non-fatal.
 	}
 
+	if p.cfg.Cache.WarmOnStart {
+		p.warmCache(ctx)
+	}
+
+	if p.cfg.Digest.Enabled {
+		p.startDigest()
+	}
+
 	status := m.Status()
 	logger.Info("[MemoryCore] started (provider=%s, model=%s, files=%d, chunks=%d, fts=%v)",
 		status.Provider, status.Model, status.FileCount, status.ChunkCount, status.FTSAvailable)
@@ -163,8 +208,29 @@ func (p *memoryCorePlugin) Start(ctx context.Context) error {
 	return nil
 }
 
+// warmCache runs the manager warm-up, bounded by CacheConfig.WarmTimeoutSeconds.
+// Failures are logged only: a cold cache is slower, not broken.
+func (p *memoryCorePlugin) warmCache(ctx context.Context) {
+	timeout := time.Duration(p.cfg.Cache.WarmTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := p.manager.Warm(ctx, p.cfg.Cache.WarmQueries); err != nil {
+		logger.Warn("[MemoryCore] cache warm-up incomplete: %v", err)
+	}
+}
+
 // Stop implements plugin.LifecyclePlugin.
+// Pending memory flushes are given until ctx is done to finish.
 func (p *memoryCorePlugin) Stop(ctx context.Context) error {
+	if p.digestStop != nil {
+		close(p.digestStop)
+		p.digestStop = nil
+	}
+	p.waitFlushes(ctx)
 	if p.manager != nil {
 		logger.Info("[MemoryCore] stopping memory-core plugin...")
 		return p.manager.Close()
@@ -172,6 +238,29 @@ func (p *memoryCorePlugin) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Health implements plugin.HealthChecker.
+// The plugin is degraded when the manager failed to start, when the index
+// holds no chunks, or when embeddings fell back from the configured provider.
+func (p *memoryCorePlugin) Health(ctx context.Context) error {
+	if !p.cfg.Enabled {
+		return nil
+	}
+	if p.manager == nil {
+		return fmt.Errorf("memory manager is not initialized")
+	}
+
+	status := p.manager.Status()
+	var errs []error
+	if status.ChunkCount == 0 {
+		errs = append(errs, fmt.Errorf("memory index is empty"))
+	}
+	if status.ProviderIndex > 0 {
+		errs = append(errs, fmt.Errorf("embedding provider fell back to %s/%s: %s",
+			status.Provider, status.Model, status.FailoverReason))
+	}
+	return errors.Join(errs...)
+}
+
 // --- Tool Handlers ---
 
 func (p *memoryCorePlugin) handleMemorySearch(ctx context.Context, params map[string]interface{}) (interface{}, error) {
@@ -184,7 +273,12 @@ func (p *memoryCorePlugin) handleMemorySearch(ctx context.Context, params map[st
 		return nil, fmt.Errorf("parameter 'query' is required and must be a string")
 	}
 
-	results, err := p.manager.Search(ctx, query)
+	// Agents only recall shared memories and their own.
+	opts := []manager.SearchOption{manager.WithNamespace(plugin.AgentIDFromContext(ctx))}
+	if v, ok := params["context_lines"].(float64); ok && v >= 0 {
+		opts = append(opts, manager.WithSnippet(0, int(v)))
+	}
+	results, err := p.manager.Search(ctx, query, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("memory search failed: %w", err)
 	}
@@ -216,17 +310,57 @@ func (p *memoryCorePlugin) handleMemoryRead(ctx context.Context, params map[stri
 		}
 	}
 
-	content, err := p.manager.ReadFile(path, from, lines)
+	// Prefer the agent-scoped copy; fall back to the path as given so shared
+	// files (MEMORY.md, legacy memory/*.md) stay readable.
+	readPath := path
+	if agentID := plugin.AgentIDFromContext(ctx); agentID != "" {
+		scoped, err := meminternal.ScopeMemoryPath(agentID, path)
+		if err != nil {
+			return nil, fmt.Errorf("memory read failed: %w", err)
+		}
+		if p.manager.Exists(scoped) {
+			readPath = scoped
+		}
+	}
+
+	content, err := p.manager.ReadFile(readPath, from, lines)
 	if err != nil {
 		return nil, fmt.Errorf("memory read failed: %w", err)
 	}
 
 	return map[string]interface{}{
-		"path":    path,
+		"path":    readPath,
 		"content": content,
 	}, nil
 }
 
+func (p *memoryCorePlugin) handleMemoryList(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if p.manager == nil {
+		return nil, fmt.Errorf("memory system is not initialized")
+	}
+
+	offset := 0
+	if v, ok := params["offset"].(float64); ok {
+		offset = int(v)
+	}
+	limit := 0
+	if v, ok := params["limit"].(float64); ok {
+		limit = int(v)
+	}
+
+	// Agents only see shared memories and their own.
+	files, total, err := p.manager.ListFiles(plugin.AgentIDFromContext(ctx), offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("memory list failed: %w", err)
+	}
+
+	return map[string]interface{}{
+		"files": files,
+		"count": len(files),
+		"total": total,
+	}, nil
+}
+
 func (p *memoryCorePlugin) handleMemoryWrite(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	if p.manager == nil {
 		return nil, fmt.Errorf("memory system is not initialized")
@@ -250,6 +384,11 @@ func (p *memoryCorePlugin) handleMemoryWrite(ctx context.Context, params map[str
 		}
 	}
 
+	path, err := meminternal.ScopeMemoryPath(plugin.AgentIDFromContext(ctx), path)
+	if err != nil {
+		return nil, fmt.Errorf("memory write failed: %w", err)
+	}
+
 	if err := p.manager.WriteMemory(ctx, path, content, appendMode); err != nil {
 		return nil, fmt.Errorf("memory write failed: %w", err)
 	}
@@ -271,6 +410,11 @@ func (p *memoryCorePlugin) handleMemoryDelete(ctx context.Context, params map[st
 		return nil, fmt.Errorf("parameter 'path' is required and must be a string")
 	}
 
+	path, err := meminternal.ScopeMemoryPath(plugin.AgentIDFromContext(ctx), path)
+	if err != nil {
+		return nil, fmt.Errorf("memory delete failed: %w", err)
+	}
+
 	if err := p.manager.DeleteMemory(path); err != nil {
 		return nil, fmt.Errorf("memory delete failed: %w", err)
 	}
@@ -281,6 +425,48 @@ func (p *memoryCorePlugin) handleMemoryDelete(ctx context.Context, params map[st
 	}, nil
 }
 
+func (p *memoryCorePlugin) handleMemoryReindex(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if p.manager == nil {
+		return nil, fmt.Errorf("memory system is not initialized")
+	}
+
+	opts := manager.RebuildOpts{Reason: "memory-reindex"}
+
+	chunking := p.cfg.Chunking
+	override := false
+	if v, ok := params["tokens"].(float64); ok && v > 0 {
+		chunking.Tokens = int(v)
+		override = true
+	}
+	if v, ok := params["overlap"].(float64); ok && v >= 0 {
+		chunking.Overlap = int(v)
+		override = true
+	}
+	if override {
+		if chunking.Overlap >= chunking.Tokens {
+			return nil, fmt.Errorf("parameter 'overlap' must be smaller than 'tokens'")
+		}
+		opts.Chunking = &chunking
+	}
+
+	before := p.manager.Status()
+	start := time.Now()
+	if err := p.manager.Rebuild(ctx, opts); err != nil {
+		return nil, fmt.Errorf("memory reindex failed: %w", err)
+	}
+	after := p.manager.Status()
+
+	return map[string]interface{}{
+		"status":        "reindexed",
+		"files":         after.FileCount,
+		"chunks_before": before.ChunkCount,
+		"chunks_after":  after.ChunkCount,
+		"chunk_tokens":  chunking.Tokens,
+		"chunk_overlap": chunking.Overlap,
+		"elapsed_ms":    time.Since(start).Milliseconds(),
+	}, nil
+}
+
 // --- Hook Handlers ---
 // onBeforeAgentStart syncs memory before each agent session.
 func (p *memoryCorePlugin) onBeforeAgentStart(ctx context.Context, data interface{}) error {
@@ -290,7 +476,7 @@ func (p *memoryCorePlugin) onBeforeAgentStart(ctx context.Context, data interfac
 
 	// Sync before agent starts to ensure latest memory is available.
 	if err := p.manager.Sync(ctx, manager.SyncOpts{Reason: "before-agent-start"}); err != nil {
-		logger.Warn("[MemoryCore] sync before agent start failed: %v", err)
+		logger.CtxWarn(ctx, "[MemoryCore] sync before agent start failed: %v", err)
 	}
 
 	// When PromptPipeline is active, MemorySection handles the instruction injection.
@@ -298,13 +484,13 @@ func (p *memoryCorePlugin) onBeforeAgentStart(ctx context.Context, data interfac
 	// We detect this by checking if promptPipelineActive is set (set during Init
 	// when the framework has a PromptPipeline).
 	if p.promptPipelineActive {
-		logger.Debug("[MemoryCore] PromptPipeline active, skipping legacy hook injection")
+		logger.CtxDebug(ctx, "[MemoryCore] PromptPipeline active, skipping legacy hook injection")
 		return nil
 	}
 
 	// Legacy path: inject memory recall instruction as a system message.
 	hookData, ok := data.(map[string]interface{})
-	if !ok {
+	if !ok || memoryDisabled(hookData) {
 		return nil
 	}
 
@@ -320,20 +506,22 @@ func (p *memoryCorePlugin) onBeforeAgentStart(ctx context.Context, data interfac
 		injected = append(injected, msg)
 		hookData["injected_messages"] = injected
 
-		logger.Debug("[MemoryCore] injected memory recall instruction (chunks=%d)", status.ChunkCount)
+		logger.CtxDebug(ctx, "[MemoryCore] injected memory recall instruction (chunks=%d)", status.ChunkCount)
 	}
 
 	return nil
 }
 
-// onAgentEnd extracts key information from the conversation and persists it to memory.
+// onAgentEnd extracts key information from the conversation and persists it
+// to memory. The extraction and the write run in the background (see
+// startFlush), so the hook does not delay the end of the run.
 func (p *memoryCorePlugin) onAgentEnd(ctx context.Context, data interface{}) error {
 	if p.manager == nil {
 		return nil
 	}
 
 	hookData, ok := data.(map[string]interface{})
-	if !ok {
+	if !ok || memoryDisabled(hookData) {
 		return nil
 	}
 
@@ -343,8 +531,12 @@ func (p *memoryCorePlugin) onAgentEnd(ctx context.Context, data interface{}) err
 	}
 
 	// Only flush if the conversation is substantial enough.
+	flush := p.cfg.Flush
+	if flush.MinMessages < 0 {
+		return nil
+	}
 	activeMessages := session.ActiveMessages()
-	if len(activeMessages) < minFlushMessages {
+	if len(activeMessages) < flush.MinMessages {
 		return nil
 	}
 
@@ -367,27 +559,24 @@ func (p *memoryCorePlugin) onAgentEnd(ctx context.Context, data interface{}) err
 		return nil
 	}
 
-	// Build a simple summary entry (no LLM call — lightweight approach).
-	// Format: timestamped bullet points of user query + assistant key response.
-	now := time.Now()
-	datePath := fmt.Sprintf("memory/%s.md", now.Format("2006-01-02"))
-
-	// Truncate long messages for the summary.
-	userSnippet := truncate(lastUserMsg, 200)
-	assistantSnippet := truncate(lastAssistantMsg, 400)
-
-	entry := fmt.Sprintf("\n## %s\n\n- **User**: %s\n- **Assistant**: %s\n",
-		now.Format("15:04:05"),
-		userSnippet,
-		assistantSnippet,
-	)
+	// Skip low-value chatter ("thanks" / "you're welcome").
+	if userChars := utf8.RuneCountInString(strings.TrimSpace(lastUserMsg)); userChars < flush.MinUserChars {
+		logger.CtxDebug(ctx, "[MemoryCore] memory flush skipped: user message too short (%d < %d chars)", userChars, flush.MinUserChars)
+		return nil
+	}
+	if assistantChars := utf8.RuneCountInString(strings.TrimSpace(lastAssistantMsg)); assistantChars < flush.MinAssistantChars {
+		logger.CtxDebug(ctx, "[MemoryCore] memory flush skipped: assistant reply too short (%d < %d chars)", assistantChars, flush.MinAssistantChars)
+		return nil
+	}
 
-	if err := p.manager.WriteMemory(ctx, datePath, entry, true); err != nil {
-		logger.Warn("[MemoryCore] memory flush failed: %v", err)
-		return nil // Non-fatal.
+	now := time.Now()
+	datePath, err := meminternal.ScopeMemoryPath(session.AgentID, fmt.Sprintf("memory/%s.md", now.Format("2006-01-02")))
+	if err != nil {
+		logger.CtxWarn(ctx, "[MemoryCore] memory flush skipped: %v", err)
+		return nil
 	}
 
-	logger.Info("[MemoryCore] memory flush: appended conversation entry to %s", datePath)
+	p.startFlush(ctx, session.AgentID, datePath, now, lastUserMsg, lastAssistantMsg)
 	return nil
 }
 
@@ -421,11 +610,18 @@ type MemorySection struct {
 func (s *MemorySection) Name() string  { return "memory" }
 func (s *MemorySection) Priority() int { return 400 }
 
-// Enabled returns true when the memory manager is initialized and has indexed content.
-func (s *MemorySection) Enabled(_ context.Context, _ *prompt.PromptContext) bool {
+// MinPromptMode limits memory instructions to full-mode prompts.
+func (s *MemorySection) MinPromptMode() prompt.PromptMode { return prompt.PromptModeFull }
+
+// Enabled returns true when the memory manager is initialized and has indexed
+// content, and the agent has not disabled memory.
+func (s *MemorySection) Enabled(_ context.Context, pc *prompt.PromptContext) bool {
 	if s.plugin.manager == nil {
 		return false
 	}
+	if pc != nil && pc.Agent != nil && pc.Agent.MemoryDisabled {
+		return false
+	}
 	status := s.plugin.manager.Status()
 	return status.ChunkCount > 0
 }
@@ -461,8 +657,20 @@ func (p *memoryCorePlugin) Manager() *manager.Manager {
 	return p.manager
 }
 
+// Config returns the memory configuration this plugin was created with.
+// Like Manager, it is exposed for diagnostics/status queries only.
+func (p *memoryCorePlugin) Config() *entity.MemoryConfig {
+	return p.cfg
+}
+
 // --- Helpers ---
 
+// memoryDisabled reports whether the hook's agent opted out of memory.
+func memoryDisabled(hookData map[string]interface{}) bool {
+	agent, _ := hookData["agent"].(*agentEntity.Agent)
+	return agent != nil && !agent.MemoryEnabled()
+}
+
 func modeLabel(appendMode bool) string {
 	if appendMode {
 		return "append"
@@ -1,12 +1,14 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/mattn/go-sqlite3"
 )
 
 // GetFileRecord retrieves the hash of a file from the database.
@@ -32,29 +34,31 @@ func UpsertFileRecord(db *sql.DB, entry *entity.MemoryFileEntry, source entity.M
 }
 
 // DeleteFileAndChunks deletes a file and its chunks from the database.
-func DeleteFileAndChunks(db *sql.DB, path string, source entity.MemorySource, model string, ftsAvailable bool) (err error) {
+// manualFTS deletes the matching FTS rows too; pass false when the FTS
+// triggers already handle it.
+func DeleteFileAndChunks(db *sql.DB, path string, source entity.MemorySource, manualFTS bool) (err error) {
 	_, err = db.Exec(`DELETE FROM `+TableFiles+` WHERE path = ? AND source = ?`, path, string(source))
 	_, err = db.Exec(`DELETE FROM `+TableChunksVec+` WHERE id IN (SELECT id FROM `+TableChunks+` WHERE path = ? AND source = ?)`, path, string(source))
 	_, err = db.Exec(`DELETE FROM `+TableChunks+` WHERE path = ? AND source = ?`, path, string(source))
 
-	if ftsAvailable {
-		_, err = db.Exec(
-			`DELETE FROM `+TableChunksFTS+` WHERE path = ? AND source = ? AND model = ?`,
-			path, string(source), model)
+	if manualFTS {
+		_, err = db.Exec(`DELETE FROM `+TableChunksFTS+` WHERE path = ? AND source = ?`, path, string(source))
 	}
 	return err
 }
 
 // InsertChunk inserts a chunk into the database.
-func InsertChunk(db *sql.DB, chunkID, path string, source entity.MemorySource,
+// namespace is the owning agent ID, or "" for chunks shared by all agents.
+func InsertChunk(db *sql.DB, chunkID, path string, source entity.MemorySource, namespace string,
 	startLine, endLine int, hash, model, text, embeddingJSON string) (err error) {
 	_, err = db.Exec(
-		`INSERT INTO `+TableChunks+` (id, path, source, start_line, end_line, hash, model, text, embedding, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		chunkID, path, string(source), startLine, endLine, hash, model, text, embeddingJSON, time.Now().UnixMilli())
+		`INSERT INTO `+TableChunks+` (id, path, source, namespace, start_line, end_line, hash, model, text, embedding, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		chunkID, path, string(source), namespace, startLine, endLine, hash, model, text, embeddingJSON, time.Now().UnixMilli())
 	return err
 }
 
-// InsertFTSChunk inserts a chunk into the FTS table.
+// InsertFTSChunk inserts a chunk into the FTS table. Only needed when the
+// FTS triggers are not installed.
 func InsertFTSChunk(db *sql.DB, text, chunkID, path string, source entity.MemorySource,
 	model string, startLine, endLine int) (err error) {
 	_, err = db.Exec(
@@ -116,6 +120,71 @@ func CountFiles(db *sql.DB) (int, error) {
 	return count, err
 }
 
+// FileStat is an indexed file with the size of its index.
+type FileStat struct {
+	Path      string
+	Source    string
+	Chunks    int
+	UpdatedAt int64 // unix ms of the newest chunk, or the file mtime if it has none
+}
+
+// ListFileStatsParams selects a page of indexed files.
+type ListFileStatsParams struct {
+	// HiddenPrefix excludes files nested below it ("<prefix><dir>/...")
+	// unless they are under VisiblePrefix. Empty keeps every file.
+	HiddenPrefix  string
+	VisiblePrefix string
+
+	Offset int
+	Limit  int // <= 0 means no limit
+}
+
+// ListFileStats returns a page of indexed files with their chunk counts,
+// ordered by path, and the number of files matching params in total.
+func ListFileStats(db *sql.DB, params ListFileStatsParams) ([]FileStat, int, error) {
+	where := ""
+	var args []any
+	if params.HiddenPrefix != "" {
+		where = ` WHERE substr(path, 1, length(?)) != ? OR instr(substr(path, length(?) + 1), '/') = 0`
+		args = append(args, params.HiddenPrefix, params.HiddenPrefix, params.HiddenPrefix)
+		if params.VisiblePrefix != "" {
+			where += ` OR substr(path, 1, length(?)) = ?`
+			args = append(args, params.VisiblePrefix, params.VisiblePrefix)
+		}
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM `+TableFiles+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := db.Query(
+		`SELECT f.path, f.source, COUNT(c.id), COALESCE(MAX(c.updated_at), f.mtime)
+		FROM (SELECT path, source, mtime FROM `+TableFiles+where+` ORDER BY path LIMIT ? OFFSET ?) f
+		LEFT JOIN `+TableChunks+` c ON c.path = f.path AND c.source = f.source
+		GROUP BY f.path, f.source
+		ORDER BY f.path`,
+		append(args, limit, max(params.Offset, 0))...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var stats []FileStat
+	for rows.Next() {
+		var s FileStat
+		if err := rows.Scan(&s.Path, &s.Source, &s.Chunks, &s.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, total, rows.Err()
+}
+
 // LoadEmbeddingCache retrieves cached embeddings for a set of chunk hashes.
 func LoadEmbeddingCache(db *sql.DB, provider, model, providerKey string, hashes []string) (map[string]string, error) {
 	if len(hashes) == 0 {
@@ -136,6 +205,30 @@ func LoadEmbeddingCache(db *sql.DB, provider, model, providerKey string, hashes
 	return result, nil
 }
 
+// LoadRecentEmbeddingCache returns up to limit cached embeddings of a
+// provider/model, most recently updated first, keyed by hash.
+func LoadRecentEmbeddingCache(ctx context.Context, db *sql.DB, provider, model, providerKey string, limit int) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT hash, embedding FROM `+TableEmbeddingCache+` WHERE provider = ? AND model = ? AND provider_key = ?
+			ORDER BY updated_at DESC LIMIT ?`,
+		provider, model, providerKey, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var hash, embedding string
+		if err := rows.Scan(&hash, &embedding); err != nil {
+			return result, err
+		}
+		result[hash] = embedding
+	}
+	return result, rows.Err()
+}
+
 // UpsertEmbeddingCache inserts or updates an embedding cache entry.
 func UpsertEmbeddingCache(db *sql.DB, provider, model, providerKey, hash, embedding string, dims int) error {
 	_, err := db.Exec(
@@ -159,3 +252,33 @@ func PruneEmbeddingCache(db *sql.DB, maxEntries int) error {
 	)
 	return err
 }
+
+// CheckpointResult is the outcome of a WAL checkpoint.
+type CheckpointResult struct {
+	// Busy is true when the checkpoint could not complete because of concurrent readers/writers.
+	Busy bool
+	// LogFrames is the number of frames in the WAL file.
+	LogFrames int
+	// CheckpointedFrames is the number of frames copied back into the database.
+	CheckpointedFrames int
+}
+
+// CheckpointWAL runs PRAGMA wal_checkpoint(TRUNCATE), copying the WAL back into
+// the database and truncating the -wal file to zero bytes on success.
+func CheckpointWAL(db *sql.DB) (CheckpointResult, error) {
+	var busy int
+	var res CheckpointResult
+	err := db.QueryRow(`PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &res.LogFrames, &res.CheckpointedFrames)
+	res.Busy = busy != 0
+	return res, err
+}
+
+// IsLockedError reports whether err is SQLITE_BUSY or SQLITE_LOCKED
+// ("database is locked"), i.e. a transient contention error worth retrying later.
+func IsLockedError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
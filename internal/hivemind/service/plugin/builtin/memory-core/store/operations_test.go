@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestListFileStats(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := EnsureSchema(db, false, nil); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+
+	for _, path := range []string{
+		"MEMORY.md",
+		"memory/a.md",
+		"memory/agents/bot/x.md",
+		"memory/agents/bot_2/y.md",
+		"memory/agents/notes.md",
+		"memory/b.md",
+	} {
+		entry := &entity.MemoryFileEntry{Path: path, Hash: "h", MtimeMs: 1}
+		if err := UpsertFileRecord(db, entry, entity.MemorySourceMemory); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insertTestChunk(t, db, "c1", "memory/a.md", "one")
+	insertTestChunk(t, db, "c2", "memory/a.md", "two")
+
+	tests := []struct {
+		name      string
+		params    ListFileStatsParams
+		wantPaths []string
+		wantTotal int
+	}{
+		{
+			name:      "all",
+			wantPaths: []string{"MEMORY.md", "memory/a.md", "memory/agents/bot/x.md", "memory/agents/bot_2/y.md", "memory/agents/notes.md", "memory/b.md"},
+			wantTotal: 6,
+		},
+		{
+			name:      "page",
+			params:    ListFileStatsParams{Offset: 1, Limit: 2},
+			wantPaths: []string{"memory/a.md", "memory/agents/bot/x.md"},
+			wantTotal: 6,
+		},
+		{
+			name:      "offset past end",
+			params:    ListFileStatsParams{Offset: 10, Limit: 2},
+			wantTotal: 6,
+		},
+		{
+			name:      "namespace",
+			params:    ListFileStatsParams{HiddenPrefix: "memory/agents/", VisiblePrefix: "memory/agents/bot/"},
+			wantPaths: []string{"MEMORY.md", "memory/a.md", "memory/agents/bot/x.md", "memory/agents/notes.md", "memory/b.md"},
+			wantTotal: 5,
+		},
+		{
+			name:      "namespace page",
+			params:    ListFileStatsParams{HiddenPrefix: "memory/agents/", VisiblePrefix: "memory/agents/bot_2/", Offset: 2, Limit: 2},
+			wantPaths: []string{"memory/agents/bot_2/y.md", "memory/agents/notes.md"},
+			wantTotal: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, total, err := ListFileStats(db, tt.params)
+			if err != nil {
+				t.Fatalf("ListFileStats: %v", err)
+			}
+			var paths []string
+			for _, s := range stats {
+				paths = append(paths, s.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Fatalf("paths = %v, want %v", paths, tt.wantPaths)
+			}
+			if total != tt.wantTotal {
+				t.Fatalf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+
+	stats, _, err := ListFileStats(db, ListFileStatsParams{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[1].Path != "memory/a.md" || stats[1].Chunks != 2 || stats[0].Chunks != 0 {
+		t.Fatalf("chunk counts = %+v", stats)
+	}
+}
+
+func TestLoadRecentEmbeddingCache(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := EnsureSchema(db, false, nil); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+
+	for _, row := range []struct {
+		model, hash string
+		updatedAt   int64
+	}{
+		{"m", "old", 1},
+		{"m", "newest", 3},
+		{"m", "newer", 2},
+		{"other", "foreign", 4},
+	} {
+		if _, err := db.Exec(`INSERT INTO `+TableEmbeddingCache+` (provider, model, provider_key, hash, embedding, dims, updated_at)
+			VALUES ('p', ?, 'k', ?, '[1]', 1, ?)`, row.model, row.hash, row.updatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := LoadRecentEmbeddingCache(context.Background(), db, "p", "m", "k", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"newest": "[1]", "newer": "[1]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("recent cache = %v, want %v", got, want)
+	}
+}
+
+func TestCheckpointWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.sqlite")
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := EnsureSchema(db, false, nil); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	text := strings.Repeat("memory chunk text ", 100)
+	for i := range 500 {
+		insertTestChunk(t, db, fmt.Sprintf("c%d", i), "memory/a.md", text)
+	}
+	walSize := func() int64 {
+		t.Helper()
+		fi, err := os.Stat(path + "-wal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	before := walSize()
+	if before == 0 {
+		t.Fatal("WAL is empty after the writes")
+	}
+
+	res, err := CheckpointWAL(db)
+	if err != nil {
+		t.Fatalf("CheckpointWAL: %v", err)
+	}
+	if res.Busy || res.LogFrames != res.CheckpointedFrames {
+		t.Fatalf("checkpoint = %+v, want a complete checkpoint", res)
+	}
+	if after := walSize(); after >= before {
+		t.Fatalf("WAL size %d after checkpoint, want it to shrink from %d", after, before)
+	}
+	if res, _ := CheckpointWAL(db); res.LogFrames != 0 {
+		t.Fatalf("WAL not truncated: %d frames left", res.LogFrames)
+	}
+}
+
+func TestIsLockedError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.sqlite")
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	holder, writer := open(), open()
+	if _, err := EnsureSchema(holder, false, nil); err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	tx, err := holder.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM ` + TableChunks); err != nil {
+		t.Fatal(err)
+	}
+	lockedErr := InsertChunk(writer, "c1", "memory/a.md", entity.MemorySourceMemory, "", 1, 1, "h", "m", "text", "[]")
+	if lockedErr == nil {
+		t.Fatal("insert succeeded while another connection held the write lock")
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"locked database", lockedErr, true},
+		{"wrapped", fmt.Errorf("insert chunk: %w", lockedErr), true},
+		{"locked table", sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{"other sqlite error", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"other error", errors.New("database is locked"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLockedError(tt.err); got != tt.want {
+				t.Fatalf("IsLockedError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
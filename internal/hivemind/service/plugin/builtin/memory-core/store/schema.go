@@ -2,7 +2,12 @@ package store
 
 import (
 	"database/sql"
+	"encoding/binary"
 	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -14,8 +19,9 @@ const (
 	TableChunksVec      = "chunks_vec"
 
 	// Meta keys.
-	MetaKeyProvider = "provider"
-	MetaKeyModel    = "model"
+	MetaKeyProvider  = "provider"
+	MetaKeyModel     = "model"
+	MetaKeyNormalize = "normalize"
 )
 
 // SchemaResult holds the outcome of schema initialization.
@@ -26,11 +32,20 @@ type SchemaResult struct {
 	// FTSError is the error message if FTS5 creation failed.
 	FTSError string
 
+	// FTSTriggers indicates that triggers on the chunks table keep the FTS
+	// table in sync. When false, callers must dual-write FTS rows themselves.
+	FTSTriggers bool
+
 	// VecAvailable indicates whether vector index was successfully created.
 	VecAvailable bool
 
 	// VecError is the error message if vector index creation failed.
 	VecError string
+
+	// VecRecreated indicates that an existing vector index had a different
+	// dimension and was recreated empty. Indexed chunks have no vector rows
+	// until they are reindexed.
+	VecRecreated bool
 }
 
 // EnsureSchema creates all required tables and indexes.
@@ -52,6 +67,7 @@ func EnsureSchema(db *sql.DB, ftsEnabled bool, vecConfig *VecSchemaConfig) (*Sch
 			id TEXT PRIMARY KEY,
 			path TEXT NOT NULL,
 			source TEXT NOT NULL DEFAULT 'memory',
+			namespace TEXT NOT NULL DEFAULT '',
 			start_line INTEGER NOT NULL,
 			end_line INTEGER NOT NULL,
 			hash TEXT NOT NULL,
@@ -84,22 +100,21 @@ func EnsureSchema(db *sql.DB, ftsEnabled bool, vecConfig *VecSchemaConfig) (*Sch
 	// Ensure columns exist (migration support).
 	ensureColumn(db, TableFiles, "source", "TEXT NOT NULL DEFAULT 'memory'")
 	ensureColumn(db, TableChunks, "source", "TEXT NOT NULL DEFAULT 'memory'")
+	if ensureColumn(db, TableChunks, "namespace", "TEXT NOT NULL DEFAULT ''") {
+		// Backfill agent namespaces for chunks indexed before the column existed:
+		// memory/agents/<id>/... → <id>.
+		db.Exec(`UPDATE ` + TableChunks + ` SET namespace = substr(path, 15, instr(substr(path, 15), '/') - 1)
+			WHERE path LIKE 'memory/agents/%/%'`)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_chunks_namespace ON ` + TableChunks + `(namespace)`)
 
 	result := &SchemaResult{}
 	if ftsEnabled {
-		ftsSQL := `CREATE VIRTUAL TABLE IF NOT EXISTS ` + TableChunksFTS + ` USING fts5(
-			text,
-			id UNINDEXED,
-			path UNINDEXED,
-			source UNINDEXED,
-			model UNINDEXED,
-			start_line UNINDEXED,
-			end_line UNINDEXED
-		)`
-		if _, err := db.Exec(ftsSQL); err != nil {
+		if triggers, err := ensureFTS(db); err != nil {
 			result.FTSError = err.Error()
 		} else {
 			result.FTSAvailable = true
+			result.FTSTriggers = triggers
 		}
 	}
 
@@ -108,6 +123,19 @@ func EnsureSchema(db *sql.DB, ftsEnabled bool, vecConfig *VecSchemaConfig) (*Sch
 		if vecConfig.ExtensionPath != "" {
 			_, _ = db.Exec("SELECT load_extension(?)", vecConfig.ExtensionPath)
 		}
+		var existed int
+		db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = ?`, TableChunksVec).Scan(&existed)
+		if existed > 0 {
+			// vec0 tables have a fixed dimension; one created for a previous
+			// model would reject every insert and query.
+			if dims := vecTableDimensions(db); dims > 0 && dims != vecConfig.Dimensions {
+				if _, err := db.Exec(`DROP TABLE ` + TableChunksVec); err != nil {
+					result.VecError = fmt.Sprintf("drop %d-dimension vector index: %v", dims, err)
+					return result, nil
+				}
+				result.VecRecreated = true
+			}
+		}
 		vecSQL := fmt.Sprintf(
 			`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING vec0(chunk_id TEXT PRIMARY KEY,
 			embedding float[%d])`,
@@ -122,6 +150,159 @@ func EnsureSchema(db *sql.DB, ftsEnabled bool, vecConfig *VecSchemaConfig) (*Sch
 	return result, nil
 }
 
+// FTS sync trigger names.
+const (
+	triggerChunksFTSInsert = "chunks_fts_ai"
+	triggerChunksFTSDelete = "chunks_fts_ad"
+	triggerChunksFTSUpdate = "chunks_fts_au"
+)
+
+// ftsColumns are the FTS table columns, named after the chunks columns
+// they index or read.
+const ftsColumns = `text, id, path, source, model, start_line, end_line`
+
+// ensureFTS creates the FTS table and reports whether triggers keep it in
+// sync with the chunks table. When the triggers cannot be created (e.g. older
+// SQLite), it falls back to a self-contained FTS table that callers maintain
+// with dual-writes.
+func ensureFTS(db *sql.DB) (triggers bool, err error) {
+	triggerErr := ensureFTSTriggers(db)
+	if triggerErr == nil {
+		return true, nil
+	}
+	if err := ensureStandaloneFTS(db); err != nil {
+		return false, fmt.Errorf("%v; fallback: %w", triggerErr, err)
+	}
+	return false, nil
+}
+
+// ensureFTSTriggers creates the FTS table as an external-content index of the
+// chunks table, keyed by the chunks rowid, and the triggers that keep it in
+// sync. The chunks table is never VACUUMed, so its implicit rowids stay
+// stable. An FTS table created by an earlier version, which stored its own
+// copy of each chunk, is replaced. When the table or the triggers are new,
+// the index is rebuilt from the chunks table.
+func ensureFTSTriggers(db *sql.DB) (err error) {
+	var ddl string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, TableChunksFTS).Scan(&ddl)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("check fts table: %w", err)
+	}
+	legacy := err == nil && !strings.Contains(ddl, "content=")
+
+	var triggers int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?)`,
+		triggerChunksFTSInsert, triggerChunksFTSDelete, triggerChunksFTSUpdate).Scan(&triggers); err != nil {
+		return fmt.Errorf("check fts triggers: %w", err)
+	}
+	if ddl != "" && !legacy && triggers == 3 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmts := []string{
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSInsert,
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSDelete,
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSUpdate,
+	}
+	if legacy {
+		stmts = append(stmts, `DROP TABLE `+TableChunksFTS)
+	}
+	stmts = append(stmts,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS `+TableChunksFTS+` USING fts5(
+			text,
+			id UNINDEXED,
+			path UNINDEXED,
+			source UNINDEXED,
+			model UNINDEXED,
+			start_line UNINDEXED,
+			end_line UNINDEXED,
+			content='`+TableChunks+`',
+			content_rowid='rowid'
+		)`,
+		`CREATE TRIGGER `+triggerChunksFTSInsert+` AFTER INSERT ON `+TableChunks+` BEGIN
+			INSERT INTO `+TableChunksFTS+` (rowid, `+ftsColumns+`)
+			VALUES (new.rowid, new.text, new.id, new.path, new.source, new.model, new.start_line, new.end_line);
+		END`,
+		`CREATE TRIGGER `+triggerChunksFTSDelete+` AFTER DELETE ON `+TableChunks+` BEGIN
+			INSERT INTO `+TableChunksFTS+` (`+TableChunksFTS+`, rowid, `+ftsColumns+`)
+			VALUES ('delete', old.rowid, old.text, old.id, old.path, old.source, old.model, old.start_line, old.end_line);
+		END`,
+		`CREATE TRIGGER `+triggerChunksFTSUpdate+` AFTER UPDATE ON `+TableChunks+` BEGIN
+			INSERT INTO `+TableChunksFTS+` (`+TableChunksFTS+`, rowid, `+ftsColumns+`)
+			VALUES ('delete', old.rowid, old.text, old.id, old.path, old.source, old.model, old.start_line, old.end_line);
+			INSERT INTO `+TableChunksFTS+` (rowid, `+ftsColumns+`)
+			VALUES (new.rowid, new.text, new.id, new.path, new.source, new.model, new.start_line, new.end_line);
+		END`,
+		`INSERT INTO `+TableChunksFTS+` (`+TableChunksFTS+`) VALUES ('rebuild')`,
+	)
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("create fts index: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ensureStandaloneFTS creates an FTS table that stores its own copy of each
+// chunk and drops any sync triggers, so that the dual-writes are the only
+// writers. A table that was an external-content index is replaced and filled
+// from the chunks table.
+func ensureStandaloneFTS(db *sql.DB) (err error) {
+	var ddl string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, TableChunksFTS).Scan(&ddl)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("check fts table: %w", err)
+	}
+	rebuild := err == sql.ErrNoRows || strings.Contains(ddl, "content=")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmts := []string{
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSInsert,
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSDelete,
+		`DROP TRIGGER IF EXISTS ` + triggerChunksFTSUpdate,
+	}
+	if rebuild {
+		stmts = append(stmts,
+			`DROP TABLE IF EXISTS `+TableChunksFTS,
+			`CREATE VIRTUAL TABLE `+TableChunksFTS+` USING fts5(
+				text,
+				id UNINDEXED,
+				path UNINDEXED,
+				source UNINDEXED,
+				model UNINDEXED,
+				start_line UNINDEXED,
+				end_line UNINDEXED
+			)`,
+			`INSERT INTO `+TableChunksFTS+` (`+ftsColumns+`) SELECT `+ftsColumns+` FROM `+TableChunks,
+		)
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("create fts table: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
 // VecSchemaConfig holds configuration for sqlite-vector index creation.
 type VecSchemaConfig struct {
 	// Enabled indicates whether to create the vector index.
@@ -134,17 +315,32 @@ type VecSchemaConfig struct {
 	ExtensionPath string
 }
 
+// vecDimensionsRe extracts the dimension from a vec0 table definition.
+var vecDimensionsRe = regexp.MustCompile(`float\[(\d+)\]`)
+
+// vecTableDimensions returns the dimension of the existing vec0 table,
+// or 0 if it cannot be determined.
+func vecTableDimensions(db *sql.DB) int {
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = ?`, TableChunksVec).Scan(&ddl); err != nil {
+		return 0
+	}
+	m := vecDimensionsRe.FindStringSubmatch(ddl)
+	if m == nil {
+		return 0
+	}
+	dims, _ := strconv.Atoi(m[1])
+	return dims
+}
+
 // InsertVecChunk inserts a chunk embedding into the vec0 virtual table.
 func InsertVecChunk(db *sql.DB, chunkID string, embedding []float32) error {
 	if len(embedding) == 0 {
 		return nil
 	}
-	// sqlite-vec expects the embedding as a JSON array or binary blob.
-	// Using JSON array format for compatibility.
-	vecJSON := float32SliceToJSON(embedding)
 	_, err := db.Exec(
 		`INSERT OR REPLACE INTO `+TableChunksVec+` (chunk_id, embedding) VALUES (?, ?)`,
-		chunkID, vecJSON,
+		chunkID, float32SliceToBlob(embedding),
 	)
 	return err
 }
@@ -164,11 +360,9 @@ func SearchVec(db *sql.DB, queryVec []float32, limit int) ([]VecSearchResult, er
 		return nil, nil
 	}
 
-	vecJSON := float32SliceToJSON(queryVec)
-
 	rows, err := db.Query(
 		`SELECT chunk_id, distance FROM `+TableChunksVec+` WHERE embedding MATCH ? ORDER BY distance LIMIT ?`,
-		vecJSON, limit,
+		float32SliceToBlob(queryVec), limit,
 	)
 	if err != nil {
 		return nil, err
@@ -192,25 +386,24 @@ type VecSearchResult struct {
 	Distance float64
 }
 
-// float32SliceToJSON converts a float32 slice to a JSON array string.
-func float32SliceToJSON(v []float32) string {
-	buf := make([]byte, 0, len(v)*10+2)
-	buf = append(buf, '[')
+// float32SliceToBlob converts a float32 slice to sqlite-vec's compact binary
+// format: little-endian float32 values. vec0 stores vectors in this layout
+// whatever encoding they were inserted with, so tables populated from JSON
+// arrays need no migration.
+func float32SliceToBlob(v []float32) []byte {
+	buf := make([]byte, len(v)*4)
 	for i, f := range v {
-		if i > 0 {
-			buf = append(buf, ',')
-		}
-		buf = append(buf, fmt.Sprintf("%g", f)...)
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
 	}
-	buf = append(buf, ']')
-	return string(buf)
+	return buf
 }
 
 // ensureColumn adds a column to an existing table if it doesn't already exist.
-func ensureColumn(db *sql.DB, table, column, definition string) {
+// It reports whether the column was added by this call.
+func ensureColumn(db *sql.DB, table, column, definition string) bool {
 	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return
+		return false
 	}
 	defer rows.Close()
 
@@ -224,9 +417,11 @@ func ensureColumn(db *sql.DB, table, column, definition string) {
 			continue
 		}
 		if name == column {
-			return // column already exists
+			return false // column already exists
 		}
 	}
+	rows.Close()
 
-	db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err == nil
 }
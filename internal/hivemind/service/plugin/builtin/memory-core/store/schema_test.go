@@ -0,0 +1,443 @@
+package store
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverNoTriggers is a SQLite driver whose connections refuse CREATE TRIGGER,
+// standing in for a SQLite that cannot create the FTS sync triggers.
+const driverNoTriggers = "sqlite3_no_triggers"
+
+func init() {
+	sql.Register(driverNoTriggers, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.RegisterAuthorizer(func(op int, _, _, _ string) int {
+				if op == sqlite3.SQLITE_CREATE_TRIGGER {
+					return sqlite3.SQLITE_DENY
+				}
+				return sqlite3.SQLITE_OK
+			})
+			return nil
+		},
+	})
+}
+
+// openFTSTestDB opens an in-memory database, skipping the test when the
+// SQLite driver is built without FTS5 (build tag sqlite_fts5).
+func openFTSTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE temp.fts5_probe USING fts5(x)`); err != nil {
+		t.Skipf("sqlite built without fts5: %v", err)
+	}
+	return db
+}
+
+func ensureFTSSchema(t *testing.T, db *sql.DB) {
+	t.Helper()
+	res, err := EnsureSchema(db, true, nil)
+	if err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	if !res.FTSAvailable {
+		t.Fatalf("fts unavailable: %s", res.FTSError)
+	}
+}
+
+func insertTestChunk(t *testing.T, db *sql.DB, id, path, text string) {
+	t.Helper()
+	if err := InsertChunk(db, id, path, entity.MemorySourceMemory, "", 1, 1, "h-"+id, "m", text, "[]"); err != nil {
+		t.Fatalf("insert %s: %v", id, err)
+	}
+}
+
+// ftsMatch returns the ids of the chunks matching query, sorted by id.
+func ftsMatch(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT id FROM `+TableChunksFTS+` WHERE `+TableChunksFTS+` MATCH ? ORDER BY id`, query)
+	if err != nil {
+		t.Fatalf("match %q: %v", query, err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func checkFTSIntegrity(t *testing.T, db *sql.DB) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO ` + TableChunksFTS + `(` + TableChunksFTS + `, rank) VALUES ('integrity-check', 1)`); err != nil {
+		t.Fatalf("fts index out of sync with chunks: %v", err)
+	}
+}
+
+func TestFTSFollowsChunks(t *testing.T) {
+	db := openFTSTestDB(t)
+	ensureFTSSchema(t, db)
+
+	insertTestChunk(t, db, "c1", "memory/a.md", "green tea in the morning")
+	insertTestChunk(t, db, "c2", "memory/a.md", "black coffee at night")
+	insertTestChunk(t, db, "c3", "memory/b.md", "green smoothie")
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c1,c3" {
+		t.Fatalf("match green = %s", got)
+	}
+
+	if _, err := db.Exec(`UPDATE `+TableChunks+` SET text = ? WHERE id = ?`, "oolong tea", "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c3" {
+		t.Fatalf("match green after update = %s", got)
+	}
+	if got := strings.Join(ftsMatch(t, db, "oolong"), ","); got != "c1" {
+		t.Fatalf("match oolong after update = %s", got)
+	}
+
+	if err := DeleteFileAndChunks(db, "memory/a.md", entity.MemorySourceMemory, false); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got := ftsMatch(t, db, "tea OR coffee"); len(got) != 0 {
+		t.Fatalf("deleted chunks still match: %v", got)
+	}
+	checkFTSIntegrity(t, db)
+
+	// A second run leaves the index alone.
+	ensureFTSSchema(t, db)
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c3" {
+		t.Fatalf("match green after re-run = %s", got)
+	}
+	checkFTSIntegrity(t, db)
+}
+
+func TestFTSMigratesLegacyTable(t *testing.T) {
+	db := openFTSTestDB(t)
+	if _, err := EnsureSchema(db, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The previous layout: a self-contained FTS table whose delete trigger
+	// matched rows by the unindexed id column.
+	legacy := []string{
+		`CREATE VIRTUAL TABLE ` + TableChunksFTS + ` USING fts5(text, id UNINDEXED, path UNINDEXED,
+			source UNINDEXED, model UNINDEXED, start_line UNINDEXED, end_line UNINDEXED)`,
+		`CREATE TRIGGER chunks_fts_ai AFTER INSERT ON ` + TableChunks + ` BEGIN
+			INSERT INTO ` + TableChunksFTS + ` (text, id, path, source, model, start_line, end_line)
+			VALUES (new.text, new.id, new.path, new.source, new.model, new.start_line, new.end_line);
+		END`,
+		`CREATE TRIGGER chunks_fts_ad AFTER DELETE ON ` + TableChunks + ` BEGIN
+			DELETE FROM ` + TableChunksFTS + ` WHERE id = old.id;
+		END`,
+	}
+	for _, stmt := range legacy {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insertTestChunk(t, db, "c1", "memory/a.md", "green tea")
+	insertTestChunk(t, db, "c2", "memory/b.md", "green smoothie")
+
+	ensureFTSSchema(t, db)
+
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = ?`, TableChunksFTS).Scan(&ddl); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ddl, "content='chunks'") {
+		t.Fatalf("fts table not replaced: %s", ddl)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c1,c2" {
+		t.Fatalf("existing chunks not reindexed: %s", got)
+	}
+	if err := DeleteFileAndChunks(db, "memory/a.md", entity.MemorySourceMemory, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c2" {
+		t.Fatalf("match after delete = %s", got)
+	}
+	checkFTSIntegrity(t, db)
+}
+
+func TestFTSFallbackWithoutTriggers(t *testing.T) {
+	openFTSTestDB(t) // skips without fts5
+	db, err := sql.Open(driverNoTriggers, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := EnsureSchema(db, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	insertTestChunk(t, db, "c1", "memory/a.md", "green tea")
+
+	res, err := EnsureSchema(db, true, nil)
+	if err != nil {
+		t.Fatalf("EnsureSchema: %v", err)
+	}
+	if !res.FTSAvailable || res.FTSTriggers {
+		t.Fatalf("fts available %v, triggers %v (%s); want the dual-write fallback", res.FTSAvailable, res.FTSTriggers, res.FTSError)
+	}
+	var triggers int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'trigger'`).Scan(&triggers); err != nil || triggers != 0 {
+		t.Fatalf("%d triggers installed (%v), want none", triggers, err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c1" {
+		t.Fatalf("existing chunks not indexed: %s", got)
+	}
+
+	// Without triggers, chunk writes alone do not reach the index.
+	insertTestChunk(t, db, "c2", "memory/b.md", "green smoothie")
+	if got := strings.Join(ftsMatch(t, db, "smoothie"), ","); got != "" {
+		t.Fatalf("chunk indexed without a dual-write: %s", got)
+	}
+	if err := InsertFTSChunk(db, "green smoothie", "c2", "memory/b.md", entity.MemorySourceMemory, "m", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c1,c2" {
+		t.Fatalf("match green = %s", got)
+	}
+
+	if err := DeleteFileAndChunks(db, "memory/a.md", entity.MemorySourceMemory, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c2" {
+		t.Fatalf("match green after delete = %s", got)
+	}
+
+	// A second run keeps the dual-written index.
+	if res, err := EnsureSchema(db, true, nil); err != nil || !res.FTSAvailable || res.FTSTriggers {
+		t.Fatalf("re-run: %+v, %v", res, err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c2" {
+		t.Fatalf("match green after re-run = %s", got)
+	}
+}
+
+func TestFTSFallbackReplacesExternalContentTable(t *testing.T) {
+	openFTSTestDB(t)
+	path := filepath.Join(t.TempDir(), "index.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureFTSSchema(t, db)
+	insertTestChunk(t, db, "c1", "memory/a.md", "green tea")
+	// Drop one trigger so the next run has to create it again.
+	if _, err := db.Exec(`DROP TRIGGER ` + triggerChunksFTSUpdate); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = sql.Open(driverNoTriggers, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	res, err := EnsureSchema(db, true, nil)
+	if err != nil || !res.FTSAvailable || res.FTSTriggers {
+		t.Fatalf("EnsureSchema = %+v, %v; want the dual-write fallback", res, err)
+	}
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = ?`, TableChunksFTS).Scan(&ddl); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ddl, "content=") {
+		t.Fatalf("fts table still reads from chunks: %s", ddl)
+	}
+	var triggers int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'trigger'`).Scan(&triggers); err != nil || triggers != 0 {
+		t.Fatalf("%d triggers left (%v), want none", triggers, err)
+	}
+	if got := strings.Join(ftsMatch(t, db, "green"), ","); got != "c1" {
+		t.Fatalf("existing chunks not indexed: %s", got)
+	}
+}
+
+func TestFloat32SliceToBlob(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []float32
+		want []byte
+	}{
+		{"empty", nil, []byte{}},
+		{"one", []float32{1}, []byte{0x00, 0x00, 0x80, 0x3f}},
+		{"little endian", []float32{0.5, -2}, []byte{0x00, 0x00, 0x00, 0x3f, 0x00, 0x00, 0x00, 0xc0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := float32SliceToBlob(tt.in); !bytes.Equal(got, tt.want) {
+				t.Fatalf("float32SliceToBlob(%v) = %x, want %x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVecTableDimensions(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string // empty = no table
+		want int
+	}{
+		{"no table", "", 0},
+		{"dimension", `CREATE TABLE ` + TableChunksVec + ` (chunk_id TEXT PRIMARY KEY, embedding float[384])`, 384},
+		{"multi-line", "CREATE TABLE " + TableChunksVec + " (chunk_id TEXT PRIMARY KEY,\n\t\t\tembedding float[1536])", 1536},
+		{"no dimension", `CREATE TABLE ` + TableChunksVec + ` (chunk_id TEXT PRIMARY KEY, embedding BLOB)`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.SetMaxOpenConns(1)
+			t.Cleanup(func() { db.Close() })
+			// vec0 needs the sqlite-vec extension; a plain table with the
+			// same definition exercises the DDL parsing.
+			if tt.ddl != "" {
+				if _, err := db.Exec(tt.ddl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := vecTableDimensions(db); got != tt.want {
+				t.Fatalf("vecTableDimensions = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// driverVec is a SQLite driver that loads the sqlite-vec extension named by
+// $SQLITE_VEC_PATH into every connection.
+const driverVec = "sqlite3_vec"
+
+var registerVecDriver sync.Once
+
+// openVecTestDB opens the database at path with sqlite-vec loaded, skipping
+// the test when $SQLITE_VEC_PATH is unset or the extension cannot be loaded.
+func openVecTestDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	ext := os.Getenv("SQLITE_VEC_PATH")
+	if ext == "" {
+		t.Skip("SQLITE_VEC_PATH not set")
+	}
+	registerVecDriver.Do(func() {
+		sql.Register(driverVec, &sqlite3.SQLiteDriver{Extensions: []string{ext}})
+	})
+	db, err := sql.Open(driverVec, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("load sqlite-vec: %v", err)
+	}
+	return db
+}
+
+// vecRows returns the number of rows in the vec0 table.
+func vecRows(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT count(*) FROM ` + TableChunksVec).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestVecTableRecreatedOnDimensionChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.sqlite")
+	db := openVecTestDB(t, path)
+	res, err := EnsureSchema(db, false, &VecSchemaConfig{Enabled: true, Dimensions: 768})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.VecAvailable {
+		db.Close()
+		t.Skipf("vec0 unavailable: %s", res.VecError)
+	}
+	if err := InsertVecChunk(db, "c1", make([]float32, 768)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Reopen with a model of another dimension.
+	db = openVecTestDB(t, path)
+	t.Cleanup(func() { db.Close() })
+	res, err = EnsureSchema(db, false, &VecSchemaConfig{Enabled: true, Dimensions: 1536})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.VecRecreated || !res.VecAvailable {
+		t.Fatalf("recreated %v, available %v (%s); want the index rebuilt", res.VecRecreated, res.VecAvailable, res.VecError)
+	}
+	if got := vecTableDimensions(db); got != 1536 {
+		t.Fatalf("vec table dimension = %d, want 1536", got)
+	}
+	if n := vecRows(t, db); n != 0 {
+		t.Fatalf("%d stale 768-dimension rows kept", n)
+	}
+	if err := InsertVecChunk(db, "c1", make([]float32, 1536)); err != nil {
+		t.Fatalf("insert into the rebuilt index: %v", err)
+	}
+
+	// The same dimension again keeps the index.
+	res, err = EnsureSchema(db, false, &VecSchemaConfig{Enabled: true, Dimensions: 1536})
+	if err != nil || res.VecRecreated || !res.VecAvailable {
+		t.Fatalf("re-run = %+v, %v; want the index kept", res, err)
+	}
+	if n := vecRows(t, db); n != 1 {
+		t.Fatalf("%d rows after re-run, want 1", n)
+	}
+}
+
+func TestVecTableDroppedOnDimensionChange(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	// A plain table stands in for a 768-dimension vec0 table left by an
+	// earlier model; it runs without sqlite-vec.
+	for _, stmt := range []string{
+		`CREATE TABLE ` + TableChunksVec + ` (chunk_id TEXT PRIMARY KEY, embedding float[768])`,
+		`INSERT INTO ` + TableChunksVec + ` VALUES ('c1', x'00')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := EnsureSchema(db, false, &VecSchemaConfig{Enabled: true, Dimensions: 1536})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.VecRecreated {
+		t.Fatal("VecRecreated = false, want the 768-dimension table replaced")
+	}
+	if got := vecTableDimensions(db); got == 768 {
+		t.Fatal("stale 768-dimension table kept")
+	}
+	if res.VecAvailable {
+		if n := vecRows(t, db); n != 0 {
+			t.Fatalf("%d stale rows kept", n)
+		}
+	}
+}
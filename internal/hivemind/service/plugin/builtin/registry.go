@@ -2,6 +2,7 @@ package builtin
 
 import (
 	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin"
+	"github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/delegate"
 	memorycore "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core"
 	memoryentity "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
 	genericoptions "github.com/kiosk404/echoryn/internal/pkg/options"
@@ -12,6 +13,7 @@ import (
 // Each plugin receives its config via PluginArgs["config"], resolved from the unified PluginsOptions
 // The default plugins are:
 // - memory-core: default memory system (SQLite + hybrid search)
+// - delegate: sub-agent delegation tool
 func NewInTreeRegistry(opts *genericoptions.PluginsOptions) *plugin.InTreeRegistry {
 	registry := plugin.NewInTreeRegistry()
 
@@ -23,9 +25,33 @@ func NewInTreeRegistry(opts *genericoptions.PluginsOptions) *plugin.InTreeRegist
 			"config": resolveMemoryCoreConfig(opts),
 		})
 
+	// --- delegate: sub-agent delegation tool
+	registry.Register(
+		delegate.PluginDefinition(),
+		delegate.Factory,
+		plugin.PluginArgs{
+			"config": resolveDelegateConfig(opts),
+		})
+
 	return registry
 }
 
+// resolveDelegateConfig resolves the delegate plugin config from the given options.
+func resolveDelegateConfig(opts *genericoptions.PluginsOptions) delegate.Config {
+	cfg := delegate.Config{MaxDepth: delegate.DefaultMaxDepth}
+	if opts == nil {
+		return cfg
+	}
+	entry, ok := opts.Entries[delegate.PluginName]
+	if !ok || entry.Config == nil {
+		return cfg
+	}
+	if n, ok := intConfig(entry.Config, "max_depth"); ok && n > 0 {
+		cfg.MaxDepth = n
+	}
+	return cfg
+}
+
 // resolveMemoryCoreConfig resolves the memory-core plugin config from the given options.
 func resolveMemoryCoreConfig(opts *genericoptions.PluginsOptions) *memoryentity.MemoryConfig {
 	cfg := memoryentity.DefaultMemoryConfig()
@@ -53,6 +79,65 @@ func resolveMemoryCoreConfig(opts *genericoptions.PluginsOptions) *memoryentity.
 			cfg.Store.Path = s
 		}
 	}
+	if n, ok := intConfig(entry.Config, "wal_checkpoint_interval_minutes"); ok {
+		cfg.Store.WALCheckpointIntervalMinutes = n
+	}
+	if v, ok := entry.Config["flush_mode"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Flush.Mode = memoryentity.FlushMode(s)
+		}
+	}
+	if v, ok := entry.Config["flush_model"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Flush.Model = s
+		}
+	}
+	if f, ok := floatConfig(entry.Config, "flush_dedup_threshold"); ok {
+		cfg.Flush.DedupThreshold = f
+	}
+	if f, ok := floatConfig(entry.Config, "indexing_dedup_threshold"); ok {
+		cfg.Indexing.DedupThreshold = f
+	}
+	if n, ok := intConfig(entry.Config, "flush_min_messages"); ok {
+		cfg.Flush.MinMessages = n
+	}
+	if n, ok := intConfig(entry.Config, "flush_min_user_chars"); ok {
+		cfg.Flush.MinUserChars = n
+	}
+	if n, ok := intConfig(entry.Config, "flush_min_assistant_chars"); ok {
+		cfg.Flush.MinAssistantChars = n
+	}
+	if v, ok := entry.Config["digest_enabled"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Digest.Enabled = b
+		}
+	}
+	if n, ok := intConfig(entry.Config, "digest_interval_minutes"); ok {
+		cfg.Digest.IntervalMinutes = n
+	}
+	if n, ok := intConfig(entry.Config, "digest_min_age_days"); ok {
+		cfg.Digest.MinAgeDays = n
+	}
+	if v, ok := entry.Config["digest_model"]; ok {
+		if s, ok := v.(string); ok {
+			cfg.Digest.Model = s
+		}
+	}
+	if n, ok := intConfig(entry.Config, "max_file_bytes"); ok && n >= 0 {
+		cfg.MaxFileBytes = int64(n)
+	}
+	if n, ok := intConfig(entry.Config, "write_debounce_ms"); ok {
+		cfg.Sync.WriteDebounceMs = n
+	}
+	if n, ok := intConfig(entry.Config, "snippet_max_chars"); ok {
+		cfg.Query.SnippetMaxChars = n
+	}
+	if n, ok := intConfig(entry.Config, "snippet_context_lines"); ok {
+		cfg.Query.SnippetContextLines = n
+	}
+	if f, ok := floatConfig(entry.Config, "adaptive_cutoff"); ok {
+		cfg.Query.AdaptiveCutoff = f
+	}
 	if v, ok := entry.Config["embedding_provider"]; ok {
 		if s, ok := v.(string); ok {
 			cfg.Embedding.Provider = s
@@ -63,6 +148,22 @@ func resolveMemoryCoreConfig(opts *genericoptions.PluginsOptions) *memoryentity.
 			cfg.Embedding.Model = s
 		}
 	}
+	if v, ok := entry.Config["cache_warm_on_start"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Cache.WarmOnStart = b
+		}
+	}
+	if queries := stringListConfig(entry.Config, "cache_warm_queries"); len(queries) > 0 {
+		cfg.Cache.WarmQueries = queries
+	}
+	if n, ok := intConfig(entry.Config, "cache_warm_timeout_seconds"); ok {
+		cfg.Cache.WarmTimeoutSeconds = n
+	}
+	if v, ok := entry.Config["embedding_normalize"]; ok {
+		if b, ok := v.(bool); ok {
+			cfg.Embedding.Normalize = b
+		}
+	}
 	if v, ok := entry.Config["embedding_api_key"]; ok {
 		if s, ok := v.(string); ok {
 			if cfg.Embedding.Remote == nil {
@@ -79,5 +180,75 @@ func resolveMemoryCoreConfig(opts *genericoptions.PluginsOptions) *memoryentity.
 			cfg.Embedding.Remote.BaseURL = s
 		}
 	}
+	if paths := stringListConfig(entry.Config, "code_paths"); len(paths) > 0 {
+		// Configuring code paths enables the "code" source.
+		cfg.Code.Paths = paths
+		cfg.Sources = append(cfg.Sources, memoryentity.MemorySourceCode)
+	}
+	if exts := stringListConfig(entry.Config, "code_extensions"); len(exts) > 0 {
+		cfg.Code.Extensions = exts
+	}
+	if n, ok := intConfig(entry.Config, "code_max_file_bytes"); ok {
+		cfg.Code.MaxFileBytes = int64(n)
+	}
+	if list, ok := entry.Config["embedding_fallbacks"].([]interface{}); ok {
+		for _, item := range list {
+			fb, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			provider, _ := fb["provider"].(string)
+			if provider == "" {
+				continue
+			}
+			model, _ := fb["model"].(string)
+			fbCfg := memoryentity.EmbeddingProviderConfig{Provider: provider, Model: model}
+			apiKey, _ := fb["api_key"].(string)
+			baseURL, _ := fb["base_url"].(string)
+			if apiKey != "" || baseURL != "" {
+				fbCfg.Remote = &memoryentity.RemoteEmbeddingConfig{APIKey: apiKey, BaseURL: baseURL}
+			}
+			cfg.Embedding.Fallbacks = append(cfg.Embedding.Fallbacks, fbCfg)
+		}
+	}
 	return cfg
 }
+
+// intConfig reads an integer option from a plugin config map.
+// YAML decodes integers as int while JSON decodes them as float64; both are accepted.
+func intConfig(config map[string]interface{}, key string) (int, bool) {
+	switch n := config[key].(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// stringListConfig reads a list of non-empty strings from a plugin config map.
+func stringListConfig(config map[string]interface{}, key string) []string {
+	list, ok := config[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	var result []string
+	for _, item := range list {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// floatConfig reads a floating-point option from a plugin config map.
+// Whole numbers may decode as int (YAML); both int and float64 are accepted.
+func floatConfig(config map[string]interface{}, key string) (float64, bool) {
+	switch f := config[key].(type) {
+	case float64:
+		return f, true
+	case int:
+		return float64(f), true
+	}
+	return 0, false
+}
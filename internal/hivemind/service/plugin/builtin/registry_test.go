@@ -0,0 +1,141 @@
+package builtin
+
+import (
+	"slices"
+	"testing"
+
+	memorycore "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core"
+	memoryentity "github.com/kiosk404/echoryn/internal/hivemind/service/plugin/builtin/memory-core/entity"
+	genericoptions "github.com/kiosk404/echoryn/internal/pkg/options"
+)
+
+func TestIntConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"yaml":   3,
+		"json":   float64(4),
+		"string": "5",
+	}
+	tests := []struct {
+		key    string
+		want   int
+		wantOK bool
+	}{
+		{"yaml", 3, true},
+		{"json", 4, true},
+		{"string", 0, false},
+		{"missing", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := intConfig(config, tt.key)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("intConfig(%q) = %d, %v; want %d, %v", tt.key, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveMemoryCoreConfigFlushThresholds(t *testing.T) {
+	defaults := memoryentity.DefaultMemoryConfig()
+	if cfg := resolveMemoryCoreConfig(nil); cfg.Flush != defaults.Flush {
+		t.Fatalf("nil options: flush = %+v, want defaults %+v", cfg.Flush, defaults.Flush)
+	}
+
+	opts := &genericoptions.PluginsOptions{Entries: map[string]genericoptions.PluginEntryConfig{
+		memorycore.PluginName: {Config: map[string]interface{}{
+			"wal_checkpoint_interval_minutes": 15,
+			"flush_min_messages":              float64(6),
+			"flush_min_user_chars":            40,
+			"flush_min_assistant_chars":       "80",
+		}},
+	}}
+	cfg := resolveMemoryCoreConfig(opts)
+	if cfg.Store.WALCheckpointIntervalMinutes != 15 {
+		t.Errorf("WAL checkpoint interval = %d, want 15", cfg.Store.WALCheckpointIntervalMinutes)
+	}
+	if cfg.Flush.MinMessages != 6 || cfg.Flush.MinUserChars != 40 {
+		t.Errorf("flush = %+v, want min messages 6 and min user chars 40", cfg.Flush)
+	}
+	if cfg.Flush.MinAssistantChars != defaults.Flush.MinAssistantChars {
+		t.Errorf("min assistant chars = %d, want the default %d for a non-numeric value",
+			cfg.Flush.MinAssistantChars, defaults.Flush.MinAssistantChars)
+	}
+}
+
+func TestResolveMemoryCoreConfigNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bool
+	}{
+		{"enabled", true, true},
+		{"disabled", false, false},
+		{"not a bool", "true", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &genericoptions.PluginsOptions{Entries: map[string]genericoptions.PluginEntryConfig{
+				memorycore.PluginName: {Config: map[string]interface{}{"embedding_normalize": tt.value}},
+			}}
+			if got := resolveMemoryCoreConfig(opts).Embedding.Normalize; got != tt.want {
+				t.Fatalf("Normalize = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMemoryCoreConfigAdaptiveCutoff(t *testing.T) {
+	if cfg := resolveMemoryCoreConfig(nil); cfg.Query.AdaptiveCutoff != 0 {
+		t.Fatalf("nil options: adaptive cutoff = %v, want 0", cfg.Query.AdaptiveCutoff)
+	}
+	opts := &genericoptions.PluginsOptions{Entries: map[string]genericoptions.PluginEntryConfig{
+		memorycore.PluginName: {Config: map[string]interface{}{"adaptive_cutoff": 0.6}},
+	}}
+	if got := resolveMemoryCoreConfig(opts).Query.AdaptiveCutoff; got != 0.6 {
+		t.Fatalf("adaptive cutoff = %v, want 0.6", got)
+	}
+}
+
+func TestResolveMemoryCoreConfigCacheWarmUp(t *testing.T) {
+	defaults := memoryentity.DefaultMemoryConfig().Cache
+	if cfg := resolveMemoryCoreConfig(nil).Cache; cfg.WarmOnStart || cfg.WarmTimeoutSeconds != defaults.WarmTimeoutSeconds {
+		t.Fatalf("nil options: cache = %+v, want warm-up off with the default timeout", cfg)
+	}
+
+	opts := &genericoptions.PluginsOptions{Entries: map[string]genericoptions.PluginEntryConfig{
+		memorycore.PluginName: {Config: map[string]interface{}{
+			"cache_warm_on_start":        true,
+			"cache_warm_queries":         []interface{}{"deploy day", "", 3, "quotas"},
+			"cache_warm_timeout_seconds": 30,
+		}},
+	}}
+	cfg := resolveMemoryCoreConfig(opts).Cache
+	if !cfg.WarmOnStart || cfg.WarmTimeoutSeconds != 30 {
+		t.Fatalf("cache = %+v, want warm-up on with a 30s timeout", cfg)
+	}
+	if !slices.Equal(cfg.WarmQueries, []string{"deploy day", "quotas"}) {
+		t.Fatalf("warm queries = %q, want the non-empty strings", cfg.WarmQueries)
+	}
+}
+
+func TestResolveMemoryCoreConfigMaxFileBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int64
+	}{
+		{"set", 65536, 65536},
+		{"zero disables", 0, 0},
+		{"negative ignored", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &genericoptions.PluginsOptions{Entries: map[string]genericoptions.PluginEntryConfig{
+				memorycore.PluginName: {Config: map[string]interface{}{"max_file_bytes": tt.value}},
+			}}
+			if got := resolveMemoryCoreConfig(opts).MaxFileBytes; got != tt.want {
+				t.Fatalf("MaxFileBytes = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ type Framework struct {
 	handle         *handleImpl
 	slotConfig     SlotConfig
 	factories      map[string]registeredFactory
+	order          []string // factory IDs in registration order
 	promptPipeLine *prompt.Pipeline
 }
 
@@ -83,6 +84,7 @@ func (f *Framework) RegisterFactory(def Definition, factory PluginFactory, args
 		factory:    factory,
 		args:       args,
 	}
+	f.order = append(f.order, def.ID)
 	return nil
 }
 
@@ -92,7 +94,7 @@ func (f *Framework) RegisterFactory(def Definition, factory PluginFactory, args
 // Init/Register on each plugin.
 //
 // This corresponds to the "plugin loading" phase in OpenClaw:
-// 1. Iterate factories in registration order
+// 1. Sort factories by After/Before constraints, else registration order
 // 2. Resolve slot constraints
 // 3. Instantiate plugin via factory
 // 4. Call InitPlugin.Init() if implemented (register Tool/CLI/Hook/Service)
@@ -100,9 +102,14 @@ func (f *Framework) RegisterFactory(def Definition, factory PluginFactory, args
 func (f *Framework) Init() error {
 	logger.Info("[Plugin] initializing framework with %d plugin factories", len(f.factories))
 
+	entries, err := f.sortFactories()
+	if err != nil {
+		return err
+	}
+
 	activeSlots := make(map[string]string)
 
-	for _, entry := range f.factories {
+	for _, entry := range entries {
 		def := entry.definition
 
 		// Step 1: Slot resolution.
@@ -256,6 +263,25 @@ func (f *Framework) Stop(ctx context.Context) error {
 	return nil
 }
 
+// PluginHealth is the health of one plugin that implements HealthChecker.
+type PluginHealth struct {
+	Name string
+	Err  error // nil when healthy
+}
+
+// Health polls every loaded plugin that implements HealthChecker, in
+// registration order. Plugins without health checks are not listed.
+func (f *Framework) Health(ctx context.Context) []PluginHealth {
+	var results []PluginHealth
+	for _, name := range f.registry.PluginNames() {
+		p, _ := f.registry.GetPlugin(name)
+		if hc, ok := p.(HealthChecker); ok {
+			results = append(results, PluginHealth{Name: name, Err: hc.Health(ctx)})
+		}
+	}
+	return results
+}
+
 // --- Accessors ---
 
 // Registry returns the underlying plugin registry.
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kiosk404/echoryn/pkg/logger"
+)
+
+// sortFactories orders the registered factories so that every plugin comes
+// after the plugins listed in its Definition.After and before those listed in
+// its Definition.Before. Plugins without constraints between them keep their
+// registration order, so the result is deterministic.
+//
+// IDs that name no registered plugin are ignored (the dependency is
+// optional); a dependency cycle is an error.
+func (f *Framework) sortFactories() ([]registeredFactory, error) {
+	// edges[a] lists the plugins that must come after a.
+	edges := make(map[string][]string, len(f.order))
+	inDegree := make(map[string]int, len(f.order))
+	for _, id := range f.order {
+		inDegree[id] = 0
+	}
+	addEdge := func(from, to, owner string) {
+		if _, ok := f.factories[from]; !ok {
+			logger.Debug("[Plugin] plugin %q orders itself relative to unknown plugin %q, ignoring", owner, from)
+			return
+		}
+		if _, ok := f.factories[to]; !ok {
+			logger.Debug("[Plugin] plugin %q orders itself relative to unknown plugin %q, ignoring", owner, to)
+			return
+		}
+		edges[from] = append(edges[from], to)
+		inDegree[to]++
+	}
+	for _, id := range f.order {
+		def := f.factories[id].definition
+		for _, dep := range def.After {
+			addEdge(dep, id, id)
+		}
+		for _, dep := range def.Before {
+			addEdge(id, dep, id)
+		}
+	}
+
+	// Kahn's algorithm, always picking the earliest-registered ready plugin.
+	sorted := make([]registeredFactory, 0, len(f.order))
+	done := make(map[string]bool, len(f.order))
+	for len(sorted) < len(f.order) {
+		next := ""
+		for _, id := range f.order {
+			if !done[id] && inDegree[id] == 0 {
+				next = id
+				break
+			}
+		}
+		if next == "" {
+			var cycle []string
+			for _, id := range f.order {
+				if !done[id] {
+					cycle = append(cycle, id)
+				}
+			}
+			return nil, fmt.Errorf("plugin ordering cycle among %s", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		sorted = append(sorted, f.factories[next])
+		for _, to := range edges[next] {
+			inDegree[to]--
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// namedPlugin is a plugin without capabilities.
+type namedPlugin struct{ name string }
+
+func (p *namedPlugin) Name() string { return p.name }
+
+func TestInitOrdersPlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []Definition
+		want    []string
+		wantErr string
+	}{
+		{"registration order", []Definition{{ID: "a"}, {ID: "b"}, {ID: "c"}}, []string{"a", "b", "c"}, ""},
+		{"after", []Definition{{ID: "memory", After: []string{"provider"}}, {ID: "provider"}}, []string{"provider", "memory"}, ""},
+		{"before", []Definition{{ID: "a"}, {ID: "b"}, {ID: "c", Before: []string{"a"}}}, []string{"b", "c", "a"}, ""},
+		{"unknown dependency ignored", []Definition{{ID: "a", After: []string{"missing"}}, {ID: "b"}}, []string{"a", "b"}, ""},
+		{"cycle", []Definition{{ID: "a", After: []string{"b"}}, {ID: "b", After: []string{"a"}}, {ID: "c"}}, nil, "cycle among a, b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fw := (&Config{}).Complete().New()
+			var inited []string
+			for _, def := range tt.defs {
+				factory := func(PluginArgs, Handle) (Plugin, error) {
+					inited = append(inited, def.ID)
+					return &namedPlugin{name: def.ID}, nil
+				}
+				if err := fw.RegisterFactory(def, factory, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := fw.Init()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Init() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(inited) != 0 {
+					t.Fatalf("plugins %v instantiated despite the cycle", inited)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(inited, tt.want) {
+				t.Fatalf("init order = %v, want %v", inited, tt.want)
+			}
+		})
+	}
+}
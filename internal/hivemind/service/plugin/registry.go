@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/kiosk404/echoryn/pkg/logger"
@@ -200,6 +202,53 @@ func (r *Registry) Len() int {
 	return len(r.plugins)
 }
 
+// ToolsOfKind returns the names of the tools registered by plugins of the
+// given kind (e.g. "memory"), sorted.
+func (r *Registry) ToolsOfKind(kind string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for name, owner := range r.toolOwners {
+		if def, ok := r.definitions[owner]; ok && def.Kind == kind {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnavailableTools returns the names of the tools whose Available func
+// rejects the run bound to ctx, sorted.
+func (r *Registry) UnavailableTools(ctx context.Context) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for name, tool := range r.tools {
+		if tool.Available != nil && !tool.Available(ctx) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VisionTools returns the names of the tools marked RequiresVision, sorted.
+func (r *Registry) VisionTools() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for name, tool := range r.tools {
+		if tool.RequiresVision {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // --- Internal registration ---
 
 // registerPlugin adds a plugin to the registry. Called by Framework.
@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestUnavailableTools(t *testing.T) {
+	r := NewRegistry()
+	r.addTool("p", ToolDefinition{Name: "always"})
+	r.addTool("p", ToolDefinition{Name: "top_level_only", Available: func(ctx context.Context) bool {
+		return DelegationDepthFromContext(ctx) == 0
+	}})
+
+	tests := []struct {
+		name  string
+		depth int
+		want  []string
+	}{
+		{"top-level run", 0, nil},
+		{"delegated run", 1, []string{"top_level_only"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.UnavailableTools(WithDelegationDepth(context.Background(), tt.depth))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("unavailable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
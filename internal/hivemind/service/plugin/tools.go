@@ -16,6 +16,14 @@ type ToolDefinition struct {
 	Parameters []ParameterDef
 	// Handler is the function that is called when the tool is invoked.
 	Handler ToolHandler
+	// RequiresVision marks tools that return or work on images (screenshots,
+	// image analysis). They are not offered to agents with a model lacking
+	// image understanding. The built-in memory and delegate tools are text only.
+	RequiresVision bool
+	// Available, when set, reports whether the tool is offered to the run
+	// bound to ctx (see WithAgentID, WithDelegationDepth). Tools it rejects
+	// are left out of that run. nil offers the tool to every run.
+	Available func(ctx context.Context) bool
 }
 
 // ParameterDef defines a single parameter for a tool.
@@ -46,3 +54,35 @@ type ToolProvider interface {
 	// These are registered into the shared ToolRegistry during framework init.
 	Tools() []ToolDefinition
 }
+
+// agentIDKey is the context key under which the running agent's ID is stored.
+type agentIDKey struct{}
+
+// WithAgentID returns a copy of ctx carrying the ID of the agent on whose
+// behalf tools and hooks are invoked. Plugins use it to scope per-agent state.
+func WithAgentID(ctx context.Context, agentID string) context.Context {
+	return context.WithValue(ctx, agentIDKey{}, agentID)
+}
+
+// AgentIDFromContext returns the agent ID stored by WithAgentID, or "" when
+// the call is not bound to an agent run.
+func AgentIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(agentIDKey{}).(string)
+	return id
+}
+
+// delegationDepthKey is the context key under which the delegation depth is stored.
+type delegationDepthKey struct{}
+
+// WithDelegationDepth returns a copy of ctx recording how many agent
+// delegations led to the current run (0 for a top-level run).
+func WithDelegationDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, delegationDepthKey{}, depth)
+}
+
+// DelegationDepthFromContext returns the depth stored by WithDelegationDepth,
+// or 0 for a top-level run.
+func DelegationDepthFromContext(ctx context.Context) int {
+	depth, _ := ctx.Value(delegationDepthKey{}).(int)
+	return depth
+}
@@ -37,6 +37,16 @@ type LifecyclePlugin interface {
 	Stop(ctx context.Context) error
 }
 
+// HealthChecker is an optional interface for plugins that can report
+// whether they are working as configured. It is polled by GET /readyz.
+type HealthChecker interface {
+	Plugin
+
+	// Health returns nil when the plugin is healthy, or an error describing
+	// why it is degraded.
+	Health(ctx context.Context) error
+}
+
 // PluginFactory is a function that creates a new instance of a plugin.
 // It is called during framework initialization, after all plugins have been registered.
 type PluginFactory func(args PluginArgs, handle Handle) (Plugin, error)
@@ -52,6 +62,12 @@ type Definition struct {
 	Name        string
 	Kind        string
 	Description string
+
+	// After lists plugin IDs that must be initialized before this plugin.
+	// Before lists plugin IDs that must be initialized after it. IDs of
+	// plugins that are not registered are ignored.
+	After  []string
+	Before []string
 }
 
 // Handle is the interface that plugins use to access the framework's runtime API.
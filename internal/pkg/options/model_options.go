@@ -2,6 +2,10 @@ package options
 
 import (
 	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -11,8 +15,48 @@ type ModelOptions struct {
 	DefaultProvider string                     `json:"default-provider" mapstructure:"default-provider"`
 	DefaultModel    string                     `json:"default-model" mapstructure:"default-model"`
 	Providers       map[string]*ProviderConfig `json:"providers" mapstructure:"providers"`
+
+	// ProbeOnStart probes the default model of every provider auto-discovered
+	// from the environment at startup and disables unreachable providers.
+	ProbeOnStart bool `json:"probe-on-start" mapstructure:"probe-on-start"`
+
+	// Aliases maps logical model names (e.g. "fast", "smart") to concrete models.
+	// Agents bound to "alias/<name>" follow the alias when it is re-pointed.
+	Aliases map[string]ModelRef `json:"aliases" mapstructure:"aliases"`
+
+	// Cooldown controls when a failing fallback candidate is skipped for a while.
+	// A candidate is never skipped when every other one is cooling down too.
+	Cooldown CooldownOptions `json:"cooldown" mapstructure:"cooldown"`
+
+	// Debug wraps every built ChatModel with a logger that records the final
+	// messages, params and raw errors sent to the provider SDK (API keys redacted).
+	Debug              bool   `json:"debug" mapstructure:"debug"`
+	DebugLogFile       string `json:"debug-log-file" mapstructure:"debug-log-file"`
+	DebugLogMaxSizeMB  int    `json:"debug-log-max-size-mb" mapstructure:"debug-log-max-size-mb"`
+	DebugLogMaxBackups int    `json:"debug-log-max-backups" mapstructure:"debug-log-max-backups"`
+}
+
+// ModelRef names a concrete provider model, the target of a model alias.
+type ModelRef struct {
+	Provider string `json:"provider" mapstructure:"provider"`
+	Model    string `json:"model" mapstructure:"model"`
 }
 
+// CooldownOptions configures fallback-candidate cooldowns.
+type CooldownOptions struct {
+	// FailureThreshold is the number of consecutive failures that puts a model
+	// into cooldown. 0 disables cooldowns.
+	FailureThreshold int `json:"failure-threshold" mapstructure:"failure-threshold"`
+	// Duration is the cooldown length for reasons not listed in Reasons.
+	Duration time.Duration `json:"duration" mapstructure:"duration"`
+	// Reasons overrides Duration per failure reason, e.g. {"rate_limit": "5m", "timeout": "15s"}.
+	Reasons map[string]time.Duration `json:"reasons" mapstructure:"reasons"`
+}
+
+// cooldownReasons are the failure reasons a cooldown can be configured for.
+// Format errors never trigger a cooldown.
+var cooldownReasons = []string{"unknown", "auth", "rate_limit", "billing", "timeout", "unavailable", "server_error"}
+
 type ProviderConfig struct {
 	BaseURL    string            `json:"base-url" mapstructure:"base-url"`
 	APIKey     string            `json:"api-key" mapstructure:"api-key"`
@@ -20,6 +64,13 @@ type ProviderConfig struct {
 	AuthHeader *bool             `json:"auth-header" mapstructure:"auth-header"`
 	Headers    map[string]string `json:"headers" mapstructure:"headers"`
 	Models     []ModelDefinition `json:"models" mapstructure:"models"`
+
+	// DefaultHeaders are sent on every request to this provider (e.g. HTTP-Referer for OpenRouter).
+	// Entries in Headers take precedence on key conflicts.
+	DefaultHeaders map[string]string `json:"default-headers" mapstructure:"default-headers"`
+
+	// ProxyURL routes requests to this provider through an HTTP(S) proxy.
+	ProxyURL string `json:"proxy-url" mapstructure:"proxy-url"`
 }
 
 type ModelDefinition struct {
@@ -45,6 +96,13 @@ func NewModelOptions() *ModelOptions {
 	return &ModelOptions{
 		Mode:      "merge",
 		Providers: make(map[string]*ProviderConfig),
+		Cooldown: CooldownOptions{
+			FailureThreshold: 3,
+			Duration:         time.Minute,
+		},
+		DebugLogFile:       "logs/llm-debug.log",
+		DebugLogMaxSizeMB:  50,
+		DebugLogMaxBackups: 3,
 	}
 }
 
@@ -57,6 +115,11 @@ func (o *ModelOptions) Validate() []error {
 		if p.BaseURL == "" {
 			errs = append(errs, fmt.Errorf("provider %q, base_url is required", id))
 		}
+		if p.ProxyURL != "" {
+			if _, err := url.Parse(p.ProxyURL); err != nil {
+				errs = append(errs, fmt.Errorf("provider %q, invalid proxy-url: %w", id, err))
+			}
+		}
 		if len(p.Models) == 0 {
 			errs = append(errs, fmt.Errorf("provider %q, at least one model is required", id))
 		}
@@ -66,6 +129,31 @@ func (o *ModelOptions) Validate() []error {
 			}
 		}
 	}
+	for name, ref := range o.Aliases {
+		if name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("invalid model alias %q: must be non-empty and contain no '/'", name))
+		}
+		if ref.Provider == "" || ref.Model == "" {
+			errs = append(errs, fmt.Errorf("model alias %q: provider and model are required", name))
+		}
+		if ref.Provider == "alias" {
+			errs = append(errs, fmt.Errorf("model alias %q: aliases cannot point to other aliases", name))
+		}
+	}
+	if o.Cooldown.FailureThreshold < 0 {
+		errs = append(errs, fmt.Errorf("cooldown failure-threshold must be >= 0, got %d", o.Cooldown.FailureThreshold))
+	}
+	if o.Cooldown.Duration < 0 {
+		errs = append(errs, fmt.Errorf("cooldown duration must be >= 0, got %s", o.Cooldown.Duration))
+	}
+	for reason, d := range o.Cooldown.Reasons {
+		if !slices.Contains(cooldownReasons, reason) {
+			errs = append(errs, fmt.Errorf("cooldown reason %q is unknown, must be one of %s", reason, strings.Join(cooldownReasons, ", ")))
+		}
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("cooldown reason %q: duration must be >= 0, got %s", reason, d))
+		}
+	}
 	return errs
 }
 
@@ -73,4 +161,11 @@ func (o *ModelOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Mode, "models.mode", o.Mode, "Model provider merge mode: 'merge' or 'replace'.")
 	fs.StringVar(&o.DefaultProvider, "models.default-provider", o.DefaultProvider, "Default provider ID.")
 	fs.StringVar(&o.DefaultModel, "models.default-model", o.DefaultModel, "Default model ID.")
+	fs.BoolVar(&o.ProbeOnStart, "models.probe-on-start", o.ProbeOnStart, "Probe auto-discovered providers at startup and disable those that cannot be reached.")
+	fs.IntVar(&o.Cooldown.FailureThreshold, "models.cooldown.failure-threshold", o.Cooldown.FailureThreshold, "Consecutive failures after which a fallback candidate is put into cooldown (0 disables cooldowns).")
+	fs.DurationVar(&o.Cooldown.Duration, "models.cooldown.duration", o.Cooldown.Duration, "How long a failing fallback candidate stays in cooldown.")
+	fs.BoolVar(&o.Debug, "models.debug", o.Debug, "Log every provider request/response (API keys redacted) to the debug log file.")
+	fs.StringVar(&o.DebugLogFile, "models.debug-log-file", o.DebugLogFile, "Path of the rotating provider debug log.")
+	fs.IntVar(&o.DebugLogMaxSizeMB, "models.debug-log-max-size-mb", o.DebugLogMaxSizeMB, "Max size in MB of the provider debug log before rotation.")
+	fs.IntVar(&o.DebugLogMaxBackups, "models.debug-log-max-backups", o.DebugLogMaxBackups, "Number of rotated provider debug logs to keep.")
 }
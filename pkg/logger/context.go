@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Correlation field names attached to log lines by the Ctx* functions.
+const (
+	FieldRunID     = "run_id"
+	FieldSessionID = "session_id"
+	FieldAgentID   = "agent_id"
+	FieldLogID     = "log_id"
+)
+
+type ctxFieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying the given key/value pairs as
+// correlation fields. Every Ctx* log call made with the returned context (or a
+// context derived from it, including one handed to a goroutine) includes
+// them, so all log lines of e.g. one agent run can be filtered together.
+// Existing fields are kept; a repeated key is overwritten. Empty values are
+// skipped.
+func WithFields(ctx context.Context, keyvals ...string) context.Context {
+	parent, _ := ctx.Value(ctxFieldsKey{}).(logrus.Fields)
+	fields := make(logrus.Fields, len(parent)+len(keyvals)/2)
+	for k, v := range parent {
+		fields[k] = v
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i+1] != "" {
+			fields[keyvals[i]] = keyvals[i+1]
+		}
+	}
+	return context.WithValue(ctx, ctxFieldsKey{}, fields)
+}
+
+// Fields returns the correlation fields carried by ctx, plus the log ID when
+// one is set. The result must not be modified.
+func Fields(ctx context.Context) logrus.Fields {
+	if ctx == nil {
+		return logrus.Fields{}
+	}
+	fields, _ := ctx.Value(ctxFieldsKey{}).(logrus.Fields)
+	logID, _ := ctx.Value(CtxKeyLogID).(string)
+	if logID == "" {
+		if fields == nil {
+			return logrus.Fields{}
+		}
+		return fields
+	}
+	withID := make(logrus.Fields, len(fields)+1)
+	for k, v := range fields {
+		withID[k] = v
+	}
+	withID[FieldLogID] = logID
+	return withID
+}
+
+func CtxDebug(ctx context.Context, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).Debugf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).Debug(format)
+	} else {
+		instance.WithFields(Fields(ctx)).Debugf(format, args...)
+	}
+}
+
+func CtxInfo(ctx context.Context, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).Infof(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).Info(format)
+	} else {
+		instance.WithFields(Fields(ctx)).Infof(format, args...)
+	}
+}
+
+func CtxWarn(ctx context.Context, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).Warnf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).Warn(format)
+	} else {
+		instance.WithFields(Fields(ctx)).Warnf(format, args...)
+	}
+}
+
+func CtxError(ctx context.Context, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).Errorf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).Error(format)
+	} else {
+		instance.WithFields(Fields(ctx)).Errorf(format, args...)
+	}
+}
+
+func CtxDebugX(ctx context.Context, field string, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).WithField("module", field).Debugf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Debug(format)
+	} else {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Debugf(format, args...)
+	}
+}
+
+func CtxInfoX(ctx context.Context, field string, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).WithField("module", field).Infof(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Info(format)
+	} else {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Infof(format, args...)
+	}
+}
+
+func CtxWarnX(ctx context.Context, field string, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).WithField("module", field).Warnf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Warn(format)
+	} else {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Warnf(format, args...)
+	}
+}
+
+func CtxErrorX(ctx context.Context, field string, format string, args ...interface{}) {
+	if instance == nil {
+		logrus.WithFields(Fields(ctx)).WithField("module", field).Errorf(format, args...)
+		return
+	}
+	if len(args) == 0 {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Error(format)
+	} else {
+		instance.WithFields(Fields(ctx)).WithField("module", field).Errorf(format, args...)
+	}
+}
@@ -178,7 +178,7 @@ func NewLogger(filename string) (*Logger, error) {
 		if !ok {
 			return "", ""
 		}
-		if strings.Contains(filename, "pkg/logger/log.go") {
+		if strings.Contains(filename, "pkg/logger/log.go") || strings.Contains(filename, "pkg/logger/context.go") {
 			_, filename, line, ok = runtime.Caller(12)
 			if !ok {
 				return "", ""
@@ -19,7 +19,7 @@ func Recovery(ctx context.Context) {
 	}
 
 	err := fmt.Errorf("%v", e)
-	logger.Error(fmt.Sprintf("[catch panic] err = %v \n stacktrace:\n%s", err, debug.Stack()))
+	logger.CtxError(ctx, "[catch panic] err = %v \n stacktrace:\n%s", err, debug.Stack())
 }
 
 func Go(ctx context.Context, fn func()) {